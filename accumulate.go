@@ -0,0 +1,104 @@
+package tooladapter
+
+import (
+	"github.com/openai/openai-go/v3"
+)
+
+// Accumulate reads the rest of the stream and returns it as a non-streaming
+// response: content deltas are concatenated, tool call deltas are merged by index,
+// and each choice ends with the last finish reason received. The stream is closed
+// afterwards.
+//
+// Unlike openai.ChatCompletionAccumulator, it accepts the chunks the adapter
+// synthesizes, which carry no ID or model, and keep-alive chunks from
+// WithStreamHeartbeat. The response takes its ID, model and usage from the upstream
+// chunks, including those the adapter held back. A choice that received tool calls finishes with "tool_calls", even when the
+// upstream finish chunk that followed them reported "stop".
+//
+// When the stream fails, the response accumulated so far is returned with the
+// stream's error.
+func (s *StreamAdapter) Accumulate() (openai.ChatCompletion, error) {
+	defer func() { _ = s.Close() }()
+
+	var completion openai.ChatCompletion
+	for s.Next() {
+		accumulateChunk(&completion, s.Current())
+	}
+
+	// Tool call chunks are synthesized without the upstream ID and model
+	s.mu.Lock()
+	first := s.firstChunk
+	s.mu.Unlock()
+	if completion.ID == "" {
+		completion.ID = first.ID
+	}
+	if completion.Model == "" {
+		completion.Model = first.Model
+	}
+	if completion.Created == 0 {
+		completion.Created = first.Created
+	}
+
+	for i := range completion.Choices {
+		choice := &completion.Choices[i]
+		choice.Message.Role = "assistant"
+		if len(choice.Message.ToolCalls) > 0 {
+			choice.FinishReason = "tool_calls"
+		}
+	}
+	completion.Object = "chat.completion"
+	return completion, s.Err()
+}
+
+// accumulateChunk adds a chunk to a response built by Accumulate.
+func accumulateChunk(completion *openai.ChatCompletion, chunk openai.ChatCompletionChunk) {
+	if completion.ID == "" {
+		completion.ID = chunk.ID
+	}
+	if chunk.Model != "" {
+		completion.Model = chunk.Model
+	}
+	if chunk.Created != 0 {
+		completion.Created = chunk.Created
+	}
+	if chunk.SystemFingerprint != "" {
+		completion.SystemFingerprint = chunk.SystemFingerprint
+	}
+	if chunk.ServiceTier != "" {
+		completion.ServiceTier = openai.ChatCompletionServiceTier(chunk.ServiceTier)
+	}
+	// Providers report usage once, in the last upstream chunk
+	if chunk.Usage.TotalTokens > 0 {
+		completion.Usage = chunk.Usage
+	}
+
+	for _, delta := range chunk.Choices {
+		index := int(delta.Index)
+		for len(completion.Choices) <= index {
+			completion.Choices = append(completion.Choices, openai.ChatCompletionChoice{Index: int64(len(completion.Choices))})
+		}
+		choice := &completion.Choices[index]
+
+		if delta.FinishReason != "" {
+			choice.FinishReason = delta.FinishReason
+		}
+		choice.Message.Content += delta.Delta.Content
+		choice.Message.Refusal += delta.Delta.Refusal
+
+		for _, deltaTool := range delta.Delta.ToolCalls {
+			toolIndex := int(deltaTool.Index)
+			for len(choice.Message.ToolCalls) <= toolIndex {
+				choice.Message.ToolCalls = append(choice.Message.ToolCalls, openai.ChatCompletionMessageToolCallUnion{Type: functionType})
+			}
+			tool := &choice.Message.ToolCalls[toolIndex]
+			if deltaTool.ID != "" {
+				tool.ID = deltaTool.ID
+			}
+			tool.Function.Name += deltaTool.Function.Name
+			tool.Function.Arguments += deltaTool.Function.Arguments
+		}
+
+		choice.Logprobs.Content = append(choice.Logprobs.Content, delta.Logprobs.Content...)
+		choice.Logprobs.Refusal = append(choice.Logprobs.Refusal, delta.Logprobs.Refusal...)
+	}
+}
@@ -0,0 +1,90 @@
+package tooladapter_test
+
+import (
+	"errors"
+	"testing"
+
+	tooladapter "github.com/juburr/openai-tool-adapter/v3"
+	"github.com/juburr/openai-tool-adapter/v3/tooltest"
+	"github.com/openai/openai-go/v3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// upstreamChunk returns a content chunk carrying the metadata of an upstream chunk.
+func upstreamChunk(content string) openai.ChatCompletionChunk {
+	chunk := tooltest.ContentChunk(content)
+	chunk.ID = "chatcmpl-123"
+	chunk.Model = "llama-3"
+	chunk.Created = 1700000000
+	return chunk
+}
+
+func TestStreamAdapterAccumulate(t *testing.T) {
+	t.Run("Content", func(t *testing.T) {
+		finish := tooltest.FinishChunk("stop")
+		finish.Usage = openai.CompletionUsage{PromptTokens: 10, CompletionTokens: 3, TotalTokens: 13}
+		upstream := tooltest.NewMockStream(upstreamChunk("Hello"), upstreamChunk(", world"), finish)
+
+		completion, err := tooladapter.New().TransformStreamingResponse(upstream).Accumulate()
+		require.NoError(t, err)
+
+		assert.Equal(t, "chatcmpl-123", completion.ID)
+		assert.Equal(t, "llama-3", completion.Model)
+		assert.Equal(t, int64(13), completion.Usage.TotalTokens)
+		require.Len(t, completion.Choices, 1)
+		assert.Equal(t, "Hello, world", completion.Choices[0].Message.Content)
+		assert.Equal(t, "stop", completion.Choices[0].FinishReason)
+		assert.True(t, upstream.Closed())
+	})
+
+	t.Run("ToolCalls", func(t *testing.T) {
+		upstream := tooltest.NewMockStream(
+			upstreamChunk(`[{"name": "get_weather", "parameters": {"city": "Paris"}}, `),
+			upstreamChunk(`{"name": "get_time", "parameters": {"zone": "CET"}}]`),
+			tooltest.FinishChunk("stop"),
+		)
+		adapter := tooladapter.New(tooladapter.WithToolPolicy(tooladapter.ToolDrainAll))
+
+		completion, err := adapter.TransformStreamingResponse(upstream).Accumulate()
+		require.NoError(t, err)
+
+		assert.Equal(t, "chatcmpl-123", completion.ID, "taken from the buffered upstream chunks")
+		assert.Equal(t, "llama-3", completion.Model)
+		choice := completion.Choices[0]
+		assert.Equal(t, "tool_calls", choice.FinishReason)
+		assert.Empty(t, choice.Message.Content)
+		require.Len(t, choice.Message.ToolCalls, 2)
+		assert.Equal(t, "get_weather", choice.Message.ToolCalls[0].Function.Name)
+		assert.JSONEq(t, `{"city": "Paris"}`, choice.Message.ToolCalls[0].Function.Arguments)
+		assert.Equal(t, "get_time", choice.Message.ToolCalls[1].Function.Name)
+		assert.NotEmpty(t, choice.Message.ToolCalls[0].ID)
+		assert.NotEqual(t, choice.Message.ToolCalls[0].ID, choice.Message.ToolCalls[1].ID)
+	})
+
+	t.Run("MatchesResponseTransformation", func(t *testing.T) {
+		completion, err := tooladapter.New().TransformStreamingResponse(tooltest.NewContentStream(weatherJSON)).Accumulate()
+		require.NoError(t, err)
+		transformed, err := tooladapter.New().TransformCompletionsResponse(tooltest.Completion(weatherJSON))
+		require.NoError(t, err)
+
+		want, got := transformed.Choices[0], completion.Choices[0]
+		assert.Equal(t, want.FinishReason, got.FinishReason)
+		assert.Equal(t, want.Message.Content, got.Message.Content)
+		require.Len(t, got.Message.ToolCalls, 1)
+		assert.Equal(t, want.Message.ToolCalls[0].Type, got.Message.ToolCalls[0].Type)
+		assert.Equal(t, want.Message.ToolCalls[0].Function.Name, got.Message.ToolCalls[0].Function.Name)
+		assert.JSONEq(t, want.Message.ToolCalls[0].Function.Arguments, got.Message.ToolCalls[0].Function.Arguments)
+	})
+
+	t.Run("Error", func(t *testing.T) {
+		upstreamErr := errors.New("connection reset")
+		upstream := tooltest.NewContentStream("Partial answer")
+		upstream.SetError(upstreamErr)
+
+		completion, err := tooladapter.New().TransformStreamingResponse(upstream).Accumulate()
+		require.ErrorIs(t, err, upstreamErr)
+		require.Len(t, completion.Choices, 1)
+		assert.Equal(t, "Partial answer", completion.Choices[0].Message.Content)
+	})
+}
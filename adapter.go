@@ -17,10 +17,13 @@ import (
 	"log/slog"
 	"strings"
 	"sync"
+	"sync/atomic"
+	"text/template"
 	"time"
 
 	"github.com/google/uuid"
 	"github.com/openai/openai-go/v3"
+	"github.com/openai/openai-go/v3/packages/param"
 )
 
 // Define the constant value for "function" type
@@ -47,28 +50,251 @@ type Adapter struct {
 	promptTemplate  string
 	logger          *slog.Logger
 	metricsCallback func(MetricEventData)
+	parseEventHook  func(ParseEvent)
+	streamRecorder  *streamRecorder
+
+	// Receives tool call arguments while they stream (see WithArgumentStreaming)
+	argumentHandler func(ArgumentStream)
+
+	// Receives a report after each non-streaming transformation (see
+	// WithPostTransformHook); reports wait in the queue for one of the workers,
+	// which start with the first report (see WithPostTransformHookPool)
+	postTransformHook      func(context.Context, PostTransformReport)
+	postTransformWorkers   int
+	postTransformQueueSize int
+	postTransformQueue     chan postTransformJob
+	postTransformStart     sync.Once
+	postTransformDropped   atomic.Int64
+
+	// Emit the per-phase metric events enabled by WithPhaseMetrics
+	phaseMetrics bool
+
+	// text/template prompt from WithToolPromptTemplate; replaces promptTemplate when set
+	toolPromptTemplate *template.Template
+
+	// Prompt template variants of WithTemplateExperiments, each served by an adapter
+	// built with its template; templateVariant names the variant of such an adapter
+	templateExperiments  []TemplateExperiment
+	experimentAssignment ExperimentAssignment
+	templateVariants     []templateVariant
+	templateVariant      string
+
+	// Language pack for injected instructions, e.g. "de"; "" => English
+	promptLanguage string
+
+	// Rendering of tool parameter schemas in the injected prompt
+	schemaFormat SchemaFormat
+
+	// Presentation of tool definitions in the injected prompt
+	promptStyle PromptStyle
+
+	// Rendered tool prompts keyed by tool definitions; nil => no caching
+	promptCache *lruCache[renderedToolPrompt]
+
+	// Few-shot examples keyed by declared tool name or GlobalExamples
+	toolExamples map[string][]Example
+
+	// Chooses the tools injected for each request; nil => all tools
+	toolSelector ToolSelector
+
+	// Rewrites tool result content before injection; nil => injected as is
+	toolResultTransformer ToolResultTransformer
+
+	// Re-injects image, audio and file parts of tool results as content parts
+	multimodalToolResults bool
+
+	// Reports models that support tools natively and bypass the adapter; nil => none
+	nativePassthrough func(model string) bool
+
+	// Per-model profiles from WithModelRules and their compiled adapters
+	modelProfiles map[string]ModelProfile
+	modelRules    []modelRule
+
+	// Request body fields of the model profile this adapter was built for
+	extraBody map[string]any
+
+	// Constrains tool calls with a GBNF grammar in the request's grammar field, or
+	// with a JSON Schema sent as the constraint selects
+	gbnfGrammar        bool
+	toolCallConstraint ToolCallConstraint
+
+	// Middleware from WithRequestMiddleware and its siblings, outermost first, and the
+	// transforms wrapped in it; nil chains => no middleware
+	requestMiddleware  []func(RequestTransform) RequestTransform
+	responseMiddleware []func(ResponseTransform) ResponseTransform
+	streamMiddleware   []func(StreamTransform) StreamTransform
+	requestChain       RequestTransform
+	responseChain      ResponseTransform
+	streamChain        StreamTransform
 
 	// Tool policy configuration
-	toolPolicy           ToolPolicy
-	toolCollectWindow    time.Duration // streaming only; 0 => structure-only (no timer)
-	toolMaxCalls         int           // cap across streaming + non-streaming (e.g., 8)
-	toolCollectMaxBytes  int           // safety cap for JSON collection (e.g., 64*1024)
-	cancelUpstreamOnStop bool          // streaming only; default true
+	toolPolicy             ToolPolicy
+	toolCollectWindow      time.Duration // streaming only; 0 => structure-only (no timer)
+	toolCollectTotalBudget time.Duration // streaming only; 0 => unlimited buffering per stream
+	toolMaxCalls           int           // cap across streaming + non-streaming (e.g., 8)
+	toolCollectMaxBytes    int           // safety cap for JSON collection (e.g., 64*1024)
+	cancelUpstreamOnStop   bool          // streaming only; default true
 
 	// Buffer size configuration
 	streamBufferLimit    int // streaming buffer limit (e.g., 10*1024*1024)
 	bufferPoolThreshold  int // buffer pool size threshold (e.g., 64*1024)
 	streamLookAheadLimit int // early tool detection lookahead limit in chars (e.g., 100)
 
+	// Content size above which non-streaming responses are not parsed; 0 => unlimited
+	responseParseMaxBytes int
+
+	// Memory a stream, and all streams together, may retain for tool call
+	// detection; 0 => unlimited. streamMemory counts the retained total.
+	streamMemoryLimit          int
+	aggregateStreamMemoryLimit int
+	streamMemory               *streamMemory
+
+	// Transformed responses keyed by response content; nil => no caching
+	responseCache *lruCache[cachedCompletion]
+
+	// Worker count for batch transformations; 0 => GOMAXPROCS
+	batchConcurrency int
+
+	// Bytes to peek at JSON-looking content before deciding to buffer it
+	bufferDecisionLookahead int // streaming only; 0 => decide on each chunk alone
+
+	// Accept JSON5 and simple YAML tool calls in addition to strict JSON
+	lenientParsing bool
+
+	// Stop sequences added to requests that carry tools
+	toolStopSequences []string
+
+	// Start of the assistant turn appended to requests that carry tools; "" => none
+	assistantPrefill string
+
+	// Keep-alive configuration
+	streamHeartbeat time.Duration // streaming only; 0 => no keep-alive chunks while buffering
+
+	// Upstream chunks prefetched by a background reader; 0 => synchronous reads
+	streamReadAhead int
+
+	// Stream timeouts; 0 => none
+	streamIdleTimeout time.Duration // longest wait for an upstream chunk
+	streamMaxDuration time.Duration // longest total stream duration
+
+	// Handling of a partly buffered tool call when the upstream stream fails
+	streamErrorPolicy StreamErrorPolicy
+
+	// Handling of an incomplete ToolCollectThenStop collection at the end of a stream
+	streamEOFPolicy StreamEOFPolicy
+
+	// Handling of <think> blocks of reasoning models
+	thinkBlocks ThinkBlockPolicy
+
+	// Chat template stop tokens removed from content (see WithStopTokenStripping);
+	// nil tokens with stripping enabled select DefaultStopTokens by model
+	stripStopTokens bool
+	stopTokens      []string
+
+	// Minimum length in characters of a tool result passage copied into the reply
+	// that WithEchoSuppression removes; 0 disables it
+	echoThreshold int
+
+	// Where the tool prompt is injected (see WithInjectionPosition)
+	injectionPosition InjectionPosition
+
 	// Indicates whether the model and its chat template support system messages
 	// We leave it up to the caller to determine versus building a giant model registry
 	systemMessagesSupported bool
+
+	// Inject into developer messages, for servers that distinguish them from system
+	developerMessagesSupported bool
+
+	// Keep Tools, ToolChoice and ParallelToolCalls on transformed requests
+	preserveToolsField bool
+
+	// Emulation of response_format json_object for backends that ignore it
+	jsonMode JSONModePolicy
+
+	// Emit chunks and completions exactly as the OpenAI API populates them
+	strictOpenAI bool
+
+	// Copy the first tool call of each choice into the legacy function_call field
+	legacyFunctionCall bool
+
+	// Sentinel markers around injected prompts
+	markInjectedContent  bool   // wrap injected prompts in the markers below
+	injectionBeginMarker string // e.g., DefaultInjectionBeginMarker
+	injectionEndMarker   string // e.g., DefaultInjectionEndMarker
+	promptCompaction     bool   // remove marked blocks from history before injecting
+
+	// Strip instruction-like content from tool descriptions before injection
+	sanitizeToolDefinitions bool
+
+	// Token estimates for injected prompts and tool call output; nil => not counted
+	tokenCounter TokenCounter
+
+	// Ceilings on the injected prompt; 0 => unlimited
+	maxInjectedPromptBytes  int
+	maxInjectedPromptTokens int
+
+	// Conversation truncation to the model's context window; 0 => disabled
+	contextWindow      int
+	truncationStrategy TruncationStrategy
+
+	// Redactors applied to log output, metric payloads and stream transcripts
+	redactors []Redactor
+
+	// Log volume control; see WithLogSampling
+	logSampling         float64                      // fraction of Debug and Info records kept
+	logCategoryLevels   map[LogCategory]slog.Level   // minimum level per category
+	logCategorySampling map[LogCategory]float64      // sampling rate per category
+	categoryLoggers     map[LogCategory]*slog.Logger // built by New from logger
+
+	// Tool naming configuration
+	toolNamespace       string              // "prefix" => tools exposed as "prefix.name"
+	toolCollisionPolicy ToolCollisionPolicy // how duplicate function names are handled
+
+	// Handling of tools other than function tools, which cannot be injected
+	unsupportedToolPolicy UnsupportedToolPolicy
+
+	// Response-side tool call filtering
+	allowedToolNames map[string]struct{}                          // nil => all names allowed
+	toolCallFilter   func(name string, args json.RawMessage) bool // nil => no custom filter
+	toolCallDedup    bool                                         // collapse repeated identical calls
+	argumentCoercion bool                                         // coerce arguments to ContextWithTools schemas
+
+	// Tools whose argument is written as a fenced code block (see
+	// WithToolArgumentModes), keyed by function name; nil => all JSON
+	argumentModes map[string]ArgumentMode
+
+	// Formats whose candidates are tried first, highest priority first (see
+	// WithParserPriority); nil => the order the formats were found in
+	parserPriority []CallFormat
+
+	// Tools that fenced code blocks of a language call (see WithCodeBlockTools),
+	// keyed by lower-case language tag; nil => none
+	codeBlockTools map[string]CodeBlockTool
+
+	// Approves, rewrites or rejects calls before they are emitted; nil => none
+	toolCallInterceptor ToolCallInterceptor
+
+	// Size and shape limits for parsed arguments; zero fields => unlimited
+	argumentLimits ArgumentLimits
+
+	// Handling of calls to functions outside allowedToolNames
+	unknownToolPolicy         UnknownToolPolicy
+	unknownToolMatchThreshold float64 // minimum similarity for UnknownToolCorrect
+
+	// Tool call rate limits; 0 => unlimited
+	maxToolCallsPerResponse     int
+	maxToolCallsPerConversation int // counted on the Conversation attached to the context
+
+	// Handling of arguments that break enum, range or length constraints
+	argumentViolationPolicy ArgumentViolationPolicy
 }
 
 // Internal structs for JSON manipulation
 type functionCall struct {
 	Name       string          `json:"name"`
 	Parameters json.RawMessage `json:"parameters"`
+	ID         string          `json:"-"` // Set when the model supplied its own tool call ID
+	source     *callSource     // Set when ContextWithToolCallSources asks for sources
 }
 
 // New creates a new tool adapter with optional configurations
@@ -92,6 +318,16 @@ func New(opts ...Option) *Adapter {
 		bufferPoolThreshold:     64 * 1024,        // 64KB buffer pool threshold
 		streamLookAheadLimit:    0,                // 0 = disabled, early detection off by default
 		systemMessagesSupported: false,            // gemma will be the top model used with this package
+
+		unknownToolMatchThreshold: 0.8,
+
+		logSampling: 1,
+
+		injectionBeginMarker: DefaultInjectionBeginMarker,
+		injectionEndMarker:   DefaultInjectionEndMarker,
+
+		postTransformWorkers:   postTransformHookWorkers,
+		postTransformQueueSize: postTransformHookQueueSize,
 	}
 
 	// Apply all provided options
@@ -99,6 +335,32 @@ func New(opts ...Option) *Adapter {
 		opt(adapter)
 	}
 
+	// Size the hook queue regardless of the order of WithPostTransformHook and
+	// WithPostTransformHookPool
+	if adapter.postTransformHook != nil {
+		adapter.postTransformQueue = make(chan postTransformJob, adapter.postTransformQueueSize)
+	}
+
+	// Translate the default template or select the style's template regardless of
+	// option order; a custom template is never replaced
+	if adapter.promptTemplate == DefaultPromptTemplate {
+		adapter.promptTemplate = adapter.styleTemplate()
+	}
+
+	// Redact log output regardless of the order of WithLogger and WithLogRedaction
+	if len(adapter.redactors) > 0 {
+		adapter.logger = slog.New(&redactingHandler{handler: adapter.logger.Handler(), adapter: adapter})
+	}
+
+	// Sample and filter log output by category; every category logger shares the filter
+	if adapter.hasLogFilter() {
+		adapter.logger = slog.New(&filteringHandler{handler: adapter.logger.Handler(), filter: newLogFilter(adapter)})
+	}
+	adapter.categoryLoggers = make(map[LogCategory]*slog.Logger, len(logCategories))
+	for _, category := range logCategories {
+		adapter.categoryLoggers[category] = adapter.logger.With(LogCategoryKey, string(category))
+	}
+
 	// Buffer pool for efficient string building with memory growth protection
 	adapter.bufferPool = sync.Pool{
 		New: func() interface{} {
@@ -106,6 +368,20 @@ func New(opts ...Option) *Adapter {
 		},
 	}
 
+	// Count the memory of all streams against WithAggregateStreamMemoryLimit
+	if adapter.aggregateStreamMemoryLimit > 0 {
+		adapter.streamMemory = &streamMemory{limit: int64(adapter.aggregateStreamMemoryLimit)}
+	}
+
+	// Build the adapters selected per model by WithModelRules
+	adapter.compileModelRules(opts)
+
+	// Build the adapters of the WithTemplateExperiments variants
+	adapter.compileTemplateExperiments(opts)
+
+	// Wrap the transforms in WithRequestMiddleware and its siblings
+	adapter.buildMiddlewareChains()
+
 	return adapter
 }
 
@@ -133,7 +409,22 @@ func (a *Adapter) TransformCompletionsRequest(req openai.ChatCompletionNewParams
 // TransformCompletionsRequestWithContext modifies a chat completion request to inject tool definitions
 // and process tool results with context support for cancellation and timeouts.
 func (a *Adapter) TransformCompletionsRequestWithContext(ctx context.Context, req openai.ChatCompletionNewParams) (openai.ChatCompletionNewParams, error) {
+	if a.postTransformHook != nil && transformReportFrom(ctx) == nil {
+		transformed, _, err := a.TransformCompletionsRequestWithReport(ctx, req)
+		return transformed, err
+	}
+
+	if a.requestChain != nil {
+		return a.requestChain(ctx, req)
+	}
+	return a.transformRequest(ctx, req)
+}
+
+// transformRequest implements TransformCompletionsRequestWithContext inside any
+// WithRequestMiddleware.
+func (a *Adapter) transformRequest(ctx context.Context, req openai.ChatCompletionNewParams) (openai.ChatCompletionNewParams, error) {
 	startTime := time.Now()
+	requestToolCount := len(req.Tools)
 
 	// Check for cancellation early
 	select {
@@ -142,26 +433,117 @@ func (a *Adapter) TransformCompletionsRequestWithContext(ctx context.Context, re
 	default:
 	}
 
+	// Hand the request to the adapter configured for its model
+	if adapter := a.forModel(string(req.Model)); adapter != a {
+		transformed, err := adapter.TransformCompletionsRequestWithContext(ctx, req)
+		if err != nil {
+			return transformed, err
+		}
+		return adapter.addExtraBody(transformed), nil
+	}
+
+	// Hand the request to the adapter of its prompt template experiment variant
+	if adapter := a.forExperiment(ctx, req); adapter != a {
+		return adapter.TransformCompletionsRequestWithContext(ctx, req)
+	}
+
+	// Track where each message of the transformed request comes from
+	origins := newMessageOrigins(ctx, req.Messages)
+
+	// Models with native function calling receive the request as is
+	if a.usesNativeTools(string(req.Model)) {
+		a.log(LogCategoryRequest).Debug("Model supports tools natively, passing request through",
+			"model", req.Model,
+			"tool_count", len(req.Tools))
+		if report := transformReportFrom(ctx); report != nil {
+			report.Passthrough, report.NativeTools = true, true
+		}
+		origins.record(ctx)
+		a.emitRequestTransformed(req, requestToolCount, 0, 0, true, startTime)
+		return req, nil
+	}
+
+	// Leave out tools the prompt cannot describe, or reject the request
+	tools, err := a.supportedTools(req.Tools)
+	if err != nil {
+		a.log(LogCategoryRequest).Error("Request declares an unsupported tool", "error", err)
+		return openai.ChatCompletionNewParams{}, wrapTransformError(PhaseRequest, "check tool types", -1, err)
+	}
+	if len(tools) < len(req.Tools) {
+		req.Tools = tools
+		if len(tools) == 0 {
+			req.ToolChoice = openai.ChatCompletionToolChoiceOptionUnionParam{}
+		}
+	}
+
 	// Extract tool results from messages and filter out ToolMessage types
-	toolResults, cleanMessages, err := a.extractToolResults(req.Messages)
+	toolResults, cleanMessages, err := a.extractToolResults(req.Messages, origins)
 	if err != nil {
-		a.logger.Error("Failed to extract tool results", "error", err)
-		return openai.ChatCompletionNewParams{}, fmt.Errorf("failed to extract tool results: %w", err)
+		a.log(LogCategoryRequest).Error("Failed to extract tool results", "error", err)
+		return openai.ChatCompletionNewParams{}, wrapTransformError(PhaseRequest, "extract tool results", -1, err)
+	}
+
+	// Label results with the function names a Session recorded for their call IDs
+	for i := range toolResults {
+		toolResults[i].Name = sessionToolCallName(ctx, toolResults[i].CallID)
+	}
+
+	// Move images, audio and files out of tool results so the text can be injected
+	attachments := a.extractToolResultAttachments(toolResults)
+
+	// Shorten tool results before they are injected into the prompt
+	a.transformToolResults(toolResults)
+
+	// Remove tool instructions injected into earlier turns that the caller passed back
+	if a.promptCompaction {
+		cleanMessages = a.compactInjectedPrompts(cleanMessages, origins)
+	}
+
+	// Keep only the tools the selector considers relevant to this request
+	if a.toolSelector != nil && len(req.Tools) > 0 {
+		req.Tools = a.selectTools(ctx, req)
+		if len(req.Tools) == 0 {
+			req.ToolChoice = openai.ChatCompletionToolChoiceOptionUnionParam{}
+		}
 	}
 
 	// Determine what we have: tools, tool results, both, or neither
 	hasTools := len(req.Tools) > 0
 	hasToolResults := len(toolResults) > 0
 
+	// Emulate response_format json_object when there are no tools to call
+	var jsonPrompt string
+	if !hasTools {
+		jsonPrompt = a.jsonModePrompt(req)
+	}
+
 	// Case 1: Neither tools nor tool results - pass through unchanged
-	if !hasTools && !hasToolResults {
-		a.logger.Debug("No tools or tool results present, passing through unchanged")
+	if !hasTools && !hasToolResults && jsonPrompt == "" {
+		a.log(LogCategoryRequest).Debug("No tools or tool results present, passing through unchanged")
+		if a.promptCompaction {
+			req.Messages = cleanMessages
+		}
+		if report := transformReportFrom(ctx); report != nil {
+			report.Passthrough = true
+		}
+		origins.record(ctx)
+		a.emitRequestTransformed(req, requestToolCount, 0, 0, true, startTime)
 		return req, nil
 	}
 
+	// Apply the tool namespace and reject (or rename) colliding function names
+	tools, err = a.resolveToolNames(req.Tools)
+	if err != nil {
+		a.log(LogCategoryRequest).Error("Failed to resolve tool names", "error", err, "tool_count", len(req.Tools))
+		return openai.ChatCompletionNewParams{}, wrapTransformError(PhaseRequest, "resolve tool names", -1, err)
+	}
+
+	// Strip instruction-like content from tool descriptions before injecting them
+	tools = a.sanitizeTools(tools)
+
 	// Extract tool names for logging and metrics
-	toolNames := make([]string, 0, len(req.Tools))
-	for _, tool := range req.Tools {
+	toolNames := make([]string, 0, len(tools))
+	for _, tool := range tools {
 		if function := tool.GetFunction(); function != nil {
 			toolNames = append(toolNames, function.Name)
 		}
@@ -176,18 +558,37 @@ func (a *Adapter) TransformCompletionsRequestWithContext(ctx context.Context, re
 
 	// Build the combined prompt based on what we have
 	var combinedPrompt string
+	var toolPrompt renderedToolPrompt
+	var promptCacheHit bool
 
-	if hasTools && hasToolResults {
+	if a.toolPromptTemplate != nil && (hasTools || hasToolResults) {
+		// A WithToolPromptTemplate template renders tools and tool results together
+		combinedPrompt, err = a.renderToolPrompt(ctx, req, tools, toolResults)
+		if err != nil {
+			a.log(LogCategoryRequest).Error("Failed to render prompt template", "error", err, "tool_count", len(req.Tools))
+			return openai.ChatCompletionNewParams{}, wrapTransformError(PhaseRequest, "render prompt template", -1, err)
+		}
+		if hasTools {
+			combinedPrompt = a.appendToolInstructions(ctx, req, tools, combinedPrompt)
+		}
+
+		a.log(LogCategoryRequest).Info("Transformed request: prompt template rendered",
+			"tool_count", len(req.Tools),
+			"tool_names", toolNames,
+			"tool_results_count", len(toolResults),
+			"prompt_length", len(combinedPrompt))
+
+	} else if hasTools && hasToolResults {
 		// Case 2: Both tools and tool results
-		toolPrompt, err := a.buildToolPromptWithContext(ctx, req.Tools)
+		toolPrompt, promptCacheHit, err = a.cachedToolPrompt(ctx, tools)
 		if err != nil {
-			a.logger.Error("Failed to build tool prompt", "error", err, "tool_count", len(req.Tools))
-			return openai.ChatCompletionNewParams{}, fmt.Errorf("failed to build tool prompt: %w", err)
+			a.log(LogCategoryRequest).Error("Failed to build tool prompt", "error", err, "tool_count", len(req.Tools))
+			return openai.ChatCompletionNewParams{}, wrapTransformError(PhaseRequest, "build tool prompt", -1, err)
 		}
 		toolResultsPrompt := a.buildToolResultsPrompt(toolResults)
-		combinedPrompt = toolPrompt + "\n\n" + toolResultsPrompt
+		combinedPrompt = a.appendToolInstructions(ctx, req, tools, toolPrompt.text) + "\n\n" + toolResultsPrompt
 
-		a.logger.Info("Transformed request: tools and tool results present",
+		a.log(LogCategoryRequest).Info("Transformed request: tools and tool results present",
 			"tool_count", len(req.Tools),
 			"tool_names", toolNames,
 			"tool_results_count", len(toolResults),
@@ -195,42 +596,129 @@ func (a *Adapter) TransformCompletionsRequestWithContext(ctx context.Context, re
 
 	} else if hasTools {
 		// Case 3: Only tools (original behavior)
-		combinedPrompt, err = a.buildToolPromptWithContext(ctx, req.Tools)
+		toolPrompt, promptCacheHit, err = a.cachedToolPrompt(ctx, tools)
 		if err != nil {
-			a.logger.Error("Failed to build tool prompt", "error", err, "tool_count", len(req.Tools))
-			return openai.ChatCompletionNewParams{}, fmt.Errorf("failed to build tool prompt: %w", err)
+			a.log(LogCategoryRequest).Error("Failed to build tool prompt", "error", err, "tool_count", len(req.Tools))
+			return openai.ChatCompletionNewParams{}, wrapTransformError(PhaseRequest, "build tool prompt", -1, err)
 		}
+		combinedPrompt = a.appendToolInstructions(ctx, req, tools, toolPrompt.text)
 
-		a.logger.Info("Transformed request: tools present",
+		a.log(LogCategoryRequest).Info("Transformed request: tools present",
 			"tool_count", len(req.Tools),
 			"tool_names", toolNames,
 			"prompt_length", len(combinedPrompt))
 
-	} else {
+	} else if hasToolResults {
 		// Case 4: Only tool results (no callable tools)
 		combinedPrompt = a.buildToolResultsPrompt(toolResults)
 
-		a.logger.Info("Transformed request: tool results present",
+		a.log(LogCategoryRequest).Info("Transformed request: tool results present",
 			"tool_results_count", len(toolResults),
 			"prompt_length", len(combinedPrompt))
 	}
 
+	if jsonPrompt != "" {
+		if combinedPrompt != "" {
+			combinedPrompt += "\n\n"
+		}
+		combinedPrompt += jsonPrompt
+		a.log(LogCategoryRequest).Debug("Injected JSON mode instruction",
+			"policy", a.jsonMode.String())
+	}
+
+	injectedPrompt := combinedPrompt
+	if a.markInjectedContent {
+		injectedPrompt = a.wrapInjectedPrompt(injectedPrompt)
+	}
+	injectedTokens := a.countTokens(injectedPrompt)
+	if err := a.checkInjectedPromptSize(injectedPrompt, injectedTokens, len(tools)); err != nil {
+		return openai.ChatCompletionNewParams{}, wrapTransformError(PhaseRequest, "check injected prompt size", -1, err)
+	}
+	recordInjectedTokens(ctx, injectedTokens)
+	if report := transformReportFrom(ctx); report != nil {
+		report.InjectedBytes = len(injectedPrompt)
+		report.InjectedTokens = injectedTokens
+		report.ToolsRendered = toolNames
+		report.ToolResultsConsumed = len(toolResults)
+		report.TemplateVariant = a.templateVariant
+	}
+
+	a.emitPhaseMetric(ToolPromptRenderedData{
+		ToolCount:       len(tools),
+		ToolResultCount: len(toolResults),
+		PromptLength:    len(injectedPrompt),
+		PromptTokens:    injectedTokens,
+		CustomTemplate:  hasTools && (a.toolPromptTemplate != nil || a.promptTemplate != a.styleTemplate()),
+	})
+
 	totalDuration := time.Since(startTime)
 
 	// Emit metrics event
-	a.emitMetric(ToolTransformationData{
-		ToolCount:    len(req.Tools),
-		ToolNames:    toolNames,
-		PromptLength: len(combinedPrompt),
-		Performance: PerformanceMetrics{
-			ProcessingDuration: totalDuration,
-		},
-	})
+	if a.metricsCallback != nil {
+		if !toolPrompt.measured {
+			toolPrompt.schemaBytes, toolPrompt.sourceSchemaBytes = a.schemaSizes(tools)
+		}
+		a.emitMetric(ToolTransformationData{
+			ToolCount:        len(req.Tools),
+			ToolNames:        toolNames,
+			PromptLength:     len(combinedPrompt),
+			InjectedTokens:   injectedTokens,
+			SchemaBytes:      toolPrompt.schemaBytes,
+			SchemaBytesSaved: toolPrompt.sourceSchemaBytes - toolPrompt.schemaBytes,
+			PromptCacheHit:   promptCacheHit,
+			TemplateVariant:  a.templateVariant,
+			Performance: PerformanceMetrics{
+				ProcessingDuration: totalDuration,
+			},
+		})
+	}
 
 	// Apply the combined prompt with cleaned messages (ToolMessages removed)
 	modifiedReq := req
-	modifiedReq.Messages = cleanMessages
-	return a.applyToolPrompt(modifiedReq, combinedPrompt), nil
+	modifiedReq.Messages = a.truncateMessages(ctx, req, cleanMessages, injectedPrompt, origins)
+	if hasTools {
+		modifiedReq.Stop = a.mergeStopSequences(req.Stop)
+		modifiedReq = a.addToolGrammar(modifiedReq, tools)
+		modifiedReq = a.addToolCallSchema(modifiedReq, tools)
+	}
+	modifiedReq = a.applyToolPrompt(modifiedReq, injectedPrompt, origins)
+	if attachments > 0 {
+		origins.insert(len(modifiedReq.Messages))
+		modifiedReq.Messages = append(modifiedReq.Messages, a.toolResultAttachmentsMessage(toolResults))
+	}
+	if hasTools && a.assistantPrefill != "" {
+		origins.insert(len(modifiedReq.Messages))
+		modifiedReq.Messages = append(modifiedReq.Messages, openai.AssistantMessage(a.assistantPrefill))
+	}
+	origins.record(ctx)
+	a.emitRequestTransformed(modifiedReq, requestToolCount, len(tools), len(toolResults), false, startTime)
+	return modifiedReq, nil
+}
+
+// appendToolInstructions adds the instructions that depend on the request to a tool
+// prompt: how to pass text arguments and run code blocks, the single-call
+// instruction and the note on tools changed since an earlier turn.
+func (a *Adapter) appendToolInstructions(ctx context.Context, req openai.ChatCompletionNewParams, tools []openai.ChatCompletionToolUnionParam, toolPrompt string) string {
+	toolPrompt = a.appendTextArgumentTools(tools, toolPrompt)
+	toolPrompt = a.appendCodeBlockTools(tools, toolPrompt)
+	toolPrompt = a.appendSingleToolCall(req, toolPrompt)
+	return a.appendToolsUpdated(ctx, tools, toolPrompt)
+}
+
+// emitRequestTransformed emits a RequestTransformedData metric for a request
+// returned by TransformCompletionsRequestWithContext.
+func (a *Adapter) emitRequestTransformed(req openai.ChatCompletionNewParams, toolCount, injectedToolCount, toolResultCount int, passthrough bool, startTime time.Time) {
+	a.emitPhaseMetric(RequestTransformedData{
+		Model:             string(req.Model),
+		ToolCount:         toolCount,
+		InjectedToolCount: injectedToolCount,
+		ToolResultCount:   toolResultCount,
+		MessageCount:      len(req.Messages),
+		Passthrough:       passthrough,
+		Performance: PerformanceMetrics{
+			ProcessingDuration: time.Since(startTime),
+		},
+	})
 }
 
 // TransformCompletionsResponse processes LLM responses to extract and format tool calls.
@@ -241,27 +729,31 @@ func (a *Adapter) TransformCompletionsResponse(resp openai.ChatCompletion) (open
 }
 
 // processChoiceForToolCalls extracts and processes tool calls from a single choice
-// Returns the function calls, timing metrics, and whether processing should continue
+// Returns the function calls, timing metrics, whether processing should continue,
+// and any error raised by a response policy (e.g. UnknownToolError)
 func (a *Adapter) processChoiceForToolCalls(
 	ctx context.Context,
 	choice *openai.ChatCompletionChoice,
 	choiceIndex int,
 	startTime time.Time,
-) ([]functionCall, time.Duration, time.Duration, bool) {
+) ([]functionCall, time.Duration, time.Duration, bool, error) {
 	// Skip choices without content
 	if choice.Message.Content == "" {
-		a.logger.Debug("No content in choice, skipping",
+		a.log(LogCategoryParse).Debug("No content in choice, skipping",
 			"choice_index", choiceIndex)
-		return nil, 0, 0, false
+		return nil, 0, 0, false, nil
 	}
 
-	content := choice.Message.Content
+	content := a.detectableContent(choice.Message.Content)
 	contentLength := len(content)
+	if a.skipsResponseParse(contentLength, choiceIndex) {
+		return nil, 0, 0, false, nil
+	}
 
 	// Check for cancellation before expensive parsing
 	select {
 	case <-ctx.Done():
-		return nil, 0, 0, false
+		return nil, 0, 0, false, nil
 	default:
 	}
 
@@ -269,38 +761,63 @@ func (a *Adapter) processChoiceForToolCalls(
 	jsonStartTime := time.Now()
 
 	// Use state machine parser to extract JSON blocks
-	extractor := NewJSONExtractor(content)
-	candidates := extractor.ExtractJSONBlocks()
+	candidates := a.extractCandidates(content, false)
 
 	jsonParsingTime := time.Since(jsonStartTime)
 
 	if len(candidates) == 0 {
-		a.logger.Debug("No JSON candidates found in choice content",
-			"choice_index", choiceIndex,
-			"content_length", contentLength)
-		return nil, jsonParsingTime, 0, false
+		// Guarded so the no-tool-call fast path does not box log arguments
+		if a.logger.Enabled(ctx, slog.LevelDebug) {
+			a.log(LogCategoryParse).Debug("No JSON candidates found in choice content",
+				"choice_index", choiceIndex,
+				"content_length", contentLength)
+		}
+		return nil, jsonParsingTime, 0, false, nil
 	}
 
 	// Track timing for function call extraction
 	extractionStartTime := time.Now()
 
 	// Extract function calls from candidates
-	calls := ExtractFunctionCalls(candidates)
+	matched, winner, parser := matchFunctionCalls(candidates)
+	report := choiceParseReport(ctx)
+	if len(matched) > 0 && (toolCallSourcesFrom(ctx) != nil || report != nil) {
+		a.attachCallSources(matched, content, candidates[winner], parser)
+	}
+	if report != nil && len(matched) > 0 {
+		report.CallsFound = len(matched)
+		report.Format = parser
+		report.Repair = matched[0].source.repair
+	}
+	calls, err := a.postProcessCalls(ctx, matched)
 
 	extractionTime := time.Since(extractionStartTime)
 
+	if err != nil {
+		return nil, jsonParsingTime, extractionTime, false, wrapTransformError(PhaseResponse, "process tool calls", choiceIndex, err)
+	}
+
+	var beforeDedup []functionCall
+	if report != nil {
+		beforeDedup = append(beforeDedup, calls...)
+	}
+	calls, duplicates := a.deduplicateCalls(calls, nil)
+	if duplicates > 0 {
+		recordDroppedCalls(ctx, removedCalls(beforeDedup, calls), DropReasonDuplicate)
+	}
+
 	if len(calls) == 0 {
-		a.logger.Debug("No valid function calls extracted from JSON candidates",
+		a.log(LogCategoryParse).Debug("No valid function calls extracted from JSON candidates",
 			"choice_index", choiceIndex,
 			"candidate_count", len(candidates),
 			"content_length", contentLength)
-		return nil, jsonParsingTime, extractionTime, false
+		return nil, jsonParsingTime, extractionTime, false, nil
 	}
 
 	// Log and emit metrics for detected function calls
-	a.logAndEmitFunctionCalls(ctx, calls, choiceIndex, contentLength, len(candidates), startTime, jsonParsingTime, extractionTime)
+	a.logAndEmitFunctionCalls(ctx, calls, choiceIndex, content, len(candidates), duplicates, startTime, jsonParsingTime, extractionTime)
 
-	return calls, jsonParsingTime, extractionTime, true
+	return calls, jsonParsingTime, extractionTime, true, nil
 }
 
 // logAndEmitFunctionCalls handles logging and metrics emission for detected function calls
@@ -308,8 +825,9 @@ func (a *Adapter) logAndEmitFunctionCalls(
 	ctx context.Context,
 	calls []functionCall,
 	choiceIndex int,
-	contentLength int,
+	content string,
 	candidateCount int,
+	duplicates int,
 	startTime time.Time,
 	jsonParsingTime time.Duration,
 	extractionTime time.Duration,
@@ -319,6 +837,7 @@ func (a *Adapter) logAndEmitFunctionCalls(
 	for i, call := range calls {
 		functionNames[i] = call.Name
 	}
+	contentLength := len(content)
 
 	// Log the detection and conversion for this choice
 	logAttrs := []any{
@@ -342,15 +861,19 @@ func (a *Adapter) logAndEmitFunctionCalls(
 		logAttrs = append(logAttrs, "function_arguments", args)
 	}
 
-	a.logger.Info("Transformed choice: detected and converted function calls", logAttrs...)
+	a.log(LogCategoryParse).Info("Transformed choice: detected and converted function calls", logAttrs...)
+
+	a.emitToolDetectedEvents(calls, choiceIndex, contentLength, false)
 
 	// Emit metrics for this specific choice
 	a.emitMetric(FunctionCallDetectionData{
-		FunctionCount:  len(calls),
-		FunctionNames:  functionNames,
-		ContentLength:  contentLength,
-		JSONCandidates: candidateCount,
-		Streaming:      false,
+		FunctionCount:     len(calls),
+		FunctionNames:     functionNames,
+		ContentLength:     contentLength,
+		JSONCandidates:    candidateCount,
+		Streaming:         false,
+		DuplicatesRemoved: duplicates,
+		ContentTokens:     a.countTokens(content),
 		Performance: PerformanceMetrics{
 			ProcessingDuration: time.Since(startTime),
 			SubOperations: map[string]time.Duration{
@@ -365,6 +888,19 @@ func (a *Adapter) logAndEmitFunctionCalls(
 // with context support for cancellation and timeouts.
 // This function now processes ALL choices in the response, not just the first one.
 func (a *Adapter) TransformCompletionsResponseWithContext(ctx context.Context, resp openai.ChatCompletion) (openai.ChatCompletion, error) {
+	if a.postTransformHook != nil && parseReportFrom(ctx) == nil {
+		transformed, _, err := a.TransformCompletionsResponseWithReport(ctx, resp)
+		return transformed, err
+	}
+	if a.responseChain != nil {
+		return a.responseChain(ctx, resp)
+	}
+	return a.transformResponse(ctx, resp)
+}
+
+// transformResponse implements TransformCompletionsResponseWithContext inside any
+// WithResponseMiddleware.
+func (a *Adapter) transformResponse(ctx context.Context, resp openai.ChatCompletion) (openai.ChatCompletion, error) {
 	startTime := time.Now()
 
 	// Check for cancellation early
@@ -376,9 +912,47 @@ func (a *Adapter) TransformCompletionsResponseWithContext(ctx context.Context, r
 
 	// Guard clauses: return early if there's nothing to process
 	if len(resp.Choices) == 0 {
-		a.logger.Debug("No choices in response, passing through unchanged")
+		a.log(LogCategoryParse).Debug("No choices in response, passing through unchanged")
+		return resp, nil
+	}
+	if adapter := a.forModel(resp.Model); adapter != a {
+		return adapter.TransformCompletionsResponseWithContext(ctx, resp)
+	}
+	if a.usesNativeTools(resp.Model) {
+		a.log(LogCategoryParse).Debug("Model supports tools natively, passing response through",
+			"model", resp.Model)
+		if report := parseReportFrom(ctx); report != nil {
+			report.NativeTools = true
+		}
 		return resp, nil
 	}
+	var result openai.ChatCompletion
+	var err error
+	if a.responseCache != nil {
+		result, err = a.cachedResponse(ctx, resp, startTime)
+	} else {
+		result, err = a.parseResponse(ctx, resp, startTime)
+	}
+	if err == nil {
+		result = a.suppressEchoes(ctx, result)
+		result, err = a.applyJSONMode(ctx, result)
+	}
+	if err == nil && a.legacyFunctionCall {
+		result = a.legacyFunctionCallCompletion(result)
+	}
+	if err == nil && a.strictOpenAI {
+		result = strictCompletion(result)
+	}
+	return result, err
+}
+
+// parseResponse parses the choices of a response for tool calls and rewrites the
+// choices that contain them.
+func (a *Adapter) parseResponse(ctx context.Context, resp openai.ChatCompletion, startTime time.Time) (openai.ChatCompletion, error) {
+	upstream := resp.Choices
+	resp = a.stripThinkBlocks(a.stripStopTokensFromResponse(resp))
+	sources := toolCallSourcesFrom(ctx)
+	report := parseReportFrom(ctx)
 
 	// Track whether we've modified anything to avoid unnecessary copying
 	var modifiedResp openai.ChatCompletion
@@ -390,9 +964,15 @@ func (a *Adapter) TransformCompletionsResponseWithContext(ctx context.Context, r
 	// Process each choice independently
 	for choiceIndex := range resp.Choices {
 		choice := &resp.Choices[choiceIndex]
+		if report != nil {
+			report.choice = choiceIndex
+		}
 
 		// Process the choice for tool calls
-		calls, _, _, shouldContinue := a.processChoiceForToolCalls(ctx, choice, choiceIndex, startTime)
+		calls, _, _, shouldContinue, err := a.processChoiceForToolCalls(ctx, choice, choiceIndex, startTime)
+		if err != nil {
+			return openai.ChatCompletion{}, err
+		}
 		if !shouldContinue {
 			// Check if context was cancelled
 			select {
@@ -410,11 +990,23 @@ func (a *Adapter) TransformCompletionsResponseWithContext(ctx context.Context, r
 		// Apply tool policy to this specific choice
 		transformedChoice, err := a.applyToolPolicyToChoice(*choice, calls, choiceIndex)
 		if err != nil {
-			a.logger.Error("Failed to apply tool policy to choice",
+			a.log(LogCategoryParse).Error("Failed to apply tool policy to choice",
 				"choice_index", choiceIndex,
 				"error", err)
 			continue
 		}
+		emitted := len(transformedChoice.Message.ToolCalls)
+		transformedChoice = a.limitChoiceToolCalls(ctx, transformedChoice)
+		if emitted < len(calls) {
+			recordDroppedCalls(ctx, calls[emitted:], DropReasonToolPolicy)
+		}
+		if limited := len(transformedChoice.Message.ToolCalls); limited < emitted {
+			recordDroppedCalls(ctx, calls[limited:emitted], DropReasonRateLimit)
+		}
+		if sources != nil {
+			recordToolCallSources(sources, upstream[choiceIndex].Message.Content, a.detectableContent(choice.Message.Content),
+				choiceIndex, transformedChoice.Message.ToolCalls, calls)
+		}
 
 		// Only create a copy of the response if this is the first modification.
 		// This lazy allocation avoids copying when no tool calls are found.
@@ -439,12 +1031,12 @@ func (a *Adapter) TransformCompletionsResponseWithContext(ctx context.Context, r
 
 	// If we never copied (no tool calls found), return the original response
 	if !choicesCopied {
-		a.logger.Debug("No tool calls found in any choice, returning original response",
+		a.log(LogCategoryParse).Debug("No tool calls found in any choice, returning original response",
 			"total_choices", len(resp.Choices))
 		return resp, nil
 	}
 
-	a.logger.Debug("Completed multi-choice transformation",
+	a.log(LogCategoryParse).Debug("Completed multi-choice transformation",
 		"total_choices", len(resp.Choices),
 		"choices_with_tools", choicesWithTools,
 		"total_tool_calls", totalToolCallsAcrossChoices,
@@ -453,6 +1045,27 @@ func (a *Adapter) TransformCompletionsResponseWithContext(ctx context.Context, r
 	return modifiedResp, nil
 }
 
+// limitChoiceToolCalls removes the tool calls of a transformed choice that exceed
+// WithToolCallRateLimit. A choice left without tool calls finishes with "stop".
+func (a *Adapter) limitChoiceToolCalls(ctx context.Context, choice openai.ChatCompletionChoice) openai.ChatCompletionChoice {
+	toolCalls := choice.Message.ToolCalls
+	names := make([]string, len(toolCalls))
+	for i, toolCall := range toolCalls {
+		names[i] = toolCall.Function.Name
+	}
+	allowed := a.allowToolCalls(ctx, names, 0, false)
+	if allowed == len(toolCalls) {
+		return choice
+	}
+	if allowed == 0 {
+		choice.Message.ToolCalls = nil
+		choice.FinishReason = "stop"
+		return choice
+	}
+	choice.Message.ToolCalls = toolCalls[:allowed:allowed]
+	return choice
+}
+
 // applyToolPolicyToChoice applies the configured tool policy to a single choice
 // from the response. This allows each choice to be transformed independently
 // according to the policy.
@@ -468,7 +1081,7 @@ func (a *Adapter) applyToolPolicyToChoice(
 
 	// Apply policy-specific transformations
 	switch a.toolPolicy {
-	case ToolAllowMixed:
+	case ToolAllowMixed, ToolEmitAndContinue:
 		// In mixed mode, return both content and tool calls
 		return a.buildMixedChoice(choice, calls, choiceIndex)
 
@@ -486,8 +1099,8 @@ func (a *Adapter) applyToolPolicyToChoice(
 
 	default:
 		// Fallback to ToolStopOnFirst for unknown policies
-		a.logger.Warn("Unknown tool policy, falling back to ToolStopOnFirst",
-			"policy", a.toolPolicy,
+		a.log(LogCategoryPolicy).Warn("Unknown tool policy, falling back to ToolStopOnFirst",
+			"policy", a.toolPolicy.String(),
 			"choice_index", choiceIndex)
 		return a.buildStopOnFirstChoice(choice, calls, choiceIndex)
 	}
@@ -499,10 +1112,11 @@ func (a *Adapter) buildMixedChoice(choice openai.ChatCompletionChoice, calls []f
 	maxCalls := len(calls)
 	if a.toolMaxCalls > 0 && a.toolMaxCalls < maxCalls {
 		maxCalls = a.toolMaxCalls
-		a.logger.Debug("Applied tool call limit in mixed mode",
+		a.log(LogCategoryLimit).Debug("Applied tool call limit in mixed mode",
 			"choice_index", choiceIndex,
 			"original_calls", len(calls),
 			"limited_to", maxCalls)
+		a.emitPolicyLimitHit(PolicyLimitToolMaxCalls, len(calls), maxCalls, false)
 	}
 
 	// Create tool calls while preserving original content
@@ -514,7 +1128,7 @@ func (a *Adapter) buildMixedChoice(choice openai.ChatCompletionChoice, calls []f
 		}
 
 		toolCalls[i] = openai.ChatCompletionMessageToolCallUnion{
-			ID:   a.GenerateToolCallID(),
+			ID:   a.toolCallID(call.ID),
 			Type: functionType,
 			Function: openai.ChatCompletionMessageFunctionToolCallFunction{
 				Name:      call.Name,
@@ -534,7 +1148,7 @@ func (a *Adapter) buildMixedChoice(choice openai.ChatCompletionChoice, calls []f
 		modifiedChoice.FinishReason = "tool_calls"
 	}
 
-	a.logger.Debug("Built mixed choice with content and tool calls",
+	a.log(LogCategoryParse).Debug("Built mixed choice with content and tool calls",
 		"choice_index", choiceIndex,
 		"content_preserved", true,
 		"collected_calls", len(toolCalls),
@@ -554,7 +1168,7 @@ func (a *Adapter) buildStopOnFirstChoice(choice openai.ChatCompletionChoice, cal
 
 	toolCalls := []openai.ChatCompletionMessageToolCallUnion{
 		{
-			ID:   a.GenerateToolCallID(),
+			ID:   a.toolCallID(firstCall.ID),
 			Type: functionType,
 			Function: openai.ChatCompletionMessageFunctionToolCallFunction{
 				Name:      firstCall.Name,
@@ -569,7 +1183,7 @@ func (a *Adapter) buildStopOnFirstChoice(choice openai.ChatCompletionChoice, cal
 	modifiedChoice.Message.ToolCalls = toolCalls
 	modifiedChoice.FinishReason = "tool_calls"
 
-	a.logger.Debug("Built stop-on-first choice",
+	a.log(LogCategoryParse).Debug("Built stop-on-first choice",
 		"choice_index", choiceIndex,
 		"content_cleared", true,
 		"first_tool_call", firstCall.Name,
@@ -584,10 +1198,11 @@ func (a *Adapter) buildCollectThenStopChoice(choice openai.ChatCompletionChoice,
 	maxCalls := len(calls)
 	if a.toolMaxCalls > 0 && a.toolMaxCalls < maxCalls {
 		maxCalls = a.toolMaxCalls
-		a.logger.Debug("Applied tool call limit in collect-then-stop mode",
+		a.log(LogCategoryLimit).Debug("Applied tool call limit in collect-then-stop mode",
 			"choice_index", choiceIndex,
 			"original_calls", len(calls),
 			"limited_to", maxCalls)
+		a.emitPolicyLimitHit(PolicyLimitToolMaxCalls, len(calls), maxCalls, false)
 	}
 
 	// Create tool calls up to the limit
@@ -599,7 +1214,7 @@ func (a *Adapter) buildCollectThenStopChoice(choice openai.ChatCompletionChoice,
 		}
 
 		toolCalls[i] = openai.ChatCompletionMessageToolCallUnion{
-			ID:   a.GenerateToolCallID(),
+			ID:   a.toolCallID(call.ID),
 			Type: functionType,
 			Function: openai.ChatCompletionMessageFunctionToolCallFunction{
 				Name:      call.Name,
@@ -614,7 +1229,7 @@ func (a *Adapter) buildCollectThenStopChoice(choice openai.ChatCompletionChoice,
 	modifiedChoice.Message.ToolCalls = toolCalls
 	modifiedChoice.FinishReason = "tool_calls"
 
-	a.logger.Debug("Built collect-then-stop choice",
+	a.log(LogCategoryParse).Debug("Built collect-then-stop choice",
 		"choice_index", choiceIndex,
 		"content_cleared", true,
 		"collected_calls", len(toolCalls),
@@ -629,10 +1244,11 @@ func (a *Adapter) buildDrainAllChoice(choice openai.ChatCompletionChoice, calls
 	maxCalls := len(calls)
 	if a.toolMaxCalls > 0 && a.toolMaxCalls < maxCalls {
 		maxCalls = a.toolMaxCalls
-		a.logger.Debug("Applied tool call limit in drain-all mode",
+		a.log(LogCategoryLimit).Debug("Applied tool call limit in drain-all mode",
 			"choice_index", choiceIndex,
 			"original_calls", len(calls),
 			"limited_to", maxCalls)
+		a.emitPolicyLimitHit(PolicyLimitToolMaxCalls, len(calls), maxCalls, false)
 	}
 
 	// Create tool calls for all detected calls
@@ -644,7 +1260,7 @@ func (a *Adapter) buildDrainAllChoice(choice openai.ChatCompletionChoice, calls
 		}
 
 		toolCalls[i] = openai.ChatCompletionMessageToolCallUnion{
-			ID:   a.GenerateToolCallID(),
+			ID:   a.toolCallID(call.ID),
 			Type: functionType,
 			Function: openai.ChatCompletionMessageFunctionToolCallFunction{
 				Name:      call.Name,
@@ -659,7 +1275,7 @@ func (a *Adapter) buildDrainAllChoice(choice openai.ChatCompletionChoice, calls
 	modifiedChoice.Message.ToolCalls = toolCalls
 	modifiedChoice.FinishReason = "tool_calls"
 
-	a.logger.Debug("Built drain-all choice",
+	a.log(LogCategoryParse).Debug("Built drain-all choice",
 		"choice_index", choiceIndex,
 		"content_cleared", true,
 		"drained_calls", len(toolCalls))
@@ -692,12 +1308,38 @@ func (a *Adapter) buildToolPromptWithContext(ctx context.Context, tools []openai
 		a.putBufferToPool(buf)
 	}()
 
+	if err := a.writeToolDefinitions(ctx, buf, tools); err != nil {
+		return "", err
+	}
+
+	// Format the complete prompt using our template
+	prompt := fmt.Sprintf(a.promptTemplate, buf.String())
+	if examples := a.buildExamplesPrompt(a.promptExamples(tools)); examples != "" {
+		prompt += "\n\n" + examples
+	}
+
+	duration := time.Since(startTime)
+	a.log(LogCategoryRequest).Debug("Built tool prompt",
+		"tool_count", len(tools),
+		"prompt_length", len(prompt),
+		"build_duration", duration)
+
+	return prompt, nil
+}
+
+// writeToolDefinitions writes the tool list that fills the %s placeholder of the
+// prompt template: human-readable lines, or a JSON array with PromptStyleSpec.
+func (a *Adapter) writeToolDefinitions(ctx context.Context, buf *bytes.Buffer, tools []openai.ChatCompletionToolUnionParam) error {
+	if a.promptStyle == PromptStyleSpec {
+		return a.writeToolSpec(ctx, buf, tools)
+	}
+
 	// Build human-readable tool descriptions
 	for i, tool := range tools {
 		// Check for cancellation in tool processing loop
 		select {
 		case <-ctx.Done():
-			return "", ctx.Err()
+			return ctx.Err()
 		default:
 		}
 
@@ -715,11 +1357,10 @@ func (a *Adapter) buildToolPromptWithContext(ctx context.Context, tools []openai
 			fmt.Fprintf(buf, ": %s", desc)
 		}
 
-		// Include parameter schema if available - use compact JSON (no indentation)
+		// Include parameter schema if available, rendered as WithSchemaFormat selects
 		if function.Parameters != nil {
-			paramsJSON, err := json.Marshal(function.Parameters) // Compact JSON, no indent
-			if err == nil {
-				fmt.Fprintf(buf, "\n  Parameters: %s", string(paramsJSON))
+			if schema, ok := a.renderSchema(function.Parameters); ok {
+				fmt.Fprintf(buf, "\n  Parameters: %s", schema)
 			}
 		}
 
@@ -735,17 +1376,7 @@ func (a *Adapter) buildToolPromptWithContext(ctx context.Context, tools []openai
 			buf.WriteString("\n")
 		}
 	}
-
-	// Format the complete prompt using our template
-	prompt := fmt.Sprintf(a.promptTemplate, buf.String())
-
-	duration := time.Since(startTime)
-	a.logger.Debug("Built tool prompt",
-		"tool_count", len(tools),
-		"prompt_length", len(prompt),
-		"build_duration", duration)
-
-	return prompt, nil
+	return nil
 }
 
 // applyToolPrompt injects tool instructions while preserving existing message parts
@@ -763,37 +1394,62 @@ func (a *Adapter) buildToolPromptWithContext(ctx context.Context, tools []openai
 //     templates that expect a leading system with strict role alternation.
 //  3. Else (no system and no user present): INSERT a new instruction message. Prefer
 //     SYSTEM for generic compatibility; prefer USER for models without system support.
-func (a *Adapter) applyToolPrompt(req openai.ChatCompletionNewParams, toolPrompt string) openai.ChatCompletionNewParams {
+func (a *Adapter) applyToolPrompt(req openai.ChatCompletionNewParams, toolPrompt string, origins *messageOrigins) openai.ChatCompletionNewParams {
 	modifiedReq := req
 
-	// Remove tool-related fields since the target model doesn't support them
-	modifiedReq.Tools = nil
-	// Clear tool choice - use zero value of the union type
-	modifiedReq.ToolChoice = openai.ChatCompletionToolChoiceOptionUnionParam{}
+	// Remove tool-related fields since the target model doesn't support them, unless
+	// the caller keeps them for a backend or proxy that decides later
+	if !a.preserveToolsField {
+		modifiedReq.Tools = nil
+		// Clear tool choice - use zero value of the union type
+		modifiedReq.ToolChoice = openai.ChatCompletionToolChoiceOptionUnionParam{}
+		// Providers reject parallel_tool_calls without tools; the prompt carries it instead
+		modifiedReq.ParallelToolCalls = param.Opt[bool]{}
+	}
 
 	// Handle empty messages case first
 	if len(modifiedReq.Messages) == 0 {
 		// No messages: create instruction message based on system support configuration
-		if a.systemMessagesSupported {
+		if a.developerMessagesSupported {
+			modifiedReq.Messages = []openai.ChatCompletionMessageParamUnion{
+				openai.DeveloperMessage(toolPrompt),
+			}
+			a.log(LogCategoryRequest).Debug("Created new developer message with tool prompt",
+				"developer_prompt_length", len(toolPrompt))
+		} else if a.systemMessagesSupported {
 			modifiedReq.Messages = []openai.ChatCompletionMessageParamUnion{
 				openai.SystemMessage(toolPrompt),
 			}
-			a.logger.Debug("Created new system message with tool prompt",
+			a.log(LogCategoryRequest).Debug("Created new system message with tool prompt",
 				"system_prompt_length", len(toolPrompt))
 		} else {
 			modifiedReq.Messages = []openai.ChatCompletionMessageParamUnion{
 				openai.UserMessage(toolPrompt),
 			}
-			a.logger.Debug("Created new user instruction with tool prompt",
+			a.log(LogCategoryRequest).Debug("Created new user instruction with tool prompt",
 				"instruction_length", len(toolPrompt))
 		}
+		origins.insert(0)
+		origins.promptAt(0)
 		return modifiedReq
 	}
 
-	// Find LAST system message or first user message to anchor insertion point
+	// Place the prompt where WithInjectionPosition asks, if the conversation allows
+	if a.injectionPosition != PositionAuto {
+		if injected, ok := a.injectAtPosition(modifiedReq.Messages, toolPrompt, origins); ok {
+			modifiedReq.Messages = injected
+			return modifiedReq
+		}
+	}
+
+	// Find LAST developer and system messages or first user message to anchor insertion point
+	lastDeveloperIndex := -1
 	lastSystemIndex := -1
 	firstUserIndex := -1
 	for i, m := range modifiedReq.Messages {
+		if m.OfDeveloper != nil {
+			lastDeveloperIndex = i
+		}
 		if m.OfSystem != nil {
 			lastSystemIndex = i // Keep updating to find the LAST one
 		}
@@ -807,22 +1463,49 @@ func (a *Adapter) applyToolPrompt(req openai.ChatCompletionNewParams, toolPrompt
 	copy(newMessages, modifiedReq.Messages)
 
 	// Preferred strategy:
+	// - With developer support and a developer message: append to the LAST developer message
 	// - If a system message exists: append tool instructions to the LAST system message (safe, text-only)
+	// - With developer support: PREPEND a DEVELOPER instruction at the start
 	// - Else decide based on model capabilities (heuristic):
 	//   * If model lacks system support (e.g., Gemma 3): INSERT a USER instruction BEFORE the first user
 	//     (or at start if no user found) to preserve multimodal content and avoid system role.
 	//   * Otherwise: PREPEND a SYSTEM instruction at the start to satisfy templates.
-	if lastSystemIndex != -1 {
+	if a.developerMessagesSupported && lastDeveloperIndex != -1 {
+		// Developer message exists: append tool prompt to the LAST one, keeping its name
+		originalContent := extractSystemContent(newMessages[lastDeveloperIndex])
+		combinedContent := originalContent + "\n\n" + toolPrompt
+		developerMsg := *newMessages[lastDeveloperIndex].OfDeveloper
+		developerMsg.Content = openai.ChatCompletionDeveloperMessageParamContentUnion{OfString: openai.String(combinedContent)}
+		newMessages[lastDeveloperIndex] = openai.ChatCompletionMessageParamUnion{OfDeveloper: &developerMsg}
+		origins.modify(lastDeveloperIndex)
+		origins.promptAt(lastDeveloperIndex)
+
+		a.log(LogCategoryRequest).Debug("Appended tool prompt to last developer message",
+			"developer_index", lastDeveloperIndex,
+			"original_length", len(originalContent),
+			"tool_prompt_length", len(toolPrompt),
+			"combined_length", len(combinedContent))
+	} else if lastSystemIndex != -1 {
 		// System message exists: append tool prompt to the LAST one (keeps count unchanged)
 		originalContent := extractSystemContent(newMessages[lastSystemIndex])
 		combinedContent := originalContent + "\n\n" + toolPrompt
 		newMessages[lastSystemIndex] = openai.SystemMessage(combinedContent)
+		origins.modify(lastSystemIndex)
+		origins.promptAt(lastSystemIndex)
 
-		a.logger.Debug("Appended tool prompt to last system message",
+		a.log(LogCategoryRequest).Debug("Appended tool prompt to last system message",
 			"system_index", lastSystemIndex,
 			"original_length", len(originalContent),
 			"tool_prompt_length", len(toolPrompt),
 			"combined_length", len(combinedContent))
+	} else if a.developerMessagesSupported {
+		// No developer or system message: prepend a DEVELOPER instruction
+		newMessages = append([]openai.ChatCompletionMessageParamUnion{openai.DeveloperMessage(toolPrompt)}, newMessages...)
+		origins.insert(0)
+		origins.promptAt(0)
+		a.log(LogCategoryRequest).Debug("Prepended developer instruction (configured developer support)",
+			"tool_prompt_length", len(toolPrompt),
+			"new_message_count", len(newMessages))
 	} else if firstUserIndex != -1 {
 		// No system message present.
 		if !a.systemMessagesSupported {
@@ -831,14 +1514,18 @@ func (a *Adapter) applyToolPrompt(req openai.ChatCompletionNewParams, toolPrompt
 			// Providers like vLLM w/ Gemma 3 will error out if you try to use
 			// two user messages consecutively.
 			newMessages[firstUserIndex] = prependToolPromptToUserMessage(newMessages[firstUserIndex], toolPrompt)
+			origins.modify(firstUserIndex)
+			origins.promptAt(firstUserIndex)
 
-			a.logger.Debug("Prepended tool prompt to first user message",
+			a.log(LogCategoryRequest).Debug("Prepended tool prompt to first user message",
 				"user_index", firstUserIndex,
 				"tool_prompt_length", len(toolPrompt))
 		} else {
 			// Prepend a SYSTEM instruction to satisfy templates that expect it
 			newMessages = append([]openai.ChatCompletionMessageParamUnion{openai.SystemMessage(toolPrompt)}, newMessages...)
-			a.logger.Debug("Prepended system instruction (configured RoleSystem)",
+			origins.insert(0)
+			origins.promptAt(0)
+			a.log(LogCategoryRequest).Debug("Prepended system instruction (configured RoleSystem)",
 				"tool_prompt_length", len(toolPrompt),
 				"new_message_count", len(newMessages))
 		}
@@ -846,12 +1533,16 @@ func (a *Adapter) applyToolPrompt(req openai.ChatCompletionNewParams, toolPrompt
 		// No system or user messages (only assistant or empty). Use configured default.
 		if !a.systemMessagesSupported {
 			newMessages = append([]openai.ChatCompletionMessageParamUnion{openai.UserMessage(toolPrompt)}, newMessages...)
-			a.logger.Debug("Prepended new user instruction (no system/user messages found, configured RoleUser)",
+			origins.insert(0)
+			origins.promptAt(0)
+			a.log(LogCategoryRequest).Debug("Prepended new user instruction (no system/user messages found, configured RoleUser)",
 				"original_message_count", len(modifiedReq.Messages),
 				"new_message_count", len(newMessages))
 		} else {
 			newMessages = append([]openai.ChatCompletionMessageParamUnion{openai.SystemMessage(toolPrompt)}, newMessages...)
-			a.logger.Debug("Prepended new system message (no system/user messages found, configured RoleSystem)",
+			origins.insert(0)
+			origins.promptAt(0)
+			a.log(LogCategoryRequest).Debug("Prepended new system message (no system/user messages found, configured RoleSystem)",
 				"original_message_count", len(modifiedReq.Messages),
 				"new_message_count", len(newMessages))
 		}
@@ -862,20 +1553,26 @@ func (a *Adapter) applyToolPrompt(req openai.ChatCompletionNewParams, toolPrompt
 }
 
 // prependToolPromptToUserMessage creates a new user message with tool prompt prepended
-// while preserving any existing multimodal content (images, etc.)
+// while preserving any existing multimodal content. All text parts are merged into a
+// single leading text part; every other part (image_url, input_audio, file) is kept
+// in its original relative order. Other message fields, such as Name, are preserved.
 func prependToolPromptToUserMessage(msg openai.ChatCompletionMessageParamUnion, toolPrompt string) openai.ChatCompletionMessageParamUnion {
 	if msg.OfUser == nil {
 		// Not a user message, return as-is
 		return msg
 	}
 
-	content := msg.OfUser.Content
+	// Copy the message so the caller's request is never modified
+	userMsg := *msg.OfUser
+	content := userMsg.Content
 
 	// Handle simple text content
 	if str := content.OfString.Or(""); str != "" {
 		// Simple text message - combine with tool prompt
-		combinedContent := toolPrompt + "\n\n" + str
-		return openai.UserMessage(combinedContent)
+		userMsg.Content = openai.ChatCompletionUserMessageParamContentUnion{
+			OfString: openai.String(toolPrompt + "\n\n" + str),
+		}
+		return openai.ChatCompletionMessageParamUnion{OfUser: &userMsg}
 	}
 
 	// Handle multimodal content (array of parts)
@@ -895,7 +1592,7 @@ func prependToolPromptToUserMessage(msg openai.ChatCompletionMessageParamUnion,
 				}
 				existingText.WriteString(textPart.Text)
 			} else {
-				// Preserve non-text parts (images, etc.)
+				// Preserve non-text parts (images, audio, files) in order
 				nonTextParts = append(nonTextParts, part)
 			}
 		}
@@ -914,19 +1611,32 @@ func prependToolPromptToUserMessage(msg openai.ChatCompletionMessageParamUnion,
 			},
 		})
 
-		// Add all non-text parts (images, etc.)
+		// Add all non-text parts (images, audio, files)
 		newParts = append(newParts, nonTextParts...)
 
 		// Create new user message with multimodal content
-		return openai.UserMessage(newParts)
+		userMsg.Content = openai.ChatCompletionUserMessageParamContentUnion{OfArrayOfContentParts: newParts}
+		return openai.ChatCompletionMessageParamUnion{OfUser: &userMsg}
 	}
 
 	// Fallback: empty content, just add tool prompt
-	return openai.UserMessage(toolPrompt)
+	userMsg.Content = openai.ChatCompletionUserMessageParamContentUnion{OfString: openai.String(toolPrompt)}
+	return openai.ChatCompletionMessageParamUnion{OfUser: &userMsg}
 }
 
-// extractSystemContent extracts content from a system message
+// extractSystemContent extracts content from a system or developer message
 func extractSystemContent(msg openai.ChatCompletionMessageParamUnion) string {
+	if msg.OfDeveloper != nil {
+		content := msg.OfDeveloper.Content
+		if str := content.OfString.Or(""); str != "" {
+			return str
+		}
+		var result strings.Builder
+		for _, part := range content.OfArrayOfContentParts {
+			result.WriteString(part.Text)
+		}
+		return result.String()
+	}
 	if msg.OfSystem != nil {
 		// System messages have a ContentUnion with OfString or OfArrayOfContentParts
 		content := msg.OfSystem.Content
@@ -953,13 +1663,18 @@ func extractSystemContent(msg openai.ChatCompletionMessageParamUnion) string {
 // toolResult represents a parsed tool execution result
 type toolResult struct {
 	CallID  string
+	Name    string // function name, when known from a Session
 	Content string
+
+	// Attachments are the non-text parts of a multimodal result, referenced from
+	// Content by numbered placeholders
+	Attachments []openai.ChatCompletionContentPartUnionParam
 }
 
 // extractToolResults extracts ToolMessage types from messages and returns them along with cleaned messages.
 // This implementation uses the OpenAI SDK's union type fields directly instead of JSON marshaling
 // for efficient message type detection and content extraction.
-func (a *Adapter) extractToolResults(messages []openai.ChatCompletionMessageParamUnion) ([]toolResult, []openai.ChatCompletionMessageParamUnion, error) {
+func (a *Adapter) extractToolResults(messages []openai.ChatCompletionMessageParamUnion, origins *messageOrigins) ([]toolResult, []openai.ChatCompletionMessageParamUnion, error) {
 	var results []toolResult
 	var cleanMessages []openai.ChatCompletionMessageParamUnion
 
@@ -991,7 +1706,8 @@ func (a *Adapter) extractToolResults(messages []openai.ChatCompletionMessagePara
 				Content: content,
 			})
 
-			a.logger.Debug("Extracted tool result", "tool_call_id", callID, "content_length", len(content))
+			origins.remove(len(cleanMessages))
+			a.log(LogCategoryRequest).Debug("Extracted tool result", "tool_call_id", callID, "content_length", len(content))
 		} else {
 			// Not a tool message, keep it in clean messages
 			cleanMessages = append(cleanMessages, msg)
@@ -1007,14 +1723,18 @@ func (a *Adapter) buildToolResultsPrompt(results []toolResult) string {
 		return ""
 	}
 
+	language := a.language()
+
 	var promptBuilder strings.Builder
-	promptBuilder.WriteString("Previous tool calls requested by you returned the following results. They likely need formatting into a natural language response for the user:\n\n")
+	promptBuilder.WriteString(language.toolResultsIntro)
 
 	for i, result := range results {
-		if result.CallID != "" {
-			promptBuilder.WriteString(fmt.Sprintf("Tool call %s result:\n", result.CallID))
+		if result.CallID != "" && result.Name != "" {
+			promptBuilder.WriteString(fmt.Sprintf(language.namedToolCallResult, result.CallID, result.Name))
+		} else if result.CallID != "" {
+			promptBuilder.WriteString(fmt.Sprintf(language.toolCallResult, result.CallID))
 		} else {
-			promptBuilder.WriteString(fmt.Sprintf("Tool result %d:\n", i+1))
+			promptBuilder.WriteString(fmt.Sprintf(language.toolResult, i+1))
 		}
 		promptBuilder.WriteString(result.Content)
 		promptBuilder.WriteString("\n\n")
@@ -1048,7 +1768,20 @@ func (a *Adapter) emitMetric(data MetricEventData) {
 		}
 	}()
 
-	a.metricsCallback(data)
+	a.metricsCallback(a.redactMetric(data))
+}
+
+// phaseMetricsEnabled reports whether the events enabled by WithPhaseMetrics reach a
+// metrics callback.
+func (a *Adapter) phaseMetricsEnabled() bool {
+	return a.phaseMetrics && a.metricsCallback != nil
+}
+
+// emitPhaseMetric emits one of the events enabled by WithPhaseMetrics.
+func (a *Adapter) emitPhaseMetric(data MetricEventData) {
+	if a.phaseMetrics {
+		a.emitMetric(data)
+	}
 }
 
 // GenerateToolCallID generates a unique ID for a tool call using UUIDv7.
@@ -1082,3 +1815,62 @@ func (a *Adapter) GenerateToolCallID() string {
 	}
 	return "call_" + id.String()
 }
+
+// toolCallID returns the ID supplied by the model when present, otherwise a newly
+// generated one. Preserving model-supplied IDs keeps them consistent with any text
+// the model may reference them from.
+func (a *Adapter) toolCallID(provided string) string {
+	if provided != "" {
+		return provided
+	}
+	return a.GenerateToolCallID()
+}
+
+// usesNativeTools reports whether WithNativePassthrough marks model as supporting
+// function calling natively.
+func (a *Adapter) usesNativeTools(model string) bool {
+	return a.nativePassthrough != nil && a.nativePassthrough(model)
+}
+
+// exceedsResponseParseLimit reports whether non-streaming content of contentLength
+// bytes is above WithResponseParseMaxBytes.
+func (a *Adapter) exceedsResponseParseLimit(contentLength int) bool {
+	return a.responseParseMaxBytes > 0 && contentLength > a.responseParseMaxBytes
+}
+
+// skipsResponseParse reports whether a choice's content is returned without tool
+// call parsing because of WithResponseParseMaxBytes, reporting the skip when it is.
+func (a *Adapter) skipsResponseParse(contentLength, choiceIndex int) bool {
+	if !a.exceedsResponseParseLimit(contentLength) {
+		return false
+	}
+	a.log(LogCategoryLimit).Warn("Response content exceeds parse limit, returning it without tool call parsing",
+		"choice_index", choiceIndex,
+		"content_length", contentLength,
+		"limit", a.responseParseMaxBytes)
+	a.emitParseEvent(ParseEvent{
+		Type:   ParseEventLimitExceeded,
+		Size:   contentLength,
+		Limit:  a.responseParseMaxBytes,
+		Detail: ParseDetailResponseParseMaxBytes,
+		Err:    ErrBufferLimitExceeded,
+	})
+	a.emitMetric(ResponseParseSkippedData{
+		ChoiceIndex:   choiceIndex,
+		ContentLength: contentLength,
+		MaxBytes:      a.responseParseMaxBytes,
+	})
+	a.emitPolicyLimitHit(PolicyLimitResponseParseMaxBytes, contentLength, a.responseParseMaxBytes, false)
+	return true
+}
+
+// emitPolicyLimitHit emits a PolicyLimitHitData metric for a limit that cut tool
+// call detection or the calls delivered short.
+func (a *Adapter) emitPolicyLimitHit(limit string, value, maxValue int, streaming bool) {
+	a.emitPhaseMetric(PolicyLimitHitData{
+		Limit:     limit,
+		Value:     value,
+		Max:       maxValue,
+		Streaming: streaming,
+	})
+}
@@ -106,6 +106,35 @@ func BenchmarkTransformCompletionsResponse_EndToEnd(b *testing.B) {
 	}
 }
 
+// BenchmarkTransformCompletionsResponse tracks the allocation profile of the response
+// hot path. Plain-text responses take the no-tool-call fast path and must stay below
+// one allocation per operation; see TestTransformCompletionsResponse_NoToolCallAllocs.
+func BenchmarkTransformCompletionsResponse(b *testing.B) {
+	adapter := New(WithLogLevel(slog.LevelError))
+
+	testCases := []struct {
+		name    string
+		content string
+	}{
+		{"NoToolCalls", "Just a simple text response with no tool calls."},
+		{"NoToolCalls_Large", strings.Repeat("The quick brown fox jumps over the lazy dog. ", 1000)},
+		{"JSONWithoutToolCall", `Here is the data: {"temperature": 21, "unit": "celsius"}`},
+		{"SingleToolCall", `[{"name": "get_weather", "parameters": {"location": "Boston", "unit": "celsius"}}]`},
+		{"MixedContent", createMixedContentResponse()},
+	}
+
+	for _, tc := range testCases {
+		response := createBenchmarkResponse(tc.content)
+		b.Run(tc.name, func(b *testing.B) {
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				benchResponseResult, benchError = adapter.TransformCompletionsResponse(response)
+			}
+		})
+	}
+}
+
 // BenchmarkFullWorkflow_EndToEnd benchmarks a complete request -> response cycle
 func BenchmarkFullWorkflow_EndToEnd(b *testing.B) {
 	adapter := New(WithLogLevel(slog.LevelError))
@@ -619,3 +648,22 @@ func BenchmarkMultiChoiceWithPolicies(b *testing.B) {
 		})
 	}
 }
+
+// TestTransformCompletionsResponse_NoToolCallAllocs guards the allocation target of the
+// no-tool-call fast path measured by BenchmarkTransformCompletionsResponse.
+func TestTransformCompletionsResponse_NoToolCallAllocs(t *testing.T) {
+	adapter := New(WithLogLevel(slog.LevelError))
+
+	for _, content := range []string{
+		"Just a simple text response with no tool calls.",
+		strings.Repeat("The quick brown fox jumps over the lazy dog. ", 1000),
+	} {
+		response := createBenchmarkResponse(content)
+		allocs := testing.AllocsPerRun(100, func() {
+			benchResponseResult, benchError = adapter.TransformCompletionsResponse(response)
+		})
+		if allocs >= 1 {
+			t.Errorf("expected < 1 alloc/op for %d bytes of plain text, got %.1f", len(content), allocs)
+		}
+	}
+}
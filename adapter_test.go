@@ -2904,3 +2904,203 @@ func TestMultimodalMessageHandling(t *testing.T) {
 		assert.Equal(t, "data:image/jpeg;base64,/9j/test...", imagePart.ImageURL.URL)
 	})
 }
+
+// TestLegacyFunctionCallResponse tests that the deprecated function_call shape is
+// normalized into tool calls in both non-streaming and streaming responses
+func TestLegacyFunctionCallResponse(t *testing.T) {
+	adapter := tooladapter.New()
+	content := `{"function_call": {"name": "get_weather", "arguments": "{\"location\": \"Paris\"}"}}`
+
+	t.Run("NonStreaming", func(t *testing.T) {
+		resp, err := adapter.TransformCompletionsResponse(createMockCompletion(content))
+		require.NoError(t, err)
+
+		toolCalls := resp.Choices[0].Message.ToolCalls
+		require.Len(t, toolCalls, 1)
+		assert.Equal(t, "get_weather", toolCalls[0].Function.Name)
+		assert.JSONEq(t, `{"location": "Paris"}`, toolCalls[0].Function.Arguments)
+		assert.Equal(t, "tool_calls", resp.Choices[0].FinishReason)
+	})
+
+	t.Run("Streaming", func(t *testing.T) {
+		stream := adapter.TransformStreamingResponse(NewMockStream([]openai.ChatCompletionChunk{
+			createStreamChunk(content),
+		}))
+
+		var toolCalls []openai.ChatCompletionChunkChoiceDeltaToolCall
+		for stream.Next() {
+			toolCalls = append(toolCalls, stream.Current().Choices[0].Delta.ToolCalls...)
+		}
+		require.NoError(t, stream.Err())
+		require.Len(t, toolCalls, 1)
+		assert.Equal(t, "get_weather", toolCalls[0].Function.Name)
+		assert.JSONEq(t, `{"location": "Paris"}`, toolCalls[0].Function.Arguments)
+	})
+}
+
+// TestToolCallsWrapperResponse tests that tool_calls wrapper objects are mapped to
+// tool calls and that model-supplied IDs are preserved
+func TestToolCallsWrapperResponse(t *testing.T) {
+	adapter := tooladapter.New(tooladapter.WithToolPolicy(tooladapter.ToolDrainAll))
+	content := `{"tool_calls": [
+		{"id": "call_weather", "type": "function", "function": {"name": "get_weather", "arguments": "{\"location\": \"Paris\"}"}},
+		{"type": "function", "function": {"name": "get_time", "arguments": "{}"}}
+	]}`
+
+	t.Run("NonStreaming", func(t *testing.T) {
+		resp, err := adapter.TransformCompletionsResponse(createMockCompletion(content))
+		require.NoError(t, err)
+
+		toolCalls := resp.Choices[0].Message.ToolCalls
+		require.Len(t, toolCalls, 2)
+		assert.Equal(t, "call_weather", toolCalls[0].ID)
+		assert.Equal(t, "get_weather", toolCalls[0].Function.Name)
+		assert.JSONEq(t, `{"location": "Paris"}`, toolCalls[0].Function.Arguments)
+		assert.True(t, strings.HasPrefix(toolCalls[1].ID, "call_"), "missing IDs should be generated")
+		assert.NotEqual(t, "call_weather", toolCalls[1].ID)
+	})
+
+	t.Run("Streaming", func(t *testing.T) {
+		stream := adapter.TransformStreamingResponse(NewMockStream([]openai.ChatCompletionChunk{
+			createStreamChunk(content),
+		}))
+
+		var toolCalls []openai.ChatCompletionChunkChoiceDeltaToolCall
+		for stream.Next() {
+			toolCalls = append(toolCalls, stream.Current().Choices[0].Delta.ToolCalls...)
+		}
+		require.NoError(t, stream.Err())
+		require.Len(t, toolCalls, 2)
+		assert.Equal(t, "call_weather", toolCalls[0].ID)
+		assert.Equal(t, "get_time", toolCalls[1].Function.Name)
+	})
+}
+
+// TestMultimodalAudioAndFileParts tests that input_audio and file parts survive tool
+// prompt injection in their original relative order
+func TestMultimodalAudioAndFileParts(t *testing.T) {
+	adapter := tooladapter.New()
+	tools := []openai.ChatCompletionToolUnionParam{createMockTool("transcribe", "Transcribe audio")}
+
+	audio := openai.InputAudioContentPart(openai.ChatCompletionContentPartInputAudioInputAudioParam{
+		Data:   "UklGRg==",
+		Format: "wav",
+	})
+	file := openai.FileContentPart(openai.ChatCompletionContentPartFileFileParam{
+		FileID: openai.String("file-abc123"),
+	})
+	image := openai.ImageContentPart(openai.ChatCompletionContentPartImageImageURLParam{
+		URL: "https://example.com/cat.png",
+	})
+
+	// partKind identifies the populated variant of a content part
+	partKind := func(part openai.ChatCompletionContentPartUnionParam) string {
+		switch {
+		case part.OfText != nil:
+			return "text"
+		case part.OfImageURL != nil:
+			return "image_url"
+		case part.OfInputAudio != nil:
+			return "input_audio"
+		case part.OfFile != nil:
+			return "file"
+		default:
+			return "unknown"
+		}
+	}
+
+	testCases := []struct {
+		name          string
+		parts         []openai.ChatCompletionContentPartUnionParam
+		expectedKinds []string
+		expectedText  string
+	}{
+		{
+			name:          "AudioOnly",
+			parts:         []openai.ChatCompletionContentPartUnionParam{audio},
+			expectedKinds: []string{"text", "input_audio"},
+		},
+		{
+			name:          "TextThenAudio",
+			parts:         []openai.ChatCompletionContentPartUnionParam{openai.TextContentPart("What was said?"), audio},
+			expectedKinds: []string{"text", "input_audio"},
+			expectedText:  "What was said?",
+		},
+		{
+			name:          "AudioThenText",
+			parts:         []openai.ChatCompletionContentPartUnionParam{audio, openai.TextContentPart("Summarize this")},
+			expectedKinds: []string{"text", "input_audio"},
+			expectedText:  "Summarize this",
+		},
+		{
+			name: "FileAudioImageInterleavedWithText",
+			parts: []openai.ChatCompletionContentPartUnionParam{
+				file,
+				openai.TextContentPart("Compare"),
+				audio,
+				openai.TextContentPart("with"),
+				image,
+			},
+			expectedKinds: []string{"text", "file", "input_audio", "image_url"},
+			expectedText:  "Compare with",
+		},
+		{
+			name:          "ImageBeforeFile",
+			parts:         []openai.ChatCompletionContentPartUnionParam{image, file},
+			expectedKinds: []string{"text", "image_url", "file"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := createMockRequest(tools)
+			req.Messages = []openai.ChatCompletionMessageParamUnion{openai.UserMessage(tc.parts)}
+
+			result, err := adapter.TransformCompletionsRequest(req)
+			require.NoError(t, err)
+			require.Len(t, result.Messages, 1)
+
+			resultParts := result.Messages[0].OfUser.Content.OfArrayOfContentParts
+			kinds := make([]string, len(resultParts))
+			for i, part := range resultParts {
+				kinds[i] = partKind(part)
+			}
+			assert.Equal(t, tc.expectedKinds, kinds)
+
+			text := resultParts[0].OfText.Text
+			assert.True(t, strings.HasPrefix(text, "System/tooling instructions"))
+			if tc.expectedText != "" {
+				assert.True(t, strings.HasSuffix(text, "\n\n"+tc.expectedText))
+			}
+
+			for _, part := range resultParts {
+				if part.OfInputAudio != nil {
+					assert.Equal(t, "UklGRg==", part.OfInputAudio.InputAudio.Data)
+					assert.Equal(t, "wav", part.OfInputAudio.InputAudio.Format)
+				}
+				if part.OfFile != nil {
+					assert.Equal(t, "file-abc123", part.OfFile.File.FileID.Value)
+				}
+			}
+
+			// The caller's message must not be modified
+			assert.Len(t, req.Messages[0].OfUser.Content.OfArrayOfContentParts, len(tc.parts))
+		})
+	}
+
+	t.Run("PreservesUserName", func(t *testing.T) {
+		msg := openai.UserMessage([]openai.ChatCompletionContentPartUnionParam{audio})
+		msg.OfUser.Name = openai.String("caller")
+		req := createMockRequest(tools)
+		req.Messages = []openai.ChatCompletionMessageParamUnion{msg}
+
+		result, err := adapter.TransformCompletionsRequest(req)
+		require.NoError(t, err)
+		assert.Equal(t, "caller", result.Messages[0].OfUser.Name.Value)
+
+		data, err := json.Marshal(result.Messages[0])
+		require.NoError(t, err)
+		assert.Contains(t, string(data), `"type":"input_audio"`)
+		assert.Contains(t, string(data), `"role":"user"`)
+	})
+}
@@ -0,0 +1,146 @@
+package tooladapter
+
+import (
+	"context"
+	"slices"
+
+	"github.com/openai/openai-go/v3"
+)
+
+// Origin values of MessageAnnotation.
+const (
+	// MessageOriginCaller marks a message passed through from the original request
+	MessageOriginCaller = "caller"
+
+	// MessageOriginModified marks a message of the original request whose content
+	// the adapter changed, for example by injecting the tool prompt into it
+	MessageOriginModified = "modified"
+
+	// MessageOriginInjected marks a message the adapter created, such as a system
+	// message holding the tool prompt, a truncation note or an assistant prefill
+	MessageOriginInjected = "injected"
+)
+
+// MessageAnnotation describes where a message of a transformed request came from.
+type MessageAnnotation struct {
+	// Origin is one of the MessageOrigin constants
+	Origin string `json:"origin"`
+
+	// SourceIndex is the index of the message in the original request's messages,
+	// or -1 for injected messages
+	SourceIndex int `json:"source_index"`
+}
+
+// MessageAnnotations holds one annotation per message of a transformed request, in
+// message order. Attach one to a context with ContextWithMessageAnnotations.
+type MessageAnnotations []MessageAnnotation
+
+// messageAnnotationsKey is the context key for annotations attached by
+// ContextWithMessageAnnotations.
+type messageAnnotationsKey struct{}
+
+// ContextWithMessageAnnotations returns a copy of ctx that makes request
+// transformation record in annotations where each message of the transformed
+// request came from, so logging and analytics layers can tell user-authored content
+// from adapter-injected content:
+//
+//	var annotations tooladapter.MessageAnnotations
+//	ctx = tooladapter.ContextWithMessageAnnotations(ctx, &annotations)
+//	transformed, err := adapter.TransformCompletionsRequestWithContext(ctx, req)
+//	for i, msg := range transformed.Messages {
+//		if annotations[i].Origin == tooladapter.MessageOriginCaller {
+//			logUserContent(msg)
+//		}
+//	}
+//
+// Messages are annotated as the adapter builds the request, relative to the request
+// it receives. Changes WithRequestMiddleware makes to the messages are not
+// annotated: middleware that edits them before calling next shifts SourceIndex to
+// its own request, and messages it edits afterwards keep the adapter's annotation.
+// Tool messages folded into the prompt have no annotation, since they are no longer
+// sent. To find the injected text within modified messages, enable
+// WithInjectionMarkers. Use a new value for each request.
+func ContextWithMessageAnnotations(ctx context.Context, annotations *MessageAnnotations) context.Context {
+	return context.WithValue(ctx, messageAnnotationsKey{}, annotations)
+}
+
+// messageAnnotationsFrom returns the annotations attached to ctx by
+// ContextWithMessageAnnotations.
+func messageAnnotationsFrom(ctx context.Context) *MessageAnnotations {
+	if ctx == nil {
+		return nil
+	}
+	annotations, _ := ctx.Value(messageAnnotationsKey{}).(*MessageAnnotations)
+	return annotations
+}
+
+// messageOrigins tracks the annotation of each message while transformRequest
+// builds a request, along with the message holding the injected prompt. Steps that
+// drop, rewrite or add messages report it at the message's current index. Its
+// methods do nothing on a nil receiver, which newMessageOrigins returns when the
+// context asks for neither annotations nor a report.
+type messageOrigins struct {
+	annotations MessageAnnotations
+	prompt      int
+}
+
+// newMessageOrigins returns a tracker for the caller's messages when ctx carries
+// ContextWithMessageAnnotations annotations or a TransformReport.
+func newMessageOrigins(ctx context.Context, messages []openai.ChatCompletionMessageParamUnion) *messageOrigins {
+	if messageAnnotationsFrom(ctx) == nil && transformReportFrom(ctx) == nil {
+		return nil
+	}
+	o := &messageOrigins{annotations: make(MessageAnnotations, len(messages)), prompt: -1}
+	for i := range messages {
+		o.annotations[i] = MessageAnnotation{Origin: MessageOriginCaller, SourceIndex: i}
+	}
+	return o
+}
+
+// remove drops the annotation of a message left out of the request.
+func (o *messageOrigins) remove(i int) {
+	if o == nil {
+		return
+	}
+	o.annotations = slices.Delete(o.annotations, i, i+1)
+}
+
+// modify marks a caller message whose content the adapter changed.
+func (o *messageOrigins) modify(i int) {
+	if o != nil && o.annotations[i].Origin == MessageOriginCaller {
+		o.annotations[i].Origin = MessageOriginModified
+	}
+}
+
+// insert annotates a message the adapter created at index i.
+func (o *messageOrigins) insert(i int) {
+	if o == nil {
+		return
+	}
+	o.annotations = slices.Insert(o.annotations, i, MessageAnnotation{Origin: MessageOriginInjected, SourceIndex: -1})
+	if o.prompt >= i {
+		o.prompt++
+	}
+}
+
+// promptAt records that the message at index i holds the injected prompt.
+func (o *messageOrigins) promptAt(i int) {
+	if o != nil {
+		o.prompt = i
+	}
+}
+
+// record hands the annotations to the ContextWithMessageAnnotations value and the
+// TransformReport of ctx.
+func (o *messageOrigins) record(ctx context.Context) {
+	if o == nil {
+		return
+	}
+	if annotations := messageAnnotationsFrom(ctx); annotations != nil {
+		*annotations = o.annotations
+	}
+	if report := transformReportFrom(ctx); report != nil {
+		report.Messages = o.annotations
+		report.PromptMessageIndex = o.prompt
+	}
+}
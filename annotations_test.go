@@ -0,0 +1,96 @@
+package tooladapter_test
+
+import (
+	"context"
+	"testing"
+
+	tooladapter "github.com/juburr/openai-tool-adapter/v3"
+	"github.com/juburr/openai-tool-adapter/v3/tooltest"
+	"github.com/openai/openai-go/v3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// transformAnnotated transforms req and returns the request with its annotations.
+func transformAnnotated(t *testing.T, adapter *tooladapter.Adapter, req openai.ChatCompletionNewParams) (openai.ChatCompletionNewParams, tooladapter.MessageAnnotations) {
+	t.Helper()
+	var annotations tooladapter.MessageAnnotations
+	ctx := tooladapter.ContextWithMessageAnnotations(context.Background(), &annotations)
+	transformed, err := adapter.TransformCompletionsRequestWithContext(ctx, req)
+	require.NoError(t, err)
+	require.Len(t, annotations, len(transformed.Messages))
+	return transformed, annotations
+}
+
+func TestMessageAnnotations(t *testing.T) {
+	caller := func(i int) tooladapter.MessageAnnotation {
+		return tooladapter.MessageAnnotation{Origin: tooladapter.MessageOriginCaller, SourceIndex: i}
+	}
+	modified := func(i int) tooladapter.MessageAnnotation {
+		return tooladapter.MessageAnnotation{Origin: tooladapter.MessageOriginModified, SourceIndex: i}
+	}
+	injected := tooladapter.MessageAnnotation{Origin: tooladapter.MessageOriginInjected, SourceIndex: -1}
+	weather := tooltest.Tool("get_weather", "Get the weather")
+
+	t.Run("PromptInUserMessage", func(t *testing.T) {
+		req := tooltest.Request(weather)
+		req.Messages = append(req.Messages, openai.AssistantMessage("Which city?"), openai.UserMessage("Paris"))
+		_, annotations := transformAnnotated(t, tooladapter.New(), req)
+		assert.Equal(t, tooladapter.MessageAnnotations{modified(0), caller(1), caller(2)}, annotations)
+	})
+
+	t.Run("PromptInSystemMessage", func(t *testing.T) {
+		req := tooltest.Request(weather)
+		req.Messages = append([]openai.ChatCompletionMessageParamUnion{openai.SystemMessage("Be brief.")}, req.Messages...)
+		_, annotations := transformAnnotated(t, tooladapter.New(tooladapter.WithSystemMessageSupport(true)), req)
+		assert.Equal(t, tooladapter.MessageAnnotations{modified(0), caller(1)}, annotations)
+	})
+
+	t.Run("InjectedMessages", func(t *testing.T) {
+		adapter := tooladapter.New(
+			tooladapter.WithSystemMessageSupport(true),
+			tooladapter.WithAssistantPrefill(`{"name": "`))
+		_, annotations := transformAnnotated(t, adapter, tooltest.Request(weather))
+		assert.Equal(t, tooladapter.MessageAnnotations{injected, caller(0), injected}, annotations)
+	})
+
+	t.Run("ToolMessagesFolded", func(t *testing.T) {
+		req := tooltest.Request(weather)
+		req.Messages = append(req.Messages,
+			openai.AssistantMessage(""),
+			openai.ToolMessage("Sunny", "call_1"),
+			openai.UserMessage("Thanks"))
+		_, annotations := transformAnnotated(t, tooladapter.New(), req)
+		assert.Equal(t, tooladapter.MessageAnnotations{modified(0), caller(1), caller(3)}, annotations)
+	})
+
+	t.Run("ImageOnlyUserMessage", func(t *testing.T) {
+		image := openai.ImageContentPart(openai.ChatCompletionContentPartImageImageURLParam{URL: "https://example.com/map.png"})
+		req := tooltest.Request(weather)
+		req.Messages = []openai.ChatCompletionMessageParamUnion{
+			openai.UserMessage([]openai.ChatCompletionContentPartUnionParam{image}),
+		}
+		_, annotations := transformAnnotated(t, tooladapter.New(), req)
+		assert.Equal(t, tooladapter.MessageAnnotations{modified(0)}, annotations)
+	})
+
+	t.Run("CompactedPromptReinjected", func(t *testing.T) {
+		adapter := tooladapter.New(
+			tooladapter.WithSystemMessageSupport(true),
+			tooladapter.WithPromptCompaction(true))
+		first, err := adapter.TransformCompletionsRequest(tooltest.Request(weather))
+		require.NoError(t, err)
+		second := tooltest.Request(weather)
+		second.Messages = append(first.Messages, openai.AssistantMessage("It is sunny."), openai.UserMessage("And tomorrow?"))
+
+		_, annotations := transformAnnotated(t, adapter, second)
+		assert.Equal(t, tooladapter.MessageAnnotations{injected, caller(1), caller(2), caller(3)}, annotations,
+			"the earlier prompt is dropped and the same text injected anew")
+	})
+
+	t.Run("Passthrough", func(t *testing.T) {
+		req := tooltest.Request()
+		_, annotations := transformAnnotated(t, tooladapter.New(), req)
+		assert.Equal(t, tooladapter.MessageAnnotations{caller(0)}, annotations)
+	})
+}
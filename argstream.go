@@ -0,0 +1,234 @@
+package tooladapter
+
+import (
+	"encoding/json"
+	"io"
+	"regexp"
+	"sync"
+)
+
+// ArgumentStream is a tool call detected in a stream whose arguments are still being
+// generated, delivered by WithArgumentStreaming.
+type ArgumentStream struct {
+	// Name is the called function, as supplied in the request's tools.
+	Name string
+
+	// Index counts the tool calls detected in the stream, starting at 0.
+	Index int
+
+	// Arguments reads the raw JSON value of the call's arguments as the model
+	// generates it. Reads block until more of the value arrives. The reader returns
+	// io.EOF once the value is complete, or ErrArgumentsIncomplete when the stream
+	// ends, fails or gives up on the tool call first.
+	Arguments io.Reader
+}
+
+// argumentHeaderPattern matches the start of a tool call object up to its arguments
+// value, in the key order the tool prompt asks for.
+var argumentHeaderPattern = regexp.MustCompile(`"name"\s*:\s*("(?:[^"\\]|\\.)*")\s*,\s*"parameters"\s*:\s*`)
+
+// argumentHeaderLookback is how much of the scanned buffer is searched again for a
+// tool call header split across chunks. Function names are at most 64 characters.
+const argumentHeaderLookback = 256
+
+// argumentStreamer feeds the arguments of tool calls in a StreamAdapter's buffer to
+// the WithArgumentStreaming handler while they are generated. It is driven by the
+// stream under s.mu; only the pipes are shared with handler goroutines.
+type argumentStreamer struct {
+	adapter   *Adapter
+	offset    int // bytes of the buffer already scanned
+	calls     int // tool calls handed to the handler
+	callStart int // start of the header of the call being streamed
+
+	// Arguments value being streamed; nil while searching for a tool call
+	pipe     *argumentPipe
+	started  bool
+	scalar   bool
+	inString bool
+	escaped  bool
+	depth    int
+}
+
+func newArgumentStreamer(a *Adapter) *argumentStreamer {
+	return &argumentStreamer{adapter: a}
+}
+
+// feed scans buffer, which extends the buffer seen by the previous call, for new
+// argument bytes.
+func (as *argumentStreamer) feed(buffer string) {
+	for as.offset < len(buffer) {
+		if as.pipe != nil {
+			as.scanValue(buffer)
+			continue
+		}
+		loc := argumentHeaderPattern.FindStringSubmatchIndex(buffer[as.offset:])
+		if loc == nil || as.offset+loc[1] == len(buffer) {
+			// Wait for the rest of a header split across chunks, or for the value
+			if loc == nil {
+				as.offset = max(as.offset, len(buffer)-argumentHeaderLookback)
+			}
+			return
+		}
+		var name string
+		if err := json.Unmarshal([]byte(buffer[as.offset+loc[2]:as.offset+loc[3]]), &name); err != nil {
+			as.offset += loc[1]
+			continue
+		}
+		as.callStart = as.offset + loc[0]
+		as.offset += loc[1]
+		as.open(name)
+	}
+}
+
+// open starts streaming the arguments of a tool call to the handler.
+func (as *argumentStreamer) open(name string) {
+	as.pipe = newArgumentPipe()
+	as.started, as.scalar, as.inString, as.escaped, as.depth = false, false, false, false, 0
+
+	call := ArgumentStream{
+		Name:      as.adapter.restoreToolName(name),
+		Index:     as.calls,
+		Arguments: as.pipe,
+	}
+	as.calls++
+	as.adapter.log(LogCategoryStream).Debug("Streaming tool call arguments",
+		"function_name", call.Name,
+		"index", call.Index)
+
+	handler := as.adapter.argumentHandler
+	logger := as.adapter.logger
+	go func() {
+		defer func() {
+			if r := recover(); r != nil {
+				logger.Error("Argument streaming handler panicked - the stream continues",
+					"panic", r,
+					"function_name", call.Name)
+			}
+		}()
+		handler(call)
+	}()
+}
+
+// scanValue writes the bytes of the arguments value from the buffer to the pipe,
+// tracking strings and nesting to find where the value ends.
+func (as *argumentStreamer) scanValue(buffer string) {
+	start := as.offset
+	for as.offset < len(buffer) {
+		c := buffer[as.offset]
+		if !as.started {
+			as.started = true
+			switch c {
+			case '{', '[':
+				as.depth = 1
+			case '"':
+				as.inString = true
+			default:
+				as.scalar = true
+			}
+			as.offset++
+			continue
+		}
+		switch {
+		case as.scalar:
+			if c == ',' || c == '}' || c == ']' || c == ' ' || c == '\t' || c == '\r' || c == '\n' {
+				as.finish(buffer[start:as.offset])
+				return
+			}
+		case as.inString:
+			switch {
+			case as.escaped:
+				as.escaped = false
+			case c == '\\':
+				as.escaped = true
+			case c == '"':
+				as.inString = false
+				if as.depth == 0 {
+					as.offset++
+					as.finish(buffer[start:as.offset])
+					return
+				}
+			}
+		default:
+			switch c {
+			case '{', '[':
+				as.depth++
+			case '}', ']':
+				as.depth--
+				if as.depth == 0 {
+					as.offset++
+					as.finish(buffer[start:as.offset])
+					return
+				}
+			case '"':
+				as.inString = true
+			}
+		}
+		as.offset++
+	}
+	as.pipe.write(buffer[start:as.offset])
+}
+
+// finish writes the end of the arguments value and completes its reader.
+func (as *argumentStreamer) finish(tail string) {
+	as.pipe.write(tail)
+	as.pipe.close(io.EOF)
+	as.pipe = nil
+}
+
+// reset follows the buffer being cleared. A value still being streamed will not
+// complete, so its reader fails.
+func (as *argumentStreamer) reset() {
+	if as.pipe != nil {
+		as.pipe.close(ErrArgumentsIncomplete)
+		as.pipe = nil
+	}
+	as.offset = 0
+}
+
+// argumentPipe is an io.Reader fed by the stream. Unlike io.Pipe, writes never block,
+// so a slow handler cannot stall the stream; unread bytes are held in memory.
+type argumentPipe struct {
+	mu   sync.Mutex
+	cond *sync.Cond
+	data []byte
+	err  error // returned once data is drained; nil while open
+}
+
+func newArgumentPipe() *argumentPipe {
+	p := &argumentPipe{}
+	p.cond = sync.NewCond(&p.mu)
+	return p
+}
+
+func (p *argumentPipe) Read(b []byte) (int, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for len(p.data) == 0 && p.err == nil {
+		p.cond.Wait()
+	}
+	if len(p.data) > 0 {
+		n := copy(b, p.data)
+		p.data = p.data[n:]
+		return n, nil
+	}
+	return 0, p.err
+}
+
+func (p *argumentPipe) write(s string) {
+	if s == "" {
+		return
+	}
+	p.mu.Lock()
+	p.data = append(p.data, s...)
+	p.mu.Unlock()
+	p.cond.Broadcast()
+}
+
+func (p *argumentPipe) close(err error) {
+	p.mu.Lock()
+	if p.err == nil {
+		p.err = err
+	}
+	p.mu.Unlock()
+	p.cond.Broadcast()
+}
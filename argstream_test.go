@@ -0,0 +1,182 @@
+package tooladapter_test
+
+import (
+	"io"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	tooladapter "github.com/juburr/openai-tool-adapter/v3"
+	"github.com/juburr/openai-tool-adapter/v3/tooltest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// gatedStream is a MockStream that reads a chunk only when the test releases it.
+type gatedStream struct {
+	*tooltest.MockStream
+	release chan struct{}
+}
+
+func (g *gatedStream) Next() bool {
+	<-g.release
+	return g.MockStream.Next()
+}
+
+// argumentCollector records the argument streams handed to a WithArgumentStreaming
+// handler and reads each to the end.
+type argumentCollector struct {
+	mu    sync.Mutex
+	calls []collectedArguments
+	read  chan struct{}
+}
+
+func newArgumentCollector() *argumentCollector {
+	return &argumentCollector{read: make(chan struct{}, 16)}
+}
+
+type collectedArguments struct {
+	name  string
+	index int
+	data  string
+	err   error
+}
+
+func (c *argumentCollector) handle(call tooladapter.ArgumentStream) {
+	data, err := io.ReadAll(call.Arguments)
+	c.mu.Lock()
+	c.calls = append(c.calls, collectedArguments{call.Name, call.Index, string(data), err})
+	c.mu.Unlock()
+	c.read <- struct{}{}
+}
+
+// results waits for n argument streams to be read to the end.
+func (c *argumentCollector) results(t *testing.T, n int) []collectedArguments {
+	t.Helper()
+	for range n {
+		select {
+		case <-c.read:
+		case <-time.After(time.Second):
+			t.Fatal("argument stream not read to the end")
+		}
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.calls
+}
+
+func TestWithArgumentStreaming(t *testing.T) {
+	t.Run("ReadsBeforeJSONCompletes", func(t *testing.T) {
+		calls := make(chan tooladapter.ArgumentStream, 1)
+		adapter := tooladapter.New(tooladapter.WithArgumentStreaming(func(call tooladapter.ArgumentStream) {
+			calls <- call
+		}))
+		upstream := &gatedStream{
+			MockStream: tooltest.NewContentStream(
+				`{"name": "write_file", "parameters": {"body": "chapter one `,
+				`chapter two"}}`,
+			),
+			release: make(chan struct{}),
+		}
+
+		done := make(chan tooltest.StreamResult)
+		go func() { done <- tooltest.Drain(adapter.TransformStreamingResponse(upstream)) }()
+
+		upstream.release <- struct{}{}
+		var call tooladapter.ArgumentStream
+		select {
+		case call = <-calls:
+		case <-time.After(time.Second):
+			t.Fatal("handler not called for the first chunk")
+		}
+		assert.Equal(t, "write_file", call.Name)
+		assert.Equal(t, 0, call.Index)
+
+		want := `{"body": "chapter one `
+		got := make([]byte, len(want))
+		_, err := io.ReadFull(call.Arguments, got)
+		require.NoError(t, err)
+		assert.Equal(t, want, string(got), "the first delta is readable before the JSON completes")
+
+		close(upstream.release)
+		rest, err := io.ReadAll(call.Arguments)
+		require.NoError(t, err)
+		assert.Equal(t, `chapter two"}`, string(rest))
+
+		result := <-done
+		require.NoError(t, result.Err)
+		assert.Equal(t, []string{"write_file"}, result.ToolNames())
+	})
+
+	t.Run("MultipleCalls", func(t *testing.T) {
+		collector := newArgumentCollector()
+		adapter := tooladapter.New(
+			tooladapter.WithToolPolicy(tooladapter.ToolDrainAll),
+			tooladapter.WithArgumentStreaming(collector.handle),
+		)
+		upstream := tooltest.NewContentStream(
+			`[{"name": "get_weather", "param`,
+			`eters": {"city": "Par`, `is, \"FR\" {x}"}}, `,
+			`{"name": "get_time", "parameters": {"zone": ["CET"]}}]`,
+		)
+
+		result := tooltest.Drain(adapter.TransformStreamingResponse(upstream))
+		require.NoError(t, result.Err)
+		assert.Equal(t, []string{"get_weather", "get_time"}, result.ToolNames())
+
+		calls := collector.results(t, 2)
+		require.Len(t, calls, 2)
+		byIndex := map[int]collectedArguments{calls[0].index: calls[0], calls[1].index: calls[1]}
+		assert.Equal(t, collectedArguments{"get_weather", 0, `{"city": "Paris, \"FR\" {x}"}`, nil}, byIndex[0])
+		assert.Equal(t, collectedArguments{"get_time", 1, `{"zone": ["CET"]}`, nil}, byIndex[1])
+	})
+
+	t.Run("IncompleteArguments", func(t *testing.T) {
+		collector := newArgumentCollector()
+		adapter := tooladapter.New(tooladapter.WithArgumentStreaming(collector.handle))
+		upstream := tooltest.NewContentStream(`{"name": "write_file", "parameters": {"body": "trunc`)
+
+		tooltest.Drain(adapter.TransformStreamingResponse(upstream))
+
+		calls := collector.results(t, 1)
+		require.Len(t, calls, 1)
+		assert.Equal(t, `{"body": "trunc`, calls[0].data)
+		require.ErrorIs(t, calls[0].err, tooladapter.ErrArgumentsIncomplete)
+	})
+
+	t.Run("RestoresNamespacedName", func(t *testing.T) {
+		collector := newArgumentCollector()
+		adapter := tooladapter.New(
+			tooladapter.WithToolNamespace("acme"),
+			tooladapter.WithArgumentStreaming(collector.handle),
+		)
+		upstream := tooltest.NewContentStream(`{"name": "acme.get_weather", "parameters": {}}`)
+
+		result := tooltest.Drain(adapter.TransformStreamingResponse(upstream))
+		assert.Equal(t, []string{"get_weather"}, result.ToolNames())
+		calls := collector.results(t, 1)
+		require.Len(t, calls, 1)
+		assert.Equal(t, "get_weather", calls[0].name)
+		assert.Equal(t, "{}", calls[0].data)
+	})
+
+	t.Run("PlainTextNotStreamed", func(t *testing.T) {
+		collector := newArgumentCollector()
+		adapter := tooladapter.New(tooladapter.WithArgumentStreaming(collector.handle))
+		result := tooltest.Drain(adapter.TransformStreamingResponse(tooltest.NewContentStream("Just text")))
+		assert.Equal(t, "Just text", result.Content)
+		assert.Empty(t, collector.results(t, 0))
+	})
+
+	t.Run("HandlerPanicRecovered", func(t *testing.T) {
+		adapter := tooladapter.New(tooladapter.WithArgumentStreaming(func(tooladapter.ArgumentStream) {
+			panic("boom")
+		}))
+		upstream := tooltest.NewContentStream(`{"name": "get_weather", "parameters": {"city": "Paris"}}`)
+		result := tooltest.Drain(adapter.TransformStreamingResponse(upstream))
+		require.NoError(t, result.Err)
+		assert.Equal(t, []string{"get_weather"}, result.ToolNames())
+		assert.False(t, strings.Contains(result.Content, "get_weather"))
+	})
+}
@@ -0,0 +1,169 @@
+package tooladapter
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Constraint values reported in ArgumentViolation for WithArgumentLimits.
+const (
+	ConstraintMaxBytes = "maxBytes"
+	ConstraintMaxDepth = "maxDepth"
+	ConstraintMaxItems = "maxItems"
+)
+
+// ArgumentLimits bounds the size and shape of parsed tool call arguments. Zero
+// fields are unlimited.
+type ArgumentLimits struct {
+	// MaxBytes is the largest encoded size of a call's arguments.
+	MaxBytes int
+
+	// MaxDepth is the deepest nesting of objects and arrays; the arguments object
+	// itself is depth 1.
+	MaxDepth int
+
+	// MaxArrayLength is the most elements of any array in the arguments.
+	MaxArrayLength int
+}
+
+// enabled reports whether any limit is set.
+func (l ArgumentLimits) enabled() bool {
+	return l.MaxBytes > 0 || l.MaxDepth > 0 || l.MaxArrayLength > 0
+}
+
+// limitArguments applies the argument limits to calls. Violations are logged and
+// reported in an ArgumentViolationData metric. Under ArgumentViolationError they
+// fail the response, otherwise the offending calls are dropped.
+func (a *Adapter) limitArguments(ctx context.Context, calls []functionCall) ([]functionCall, error) {
+	var violations []ArgumentViolation
+	kept := calls[:0]
+	for _, call := range calls {
+		violation, ok := checkArgumentLimits(call.Name, call.Parameters, a.argumentLimits)
+		if ok {
+			kept = append(kept, call)
+			continue
+		}
+		a.log(LogCategoryLimit).Warn("Tool call arguments exceed limit",
+			"function_name", violation.ToolName,
+			"path", violation.Path,
+			"constraint", violation.Constraint,
+			"violation", violation.Message)
+		violations = append(violations, violation)
+		if a.argumentViolationPolicy != ArgumentViolationError {
+			recordDroppedCalls(ctx, []functionCall{call}, DropReasonArgumentLimit)
+		}
+	}
+	if len(violations) == 0 {
+		return kept, nil
+	}
+
+	a.emitMetric(ArgumentViolationData{Violations: violations})
+	if a.argumentViolationPolicy == ArgumentViolationError {
+		return nil, &ToolArgumentError{Violations: violations}
+	}
+	return kept, nil
+}
+
+// argumentFrame is an open object or array while scanning arguments.
+type argumentFrame struct {
+	array     bool
+	key       string // current key of an object
+	expectKey bool   // the next string token of an object is a key
+	length    int    // elements seen in an array
+}
+
+// checkArgumentLimits scans raw arguments without decoding them into values and
+// returns the first limit they exceed. Arguments that are not valid JSON pass, as
+// they are rejected by parsing.
+func checkArgumentLimits(toolName string, raw json.RawMessage, limits ArgumentLimits) (ArgumentViolation, bool) {
+	violation := func(path, constraint, message string, value json.RawMessage) (ArgumentViolation, bool) {
+		if path == "" {
+			path = "arguments"
+		}
+		return ArgumentViolation{
+			ToolName:   toolName,
+			Path:       path,
+			Constraint: constraint,
+			Value:      value,
+			Message:    path + " " + message,
+		}, false
+	}
+
+	if limits.MaxBytes > 0 && len(raw) > limits.MaxBytes {
+		return violation("", ConstraintMaxBytes,
+			fmt.Sprintf("is %d bytes, above the limit of %d", len(raw), limits.MaxBytes), nil)
+	}
+	if limits.MaxDepth <= 0 && limits.MaxArrayLength <= 0 {
+		return ArgumentViolation{}, true
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(raw))
+	decoder.UseNumber()
+	var stack []argumentFrame
+	for {
+		token, err := decoder.Token()
+		if err != nil {
+			return ArgumentViolation{}, true
+		}
+
+		// Object keys only update the path
+		if key, ok := token.(string); ok && len(stack) > 0 && stack[len(stack)-1].expectKey {
+			stack[len(stack)-1].key = key
+			stack[len(stack)-1].expectKey = false
+			continue
+		}
+
+		if delim, ok := token.(json.Delim); ok && (delim == '}' || delim == ']') {
+			stack = stack[:len(stack)-1]
+			if len(stack) == 0 {
+				return ArgumentViolation{}, true
+			}
+			continue
+		}
+
+		// A value: count it in its container
+		if len(stack) > 0 {
+			top := &stack[len(stack)-1]
+			if top.array {
+				top.length++
+				if limits.MaxArrayLength > 0 && top.length > limits.MaxArrayLength {
+					return violation(argumentPath(stack[:len(stack)-1]), ConstraintMaxItems,
+						fmt.Sprintf("has more than %d items", limits.MaxArrayLength), nil)
+				}
+			} else {
+				top.expectKey = true
+			}
+		}
+
+		if delim, ok := token.(json.Delim); ok {
+			if limits.MaxDepth > 0 && len(stack)+1 > limits.MaxDepth {
+				return violation(argumentPath(stack), ConstraintMaxDepth,
+					fmt.Sprintf("is nested deeper than %d levels", limits.MaxDepth), nil)
+			}
+			stack = append(stack, argumentFrame{array: delim == '[', expectKey: delim == '{'})
+		} else if len(stack) == 0 {
+			return ArgumentViolation{}, true
+		}
+	}
+}
+
+// argumentPath formats the position of the current value of the innermost frame,
+// such as "stops[2].city".
+func argumentPath(stack []argumentFrame) string {
+	var b strings.Builder
+	for _, frame := range stack {
+		if frame.array {
+			b.WriteString("[" + strconv.Itoa(frame.length-1) + "]")
+			continue
+		}
+		if b.Len() > 0 {
+			b.WriteByte('.')
+		}
+		b.WriteString(frame.key)
+	}
+	return b.String()
+}
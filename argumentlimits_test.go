@@ -0,0 +1,79 @@
+package tooladapter_test
+
+import (
+	"strings"
+	"testing"
+
+	tooladapter "github.com/juburr/openai-tool-adapter/v3"
+	"github.com/juburr/openai-tool-adapter/v3/tooltest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWithArgumentLimits(t *testing.T) {
+	limits := tooladapter.ArgumentLimits{MaxBytes: 200, MaxDepth: 3, MaxArrayLength: 2}
+
+	limited := func(opts ...tooladapter.Option) (*tooladapter.Adapter, *[]tooladapter.ArgumentViolation) {
+		var violations []tooladapter.ArgumentViolation
+		opts = append(opts,
+			tooladapter.WithArgumentLimits(limits),
+			tooladapter.WithMetricsCallback(func(data tooladapter.MetricEventData) {
+				if d, ok := data.(tooladapter.ArgumentViolationData); ok {
+					violations = append(violations, d.Violations...)
+				}
+			}))
+		return tooladapter.New(opts...), &violations
+	}
+
+	cases := map[string]struct {
+		arguments  string
+		path       string
+		constraint string
+	}{
+		"Bytes":      {`{"text": "` + strings.Repeat("a", 200) + `"}`, "arguments", tooladapter.ConstraintMaxBytes},
+		"Depth":      {`{"trip": {"stops": [{"city": "Paris"}]}}`, "trip.stops[0]", tooladapter.ConstraintMaxDepth},
+		"ArrayItems": {`{"trip": {"cities": ["Paris", "Rome", "Oslo"]}}`, "trip.cities", tooladapter.ConstraintMaxItems},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			adapter, violations := limited()
+			content := `{"name": "plan_trip", "parameters": ` + tc.arguments + `}`
+			resp, err := adapter.TransformCompletionsResponse(tooltest.Completion(content))
+			require.NoError(t, err)
+			assert.Empty(t, resp.Choices[0].Message.ToolCalls, "the call is dropped")
+
+			require.Len(t, *violations, 1)
+			assert.Equal(t, "plan_trip", (*violations)[0].ToolName)
+			assert.Equal(t, tc.path, (*violations)[0].Path)
+			assert.Equal(t, tc.constraint, (*violations)[0].Constraint)
+		})
+	}
+
+	t.Run("WithinLimits", func(t *testing.T) {
+		adapter, violations := limited()
+		content := `{"name": "plan_trip", "parameters": {"trip": {"cities": ["Paris", "Rome"]}}}`
+		resp, err := adapter.TransformCompletionsResponse(tooltest.Completion(content))
+		require.NoError(t, err)
+		assert.Len(t, resp.Choices[0].Message.ToolCalls, 1)
+		assert.Empty(t, *violations)
+	})
+
+	t.Run("ErrorPolicy", func(t *testing.T) {
+		adapter, _ := limited(tooladapter.WithArgumentViolationPolicy(tooladapter.ArgumentViolationError))
+		content := `{"name": "plan_trip", "parameters": {"cities": ["Paris", "Rome", "Oslo"]}}`
+		_, err := adapter.TransformCompletionsResponse(tooltest.Completion(content))
+		require.ErrorIs(t, err, tooladapter.ErrArgumentViolation)
+
+		result := tooltest.Drain(adapter.TransformStreamingResponse(tooltest.NewContentStream(content)))
+		require.ErrorIs(t, result.Err, tooladapter.ErrArgumentViolation)
+	})
+
+	t.Run("Config", func(t *testing.T) {
+		cfg, err := tooladapter.LoadConfig(strings.NewReader(`{"argument_max_depth": 4}`))
+		require.NoError(t, err)
+		assert.Equal(t, 4, cfg.ArgumentMaxDepth)
+
+		_, err = tooladapter.NewFromConfig(tooladapter.Config{ArgumentMaxBytes: -1})
+		require.ErrorIs(t, err, tooladapter.ErrInvalidConfig)
+	})
+}
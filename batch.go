@@ -0,0 +1,145 @@
+package tooladapter
+
+import (
+	"context"
+	"fmt"
+	"runtime"
+	"sync"
+
+	"github.com/openai/openai-go/v3"
+)
+
+// BatchError is returned by the batch transformations when one or more items fail.
+// Items that succeeded are still returned in the result slice, so a gateway can
+// answer them and report the failures individually.
+type BatchError struct {
+	// Errors holds the error of each batch item by index, nil for items that
+	// were transformed successfully.
+	Errors []error
+}
+
+func (e *BatchError) Error() string {
+	failed, first := 0, -1
+	for i, err := range e.Errors {
+		if err != nil {
+			failed++
+			if first < 0 {
+				first = i
+			}
+		}
+	}
+	return fmt.Sprintf("%d of %d batch items failed; item %d: %v", failed, len(e.Errors), first, e.Errors[first])
+}
+
+// Unwrap returns the errors of the failed items, so errors.Is and errors.As match
+// any of them.
+func (e *BatchError) Unwrap() []error {
+	var errs []error
+	for _, err := range e.Errors {
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errs
+}
+
+// TransformCompletionsRequests transforms a batch of requests concurrently.
+// This is the backward-compatible version that uses context.Background().
+func (a *Adapter) TransformCompletionsRequests(reqs []openai.ChatCompletionNewParams) ([]openai.ChatCompletionNewParams, error) {
+	return a.TransformCompletionsRequestsWithContext(context.Background(), reqs)
+}
+
+// TransformCompletionsRequestsWithContext transforms a batch of requests on a pool of
+// up to WithBatchConcurrency workers, for proxies that receive requests in bursts.
+// Each request is transformed exactly as by TransformCompletionsRequestWithContext,
+// and the results are returned in the order of reqs.
+//
+// Concurrency guarantees:
+//   - The workers share the adapter's prompt buffer pool, so a burst reuses the same
+//     buffers instead of allocating one per request
+//   - Requests are independent; the failure of one does not affect the others
+//   - Options callbacks such as WithMetricsCallback are called from the worker
+//     goroutines and must be safe for concurrent use
+//   - All workers have exited when the method returns
+//
+// If any request fails, the error is a *BatchError indexed like reqs. Requests not
+// started before ctx is canceled fail with the context's error.
+func (a *Adapter) TransformCompletionsRequestsWithContext(ctx context.Context, reqs []openai.ChatCompletionNewParams) ([]openai.ChatCompletionNewParams, error) {
+	results := make([]openai.ChatCompletionNewParams, len(reqs))
+	err := a.runBatch(ctx, len(reqs), func(ctx context.Context, i int) error {
+		var err error
+		results[i], err = a.TransformCompletionsRequestWithContext(ctx, reqs[i])
+		return err
+	})
+	return results, err
+}
+
+// TransformCompletionsResponses transforms a batch of responses concurrently.
+// This is the backward-compatible version that uses context.Background().
+func (a *Adapter) TransformCompletionsResponses(resps []openai.ChatCompletion) ([]openai.ChatCompletion, error) {
+	return a.TransformCompletionsResponsesWithContext(context.Background(), resps)
+}
+
+// TransformCompletionsResponsesWithContext transforms a batch of responses on a pool
+// of up to WithBatchConcurrency workers. Each response is transformed exactly as by
+// TransformCompletionsResponseWithContext, and the results are returned in the order
+// of resps. It offers the same guarantees as TransformCompletionsRequestsWithContext.
+//
+// The responses share ctx, so a Conversation attached with ContextWithConversation is
+// counted by every response in the batch. Attach tools with ContextWithTools only
+// when all responses answer requests with the same tools.
+func (a *Adapter) TransformCompletionsResponsesWithContext(ctx context.Context, resps []openai.ChatCompletion) ([]openai.ChatCompletion, error) {
+	results := make([]openai.ChatCompletion, len(resps))
+	err := a.runBatch(ctx, len(resps), func(ctx context.Context, i int) error {
+		var err error
+		results[i], err = a.TransformCompletionsResponseWithContext(ctx, resps[i])
+		return err
+	})
+	return results, err
+}
+
+// runBatch calls transform for each of n items on a pool of workers and waits for them
+// to finish. It returns a *BatchError if any item fails.
+func (a *Adapter) runBatch(ctx context.Context, n int, transform func(ctx context.Context, i int) error) error {
+	if n == 0 {
+		return nil
+	}
+
+	workers := a.batchConcurrency
+	if workers == 0 {
+		workers = runtime.GOMAXPROCS(0)
+	}
+	workers = min(workers, n)
+
+	errs := make([]error, n)
+	items := make(chan int)
+	var wg sync.WaitGroup
+	wg.Add(workers)
+	for range workers {
+		go func() {
+			defer wg.Done()
+			for i := range items {
+				if err := ctx.Err(); err != nil {
+					errs[i] = err
+					continue
+				}
+				errs[i] = transform(ctx, i)
+			}
+		}()
+	}
+	for i := range n {
+		items <- i
+	}
+	close(items)
+	wg.Wait()
+
+	for _, err := range errs {
+		if err != nil {
+			a.logger.Debug("Batch transformation had failures",
+				"batch_size", n,
+				"workers", workers)
+			return &BatchError{Errors: errs}
+		}
+	}
+	return nil
+}
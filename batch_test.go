@@ -0,0 +1,89 @@
+package tooladapter_test
+
+import (
+	"context"
+	"fmt"
+	"sync/atomic"
+	"testing"
+
+	tooladapter "github.com/juburr/openai-tool-adapter/v3"
+	"github.com/openai/openai-go/v3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTransformCompletionsRequests(t *testing.T) {
+	adapter := tooladapter.New(tooladapter.WithBatchConcurrency(3))
+
+	reqs := make([]openai.ChatCompletionNewParams, 20)
+	for i := range reqs {
+		reqs[i] = createMockRequest([]openai.ChatCompletionToolUnionParam{
+			createMockTool(fmt.Sprintf("tool_%d", i), "A test tool"),
+		})
+	}
+	// Duplicate function names are rejected, failing this item only
+	reqs[7].Tools = append(reqs[7].Tools, reqs[7].Tools[0])
+
+	results, err := adapter.TransformCompletionsRequests(reqs)
+	require.Error(t, err)
+	assert.ErrorIs(t, err, tooladapter.ErrToolNameCollision)
+
+	var batchErr *tooladapter.BatchError
+	require.ErrorAs(t, err, &batchErr)
+	require.Len(t, batchErr.Errors, len(reqs))
+	require.Len(t, results, len(reqs))
+	for i, result := range results {
+		if i == 7 {
+			assert.Error(t, batchErr.Errors[i])
+			continue
+		}
+		assert.NoError(t, batchErr.Errors[i])
+		assert.Empty(t, result.Tools)
+		assert.Contains(t, result.Messages[0].OfUser.Content.OfString.Value, fmt.Sprintf("tool_%d", i),
+			"results are returned in request order")
+	}
+}
+
+func TestTransformCompletionsResponses(t *testing.T) {
+	var calls atomic.Int64
+	adapter := tooladapter.New(
+		tooladapter.WithBatchConcurrency(4),
+		tooladapter.WithMetricsCallback(func(data tooladapter.MetricEventData) {
+			if d, ok := data.(tooladapter.FunctionCallDetectionData); ok {
+				calls.Add(int64(d.FunctionCount))
+			}
+		}))
+
+	resps := make([]openai.ChatCompletion, 50)
+	for i := range resps {
+		resps[i] = createMockCompletion(fmt.Sprintf(`{"name": "tool_%d", "parameters": {}}`, i))
+	}
+
+	results, err := adapter.TransformCompletionsResponses(resps)
+	require.NoError(t, err)
+	require.Len(t, results, len(resps))
+	for i, result := range results {
+		require.Len(t, result.Choices[0].Message.ToolCalls, 1)
+		assert.Equal(t, fmt.Sprintf("tool_%d", i), result.Choices[0].Message.ToolCalls[0].Function.Name)
+	}
+	assert.Equal(t, int64(len(resps)), calls.Load())
+
+	t.Run("Empty", func(t *testing.T) {
+		results, err := adapter.TransformCompletionsResponses(nil)
+		require.NoError(t, err)
+		assert.Empty(t, results)
+	})
+
+	t.Run("Canceled", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		_, err := adapter.TransformCompletionsResponsesWithContext(ctx, resps[:3])
+		require.ErrorIs(t, err, context.Canceled)
+
+		var batchErr *tooladapter.BatchError
+		require.ErrorAs(t, err, &batchErr)
+		for _, itemErr := range batchErr.Errors {
+			assert.ErrorIs(t, itemErr, context.Canceled)
+		}
+	})
+}
@@ -0,0 +1,283 @@
+package tooladapter
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/openai/openai-go/v3"
+)
+
+// streamCheckpointVersion is the version of the streamCheckpoint encoding. Restore
+// rejects checkpoints of other versions.
+const streamCheckpointVersion = 1
+
+// streamCheckpoint is the encoding of a StreamAdapter's buffering state returned by
+// Snapshot.
+type streamCheckpoint struct {
+	Version    int                        `json:"version"`
+	Chunks     int                        `json:"chunks"`
+	FirstChunk openai.ChatCompletionChunk `json:"first_chunk"`
+	Native     bool                       `json:"native,omitempty"`
+
+	Buffer       string `json:"buffer,omitempty"`
+	Peek         string `json:"peek,omitempty"`
+	MixedEmitted string `json:"mixed_emitted,omitempty"`
+	HasEmitted   bool   `json:"has_emitted,omitempty"`
+
+	ToolCallsEmitted   bool                        `json:"tool_calls_emitted,omitempty"`
+	CollectionState    toolCollectionState         `json:"collection_state,omitempty"`
+	CollectedTools     []checkpointToolCall        `json:"collected_tools,omitempty"`
+	CollectedText      string                      `json:"collected_text,omitempty"`
+	CollectionStarted  time.Time                   `json:"collection_started,omitzero"`
+	BytesCollected     int                         `json:"bytes_collected,omitempty"`
+	ContentSuppressed  bool                        `json:"content_suppressed,omitempty"`
+	StopProcessing     bool                        `json:"stop_processing,omitempty"`
+	PassThrough        bool                        `json:"pass_through,omitempty"`
+	BufferingSpent     time.Duration               `json:"buffering_spent,omitempty"`
+	SeenCalls          []string                    `json:"seen_calls,omitempty"`
+	DeliveredToolCalls int                         `json:"delivered_tool_calls,omitempty"`
+	PendingFinish      *openai.ChatCompletionChunk `json:"pending_finish,omitempty"`
+	PendingSplit       *openai.ChatCompletionChunk `json:"pending_split,omitempty"`
+
+	StopTokens *stopTokenCheckpoint `json:"stop_tokens,omitempty"`
+	Think      *thinkCheckpoint     `json:"think,omitempty"`
+	Strict     *strictCheckpoint    `json:"strict,omitempty"`
+	Arguments  *argumentCheckpoint  `json:"arguments,omitempty"`
+}
+
+// checkpointToolCall is a collected tool call in a checkpoint.
+type checkpointToolCall struct {
+	Name       string          `json:"name"`
+	Parameters json.RawMessage `json:"parameters,omitempty"`
+	ID         string          `json:"id,omitempty"`
+}
+
+// thinkCheckpoint is the state of the think block splitter (see WithThinkBlocks).
+type thinkCheckpoint struct {
+	InThink bool                         `json:"in_think,omitempty"`
+	Pending string                       `json:"pending,omitempty"`
+	Queue   []openai.ChatCompletionChunk `json:"queue,omitempty"`
+	Queued  []bool                       `json:"queued_thinking,omitempty"`
+	Thought string                       `json:"thought,omitempty"`
+	Ended   bool                         `json:"ended,omitempty"`
+}
+
+// checkpoint returns the state of the think block splitter.
+func (t *thinkStream) checkpoint() *thinkCheckpoint {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	checkpoint := &thinkCheckpoint{
+		InThink: t.splitter.inThink,
+		Pending: t.splitter.pending,
+		Thought: t.thought.String(),
+		Ended:   t.ended,
+	}
+	for _, queued := range t.queue {
+		checkpoint.Queue = append(checkpoint.Queue, queued.chunk)
+		checkpoint.Queued = append(checkpoint.Queued, queued.thinking)
+	}
+	return checkpoint
+}
+
+// restore resumes the state of a think block splitter.
+func (t *thinkStream) restore(checkpoint thinkCheckpoint) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.splitter = thinkSplitter{inThink: checkpoint.InThink, pending: checkpoint.Pending}
+	t.thought.Reset()
+	t.thought.WriteString(checkpoint.Thought)
+	t.ended = checkpoint.Ended
+	t.queue = nil
+	for i, chunk := range checkpoint.Queue {
+		t.queue = append(t.queue, thinkChunk{chunk: chunk, thinking: i < len(checkpoint.Queued) && checkpoint.Queued[i]})
+	}
+}
+
+// stopTokenCheckpoint is the state of stop token stripping (see
+// WithStopTokenStripping).
+type stopTokenCheckpoint struct {
+	Tokens  []string                     `json:"tokens,omitempty"`
+	Pending string                       `json:"pending,omitempty"`
+	Queue   []openai.ChatCompletionChunk `json:"queue,omitempty"`
+	Ended   bool                         `json:"ended,omitempty"`
+}
+
+// checkpoint returns the state of stop token stripping.
+func (t *stopTokenStream) checkpoint() *stopTokenCheckpoint {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return &stopTokenCheckpoint{Tokens: t.tokens, Pending: t.pending, Queue: t.queue, Ended: t.ended}
+}
+
+// restore resumes the state of stop token stripping.
+func (t *stopTokenStream) restore(checkpoint stopTokenCheckpoint) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.tokens, t.pending, t.queue, t.ended = checkpoint.Tokens, checkpoint.Pending, checkpoint.Queue, checkpoint.Ended
+}
+
+// strictCheckpoint is the state of WithStrictOpenAICompatibility rewriting.
+type strictCheckpoint struct {
+	Queue     []openai.ChatCompletionChunk `json:"queue,omitempty"`
+	RoleSent  bool                         `json:"role_sent,omitempty"`
+	ToolCalls bool                         `json:"tool_calls,omitempty"`
+	Finished  bool                         `json:"finished,omitempty"`
+}
+
+// argumentCheckpoint is the state of WithArgumentStreaming. A tool call whose
+// arguments were being streamed is scanned again from its start.
+type argumentCheckpoint struct {
+	Offset int `json:"offset"`
+	Calls  int `json:"calls"`
+}
+
+// Snapshot returns the stream's buffering state as a JSON checkpoint, for proxies
+// that let a dropped client reconnect and resume. Restore the checkpoint on a new
+// StreamAdapter reading the rest of the upstream stream, starting after the last
+// chunk this stream read, and the new stream continues where this one left off,
+// including a tool call still being collected, the WithToolCollectTotalBudget time
+// already spent and a degradation to pass-through. The checkpoint holds the buffered
+// model output as it is, so store it like the conversation itself.
+//
+// Snapshot may be called between calls to Next, or from another goroutine while Next
+// waits for upstream data, as it does while a tool call is buffered. Chunks held by
+// WithStreamReadAhead but not yet processed are not part of the checkpoint, so
+// resumable streams should not enable read-ahead. Snapshot fails once the stream has
+// ended.
+func (s *StreamAdapter) Snapshot() ([]byte, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.done {
+		return nil, errors.New("stream has ended")
+	}
+
+	checkpoint := streamCheckpoint{
+		Version:            streamCheckpointVersion,
+		Chunks:             s.processedChunks,
+		FirstChunk:         s.firstChunk,
+		Native:             s.native,
+		Buffer:             s.buffer.String(),
+		Peek:               s.peek.String(),
+		MixedEmitted:       s.mixedEmitted,
+		HasEmitted:         s.hasEmitted,
+		ToolCallsEmitted:   s.toolCallsEmitted,
+		CollectionState:    s.toolCollectionState,
+		CollectedText:      s.collectedText,
+		CollectionStarted:  s.collectionStartTime,
+		BytesCollected:     s.bytesCollected,
+		ContentSuppressed:  s.contentSuppressed,
+		StopProcessing:     s.stopProcessing,
+		PassThrough:        s.passThrough,
+		BufferingSpent:     s.bufferingSpent,
+		DeliveredToolCalls: s.deliveredToolCalls,
+		PendingFinish:      s.pendingFinish,
+		PendingSplit:       s.pendingSplit,
+	}
+	if !s.bufferingSince.IsZero() {
+		checkpoint.BufferingSpent += time.Since(s.bufferingSince)
+	}
+	for _, call := range s.collectedTools {
+		checkpoint.CollectedTools = append(checkpoint.CollectedTools, checkpointToolCall{Name: call.Name, Parameters: call.Parameters, ID: call.ID})
+	}
+	for key := range s.seenCalls {
+		checkpoint.SeenCalls = append(checkpoint.SeenCalls, key)
+	}
+	if s.stopTokens != nil {
+		checkpoint.StopTokens = s.stopTokens.checkpoint()
+	}
+	if s.think != nil {
+		checkpoint.Think = s.think.checkpoint()
+	}
+	if c := s.strict; c != nil {
+		checkpoint.Strict = &strictCheckpoint{Queue: c.queue, RoleSent: c.roleSent, ToolCalls: c.toolCalls, Finished: c.finished}
+	}
+	if as := s.args; as != nil {
+		checkpoint.Arguments = &argumentCheckpoint{Offset: as.offset, Calls: as.calls}
+		if as.pipe != nil {
+			checkpoint.Arguments = &argumentCheckpoint{Offset: as.callStart, Calls: as.calls - 1}
+		}
+	}
+	return json.Marshal(checkpoint)
+}
+
+// Restore resumes the buffering state of a checkpoint returned by Snapshot. Call it
+// before the first call to Next, on a stream transformed by an adapter configured
+// like the one that took the snapshot. The model rule and native passthrough
+// decision of the original stream carry over, and the restored content counts
+// against WithAggregateStreamMemoryLimit right away. A WithArgumentStreaming handler is
+// called again for a tool call whose arguments were being streamed at the snapshot.
+// Restore returns an error wrapping ErrInvalidCheckpoint when the checkpoint cannot
+// be decoded or the stream has already started.
+func (s *StreamAdapter) Restore(data []byte) error {
+	var checkpoint streamCheckpoint
+	if err := json.Unmarshal(data, &checkpoint); err != nil {
+		return fmt.Errorf("%w: %w", ErrInvalidCheckpoint, err)
+	}
+	if checkpoint.Version != streamCheckpointVersion {
+		return fmt.Errorf("%w: unsupported version %d", ErrInvalidCheckpoint, checkpoint.Version)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.processedChunks > 0 || s.done {
+		return fmt.Errorf("%w: stream has already started", ErrInvalidCheckpoint)
+	}
+
+	if checkpoint.Chunks > 0 {
+		s.selectModelAdapter(checkpoint.FirstChunk.Model, s.upstream)
+	}
+	s.processedChunks = checkpoint.Chunks
+	s.firstChunk = checkpoint.FirstChunk
+	s.native = checkpoint.Native
+	s.buffer.Reset()
+	s.buffer.WriteString(checkpoint.Buffer)
+	s.peek.Reset()
+	s.peek.WriteString(checkpoint.Peek)
+	s.mixedEmitted = checkpoint.MixedEmitted
+	s.hasEmitted = checkpoint.HasEmitted
+	s.toolCallsEmitted = checkpoint.ToolCallsEmitted
+	s.toolCollectionState = checkpoint.CollectionState
+	s.collectionStartTime = checkpoint.CollectionStarted
+	s.bytesCollected = checkpoint.BytesCollected
+	s.contentSuppressed = checkpoint.ContentSuppressed
+	s.stopProcessing = checkpoint.StopProcessing
+	s.passThrough = checkpoint.PassThrough
+	s.bufferingSpent = checkpoint.BufferingSpent
+	s.deliveredToolCalls = checkpoint.DeliveredToolCalls
+	s.pendingFinish = checkpoint.PendingFinish
+	s.pendingSplit = checkpoint.PendingSplit
+	s.collectedText = checkpoint.CollectedText
+	s.collectedTools = nil
+	for _, call := range checkpoint.CollectedTools {
+		s.collectedTools = append(s.collectedTools, functionCall{Name: call.Name, Parameters: call.Parameters, ID: call.ID})
+	}
+	if s.seenCalls != nil {
+		for _, key := range checkpoint.SeenCalls {
+			s.seenCalls[key] = struct{}{}
+		}
+	}
+	if s.stopTokens != nil && checkpoint.StopTokens != nil {
+		s.stopTokens.restore(*checkpoint.StopTokens)
+	}
+	if s.think != nil && checkpoint.Think != nil {
+		s.think.restore(*checkpoint.Think)
+	}
+	if c, saved := s.strict, checkpoint.Strict; c != nil && saved != nil {
+		c.queue, c.roleSent, c.toolCalls, c.finished = saved.Queue, saved.RoleSent, saved.ToolCalls, saved.Finished
+	}
+	if as, saved := s.args, checkpoint.Arguments; as != nil && saved != nil {
+		as.offset, as.calls = saved.Offset, saved.Calls
+		as.feed(s.buffer.String())
+	}
+	s.trackBuffering()
+	s.accountMemory()
+	s.lastEmitTime = time.Now()
+
+	s.adapter.log(LogCategoryStream).Debug("Restored stream checkpoint",
+		"processed_chunks", s.processedChunks,
+		"buffer_length", s.buffer.Len(),
+		"collected_tools", len(s.collectedTools))
+	return nil
+}
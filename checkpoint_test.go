@@ -0,0 +1,224 @@
+package tooladapter_test
+
+import (
+	"encoding/json"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	tooladapter "github.com/juburr/openai-tool-adapter/v3"
+	"github.com/juburr/openai-tool-adapter/v3/tooltest"
+	"github.com/openai/openai-go/v3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// stalledStream is a MockStream that, once its chunks are read and delay has passed,
+// reports that it is waiting and blocks until the gate closes, like an upstream
+// connection stalled mid tool call.
+type stalledStream struct {
+	*tooltest.MockStream
+	delay   time.Duration
+	waiting chan struct{}
+	gate    chan struct{}
+}
+
+func newStalledStream(contents ...string) *stalledStream {
+	chunks := make([]openai.ChatCompletionChunk, 0, len(contents))
+	for _, content := range contents {
+		chunks = append(chunks, tooltest.ContentChunk(content))
+	}
+	return &stalledStream{
+		MockStream: tooltest.NewMockStream(chunks...),
+		waiting:    make(chan struct{}),
+		gate:       make(chan struct{}),
+	}
+}
+
+func (s *stalledStream) Next() bool {
+	if s.MockStream.Next() {
+		return true
+	}
+	time.Sleep(s.delay)
+	close(s.waiting)
+	<-s.gate
+	return false
+}
+
+// snapshotWhileWaiting drains a stream of upstream in the background, snapshots it
+// once upstream stalls, then lets it end and returns the checkpoint with the content
+// emitted before the snapshot.
+func snapshotWhileWaiting(t *testing.T, adapter *tooladapter.Adapter, upstream *stalledStream) ([]byte, string) {
+	t.Helper()
+	stream := adapter.TransformStreamingResponse(upstream)
+	emitted := make(chan string, 16)
+	drained := make(chan struct{})
+	go func() {
+		defer close(drained)
+		for stream.Next() {
+			if chunk := stream.Current(); len(chunk.Choices) > 0 {
+				emitted <- chunk.Choices[0].Delta.Content
+			}
+		}
+	}()
+
+	select {
+	case <-upstream.waiting:
+	case <-time.After(5 * time.Second):
+		t.Fatal("upstream was not read")
+	}
+	checkpoint, err := stream.Snapshot()
+	require.NoError(t, err)
+
+	var before string
+	for len(emitted) > 0 {
+		before += <-emitted
+	}
+	close(upstream.gate)
+	<-drained
+	require.NoError(t, stream.Close())
+
+	_, err = stream.Snapshot()
+	require.Error(t, err, "the stream has ended")
+	return checkpoint, before
+}
+
+func TestStreamCheckpoint(t *testing.T) {
+	t.Run("ResumeMidToolCall", func(t *testing.T) {
+		adapter := tooladapter.New()
+		checkpoint, before := snapshotWhileWaiting(t, adapter,
+			newStalledStream("Let me check. ", `{"name": "get_`, `weather", "parameters": {"city": "Par`))
+		assert.NotContains(t, before, "get_weather")
+
+		resumed := adapter.TransformStreamingResponse(tooltest.NewMockStream(
+			tooltest.ContentChunk(`is"}}`),
+			tooltest.FinishChunk("stop")))
+		require.NoError(t, resumed.Restore(checkpoint))
+
+		result := tooltest.Drain(resumed)
+		require.NoError(t, result.Err)
+		require.Len(t, result.ToolCalls, 1)
+		assert.Equal(t, "get_weather", result.ToolCalls[0].Function.Name)
+		assert.JSONEq(t, `{"city": "Paris"}`, result.ToolCalls[0].Function.Arguments)
+		assert.NotContains(t, result.Content, "get_weather")
+	})
+
+	t.Run("ThinkBlocks", func(t *testing.T) {
+		adapter := tooladapter.New(tooladapter.WithThinkBlocks(tooladapter.ThinkBlocksStrip))
+		checkpoint, _ := snapshotWhileWaiting(t, adapter,
+			newStalledStream("<think>call get_", `weather</think>{"name": "get_weather", `))
+
+		resumed := adapter.TransformStreamingResponse(tooltest.NewMockStream(
+			tooltest.ContentChunk(`"parameters": {"city": "Oslo"}}`),
+			tooltest.FinishChunk("stop")))
+		require.NoError(t, resumed.Restore(checkpoint))
+
+		completion, err := resumed.Accumulate()
+		require.NoError(t, err)
+		require.Len(t, completion.Choices[0].Message.ToolCalls, 1)
+		assert.JSONEq(t, `{"city": "Oslo"}`, completion.Choices[0].Message.ToolCalls[0].Function.Arguments)
+	})
+
+	t.Run("ArgumentStreaming", func(t *testing.T) {
+		arguments := make(chan string, 4)
+		adapter := tooladapter.New(tooladapter.WithArgumentStreaming(func(call tooladapter.ArgumentStream) {
+			data, err := io.ReadAll(call.Arguments)
+			if err == nil {
+				arguments <- string(data)
+			}
+		}))
+		checkpoint, _ := snapshotWhileWaiting(t, adapter,
+			newStalledStream(`{"name": "get_weather", "parameters": {"city": "Be`))
+
+		resumed := adapter.TransformStreamingResponse(tooltest.NewMockStream(
+			tooltest.ContentChunk(`rlin"}}`),
+			tooltest.FinishChunk("stop")))
+		require.NoError(t, resumed.Restore(checkpoint))
+		require.Len(t, tooltest.Drain(resumed).ToolCalls, 1)
+
+		select {
+		case args := <-arguments:
+			assert.JSONEq(t, `{"city": "Berlin"}`, args, "the handler is called again for the interrupted call")
+		case <-time.After(5 * time.Second):
+			t.Fatal("arguments were not streamed after the restore")
+		}
+	})
+
+	t.Run("CollectedCallsFlushedAsText", func(t *testing.T) {
+		adapter := tooladapter.New(
+			tooladapter.WithToolPolicy(tooladapter.ToolCollectThenStop),
+			tooladapter.WithStreamEOFPolicy(tooladapter.StreamEOFFlushText))
+		chunks := []string{`{"name": "first", "parameters": {}}`, "\n[", `{"name": "second", "parameters": {}}, `}
+		checkpoint, before := snapshotWhileWaiting(t, adapter, newStalledStream(chunks...))
+
+		// The array never closes, so the collection is flushed as the model's text
+		rest := `{"name": "third", "parameters": {"city": "Par`
+		resumed := adapter.TransformStreamingResponse(tooltest.NewMockStream(tooltest.ContentChunk(rest)))
+		require.NoError(t, resumed.Restore(checkpoint))
+
+		result := tooltest.Drain(resumed)
+		require.NoError(t, result.Err)
+		assert.Empty(t, result.ToolCalls)
+		assert.Equal(t, strings.Join(chunks, "")+rest, before+result.Content,
+			"text of calls collected before the snapshot is flushed too")
+	})
+
+	t.Run("PassThroughCarriesOver", func(t *testing.T) {
+		adapter := tooladapter.New(
+			tooladapter.WithToolPolicy(tooladapter.ToolDrainAll),
+			tooladapter.WithStreamMemoryLimit(20))
+		checkpoint, before := snapshotWhileWaiting(t, adapter, newStalledStream("Hello ", "world, ", "this is long "))
+
+		late := `{"name": "late", "parameters": {}}`
+		resumed := adapter.TransformStreamingResponse(tooltest.NewContentStream(late))
+		require.NoError(t, resumed.Restore(checkpoint))
+
+		result := tooltest.Drain(resumed)
+		require.NoError(t, result.Err)
+		assert.Empty(t, result.ToolCalls, "the stream stays degraded after the restore")
+		assert.Equal(t, "Hello world, this is long "+late, before+result.Content)
+	})
+
+	t.Run("CollectBudgetCarriesOver", func(t *testing.T) {
+		adapter := tooladapter.New(tooladapter.WithToolCollectTotalBudget(75 * time.Millisecond))
+		upstream := newStalledStream(`{"name": "get_`)
+		upstream.delay = 100 * time.Millisecond
+		checkpoint, before := snapshotWhileWaiting(t, adapter, upstream)
+
+		rest := `weather", "parameters": {}}`
+		resumed := adapter.TransformStreamingResponse(tooltest.NewContentStream(rest))
+		require.NoError(t, resumed.Restore(checkpoint))
+
+		result := tooltest.Drain(resumed)
+		require.NoError(t, result.Err)
+		assert.Empty(t, result.ToolCalls, "buffering time before the snapshot counts against the budget")
+		assert.Equal(t, `{"name": "get_`+rest, before+result.Content)
+	})
+
+	t.Run("AggregateMemoryCounted", func(t *testing.T) {
+		adapter := tooladapter.New(tooladapter.WithAggregateStreamMemoryLimit(1 << 20))
+		checkpoint, _ := snapshotWhileWaiting(t, adapter, newStalledStream(`{"name": "get_weather", "parameters": {"city": "Par`))
+		require.Zero(t, adapter.RetainedStreamMemory())
+
+		resumed := adapter.TransformStreamingResponse(tooltest.NewMockStream(
+			tooltest.ContentChunk(`is"}}`),
+			tooltest.FinishChunk("stop")))
+		require.NoError(t, resumed.Restore(checkpoint))
+		assert.Positive(t, adapter.RetainedStreamMemory(), "the restored buffer is counted before the first chunk")
+
+		require.Len(t, tooltest.Drain(resumed).ToolCalls, 1)
+		assert.Zero(t, adapter.RetainedStreamMemory())
+	})
+
+	t.Run("InvalidCheckpoint", func(t *testing.T) {
+		stream := tooladapter.New().TransformStreamingResponse(tooltest.NewContentStream("Hi"))
+		assert.ErrorIs(t, stream.Restore([]byte("{")), tooladapter.ErrInvalidCheckpoint)
+		assert.ErrorIs(t, stream.Restore([]byte(`{"version": 99}`)), tooladapter.ErrInvalidCheckpoint)
+
+		checkpoint, err := json.Marshal(map[string]any{"version": 1})
+		require.NoError(t, err)
+		tooltest.Drain(stream)
+		assert.ErrorIs(t, stream.Restore(checkpoint), tooladapter.ErrInvalidCheckpoint, "the stream has started")
+	})
+}
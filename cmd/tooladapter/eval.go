@@ -0,0 +1,358 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	tooladapter "github.com/juburr/openai-tool-adapter/v3"
+	"github.com/openai/openai-go/v3"
+	"github.com/openai/openai-go/v3/option"
+)
+
+// evalConfig holds the parsed eval command line.
+type evalConfig struct {
+	presets  string
+	baseURL  string
+	apiKey   string
+	model    string
+	timeout  time.Duration
+	compact  bool
+	logLevel string
+}
+
+// evalCase is one line of an eval dataset.
+type evalCase struct {
+	ID            string                                   `json:"id"`
+	Messages      []openai.ChatCompletionMessageParamUnion `json:"messages"`
+	Tools         []openai.ChatCompletionToolUnionParam    `json:"tools"`
+	ExpectedCalls []expectedCall                           `json:"expected_calls"`
+}
+
+// expectedCall is a tool call an eval case expects.
+type expectedCall struct {
+	Name      string          `json:"name"`
+	Arguments json.RawMessage `json:"arguments"`
+}
+
+// evalPreset is a named adapter configuration under evaluation.
+type evalPreset struct {
+	name    string
+	adapter *tooladapter.Adapter
+}
+
+// evalReport scores one preset over the dataset.
+type evalReport struct {
+	Preset     string   `json:"preset"`
+	Cases      int      `json:"cases"`
+	ExactMatch float64  `json:"exact_match"`
+	Precision  float64  `json:"precision"`
+	Recall     float64  `json:"recall"`
+	F1         float64  `json:"f1"`
+	Errors     int      `json:"errors"`
+	Mismatched []string `json:"mismatched,omitempty"`
+}
+
+// runEval executes the eval subcommand and returns its exit status.
+func runEval(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	var cfg evalConfig
+	fs := newEvalFlagSet(&cfg, stderr)
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return exitOK
+		}
+		return exitUsage
+	}
+	if fs.NArg() > 0 {
+		fmt.Fprintf(stderr, "tooladapter eval: unexpected arguments %q; the dataset is read from stdin\n", fs.Args())
+		return exitUsage
+	}
+	if cfg.baseURL == "" || cfg.model == "" {
+		fmt.Fprintln(stderr, "tooladapter eval: -base-url and -model are required")
+		return exitUsage
+	}
+
+	presets, err := cfg.loadPresets(stderr)
+	if err != nil {
+		fmt.Fprintf(stderr, "tooladapter eval: %v\n", err)
+		return exitUsage
+	}
+	cases, err := readDataset(stdin)
+	if err != nil {
+		fmt.Fprintf(stderr, "tooladapter eval: %v\n", err)
+		return exitUsage
+	}
+
+	client := openai.NewClient(option.WithBaseURL(cfg.baseURL), option.WithAPIKey(cfg.apiKey))
+	reports := make([]evalReport, 0, len(presets))
+	failed := false
+	for _, preset := range presets {
+		report := evaluate(&client.Chat.Completions, cfg, preset, cases, stderr)
+		failed = failed || report.Errors > 0
+		reports = append(reports, report)
+	}
+
+	enc := json.NewEncoder(stdout)
+	if !cfg.compact {
+		enc.SetIndent("", "  ")
+	}
+	if err := enc.Encode(reports); err != nil {
+		fmt.Fprintf(stderr, "tooladapter eval: %v\n", err)
+		return exitTransform
+	}
+	if failed {
+		return exitTransform
+	}
+	return exitOK
+}
+
+// newEvalFlagSet defines the eval command line flags on cfg.
+func newEvalFlagSet(cfg *evalConfig, stderr io.Writer) *flag.FlagSet {
+	fs := flag.NewFlagSet("tooladapter eval", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	fs.Usage = func() {
+		fmt.Fprint(stderr, `Usage: tooladapter eval -base-url URL -model MODEL [-presets presets.json] < dataset.jsonl
+
+Sends every case of a JSON lines dataset through the adapter to an endpoint once
+per preset and scores the tool calls against the expected ones. Each dataset line
+holds "id", "messages", "tools" and "expected_calls", a list of {"name", "arguments"}
+objects. The presets file maps preset names to adapter configurations in the
+LoadConfig format; without it a single "default" preset is evaluated.
+
+Prints one report per preset with the share of cases whose calls match exactly and
+the micro-averaged precision, recall and F1 over call names and top-level arguments.
+
+Flags:
+`)
+		fs.PrintDefaults()
+	}
+
+	fs.StringVar(&cfg.presets, "presets", "", "JSON file mapping preset names to adapter configurations")
+	fs.StringVar(&cfg.baseURL, "base-url", "", "OpenAI-compatible API base URL, such as http://localhost:8000/v1")
+	fs.StringVar(&cfg.apiKey, "api-key", os.Getenv("OPENAI_API_KEY"), "API key, defaulting to $OPENAI_API_KEY")
+	fs.StringVar(&cfg.model, "model", "", "model to send the cases to")
+	fs.DurationVar(&cfg.timeout, "timeout", time.Minute, "timeout for each request")
+	fs.BoolVar(&cfg.compact, "compact", false, "print compact instead of indented JSON")
+	fs.StringVar(&cfg.logLevel, "log-level", "warn", "adapter log level on stderr: debug, info, warn, error or off")
+	return fs
+}
+
+// loadPresets builds an adapter for each preset, sorted by name.
+func (cfg *evalConfig) loadPresets(stderr io.Writer) ([]evalPreset, error) {
+	logger, err := newLogger(cfg.logLevel, stderr)
+	if err != nil {
+		return nil, err
+	}
+	configs := map[string]json.RawMessage{"default": json.RawMessage("{}")}
+	if cfg.presets != "" {
+		data, err := os.ReadFile(cfg.presets)
+		if err != nil {
+			return nil, err
+		}
+		configs = nil
+		if err := json.Unmarshal(data, &configs); err != nil {
+			return nil, fmt.Errorf("%s: %w", cfg.presets, err)
+		}
+		if len(configs) == 0 {
+			return nil, fmt.Errorf("%s: no presets", cfg.presets)
+		}
+	}
+
+	presets := make([]evalPreset, 0, len(configs))
+	for name, raw := range configs {
+		adapterCfg, err := tooladapter.LoadConfig(bytes.NewReader(raw))
+		if err != nil {
+			return nil, fmt.Errorf("preset %q: %w", name, err)
+		}
+		adapter, err := tooladapter.NewFromConfig(adapterCfg, tooladapter.WithLogger(logger.With("preset", name)))
+		if err != nil {
+			return nil, fmt.Errorf("preset %q: %w", name, err)
+		}
+		presets = append(presets, evalPreset{name: name, adapter: adapter})
+	}
+	sort.Slice(presets, func(i, j int) bool { return presets[i].name < presets[j].name })
+	return presets, nil
+}
+
+// readDataset decodes a JSON lines dataset, skipping blank lines.
+func readDataset(r io.Reader) ([]evalCase, error) {
+	var cases []evalCase
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		text := bytes.TrimSpace(scanner.Bytes())
+		if len(text) == 0 {
+			continue
+		}
+		var c evalCase
+		if err := json.Unmarshal(text, &c); err != nil {
+			return nil, fmt.Errorf("dataset line %d: %w", line, err)
+		}
+		if len(c.Messages) == 0 {
+			return nil, fmt.Errorf("dataset line %d: no messages", line)
+		}
+		if c.ID == "" {
+			c.ID = fmt.Sprintf("line %d", line)
+		}
+		cases = append(cases, c)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if len(cases) == 0 {
+		return nil, errors.New("empty dataset")
+	}
+	return cases, nil
+}
+
+// evaluate runs every case with preset and scores the result. Failed requests are
+// reported on stderr and score as cases without calls.
+func evaluate(client tooladapter.ChatCompletionsClient, cfg evalConfig, preset evalPreset, cases []evalCase, stderr io.Writer) evalReport {
+	report := evalReport{Preset: preset.name, Cases: len(cases)}
+	var exact int
+	var totals itemCounts
+	for _, c := range cases {
+		got, err := runCase(client, cfg, preset.adapter, c)
+		if err != nil {
+			fmt.Fprintf(stderr, "tooladapter eval: preset %q, case %q: %v\n", preset.name, c.ID, err)
+			report.Errors++
+		}
+		counts, match := scoreCalls(got, c.ExpectedCalls)
+		totals.add(counts)
+		if match && err == nil {
+			exact++
+		} else {
+			report.Mismatched = append(report.Mismatched, c.ID)
+		}
+	}
+	report.ExactMatch = float64(exact) / float64(len(cases))
+	report.Precision, report.Recall, report.F1 = totals.scores()
+	return report
+}
+
+// runCase sends one case through adapter and returns the tool calls of the reply.
+func runCase(client tooladapter.ChatCompletionsClient, cfg evalConfig, adapter *tooladapter.Adapter, c evalCase) ([]openai.ChatCompletionMessageToolCallUnion, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), cfg.timeout)
+	defer cancel()
+
+	session := adapter.NewSession()
+	req, err := session.TransformRequest(ctx, openai.ChatCompletionNewParams{
+		Model:    cfg.model,
+		Messages: c.Messages,
+		Tools:    c.Tools,
+	})
+	if err != nil {
+		return nil, err
+	}
+	completion, err := client.New(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := session.TransformResponse(ctx, *completion)
+	if err != nil {
+		return nil, err
+	}
+	if len(resp.Choices) == 0 {
+		return nil, nil
+	}
+	return resp.Choices[0].Message.ToolCalls, nil
+}
+
+// itemCounts accumulates scored items: one per call name and one per top-level
+// argument of each call.
+type itemCounts struct {
+	matched, predicted, expected int
+}
+
+func (c *itemCounts) add(other itemCounts) {
+	c.matched += other.matched
+	c.predicted += other.predicted
+	c.expected += other.expected
+}
+
+// scores returns precision, recall and F1. Precision is 1 without predicted items
+// and recall is 1 without expected items.
+func (c itemCounts) scores() (precision, recall, f1 float64) {
+	precision, recall = 1, 1
+	if c.predicted > 0 {
+		precision = float64(c.matched) / float64(c.predicted)
+	}
+	if c.expected > 0 {
+		recall = float64(c.matched) / float64(c.expected)
+	}
+	if precision+recall > 0 {
+		f1 = 2 * precision * recall / (precision + recall)
+	}
+	return precision, recall, f1
+}
+
+// scoreCalls compares the calls of a reply with the expected ones. It returns the
+// item counts and whether both hold the same calls, in any order.
+func scoreCalls(got []openai.ChatCompletionMessageToolCallUnion, want []expectedCall) (itemCounts, bool) {
+	gotCalls := make([]string, len(got))
+	gotItems := map[string]int{}
+	var counts itemCounts
+	for i, call := range got {
+		gotCalls[i] = call.Function.Name + canonicalJSON([]byte(call.Function.Arguments))
+		for _, item := range callItems(call.Function.Name, []byte(call.Function.Arguments)) {
+			gotItems[item]++
+			counts.predicted++
+		}
+	}
+	wantCalls := make([]string, len(want))
+	for i, call := range want {
+		wantCalls[i] = call.Name + canonicalJSON(call.Arguments)
+		for _, item := range callItems(call.Name, call.Arguments) {
+			counts.expected++
+			if gotItems[item] > 0 {
+				gotItems[item]--
+				counts.matched++
+			}
+		}
+	}
+
+	sort.Strings(gotCalls)
+	sort.Strings(wantCalls)
+	return counts, strings.Join(gotCalls, "\n") == strings.Join(wantCalls, "\n")
+}
+
+// callItems returns the scored items of a call: its name, and its name with each
+// top-level argument and canonical value.
+func callItems(name string, arguments []byte) []string {
+	items := []string{name}
+	var args map[string]json.RawMessage
+	if json.Unmarshal(arguments, &args) != nil {
+		return items
+	}
+	for key, value := range args {
+		items = append(items, name+"\x00"+key+"\x00"+canonicalJSON(value))
+	}
+	return items
+}
+
+// canonicalJSON re-encodes a JSON value with sorted keys. Empty values are treated
+// as null and invalid JSON is kept as is.
+func canonicalJSON(data []byte) string {
+	trimmed := bytes.TrimSpace(data)
+	if len(trimmed) == 0 {
+		return "null"
+	}
+	var value any
+	if json.Unmarshal(trimmed, &value) != nil {
+		return string(trimmed)
+	}
+	canonical, err := json.Marshal(value)
+	if err != nil {
+		return string(trimmed)
+	}
+	return string(canonical)
+}
@@ -0,0 +1,147 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/juburr/openai-tool-adapter/v3/tooltest"
+	"github.com/openai/openai-go/v3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// evalServer answers each request with the reply whose key a user message contains.
+func evalServer(t *testing.T, replies map[string]string) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			Messages []struct {
+				Role    string `json:"role"`
+				Content string `json:"content"`
+			} `json:"messages"`
+		}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+		reply := "I cannot help with that."
+		for _, msg := range req.Messages {
+			for prompt, content := range replies {
+				if msg.Role == "user" && strings.Contains(msg.Content, prompt) {
+					reply = content
+				}
+			}
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(tooltest.Completion(reply))
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+// datasetLine formats an eval case as a dataset line.
+func datasetLine(t *testing.T, id, prompt, expected string) string {
+	t.Helper()
+	return mustJSON(t, map[string]any{
+		"id":             id,
+		"messages":       []openai.ChatCompletionMessageParamUnion{openai.UserMessage(prompt)},
+		"tools":          []openai.ChatCompletionToolUnionParam{tooltest.Tool("get_weather", ""), tooltest.Tool("get_time", "")},
+		"expected_calls": json.RawMessage(expected),
+	}) + "\n"
+}
+
+func TestRunEval(t *testing.T) {
+	server := evalServer(t, map[string]string{
+		"Weather in Paris?":         `{"name": "get_weather", "parameters": {"city": "Paris"}}`,
+		"Weather and time in Oslo?": `[{"name": "get_weather", "parameters": {"city": "Oslo"}}, {"name": "get_time", "parameters": {"zone": "CET"}}]`,
+	})
+	dataset := datasetLine(t, "single", "Weather in Paris?", `[{"name": "get_weather", "arguments": {"city": "Paris"}}]`) +
+		"\n" +
+		datasetLine(t, "parallel", "Weather and time in Oslo?",
+			`[{"name": "get_time", "arguments": {"zone": "CET"}}, {"name": "get_weather", "arguments": {"city": "Oslo"}}]`)
+	presets := filepath.Join(t.TempDir(), "presets.json")
+	require.NoError(t, os.WriteFile(presets, []byte(`{"first": {}, "all": {"tool_policy": "drain_all"}}`), 0o600))
+
+	status, stdout, stderr := runWith(t, dataset, "eval", "-base-url", server.URL, "-model", "gemma-3", "-presets", presets)
+	require.Equal(t, exitOK, status, stderr)
+
+	var reports []evalReport
+	require.NoError(t, json.Unmarshal([]byte(stdout), &reports))
+	require.Len(t, reports, 2)
+
+	assert.Equal(t, evalReport{Preset: "all", Cases: 2, ExactMatch: 1, Precision: 1, Recall: 1, F1: 1}, reports[0])
+
+	first := reports[1]
+	assert.Equal(t, "first", first.Preset)
+	assert.InDelta(t, 0.5, first.ExactMatch, 1e-9)
+	assert.InDelta(t, 1, first.Precision, 1e-9)
+	assert.InDelta(t, 4.0/6, first.Recall, 1e-9, "the second call and its argument are missed")
+	assert.InDelta(t, 0.8, first.F1, 1e-9)
+	assert.Equal(t, []string{"parallel"}, first.Mismatched)
+}
+
+func TestRunEvalRequestError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		http.Error(w, `{"error": {"message": "model not found"}}`, http.StatusNotFound)
+	}))
+	defer server.Close()
+
+	dataset := datasetLine(t, "single", "Weather in Paris?", `[{"name": "get_weather", "arguments": {"city": "Paris"}}]`)
+	status, stdout, stderr := runWith(t, dataset, "eval", "-base-url", server.URL, "-model", "gemma-3")
+	assert.Equal(t, exitTransform, status)
+	assert.Contains(t, stderr, `case "single"`)
+
+	var reports []evalReport
+	require.NoError(t, json.Unmarshal([]byte(stdout), &reports))
+	require.Len(t, reports, 1)
+	assert.Equal(t, "default", reports[0].Preset)
+	assert.Equal(t, 1, reports[0].Errors)
+	assert.Zero(t, reports[0].ExactMatch)
+}
+
+func TestRunEvalUsageErrors(t *testing.T) {
+	dataset := datasetLine(t, "single", "Hi", `[]`)
+	badPresets := filepath.Join(t.TempDir(), "presets.json")
+	require.NoError(t, os.WriteFile(badPresets, []byte(`{"typo": {"tool_polcy": "drain_all"}}`), 0o600))
+
+	tests := []struct {
+		name  string
+		input string
+		args  []string
+	}{
+		{"MissingModel", dataset, []string{"-base-url", "http://localhost"}},
+		{"EmptyDataset", "\n", []string{"-base-url", "http://localhost", "-model", "m"}},
+		{"MalformedDataset", "{", []string{"-base-url", "http://localhost", "-model", "m"}},
+		{"NoMessages", `{"id": "x"}`, []string{"-base-url", "http://localhost", "-model", "m"}},
+		{"InvalidPreset", dataset, []string{"-base-url", "http://localhost", "-model", "m", "-presets", badPresets}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			status, stdout, stderr := runWith(t, tt.input, append([]string{"eval"}, tt.args...)...)
+			assert.Equal(t, exitUsage, status)
+			assert.Empty(t, stdout)
+			assert.NotEmpty(t, stderr)
+		})
+	}
+}
+
+func TestScoreCalls(t *testing.T) {
+	got := []openai.ChatCompletionMessageToolCallUnion{
+		{Function: openai.ChatCompletionMessageFunctionToolCallFunction{Name: "search", Arguments: `{"query": "go", "limit": 5}`}},
+	}
+
+	counts, match := scoreCalls(got, []expectedCall{{Name: "search", Arguments: json.RawMessage(`{"limit": 5, "query": "go"}`)}})
+	assert.True(t, match)
+	assert.Equal(t, itemCounts{matched: 3, predicted: 3, expected: 3}, counts)
+
+	counts, match = scoreCalls(got, []expectedCall{{Name: "search", Arguments: json.RawMessage(`{"query": "golang"}`)}})
+	assert.False(t, match)
+	assert.Equal(t, itemCounts{matched: 1, predicted: 3, expected: 2}, counts)
+
+	counts, match = scoreCalls(nil, nil)
+	assert.True(t, match)
+	precision, recall, f1 := counts.scores()
+	assert.Equal(t, []float64{1, 1, 1}, []float64{precision, recall, f1})
+}
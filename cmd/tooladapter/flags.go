@@ -0,0 +1,212 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+
+	tooladapter "github.com/juburr/openai-tool-adapter/v3"
+)
+
+// config holds the parsed command line.
+type config struct {
+	input    string
+	tools    string
+	summary  bool
+	compact  bool
+	logLevel string
+
+	systemMessages     bool
+	promptTemplate     string
+	toolPromptTemplate string
+	language           string
+	namespace          string
+	collisionPolicy    string
+	allowedTools       string
+	unknownToolPolicy  string
+	violationPolicy    string
+	toolPolicy         string
+	maxCalls           int
+	stopSequences      string
+	lenient            bool
+	coerce             bool
+	dedupe             bool
+	sanitize           bool
+	markers            bool
+	compaction         bool
+}
+
+// newFlagSet defines the command line flags on cfg.
+func newFlagSet(cfg *config, stderr io.Writer) *flag.FlagSet {
+	fs := flag.NewFlagSet("tooladapter", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	fs.Usage = func() {
+		fmt.Fprint(stderr, `Usage: tooladapter [flags] < input
+       tooladapter eval [flags] < dataset.jsonl
+
+Reads a chat completion request, a chat completion response, an SSE capture of a
+streamed completion or a WithStreamRecorder transcript from stdin and prints what
+the adapter makes of it. Run "tooladapter eval -h" for the eval subcommand.
+
+Flags:
+`)
+		fs.PrintDefaults()
+	}
+
+	fs.StringVar(&cfg.input, "input", "auto", "input kind: auto, request, response, sse or transcript")
+	fs.StringVar(&cfg.tools, "tools", "", "JSON file with the request's tools, for argument coercion and checks on responses")
+	fs.BoolVar(&cfg.summary, "summary", false, "print a summary of streams instead of their chunks")
+	fs.BoolVar(&cfg.compact, "compact", false, "print compact instead of indented JSON")
+	fs.StringVar(&cfg.logLevel, "log-level", "warn", "adapter log level on stderr: debug, info, warn, error or off")
+
+	fs.BoolVar(&cfg.systemMessages, "system-messages", false, "the model supports system messages (WithSystemMessageSupport)")
+	fs.StringVar(&cfg.promptTemplate, "prompt-template", "", "file with a printf-style prompt template (WithCustomPromptTemplate)")
+	fs.StringVar(&cfg.toolPromptTemplate, "tool-prompt-template", "", "file with a text/template prompt (WithToolPromptTemplate)")
+	fs.StringVar(&cfg.language, "language", "", "prompt language code (WithPromptLanguage)")
+	fs.StringVar(&cfg.namespace, "namespace", "", "tool name prefix (WithToolNamespace)")
+	fs.StringVar(&cfg.collisionPolicy, "collision-policy", "error", "duplicate tool names: error or rename (WithToolCollisionPolicy)")
+	fs.StringVar(&cfg.allowedTools, "allowed-tools", "", "comma-separated tool names to accept (WithAllowedToolNames)")
+	fs.StringVar(&cfg.unknownToolPolicy, "unknown-tool-policy", "drop", "calls to other tools: drop, content, error or correct (WithUnknownToolPolicy)")
+	fs.StringVar(&cfg.violationPolicy, "argument-violation-policy", "ignore", "schema violations: ignore, report or error (WithArgumentViolationPolicy)")
+	fs.StringVar(&cfg.toolPolicy, "tool-policy", "stop-on-first", "streaming: stop-on-first, collect-then-stop, drain-all, allow-mixed or emit-and-continue (WithToolPolicy)")
+	fs.IntVar(&cfg.maxCalls, "max-calls", 0, "maximum tool calls per response, 0 for the default (WithToolMaxCalls)")
+	fs.StringVar(&cfg.stopSequences, "stop-sequences", "", "comma-separated stop sequences for requests with tools (WithToolStopSequences)")
+	fs.BoolVar(&cfg.lenient, "lenient", false, "accept JSON5 and YAML tool calls (WithLenientParsing)")
+	fs.BoolVar(&cfg.coerce, "coerce", false, "coerce arguments to the -tools schemas (WithArgumentCoercion)")
+	fs.BoolVar(&cfg.dedupe, "dedupe", false, "collapse repeated identical calls (WithToolCallDeduplication)")
+	fs.BoolVar(&cfg.sanitize, "sanitize", false, "sanitize tool definitions (WithSanitizeToolDefinitions)")
+	fs.BoolVar(&cfg.markers, "markers", false, "wrap injected prompts in the default markers (WithInjectionMarkers)")
+	fs.BoolVar(&cfg.compaction, "compaction", false, "remove marked blocks from history (WithPromptCompaction)")
+	return fs
+}
+
+// options converts the flags to adapter options.
+func (cfg *config) options(stderr io.Writer) ([]tooladapter.Option, error) {
+	logger, err := newLogger(cfg.logLevel, stderr)
+	if err != nil {
+		return nil, err
+	}
+	opts := []tooladapter.Option{
+		tooladapter.WithLogger(logger),
+		tooladapter.WithSystemMessageSupport(cfg.systemMessages),
+		tooladapter.WithLenientParsing(cfg.lenient),
+		tooladapter.WithArgumentCoercion(cfg.coerce),
+		tooladapter.WithToolCallDeduplication(cfg.dedupe),
+		tooladapter.WithSanitizeToolDefinitions(cfg.sanitize),
+		tooladapter.WithPromptCompaction(cfg.compaction),
+	}
+
+	if cfg.promptTemplate != "" {
+		text, err := os.ReadFile(cfg.promptTemplate)
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts, tooladapter.WithCustomPromptTemplate(string(text)))
+	}
+	if cfg.toolPromptTemplate != "" {
+		text, err := os.ReadFile(cfg.toolPromptTemplate)
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts, tooladapter.WithToolPromptTemplate(string(text)))
+	}
+	if cfg.language != "" {
+		opts = append(opts, tooladapter.WithPromptLanguage(cfg.language))
+	}
+	if cfg.namespace != "" {
+		opts = append(opts, tooladapter.WithToolNamespace(cfg.namespace))
+	}
+	if names := splitList(cfg.allowedTools); len(names) > 0 {
+		opts = append(opts, tooladapter.WithAllowedToolNames(names))
+	}
+	if sequences := splitList(cfg.stopSequences); len(sequences) > 0 {
+		opts = append(opts, tooladapter.WithToolStopSequences(sequences...))
+	}
+	if cfg.maxCalls > 0 {
+		opts = append(opts, tooladapter.WithToolMaxCalls(cfg.maxCalls))
+	}
+	if cfg.markers {
+		opts = append(opts, tooladapter.WithInjectionMarkers(tooladapter.DefaultInjectionBeginMarker, tooladapter.DefaultInjectionEndMarker))
+	}
+
+	collision, err := parseChoice("collision-policy", cfg.collisionPolicy, map[string]tooladapter.ToolCollisionPolicy{
+		"error":  tooladapter.ToolCollisionError,
+		"rename": tooladapter.ToolCollisionRename,
+	})
+	if err != nil {
+		return nil, err
+	}
+	unknown, err := parseChoice("unknown-tool-policy", cfg.unknownToolPolicy, map[string]tooladapter.UnknownToolPolicy{
+		"drop":    tooladapter.UnknownToolDrop,
+		"content": tooladapter.UnknownToolAsContent,
+		"error":   tooladapter.UnknownToolError,
+		"correct": tooladapter.UnknownToolCorrect,
+	})
+	if err != nil {
+		return nil, err
+	}
+	violations, err := parseChoice("argument-violation-policy", cfg.violationPolicy, map[string]tooladapter.ArgumentViolationPolicy{
+		"ignore": tooladapter.ArgumentViolationIgnore,
+		"report": tooladapter.ArgumentViolationReport,
+		"error":  tooladapter.ArgumentViolationError,
+	})
+	if err != nil {
+		return nil, err
+	}
+	policy, err := parseChoice("tool-policy", cfg.toolPolicy, map[string]tooladapter.ToolPolicy{
+		"stop-on-first":     tooladapter.ToolStopOnFirst,
+		"collect-then-stop": tooladapter.ToolCollectThenStop,
+		"drain-all":         tooladapter.ToolDrainAll,
+		"allow-mixed":       tooladapter.ToolAllowMixed,
+		"emit-and-continue": tooladapter.ToolEmitAndContinue,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return append(opts,
+		tooladapter.WithToolCollisionPolicy(collision),
+		tooladapter.WithUnknownToolPolicy(unknown),
+		tooladapter.WithArgumentViolationPolicy(violations),
+		tooladapter.WithToolPolicy(policy),
+	), nil
+}
+
+// newLogger returns a text logger writing to stderr at the named level.
+func newLogger(level string, stderr io.Writer) (*slog.Logger, error) {
+	levels := map[string]slog.Level{
+		"debug": slog.LevelDebug,
+		"info":  slog.LevelInfo,
+		"warn":  slog.LevelWarn,
+		"error": slog.LevelError,
+		"off":   slog.LevelError + 1,
+	}
+	lvl, err := parseChoice("log-level", level, levels)
+	if err != nil {
+		return nil, err
+	}
+	return slog.New(slog.NewTextHandler(stderr, &slog.HandlerOptions{Level: lvl})), nil
+}
+
+// parseChoice looks up the value of an enumerated flag.
+func parseChoice[T any](name, value string, choices map[string]T) (T, error) {
+	if choice, ok := choices[strings.ToLower(value)]; ok {
+		return choice, nil
+	}
+	var zero T
+	return zero, fmt.Errorf("invalid -%s %q", name, value)
+}
+
+// splitList splits a comma-separated flag value, dropping empty items.
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
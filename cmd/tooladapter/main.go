@@ -0,0 +1,338 @@
+// Command tooladapter runs the adapter offline on a captured request, response or
+// stream, for debugging model output samples without a model server:
+//
+//	tooladapter < request.json                 # print the transformed request
+//	tooladapter -tools tools.json < resp.json  # print the parsed tool calls
+//	tooladapter -summary < capture.sse         # summarize a streamed completion
+//
+// The input kind is detected from stdin: a JSON object with "messages" is a request,
+// one with "choices" a response, "data:" lines an SSE capture and JSON lines with a
+// "direction" field a WithStreamRecorder transcript. Flags mirror the adapter options;
+// run with -h for the list. Adapter warnings are logged to stderr.
+//
+// The eval subcommand scores adapter configurations against a live endpoint, to
+// choose prompt presets and options with data:
+//
+//	tooladapter eval -base-url http://localhost:8000/v1 -model gemma-3 \
+//	    -presets presets.json < dataset.jsonl
+//
+// The exit status is 0 on success, 1 when a transformation or eval request fails
+// and 2 for usage errors and unreadable input.
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	tooladapter "github.com/juburr/openai-tool-adapter/v3"
+	"github.com/openai/openai-go/v3"
+)
+
+// Input kinds.
+const (
+	inputRequest    = "request"
+	inputResponse   = "response"
+	inputSSE        = "sse"
+	inputTranscript = "transcript"
+)
+
+// Exit statuses.
+const (
+	exitOK        = 0
+	exitTransform = 1
+	exitUsage     = 2
+)
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdin, os.Stdout, os.Stderr))
+}
+
+// run executes the command and returns its exit status.
+func run(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	if len(args) > 0 && args[0] == "eval" {
+		return runEval(args[1:], stdin, stdout, stderr)
+	}
+
+	var cfg config
+	fs := newFlagSet(&cfg, stderr)
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return exitOK
+		}
+		return exitUsage
+	}
+	if fs.NArg() > 0 {
+		fmt.Fprintf(stderr, "tooladapter: unexpected arguments %q; the input is read from stdin\n", fs.Args())
+		return exitUsage
+	}
+
+	opts, err := cfg.options(stderr)
+	if err != nil {
+		fmt.Fprintf(stderr, "tooladapter: %v\n", err)
+		return exitUsage
+	}
+	input, err := io.ReadAll(stdin)
+	if err != nil {
+		fmt.Fprintf(stderr, "tooladapter: failed to read input: %v\n", err)
+		return exitUsage
+	}
+	kind := cfg.input
+	if kind == "auto" {
+		if kind = detectInput(input); kind == "" {
+			fmt.Fprintln(stderr, "tooladapter: cannot detect the input kind; use -input")
+			return exitUsage
+		}
+	}
+
+	cmd := &command{
+		cfg:     cfg,
+		adapter: tooladapter.New(opts...),
+		stdout:  stdout,
+	}
+	switch kind {
+	case inputRequest:
+		err = cmd.transformRequest(input)
+	case inputResponse:
+		err = cmd.transformResponse(input)
+	case inputSSE:
+		err = cmd.transformSSE(input)
+	case inputTranscript:
+		err = cmd.transformTranscript(input)
+	default:
+		fmt.Fprintf(stderr, "tooladapter: invalid -input %q\n", kind)
+		return exitUsage
+	}
+
+	var inputErr *inputError
+	switch {
+	case errors.As(err, &inputErr):
+		fmt.Fprintf(stderr, "tooladapter: %v\n", err)
+		return exitUsage
+	case err != nil:
+		fmt.Fprintf(stderr, "tooladapter: %v\n", err)
+		return exitTransform
+	}
+	return exitOK
+}
+
+// inputError reports input that cannot be decoded.
+type inputError struct {
+	err error
+}
+
+func (e *inputError) Error() string { return "invalid input: " + e.err.Error() }
+func (e *inputError) Unwrap() error { return e.err }
+
+// detectInput guesses the kind of input, returning "" when it cannot.
+func detectInput(input []byte) string {
+	trimmed := bytes.TrimSpace(input)
+	for _, prefix := range []string{"data:", "event:", "id:", ":"} {
+		if bytes.HasPrefix(trimmed, []byte(prefix)) {
+			return inputSSE
+		}
+	}
+
+	var fields map[string]json.RawMessage
+	if err := json.NewDecoder(bytes.NewReader(trimmed)).Decode(&fields); err != nil {
+		return ""
+	}
+	switch {
+	case fields["direction"] != nil:
+		return inputTranscript
+	case fields["messages"] != nil:
+		return inputRequest
+	case fields["choices"] != nil:
+		return inputResponse
+	default:
+		return ""
+	}
+}
+
+// command transforms one input.
+type command struct {
+	cfg     config
+	adapter *tooladapter.Adapter
+	stdout  io.Writer
+}
+
+func (c *command) transformRequest(input []byte) error {
+	var req openai.ChatCompletionNewParams
+	if err := json.Unmarshal(input, &req); err != nil {
+		return &inputError{err}
+	}
+	transformed, err := c.adapter.TransformCompletionsRequest(req)
+	if err != nil {
+		return err
+	}
+	return c.printJSON(transformed)
+}
+
+func (c *command) transformResponse(input []byte) error {
+	var resp openai.ChatCompletion
+	if err := json.Unmarshal(input, &resp); err != nil {
+		return &inputError{err}
+	}
+	ctx, err := c.toolsContext()
+	if err != nil {
+		return err
+	}
+	transformed, err := c.adapter.TransformCompletionsResponseWithContext(ctx, resp)
+	if err != nil {
+		return err
+	}
+	return c.printJSON(transformed)
+}
+
+func (c *command) transformSSE(input []byte) error {
+	reader := tooladapter.NewSSEReaderFromReadCloser(io.NopCloser(bytes.NewReader(input)))
+	var chunks []openai.ChatCompletionChunk
+	for reader.Next() {
+		var chunk openai.ChatCompletionChunk
+		if err := json.Unmarshal([]byte(reader.Data()), &chunk); err != nil {
+			return &inputError{fmt.Errorf("event %d: %w", len(chunks)+1, err)}
+		}
+		chunks = append(chunks, chunk)
+	}
+	if err := reader.Err(); err != nil {
+		return &inputError{err}
+	}
+	return c.transformStream(&chunkStream{chunks: chunks, index: -1})
+}
+
+func (c *command) transformTranscript(input []byte) error {
+	stream, err := tooladapter.ReplayStream(bytes.NewReader(input))
+	if err != nil {
+		return &inputError{err}
+	}
+	return c.transformStream(stream)
+}
+
+// transformStream prints the transformed stream as SSE events, or its summary.
+func (c *command) transformStream(upstream tooladapter.ChatCompletionStreamInterface) error {
+	ctx, err := c.toolsContext()
+	if err != nil {
+		return err
+	}
+	stream := c.adapter.TransformStreamingResponseWithContext(ctx, upstream)
+	defer func() { _ = stream.Close() }()
+
+	var summary streamSummary
+	out := bufio.NewWriter(c.stdout)
+	for stream.Next() {
+		chunk := stream.Current()
+		if c.cfg.summary {
+			summary.add(chunk)
+			continue
+		}
+		data, err := json.Marshal(chunk)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(out, "data: %s\n\n", data)
+	}
+	if c.cfg.summary {
+		if err := c.printJSON(summary); err != nil {
+			return err
+		}
+		return stream.Err()
+	}
+	// Like WriteSSEStream, a failed stream ends without [DONE]
+	if err := stream.Err(); err != nil {
+		_ = out.Flush()
+		return err
+	}
+	fmt.Fprint(out, "data: [DONE]\n\n")
+	return out.Flush()
+}
+
+// toolsContext attaches the -tools file to a context.
+func (c *command) toolsContext() (context.Context, error) {
+	ctx := context.Background()
+	if c.cfg.tools == "" {
+		return ctx, nil
+	}
+	data, err := os.ReadFile(c.cfg.tools)
+	if err != nil {
+		return nil, &inputError{err}
+	}
+	var tools []openai.ChatCompletionToolUnionParam
+	if err := json.Unmarshal(data, &tools); err != nil {
+		return nil, &inputError{fmt.Errorf("%s: %w", c.cfg.tools, err)}
+	}
+	return tooladapter.ContextWithTools(ctx, tools), nil
+}
+
+func (c *command) printJSON(v any) error {
+	enc := json.NewEncoder(c.stdout)
+	if !c.cfg.compact {
+		enc.SetIndent("", "  ")
+	}
+	return enc.Encode(v)
+}
+
+// streamSummary aggregates what a transformed stream emitted.
+type streamSummary struct {
+	Content       string        `json:"content"`
+	ToolCalls     []summaryCall `json:"tool_calls,omitempty"`
+	FinishReasons []string      `json:"finish_reasons,omitempty"`
+	Chunks        int           `json:"chunks"`
+}
+
+// summaryCall is a tool call assembled from its streamed deltas.
+type summaryCall struct {
+	index     int64
+	ID        string `json:"id"`
+	Name      string `json:"name"`
+	Arguments string `json:"arguments"`
+}
+
+func (s *streamSummary) add(chunk openai.ChatCompletionChunk) {
+	s.Chunks++
+	for _, choice := range chunk.Choices {
+		s.Content += choice.Delta.Content
+		if choice.FinishReason != "" {
+			s.FinishReasons = append(s.FinishReasons, choice.FinishReason)
+		}
+		for _, delta := range choice.Delta.ToolCalls {
+			n := len(s.ToolCalls)
+			if n == 0 || s.ToolCalls[n-1].index != delta.Index || delta.ID != "" && delta.ID != s.ToolCalls[n-1].ID {
+				s.ToolCalls = append(s.ToolCalls, summaryCall{index: delta.Index, ID: delta.ID})
+				n++
+			}
+			call := &s.ToolCalls[n-1]
+			call.Name += delta.Function.Name
+			call.Arguments += delta.Function.Arguments
+		}
+	}
+}
+
+// chunkStream replays the chunks of an SSE capture.
+type chunkStream struct {
+	chunks []openai.ChatCompletionChunk
+	index  int
+}
+
+func (s *chunkStream) Next() bool {
+	if s.index+1 >= len(s.chunks) {
+		return false
+	}
+	s.index++
+	return true
+}
+
+func (s *chunkStream) Current() openai.ChatCompletionChunk {
+	if s.index < 0 || s.index >= len(s.chunks) {
+		return openai.ChatCompletionChunk{}
+	}
+	return s.chunks[s.index]
+}
+
+func (s *chunkStream) Err() error   { return nil }
+func (s *chunkStream) Close() error { return nil }
@@ -0,0 +1,180 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	tooladapter "github.com/juburr/openai-tool-adapter/v3"
+	"github.com/juburr/openai-tool-adapter/v3/tooltest"
+	"github.com/openai/openai-go/v3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// runWith runs the command on input and returns its exit status and output.
+func runWith(t *testing.T, input string, args ...string) (int, string, string) {
+	t.Helper()
+	var stdout, stderr bytes.Buffer
+	status := run(args, strings.NewReader(input), &stdout, &stderr)
+	return status, stdout.String(), stderr.String()
+}
+
+// mustJSON marshals v for use as command input.
+func mustJSON(t *testing.T, v any) string {
+	t.Helper()
+	data, err := json.Marshal(v)
+	require.NoError(t, err)
+	return string(data)
+}
+
+// sseCapture formats chunks as a captured SSE stream.
+func sseCapture(t *testing.T, chunks ...openai.ChatCompletionChunk) string {
+	t.Helper()
+	var capture strings.Builder
+	for _, chunk := range chunks {
+		capture.WriteString("data: " + mustJSON(t, chunk) + "\n\n")
+	}
+	capture.WriteString("data: [DONE]\n\n")
+	return capture.String()
+}
+
+func TestDetectInput(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  string
+	}{
+		{"Request", `{"model": "gemma", "messages": []}`, inputRequest},
+		{"Response", `{"id": "1", "choices": []}`, inputResponse},
+		{"SSE", "\ndata: {}\n\n", inputSSE},
+		{"SSEComment", ": keep-alive\n\ndata: {}\n\n", inputSSE},
+		{"Transcript", `{"stream": 1, "seq": 1, "direction": "upstream"}` + "\n", inputTranscript},
+		{"UnknownObject", `{"foo": 1}`, ""},
+		{"NotJSON", "hello", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, detectInput([]byte(tt.input)))
+		})
+	}
+}
+
+func TestRunRequest(t *testing.T) {
+	input := mustJSON(t, tooltest.Request(tooltest.Tool("get_weather", "Get the weather")))
+
+	status, stdout, stderr := runWith(t, input, "-namespace", "weather")
+	require.Equal(t, exitOK, status, stderr)
+
+	var req openai.ChatCompletionNewParams
+	require.NoError(t, json.Unmarshal([]byte(stdout), &req))
+	assert.Empty(t, req.Tools)
+	assert.Contains(t, stdout, "weather.get_weather")
+	assert.Contains(t, stdout, "\n  ", "indented by default")
+}
+
+func TestRunResponse(t *testing.T) {
+	input := mustJSON(t, tooltest.Completion(`{"name": "get_weather", "parameters": {"param1": 42}}`))
+	toolsFile := filepath.Join(t.TempDir(), "tools.json")
+	require.NoError(t, os.WriteFile(toolsFile,
+		[]byte(mustJSON(t, []openai.ChatCompletionToolUnionParam{tooltest.Tool("get_weather", "")})), 0o600))
+
+	t.Run("ToolCall", func(t *testing.T) {
+		status, stdout, stderr := runWith(t, input, "-compact", "-coerce", "-tools", toolsFile)
+		require.Equal(t, exitOK, status, stderr)
+
+		var resp openai.ChatCompletion
+		require.NoError(t, json.Unmarshal([]byte(stdout), &resp))
+		calls := resp.Choices[0].Message.ToolCalls
+		require.Len(t, calls, 1)
+		assert.Equal(t, "get_weather", calls[0].Function.Name)
+		assert.JSONEq(t, `{"param1": "42"}`, calls[0].Function.Arguments, "coerced to the -tools schema")
+		assert.Equal(t, 1, strings.Count(stdout, "\n"), "compact output")
+	})
+
+	t.Run("TransformError", func(t *testing.T) {
+		status, _, stderr := runWith(t, input,
+			"-allowed-tools", "search", "-unknown-tool-policy", "error")
+		assert.Equal(t, exitTransform, status)
+		assert.Contains(t, stderr, "get_weather")
+	})
+}
+
+func TestRunStream(t *testing.T) {
+	capture := sseCapture(t,
+		tooltest.ContentChunk(`{"name": "get_weather", `),
+		tooltest.ContentChunk(`"parameters": {"param1": "Paris"}}`),
+		tooltest.FinishChunk("stop"))
+
+	t.Run("SSE", func(t *testing.T) {
+		status, stdout, stderr := runWith(t, capture)
+		require.Equal(t, exitOK, status, stderr)
+		assert.True(t, strings.HasSuffix(stdout, "data: [DONE]\n\n"))
+		assert.Contains(t, stdout, `"name":"get_weather"`)
+	})
+
+	t.Run("Summary", func(t *testing.T) {
+		status, stdout, stderr := runWith(t, capture, "-summary")
+		require.Equal(t, exitOK, status, stderr)
+
+		var summary struct {
+			Content   string `json:"content"`
+			ToolCalls []struct {
+				Name      string `json:"name"`
+				Arguments string `json:"arguments"`
+			} `json:"tool_calls"`
+			FinishReasons []string `json:"finish_reasons"`
+		}
+		require.NoError(t, json.Unmarshal([]byte(stdout), &summary))
+		assert.Empty(t, summary.Content)
+		require.Len(t, summary.ToolCalls, 1)
+		assert.Equal(t, "get_weather", summary.ToolCalls[0].Name)
+		assert.JSONEq(t, `{"param1": "Paris"}`, summary.ToolCalls[0].Arguments)
+		assert.Equal(t, "tool_calls", summary.FinishReasons[0])
+	})
+
+	t.Run("Transcript", func(t *testing.T) {
+		var transcript bytes.Buffer
+		adapter := tooladapter.New(tooladapter.WithStreamRecorder(&transcript))
+		tooltest.Drain(adapter.TransformStreamingResponse(tooltest.NewContentStream("Hello", " there")))
+
+		status, stdout, stderr := runWith(t, transcript.String(), "-summary")
+		require.Equal(t, exitOK, status, stderr)
+		assert.Contains(t, stdout, `"content": "Hello there"`)
+	})
+}
+
+func TestRunUsageErrors(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		args  []string
+	}{
+		{"UnknownFlag", "{}", []string{"-bogus"}},
+		{"BadPolicy", "{}", []string{"-tool-policy", "sometimes"}},
+		{"BadLogLevel", "{}", []string{"-log-level", "loud"}},
+		{"Arguments", "{}", []string{"request.json"}},
+		{"Undetectable", "hello", nil},
+		{"BadInputKind", "{}", []string{"-input", "yaml"}},
+		{"MalformedRequest", `{"messages": [`, []string{"-input", "request"}},
+		{"MissingToolsFile", `{"choices": []}`, []string{"-tools", "/nonexistent/tools.json"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			status, stdout, stderr := runWith(t, tt.input, tt.args...)
+			assert.Equal(t, exitUsage, status)
+			assert.Empty(t, stdout)
+			assert.NotEmpty(t, stderr)
+		})
+	}
+}
+
+func TestRunHelp(t *testing.T) {
+	status, _, stderr := runWith(t, "", "-h")
+	assert.Equal(t, exitOK, status)
+	assert.Contains(t, stderr, "Usage: tooladapter")
+	assert.Contains(t, stderr, "-unknown-tool-policy")
+}
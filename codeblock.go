@@ -0,0 +1,55 @@
+package tooladapter
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/openai/openai-go/v3"
+)
+
+// CodeBlockTool is the tool a WithCodeBlockTools language calls.
+type CodeBlockTool struct {
+	// Name is the caller's function name of the tool, such as "execute_python"
+	Name string `json:"name" yaml:"name"`
+
+	// Argument is the argument the code is passed as; empty means
+	// CodeBlockArgumentKey
+	Argument string `json:"argument,omitempty" yaml:"argument,omitempty"`
+}
+
+// CodeBlockArgumentKey is the default argument that holds the code of a
+// WithCodeBlockTools call.
+const CodeBlockArgumentKey = "code"
+
+// appendCodeBlockTools adds the instruction of the prompt language for running code
+// to a tool prompt, for every WithCodeBlockTools language whose tool is among tools,
+// the model-facing tools of a request.
+func (a *Adapter) appendCodeBlockTools(tools []openai.ChatCompletionToolUnionParam, toolPrompt string) string {
+	if len(a.codeBlockTools) == 0 {
+		return toolPrompt
+	}
+	offered := make(map[string]string, len(tools))
+	for _, tool := range tools {
+		if function := tool.GetFunction(); function != nil {
+			offered[a.restoreToolName(function.Name)] = function.Name
+		}
+	}
+
+	languages := make([]string, 0, len(a.codeBlockTools))
+	for language := range a.codeBlockTools {
+		languages = append(languages, language)
+	}
+	sort.Strings(languages)
+
+	var lines []string
+	for _, language := range languages {
+		if name, ok := offered[a.codeBlockTools[language].Name]; ok {
+			lines = append(lines, fmt.Sprintf(a.language().codeBlockTool, language, name))
+		}
+	}
+	if len(lines) == 0 {
+		return toolPrompt
+	}
+	return toolPrompt + "\n\n" + strings.Join(lines, "\n")
+}
@@ -0,0 +1,97 @@
+package tooladapter_test
+
+import (
+	"strings"
+	"testing"
+
+	tooladapter "github.com/juburr/openai-tool-adapter/v3"
+	"github.com/juburr/openai-tool-adapter/v3/tooltest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWithCodeBlockTools(t *testing.T) {
+	tools := map[string]tooladapter.CodeBlockTool{
+		"python": {Name: "execute_python"},
+		"SQL":    {Name: "run_query", Argument: "query"},
+	}
+	adapter := tooladapter.New(
+		tooladapter.WithSystemMessageSupport(true),
+		tooladapter.WithCodeBlockTools(tools))
+
+	t.Run("Prompt", func(t *testing.T) {
+		transformed, err := adapter.TransformCompletionsRequest(tooltest.Request(
+			tooltest.Tool("execute_python", "Run Python code"), tooltest.Tool("get_time", "Get the time")))
+		require.NoError(t, err)
+		prompt := systemPrompt(t, transformed)
+		assert.Contains(t, prompt, "To run python code, write it in a fenced code block tagged python instead of a JSON call; the code is passed to the function execute_python.")
+		assert.NotContains(t, prompt, "run_query", "the sql tool is not offered")
+	})
+
+	t.Run("Response", func(t *testing.T) {
+		resp, err := adapter.TransformCompletionsResponse(tooltest.Completion(
+			"Let me compute that.\n```python\nprint(\"total:\", sum(range(10)))\n```"))
+		require.NoError(t, err)
+		require.Len(t, resp.Choices[0].Message.ToolCalls, 1)
+		call := resp.Choices[0].Message.ToolCalls[0]
+		assert.Equal(t, "execute_python", call.Function.Name)
+		assert.JSONEq(t, `{"code": "print(\"total:\", sum(range(10)))"}`, call.Function.Arguments)
+	})
+
+	t.Run("ArgumentAndCase", func(t *testing.T) {
+		resp, err := adapter.TransformCompletionsResponse(tooltest.Completion("```Sql\nSELECT 1\n```"))
+		require.NoError(t, err)
+		require.Len(t, resp.Choices[0].Message.ToolCalls, 1)
+		assert.Equal(t, "run_query", resp.Choices[0].Message.ToolCalls[0].Function.Name)
+		assert.JSONEq(t, `{"query": "SELECT 1"}`, resp.Choices[0].Message.ToolCalls[0].Function.Arguments)
+	})
+
+	t.Run("OtherLanguagesAreContent", func(t *testing.T) {
+		content := "Run this:\n```bash\nls -la\n```"
+		resp, err := adapter.TransformCompletionsResponse(tooltest.Completion(content))
+		require.NoError(t, err)
+		assert.Empty(t, resp.Choices[0].Message.ToolCalls)
+		assert.Equal(t, content, resp.Choices[0].Message.Content)
+	})
+
+	t.Run("TextToolTakesPrecedence", func(t *testing.T) {
+		both := tooladapter.New(
+			tooladapter.WithCodeBlockTools(tools),
+			tooladapter.WithToolArgumentModes(map[string]tooladapter.ArgumentMode{"python": tooladapter.ArgumentModeText}))
+		resp, err := both.TransformCompletionsResponse(tooltest.Completion("```python\npass\n```"))
+		require.NoError(t, err)
+		require.Len(t, resp.Choices[0].Message.ToolCalls, 1)
+		assert.Equal(t, "python", resp.Choices[0].Message.ToolCalls[0].Function.Name)
+	})
+
+	t.Run("Namespace", func(t *testing.T) {
+		namespaced := tooladapter.New(tooladapter.WithCodeBlockTools(tools), tooladapter.WithToolNamespace("sandbox"))
+		resp, err := namespaced.TransformCompletionsResponse(tooltest.Completion("```python\npass\n```"))
+		require.NoError(t, err)
+		require.Len(t, resp.Choices[0].Message.ToolCalls, 1)
+		assert.Equal(t, "execute_python", resp.Choices[0].Message.ToolCalls[0].Function.Name)
+	})
+
+	t.Run("Streaming", func(t *testing.T) {
+		result := tooltest.Drain(adapter.TransformStreamingResponse(tooltest.NewContentStream(
+			"```python\n", "x = 1\n", "print(x)\n", "```")))
+		require.NoError(t, result.Err)
+		require.Len(t, result.ToolCalls, 1)
+		assert.Equal(t, "execute_python", result.ToolCalls[0].Function.Name)
+		assert.JSONEq(t, `{"code": "x = 1\nprint(x)"}`, result.ToolCalls[0].Function.Arguments)
+		assert.Empty(t, result.Content)
+	})
+
+	t.Run("InvalidEntriesIgnored", func(t *testing.T) {
+		invalid := tooladapter.New(tooladapter.WithCodeBlockTools(map[string]tooladapter.CodeBlockTool{"python": {}}))
+		resp, err := invalid.TransformCompletionsResponse(tooltest.Completion("```python\npass\n```"))
+		require.NoError(t, err)
+		assert.Empty(t, resp.Choices[0].Message.ToolCalls)
+	})
+
+	t.Run("Config", func(t *testing.T) {
+		cfg, err := tooladapter.LoadConfig(strings.NewReader(`{"code_block_tools": {"python": {"name": "execute_python"}}}`))
+		require.NoError(t, err)
+		assert.Equal(t, map[string]tooladapter.CodeBlockTool{"python": {Name: "execute_python"}}, cfg.CodeBlockTools)
+	})
+}
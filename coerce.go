@@ -0,0 +1,273 @@
+package tooladapter
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"strconv"
+	"strings"
+
+	"github.com/openai/openai-go/v3"
+)
+
+// toolSchemasKey is the context key for schemas attached by ContextWithTools.
+type toolSchemasKey struct{}
+
+// ContextWithTools returns a copy of ctx carrying the parameter schemas of tools, so
+// response transformation can coerce and check arguments against them (see
+// WithArgumentCoercion and WithArgumentViolationPolicy). Pass the tools of the original, untransformed request to
+// the response or streaming transformation that handles its reply:
+//
+//	ctx = tooladapter.ContextWithTools(ctx, req.Tools)
+//	resp, err := adapter.TransformCompletionsResponseWithContext(ctx, completion)
+//
+// CompleteWithRetry attaches the request's tools automatically.
+func ContextWithTools(ctx context.Context, tools []openai.ChatCompletionToolUnionParam) context.Context {
+	return context.WithValue(ctx, toolSchemasKey{}, toolSchemas(tools))
+}
+
+// toolSchemas parses the parameter schemas of tools, keyed by function name.
+func toolSchemas(tools []openai.ChatCompletionToolUnionParam) map[string]*argumentSchema {
+	schemas := make(map[string]*argumentSchema, len(tools))
+	for _, tool := range tools {
+		function := tool.GetFunction()
+		if function == nil || function.Parameters == nil {
+			continue
+		}
+		if schema := parseArgumentSchema(function.Parameters); schema != nil {
+			schemas[function.Name] = schema
+		}
+	}
+	return schemas
+}
+
+// argumentSchema is the subset of JSON Schema used for argument coercion and
+// constraint checking.
+type argumentSchema struct {
+	Type                 schemaTypes                `json:"type"`
+	Properties           map[string]*argumentSchema `json:"properties"`
+	Items                *argumentSchema            `json:"items"`
+	AdditionalProperties json.RawMessage            `json:"additionalProperties"`
+	Default              json.RawMessage            `json:"default"`
+	Enum                 []json.RawMessage          `json:"enum"`
+	Minimum              *float64                   `json:"minimum"`
+	Maximum              *float64                   `json:"maximum"`
+	ExclusiveMinimum     json.RawMessage            `json:"exclusiveMinimum"`
+	ExclusiveMaximum     json.RawMessage            `json:"exclusiveMaximum"`
+	MinLength            *int                       `json:"minLength"`
+	MaxLength            *int                       `json:"maxLength"`
+}
+
+// schemaTypes holds a schema "type", which may be a single name or a list.
+type schemaTypes []string
+
+func (t *schemaTypes) UnmarshalJSON(data []byte) error {
+	var single string
+	if err := json.Unmarshal(data, &single); err == nil {
+		*t = schemaTypes{single}
+		return nil
+	}
+	var list []string
+	if err := json.Unmarshal(data, &list); err != nil {
+		return nil // Unsupported type declarations disable coercion for the property
+	}
+	*t = list
+	return nil
+}
+
+func (t schemaTypes) has(name string) bool {
+	for _, typ := range t {
+		if typ == name {
+			return true
+		}
+	}
+	return false
+}
+
+// parseArgumentSchema converts function parameters into an argumentSchema. It
+// returns nil when the parameters cannot be represented.
+func parseArgumentSchema(parameters openai.FunctionParameters) *argumentSchema {
+	data, err := json.Marshal(parameters)
+	if err != nil {
+		return nil
+	}
+	var schema argumentSchema
+	if err := json.Unmarshal(data, &schema); err != nil {
+		return nil
+	}
+	return &schema
+}
+
+// coerceCalls rewrites the arguments of calls to match the tool schemas attached to
+// ctx with ContextWithTools. Calls without a schema are left unchanged.
+func (a *Adapter) coerceCalls(ctx context.Context, calls []functionCall) {
+	if !a.argumentCoercion || ctx == nil {
+		return
+	}
+	schemas, _ := ctx.Value(toolSchemasKey{}).(map[string]*argumentSchema)
+	if len(schemas) == 0 {
+		return
+	}
+	for i := range calls {
+		schema, ok := schemas[calls[i].Name]
+		if !ok {
+			continue
+		}
+		if coerced, ok := coerceArguments(calls[i].Parameters, schema); ok {
+			calls[i].Parameters = coerced
+		} else {
+			a.log(LogCategoryPolicy).Debug("Tool call arguments are not a JSON object, skipping coercion",
+				"function_name", calls[i].Name)
+		}
+	}
+}
+
+// coerceArguments applies schema to raw arguments and returns them re-encoded in
+// canonical form. Missing or null arguments become an object when the schema
+// declares defaults. It returns false when the arguments are not a JSON object.
+func coerceArguments(raw json.RawMessage, schema *argumentSchema) (json.RawMessage, bool) {
+	var args map[string]any
+	if len(raw) > 0 && string(raw) != "null" {
+		decoder := json.NewDecoder(bytes.NewReader(raw))
+		decoder.UseNumber() // Keep large integers exact
+		if err := decoder.Decode(&args); err != nil {
+			return raw, false
+		}
+	}
+
+	if args == nil {
+		args = make(map[string]any)
+		if !fillDefaults(args, schema) {
+			return raw, true
+		}
+	} else {
+		coerceObject(args, schema)
+	}
+
+	encoded, err := json.Marshal(args)
+	if err != nil {
+		return raw, false
+	}
+	return encoded, true
+}
+
+// coerceObject coerces the properties of obj in place, drops undeclared properties
+// when additionalProperties is false, and fills declared defaults.
+func coerceObject(obj map[string]any, schema *argumentSchema) {
+	if strings.TrimSpace(string(schema.AdditionalProperties)) == "false" {
+		for key := range obj {
+			if _, declared := schema.Properties[key]; !declared {
+				delete(obj, key)
+			}
+		}
+	}
+	for key, value := range obj {
+		if property := schema.Properties[key]; property != nil {
+			obj[key] = coerceValue(value, property)
+		}
+	}
+	fillDefaults(obj, schema)
+}
+
+// fillDefaults sets declared defaults for properties missing from obj and reports
+// whether any were added.
+func fillDefaults(obj map[string]any, schema *argumentSchema) bool {
+	filled := false
+	for key, property := range schema.Properties {
+		if property == nil || len(property.Default) == 0 {
+			continue
+		}
+		if _, present := obj[key]; present {
+			continue
+		}
+		var value any
+		decoder := json.NewDecoder(bytes.NewReader(property.Default))
+		decoder.UseNumber()
+		if decoder.Decode(&value) == nil {
+			obj[key] = value
+			filled = true
+		}
+	}
+	return filled
+}
+
+// coerceValue converts value to a type allowed by schema when it has a different
+// JSON type with an unambiguous conversion, such as "5" for an integer. Values
+// that already match, or cannot be converted, are returned unchanged.
+func coerceValue(value any, schema *argumentSchema) any {
+	switch v := value.(type) {
+	case map[string]any:
+		if len(schema.Properties) > 0 || len(schema.AdditionalProperties) > 0 {
+			coerceObject(v, schema)
+		}
+		return v
+	case []any:
+		if schema.Items != nil {
+			for i := range v {
+				v[i] = coerceValue(v[i], schema.Items)
+			}
+		}
+		return v
+	}
+
+	types := schema.Type
+	if len(types) == 0 || matchesType(value, types) {
+		return value
+	}
+
+	switch v := value.(type) {
+	case string:
+		trimmed := strings.TrimSpace(v)
+		if types.has("integer") {
+			if n, err := strconv.ParseInt(trimmed, 10, 64); err == nil {
+				return json.Number(strconv.FormatInt(n, 10))
+			}
+		}
+		if types.has("number") {
+			if f, err := strconv.ParseFloat(trimmed, 64); err == nil {
+				return json.Number(strconv.FormatFloat(f, 'f', -1, 64))
+			}
+		}
+		if types.has("boolean") {
+			switch strings.ToLower(trimmed) {
+			case "true":
+				return true
+			case "false":
+				return false
+			}
+		}
+	case json.Number:
+		if types.has("integer") {
+			if f, err := v.Float64(); err == nil && f == float64(int64(f)) {
+				return json.Number(strconv.FormatInt(int64(f), 10))
+			}
+		}
+		if types.has("string") {
+			return v.String()
+		}
+	case bool:
+		if types.has("string") {
+			return strconv.FormatBool(v)
+		}
+	}
+	return value
+}
+
+// matchesType reports whether value already has one of the JSON Schema types.
+func matchesType(value any, types schemaTypes) bool {
+	switch v := value.(type) {
+	case nil:
+		return types.has("null")
+	case string:
+		return types.has("string")
+	case bool:
+		return types.has("boolean")
+	case json.Number:
+		if types.has("number") {
+			return true
+		}
+		_, err := v.Int64()
+		return types.has("integer") && err == nil
+	}
+	return false
+}
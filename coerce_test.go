@@ -0,0 +1,93 @@
+package tooladapter_test
+
+import (
+	"context"
+	"testing"
+
+	tooladapter "github.com/juburr/openai-tool-adapter/v3"
+	"github.com/openai/openai-go/v3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func bookingTool() openai.ChatCompletionToolUnionParam {
+	return openai.ChatCompletionFunctionTool(openai.FunctionDefinitionParam{
+		Name: "book_table",
+		Parameters: openai.FunctionParameters{
+			"type": "object",
+			"properties": map[string]any{
+				"guests":    map[string]any{"type": "integer"},
+				"outdoor":   map[string]any{"type": "boolean"},
+				"budget":    map[string]any{"type": []string{"number", "null"}},
+				"reference": map[string]any{"type": "string"},
+				"time":      map[string]any{"type": "string", "default": "19:00"},
+				"options": map[string]any{
+					"type":  "array",
+					"items": map[string]any{"type": "integer"},
+				},
+			},
+			"required":             []string{"guests"},
+			"additionalProperties": false,
+		},
+	})
+}
+
+func TestArgumentCoercion(t *testing.T) {
+	adapter := tooladapter.New(tooladapter.WithArgumentCoercion(true))
+	ctx := tooladapter.ContextWithTools(context.Background(), []openai.ChatCompletionToolUnionParam{bookingTool()})
+
+	transform := func(t *testing.T, ctx context.Context, adapter *tooladapter.Adapter, content string) string {
+		t.Helper()
+		resp, err := adapter.TransformCompletionsResponseWithContext(ctx, createMockCompletion(content))
+		require.NoError(t, err)
+		require.Len(t, resp.Choices[0].Message.ToolCalls, 1)
+		return resp.Choices[0].Message.ToolCalls[0].Function.Arguments
+	}
+
+	t.Run("CoercesScalars", func(t *testing.T) {
+		args := transform(t, ctx, adapter, `{"name": "book_table", "parameters": {
+			"guests": "4", "outdoor": "True", "budget": "120.5", "reference": 1234567890123, "options": ["1", 2.0]}}`)
+		assert.Equal(t, `{"budget":120.5,"guests":4,"options":[1,2],"outdoor":true,"reference":"1234567890123","time":"19:00"}`, args)
+	})
+
+	t.Run("DropsUndeclaredProperties", func(t *testing.T) {
+		args := transform(t, ctx, adapter, `{"name": "book_table", "parameters": {"guests": 2, "note": "window seat"}}`)
+		assert.JSONEq(t, `{"guests": 2, "time": "19:00"}`, args)
+	})
+
+	t.Run("FillsDefaultsForNullArguments", func(t *testing.T) {
+		args := transform(t, ctx, adapter, `{"name": "book_table", "parameters": null}`)
+		assert.JSONEq(t, `{"time": "19:00"}`, args)
+	})
+
+	t.Run("LeavesUnconvertibleValues", func(t *testing.T) {
+		args := transform(t, ctx, adapter, `{"name": "book_table", "parameters": {"guests": "four", "budget": null}}`)
+		assert.JSONEq(t, `{"guests": "four", "budget": null, "time": "19:00"}`, args)
+	})
+
+	t.Run("RequiresContextSchemas", func(t *testing.T) {
+		args := transform(t, context.Background(), adapter, `{"name": "book_table", "parameters": {"guests": "4"}}`)
+		assert.JSONEq(t, `{"guests": "4"}`, args)
+	})
+
+	t.Run("DisabledByDefault", func(t *testing.T) {
+		args := transform(t, ctx, tooladapter.New(), `{"name": "book_table", "parameters": {"guests": "4"}}`)
+		assert.JSONEq(t, `{"guests": "4"}`, args)
+	})
+
+	t.Run("Streaming", func(t *testing.T) {
+		stream := adapter.TransformStreamingResponseWithContext(ctx, NewMockStream([]openai.ChatCompletionChunk{
+			createStreamChunk(`{"name": "book_table", "parameters": {"guests": "6"}}`),
+			createFinishChunk("stop"),
+		}))
+
+		var arguments string
+		for stream.Next() {
+			if chunk := stream.Current(); len(chunk.Choices) > 0 && len(chunk.Choices[0].Delta.ToolCalls) > 0 {
+				arguments = chunk.Choices[0].Delta.ToolCalls[0].Function.Arguments
+			}
+		}
+		require.NoError(t, stream.Err())
+		assert.JSONEq(t, `{"guests": 6, "time": "19:00"}`, arguments)
+	})
+}
@@ -0,0 +1,88 @@
+package tooladapter
+
+import (
+	"time"
+
+	"github.com/openai/openai-go/v3"
+)
+
+// isBuffering reports whether the stream is holding back content that may still
+// become a tool call, as opposed to discarding content after its tool calls.
+func (s *StreamAdapter) isBuffering() bool {
+	return s.buffer.Len() > 0 || s.peek.Len() > 0 || (s.contentSuppressed && !s.toolCallsEmitted)
+}
+
+// trackBuffering charges the time the stream spent buffering against the
+// WithToolCollectTotalBudget budget, starting the clock when buffering begins and
+// stopping it when buffering ends.
+// Callers must hold s.mu.
+func (s *StreamAdapter) trackBuffering() {
+	if s.adapter.toolCollectTotalBudget <= 0 || s.passThrough {
+		return
+	}
+	buffering := s.isBuffering()
+	switch {
+	case buffering && s.bufferingSince.IsZero():
+		s.bufferingSince = time.Now()
+	case !buffering && !s.bufferingSince.IsZero():
+		s.bufferingSpent += time.Since(s.bufferingSince)
+		s.bufferingSince = time.Time{}
+	}
+}
+
+// exhaustCollectBudget degrades the stream to pass-through with passThroughRest
+// once it has spent the WithToolCollectTotalBudget budget buffering. It returns
+// false while budget remains.
+// Callers must hold s.mu.
+func (s *StreamAdapter) exhaustCollectBudget(chunk openai.ChatCompletionChunk) bool {
+	budget := s.adapter.toolCollectTotalBudget
+	if budget <= 0 || s.passThrough || s.bufferingSince.IsZero() {
+		return false
+	}
+	spent := s.bufferingSpent + time.Since(s.bufferingSince)
+	if spent <= budget {
+		return false
+	}
+
+	s.adapter.log(LogCategoryLimit).Warn("Tool collection budget exhausted, passing the rest of the stream through",
+		"buffering_time", spent,
+		"budget", budget,
+		"recommendation", "Consider increasing the budget with WithToolCollectTotalBudget() if legitimate use case")
+	s.passThroughRest(chunk, BufferFlushBudget)
+	return true
+}
+
+// passThroughRest degrades the stream to pass-through: the withheld content,
+// including the text of tool calls collected but not emitted, is released as text
+// together with chunk's content, reported as a buffer flush for reason, and later
+// content is emitted as it arrives without tool call detection.
+// Callers must hold s.mu.
+func (s *StreamAdapter) passThroughRest(chunk openai.ChatCompletionChunk, reason string) {
+	withheld := s.collectedText + s.peek.String() + s.unemittedContent(s.buffer.String())
+	s.adapter.log(LogCategoryStream).Debug("Stream degraded to pass-through",
+		"reason", reason,
+		"released_length", len(withheld),
+		"discarded_tool_count", len(s.collectedTools))
+
+	s.passThrough = true
+	if !s.bufferingSince.IsZero() {
+		s.bufferingSpent += time.Since(s.bufferingSince)
+		s.bufferingSince = time.Time{}
+	}
+	s.collectedTools = nil
+	s.collectedText = ""
+	s.contentSuppressed = false
+	s.toolCollectionState = toolStateIdle
+	s.peek.Reset()
+	s.resetBuffer()
+
+	s.hasEmitted = true
+	if withheld != "" {
+		s.emitBufferFlush(len(withheld), reason)
+	}
+	content := ""
+	if len(chunk.Choices) > 0 {
+		content = chunk.Choices[0].Delta.Content
+	}
+	s.emitReleasedContent(chunk, withheld+content)
+}
@@ -0,0 +1,71 @@
+package tooladapter_test
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	tooladapter "github.com/juburr/openai-tool-adapter/v3"
+	"github.com/juburr/openai-tool-adapter/v3/tooltest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// pacedStream delivers one content chunk per interval.
+type pacedStream struct {
+	*tooltest.MockStream
+	interval time.Duration
+}
+
+func newPacedStream(interval time.Duration, contents ...string) *pacedStream {
+	return &pacedStream{MockStream: tooltest.NewContentStream(contents...), interval: interval}
+}
+
+func (s *pacedStream) Next() bool {
+	time.Sleep(s.interval)
+	return s.MockStream.Next()
+}
+
+func TestWithToolCollectTotalBudget(t *testing.T) {
+	const interval = 50 * time.Millisecond
+	const budget = 75 * time.Millisecond
+
+	t.Run("DegradesToPassThrough", func(t *testing.T) {
+		adapter, collector := newPhaseMetricsAdapter(
+			tooladapter.WithToolPolicy(tooladapter.ToolDrainAll),
+			tooladapter.WithToolCollectTotalBudget(budget))
+		result := tooltest.Drain(adapter.TransformStreamingResponse(newPacedStream(interval,
+			"Hello ", "world ", "again, ", `{"name": "late", "parameters": {}}`)))
+		require.NoError(t, result.Err)
+		assert.Empty(t, result.ToolCalls, "no detection after the budget is spent")
+		assert.Equal(t, `Hello world again, {"name": "late", "parameters": {}}`, result.Content)
+		flushes := phaseEvents[tooladapter.StreamBufferFlushedData](collector)
+		require.Len(t, flushes, 1)
+		assert.Equal(t, tooladapter.StreamBufferFlushedData{Size: len("Hello world "), Reason: tooladapter.BufferFlushBudget}, flushes[0])
+	})
+
+	t.Run("Cumulative", func(t *testing.T) {
+		adapter := tooladapter.New(
+			tooladapter.WithToolPolicy(tooladapter.ToolEmitAndContinue),
+			tooladapter.WithToolCollectTotalBudget(budget))
+		result := tooltest.Drain(adapter.TransformStreamingResponse(newPacedStream(interval,
+			`{"name": "first", "parameters":`, ` {}}`, "Next: ", `{"name": "second", "parameters":`, ` {}}`)))
+		require.NoError(t, result.Err)
+		assert.Equal(t, []string{"first"}, result.ToolNames(), "each buffering fits the budget, their sum does not")
+		assert.Equal(t, `Next: {"name": "second", "parameters": {}}`, result.Content)
+	})
+
+	t.Run("WithinBudget", func(t *testing.T) {
+		adapter := tooladapter.New(tooladapter.WithToolCollectTotalBudget(time.Second))
+		result := tooltest.Drain(adapter.TransformStreamingResponse(newPacedStream(time.Millisecond,
+			`{"name": "first", "parameters":`, ` {}}`)))
+		require.NoError(t, result.Err)
+		assert.Equal(t, []string{"first"}, result.ToolNames())
+	})
+
+	t.Run("Config", func(t *testing.T) {
+		cfg, err := tooladapter.LoadConfig(strings.NewReader(`{"tool_collect_total_budget": "2s"}`))
+		require.NoError(t, err)
+		assert.Equal(t, tooladapter.Duration(2*time.Second), cfg.ToolCollectTotalBudget)
+	})
+}
@@ -0,0 +1,174 @@
+package tooladapter
+
+import (
+	"strings"
+
+	"github.com/openai/openai-go/v3"
+)
+
+// Default sentinel markers placed around injected instructions. They let later
+// requests find and remove blocks that a caller echoed back as part of the
+// conversation history.
+const (
+	DefaultInjectionBeginMarker = "<!-- tool-adapter:begin -->"
+	DefaultInjectionEndMarker   = "<!-- tool-adapter:end -->"
+)
+
+// StripInjectedContent returns a copy of messages with all marker-delimited text
+// injected by the adapter removed, leaving only the caller's own system and user text.
+// Messages that contained nothing but injected text are dropped. Apps that persist
+// conversation history can use this to store transformed messages without adapter
+// artifacts. Only content injected while markers were enabled can be recognized.
+func (a *Adapter) StripInjectedContent(messages []openai.ChatCompletionMessageParamUnion) []openai.ChatCompletionMessageParamUnion {
+	return a.compactInjectedPrompts(messages, nil)
+}
+
+// wrapInjectedPrompt surrounds an injected prompt with the sentinel markers.
+func (a *Adapter) wrapInjectedPrompt(prompt string) string {
+	return a.injectionBeginMarker + "\n" + prompt + "\n" + a.injectionEndMarker
+}
+
+// stripInjectedPrompts removes every marker-delimited block from text, along with the
+// blank-line separator that joined the block to the surrounding content. A begin
+// marker without a matching end marker is left in place.
+func (a *Adapter) stripInjectedPrompts(text string) (string, bool) {
+	stripped := false
+	for {
+		start := strings.Index(text, a.injectionBeginMarker)
+		if start == -1 {
+			break
+		}
+		endOffset := strings.Index(text[start+len(a.injectionBeginMarker):], a.injectionEndMarker)
+		if endOffset == -1 {
+			break
+		}
+		end := start + len(a.injectionBeginMarker) + endOffset + len(a.injectionEndMarker)
+
+		before, after := text[:start], text[end:]
+		hadBefore := strings.HasSuffix(before, "\n\n")
+		hadAfter := strings.HasPrefix(after, "\n\n")
+		before = strings.TrimSuffix(before, "\n\n")
+		after = strings.TrimPrefix(after, "\n\n")
+
+		// Keep a single separator when the block sat between two pieces of content
+		if hadBefore && hadAfter && before != "" && after != "" {
+			before += "\n\n"
+		}
+		text = before + after
+		stripped = true
+	}
+	return text, stripped
+}
+
+// compactInjectedPrompts removes previously injected tool instructions from system,
+// developer and user messages in the conversation history. Messages left without any
+// content are dropped entirely since the adapter created them. The input slice is not
+// modified.
+func (a *Adapter) compactInjectedPrompts(messages []openai.ChatCompletionMessageParamUnion, origins *messageOrigins) []openai.ChatCompletionMessageParamUnion {
+	var compacted []openai.ChatCompletionMessageParamUnion
+	compactedMessages := 0
+	droppedMessages := 0
+
+	for _, msg := range messages {
+		switch {
+		case msg.OfSystem != nil:
+			content := extractSystemContent(msg)
+			text, stripped := a.stripInjectedPrompts(content)
+			if !stripped {
+				break
+			}
+			compactedMessages++
+			if text == "" {
+				droppedMessages++
+				origins.remove(len(compacted))
+				continue
+			}
+			origins.modify(len(compacted))
+			systemMsg := *msg.OfSystem
+			systemMsg.Content = openai.ChatCompletionSystemMessageParamContentUnion{OfString: openai.String(text)}
+			msg = openai.ChatCompletionMessageParamUnion{OfSystem: &systemMsg}
+
+		case msg.OfDeveloper != nil:
+			content := extractSystemContent(msg)
+			text, stripped := a.stripInjectedPrompts(content)
+			if !stripped {
+				break
+			}
+			compactedMessages++
+			if text == "" {
+				droppedMessages++
+				origins.remove(len(compacted))
+				continue
+			}
+			origins.modify(len(compacted))
+			developerMsg := *msg.OfDeveloper
+			developerMsg.Content = openai.ChatCompletionDeveloperMessageParamContentUnion{OfString: openai.String(text)}
+			msg = openai.ChatCompletionMessageParamUnion{OfDeveloper: &developerMsg}
+
+		case msg.OfUser != nil:
+			userMsg, stripped, empty := a.stripInjectedPromptsFromUserMessage(*msg.OfUser)
+			if !stripped {
+				break
+			}
+			compactedMessages++
+			if empty {
+				droppedMessages++
+				origins.remove(len(compacted))
+				continue
+			}
+			origins.modify(len(compacted))
+			msg = openai.ChatCompletionMessageParamUnion{OfUser: &userMsg}
+		}
+		compacted = append(compacted, msg)
+	}
+
+	if compactedMessages > 0 {
+		a.log(LogCategoryRequest).Debug("Removed previously injected tool prompts from history",
+			"messages_compacted", compactedMessages,
+			"messages_dropped", droppedMessages)
+	}
+	return compacted
+}
+
+// stripInjectedPromptsFromUserMessage removes injected blocks from a user message's text
+// content. Text parts that become empty are removed; other parts are kept as-is. It
+// reports whether anything was removed and whether the message has no content left.
+func (a *Adapter) stripInjectedPromptsFromUserMessage(userMsg openai.ChatCompletionUserMessageParam) (openai.ChatCompletionUserMessageParam, bool, bool) {
+	content := userMsg.Content
+
+	if content.OfString.Valid() {
+		text, stripped := a.stripInjectedPrompts(content.OfString.Value)
+		if stripped {
+			userMsg.Content = openai.ChatCompletionUserMessageParamContentUnion{OfString: openai.String(text)}
+		}
+		return userMsg, stripped, text == ""
+	}
+
+	parts := content.OfArrayOfContentParts
+	if len(parts) == 0 {
+		return userMsg, false, false
+	}
+
+	newParts := make([]openai.ChatCompletionContentPartUnionParam, 0, len(parts))
+	strippedAny := false
+	for _, part := range parts {
+		if part.OfText != nil {
+			text, stripped := a.stripInjectedPrompts(part.OfText.Text)
+			if stripped {
+				strippedAny = true
+				if text == "" {
+					continue
+				}
+				textPart := *part.OfText
+				textPart.Text = text
+				part = openai.ChatCompletionContentPartUnionParam{OfText: &textPart}
+			}
+		}
+		newParts = append(newParts, part)
+	}
+
+	if strippedAny {
+		userMsg.Content = openai.ChatCompletionUserMessageParamContentUnion{OfArrayOfContentParts: newParts}
+	}
+	return userMsg, strippedAny, len(newParts) == 0
+}
@@ -0,0 +1,228 @@
+package tooladapter_test
+
+import (
+	"bytes"
+	"log/slog"
+	"strings"
+	"testing"
+
+	tooladapter "github.com/juburr/openai-tool-adapter/v3"
+	"github.com/openai/openai-go/v3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// countInjectedPrompts counts tool prompts across all text content in the messages
+func countInjectedPrompts(t *testing.T, messages []openai.ChatCompletionMessageParamUnion) int {
+	t.Helper()
+	count := 0
+	for _, msg := range messages {
+		switch {
+		case msg.OfSystem != nil:
+			count += strings.Count(msg.OfSystem.Content.OfString.Value, "System/tooling instructions")
+		case msg.OfUser != nil:
+			count += strings.Count(msg.OfUser.Content.OfString.Value, "System/tooling instructions")
+			for _, part := range msg.OfUser.Content.OfArrayOfContentParts {
+				if part.OfText != nil {
+					count += strings.Count(part.OfText.Text, "System/tooling instructions")
+				}
+			}
+		}
+	}
+	return count
+}
+
+func TestPromptCompaction(t *testing.T) {
+	tools := []openai.ChatCompletionToolUnionParam{createMockTool("get_weather", "Get the weather")}
+
+	// nextTurn appends an assistant reply and a new user message to a transformed request
+	nextTurn := func(prev openai.ChatCompletionNewParams, question string) openai.ChatCompletionNewParams {
+		messages := append([]openai.ChatCompletionMessageParamUnion{}, prev.Messages...)
+		messages = append(messages, openai.AssistantMessage("It is sunny."), openai.UserMessage(question))
+		return openai.ChatCompletionNewParams{Model: prev.Model, Messages: messages, Tools: tools}
+	}
+
+	t.Run("PrependedToUserMessage", func(t *testing.T) {
+		adapter := tooladapter.New(tooladapter.WithPromptCompaction(true))
+
+		first, err := adapter.TransformCompletionsRequest(createMockRequest(tools))
+		require.NoError(t, err)
+		require.Equal(t, 1, countInjectedPrompts(t, first.Messages))
+
+		second, err := adapter.TransformCompletionsRequest(nextTurn(first, "And tomorrow?"))
+		require.NoError(t, err)
+		require.Len(t, second.Messages, 3)
+		assert.Equal(t, 1, countInjectedPrompts(t, second.Messages))
+
+		userText := second.Messages[0].OfUser.Content.OfString.Value
+		assert.True(t, strings.HasPrefix(userText, "<!-- tool-adapter:begin -->"))
+		assert.True(t, strings.HasSuffix(userText, "-->\n\nHello, please help me."), "original text must be kept once: %q", userText)
+	})
+
+	t.Run("AppendedToSystemMessage", func(t *testing.T) {
+		adapter := tooladapter.New(tooladapter.WithPromptCompaction(true))
+		req := createMockRequest(tools)
+		req.Messages = append([]openai.ChatCompletionMessageParamUnion{openai.SystemMessage("You are helpful.")}, req.Messages...)
+
+		first, err := adapter.TransformCompletionsRequest(req)
+		require.NoError(t, err)
+		second, err := adapter.TransformCompletionsRequest(nextTurn(first, "And tomorrow?"))
+		require.NoError(t, err)
+
+		assert.Equal(t, 1, countInjectedPrompts(t, second.Messages))
+		systemText := second.Messages[0].OfSystem.Content.OfString.Value
+		assert.True(t, strings.HasPrefix(systemText, "You are helpful.\n\n<!-- tool-adapter:begin -->"), systemText)
+	})
+
+	t.Run("DropsAdapterCreatedMessages", func(t *testing.T) {
+		adapter := tooladapter.New(tooladapter.WithPromptCompaction(true), tooladapter.WithSystemMessageSupport(true))
+
+		first, err := adapter.TransformCompletionsRequest(createMockRequest(tools))
+		require.NoError(t, err)
+		require.Len(t, first.Messages, 2)
+
+		second, err := adapter.TransformCompletionsRequest(nextTurn(first, "And tomorrow?"))
+		require.NoError(t, err)
+		assert.Len(t, second.Messages, 4)
+		assert.Equal(t, 1, countInjectedPrompts(t, second.Messages))
+	})
+
+	t.Run("MultimodalTextPart", func(t *testing.T) {
+		adapter := tooladapter.New(tooladapter.WithPromptCompaction(true))
+		req := createMockRequest(tools)
+		req.Messages = []openai.ChatCompletionMessageParamUnion{openai.UserMessage([]openai.ChatCompletionContentPartUnionParam{
+			openai.ImageContentPart(openai.ChatCompletionContentPartImageImageURLParam{URL: "https://example.com/a.png"}),
+		})}
+
+		first, err := adapter.TransformCompletionsRequest(req)
+		require.NoError(t, err)
+		second, err := adapter.TransformCompletionsRequest(nextTurn(first, "And this one?"))
+		require.NoError(t, err)
+
+		assert.Equal(t, 1, countInjectedPrompts(t, second.Messages))
+		parts := second.Messages[0].OfUser.Content.OfArrayOfContentParts
+		require.Len(t, parts, 2)
+		assert.NotNil(t, parts[0].OfText)
+		assert.NotNil(t, parts[1].OfImageURL)
+	})
+
+	t.Run("StripsWhenNoToolsOffered", func(t *testing.T) {
+		adapter := tooladapter.New(tooladapter.WithPromptCompaction(true))
+		first, err := adapter.TransformCompletionsRequest(createMockRequest(tools))
+		require.NoError(t, err)
+
+		followUp := nextTurn(first, "Thanks!")
+		followUp.Tools = nil
+		second, err := adapter.TransformCompletionsRequest(followUp)
+		require.NoError(t, err)
+
+		assert.Zero(t, countInjectedPrompts(t, second.Messages))
+		assert.Equal(t, "Hello, please help me.", second.Messages[0].OfUser.Content.OfString.Value)
+	})
+
+	t.Run("UnmatchedMarkerLeftAlone", func(t *testing.T) {
+		adapter := tooladapter.New(tooladapter.WithPromptCompaction(true))
+		text := "Why does my page contain <!-- tool-adapter:begin --> ?"
+		req := openai.ChatCompletionNewParams{Model: "m", Messages: []openai.ChatCompletionMessageParamUnion{openai.UserMessage(text)}}
+
+		result, err := adapter.TransformCompletionsRequest(req)
+		require.NoError(t, err)
+		assert.Equal(t, text, result.Messages[0].OfUser.Content.OfString.Value)
+	})
+
+	t.Run("DisabledByDefault", func(t *testing.T) {
+		adapter := tooladapter.New()
+		first, err := adapter.TransformCompletionsRequest(createMockRequest(tools))
+		require.NoError(t, err)
+		assert.NotContains(t, first.Messages[0].OfUser.Content.OfString.Value, "tool-adapter:begin")
+	})
+}
+
+func TestInjectionMarkers(t *testing.T) {
+	tools := []openai.ChatCompletionToolUnionParam{createMockTool("get_weather", "Get the weather")}
+
+	t.Run("CustomMarkersWrapInjectedText", func(t *testing.T) {
+		adapter := tooladapter.New(tooladapter.WithInjectionMarkers("[[tools]]", "[[/tools]]"))
+		req := createMockRequest(tools)
+		req.Messages = append(req.Messages,
+			openai.AssistantMessage("Checking."),
+			openai.ToolMessage(`{"temp": 21}`, "call_1"))
+
+		result, err := adapter.TransformCompletionsRequest(req)
+		require.NoError(t, err)
+
+		text := result.Messages[0].OfUser.Content.OfString.Value
+		assert.True(t, strings.HasPrefix(text, "[[tools]]\nSystem/tooling instructions"), text)
+		assert.Contains(t, text, `{"temp": 21}`+"\n\n\n[[/tools]]\n\nHello, please help me.")
+	})
+
+	t.Run("StripInjectedContent", func(t *testing.T) {
+		adapter := tooladapter.New(tooladapter.WithInjectionMarkers("[[tools]]", "[[/tools]]"))
+		req := createMockRequest(tools)
+		req.Messages = append([]openai.ChatCompletionMessageParamUnion{openai.SystemMessage("Be brief.")}, req.Messages...)
+
+		result, err := adapter.TransformCompletionsRequest(req)
+		require.NoError(t, err)
+		require.Equal(t, 1, countInjectedPrompts(t, result.Messages))
+
+		clean := adapter.StripInjectedContent(result.Messages)
+		require.Len(t, clean, 2)
+		assert.Equal(t, "Be brief.", clean[0].OfSystem.Content.OfString.Value)
+		assert.Equal(t, "Hello, please help me.", clean[1].OfUser.Content.OfString.Value)
+
+		// The transformed messages must not be modified
+		assert.Equal(t, 1, countInjectedPrompts(t, result.Messages))
+	})
+
+	t.Run("StripDropsAdapterCreatedMessages", func(t *testing.T) {
+		adapter := tooladapter.New(
+			tooladapter.WithInjectionMarkers(tooladapter.DefaultInjectionBeginMarker, tooladapter.DefaultInjectionEndMarker),
+			tooladapter.WithSystemMessageSupport(true))
+
+		result, err := adapter.TransformCompletionsRequest(createMockRequest(tools))
+		require.NoError(t, err)
+		require.Len(t, result.Messages, 2)
+
+		clean := adapter.StripInjectedContent(result.Messages)
+		require.Len(t, clean, 1)
+		assert.NotNil(t, clean[0].OfUser)
+	})
+
+	t.Run("MarkersWithoutCompactionKeepHistory", func(t *testing.T) {
+		adapter := tooladapter.New(tooladapter.WithInjectionMarkers("[[tools]]", "[[/tools]]"))
+		first, err := adapter.TransformCompletionsRequest(createMockRequest(tools))
+		require.NoError(t, err)
+
+		req := createMockRequest(tools)
+		req.Messages = first.Messages
+		second, err := adapter.TransformCompletionsRequest(req)
+		require.NoError(t, err)
+		assert.Equal(t, 2, countInjectedPrompts(t, second.Messages))
+	})
+
+	t.Run("CompactionUsesCustomMarkers", func(t *testing.T) {
+		adapter := tooladapter.New(
+			tooladapter.WithInjectionMarkers("[[tools]]", "[[/tools]]"),
+			tooladapter.WithPromptCompaction(true))
+		first, err := adapter.TransformCompletionsRequest(createMockRequest(tools))
+		require.NoError(t, err)
+
+		req := createMockRequest(tools)
+		req.Messages = first.Messages
+		second, err := adapter.TransformCompletionsRequest(req)
+		require.NoError(t, err)
+		assert.Equal(t, 1, countInjectedPrompts(t, second.Messages))
+		assert.NotContains(t, second.Messages[0].OfUser.Content.OfString.Value, tooladapter.DefaultInjectionBeginMarker)
+	})
+
+	t.Run("InvalidMarkersIgnored", func(t *testing.T) {
+		var logs bytes.Buffer
+		logger := slog.New(slog.NewTextHandler(&logs, nil))
+		adapter := tooladapter.New(tooladapter.WithLogger(logger), tooladapter.WithInjectionMarkers("##", "##"))
+		assert.Contains(t, logs.String(), "Invalid injection markers ignored")
+
+		result, err := adapter.TransformCompletionsRequest(createMockRequest(tools))
+		require.NoError(t, err)
+		assert.True(t, strings.HasPrefix(result.Messages[0].OfUser.Content.OfString.Value, "System/tooling instructions"))
+	})
+}
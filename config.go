@@ -0,0 +1,913 @@
+package tooladapter
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Config is a serializable adapter configuration, so deployments can drive the
+// adapter from configuration files instead of code. Each field corresponds to an
+// option, named in its comment; zero values keep the option's default, and pointer
+// fields are used where the zero value is itself a valid setting.
+//
+// Policies are encoded by name, such as "drain_all" or "as_content", and durations
+// as strings such as "200ms". A JSON file can be read with LoadConfig; the yaml
+// tags match the json tags for YAML libraries.
+//
+// Settings that cannot be serialized, such as loggers, metrics callbacks, parse
+// event hooks, stream recorders, tool call filters and custom tool selectors, are
+// passed as options to NewFromConfig.
+type Config struct {
+	// Prompt
+
+	// PromptTemplate sets WithCustomPromptTemplate
+	PromptTemplate string `json:"prompt_template,omitempty" yaml:"prompt_template,omitempty"`
+
+	// ToolPromptTemplate sets WithToolPromptTemplate
+	ToolPromptTemplate string `json:"tool_prompt_template,omitempty" yaml:"tool_prompt_template,omitempty"`
+
+	// TemplateExperiments and ExperimentAssignment set WithTemplateExperiments
+	TemplateExperiments  []TemplateExperiment `json:"template_experiments,omitempty" yaml:"template_experiments,omitempty"`
+	ExperimentAssignment ExperimentAssignment `json:"experiment_assignment,omitempty" yaml:"experiment_assignment,omitempty"`
+
+	// PromptLanguage sets WithPromptLanguage
+	PromptLanguage string `json:"prompt_language,omitempty" yaml:"prompt_language,omitempty"`
+
+	// ToolExamples sets WithToolExamples
+	ToolExamples map[string][]Example `json:"tool_examples,omitempty" yaml:"tool_examples,omitempty"`
+
+	// SystemMessageSupport sets WithSystemMessageSupport
+	SystemMessageSupport bool `json:"system_message_support,omitempty" yaml:"system_message_support,omitempty"`
+
+	// InjectionPosition sets WithInjectionPosition
+	InjectionPosition InjectionPosition `json:"injection_position,omitempty" yaml:"injection_position,omitempty"`
+
+	// DeveloperMessageSupport sets WithDeveloperMessageSupport
+	DeveloperMessageSupport bool `json:"developer_message_support,omitempty" yaml:"developer_message_support,omitempty"`
+
+	// PreserveToolsField sets WithPreserveToolsField
+	PreserveToolsField bool `json:"preserve_tools_field,omitempty" yaml:"preserve_tools_field,omitempty"`
+
+	// JSONMode sets WithJSONModeEmulation
+	JSONMode JSONModePolicy `json:"json_mode,omitempty" yaml:"json_mode,omitempty"`
+
+	// StrictOpenAICompatibility sets WithStrictOpenAICompatibility
+	StrictOpenAICompatibility bool `json:"strict_openai_compatibility,omitempty" yaml:"strict_openai_compatibility,omitempty"`
+
+	// LegacyFunctionCallOutput sets WithLegacyFunctionCallOutput
+	LegacyFunctionCallOutput bool `json:"legacy_function_call_output,omitempty" yaml:"legacy_function_call_output,omitempty"`
+
+	// GBNFGrammar sets WithGBNFGrammar
+	GBNFGrammar bool `json:"gbnf_grammar,omitempty" yaml:"gbnf_grammar,omitempty"`
+
+	// ToolCallConstraint sets WithToolCallConstraint
+	ToolCallConstraint ToolCallConstraint `json:"tool_call_constraint,omitempty" yaml:"tool_call_constraint,omitempty"`
+
+	// InjectionMarkers enables WithInjectionMarkers with InjectionBeginMarker and
+	// InjectionEndMarker, which default to DefaultInjectionBeginMarker and
+	// DefaultInjectionEndMarker. Setting either marker also enables marking.
+	InjectionMarkers     bool   `json:"injection_markers,omitempty" yaml:"injection_markers,omitempty"`
+	InjectionBeginMarker string `json:"injection_begin_marker,omitempty" yaml:"injection_begin_marker,omitempty"`
+	InjectionEndMarker   string `json:"injection_end_marker,omitempty" yaml:"injection_end_marker,omitempty"`
+
+	// PromptCompaction sets WithPromptCompaction
+	PromptCompaction bool `json:"prompt_compaction,omitempty" yaml:"prompt_compaction,omitempty"`
+
+	// SanitizeToolDefinitions sets WithSanitizeToolDefinitions
+	SanitizeToolDefinitions bool `json:"sanitize_tool_definitions,omitempty" yaml:"sanitize_tool_definitions,omitempty"`
+
+	// ToolStopSequences sets WithToolStopSequences
+	ToolStopSequences []string `json:"tool_stop_sequences,omitempty" yaml:"tool_stop_sequences,omitempty"`
+
+	// AssistantPrefill sets WithAssistantPrefill
+	AssistantPrefill string `json:"assistant_prefill,omitempty" yaml:"assistant_prefill,omitempty"`
+
+	// KeywordToolSelector sets WithToolSelector(KeywordToolSelector(n)) when positive
+	KeywordToolSelector int `json:"keyword_tool_selector,omitempty" yaml:"keyword_tool_selector,omitempty"`
+
+	// ToolResultHeadBytes and ToolResultTailBytes set
+	// WithToolResultTransformer(HeadTailToolResultTransformer(head, tail)) when either
+	// is positive
+	ToolResultHeadBytes int `json:"tool_result_head_bytes,omitempty" yaml:"tool_result_head_bytes,omitempty"`
+	ToolResultTailBytes int `json:"tool_result_tail_bytes,omitempty" yaml:"tool_result_tail_bytes,omitempty"`
+
+	// MultimodalToolResults sets WithMultimodalToolResults
+	MultimodalToolResults bool `json:"multimodal_tool_results,omitempty" yaml:"multimodal_tool_results,omitempty"`
+
+	// MaxInjectedPromptBytes sets WithMaxInjectedPromptBytes
+	MaxInjectedPromptBytes int `json:"max_injected_prompt_bytes,omitempty" yaml:"max_injected_prompt_bytes,omitempty"`
+
+	// MaxInjectedPromptTokens sets WithMaxInjectedPromptTokens
+	MaxInjectedPromptTokens int `json:"max_injected_prompt_tokens,omitempty" yaml:"max_injected_prompt_tokens,omitempty"`
+
+	// ContextWindow sets WithContextWindow
+	ContextWindow int `json:"context_window,omitempty" yaml:"context_window,omitempty"`
+
+	// TruncationStrategy sets WithTruncationStrategy
+	TruncationStrategy TruncationStrategy `json:"truncation_strategy,omitempty" yaml:"truncation_strategy,omitempty"`
+
+	// SchemaFormat sets WithSchemaFormat
+	SchemaFormat SchemaFormat `json:"schema_format,omitempty" yaml:"schema_format,omitempty"`
+
+	// PromptStyle sets WithPromptStyle
+	PromptStyle PromptStyle `json:"prompt_style,omitempty" yaml:"prompt_style,omitempty"`
+
+	// PromptCacheSize sets WithPromptCache
+	PromptCacheSize int `json:"prompt_cache_size,omitempty" yaml:"prompt_cache_size,omitempty"`
+
+	// ToolNamespace sets WithToolNamespace
+	ToolNamespace string `json:"tool_namespace,omitempty" yaml:"tool_namespace,omitempty"`
+
+	// ToolCollisionPolicy sets WithToolCollisionPolicy
+	ToolCollisionPolicy ToolCollisionPolicy `json:"tool_collision_policy,omitempty" yaml:"tool_collision_policy,omitempty"`
+
+	// UnsupportedToolPolicy sets WithUnsupportedToolPolicy
+	UnsupportedToolPolicy UnsupportedToolPolicy `json:"unsupported_tool_policy,omitempty" yaml:"unsupported_tool_policy,omitempty"`
+
+	// Logging
+
+	// RedactCommonSecrets adds CommonSecretRedactor to WithLogRedaction
+	RedactCommonSecrets bool `json:"redact_common_secrets,omitempty" yaml:"redact_common_secrets,omitempty"`
+
+	// RedactPatterns adds a RegexRedactor per regular expression to WithLogRedaction
+	RedactPatterns []string `json:"redact_patterns,omitempty" yaml:"redact_patterns,omitempty"`
+
+	// RedactJSONFields adds JSONFieldRedactor for the paths to WithLogRedaction
+	RedactJSONFields []string `json:"redact_json_fields,omitempty" yaml:"redact_json_fields,omitempty"`
+
+	// LogSampling sets WithLogSampling
+	LogSampling *float64 `json:"log_sampling,omitempty" yaml:"log_sampling,omitempty"`
+
+	// LogCategoryLevels sets WithLogCategoryLevel per category, e.g. {"stream": "WARN"}
+	LogCategoryLevels map[LogCategory]slog.Level `json:"log_category_levels,omitempty" yaml:"log_category_levels,omitempty"`
+
+	// LogCategorySampling sets WithLogCategorySampling per category, e.g. {"parse": 0.01}
+	LogCategorySampling map[LogCategory]float64 `json:"log_category_sampling,omitempty" yaml:"log_category_sampling,omitempty"`
+
+	// Response processing
+
+	// LenientParsing sets WithLenientParsing
+	LenientParsing bool `json:"lenient_parsing,omitempty" yaml:"lenient_parsing,omitempty"`
+
+	// ParserPriority sets WithParserPriority, e.g. ["code_fence", "json"]
+	ParserPriority []CallFormat `json:"parser_priority,omitempty" yaml:"parser_priority,omitempty"`
+
+	// ThinkBlocks sets WithThinkBlocks
+	ThinkBlocks ThinkBlockPolicy `json:"think_blocks,omitempty" yaml:"think_blocks,omitempty"`
+
+	// StripStopTokens sets WithStopTokenStripping with the StopTokens
+	StripStopTokens bool `json:"strip_stop_tokens,omitempty" yaml:"strip_stop_tokens,omitempty"`
+
+	// StopTokens sets the tokens of WithStopTokenStripping, enabling it
+	StopTokens []string `json:"stop_tokens,omitempty" yaml:"stop_tokens,omitempty"`
+
+	// EchoSuppression sets WithEchoSuppression
+	EchoSuppression int `json:"echo_suppression,omitempty" yaml:"echo_suppression,omitempty"`
+
+	// AllowedToolNames sets WithAllowedToolNames
+	AllowedToolNames []string `json:"allowed_tool_names,omitempty" yaml:"allowed_tool_names,omitempty"`
+
+	// UnknownToolPolicy sets WithUnknownToolPolicy
+	UnknownToolPolicy UnknownToolPolicy `json:"unknown_tool_policy,omitempty" yaml:"unknown_tool_policy,omitempty"`
+
+	// UnknownToolMatchThreshold sets WithUnknownToolMatchThreshold
+	UnknownToolMatchThreshold *float64 `json:"unknown_tool_match_threshold,omitempty" yaml:"unknown_tool_match_threshold,omitempty"`
+
+	// ToolArgumentModes sets WithToolArgumentModes, e.g. {"run_sql": "text"}
+	ToolArgumentModes map[string]ArgumentMode `json:"tool_argument_modes,omitempty" yaml:"tool_argument_modes,omitempty"`
+
+	// CodeBlockTools sets WithCodeBlockTools, e.g. {"python": {"name": "execute_python"}}
+	CodeBlockTools map[string]CodeBlockTool `json:"code_block_tools,omitempty" yaml:"code_block_tools,omitempty"`
+
+	// ArgumentCoercion sets WithArgumentCoercion
+	ArgumentCoercion bool `json:"argument_coercion,omitempty" yaml:"argument_coercion,omitempty"`
+
+	// ArgumentViolationPolicy sets WithArgumentViolationPolicy
+	ArgumentViolationPolicy ArgumentViolationPolicy `json:"argument_violation_policy,omitempty" yaml:"argument_violation_policy,omitempty"`
+
+	// ArgumentMaxBytes sets ArgumentLimits.MaxBytes of WithArgumentLimits
+	ArgumentMaxBytes int `json:"argument_max_bytes,omitempty" yaml:"argument_max_bytes,omitempty"`
+
+	// ArgumentMaxDepth sets ArgumentLimits.MaxDepth of WithArgumentLimits
+	ArgumentMaxDepth int `json:"argument_max_depth,omitempty" yaml:"argument_max_depth,omitempty"`
+
+	// ArgumentMaxArrayLength sets ArgumentLimits.MaxArrayLength of WithArgumentLimits
+	ArgumentMaxArrayLength int `json:"argument_max_array_length,omitempty" yaml:"argument_max_array_length,omitempty"`
+
+	// ToolCallDeduplication sets WithToolCallDeduplication
+	ToolCallDeduplication bool `json:"tool_call_deduplication,omitempty" yaml:"tool_call_deduplication,omitempty"`
+
+	// MaxToolCallsPerResponse and MaxToolCallsPerConversation set WithToolCallRateLimit
+	MaxToolCallsPerResponse     int `json:"max_tool_calls_per_response,omitempty" yaml:"max_tool_calls_per_response,omitempty"`
+	MaxToolCallsPerConversation int `json:"max_tool_calls_per_conversation,omitempty" yaml:"max_tool_calls_per_conversation,omitempty"`
+
+	// Tool policy and streaming
+
+	// ToolPolicy sets WithToolPolicy
+	ToolPolicy ToolPolicy `json:"tool_policy,omitempty" yaml:"tool_policy,omitempty"`
+
+	// ToolCollectWindow sets WithToolCollectWindow
+	ToolCollectWindow *Duration `json:"tool_collect_window,omitempty" yaml:"tool_collect_window,omitempty"`
+
+	// ToolCollectTotalBudget sets WithToolCollectTotalBudget
+	ToolCollectTotalBudget Duration `json:"tool_collect_total_budget,omitempty" yaml:"tool_collect_total_budget,omitempty"`
+
+	// ToolMaxCalls sets WithToolMaxCalls
+	ToolMaxCalls *int `json:"tool_max_calls,omitempty" yaml:"tool_max_calls,omitempty"`
+
+	// ToolCollectMaxBytes sets WithToolCollectMaxBytes
+	ToolCollectMaxBytes *int `json:"tool_collect_max_bytes,omitempty" yaml:"tool_collect_max_bytes,omitempty"`
+
+	// CancelUpstreamOnStop sets WithCancelUpstreamOnStop
+	CancelUpstreamOnStop *bool `json:"cancel_upstream_on_stop,omitempty" yaml:"cancel_upstream_on_stop,omitempty"`
+
+	// StreamingToolBufferSize sets WithStreamingToolBufferSize
+	StreamingToolBufferSize int `json:"streaming_tool_buffer_size,omitempty" yaml:"streaming_tool_buffer_size,omitempty"`
+
+	// ResponseParseMaxBytes sets WithResponseParseMaxBytes
+	ResponseParseMaxBytes int `json:"response_parse_max_bytes,omitempty" yaml:"response_parse_max_bytes,omitempty"`
+
+	// StreamMemoryLimit sets WithStreamMemoryLimit
+	StreamMemoryLimit int `json:"stream_memory_limit,omitempty" yaml:"stream_memory_limit,omitempty"`
+
+	// AggregateStreamMemoryLimit sets WithAggregateStreamMemoryLimit
+	AggregateStreamMemoryLimit int `json:"aggregate_stream_memory_limit,omitempty" yaml:"aggregate_stream_memory_limit,omitempty"`
+
+	// ResponseCacheSize and ResponseCacheTTL set WithResponseCache
+	ResponseCacheSize int      `json:"response_cache_size,omitempty" yaml:"response_cache_size,omitempty"`
+	ResponseCacheTTL  Duration `json:"response_cache_ttl,omitempty" yaml:"response_cache_ttl,omitempty"`
+
+	// PhaseMetrics sets WithPhaseMetrics
+	PhaseMetrics bool `json:"phase_metrics,omitempty" yaml:"phase_metrics,omitempty"`
+
+	// StreamingEarlyDetection sets WithStreamingEarlyDetection
+	StreamingEarlyDetection int `json:"streaming_early_detection,omitempty" yaml:"streaming_early_detection,omitempty"`
+
+	// BufferDecisionLookahead sets WithBufferDecisionLookahead
+	BufferDecisionLookahead int `json:"buffer_decision_lookahead,omitempty" yaml:"buffer_decision_lookahead,omitempty"`
+
+	// StreamHeartbeat sets WithStreamHeartbeat
+	StreamHeartbeat Duration `json:"stream_heartbeat,omitempty" yaml:"stream_heartbeat,omitempty"`
+
+	// StreamReadAhead sets WithStreamReadAhead
+	StreamReadAhead int `json:"stream_read_ahead,omitempty" yaml:"stream_read_ahead,omitempty"`
+
+	// StreamIdleTimeout sets WithStreamIdleTimeout
+	StreamIdleTimeout Duration `json:"stream_idle_timeout,omitempty" yaml:"stream_idle_timeout,omitempty"`
+
+	// StreamMaxDuration sets WithStreamMaxDuration
+	StreamMaxDuration Duration `json:"stream_max_duration,omitempty" yaml:"stream_max_duration,omitempty"`
+
+	// StreamErrorPolicy sets WithStreamErrorPolicy
+	StreamErrorPolicy StreamErrorPolicy `json:"stream_error_policy,omitempty" yaml:"stream_error_policy,omitempty"`
+
+	// StreamEOFPolicy sets WithStreamEOFPolicy
+	StreamEOFPolicy StreamEOFPolicy `json:"stream_eof_policy,omitempty" yaml:"stream_eof_policy,omitempty"`
+
+	// Performance
+
+	// PromptBufferReuseLimit sets WithPromptBufferReuseLimit
+	PromptBufferReuseLimit int `json:"prompt_buffer_reuse_limit,omitempty" yaml:"prompt_buffer_reuse_limit,omitempty"`
+
+	// BatchConcurrency sets WithBatchConcurrency
+	BatchConcurrency int `json:"batch_concurrency,omitempty" yaml:"batch_concurrency,omitempty"`
+}
+
+// NewFromConfig creates an adapter from cfg. opts are applied after the
+// configuration, so code can override it and add settings that cannot be
+// serialized:
+//
+//	cfg, err := tooladapter.LoadConfig(file)
+//	if err != nil {
+//		return err
+//	}
+//	adapter, err := tooladapter.NewFromConfig(cfg, tooladapter.WithLogger(logger))
+//
+// Values that the corresponding option would ignore with a warning, such as an
+// invalid prompt template or a negative limit, fail with an error wrapping
+// ErrInvalidConfig that lists every rejected value. NewFromConfig(Config{}) is
+// equivalent to New().
+func NewFromConfig(cfg Config, opts ...Option) (*Adapter, error) {
+	configOpts, err := cfg.Options()
+	if err != nil {
+		return nil, err
+	}
+
+	// Options report rejected values as warnings; capture them while the
+	// configuration is applied and restore the logger for the caller's options
+	var rejected []error
+	var logger *slog.Logger
+	all := make([]Option, 0, len(configOpts)+len(opts)+2)
+	all = append(all, func(a *Adapter) {
+		logger = a.logger
+		a.logger = slog.New(&configCheckHandler{rejected: &rejected})
+	})
+	all = append(all, configOpts...)
+	all = append(all, func(a *Adapter) {
+		a.logger = logger
+	})
+	all = append(all, opts...)
+
+	adapter := New(all...)
+	if len(rejected) > 0 {
+		return nil, errors.Join(rejected...)
+	}
+	return adapter, nil
+}
+
+// Options converts cfg to options for New. Unlike NewFromConfig, invalid values are
+// only logged, as with the options themselves. The error reports redaction patterns
+// that fail to compile.
+func (c Config) Options() ([]Option, error) {
+	var opts []Option
+	add := func(opt Option) {
+		opts = append(opts, opt)
+	}
+
+	if c.PromptTemplate != "" {
+		add(WithCustomPromptTemplate(c.PromptTemplate))
+	}
+	if c.ToolPromptTemplate != "" {
+		add(WithToolPromptTemplate(c.ToolPromptTemplate))
+	}
+	if len(c.TemplateExperiments) > 0 {
+		add(WithTemplateExperiments(c.TemplateExperiments, c.ExperimentAssignment))
+	}
+	if c.PromptLanguage != "" {
+		add(WithPromptLanguage(c.PromptLanguage))
+	}
+	if len(c.ToolExamples) > 0 {
+		add(WithToolExamples(c.ToolExamples))
+	}
+	add(WithSystemMessageSupport(c.SystemMessageSupport))
+	add(WithInjectionPosition(c.InjectionPosition))
+	add(WithDeveloperMessageSupport(c.DeveloperMessageSupport))
+	add(WithPreserveToolsField(c.PreserveToolsField))
+	add(WithJSONModeEmulation(c.JSONMode))
+	add(WithStrictOpenAICompatibility(c.StrictOpenAICompatibility))
+	add(WithLegacyFunctionCallOutput(c.LegacyFunctionCallOutput))
+	if c.GBNFGrammar {
+		add(WithGBNFGrammar(true))
+	}
+	add(WithToolCallConstraint(c.ToolCallConstraint))
+	if c.InjectionMarkers || c.InjectionBeginMarker != "" || c.InjectionEndMarker != "" {
+		begin, end := c.InjectionBeginMarker, c.InjectionEndMarker
+		if begin == "" {
+			begin = DefaultInjectionBeginMarker
+		}
+		if end == "" {
+			end = DefaultInjectionEndMarker
+		}
+		add(WithInjectionMarkers(begin, end))
+	}
+	if c.PromptCompaction {
+		add(WithPromptCompaction(true))
+	}
+	if c.SanitizeToolDefinitions {
+		add(WithSanitizeToolDefinitions(true))
+	}
+	if len(c.ToolStopSequences) > 0 {
+		add(WithToolStopSequences(c.ToolStopSequences...))
+	}
+	if c.AssistantPrefill != "" {
+		add(WithAssistantPrefill(c.AssistantPrefill))
+	}
+	if c.KeywordToolSelector > 0 {
+		add(WithToolSelector(KeywordToolSelector(c.KeywordToolSelector)))
+	}
+	if c.ToolResultHeadBytes > 0 || c.ToolResultTailBytes > 0 {
+		add(WithToolResultTransformer(HeadTailToolResultTransformer(c.ToolResultHeadBytes, c.ToolResultTailBytes)))
+	}
+	if c.MultimodalToolResults {
+		add(WithMultimodalToolResults(true))
+	}
+	if c.MaxInjectedPromptBytes != 0 {
+		add(WithMaxInjectedPromptBytes(c.MaxInjectedPromptBytes))
+	}
+	if c.MaxInjectedPromptTokens != 0 {
+		add(WithMaxInjectedPromptTokens(c.MaxInjectedPromptTokens))
+	}
+	if c.ContextWindow != 0 {
+		add(WithContextWindow(c.ContextWindow))
+	}
+	add(WithTruncationStrategy(c.TruncationStrategy))
+	add(WithSchemaFormat(c.SchemaFormat))
+	add(WithPromptStyle(c.PromptStyle))
+	if c.PromptCacheSize != 0 {
+		add(WithPromptCache(c.PromptCacheSize))
+	}
+	if c.ToolNamespace != "" {
+		add(WithToolNamespace(c.ToolNamespace))
+	}
+	add(WithToolCollisionPolicy(c.ToolCollisionPolicy))
+	add(WithUnsupportedToolPolicy(c.UnsupportedToolPolicy))
+
+	var redactors []Redactor
+	if c.RedactCommonSecrets {
+		redactors = append(redactors, CommonSecretRedactor())
+	}
+	for _, pattern := range c.RedactPatterns {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("%w: redact pattern %q: %v", ErrInvalidConfig, pattern, err)
+		}
+		redactors = append(redactors, RegexRedactor(re, ""))
+	}
+	if len(c.RedactJSONFields) > 0 {
+		redactors = append(redactors, JSONFieldRedactor(c.RedactJSONFields...))
+	}
+	if len(redactors) > 0 {
+		add(WithLogRedaction(redactors...))
+	}
+	if c.LogSampling != nil {
+		add(WithLogSampling(*c.LogSampling))
+	}
+	for category, level := range c.LogCategoryLevels {
+		add(WithLogCategoryLevel(category, level))
+	}
+	for category, rate := range c.LogCategorySampling {
+		add(WithLogCategorySampling(category, rate))
+	}
+
+	if c.LenientParsing {
+		add(WithLenientParsing(true))
+	}
+	if len(c.ParserPriority) > 0 {
+		add(WithParserPriority(c.ParserPriority...))
+	}
+	add(WithThinkBlocks(c.ThinkBlocks))
+	if c.StripStopTokens || len(c.StopTokens) > 0 {
+		add(WithStopTokenStripping(c.StopTokens...))
+	}
+	if c.EchoSuppression != 0 {
+		add(WithEchoSuppression(c.EchoSuppression))
+	}
+	if len(c.AllowedToolNames) > 0 {
+		add(WithAllowedToolNames(c.AllowedToolNames))
+	}
+	add(WithUnknownToolPolicy(c.UnknownToolPolicy))
+	if c.UnknownToolMatchThreshold != nil {
+		add(WithUnknownToolMatchThreshold(*c.UnknownToolMatchThreshold))
+	}
+	if len(c.ToolArgumentModes) > 0 {
+		add(WithToolArgumentModes(c.ToolArgumentModes))
+	}
+	if len(c.CodeBlockTools) > 0 {
+		add(WithCodeBlockTools(c.CodeBlockTools))
+	}
+	if c.ArgumentCoercion {
+		add(WithArgumentCoercion(true))
+	}
+	add(WithArgumentViolationPolicy(c.ArgumentViolationPolicy))
+	if c.ArgumentMaxBytes != 0 || c.ArgumentMaxDepth != 0 || c.ArgumentMaxArrayLength != 0 {
+		add(WithArgumentLimits(ArgumentLimits{
+			MaxBytes:       c.ArgumentMaxBytes,
+			MaxDepth:       c.ArgumentMaxDepth,
+			MaxArrayLength: c.ArgumentMaxArrayLength,
+		}))
+	}
+	if c.ToolCallDeduplication {
+		add(WithToolCallDeduplication(true))
+	}
+	if c.MaxToolCallsPerResponse != 0 || c.MaxToolCallsPerConversation != 0 {
+		add(WithToolCallRateLimit(c.MaxToolCallsPerResponse, c.MaxToolCallsPerConversation))
+	}
+
+	add(WithToolPolicy(c.ToolPolicy))
+	if c.ToolCollectWindow != nil {
+		add(WithToolCollectWindow(time.Duration(*c.ToolCollectWindow)))
+	}
+	if c.ToolCollectTotalBudget != 0 {
+		add(WithToolCollectTotalBudget(time.Duration(c.ToolCollectTotalBudget)))
+	}
+	if c.ToolMaxCalls != nil {
+		add(WithToolMaxCalls(*c.ToolMaxCalls))
+	}
+	if c.ToolCollectMaxBytes != nil {
+		add(WithToolCollectMaxBytes(*c.ToolCollectMaxBytes))
+	}
+	if c.CancelUpstreamOnStop != nil {
+		add(WithCancelUpstreamOnStop(*c.CancelUpstreamOnStop))
+	}
+	if c.StreamingToolBufferSize != 0 {
+		add(WithStreamingToolBufferSize(c.StreamingToolBufferSize))
+	}
+	if c.ResponseParseMaxBytes != 0 {
+		add(WithResponseParseMaxBytes(c.ResponseParseMaxBytes))
+	}
+	if c.StreamMemoryLimit != 0 {
+		add(WithStreamMemoryLimit(c.StreamMemoryLimit))
+	}
+	if c.AggregateStreamMemoryLimit != 0 {
+		add(WithAggregateStreamMemoryLimit(c.AggregateStreamMemoryLimit))
+	}
+	if c.ResponseCacheSize != 0 || c.ResponseCacheTTL != 0 {
+		add(WithResponseCache(c.ResponseCacheSize, time.Duration(c.ResponseCacheTTL)))
+	}
+	if c.PhaseMetrics {
+		add(WithPhaseMetrics(true))
+	}
+	if c.StreamingEarlyDetection != 0 {
+		add(WithStreamingEarlyDetection(c.StreamingEarlyDetection))
+	}
+	if c.BufferDecisionLookahead != 0 {
+		add(WithBufferDecisionLookahead(c.BufferDecisionLookahead))
+	}
+	if c.StreamHeartbeat != 0 {
+		add(WithStreamHeartbeat(time.Duration(c.StreamHeartbeat)))
+	}
+	if c.StreamReadAhead != 0 {
+		add(WithStreamReadAhead(c.StreamReadAhead))
+	}
+	if c.StreamIdleTimeout != 0 {
+		add(WithStreamIdleTimeout(time.Duration(c.StreamIdleTimeout)))
+	}
+	if c.StreamMaxDuration != 0 {
+		add(WithStreamMaxDuration(time.Duration(c.StreamMaxDuration)))
+	}
+	add(WithStreamErrorPolicy(c.StreamErrorPolicy))
+	add(WithStreamEOFPolicy(c.StreamEOFPolicy))
+
+	if c.PromptBufferReuseLimit != 0 {
+		add(WithPromptBufferReuseLimit(c.PromptBufferReuseLimit))
+	}
+	if c.BatchConcurrency != 0 {
+		add(WithBatchConcurrency(c.BatchConcurrency))
+	}
+	return opts, nil
+}
+
+// LoadConfig reads a JSON configuration. Unknown fields are rejected, so misspelled
+// settings are not silently ignored. Errors wrap ErrInvalidConfig.
+func LoadConfig(r io.Reader) (Config, error) {
+	var cfg Config
+	dec := json.NewDecoder(r)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&cfg); err != nil {
+		if errors.Is(err, ErrInvalidConfig) {
+			return Config{}, err
+		}
+		return Config{}, fmt.Errorf("%w: %v", ErrInvalidConfig, err)
+	}
+	return cfg, nil
+}
+
+// configCheckHandler turns the warnings logged by options into errors.
+type configCheckHandler struct {
+	rejected *[]error
+}
+
+func (h *configCheckHandler) Enabled(_ context.Context, level slog.Level) bool {
+	return level >= slog.LevelWarn
+}
+
+func (h *configCheckHandler) Handle(_ context.Context, record slog.Record) error {
+	var details []string
+	record.Attrs(func(attr slog.Attr) bool {
+		// Advice is written for option callers, not for configuration files
+		if attr.Key != "implication" && attr.Key != "recommendation" {
+			details = append(details, attr.String())
+		}
+		return true
+	})
+	msg := record.Message
+	if len(details) > 0 {
+		msg += " (" + strings.Join(details, " ") + ")"
+	}
+	*h.rejected = append(*h.rejected, fmt.Errorf("%w: %s", ErrInvalidConfig, msg))
+	return nil
+}
+
+func (h *configCheckHandler) WithAttrs([]slog.Attr) slog.Handler { return h }
+func (h *configCheckHandler) WithGroup(string) slog.Handler      { return h }
+
+// Duration is a time.Duration encoded as a string such as "200ms" or "1.5s".
+type Duration time.Duration
+
+// MarshalText encodes d in time.Duration.String format.
+func (d Duration) MarshalText() ([]byte, error) {
+	return []byte(time.Duration(d).String()), nil
+}
+
+// UnmarshalText decodes a duration accepted by time.ParseDuration.
+func (d *Duration) UnmarshalText(text []byte) error {
+	parsed, err := time.ParseDuration(string(text))
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidConfig, err)
+	}
+	*d = Duration(parsed)
+	return nil
+}
+
+// Configuration names of the policies.
+var (
+	toolPolicyNames = map[ToolPolicy]string{
+		ToolStopOnFirst:     "stop_on_first",
+		ToolCollectThenStop: "collect_then_stop",
+		ToolDrainAll:        "drain_all",
+		ToolAllowMixed:      "allow_mixed",
+		ToolEmitAndContinue: "emit_and_continue",
+	}
+	toolCollisionPolicyNames = map[ToolCollisionPolicy]string{
+		ToolCollisionError:  "error",
+		ToolCollisionRename: "rename",
+	}
+	unsupportedToolPolicyNames = map[UnsupportedToolPolicy]string{
+		UnsupportedToolSkip:  "skip",
+		UnsupportedToolError: "error",
+	}
+	unknownToolPolicyNames = map[UnknownToolPolicy]string{
+		UnknownToolDrop:      "drop",
+		UnknownToolAsContent: "as_content",
+		UnknownToolError:     "error",
+		UnknownToolCorrect:   "correct",
+	}
+	argumentViolationPolicyNames = map[ArgumentViolationPolicy]string{
+		ArgumentViolationIgnore: "ignore",
+		ArgumentViolationReport: "report",
+		ArgumentViolationError:  "error",
+	}
+	truncationStrategyNames = map[TruncationStrategy]string{
+		TruncateDropOldest: "drop_oldest",
+		TruncateWithMarker: "marker",
+	}
+	schemaFormatNames = map[SchemaFormat]string{
+		SchemaCompact: "compact",
+		SchemaPretty:  "pretty",
+	}
+	promptStyleNames = map[PromptStyle]string{
+		PromptStyleProse: "prose",
+		PromptStyleSpec:  "spec",
+	}
+	jsonModePolicyNames = map[JSONModePolicy]string{
+		JSONModeOff:     "off",
+		JSONModeExtract: "extract",
+		JSONModeError:   "error",
+	}
+	streamErrorPolicyNames = map[StreamErrorPolicy]string{
+		StreamErrorFlush:  "flush",
+		StreamErrorReport: "report",
+		StreamErrorRepair: "repair",
+	}
+	streamEOFPolicyNames = map[StreamEOFPolicy]string{
+		StreamEOFEmitCalls: "emit_calls",
+		StreamEOFDropCalls: "drop_calls",
+		StreamEOFFlushText: "flush_text",
+	}
+	thinkBlockPolicyNames = map[ThinkBlockPolicy]string{
+		ThinkBlocksDetect:      "detect",
+		ThinkBlocksPassThrough: "pass_through",
+		ThinkBlocksStrip:       "strip",
+	}
+	injectionPositionNames = map[InjectionPosition]string{
+		PositionAuto:        "auto",
+		PositionSystemEnd:   "system_end",
+		PositionSystemStart: "system_start",
+		PositionLastUser:    "last_user",
+		PositionFirstUser:   "first_user",
+	}
+	experimentAssignmentNames = map[ExperimentAssignment]string{
+		ExperimentAssignmentHash:   "hash",
+		ExperimentAssignmentRandom: "random",
+	}
+	argumentModeNames = map[ArgumentMode]string{
+		ArgumentModeJSON: "json",
+		ArgumentModeText: "text",
+	}
+	toolCallConstraintNames = map[ToolCallConstraint]string{
+		ToolCallConstraintNone:           "none",
+		ToolCallConstraintResponseFormat: "response_format",
+		ToolCallConstraintGuidedJSON:     "guided_json",
+		ToolCallConstraintTGIGrammar:     "tgi_grammar",
+	}
+	callFormatNames = map[CallFormat]string{
+		CallFormatUnknown:   "unknown",
+		CallFormatJSON:      "json",
+		CallFormatCodeFence: "code_fence",
+		CallFormatLenient:   "lenient",
+		CallFormatTextBlock: "text_block",
+	}
+)
+
+// MarshalText encodes the policy by its configuration name, such as "drain_all".
+func (tp ToolPolicy) MarshalText() ([]byte, error) {
+	return marshalPolicy(tp, toolPolicyNames)
+}
+
+// UnmarshalText decodes a configuration name such as "drain_all" or a constant name
+// such as "ToolDrainAll".
+func (tp *ToolPolicy) UnmarshalText(text []byte) error {
+	return unmarshalPolicy(text, tp, toolPolicyNames)
+}
+
+// MarshalText encodes the policy by its configuration name, such as "rename".
+func (p ToolCollisionPolicy) MarshalText() ([]byte, error) {
+	return marshalPolicy(p, toolCollisionPolicyNames)
+}
+
+// UnmarshalText decodes a configuration name such as "rename" or a constant name
+// such as "ToolCollisionRename".
+func (p *ToolCollisionPolicy) UnmarshalText(text []byte) error {
+	return unmarshalPolicy(text, p, toolCollisionPolicyNames)
+}
+
+// MarshalText encodes the policy by its configuration name, such as "skip".
+func (p UnsupportedToolPolicy) MarshalText() ([]byte, error) {
+	return marshalPolicy(p, unsupportedToolPolicyNames)
+}
+
+// UnmarshalText decodes a configuration name such as "skip" or a constant name
+// such as "UnsupportedToolSkip".
+func (p *UnsupportedToolPolicy) UnmarshalText(text []byte) error {
+	return unmarshalPolicy(text, p, unsupportedToolPolicyNames)
+}
+
+// MarshalText encodes the policy by its configuration name, such as "as_content".
+func (p UnknownToolPolicy) MarshalText() ([]byte, error) {
+	return marshalPolicy(p, unknownToolPolicyNames)
+}
+
+// UnmarshalText decodes a configuration name such as "as_content" or a constant
+// name such as "UnknownToolAsContent".
+func (p *UnknownToolPolicy) UnmarshalText(text []byte) error {
+	return unmarshalPolicy(text, p, unknownToolPolicyNames)
+}
+
+// MarshalText encodes the policy by its configuration name, such as "report".
+func (p ArgumentViolationPolicy) MarshalText() ([]byte, error) {
+	return marshalPolicy(p, argumentViolationPolicyNames)
+}
+
+// UnmarshalText decodes a configuration name such as "report" or a constant name
+// such as "ArgumentViolationReport".
+func (p *ArgumentViolationPolicy) UnmarshalText(text []byte) error {
+	return unmarshalPolicy(text, p, argumentViolationPolicyNames)
+}
+
+// MarshalText encodes the strategy by its configuration name, such as "marker".
+func (s TruncationStrategy) MarshalText() ([]byte, error) {
+	return marshalPolicy(s, truncationStrategyNames)
+}
+
+// UnmarshalText decodes a configuration name such as "marker" or a constant name
+// such as "TruncateWithMarker".
+func (s *TruncationStrategy) UnmarshalText(text []byte) error {
+	return unmarshalPolicy(text, s, truncationStrategyNames)
+}
+
+// MarshalText encodes the format by its configuration name, such as "pretty".
+func (f SchemaFormat) MarshalText() ([]byte, error) {
+	return marshalPolicy(f, schemaFormatNames)
+}
+
+// UnmarshalText decodes a configuration name such as "pretty" or a constant name
+// such as "SchemaPretty".
+func (f *SchemaFormat) UnmarshalText(text []byte) error {
+	return unmarshalPolicy(text, f, schemaFormatNames)
+}
+
+// MarshalText encodes the policy by its configuration name, such as "extract".
+func (p JSONModePolicy) MarshalText() ([]byte, error) {
+	return marshalPolicy(p, jsonModePolicyNames)
+}
+
+// UnmarshalText decodes a configuration name such as "extract" or a constant name
+// such as "JSONModeExtract".
+func (p *JSONModePolicy) UnmarshalText(text []byte) error {
+	return unmarshalPolicy(text, p, jsonModePolicyNames)
+}
+
+// MarshalText encodes the style by its configuration name, such as "spec".
+func (s PromptStyle) MarshalText() ([]byte, error) {
+	return marshalPolicy(s, promptStyleNames)
+}
+
+// UnmarshalText decodes a configuration name such as "spec" or a constant name
+// such as "PromptStyleSpec".
+func (s *PromptStyle) UnmarshalText(text []byte) error {
+	return unmarshalPolicy(text, s, promptStyleNames)
+}
+
+// MarshalText encodes the policy by its configuration name, such as "repair".
+func (p StreamErrorPolicy) MarshalText() ([]byte, error) {
+	return marshalPolicy(p, streamErrorPolicyNames)
+}
+
+// UnmarshalText decodes a configuration name such as "repair" or a constant name
+// such as "StreamErrorRepair".
+func (p *StreamErrorPolicy) UnmarshalText(text []byte) error {
+	return unmarshalPolicy(text, p, streamErrorPolicyNames)
+}
+
+// MarshalText encodes the policy by its configuration name, such as "strip".
+func (p ThinkBlockPolicy) MarshalText() ([]byte, error) {
+	return marshalPolicy(p, thinkBlockPolicyNames)
+}
+
+// UnmarshalText decodes a configuration name such as "strip" or a constant name
+// such as "ThinkBlocksStrip".
+func (p *ThinkBlockPolicy) UnmarshalText(text []byte) error {
+	return unmarshalPolicy(text, p, thinkBlockPolicyNames)
+}
+
+// MarshalText encodes the position by its configuration name, such as "last_user".
+func (p InjectionPosition) MarshalText() ([]byte, error) {
+	return marshalPolicy(p, injectionPositionNames)
+}
+
+// UnmarshalText decodes a configuration name such as "last_user" or a constant name
+// such as "PositionLastUser".
+func (p *InjectionPosition) UnmarshalText(text []byte) error {
+	return unmarshalPolicy(text, p, injectionPositionNames)
+}
+
+// MarshalText encodes the assignment by its configuration name, such as "random".
+func (m ExperimentAssignment) MarshalText() ([]byte, error) {
+	return marshalPolicy(m, experimentAssignmentNames)
+}
+
+// UnmarshalText decodes a configuration name such as "random" or a constant name
+// such as "ExperimentAssignmentRandom".
+func (m *ExperimentAssignment) UnmarshalText(text []byte) error {
+	return unmarshalPolicy(text, m, experimentAssignmentNames)
+}
+
+// MarshalText encodes the mode by its configuration name, such as "text".
+func (m ArgumentMode) MarshalText() ([]byte, error) {
+	return marshalPolicy(m, argumentModeNames)
+}
+
+// UnmarshalText decodes a configuration name such as "text" or a constant name
+// such as "ArgumentModeText".
+func (m *ArgumentMode) UnmarshalText(text []byte) error {
+	return unmarshalPolicy(text, m, argumentModeNames)
+}
+
+// MarshalText encodes the format by its configuration name, such as "code_fence".
+func (f CallFormat) MarshalText() ([]byte, error) {
+	return marshalPolicy(f, callFormatNames)
+}
+
+// UnmarshalText decodes a configuration name such as "code_fence" or a constant
+// name such as "CallFormatCodeFence".
+func (f *CallFormat) UnmarshalText(text []byte) error {
+	return unmarshalPolicy(text, f, callFormatNames)
+}
+
+// MarshalText encodes the policy by its configuration name, such as "flush_text".
+func (p StreamEOFPolicy) MarshalText() ([]byte, error) {
+	return marshalPolicy(p, streamEOFPolicyNames)
+}
+
+// UnmarshalText decodes a configuration name such as "flush_text" or a constant
+// name such as "StreamEOFFlushText".
+func (p *StreamEOFPolicy) UnmarshalText(text []byte) error {
+	return unmarshalPolicy(text, p, streamEOFPolicyNames)
+}
+
+// MarshalText encodes the constraint by its configuration name, such as "guided_json".
+func (c ToolCallConstraint) MarshalText() ([]byte, error) {
+	return marshalPolicy(c, toolCallConstraintNames)
+}
+
+// UnmarshalText decodes a configuration name such as "guided_json" or a constant
+// name such as "ToolCallConstraintGuidedJSON".
+func (c *ToolCallConstraint) UnmarshalText(text []byte) error {
+	return unmarshalPolicy(text, c, toolCallConstraintNames)
+}
+
+// policy is implemented by the policy enums.
+type policy interface {
+	comparable
+	fmt.Stringer
+}
+
+func marshalPolicy[P policy](p P, names map[P]string) ([]byte, error) {
+	name, ok := names[p]
+	if !ok {
+		return nil, fmt.Errorf("%w: unknown %s", ErrInvalidConfig, p)
+	}
+	return []byte(name), nil
+}
+
+func unmarshalPolicy[P policy](text []byte, p *P, names map[P]string) error {
+	value := strings.ReplaceAll(strings.ToLower(strings.TrimSpace(string(text))), "-", "_")
+	valid := make([]string, 0, len(names))
+	for candidate, name := range names {
+		if value == name || value == strings.ToLower(candidate.String()) {
+			*p = candidate
+			return nil
+		}
+		valid = append(valid, name)
+	}
+	sort.Strings(valid)
+	return fmt.Errorf("%w: unknown policy %q, expected one of %s", ErrInvalidConfig, text, strings.Join(valid, ", "))
+}
@@ -0,0 +1,434 @@
+package tooladapter_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+
+	tooladapter "github.com/juburr/openai-tool-adapter/v3"
+	"github.com/juburr/openai-tool-adapter/v3/tooltest"
+	"github.com/openai/openai-go/v3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// requestBody returns a transformed request as JSON, with its extra fields.
+func requestBody(t *testing.T, req openai.ChatCompletionNewParams) []byte {
+	t.Helper()
+	body, err := json.Marshal(req)
+	require.NoError(t, err)
+	return body
+}
+
+// messagesJSON returns the messages of a transformed request as JSON.
+func messagesJSON(t *testing.T, req openai.ChatCompletionNewParams) string {
+	t.Helper()
+	data, err := json.Marshal(req.Messages)
+	require.NoError(t, err)
+	return string(data)
+}
+
+func TestLoadConfig(t *testing.T) {
+	t.Run("AllKinds", func(t *testing.T) {
+		cfg, err := tooladapter.LoadConfig(strings.NewReader(`{
+			"prompt_language": "de",
+			"tool_namespace": "weather",
+			"tool_policy": "drain_all",
+			"unknown_tool_policy": "UnknownToolAsContent",
+			"argument_violation_policy": "report",
+			"tool_collision_policy": "rename",
+			"tool_collect_window": "50ms",
+			"tool_max_calls": 0,
+			"cancel_upstream_on_stop": false,
+			"stream_heartbeat": "1.5s",
+			"allowed_tool_names": ["get_weather"],
+			"tool_examples": {"get_weather": [{"request": "Rain in Paris?", "calls": [{"parameters": {"city": "Paris"}}]}]}
+		}`))
+		require.NoError(t, err)
+
+		assert.Equal(t, "de", cfg.PromptLanguage)
+		assert.Equal(t, tooladapter.ToolDrainAll, cfg.ToolPolicy)
+		assert.Equal(t, tooladapter.UnknownToolAsContent, cfg.UnknownToolPolicy)
+		assert.Equal(t, tooladapter.ArgumentViolationReport, cfg.ArgumentViolationPolicy)
+		assert.Equal(t, tooladapter.ToolCollisionRename, cfg.ToolCollisionPolicy)
+		require.NotNil(t, cfg.ToolCollectWindow)
+		assert.Equal(t, 50*time.Millisecond, time.Duration(*cfg.ToolCollectWindow))
+		require.NotNil(t, cfg.ToolMaxCalls)
+		assert.Equal(t, 0, *cfg.ToolMaxCalls, "explicit zero is kept")
+		require.NotNil(t, cfg.CancelUpstreamOnStop)
+		assert.False(t, *cfg.CancelUpstreamOnStop)
+		assert.Equal(t, 1500*time.Millisecond, time.Duration(cfg.StreamHeartbeat))
+		require.Len(t, cfg.ToolExamples["get_weather"], 1)
+		assert.Equal(t, "Rain in Paris?", cfg.ToolExamples["get_weather"][0].Request)
+	})
+
+	t.Run("RoundTrip", func(t *testing.T) {
+		window := tooladapter.Duration(time.Second)
+		cfg := tooladapter.Config{
+			ToolPolicy:        tooladapter.ToolCollectThenStop,
+			UnknownToolPolicy: tooladapter.UnknownToolCorrect,
+			ToolCollectWindow: &window,
+			LenientParsing:    true,
+		}
+		data, err := json.Marshal(cfg)
+		require.NoError(t, err)
+		assert.JSONEq(t, `{
+			"tool_policy": "collect_then_stop",
+			"unknown_tool_policy": "correct",
+			"tool_collect_window": "1s",
+			"lenient_parsing": true
+		}`, string(data), "defaults are omitted")
+
+		loaded, err := tooladapter.LoadConfig(bytes.NewReader(data))
+		require.NoError(t, err)
+		assert.Equal(t, cfg, loaded)
+	})
+
+	t.Run("Errors", func(t *testing.T) {
+		for name, input := range map[string]string{
+			"UnknownField":   `{"tool_polcy": "drain_all"}`,
+			"UnknownPolicy":  `{"tool_policy": "sometimes"}`,
+			"BadDuration":    `{"stream_heartbeat": "soon"}`,
+			"WrongType":      `{"tool_max_calls": "eight"}`,
+			"MalformedJSON":  `{"tool_policy": `,
+			"NumericPolicy":  `{"tool_policy": 2}`,
+			"NumericTimeout": `{"tool_collect_window": 200}`,
+			"JSONMode":       `{"json_mode": "always"}`,
+			"PromptStyle":    `{"prompt_style": "poem"}`,
+			"SchemaFormat":   `{"schema_format": "tiny"}`,
+			"ToolType":       `{"unsupported_tool_policy": "ignore"}`,
+			"CallFormat":     `{"parser_priority": ["pythonic"]}`,
+			"Constraint":     `{"tool_call_constraint": "regex"}`,
+		} {
+			t.Run(name, func(t *testing.T) {
+				_, err := tooladapter.LoadConfig(strings.NewReader(input))
+				require.ErrorIs(t, err, tooladapter.ErrInvalidConfig)
+			})
+		}
+	})
+
+	t.Run("PolicyErrorListsNames", func(t *testing.T) {
+		_, err := tooladapter.LoadConfig(strings.NewReader(`{"unknown_tool_policy": "ignore"}`))
+		require.ErrorContains(t, err, "expected one of as_content, correct, drop, error")
+	})
+}
+
+func TestNewFromConfig(t *testing.T) {
+	t.Run("ZeroConfigMatchesNew", func(t *testing.T) {
+		adapter, err := tooladapter.NewFromConfig(tooladapter.Config{})
+		require.NoError(t, err)
+
+		req := tooltest.Request(tooltest.Tool("get_weather", "Get the weather"))
+		got, err := adapter.TransformCompletionsRequest(req)
+		require.NoError(t, err)
+		want, err := tooladapter.New().TransformCompletionsRequest(req)
+		require.NoError(t, err)
+		assert.Equal(t, messagesJSON(t, want), messagesJSON(t, got))
+	})
+
+	t.Run("AppliesSettings", func(t *testing.T) {
+		adapter, err := tooladapter.NewFromConfig(tooladapter.Config{
+			ToolNamespace:     "weather",
+			ToolStopSequences: []string{"</tool>"},
+			AllowedToolNames:  []string{"get_weather"},
+			UnknownToolPolicy: tooladapter.UnknownToolError,
+		})
+		require.NoError(t, err)
+
+		req, err := adapter.TransformCompletionsRequest(tooltest.Request(tooltest.Tool("get_weather", "")))
+		require.NoError(t, err)
+		assert.Contains(t, messagesJSON(t, req), "weather.get_weather")
+		assert.Equal(t, []string{"</tool>"}, req.Stop.OfStringArray)
+
+		_, err = adapter.TransformCompletionsResponse(tooltest.Completion(`{"name": "delete_all", "parameters": null}`))
+		require.ErrorIs(t, err, tooladapter.ErrUnknownTool)
+	})
+
+	t.Run("RejectsInvalidValues", func(t *testing.T) {
+		negative := -1
+		threshold := 1.5
+		adapter, err := tooladapter.NewFromConfig(tooladapter.Config{
+			PromptTemplate:            "no placeholder",
+			PromptLanguage:            "xx",
+			ToolNamespace:             "not valid!",
+			ToolMaxCalls:              &negative,
+			UnknownToolMatchThreshold: &threshold,
+			BatchConcurrency:          -2,
+		})
+		require.ErrorIs(t, err, tooladapter.ErrInvalidConfig)
+		assert.Nil(t, adapter)
+
+		msg := err.Error()
+		for _, want := range []string{
+			"Invalid prompt template",
+			"supplied_language=xx",
+			"Invalid tool namespace",
+			"supplied_maxCalls=-1",
+			"supplied_threshold=1.5",
+			"supplied_workers=-2",
+		} {
+			assert.Contains(t, msg, want)
+		}
+		assert.NotContains(t, msg, "recommendation")
+	})
+
+	t.Run("RejectsNegativeLimits", func(t *testing.T) {
+		for name, cfg := range map[string]tooladapter.Config{
+			"ArgumentMaxBytes":      {ArgumentMaxBytes: -1},
+			"PromptCacheSize":       {PromptCacheSize: -1},
+			"ResponseCacheSize":     {ResponseCacheSize: -1},
+			"ResponseParseMaxBytes": {ResponseParseMaxBytes: -1},
+			"StreamErrorPolicy":     {StreamErrorPolicy: tooladapter.StreamErrorPolicy(9)},
+		} {
+			t.Run(name, func(t *testing.T) {
+				_, err := tooladapter.NewFromConfig(cfg)
+				require.ErrorIs(t, err, tooladapter.ErrInvalidConfig)
+			})
+		}
+	})
+
+	t.Run("RejectsBadRedactPattern", func(t *testing.T) {
+		_, err := tooladapter.NewFromConfig(tooladapter.Config{RedactPatterns: []string{"("}})
+		require.ErrorIs(t, err, tooladapter.ErrInvalidConfig)
+		assert.ErrorContains(t, err, `redact pattern "("`)
+	})
+
+	t.Run("OptionsOverrideAndLog", func(t *testing.T) {
+		var logs bytes.Buffer
+		logger := slog.New(slog.NewTextHandler(&logs, &slog.HandlerOptions{Level: slog.LevelDebug}))
+		adapter, err := tooladapter.NewFromConfig(
+			tooladapter.Config{ToolNamespace: "weather", RedactCommonSecrets: true},
+			tooladapter.WithToolNamespace(""),
+			tooladapter.WithLogger(logger))
+		require.NoError(t, err)
+
+		req, err := adapter.TransformCompletionsRequest(tooltest.Request(tooltest.Tool("get_weather", "")))
+		require.NoError(t, err)
+		assert.NotContains(t, messagesJSON(t, req), "weather.get_weather", "options take precedence")
+
+		_, err = adapter.TransformCompletionsRequest(openai.ChatCompletionNewParams{
+			Model: openai.ChatModelGPT4o,
+			Messages: []openai.ChatCompletionMessageParamUnion{
+				openai.UserMessage("hi"),
+				openai.ToolMessage("key sk-abcdefghijklmnopqrstuvwxyz", "call_1"),
+			},
+		})
+		require.NoError(t, err)
+		assert.NotEmpty(t, logs.String(), "the caller's logger is used after configuration")
+		assert.NotContains(t, logs.String(), "sk-abcdefghijklmnopqrstuvwxyz", "configured redaction applies")
+	})
+
+	t.Run("LogSettings", func(t *testing.T) {
+		cfg, err := tooladapter.LoadConfig(strings.NewReader(`{
+			"log_category_levels": {"request": "WARN"},
+			"log_category_sampling": {"stream": 0}
+		}`))
+		require.NoError(t, err)
+
+		var logs bytes.Buffer
+		adapter, err := tooladapter.NewFromConfig(cfg,
+			tooladapter.WithLogger(slog.New(slog.NewTextHandler(&logs, &slog.HandlerOptions{Level: slog.LevelDebug}))))
+		require.NoError(t, err)
+		_, err = adapter.TransformCompletionsRequest(tooltest.Request(tooltest.Tool("get_weather", "")))
+		require.NoError(t, err)
+		assert.NotContains(t, logs.String(), "category=request")
+
+		_, err = tooladapter.NewFromConfig(tooladapter.Config{LogCategorySampling: map[tooladapter.LogCategory]float64{"chunks": 0.5}})
+		require.ErrorIs(t, err, tooladapter.ErrInvalidConfig)
+	})
+
+	t.Run("KeywordToolSelector", func(t *testing.T) {
+		adapter, err := tooladapter.NewFromConfig(tooladapter.Config{KeywordToolSelector: 1})
+		require.NoError(t, err)
+
+		req := tooltest.Request(tooltest.Tool("get_weather", "Weather forecast"), tooltest.Tool("send_email", "Send an email"))
+		req.Messages = []openai.ChatCompletionMessageParamUnion{openai.UserMessage("What is the weather forecast?")}
+		got, err := adapter.TransformCompletionsRequestWithContext(context.Background(), req)
+		require.NoError(t, err)
+		assert.Contains(t, messagesJSON(t, got), "get_weather")
+		assert.NotContains(t, messagesJSON(t, got), "send_email")
+	})
+}
+
+// TestNewFromConfig_Fields checks that each configuration field reaches the
+// behavior of the option it stands for.
+func TestNewFromConfig_Fields(t *testing.T) {
+	weather := tooltest.Tool("get_weather", "Get the weather")
+	call := `{"name": "get_weather", "parameters": {"city": "Paris"}}`
+
+	respond := func(t *testing.T, adapter *tooladapter.Adapter, content string) openai.ChatCompletionMessage {
+		t.Helper()
+		resp, err := adapter.TransformCompletionsResponse(tooltest.Completion(content))
+		require.NoError(t, err)
+		return resp.Choices[0].Message
+	}
+	request := func(t *testing.T, adapter *tooladapter.Adapter, req openai.ChatCompletionNewParams) openai.ChatCompletionNewParams {
+		t.Helper()
+		transformed, err := adapter.TransformCompletionsRequest(req)
+		require.NoError(t, err)
+		return transformed
+	}
+	stream := func(adapter *tooladapter.Adapter, contents ...string) tooltest.StreamResult {
+		return tooltest.Drain(adapter.TransformStreamingResponse(tooltest.NewContentStream(contents...)))
+	}
+	longPreface := []string{"Hello ", "world, ", "this is long ", call}
+
+	tests := []struct {
+		name   string
+		config string
+		check  func(t *testing.T, build func(...tooladapter.Option) *tooladapter.Adapter)
+	}{
+		{"ArgumentLimits", `{"argument_max_depth": 1}`, func(t *testing.T, build func(...tooladapter.Option) *tooladapter.Adapter) {
+			msg := respond(t, build(), `{"name": "plan_trip", "parameters": {"trip": {"city": "Paris"}}}`)
+			assert.Empty(t, msg.ToolCalls)
+		}},
+		{"CodeBlockTools", `{"code_block_tools": {"python": {"name": "execute_python"}}}`, func(t *testing.T, build func(...tooladapter.Option) *tooladapter.Adapter) {
+			msg := respond(t, build(), "```python\nprint(1)\n```")
+			require.Len(t, msg.ToolCalls, 1)
+			assert.Equal(t, "execute_python", msg.ToolCalls[0].Function.Name)
+		}},
+		{"ToolArgumentModes", `{"tool_argument_modes": {"run_sql": "text"}}`, func(t *testing.T, build func(...tooladapter.Option) *tooladapter.Adapter) {
+			msg := respond(t, build(), "```run_sql\nSELECT 1\n```")
+			require.Len(t, msg.ToolCalls, 1)
+			assert.JSONEq(t, `{"input": "SELECT 1"}`, msg.ToolCalls[0].Function.Arguments)
+		}},
+		{"ParserPriority", `{"parser_priority": ["code_fence", "CallFormatJSON"]}`, func(t *testing.T, build func(...tooladapter.Option) *tooladapter.Adapter) {
+			msg := respond(t, build(), `{"name": "bare", "parameters": {}}`+"\n```json\n"+`{"name": "fenced", "parameters": {}}`+"\n```")
+			require.NotEmpty(t, msg.ToolCalls)
+			assert.Equal(t, "fenced", msg.ToolCalls[0].Function.Name)
+		}},
+		{"ResponseParseMaxBytes", `{"response_parse_max_bytes": 32}`, func(t *testing.T, build func(...tooladapter.Option) *tooladapter.Adapter) {
+			assert.Empty(t, respond(t, build(), call).ToolCalls)
+		}},
+		{"ResponseCache", `{"response_cache_size": 32, "response_cache_ttl": "30s"}`, func(t *testing.T, build func(...tooladapter.Option) *tooladapter.Adapter) {
+			adapter := build()
+			first, second := respond(t, adapter, call), respond(t, adapter, call)
+			require.Len(t, first.ToolCalls, 1)
+			require.Len(t, second.ToolCalls, 1)
+			assert.Equal(t, first.ToolCalls[0].ID, second.ToolCalls[0].ID, "the repeated response is served from the cache")
+		}},
+		{"StopTokens", `{"stop_tokens": ["[END]"]}`, func(t *testing.T, build func(...tooladapter.Option) *tooladapter.Adapter) {
+			assert.Equal(t, "Done.", respond(t, build(), "Done.[END]").Content)
+		}},
+		{"LegacyFunctionCallOutput", `{"legacy_function_call_output": true}`, func(t *testing.T, build func(...tooladapter.Option) *tooladapter.Adapter) {
+			assert.Equal(t, "get_weather", respond(t, build(), call).FunctionCall.Name)
+		}},
+		{"DeveloperMessageSupport", `{"developer_message_support": true}`, func(t *testing.T, build func(...tooladapter.Option) *tooladapter.Adapter) {
+			transformed := request(t, build(), tooltest.Request(weather))
+			assert.NotNil(t, transformed.Messages[0].OfDeveloper)
+		}},
+		{"InjectionPosition", `{"injection_position": "last_user"}`, func(t *testing.T, build func(...tooladapter.Option) *tooladapter.Adapter) {
+			req := tooltest.Request(weather)
+			req.Messages = append(req.Messages, openai.AssistantMessage("Which city?"), openai.UserMessage("Paris"))
+			transformed := request(t, build(), req)
+			last := transformed.Messages[len(transformed.Messages)-1].OfUser
+			require.NotNil(t, last)
+			assert.True(t, strings.HasPrefix(last.Content.OfString.Value, "Paris\n\n"))
+		}},
+		{"GBNFGrammar", `{"gbnf_grammar": true}`, func(t *testing.T, build func(...tooladapter.Option) *tooladapter.Adapter) {
+			var body struct {
+				Grammar string `json:"grammar"`
+			}
+			require.NoError(t, json.Unmarshal(requestBody(t, request(t, build(), tooltest.Request(weather))), &body))
+			assert.Contains(t, body.Grammar, "get_weather")
+		}},
+		{"ToolCallConstraint", `{"tool_call_constraint": "guided_json"}`, func(t *testing.T, build func(...tooladapter.Option) *tooladapter.Adapter) {
+			req := tooltest.Request(weather)
+			req.ToolChoice = openai.ChatCompletionToolChoiceOptionUnionParam{OfAuto: openai.String("required")}
+			var body struct {
+				GuidedJSON map[string]any `json:"guided_json"`
+			}
+			require.NoError(t, json.Unmarshal(requestBody(t, request(t, build(), req)), &body))
+			assert.Equal(t, "array", body.GuidedJSON["type"])
+		}},
+		{"PreserveToolsField", `{"preserve_tools_field": true}`, func(t *testing.T, build func(...tooladapter.Option) *tooladapter.Adapter) {
+			assert.Len(t, request(t, build(), tooltest.Request(weather)).Tools, 1)
+		}},
+		{"AssistantPrefill", `{"assistant_prefill": "[{"}`, func(t *testing.T, build func(...tooladapter.Option) *tooladapter.Adapter) {
+			transformed := request(t, build(), tooltest.Request(weather))
+			last := transformed.Messages[len(transformed.Messages)-1].OfAssistant
+			require.NotNil(t, last)
+			assert.Equal(t, "[{", last.Content.OfString.Value)
+		}},
+		{"JSONMode", `{"json_mode": "extract"}`, func(t *testing.T, build func(...tooladapter.Option) *tooladapter.Adapter) {
+			assert.Contains(t, injectedText(t, request(t, build(), jsonModeRequest())), tooladapter.JSONModePrompt)
+		}},
+		{"PromptStyle", `{"prompt_style": "spec"}`, func(t *testing.T, build func(...tooladapter.Option) *tooladapter.Adapter) {
+			assert.Contains(t, injectedText(t, request(t, build(), tooltest.Request(weather))), "API specification")
+		}},
+		{"SchemaFormat", `{"schema_format": "pretty"}`, func(t *testing.T, build func(...tooladapter.Option) *tooladapter.Adapter) {
+			assert.Contains(t, injectedText(t, request(t, build(), schemaRequest())), "{\n")
+		}},
+		{"PromptCacheSize", `{"prompt_cache_size": 16}`, func(t *testing.T, build func(...tooladapter.Option) *tooladapter.Adapter) {
+			var hits []bool
+			adapter := build(tooladapter.WithMetricsCallback(func(data tooladapter.MetricEventData) {
+				if d, ok := data.(tooladapter.ToolTransformationData); ok {
+					hits = append(hits, d.PromptCacheHit)
+				}
+			}))
+			request(t, adapter, tooltest.Request(weather))
+			request(t, adapter, tooltest.Request(weather))
+			assert.Equal(t, []bool{false, true}, hits)
+		}},
+		{"ToolResultTruncation", `{"tool_result_head_bytes": 64, "tool_result_tail_bytes": 32}`, func(t *testing.T, build func(...tooladapter.Option) *tooladapter.Adapter) {
+			transformed := request(t, build(), requestWithToolResult(strings.Repeat("x", 1000)))
+			assert.Contains(t, injectedText(t, transformed), "bytes omitted")
+		}},
+		{"MultimodalToolResults", `{"multimodal_tool_results": true}`, func(t *testing.T, build func(...tooladapter.Option) *tooladapter.Adapter) {
+			content, err := json.Marshal([]openai.ChatCompletionContentPartUnionParam{
+				openai.ImageContentPart(openai.ChatCompletionContentPartImageImageURLParam{URL: "https://example.com/chart.png"}),
+			})
+			require.NoError(t, err)
+			transformed := request(t, build(), requestWithToolResult(string(content)))
+			assert.Contains(t, injectedText(t, transformed), "[Attachment 1]")
+		}},
+		{"UnsupportedToolPolicy", `{"unsupported_tool_policy": "error"}`, func(t *testing.T, build func(...tooladapter.Option) *tooladapter.Adapter) {
+			_, err := build().TransformCompletionsRequest(tooltest.Request(customTool("shell"), weather))
+			require.Error(t, err)
+		}},
+		{"ToolPolicy", `{"tool_policy": "emit_and_continue"}`, func(t *testing.T, build func(...tooladapter.Option) *tooladapter.Adapter) {
+			result := stream(build(), call, " Next: ", `{"name": "get_time", "parameters": {}}`)
+			assert.Equal(t, []string{"get_weather", "get_time"}, result.ToolNames())
+			assert.Equal(t, " Next: ", result.Content)
+		}},
+		{"ToolCollectTotalBudget", `{"tool_collect_total_budget": "1ns"}`, func(t *testing.T, build func(...tooladapter.Option) *tooladapter.Adapter) {
+			result := stream(build(), `{"name": "get_weather", `, `"parameters": {}}`)
+			assert.Empty(t, result.ToolCalls, "the budget is spent before the call completes")
+		}},
+		{"StreamMemoryLimit", `{"tool_policy": "drain_all", "stream_memory_limit": 20}`, func(t *testing.T, build func(...tooladapter.Option) *tooladapter.Adapter) {
+			assert.Empty(t, stream(build(), longPreface...).ToolCalls)
+		}},
+		{"AggregateStreamMemoryLimit", `{"tool_policy": "drain_all", "aggregate_stream_memory_limit": 20}`, func(t *testing.T, build func(...tooladapter.Option) *tooladapter.Adapter) {
+			assert.Empty(t, stream(build(), longPreface...).ToolCalls)
+		}},
+		{"StreamEOFPolicy", `{"stream_eof_policy": "flush_text"}`, func(t *testing.T, build func(...tooladapter.Option) *tooladapter.Adapter) {
+			partial := []string{"[" + call + ", ", `{"name": "get_time", "parameters": {"zone": "U`}
+			result := stream(build(tooladapter.WithToolPolicy(tooladapter.ToolCollectThenStop)), partial...)
+			assert.Empty(t, result.ToolCalls)
+			assert.Equal(t, strings.Join(partial, ""), result.Content)
+		}},
+		{"StreamErrorPolicy", `{"stream_error_policy": "report"}`, func(t *testing.T, build func(...tooladapter.Option) *tooladapter.Adapter) {
+			result := tooltest.Drain(build().TransformStreamingResponse(disconnectedStream("Let me check. ", `{"name": "get_`)))
+			assert.Equal(t, "Let me check. ", result.Content, "the partial call is not emitted")
+		}},
+		{"StrictOpenAICompatibility", `{"strict_openai_compatibility": true}`, func(t *testing.T, build func(...tooladapter.Option) *tooladapter.Adapter) {
+			result := stream(build(), call)
+			assert.Equal(t, []string{"get_weather", ""}, result.ToolNames(), "the call, then its arguments")
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg, err := tooladapter.LoadConfig(strings.NewReader(tt.config))
+			require.NoError(t, err)
+			tt.check(t, func(opts ...tooladapter.Option) *tooladapter.Adapter {
+				adapter, err := tooladapter.NewFromConfig(cfg, opts...)
+				require.NoError(t, err)
+				return adapter
+			})
+		})
+	}
+}
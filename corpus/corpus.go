@@ -0,0 +1,180 @@
+// Package corpus ships sanitized real-model outputs together with the tool calls the
+// adapter is expected to find in them, and a conformance runner that checks an
+// adapter configuration against every sample.
+//
+// Applications that customize the adapter can run the corpus in their own tests to
+// confirm their options still parse all known output shapes:
+//
+//	func TestAdapterConformance(t *testing.T) {
+//	    corpus.RunCorpus(t, tooladapter.New(myOptions...))
+//	}
+//
+// New samples are added as entries in the JSON files under samples/, one file per
+// model family.
+package corpus
+
+import (
+	"embed"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"reflect"
+	"sort"
+	"testing"
+
+	tooladapter "github.com/juburr/openai-tool-adapter/v3"
+	"github.com/openai/openai-go/v3"
+)
+
+//go:embed samples/*.json
+var samplesFS embed.FS
+
+// Sample is a single model output and the tool calls expected from it.
+type Sample struct {
+	// ID uniquely identifies the sample and names its subtest
+	ID string `json:"id"`
+
+	// Model is the model that produced the output
+	Model string `json:"model"`
+
+	// Description explains what makes the output shape interesting
+	Description string `json:"description"`
+
+	// Content is the raw assistant message content
+	Content string `json:"content"`
+
+	// ToolCalls lists the expected tool calls in order. An empty list means the
+	// content must be passed through as a regular answer.
+	ToolCalls []ExpectedCall `json:"tool_calls"`
+}
+
+// ExpectedCall is a tool call the adapter should extract from a sample.
+type ExpectedCall struct {
+	Name      string          `json:"name"`
+	Arguments json.RawMessage `json:"arguments"`
+}
+
+// Samples returns the samples shipped with the package, ordered by file and then
+// by position within the file.
+func Samples() ([]Sample, error) {
+	return LoadSamples(samplesFS)
+}
+
+// LoadSamples reads samples from every .json file in the samples directory of fsys.
+// Each file holds a JSON array of samples. Sample IDs must be unique.
+func LoadSamples(fsys fs.FS) ([]Sample, error) {
+	files, err := fs.Glob(fsys, "samples/*.json")
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(files)
+
+	var samples []Sample
+	seen := make(map[string]string)
+	for _, file := range files {
+		data, err := fs.ReadFile(fsys, file)
+		if err != nil {
+			return nil, err
+		}
+		var fileSamples []Sample
+		if err := json.Unmarshal(data, &fileSamples); err != nil {
+			return nil, fmt.Errorf("%s: %w", file, err)
+		}
+		for _, sample := range fileSamples {
+			if sample.ID == "" {
+				return nil, fmt.Errorf("%s: sample without id", file)
+			}
+			if other, dup := seen[sample.ID]; dup {
+				return nil, fmt.Errorf("%s: duplicate sample id %q (also in %s)", file, sample.ID, other)
+			}
+			seen[sample.ID] = file
+			samples = append(samples, sample)
+		}
+	}
+	return samples, nil
+}
+
+// RunCorpus runs every shipped sample through adapter as a subtest of t.
+func RunCorpus(t *testing.T, adapter *tooladapter.Adapter) {
+	t.Helper()
+	samples, err := Samples()
+	if err != nil {
+		t.Fatalf("loading corpus: %v", err)
+	}
+	RunSamples(t, adapter, samples)
+}
+
+// RunSamples runs samples through adapter's non-streaming response transformation.
+//
+// For samples that expect tool calls, the adapter must return at least one call and
+// the calls it returns must match the start of the expected list, so policies and
+// limits that keep fewer calls (such as ToolStopOnFirst) still conform. For samples
+// that expect none, the content must come back unchanged.
+func RunSamples(t *testing.T, adapter *tooladapter.Adapter, samples []Sample) {
+	t.Helper()
+	for _, sample := range samples {
+		t.Run(sample.ID, func(t *testing.T) {
+			checkSample(t, adapter, sample)
+		})
+	}
+}
+
+func checkSample(t *testing.T, adapter *tooladapter.Adapter, sample Sample) {
+	t.Helper()
+	resp, err := adapter.TransformCompletionsResponse(openai.ChatCompletion{
+		Choices: []openai.ChatCompletionChoice{
+			{
+				Message: openai.ChatCompletionMessage{
+					Role:    "assistant",
+					Content: sample.Content,
+				},
+			},
+		},
+	})
+	if err != nil {
+		t.Fatalf("%s (%s): transform failed: %v", sample.ID, sample.Model, err)
+	}
+	if len(resp.Choices) != 1 {
+		t.Fatalf("%s (%s): expected 1 choice, got %d", sample.ID, sample.Model, len(resp.Choices))
+	}
+	message := resp.Choices[0].Message
+
+	if len(sample.ToolCalls) == 0 {
+		if len(message.ToolCalls) > 0 {
+			t.Errorf("%s (%s): expected no tool calls, got %s", sample.ID, sample.Model, message.ToolCalls[0].Function.Name)
+		}
+		if message.Content != sample.Content {
+			t.Errorf("%s (%s): content was modified:\n got: %q\nwant: %q", sample.ID, sample.Model, message.Content, sample.Content)
+		}
+		return
+	}
+
+	if len(message.ToolCalls) == 0 {
+		t.Fatalf("%s (%s): no tool calls detected in %q", sample.ID, sample.Model, sample.Content)
+	}
+	if len(message.ToolCalls) > len(sample.ToolCalls) {
+		t.Fatalf("%s (%s): expected at most %d tool calls, got %d", sample.ID, sample.Model, len(sample.ToolCalls), len(message.ToolCalls))
+	}
+	for i, call := range message.ToolCalls {
+		want := sample.ToolCalls[i]
+		if call.Function.Name != want.Name {
+			t.Errorf("%s (%s): tool call %d: name %q, want %q", sample.ID, sample.Model, i, call.Function.Name, want.Name)
+		}
+		if !jsonEqual(call.Function.Arguments, want.Arguments) {
+			t.Errorf("%s (%s): tool call %d: arguments %s, want %s", sample.ID, sample.Model, i, call.Function.Arguments, want.Arguments)
+		}
+	}
+}
+
+// jsonEqual reports whether got and want hold the same JSON value. Missing
+// expected arguments are treated as null.
+func jsonEqual(got string, want json.RawMessage) bool {
+	if len(want) == 0 {
+		want = json.RawMessage("null")
+	}
+	var gotValue, wantValue any
+	if json.Unmarshal([]byte(got), &gotValue) != nil || json.Unmarshal(want, &wantValue) != nil {
+		return false
+	}
+	return reflect.DeepEqual(gotValue, wantValue)
+}
@@ -0,0 +1,60 @@
+package corpus_test
+
+import (
+	"testing"
+	"testing/fstest"
+
+	tooladapter "github.com/juburr/openai-tool-adapter/v3"
+	"github.com/juburr/openai-tool-adapter/v3/corpus"
+)
+
+func TestCorpusDefaultOptions(t *testing.T) {
+	corpus.RunCorpus(t, tooladapter.New())
+}
+
+func TestCorpusPolicies(t *testing.T) {
+	for _, policy := range []tooladapter.ToolPolicy{
+		tooladapter.ToolCollectThenStop,
+		tooladapter.ToolDrainAll,
+	} {
+		t.Run(policy.String(), func(t *testing.T) {
+			corpus.RunCorpus(t, tooladapter.New(tooladapter.WithToolPolicy(policy)))
+		})
+	}
+}
+
+func TestCorpusLenientParsing(t *testing.T) {
+	corpus.RunCorpus(t, tooladapter.New(tooladapter.WithLenientParsing(true)))
+}
+
+func TestSamplesCoverModelFamilies(t *testing.T) {
+	samples, err := corpus.Samples()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var withCalls, withoutCalls int
+	for _, sample := range samples {
+		if sample.Model == "" || sample.Content == "" {
+			t.Errorf("sample %s is missing a model or content", sample.ID)
+		}
+		if len(sample.ToolCalls) > 0 {
+			withCalls++
+		} else {
+			withoutCalls++
+		}
+	}
+	if withCalls == 0 || withoutCalls == 0 {
+		t.Errorf("corpus needs both positive and negative samples, got %d and %d", withCalls, withoutCalls)
+	}
+}
+
+func TestLoadSamplesRejectsDuplicates(t *testing.T) {
+	fsys := fstest.MapFS{
+		"samples/a.json": {Data: []byte(`[{"id": "dup", "model": "m", "content": "x"}]`)},
+		"samples/b.json": {Data: []byte(`[{"id": "dup", "model": "m", "content": "y"}]`)},
+	}
+	if _, err := corpus.LoadSamples(fsys); err == nil {
+		t.Fatal("expected duplicate id error")
+	}
+}
@@ -0,0 +1,115 @@
+package tooladapter_test
+
+import (
+	"strings"
+	"testing"
+
+	tooladapter "github.com/juburr/openai-tool-adapter/v3"
+	"github.com/juburr/openai-tool-adapter/v3/tooltest"
+	"github.com/openai/openai-go/v3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWithDeveloperMessageSupport(t *testing.T) {
+	adapter := tooladapter.New(tooladapter.WithDeveloperMessageSupport(true))
+	tool := tooltest.Tool("get_weather", "Get the weather")
+
+	t.Run("CreatesDeveloperMessage", func(t *testing.T) {
+		transformed, err := adapter.TransformCompletionsRequest(tooltest.Request(tool))
+		require.NoError(t, err)
+
+		require.Len(t, transformed.Messages, 2)
+		require.NotNil(t, transformed.Messages[0].OfDeveloper)
+		assert.Contains(t, transformed.Messages[0].OfDeveloper.Content.OfString.Value, "get_weather")
+		require.NotNil(t, transformed.Messages[1].OfUser)
+	})
+
+	t.Run("NoMessages", func(t *testing.T) {
+		req := tooltest.Request(tool)
+		req.Messages = nil
+		transformed, err := adapter.TransformCompletionsRequest(req)
+		require.NoError(t, err)
+
+		require.Len(t, transformed.Messages, 1)
+		assert.NotNil(t, transformed.Messages[0].OfDeveloper)
+	})
+
+	t.Run("AppendsToLastDeveloperMessage", func(t *testing.T) {
+		named := openai.DeveloperMessage("Be brief.")
+		named.OfDeveloper.Name = openai.String("ops")
+		req := tooltest.Request(tool)
+		req.Messages = []openai.ChatCompletionMessageParamUnion{
+			openai.DeveloperMessage("Be kind."),
+			openai.SystemMessage("You are a weather bot."),
+			named,
+			openai.UserMessage("Weather in Paris?"),
+		}
+
+		transformed, err := adapter.TransformCompletionsRequest(req)
+		require.NoError(t, err)
+
+		require.Len(t, transformed.Messages, 4)
+		assert.Equal(t, "Be kind.", transformed.Messages[0].OfDeveloper.Content.OfString.Value)
+		assert.Equal(t, "You are a weather bot.", transformed.Messages[1].OfSystem.Content.OfString.Value)
+		last := transformed.Messages[2].OfDeveloper
+		require.NotNil(t, last)
+		assert.True(t, strings.HasPrefix(last.Content.OfString.Value, "Be brief.\n\n"))
+		assert.Contains(t, last.Content.OfString.Value, "get_weather")
+		assert.Equal(t, "ops", last.Name.Value)
+		assert.Equal(t, "Be brief.", req.Messages[2].OfDeveloper.Content.OfString.Value, "the caller's request is not modified")
+	})
+
+	t.Run("FallsBackToSystemMessage", func(t *testing.T) {
+		req := tooltest.Request(tool)
+		req.Messages = append([]openai.ChatCompletionMessageParamUnion{openai.SystemMessage("You are a weather bot.")}, req.Messages...)
+
+		transformed, err := adapter.TransformCompletionsRequest(req)
+		require.NoError(t, err)
+
+		require.Len(t, transformed.Messages, 2)
+		require.NotNil(t, transformed.Messages[0].OfSystem)
+		assert.Contains(t, transformed.Messages[0].OfSystem.Content.OfString.Value, "get_weather")
+	})
+
+	t.Run("DisabledIgnoresDeveloperMessages", func(t *testing.T) {
+		req := tooltest.Request(tool)
+		req.Messages = append([]openai.ChatCompletionMessageParamUnion{openai.DeveloperMessage("Be brief.")}, req.Messages...)
+
+		transformed, err := tooladapter.New(tooladapter.WithSystemMessageSupport(true)).TransformCompletionsRequest(req)
+		require.NoError(t, err)
+
+		require.Len(t, transformed.Messages, 3)
+		assert.NotNil(t, transformed.Messages[0].OfSystem)
+		assert.Equal(t, "Be brief.", transformed.Messages[1].OfDeveloper.Content.OfString.Value)
+	})
+
+	t.Run("Compaction", func(t *testing.T) {
+		compacting := tooladapter.New(
+			tooladapter.WithDeveloperMessageSupport(true),
+			tooladapter.WithPromptCompaction(true),
+		)
+		first, err := compacting.TransformCompletionsRequest(tooltest.Request(tool))
+		require.NoError(t, err)
+
+		next := tooltest.Request(tool)
+		next.Messages = append(first.Messages, openai.AssistantMessage("Sunny."), openai.UserMessage("And Rome?"))
+		second, err := compacting.TransformCompletionsRequest(next)
+		require.NoError(t, err)
+
+		developerMessages := 0
+		for _, msg := range second.Messages {
+			if msg.OfDeveloper != nil {
+				developerMessages++
+				assert.Equal(t, 1, strings.Count(msg.OfDeveloper.Content.OfString.Value, "get_weather"))
+			}
+		}
+		assert.Equal(t, 1, developerMessages)
+	})
+
+	t.Run("Config", func(t *testing.T) {
+		cfg, err := tooladapter.LoadConfig(strings.NewReader(`{"developer_message_support": true}`))
+		require.NoError(t, err)
+		assert.True(t, cfg.DeveloperMessageSupport)
+	})
+}
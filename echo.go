@@ -0,0 +1,154 @@
+package tooladapter
+
+import (
+	"context"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/openai/openai-go/v3"
+)
+
+// toolResultsKey is the context key for the results attached by
+// ContextWithToolResults.
+type toolResultsKey struct{}
+
+// ContextWithToolResults returns a copy of ctx recording the contents of the tool
+// results injected into the request, so response transformation can apply
+// WithEchoSuppression to the reply. Pass the results of the original request to the
+// response transformation that handles its reply:
+//
+//	ctx = tooladapter.ContextWithToolResults(ctx, `{"temperature": 21, "unit": "celsius"}`)
+//	resp, err := adapter.TransformCompletionsResponseWithContext(ctx, completion)
+//
+// Sessions and CompleteWithRetry attach the request's tool results automatically.
+func ContextWithToolResults(ctx context.Context, results ...string) context.Context {
+	return context.WithValue(ctx, toolResultsKey{}, results)
+}
+
+// toolResultsFrom returns the tool results attached to ctx by ContextWithToolResults.
+func toolResultsFrom(ctx context.Context) []string {
+	if ctx == nil {
+		return nil
+	}
+	results, _ := ctx.Value(toolResultsKey{}).([]string)
+	return results
+}
+
+// toolMessageContents returns the text of the tool messages among messages.
+func toolMessageContents(messages []openai.ChatCompletionMessageParamUnion) []string {
+	var contents []string
+	for _, msg := range messages {
+		if msg.OfTool == nil {
+			continue
+		}
+		content := msg.OfTool.Content.OfString.Or("")
+		for _, part := range msg.OfTool.Content.OfArrayOfContentParts {
+			content += part.Text
+		}
+		if content != "" {
+			contents = append(contents, content)
+		}
+	}
+	return contents
+}
+
+// emptyCodeFencePattern matches a code fence left empty by the removal of the echo
+// it held.
+var emptyCodeFencePattern = regexp.MustCompile("```[A-Za-z0-9_-]*\\s*```")
+
+// blankLinesPattern matches the blank lines an echo removal can leave behind.
+var blankLinesPattern = regexp.MustCompile(`\n[ \t]*\n(?:[ \t]*\n)+`)
+
+// echoedSpans returns the byte ranges of content that copy a passage of at least
+// threshold characters verbatim from one of results, longest match first at each
+// position.
+func echoedSpans(content string, results []string, threshold int) [][2]int {
+	var spans [][2]int
+	for start := 0; start < len(content); {
+		end := start
+		for _, result := range results {
+			// Extend the match one character at a time while result still contains it
+			candidate := start
+			for candidate < len(content) {
+				_, size := utf8.DecodeRuneInString(content[candidate:])
+				if !strings.Contains(result, content[start:candidate+size]) {
+					break
+				}
+				candidate += size
+			}
+			if candidate > end {
+				end = candidate
+			}
+		}
+		if end > start && utf8.RuneCountInString(strings.TrimSpace(content[start:end])) >= threshold {
+			spans = append(spans, [2]int{start, end})
+			start = end
+			continue
+		}
+		_, size := utf8.DecodeRuneInString(content[start:])
+		start += size
+	}
+	return spans
+}
+
+// suppressEchoes removes verbatim copies of the tool results attached to ctx from
+// the content of the choices without tool calls, when WithEchoSuppression is set.
+// A choice whose content is nothing but an echo keeps its content, since removing
+// it would leave no answer; the echo is only flagged in the ParseReport and the log.
+func (a *Adapter) suppressEchoes(ctx context.Context, resp openai.ChatCompletion) openai.ChatCompletion {
+	if a.echoThreshold <= 0 {
+		return resp
+	}
+	results := toolResultsFrom(ctx)
+	if len(results) == 0 {
+		return resp
+	}
+	report := parseReportFrom(ctx)
+
+	copied := false
+	for i, choice := range resp.Choices {
+		content := choice.Message.Content
+		if len(choice.Message.ToolCalls) > 0 || content == "" {
+			continue
+		}
+		spans := echoedSpans(content, results, a.echoThreshold)
+		if len(spans) == 0 {
+			continue
+		}
+
+		var b strings.Builder
+		previous := 0
+		for _, span := range spans {
+			b.WriteString(content[previous:span[0]])
+			previous = span[1]
+		}
+		b.WriteString(content[previous:])
+		stripped := emptyCodeFencePattern.ReplaceAllString(b.String(), "")
+		stripped = strings.TrimSpace(blankLinesPattern.ReplaceAllString(stripped, "\n\n"))
+
+		if report != nil && i < len(report.Choices) {
+			report.Choices[i].EchoedToolResults = len(spans)
+		}
+		if strings.Trim(stripped, " \t\r\n.,:;-*>\"'`") == "" {
+			a.log(LogCategoryParse).Warn("Response content repeats tool results verbatim, content kept",
+				"choice_index", i,
+				"echoes", len(spans))
+			continue
+		}
+		a.log(LogCategoryParse).Debug("Removed tool result echoes from response content",
+			"choice_index", i,
+			"echoes", len(spans),
+			"removed_bytes", len(content)-len(stripped))
+
+		if !copied {
+			resp.Choices = append([]openai.ChatCompletionChoice(nil), resp.Choices...)
+			copied = true
+		}
+		resp.Choices[i].Message.Content = stripped
+		if report != nil && i < len(report.Choices) {
+			report.Choices[i].SuppressedContentLength += len(content) - len(stripped)
+		}
+	}
+	return resp
+}
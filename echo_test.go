@@ -0,0 +1,64 @@
+package tooladapter_test
+
+import (
+	"context"
+	"testing"
+
+	tooladapter "github.com/juburr/openai-tool-adapter/v3"
+	"github.com/juburr/openai-tool-adapter/v3/tooltest"
+	"github.com/openai/openai-go/v3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWithEchoSuppression(t *testing.T) {
+	const result = `{"city": "Paris", "temperature": 21, "unit": "celsius", "conditions": "partly cloudy"}`
+	adapter := tooladapter.New(tooladapter.WithEchoSuppression(30))
+	ctx := tooladapter.ContextWithToolResults(context.Background(), result)
+
+	t.Run("StripsEcho", func(t *testing.T) {
+		completion := tooltest.Completion("The tool returned:\n\n```json\n" + result + "\n```\n\nIt is 21°C and partly cloudy in Paris.")
+		resp, report, err := adapter.TransformCompletionsResponseWithReport(ctx, completion)
+		require.NoError(t, err)
+		assert.Equal(t, "The tool returned:\n\nIt is 21°C and partly cloudy in Paris.", resp.Choices[0].Message.Content)
+		assert.Equal(t, 1, report.Choices[0].EchoedToolResults)
+		assert.Positive(t, report.Choices[0].SuppressedContentLength)
+		assert.Contains(t, completion.Choices[0].Message.Content, result, "the input is not modified")
+	})
+
+	t.Run("FlagsEchoOnlyReply", func(t *testing.T) {
+		completion := tooltest.Completion(result)
+		resp, report, err := adapter.TransformCompletionsResponseWithReport(ctx, completion)
+		require.NoError(t, err)
+		assert.Equal(t, result, resp.Choices[0].Message.Content, "removing the echo would leave no answer")
+		assert.Equal(t, 1, report.Choices[0].EchoedToolResults)
+	})
+
+	t.Run("ShortQuotesKept", func(t *testing.T) {
+		content := `The conditions are "partly cloudy" at 21 degrees.`
+		resp, err := adapter.TransformCompletionsResponseWithContext(ctx, tooltest.Completion(content))
+		require.NoError(t, err)
+		assert.Equal(t, content, resp.Choices[0].Message.Content)
+	})
+
+	t.Run("WithoutToolResults", func(t *testing.T) {
+		content := "Result: " + result
+		resp, err := adapter.TransformCompletionsResponse(tooltest.Completion(content))
+		require.NoError(t, err)
+		assert.Equal(t, content, resp.Choices[0].Message.Content)
+	})
+
+	t.Run("Session", func(t *testing.T) {
+		session := adapter.NewSession()
+		req := tooltest.Request(tooltest.Tool("get_weather", "Get the weather"))
+		req.Messages = append(req.Messages,
+			openai.AssistantMessage(""),
+			openai.ToolMessage(result, "call_1"))
+		_, err := session.TransformRequest(context.Background(), req)
+		require.NoError(t, err)
+
+		resp, err := session.TransformResponse(context.Background(), tooltest.Completion(result+"\nIt is mild in Paris today."))
+		require.NoError(t, err)
+		assert.Equal(t, "It is mild in Paris today.", resp.Choices[0].Message.Content)
+	})
+}
@@ -0,0 +1,191 @@
+package tooladapter
+
+import (
+	"encoding"
+	"encoding/json"
+	"fmt"
+	"os"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// EnvPrefix starts the names of the environment variables read by ConfigFromEnv.
+const EnvPrefix = "TOOLADAPTER_"
+
+// Environment variables that are not named after a Config field.
+const (
+	// EnvConfigFile names a JSON configuration file read with LoadConfig before the
+	// other variables are applied
+	EnvConfigFile = EnvPrefix + "CONFIG_FILE"
+
+	// EnvPromptPreset selects a built-in prompt: "default" or the code of one of the
+	// translations listed by PromptLanguages
+	EnvPromptPreset = EnvPrefix + "PROMPT_PRESET"
+)
+
+// envAliases are short names for frequently set variables.
+var envAliases = map[string]string{
+	EnvPrefix + "POLICY":    EnvPrefix + "TOOL_POLICY",
+	EnvPrefix + "MAX_CALLS": EnvPrefix + "TOOL_MAX_CALLS",
+}
+
+// NewFromEnv creates an adapter configured by environment variables, for
+// container deployments where settings change without a rebuild. It is
+// NewFromConfig with the Config read by ConfigFromEnv; opts are applied after it.
+func NewFromEnv(opts ...Option) (*Adapter, error) {
+	cfg, err := ConfigFromEnv()
+	if err != nil {
+		return nil, err
+	}
+	return NewFromConfig(cfg, opts...)
+}
+
+// ConfigFromEnv reads a Config from environment variables. Each Config field is
+// set by EnvPrefix followed by its upper-case JSON name, for example
+// TOOLADAPTER_TOOL_POLICY=drain_all or TOOLADAPTER_TOOL_COLLECT_WINDOW=150ms.
+// TOOLADAPTER_POLICY and TOOLADAPTER_MAX_CALLS are short for TOOLADAPTER_TOOL_POLICY
+// and TOOLADAPTER_TOOL_MAX_CALLS, and EnvPromptPreset selects a built-in prompt.
+//
+// Values use the encodings of configuration files, except that lists are
+// comma-separated (TOOLADAPTER_ALLOWED_TOOL_NAMES=get_weather,search) and
+// TOOLADAPTER_TOOL_EXAMPLES holds JSON. Booleans accept the forms of
+// strconv.ParseBool. When EnvConfigFile is set, the file is loaded first and the
+// variables override its settings.
+//
+// Unknown TOOLADAPTER_ variables and unparsable values fail with an error wrapping
+// ErrInvalidConfig that names the variable.
+func ConfigFromEnv() (Config, error) {
+	var cfg Config
+	if path, ok := os.LookupEnv(EnvConfigFile); ok && path != "" {
+		f, err := os.Open(path)
+		if err != nil {
+			return Config{}, fmt.Errorf("%w: %s: %v", ErrInvalidConfig, EnvConfigFile, err)
+		}
+		cfg, err = LoadConfig(f)
+		_ = f.Close()
+		if err != nil {
+			return Config{}, fmt.Errorf("%s %s: %w", EnvConfigFile, path, err)
+		}
+	}
+
+	fields := configEnvFields()
+	vars := make(map[string]string)
+	for _, entry := range os.Environ() {
+		name, value, _ := strings.Cut(entry, "=")
+		if !strings.HasPrefix(name, EnvPrefix) || name == EnvConfigFile {
+			continue
+		}
+		if target, ok := envAliases[name]; ok {
+			name = target
+		} else if _, ok := fields[name]; !ok && name != EnvPromptPreset {
+			return Config{}, fmt.Errorf("%w: unknown variable %s", ErrInvalidConfig, name)
+		}
+		if other, set := vars[name]; set && other != value {
+			return Config{}, fmt.Errorf("%w: %s and its alias disagree", ErrInvalidConfig, name)
+		}
+		vars[name] = value
+	}
+
+	// Apply in a fixed order so errors are deterministic
+	names := make([]string, 0, len(vars))
+	for name := range vars {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	v := reflect.ValueOf(&cfg).Elem()
+	for _, name := range names {
+		value := vars[name]
+		if name == EnvPromptPreset {
+			if err := applyPromptPreset(&cfg, value); err != nil {
+				return Config{}, err
+			}
+			continue
+		}
+		if err := setEnvField(v.Field(fields[name]), value); err != nil {
+			return Config{}, fmt.Errorf("%w: %s: %v", ErrInvalidConfig, name, err)
+		}
+	}
+	return cfg, nil
+}
+
+// configEnvFields maps variable names to Config field indexes.
+func configEnvFields() map[string]int {
+	t := reflect.TypeOf(Config{})
+	fields := make(map[string]int, t.NumField())
+	for i := 0; i < t.NumField(); i++ {
+		name, _, _ := strings.Cut(t.Field(i).Tag.Get("json"), ",")
+		if name != "" && name != "-" {
+			fields[EnvPrefix+strings.ToUpper(name)] = i
+		}
+	}
+	return fields
+}
+
+// applyPromptPreset sets the prompt language of a preset name.
+func applyPromptPreset(cfg *Config, preset string) error {
+	if strings.EqualFold(preset, "default") {
+		cfg.PromptLanguage = defaultPromptLanguage
+		return nil
+	}
+	code, ok := lookupPromptLanguage(preset)
+	if !ok {
+		return fmt.Errorf("%w: %s: unknown preset %q, expected default or one of %s",
+			ErrInvalidConfig, EnvPromptPreset, preset, strings.Join(PromptLanguages(), ", "))
+	}
+	cfg.PromptLanguage = code
+	return nil
+}
+
+// setEnvField parses value into a Config field.
+func setEnvField(field reflect.Value, value string) error {
+	if field.Kind() == reflect.Pointer {
+		elem := reflect.New(field.Type().Elem())
+		if err := setEnvField(elem.Elem(), value); err != nil {
+			return err
+		}
+		field.Set(elem)
+		return nil
+	}
+	if u, ok := field.Addr().Interface().(encoding.TextUnmarshaler); ok {
+		return u.UnmarshalText([]byte(value))
+	}
+
+	switch field.Kind() {
+	case reflect.String:
+		field.SetString(value)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return err
+		}
+		field.SetBool(b)
+	case reflect.Int:
+		n, err := strconv.Atoi(value)
+		if err != nil {
+			return err
+		}
+		field.SetInt(int64(n))
+	case reflect.Float64:
+		f, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return err
+		}
+		field.SetFloat(f)
+	case reflect.Slice:
+		var items []string
+		for _, item := range strings.Split(value, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				items = append(items, item)
+			}
+		}
+		field.Set(reflect.ValueOf(items))
+	case reflect.Map:
+		return json.Unmarshal([]byte(value), field.Addr().Interface())
+	default:
+		return fmt.Errorf("unsupported type %s", field.Type())
+	}
+	return nil
+}
@@ -0,0 +1,126 @@
+package tooladapter_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	tooladapter "github.com/juburr/openai-tool-adapter/v3"
+	"github.com/juburr/openai-tool-adapter/v3/tooltest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConfigFromEnv(t *testing.T) {
+	t.Run("Empty", func(t *testing.T) {
+		cfg, err := tooladapter.ConfigFromEnv()
+		require.NoError(t, err)
+		assert.Equal(t, tooladapter.Config{}, cfg)
+	})
+
+	t.Run("AllKinds", func(t *testing.T) {
+		t.Setenv("TOOLADAPTER_POLICY", "collect-then-stop")
+		t.Setenv("TOOLADAPTER_MAX_CALLS", "3")
+		t.Setenv("TOOLADAPTER_TOOL_COLLECT_WINDOW", "150ms")
+		t.Setenv("TOOLADAPTER_CANCEL_UPSTREAM_ON_STOP", "false")
+		t.Setenv("TOOLADAPTER_SYSTEM_MESSAGE_SUPPORT", "1")
+		t.Setenv("TOOLADAPTER_UNKNOWN_TOOL_POLICY", "UnknownToolCorrect")
+		t.Setenv("TOOLADAPTER_UNKNOWN_TOOL_MATCH_THRESHOLD", "0.7")
+		t.Setenv("TOOLADAPTER_ALLOWED_TOOL_NAMES", "get_weather, search,")
+		t.Setenv("TOOLADAPTER_TOOL_NAMESPACE", "weather")
+		t.Setenv("TOOLADAPTER_TOOL_EXAMPLES", `{"get_weather": [{"request": "Rain in Paris?"}]}`)
+		t.Setenv("TOOLADAPTER_GBNF_GRAMMAR", "true")
+		t.Setenv("TOOLADAPTER_TOOL_CALL_CONSTRAINT", "guided-json")
+
+		cfg, err := tooladapter.ConfigFromEnv()
+		require.NoError(t, err)
+		assert.Equal(t, tooladapter.ToolCollectThenStop, cfg.ToolPolicy)
+		require.NotNil(t, cfg.ToolMaxCalls)
+		assert.Equal(t, 3, *cfg.ToolMaxCalls)
+		require.NotNil(t, cfg.ToolCollectWindow)
+		assert.Equal(t, 150*time.Millisecond, time.Duration(*cfg.ToolCollectWindow))
+		require.NotNil(t, cfg.CancelUpstreamOnStop)
+		assert.False(t, *cfg.CancelUpstreamOnStop)
+		assert.True(t, cfg.SystemMessageSupport)
+		assert.Equal(t, tooladapter.UnknownToolCorrect, cfg.UnknownToolPolicy)
+		require.NotNil(t, cfg.UnknownToolMatchThreshold)
+		assert.InDelta(t, 0.7, *cfg.UnknownToolMatchThreshold, 1e-9)
+		assert.Equal(t, []string{"get_weather", "search"}, cfg.AllowedToolNames)
+		assert.Equal(t, "weather", cfg.ToolNamespace)
+		assert.Equal(t, "Rain in Paris?", cfg.ToolExamples["get_weather"][0].Request)
+		assert.True(t, cfg.GBNFGrammar)
+		assert.Equal(t, tooladapter.ToolCallConstraintGuidedJSON, cfg.ToolCallConstraint)
+	})
+
+	t.Run("PromptPreset", func(t *testing.T) {
+		t.Setenv("TOOLADAPTER_PROMPT_PRESET", "pt-BR")
+		cfg, err := tooladapter.ConfigFromEnv()
+		require.NoError(t, err)
+		assert.Equal(t, "pt", cfg.PromptLanguage)
+
+		t.Setenv("TOOLADAPTER_PROMPT_PRESET", "default")
+		cfg, err = tooladapter.ConfigFromEnv()
+		require.NoError(t, err)
+		assert.Equal(t, "en", cfg.PromptLanguage)
+	})
+
+	t.Run("ConfigFile", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "adapter.json")
+		require.NoError(t, os.WriteFile(path, []byte(`{"tool_policy": "drain_all", "lenient_parsing": true}`), 0o600))
+		t.Setenv("TOOLADAPTER_CONFIG_FILE", path)
+		t.Setenv("TOOLADAPTER_POLICY", "allow_mixed")
+
+		cfg, err := tooladapter.ConfigFromEnv()
+		require.NoError(t, err)
+		assert.True(t, cfg.LenientParsing, "from the file")
+		assert.Equal(t, tooladapter.ToolAllowMixed, cfg.ToolPolicy, "variables override the file")
+	})
+
+	t.Run("Errors", func(t *testing.T) {
+		tests := []struct {
+			name  string
+			env   map[string]string
+			where string
+		}{
+			{"UnknownVariable", map[string]string{"TOOLADAPTER_TOOL_POLCY": "drain_all"}, "TOOLADAPTER_TOOL_POLCY"},
+			{"BadInt", map[string]string{"TOOLADAPTER_MAX_CALLS": "many"}, "TOOLADAPTER_TOOL_MAX_CALLS"},
+			{"BadBool", map[string]string{"TOOLADAPTER_LENIENT_PARSING": "maybe"}, "TOOLADAPTER_LENIENT_PARSING"},
+			{"BadPolicy", map[string]string{"TOOLADAPTER_POLICY": "sometimes"}, "TOOLADAPTER_TOOL_POLICY"},
+			{"BadDuration", map[string]string{"TOOLADAPTER_STREAM_HEARTBEAT": "soon"}, "TOOLADAPTER_STREAM_HEARTBEAT"},
+			{"BadExamples", map[string]string{"TOOLADAPTER_TOOL_EXAMPLES": "["}, "TOOLADAPTER_TOOL_EXAMPLES"},
+			{"BadPreset", map[string]string{"TOOLADAPTER_PROMPT_PRESET": "klingon"}, "TOOLADAPTER_PROMPT_PRESET"},
+			{"AliasConflict", map[string]string{"TOOLADAPTER_POLICY": "drain_all", "TOOLADAPTER_TOOL_POLICY": "allow_mixed"}, "alias"},
+			{"MissingFile", map[string]string{"TOOLADAPTER_CONFIG_FILE": "/nonexistent/adapter.json"}, "TOOLADAPTER_CONFIG_FILE"},
+		}
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				for name, value := range tt.env {
+					t.Setenv(name, value)
+				}
+				_, err := tooladapter.ConfigFromEnv()
+				require.ErrorIs(t, err, tooladapter.ErrInvalidConfig)
+				assert.ErrorContains(t, err, tt.where)
+			})
+		}
+	})
+}
+
+func TestNewFromEnv(t *testing.T) {
+	t.Run("AppliesSettings", func(t *testing.T) {
+		t.Setenv("TOOLADAPTER_TOOL_NAMESPACE", "weather")
+
+		adapter, err := tooladapter.NewFromEnv()
+		require.NoError(t, err)
+		req, err := adapter.TransformCompletionsRequest(tooltest.Request(tooltest.Tool("get_weather", "")))
+		require.NoError(t, err)
+		assert.Contains(t, messagesJSON(t, req), "weather.get_weather")
+	})
+
+	t.Run("RejectsInvalidValues", func(t *testing.T) {
+		t.Setenv("TOOLADAPTER_BATCH_CONCURRENCY", "-1")
+
+		_, err := tooladapter.NewFromEnv()
+		require.ErrorIs(t, err, tooladapter.ErrInvalidConfig)
+	})
+}
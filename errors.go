@@ -0,0 +1,284 @@
+package tooladapter
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Sentinel errors for branching with errors.Is. Errors returned by the adapter wrap
+// one of these where it applies; typed errors such as *UnknownToolCallError also
+// match their sentinel.
+var (
+	// ErrBufferLimitExceeded reports that a size limit stopped tool call detection.
+	// Streams fall back to emitting the buffered text instead of failing, so it is
+	// delivered through the Err field of ParseEventLimitExceeded events.
+	ErrBufferLimitExceeded = errors.New("buffer limit exceeded")
+
+	// ErrToolValidationFailed reports a tool or function name that does not meet
+	// the adapter's naming rules.
+	ErrToolValidationFailed = errors.New("function name validation failed")
+
+	// ErrUnknownTool reports a tool call for a function outside the allowed tool
+	// names. It is matched by *UnknownToolCallError.
+	ErrUnknownTool = errors.New("unknown tool")
+
+	// ErrToolNameCollision reports tools that resolve to the same model-facing name.
+	// It is matched by *ToolNameCollisionError.
+	ErrToolNameCollision = errors.New("tool name collision")
+
+	// ErrUnsupportedTool reports a tool other than a function tool with
+	// UnsupportedToolError in effect. It is matched by *UnsupportedToolTypeError.
+	ErrUnsupportedTool = errors.New("unsupported tool type")
+
+	// ErrTemplateRender reports a prompt template that cannot be rendered.
+	ErrTemplateRender = errors.New("template validation failed")
+
+	// ErrMalformedToolCall reports model output that attempts a tool call the parser
+	// cannot accept. It is returned by CompleteWithRetry once retries are exhausted.
+	ErrMalformedToolCall = errors.New("malformed tool call")
+
+	// ErrArgumentViolation reports tool call arguments outside the enum, range or
+	// length constraints of the tool's schema. It is matched by
+	// *ToolArgumentError.
+	ErrArgumentViolation = errors.New("argument violates schema")
+
+	// ErrPromptTooLarge reports an injected prompt over the ceiling set by
+	// WithMaxInjectedPromptBytes or WithMaxInjectedPromptTokens. It is matched by
+	// *PromptTooLargeError.
+	ErrPromptTooLarge = errors.New("injected prompt too large")
+
+	// ErrStreamInterrupted reports an upstream stream that failed while a tool call
+	// was partly buffered. It is matched by *StreamInterruptedError.
+	ErrStreamInterrupted = errors.New("stream interrupted mid tool call")
+
+	// ErrArgumentsIncomplete is returned by the Arguments reader of an
+	// ArgumentStream when the stream ends, fails or flushes the tool call as text
+	// before its arguments are complete.
+	ErrArgumentsIncomplete = errors.New("tool call arguments incomplete")
+
+	// ErrStreamTimeout reports an upstream stream that exceeded
+	// WithStreamIdleTimeout or WithStreamMaxDuration. It is matched by
+	// *StreamTimeoutError.
+	ErrStreamTimeout = errors.New("stream timeout")
+
+	// ErrInvalidJSONResponse reports a reply to a json_object request that holds no
+	// JSON object, with JSONModeError in effect.
+	ErrInvalidJSONResponse = errors.New("reply is not a JSON object")
+
+	// ErrToolTimeout reports a tool run by an Executor that did not finish within
+	// its timeout.
+	ErrToolTimeout = errors.New("tool execution timed out")
+
+	// ErrToolPanicked reports a tool run by an Executor that panicked.
+	ErrToolPanicked = errors.New("tool execution panicked")
+
+	// ErrInvalidConfig reports a Config value that NewFromConfig or LoadConfig cannot
+	// accept.
+	ErrInvalidConfig = errors.New("invalid configuration")
+
+	// ErrInvalidCheckpoint reports a stream checkpoint that StreamAdapter.Restore
+	// cannot resume.
+	ErrInvalidCheckpoint = errors.New("invalid stream checkpoint")
+)
+
+// TransformPhase identifies where in the adapter a TransformError occurred.
+type TransformPhase string
+
+const (
+	// PhaseRequest covers TransformCompletionsRequest.
+	PhaseRequest TransformPhase = "request"
+
+	// PhaseResponse covers TransformCompletionsResponse.
+	PhaseResponse TransformPhase = "response"
+
+	// PhaseStreaming covers StreamAdapter and SSEStreamAdapter.
+	PhaseStreaming TransformPhase = "streaming"
+)
+
+// TransformError wraps errors returned by request, response and stream
+// transformation with where they happened. Use errors.As to inspect it and
+// errors.Is with the sentinel errors to branch on the cause. Context cancellation
+// errors are returned unwrapped.
+type TransformError struct {
+	// Phase is the transformation that failed.
+	Phase TransformPhase
+
+	// Op describes the failed step, such as "build tool prompt".
+	Op string
+
+	// ChoiceIndex is the response choice being processed, or -1 when the error is
+	// not tied to a choice.
+	ChoiceIndex int
+
+	// Err is the underlying error.
+	Err error
+}
+
+func (e *TransformError) Error() string {
+	return fmt.Sprintf("failed to %s: %v", e.Op, e.Err)
+}
+
+func (e *TransformError) Unwrap() error {
+	return e.Err
+}
+
+// wrapTransformError wraps err in a *TransformError. Context errors are returned
+// unchanged since they originate with the caller.
+func wrapTransformError(phase TransformPhase, op string, choiceIndex int, err error) error {
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return err
+	}
+	return &TransformError{Phase: phase, Op: op, ChoiceIndex: choiceIndex, Err: err}
+}
+
+// ToolNameCollisionError is returned when a request declares multiple tools that
+// resolve to the same model-facing function name. Injecting such tools would give
+// the model ambiguous definitions, so the transformation is rejected instead.
+type ToolNameCollisionError struct {
+	// Name is the colliding model-facing function name.
+	Name string
+
+	// Indices lists the positions of the colliding tools in the request's Tools slice.
+	Indices []int
+}
+
+func (e *ToolNameCollisionError) Error() string {
+	indices := make([]string, len(e.Indices))
+	for i, idx := range e.Indices {
+		indices[i] = fmt.Sprintf("%d", idx)
+	}
+	return fmt.Sprintf("tool name collision: %q is declared by tools at indices [%s]", e.Name, strings.Join(indices, ", "))
+}
+
+// Is reports whether target is ErrToolNameCollision.
+func (e *ToolNameCollisionError) Is(target error) bool {
+	return target == ErrToolNameCollision
+}
+
+// UnsupportedToolTypeError is returned by request transformation when a request
+// declares a tool the injected prompt cannot describe, such as a custom tool, and
+// UnsupportedToolError is in effect.
+type UnsupportedToolTypeError struct {
+	// Index is the position of the tool in the request's Tools slice.
+	Index int
+
+	// Type is the tool's type, such as "custom", or "" when no variant is set.
+	Type string
+}
+
+func (e *UnsupportedToolTypeError) Error() string {
+	if e.Type == "" {
+		return fmt.Sprintf("unsupported tool type: tool at index %d declares no tool variant", e.Index)
+	}
+	return fmt.Sprintf("unsupported tool type: %q tool at index %d cannot be injected into the prompt", e.Type, e.Index)
+}
+
+// Is reports whether target is ErrUnsupportedTool.
+func (e *UnsupportedToolTypeError) Is(target error) bool {
+	return target == ErrUnsupportedTool
+}
+
+// UnknownToolCallError is returned by response transformation when the model calls a
+// function outside the allowed tool names and UnknownToolError is in effect.
+type UnknownToolCallError struct {
+	// Name is the unknown function name emitted by the model.
+	Name string
+}
+
+func (e *UnknownToolCallError) Error() string {
+	return fmt.Sprintf("model called unknown tool %q", e.Name)
+}
+
+// Is reports whether target is ErrUnknownTool.
+func (e *UnknownToolCallError) Is(target error) bool {
+	return target == ErrUnknownTool
+}
+
+// PromptTooLargeError is returned by request transformation when the rendered tool
+// instructions exceed WithMaxInjectedPromptBytes or WithMaxInjectedPromptTokens.
+// The request is rejected rather than sent to a model whose context it may not fit.
+type PromptTooLargeError struct {
+	// Bytes is the size of the injected prompt.
+	Bytes int
+
+	// MaxBytes is the configured byte ceiling, or 0 when none is set.
+	MaxBytes int
+
+	// Tokens is the estimated token count of the injected prompt, or 0 when no
+	// token ceiling is set.
+	Tokens int
+
+	// MaxTokens is the configured token ceiling, or 0 when none is set.
+	MaxTokens int
+
+	// ToolCount is the number of tools the prompt describes.
+	ToolCount int
+}
+
+func (e *PromptTooLargeError) Error() string {
+	if e.MaxBytes > 0 && e.Bytes > e.MaxBytes {
+		return fmt.Sprintf("injected prompt for %d tools is %d bytes, over the limit of %d", e.ToolCount, e.Bytes, e.MaxBytes)
+	}
+	return fmt.Sprintf("injected prompt for %d tools is about %d tokens, over the limit of %d", e.ToolCount, e.Tokens, e.MaxTokens)
+}
+
+// Is reports whether target is ErrPromptTooLarge.
+func (e *PromptTooLargeError) Is(target error) bool {
+	return target == ErrPromptTooLarge
+}
+
+// StreamInterruptedError is returned by StreamAdapter.Err when the upstream stream
+// failed while a potential tool call was buffered. What was emitted of the buffered
+// content depends on WithStreamErrorPolicy. It unwraps to the upstream error.
+type StreamInterruptedError struct {
+	// Partial is the buffered content at the time of the failure.
+	Partial string
+
+	// Repaired reports whether StreamErrorRepair completed the buffered JSON.
+	Repaired bool
+
+	// Err is the upstream error.
+	Err error
+}
+
+func (e *StreamInterruptedError) Error() string {
+	return fmt.Sprintf("upstream stream failed with %d bytes of a tool call buffered: %v", len(e.Partial), e.Err)
+}
+
+func (e *StreamInterruptedError) Unwrap() error {
+	return e.Err
+}
+
+// Is reports whether target is ErrStreamInterrupted.
+func (e *StreamInterruptedError) Is(target error) bool {
+	return target == ErrStreamInterrupted
+}
+
+// StreamTimeoutError is returned by StreamAdapter.Err when the upstream stream sent
+// no chunk within WithStreamIdleTimeout or ran longer than WithStreamMaxDuration.
+// The upstream stream is closed when the timeout expires.
+type StreamTimeoutError struct {
+	// Idle reports an idle timeout; otherwise the maximum duration was exceeded.
+	Idle bool
+
+	// Timeout is the exceeded limit.
+	Timeout time.Duration
+
+	// Chunks is the number of upstream chunks received before the timeout.
+	Chunks int
+}
+
+func (e *StreamTimeoutError) Error() string {
+	if e.Idle {
+		return fmt.Sprintf("stream timeout: no chunk received for %s after %d chunks", e.Timeout, e.Chunks)
+	}
+	return fmt.Sprintf("stream timeout: exceeded maximum duration of %s after %d chunks", e.Timeout, e.Chunks)
+}
+
+// Is reports whether target is ErrStreamTimeout.
+func (e *StreamTimeoutError) Is(target error) bool {
+	return target == ErrStreamTimeout
+}
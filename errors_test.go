@@ -0,0 +1,124 @@
+package tooladapter_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	tooladapter "github.com/juburr/openai-tool-adapter/v3"
+	"github.com/openai/openai-go/v3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestErrorTaxonomy(t *testing.T) {
+	t.Run("UnknownToolResponse", func(t *testing.T) {
+		adapter := tooladapter.New(
+			tooladapter.WithAllowedToolNames([]string{"get_weather"}),
+			tooladapter.WithUnknownToolPolicy(tooladapter.UnknownToolError))
+		completion := createMockCompletion(`{"name": "get_weather", "parameters": null}`)
+		completion.Choices = append(completion.Choices, openai.ChatCompletionChoice{
+			Index:   1,
+			Message: openai.ChatCompletionMessage{Role: "assistant", Content: `{"name": "delete_all", "parameters": null}`},
+		})
+		_, err := adapter.TransformCompletionsResponse(completion)
+
+		require.ErrorIs(t, err, tooladapter.ErrUnknownTool)
+		var transformErr *tooladapter.TransformError
+		require.ErrorAs(t, err, &transformErr)
+		assert.Equal(t, tooladapter.PhaseResponse, transformErr.Phase)
+		assert.Equal(t, "process tool calls", transformErr.Op)
+		assert.Equal(t, 1, transformErr.ChoiceIndex)
+
+		var unknown *tooladapter.UnknownToolCallError
+		require.ErrorAs(t, err, &unknown)
+		assert.Equal(t, "delete_all", unknown.Name)
+	})
+
+	t.Run("UnknownToolStreaming", func(t *testing.T) {
+		adapter := tooladapter.New(
+			tooladapter.WithAllowedToolNames([]string{"get_weather"}),
+			tooladapter.WithUnknownToolPolicy(tooladapter.UnknownToolError))
+		stream := adapter.TransformStreamingResponse(NewMockStream([]openai.ChatCompletionChunk{
+			createStreamChunk(`{"name": "delete_all", "parameters": null}`),
+			createFinishChunk("stop"),
+		}))
+		for stream.Next() {
+		}
+
+		require.ErrorIs(t, stream.Err(), tooladapter.ErrUnknownTool)
+		var transformErr *tooladapter.TransformError
+		require.ErrorAs(t, stream.Err(), &transformErr)
+		assert.Equal(t, tooladapter.PhaseStreaming, transformErr.Phase)
+	})
+
+	t.Run("ToolNameCollision", func(t *testing.T) {
+		adapter := tooladapter.New()
+		_, err := adapter.TransformCompletionsRequest(createMockRequest([]openai.ChatCompletionToolUnionParam{
+			createMockTool("search", ""),
+			createMockTool("search", ""),
+		}))
+
+		require.ErrorIs(t, err, tooladapter.ErrToolNameCollision)
+		var transformErr *tooladapter.TransformError
+		require.ErrorAs(t, err, &transformErr)
+		assert.Equal(t, tooladapter.PhaseRequest, transformErr.Phase)
+		assert.Equal(t, "resolve tool names", transformErr.Op)
+		assert.Equal(t, -1, transformErr.ChoiceIndex)
+	})
+
+	t.Run("ToolValidation", func(t *testing.T) {
+		adapter := tooladapter.New(tooladapter.WithToolNamespace("docs"))
+		_, err := adapter.TransformCompletionsRequest(createMockRequest([]openai.ChatCompletionToolUnionParam{
+			createMockTool(strings.Repeat("a", 70), ""),
+		}))
+		require.ErrorIs(t, err, tooladapter.ErrToolValidationFailed)
+
+		assert.ErrorIs(t, tooladapter.ValidateFunctionName(""), tooladapter.ErrToolValidationFailed)
+		assert.ErrorIs(t, tooladapter.ValidateFunctionName("a.b.c"), tooladapter.ErrToolValidationFailed)
+		assert.EqualError(t, tooladapter.ValidateFunctionName(""), "function name validation failed: name cannot be empty")
+	})
+
+	t.Run("TemplateRender", func(t *testing.T) {
+		err := tooladapter.ValidatePromptTemplate("No placeholder")
+		require.ErrorIs(t, err, tooladapter.ErrTemplateRender)
+		assert.Contains(t, err.Error(), "exactly one %s placeholder")
+
+		assert.ErrorIs(t, tooladapter.ValidatePromptTemplate("%s and %s"), tooladapter.ErrTemplateRender)
+		assert.NoError(t, tooladapter.ValidatePromptTemplate("Tools:\n%s"))
+	})
+
+	t.Run("ContextErrorsUnwrapped", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		_, err := tooladapter.New().TransformCompletionsRequestWithContext(ctx, createMockRequest([]openai.ChatCompletionToolUnionParam{
+			createMockTool("search", ""),
+		}))
+
+		require.ErrorIs(t, err, context.Canceled)
+		var transformErr *tooladapter.TransformError
+		assert.False(t, errors.As(err, &transformErr))
+	})
+
+	t.Run("BufferLimitEvent", func(t *testing.T) {
+		var limitErr error
+		adapter := tooladapter.New(
+			tooladapter.WithStreamingToolBufferSize(64),
+			tooladapter.WithParseEventHook(func(e tooladapter.ParseEvent) {
+				if e.Type == tooladapter.ParseEventLimitExceeded {
+					limitErr = e.Err
+				}
+			}))
+		stream := adapter.TransformStreamingResponse(NewMockStream([]openai.ChatCompletionChunk{
+			createStreamChunk(`{"name": "get_weather", "parameters": {"city": "`),
+			createStreamChunk(strings.Repeat("x", 100)),
+			createFinishChunk("stop"),
+		}))
+		for stream.Next() {
+		}
+
+		require.NoError(t, stream.Err(), "streams fall back to text instead of failing")
+		assert.ErrorIs(t, limitErr, tooladapter.ErrBufferLimitExceeded)
+	})
+}
@@ -0,0 +1,116 @@
+package tooladapter
+
+import "time"
+
+// ParseEventType identifies a step in tool call detection reported to a parse
+// event hook.
+type ParseEventType string
+
+const (
+	// ParseEventBufferStart fires when a stream starts withholding content because it
+	// may contain a tool call.
+	ParseEventBufferStart ParseEventType = "buffer_start"
+
+	// ParseEventBufferFlush fires when withheld streaming content turned out not to
+	// be a tool call and is released as regular text.
+	ParseEventBufferFlush ParseEventType = "buffer_flush"
+
+	// ParseEventToolDetected fires once for every tool call recognized in a response.
+	ParseEventToolDetected ParseEventType = "tool_detected"
+
+	// ParseEventRepairApplied fires when malformed output was converted into a valid
+	// tool call candidate, such as JSON5 or YAML accepted by WithLenientParsing.
+	ParseEventRepairApplied ParseEventType = "repair_applied"
+
+	// ParseEventLimitExceeded fires when a size limit stops tool call detection.
+	ParseEventLimitExceeded ParseEventType = "limit_exceeded"
+)
+
+// Detail values reported with ParseEventRepairApplied and ParseEventLimitExceeded.
+const (
+	ParseDetailLenient               = "lenient"
+	ParseDetailNormalized            = "normalized"
+	ParseDetailTruncated             = "truncated"
+	ParseDetailStreamBufferLimit     = "stream_buffer_limit"
+	ParseDetailToolCollectMaxBytes   = "tool_collect_max_bytes"
+	ParseDetailResponseParseMaxBytes = "response_parse_max_bytes"
+
+	ParseDetailStreamMemoryLimit          = "stream_memory_limit"
+	ParseDetailAggregateStreamMemoryLimit = "aggregate_stream_memory_limit"
+
+	// ParseDetailStopSequence is only reported in ToolCallSource, for JSON that
+	// parsed once a tool stop sequence the API stripped was restored
+	ParseDetailStopSequence = "stop_sequence"
+)
+
+// ParseEvent describes a single step in tool call detection. Events are meant for
+// debugging why a model's output was or wasn't recognized as a tool call, so they
+// carry sizes and names but never the content itself.
+type ParseEvent struct {
+	// Type identifies what happened
+	Type ParseEventType `json:"type"`
+
+	// Time is when the event occurred
+	Time time.Time `json:"time"`
+
+	// Size is the number of bytes involved: buffered bytes for buffer and limit
+	// events, the candidate length for repairs, and the content length for detections
+	Size int `json:"size"`
+
+	// Limit is the configured limit that was exceeded (ParseEventLimitExceeded only)
+	Limit int `json:"limit,omitempty"`
+
+	// ToolName is the name of the detected tool (ParseEventToolDetected only)
+	ToolName string `json:"tool_name,omitempty"`
+
+	// Streaming indicates whether the event occurred while processing a stream
+	Streaming bool `json:"streaming"`
+
+	// Detail names the repair or limit involved, using the ParseDetail constants
+	Detail string `json:"detail,omitempty"`
+
+	// Err is ErrBufferLimitExceeded for ParseEventLimitExceeded events
+	Err error `json:"-"`
+}
+
+// emitParseEvent stamps and delivers a parse event if a hook is configured. Like
+// emitMetric, panics in the hook are recovered and logged.
+func (a *Adapter) emitParseEvent(event ParseEvent) {
+	if a.parseEventHook == nil {
+		return
+	}
+
+	defer func() {
+		if r := recover(); r != nil {
+			a.logger.Error("Parse event hook panicked - event dropped but operation continues",
+				"panic", r,
+				"event_type", event.Type)
+		}
+	}()
+
+	event.Time = time.Now()
+	a.parseEventHook(event)
+}
+
+// emitToolDetectedEvents emits a ParseEventToolDetected event and a
+// ToolCallDetectedData metric for each call found in a choice.
+func (a *Adapter) emitToolDetectedEvents(calls []functionCall, choiceIndex, contentLength int, streaming bool) {
+	if a.parseEventHook == nil && !a.phaseMetricsEnabled() {
+		return
+	}
+	for i, call := range calls {
+		a.emitParseEvent(ParseEvent{
+			Type:      ParseEventToolDetected,
+			Size:      contentLength,
+			ToolName:  call.Name,
+			Streaming: streaming,
+		})
+		a.emitPhaseMetric(ToolCallDetectedData{
+			ToolName:       call.Name,
+			CallIndex:      i,
+			ChoiceIndex:    choiceIndex,
+			ArgumentsBytes: len(call.Parameters),
+			Streaming:      streaming,
+		})
+	}
+}
@@ -0,0 +1,185 @@
+package tooladapter_test
+
+import (
+	"bytes"
+	"log/slog"
+	"strings"
+	"testing"
+
+	tooladapter "github.com/juburr/openai-tool-adapter/v3"
+	"github.com/openai/openai-go/v3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// eventRecorder collects parse events delivered to a hook.
+type eventRecorder struct {
+	events []tooladapter.ParseEvent
+}
+
+func (r *eventRecorder) hook(e tooladapter.ParseEvent) {
+	r.events = append(r.events, e)
+}
+
+func (r *eventRecorder) types() []tooladapter.ParseEventType {
+	types := make([]tooladapter.ParseEventType, len(r.events))
+	for i, e := range r.events {
+		types[i] = e.Type
+	}
+	return types
+}
+
+func drainStream(t *testing.T, stream *tooladapter.StreamAdapter) {
+	t.Helper()
+	for stream.Next() {
+	}
+	require.NoError(t, stream.Err())
+}
+
+func TestParseEventHook(t *testing.T) {
+	t.Run("ToolDetectedNonStreaming", func(t *testing.T) {
+		rec := &eventRecorder{}
+		adapter := tooladapter.New(tooladapter.WithParseEventHook(rec.hook))
+
+		content := `[{"name": "get_weather", "parameters": {"city": "Paris"}}, {"name": "get_time", "parameters": null}]`
+		_, err := adapter.TransformCompletionsResponse(createMockCompletion(content))
+		require.NoError(t, err)
+
+		require.Len(t, rec.events, 2)
+		for i, name := range []string{"get_weather", "get_time"} {
+			e := rec.events[i]
+			assert.Equal(t, tooladapter.ParseEventToolDetected, e.Type)
+			assert.Equal(t, name, e.ToolName)
+			assert.Equal(t, len(content), e.Size)
+			assert.False(t, e.Streaming)
+			assert.False(t, e.Time.IsZero())
+		}
+	})
+
+	t.Run("NoEventsForPlainText", func(t *testing.T) {
+		rec := &eventRecorder{}
+		adapter := tooladapter.New(tooladapter.WithParseEventHook(rec.hook))
+
+		_, err := adapter.TransformCompletionsResponse(createMockCompletion("Just a regular answer."))
+		require.NoError(t, err)
+		assert.Empty(t, rec.events)
+	})
+
+	t.Run("RepairApplied", func(t *testing.T) {
+		rec := &eventRecorder{}
+		adapter := tooladapter.New(
+			tooladapter.WithLenientParsing(true),
+			tooladapter.WithParseEventHook(rec.hook))
+
+		_, err := adapter.TransformCompletionsResponse(createMockCompletion(`{name: 'get_weather', parameters: {city: 'Paris',},}`))
+		require.NoError(t, err)
+
+		assert.Equal(t, []tooladapter.ParseEventType{
+			tooladapter.ParseEventRepairApplied,
+			tooladapter.ParseEventToolDetected,
+		}, rec.types())
+		assert.Equal(t, tooladapter.ParseDetailLenient, rec.events[0].Detail)
+		assert.Positive(t, rec.events[0].Size)
+	})
+
+	t.Run("StreamingBufferStartAndToolDetected", func(t *testing.T) {
+		rec := &eventRecorder{}
+		adapter := tooladapter.New(tooladapter.WithParseEventHook(rec.hook))
+
+		stream := adapter.TransformStreamingResponse(NewMockStream([]openai.ChatCompletionChunk{
+			createStreamChunk(`{"name": "get_weather", `),
+			createStreamChunk(`"parameters": {"city": "Paris"}}`),
+			createFinishChunk("stop"),
+		}))
+		drainStream(t, stream)
+
+		assert.Equal(t, []tooladapter.ParseEventType{
+			tooladapter.ParseEventBufferStart,
+			tooladapter.ParseEventToolDetected,
+		}, rec.types())
+		assert.Equal(t, len(`{"name": "get_weather", `), rec.events[0].Size)
+		assert.Equal(t, "get_weather", rec.events[1].ToolName)
+		for _, e := range rec.events {
+			assert.True(t, e.Streaming)
+		}
+	})
+
+	t.Run("StreamingBufferFlush", func(t *testing.T) {
+		rec := &eventRecorder{}
+		adapter := tooladapter.New(tooladapter.WithParseEventHook(rec.hook))
+
+		content := `{"name": "Alice", "age": 30}`
+		stream := adapter.TransformStreamingResponse(NewMockStream([]openai.ChatCompletionChunk{
+			createStreamChunk(content),
+			createFinishChunk("stop"),
+		}))
+		drainStream(t, stream)
+
+		assert.Equal(t, []tooladapter.ParseEventType{
+			tooladapter.ParseEventBufferStart,
+			tooladapter.ParseEventBufferFlush,
+		}, rec.types())
+		assert.Equal(t, len(content), rec.events[1].Size)
+	})
+
+	t.Run("StreamingLimitExceeded", func(t *testing.T) {
+		rec := &eventRecorder{}
+		adapter := tooladapter.New(
+			tooladapter.WithStreamingToolBufferSize(64),
+			tooladapter.WithParseEventHook(rec.hook))
+
+		stream := adapter.TransformStreamingResponse(NewMockStream([]openai.ChatCompletionChunk{
+			createStreamChunk(`{"name": "get_weather", "parameters": {"city": "`),
+			createStreamChunk(strings.Repeat("x", 100)),
+			createFinishChunk("stop"),
+		}))
+		drainStream(t, stream)
+
+		var limit *tooladapter.ParseEvent
+		for i := range rec.events {
+			if rec.events[i].Type == tooladapter.ParseEventLimitExceeded {
+				limit = &rec.events[i]
+			}
+		}
+		require.NotNil(t, limit, "events: %v", rec.types())
+		assert.Equal(t, tooladapter.ParseDetailStreamBufferLimit, limit.Detail)
+		assert.Equal(t, 64, limit.Limit)
+		assert.Greater(t, limit.Size, 64)
+		assert.Contains(t, rec.types(), tooladapter.ParseEventBufferFlush)
+	})
+
+	t.Run("DrainAllCollectMaxBytes", func(t *testing.T) {
+		rec := &eventRecorder{}
+		adapter := tooladapter.New(
+			tooladapter.WithToolPolicy(tooladapter.ToolDrainAll),
+			tooladapter.WithToolCollectMaxBytes(32),
+			tooladapter.WithParseEventHook(rec.hook))
+
+		stream := adapter.TransformStreamingResponse(NewMockStream([]openai.ChatCompletionChunk{
+			createStreamChunk("Some text before anything else, "),
+			createStreamChunk("and then a good deal more text."),
+			createFinishChunk("stop"),
+		}))
+		drainStream(t, stream)
+
+		types := rec.types()
+		require.NotEmpty(t, types)
+		assert.Equal(t, tooladapter.ParseEventBufferStart, types[0])
+		assert.Contains(t, types, tooladapter.ParseEventLimitExceeded)
+	})
+
+	t.Run("PanicRecovered", func(t *testing.T) {
+		var logBuffer bytes.Buffer
+		logger := slog.New(slog.NewTextHandler(&logBuffer, nil))
+		adapter := tooladapter.New(
+			tooladapter.WithLogger(logger),
+			tooladapter.WithParseEventHook(func(tooladapter.ParseEvent) {
+				panic("intentional test panic")
+			}))
+
+		resp, err := adapter.TransformCompletionsResponse(createMockCompletion(`{"name": "get_weather", "parameters": null}`))
+		require.NoError(t, err)
+		require.Len(t, resp.Choices[0].Message.ToolCalls, 1)
+		assert.Contains(t, logBuffer.String(), "Parse event hook panicked")
+	})
+}
@@ -0,0 +1,132 @@
+package tooladapter
+
+import (
+	"encoding/json"
+	"strings"
+
+	"github.com/openai/openai-go/v3"
+)
+
+// GlobalExamples is the WithToolExamples key for examples that are not tied to a
+// single tool, such as examples with several calls or without any.
+const GlobalExamples = "*"
+
+// Example is a few-shot example rendered into the injected prompt: a user request
+// and the response the model should give to it.
+type Example struct {
+	// Request is the user's message
+	Request string `json:"request" yaml:"request"`
+
+	// Calls are the tool calls the model should respond with. In per-tool examples,
+	// calls without a Name call the tool the example belongs to.
+	Calls []ExampleCall `json:"calls,omitempty" yaml:"calls,omitempty"`
+
+	// Reply is the natural language answer for examples that should not call a
+	// tool; it is ignored when Calls is set
+	Reply string `json:"reply,omitempty" yaml:"reply,omitempty"`
+}
+
+// ExampleCall is a tool call in an Example.
+type ExampleCall struct {
+	// Name is the function name as declared in the request's tools
+	Name string `json:"name,omitempty" yaml:"name,omitempty"`
+
+	// Parameters are encoded as the call's JSON parameters; nil encodes as null
+	Parameters any `json:"parameters,omitempty" yaml:"parameters,omitempty"`
+}
+
+// PromptExample is an Example as rendered for the request, passed to prompt
+// templates.
+type PromptExample struct {
+	// Request is the user's message
+	Request string
+
+	// Response is the expected model output: a JSON array of tool calls with
+	// model-facing names, or the natural language reply
+	Response string
+}
+
+// promptExamples selects and renders the configured examples for the request's
+// tools: the examples of each tool in the request, in tool order, followed by the
+// global examples whose calls only use tools in the request.
+func (a *Adapter) promptExamples(tools []openai.ChatCompletionToolUnionParam) []PromptExample {
+	if len(a.toolExamples) == 0 || len(tools) == 0 {
+		return nil
+	}
+
+	// Map declared names to the model-facing names of the first tool using them
+	modelNames := make(map[string]string, len(tools))
+	var declared []string
+	for _, tool := range tools {
+		if function := tool.GetFunction(); function != nil {
+			name := a.restoreToolName(function.Name)
+			if _, seen := modelNames[name]; !seen {
+				modelNames[name] = function.Name
+				declared = append(declared, name)
+			}
+		}
+	}
+
+	var rendered []PromptExample
+	add := func(example Example, defaultName string) {
+		if len(example.Calls) == 0 {
+			rendered = append(rendered, PromptExample{Request: example.Request, Response: example.Reply})
+			return
+		}
+		type exampleCall struct {
+			Name       string `json:"name"`
+			Parameters any    `json:"parameters"`
+		}
+		calls := make([]exampleCall, 0, len(example.Calls))
+		for _, call := range example.Calls {
+			name := call.Name
+			if name == "" {
+				name = defaultName
+			}
+			modelName, ok := modelNames[name]
+			if !ok {
+				return // the example calls a tool outside the request
+			}
+			calls = append(calls, exampleCall{Name: modelName, Parameters: call.Parameters})
+		}
+		response, err := json.Marshal(calls)
+		if err != nil {
+			a.log(LogCategoryRequest).Warn("Skipping tool example with unencodable parameters",
+				"request", example.Request,
+				"error", err)
+			return
+		}
+		rendered = append(rendered, PromptExample{Request: example.Request, Response: string(response)})
+	}
+
+	for _, name := range declared {
+		for _, example := range a.toolExamples[name] {
+			add(example, name)
+		}
+	}
+	for _, example := range a.toolExamples[GlobalExamples] {
+		add(example, "")
+	}
+	return rendered
+}
+
+// buildExamplesPrompt renders examples as the section appended to the tool prompt.
+func (a *Adapter) buildExamplesPrompt(examples []PromptExample) string {
+	if len(examples) == 0 {
+		return ""
+	}
+
+	language := a.language()
+
+	var promptBuilder strings.Builder
+	promptBuilder.WriteString(language.examplesIntro)
+	for _, example := range examples {
+		promptBuilder.WriteString("\n\n")
+		promptBuilder.WriteString(language.exampleUser)
+		promptBuilder.WriteString(example.Request)
+		promptBuilder.WriteString("\n")
+		promptBuilder.WriteString(language.exampleAssistant)
+		promptBuilder.WriteString(example.Response)
+	}
+	return promptBuilder.String()
+}
@@ -0,0 +1,108 @@
+package tooladapter_test
+
+import (
+	"strings"
+	"testing"
+
+	tooladapter "github.com/juburr/openai-tool-adapter/v3"
+	"github.com/openai/openai-go/v3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var weatherExamples = map[string][]tooladapter.Example{
+	"get_weather": {{
+		Request: "Is it raining in Paris?",
+		Calls:   []tooladapter.ExampleCall{{Parameters: map[string]any{"city": "Paris"}}},
+	}},
+	"get_time": {{
+		Request: "What time is it in Tokyo?",
+		Calls:   []tooladapter.ExampleCall{{Parameters: map[string]any{"zone": "Asia/Tokyo"}}},
+	}},
+	tooladapter.GlobalExamples: {
+		{Request: "Hi!", Reply: "Hello! How can I help?"},
+		{
+			Request: "Weather in Oslo and Rome?",
+			Calls: []tooladapter.ExampleCall{
+				{Name: "get_weather", Parameters: map[string]any{"city": "Oslo"}},
+				{Name: "get_weather", Parameters: map[string]any{"city": "Rome"}},
+			},
+		},
+		{
+			Request: "Weather and time in Lima?",
+			Calls: []tooladapter.ExampleCall{
+				{Name: "get_weather", Parameters: map[string]any{"city": "Lima"}},
+				{Name: "get_time", Parameters: map[string]any{"zone": "America/Lima"}},
+			},
+		},
+	},
+}
+
+func TestWithToolExamples(t *testing.T) {
+	tools := []openai.ChatCompletionToolUnionParam{createMockTool("get_weather", "Get the weather")}
+
+	t.Run("RendersApplicableExamples", func(t *testing.T) {
+		adapter := tooladapter.New(
+			tooladapter.WithSystemMessageSupport(true),
+			tooladapter.WithToolExamples(weatherExamples))
+		result, err := adapter.TransformCompletionsRequest(createMockRequest(tools))
+		require.NoError(t, err)
+
+		prompt := systemPrompt(t, result)
+		_, examples, found := strings.Cut(prompt, "\n\nExamples of correct responses:")
+		require.True(t, found, "examples follow the instructions")
+		assert.Equal(t, `
+
+User: Is it raining in Paris?
+Assistant: [{"name":"get_weather","parameters":{"city":"Paris"}}]
+
+User: Hi!
+Assistant: Hello! How can I help?
+
+User: Weather in Oslo and Rome?
+Assistant: [{"name":"get_weather","parameters":{"city":"Oslo"}},{"name":"get_weather","parameters":{"city":"Rome"}}]`, examples)
+		assert.NotContains(t, prompt, "get_time", "examples for tools outside the request are skipped")
+	})
+
+	t.Run("Namespace", func(t *testing.T) {
+		adapter := tooladapter.New(
+			tooladapter.WithSystemMessageSupport(true),
+			tooladapter.WithToolNamespace("crm"),
+			tooladapter.WithToolExamples(weatherExamples))
+		result, err := adapter.TransformCompletionsRequest(createMockRequest(tools))
+		require.NoError(t, err)
+		assert.Contains(t, systemPrompt(t, result), `[{"name":"crm.get_weather","parameters":{"city":"Paris"}}]`)
+	})
+
+	t.Run("Localized", func(t *testing.T) {
+		adapter := tooladapter.New(
+			tooladapter.WithSystemMessageSupport(true),
+			tooladapter.WithPromptLanguage("es"),
+			tooladapter.WithToolExamples(weatherExamples))
+		result, err := adapter.TransformCompletionsRequest(createMockRequest(tools))
+		require.NoError(t, err)
+		assert.Contains(t, systemPrompt(t, result), "Ejemplos de respuestas correctas:\n\nUsuario: Is it raining in Paris?\nAsistente: [")
+	})
+
+	t.Run("PromptTemplate", func(t *testing.T) {
+		adapter := tooladapter.New(
+			tooladapter.WithSystemMessageSupport(true),
+			tooladapter.WithToolExamples(weatherExamples),
+			tooladapter.WithToolPromptTemplate(`{{range .Examples}}Q: {{.Request}} A: {{.Response}}
+{{end}}`))
+		result, err := adapter.TransformCompletionsRequest(createMockRequest(tools))
+		require.NoError(t, err)
+		assert.True(t, strings.HasPrefix(systemPrompt(t, result), `Q: Is it raining in Paris? A: [{"name":"get_weather","parameters":{"city":"Paris"}}]`))
+	})
+
+	t.Run("EmptyRequestIgnored", func(t *testing.T) {
+		adapter := tooladapter.New(
+			tooladapter.WithSystemMessageSupport(true),
+			tooladapter.WithToolExamples(map[string][]tooladapter.Example{
+				"get_weather": {{Calls: []tooladapter.ExampleCall{{}}}},
+			}))
+		result, err := adapter.TransformCompletionsRequest(createMockRequest(tools))
+		require.NoError(t, err)
+		assert.NotContains(t, systemPrompt(t, result), "Examples of correct responses")
+	})
+}
@@ -0,0 +1,146 @@
+package tooladapter
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/openai/openai-go/v3"
+)
+
+// ToolFunc runs a tool. It receives the raw JSON arguments of the call, nil when the
+// model sent none, and returns the result text passed back to the model. It should
+// stop when ctx is done.
+type ToolFunc func(ctx context.Context, arguments json.RawMessage) (string, error)
+
+// ExecutorTool is a tool an Executor runs.
+type ExecutorTool struct {
+	// Func runs the tool.
+	Func ToolFunc
+
+	// Timeout overrides Executor.Timeout for this tool. Zero uses the executor's.
+	Timeout time.Duration
+}
+
+// Executor runs the tool calls of transformed responses in-process and turns their
+// results into tool messages for the next request, which the adapter injects into
+// the prompt. Each call runs under its own timeout with its context derived from the
+// caller's; panics and timeouts become error results rather than crashing or
+// stalling the agent loop. The zero value with Tools set runs calls one at a time
+// without timeouts.
+//
+// THREAD SAFETY: An Executor is safe for concurrent use as long as its fields are
+// not modified and its tools are themselves safe for concurrent use.
+type Executor struct {
+	// Tools maps function names, as the caller declared them, to their tools.
+	Tools map[string]ExecutorTool
+
+	// Timeout limits each call. Zero means no limit.
+	Timeout time.Duration
+
+	// MaxConcurrency is the most calls Execute runs at once. Values below 1 run
+	// calls one at a time.
+	MaxConcurrency int
+}
+
+// Call runs the named tool with arguments in JSON. The returned error wraps
+// ErrUnknownTool for names outside Tools, ErrToolTimeout when the call outlives its
+// timeout and ErrToolPanicked when the tool panics. A tool that ignores its context
+// keeps running in the background after a timeout.
+func (e *Executor) Call(ctx context.Context, name, arguments string) (string, error) {
+	tool, ok := e.Tools[name]
+	if !ok || tool.Func == nil {
+		return "", fmt.Errorf("%w: %q", ErrUnknownTool, name)
+	}
+	var args json.RawMessage
+	if strings.TrimSpace(arguments) != "" {
+		args = json.RawMessage(arguments)
+	}
+
+	timeout := tool.Timeout
+	if timeout == 0 {
+		timeout = e.Timeout
+	}
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	type result struct {
+		content string
+		err     error
+	}
+	done := make(chan result, 1)
+	go func() {
+		defer func() {
+			if r := recover(); r != nil {
+				done <- result{err: fmt.Errorf("%w: %q: %v", ErrToolPanicked, name, r)}
+			}
+		}()
+		content, err := tool.Func(ctx, args)
+		done <- result{content, err}
+	}()
+
+	select {
+	case r := <-done:
+		return r.content, r.err
+	case <-ctx.Done():
+		if timeout > 0 && ctx.Err() == context.DeadlineExceeded {
+			return "", fmt.Errorf("%w: %q did not finish within %s", ErrToolTimeout, name, timeout)
+		}
+		return "", ctx.Err()
+	}
+}
+
+// Execute runs calls, up to MaxConcurrency at a time, and returns one tool message
+// per call in the order of calls, ready to append to the conversation after the
+// assistant message that made them.
+//
+// Tool failures, including unknown tools, panics and timeouts, are reported to the
+// model as "Error: ..." message content rather than aborting, so the model can
+// recover. Only cancellation of ctx aborts; the messages of calls that finished
+// first are returned with the context error.
+func (e *Executor) Execute(ctx context.Context, calls []openai.ChatCompletionMessageToolCallUnion) ([]openai.ChatCompletionMessageParamUnion, error) {
+	contents := make([]string, len(calls))
+	finished := make([]bool, len(calls))
+	slots := make(chan struct{}, max(e.MaxConcurrency, 1))
+	var wg sync.WaitGroup
+
+	for i, call := range calls {
+		select {
+		case slots <- struct{}{}:
+		case <-ctx.Done():
+		}
+		if ctx.Err() != nil {
+			break
+		}
+		wg.Add(1)
+		go func() {
+			defer func() {
+				<-slots
+				wg.Done()
+			}()
+			content, err := e.Call(ctx, call.Function.Name, call.Function.Arguments)
+			if err != nil {
+				if ctx.Err() != nil {
+					return
+				}
+				content = "Error: " + err.Error()
+			}
+			contents[i], finished[i] = content, true
+		}()
+	}
+	wg.Wait()
+
+	messages := make([]openai.ChatCompletionMessageParamUnion, 0, len(calls))
+	for i, call := range calls {
+		if finished[i] {
+			messages = append(messages, openai.ToolMessage(contents[i], call.ID))
+		}
+	}
+	return messages, ctx.Err()
+}
@@ -0,0 +1,195 @@
+package tooladapter_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	tooladapter "github.com/juburr/openai-tool-adapter/v3"
+	"github.com/openai/openai-go/v3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// toolCall returns a tool call as it appears in a transformed response.
+func toolCall(id, name, arguments string) openai.ChatCompletionMessageToolCallUnion {
+	return openai.ChatCompletionMessageToolCallUnion{
+		ID:   id,
+		Type: "function",
+		Function: openai.ChatCompletionMessageFunctionToolCallFunction{
+			Name:      name,
+			Arguments: arguments,
+		},
+	}
+}
+
+// toolMessageText returns the ID and content of a tool message.
+func toolMessageText(t *testing.T, message openai.ChatCompletionMessageParamUnion) (string, string) {
+	t.Helper()
+	require.NotNil(t, message.OfTool)
+	return message.OfTool.ToolCallID, message.OfTool.Content.OfString.Value
+}
+
+func TestExecutor(t *testing.T) {
+	weather := tooladapter.ExecutorTool{Func: func(_ context.Context, args json.RawMessage) (string, error) {
+		var params struct {
+			City string `json:"city"`
+		}
+		if err := json.Unmarshal(args, &params); err != nil {
+			return "", err
+		}
+		return "Sunny in " + params.City, nil
+	}}
+
+	t.Run("ToolMessages", func(t *testing.T) {
+		executor := &tooladapter.Executor{Tools: map[string]tooladapter.ExecutorTool{
+			"get_weather": weather,
+			"fail": {Func: func(context.Context, json.RawMessage) (string, error) {
+				return "", errors.New("backend down")
+			}},
+		}}
+		messages, err := executor.Execute(context.Background(), []openai.ChatCompletionMessageToolCallUnion{
+			toolCall("call_1", "get_weather", `{"city": "Paris"}`),
+			toolCall("call_2", "fail", ""),
+			toolCall("call_3", "get_time", "{}"),
+		})
+		require.NoError(t, err)
+		require.Len(t, messages, 3)
+
+		id, content := toolMessageText(t, messages[0])
+		assert.Equal(t, "call_1", id)
+		assert.Equal(t, "Sunny in Paris", content)
+		id, content = toolMessageText(t, messages[1])
+		assert.Equal(t, "call_2", id)
+		assert.Equal(t, "Error: backend down", content)
+		_, content = toolMessageText(t, messages[2])
+		assert.Contains(t, content, "Error: unknown tool")
+	})
+
+	t.Run("EmptyArguments", func(t *testing.T) {
+		executor := &tooladapter.Executor{Tools: map[string]tooladapter.ExecutorTool{
+			"ping": {Func: func(_ context.Context, args json.RawMessage) (string, error) {
+				assert.Nil(t, args)
+				return "pong", nil
+			}},
+		}}
+		content, err := executor.Call(context.Background(), "ping", "  ")
+		require.NoError(t, err)
+		assert.Equal(t, "pong", content)
+	})
+
+	t.Run("PanicRecovered", func(t *testing.T) {
+		executor := &tooladapter.Executor{Tools: map[string]tooladapter.ExecutorTool{
+			"crash": {Func: func(context.Context, json.RawMessage) (string, error) {
+				panic("nil map")
+			}},
+		}}
+		_, err := executor.Call(context.Background(), "crash", "{}")
+		require.ErrorIs(t, err, tooladapter.ErrToolPanicked)
+		assert.Contains(t, err.Error(), "nil map")
+
+		messages, err := executor.Execute(context.Background(), []openai.ChatCompletionMessageToolCallUnion{toolCall("call_1", "crash", "{}")})
+		require.NoError(t, err)
+		_, content := toolMessageText(t, messages[0])
+		assert.Contains(t, content, "Error: tool execution panicked")
+	})
+
+	t.Run("Timeout", func(t *testing.T) {
+		stuck := make(chan struct{})
+		defer close(stuck)
+		executor := &tooladapter.Executor{
+			Tools: map[string]tooladapter.ExecutorTool{
+				"ignores_context": {Func: func(context.Context, json.RawMessage) (string, error) {
+					<-stuck
+					return "late", nil
+				}},
+				"honors_context": {
+					Func: func(ctx context.Context, _ json.RawMessage) (string, error) {
+						<-ctx.Done()
+						return "", ctx.Err()
+					},
+					Timeout: 10 * time.Millisecond,
+				},
+			},
+			Timeout: 20 * time.Millisecond,
+		}
+
+		start := time.Now()
+		_, err := executor.Call(context.Background(), "ignores_context", "{}")
+		require.ErrorIs(t, err, tooladapter.ErrToolTimeout)
+		assert.Less(t, time.Since(start), time.Second, "a tool ignoring its context does not stall the call")
+
+		_, err = executor.Call(context.Background(), "honors_context", "{}")
+		require.ErrorIs(t, err, tooladapter.ErrToolTimeout)
+		assert.Contains(t, err.Error(), "10ms", "the tool's own timeout applies")
+	})
+
+	t.Run("ContextPropagated", func(t *testing.T) {
+		type key struct{}
+		executor := &tooladapter.Executor{Tools: map[string]tooladapter.ExecutorTool{
+			"whoami": {Func: func(ctx context.Context, _ json.RawMessage) (string, error) {
+				return ctx.Value(key{}).(string), nil
+			}},
+		}}
+		content, err := executor.Call(context.WithValue(context.Background(), key{}, "alice"), "whoami", "")
+		require.NoError(t, err)
+		assert.Equal(t, "alice", content)
+	})
+
+	t.Run("MaxConcurrency", func(t *testing.T) {
+		var running, peak atomic.Int32
+		executor := &tooladapter.Executor{
+			Tools: map[string]tooladapter.ExecutorTool{
+				"work": {Func: func(context.Context, json.RawMessage) (string, error) {
+					n := running.Add(1)
+					defer running.Add(-1)
+					for {
+						p := peak.Load()
+						if n <= p || peak.CompareAndSwap(p, n) {
+							break
+						}
+					}
+					time.Sleep(5 * time.Millisecond)
+					return "done", nil
+				}},
+			},
+			MaxConcurrency: 2,
+		}
+
+		calls := make([]openai.ChatCompletionMessageToolCallUnion, 6)
+		for i := range calls {
+			calls[i] = toolCall("call_"+string(rune('a'+i)), "work", "{}")
+		}
+		messages, err := executor.Execute(context.Background(), calls)
+		require.NoError(t, err)
+		require.Len(t, messages, 6)
+		for i, message := range messages {
+			id, _ := toolMessageText(t, message)
+			assert.Equal(t, calls[i].ID, id, "messages keep the order of the calls")
+		}
+		assert.LessOrEqual(t, peak.Load(), int32(2))
+	})
+
+	t.Run("Cancelled", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		executor := &tooladapter.Executor{Tools: map[string]tooladapter.ExecutorTool{
+			"get_weather": weather,
+			"cancel": {Func: func(ctx context.Context, _ json.RawMessage) (string, error) {
+				cancel()
+				return "", ctx.Err()
+			}},
+		}}
+		messages, err := executor.Execute(ctx, []openai.ChatCompletionMessageToolCallUnion{
+			toolCall("call_1", "get_weather", `{"city": "Paris"}`),
+			toolCall("call_2", "cancel", "{}"),
+			toolCall("call_3", "get_weather", `{"city": "Rome"}`),
+		})
+		require.ErrorIs(t, err, context.Canceled)
+		require.Len(t, messages, 1)
+		id, _ := toolMessageText(t, messages[0])
+		assert.Equal(t, "call_1", id)
+	})
+}
@@ -0,0 +1,188 @@
+package tooladapter
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"math/rand/v2"
+	"strings"
+
+	"github.com/openai/openai-go/v3"
+)
+
+// TemplateExperiment is one variant of a WithTemplateExperiments experiment.
+type TemplateExperiment struct {
+	// Name labels the variant in metrics and reports; variants without one are
+	// named "variant-1", "variant-2" and so on, by position
+	Name string `json:"name,omitempty" yaml:"name,omitempty"`
+
+	// Template is a WithCustomPromptTemplate format string with one %s placeholder
+	Template string `json:"template" yaml:"template"`
+
+	// Weight is the variant's share of requests relative to the other variants
+	Weight float64 `json:"weight" yaml:"weight"`
+}
+
+// ExperimentAssignment selects how WithTemplateExperiments assigns a request to a
+// variant.
+type ExperimentAssignment int
+
+const (
+	// ExperimentAssignmentHash assigns requests by a hash of the key attached with
+	// ContextWithExperimentKey or, without one, of the model and the first user
+	// message, so every turn of a conversation gets the same variant (default).
+	ExperimentAssignmentHash ExperimentAssignment = iota
+
+	// ExperimentAssignmentRandom assigns every request independently at random.
+	ExperimentAssignmentRandom
+)
+
+// String returns a human-readable string representation of the ExperimentAssignment.
+func (m ExperimentAssignment) String() string {
+	switch m {
+	case ExperimentAssignmentHash:
+		return "ExperimentAssignmentHash"
+	case ExperimentAssignmentRandom:
+		return "ExperimentAssignmentRandom"
+	default:
+		return fmt.Sprintf("ExperimentAssignment(%d)", int(m))
+	}
+}
+
+// experimentKey is the context key for keys attached by ContextWithExperimentKey.
+type experimentKey struct{}
+
+// ContextWithExperimentKey returns a copy of ctx whose requests
+// ExperimentAssignmentHash assigns by key, such as a user or tenant ID, instead of
+// by their first user message:
+//
+//	ctx = tooladapter.ContextWithExperimentKey(ctx, userID)
+//	transformed, err := adapter.TransformCompletionsRequestWithContext(ctx, req)
+func ContextWithExperimentKey(ctx context.Context, key string) context.Context {
+	return context.WithValue(ctx, experimentKey{}, key)
+}
+
+// templateVariant is a compiled WithTemplateExperiments variant.
+type templateVariant struct {
+	weight  float64
+	adapter *Adapter
+}
+
+// withoutTemplateExperiments keeps the adapters built for variants from building
+// their own.
+func withoutTemplateExperiments(a *Adapter) {
+	a.templateExperiments = nil
+}
+
+// withTemplateVariant names the variant an adapter was built for.
+func withTemplateVariant(name string) Option {
+	return func(a *Adapter) {
+		a.templateVariant = name
+	}
+}
+
+// compileTemplateExperiments builds the adapter of every WithTemplateExperiments
+// variant from the options the adapter was created with.
+func (a *Adapter) compileTemplateExperiments(opts []Option) {
+	if len(a.templateExperiments) == 0 {
+		return
+	}
+	a.templateVariants = make([]templateVariant, 0, len(a.templateExperiments))
+	for _, experiment := range a.templateExperiments {
+		variantOpts := append(opts[:len(opts):len(opts)],
+			WithCustomPromptTemplate(experiment.Template),
+			withTemplateVariant(experiment.Name),
+			withoutTemplateExperiments, withoutModelRules, withoutMiddleware)
+		a.templateVariants = append(a.templateVariants, templateVariant{
+			weight:  experiment.Weight,
+			adapter: New(variantOpts...),
+		})
+	}
+	a.log(LogCategoryRequest).Debug("Compiled template experiments", "variants", len(a.templateVariants))
+}
+
+// forExperiment returns the adapter of the variant req is assigned to, or a itself
+// without template experiments.
+func (a *Adapter) forExperiment(ctx context.Context, req openai.ChatCompletionNewParams) *Adapter {
+	if len(a.templateVariants) == 0 {
+		return a
+	}
+	var total float64
+	for _, variant := range a.templateVariants {
+		total += variant.weight
+	}
+
+	var point float64
+	if a.experimentAssignment == ExperimentAssignmentRandom {
+		point = rand.Float64() * total
+	} else {
+		h := sha256.New()
+		if key, ok := ctx.Value(experimentKey{}).(string); ok {
+			h.Write([]byte(key))
+		} else {
+			h.Write([]byte(req.Model))
+			for _, msg := range req.Messages {
+				if msg.OfUser != nil {
+					_, text, parts := messageContent(msg)
+					h.Write([]byte{0})
+					h.Write([]byte(text + parts))
+					break
+				}
+			}
+		}
+		point = float64(binary.BigEndian.Uint64(h.Sum(nil))>>11) / (1 << 53) * total
+	}
+
+	for _, variant := range a.templateVariants {
+		if point < variant.weight {
+			return variant.adapter
+		}
+		point -= variant.weight
+	}
+	return a.templateVariants[len(a.templateVariants)-1].adapter
+}
+
+// messageContent returns the role of msg, its text and the JSON encoding of its
+// non-text content parts.
+func messageContent(msg openai.ChatCompletionMessageParamUnion) (string, string, string) {
+	switch {
+	case msg.OfDeveloper != nil:
+		var text []string
+		for _, part := range msg.OfDeveloper.Content.OfArrayOfContentParts {
+			text = append(text, part.Text)
+		}
+		return "developer", msg.OfDeveloper.Content.OfString.Or("") + strings.Join(text, " "), ""
+	case msg.OfSystem != nil:
+		var text []string
+		for _, part := range msg.OfSystem.Content.OfArrayOfContentParts {
+			text = append(text, part.Text)
+		}
+		return "system", msg.OfSystem.Content.OfString.Or("") + strings.Join(text, " "), ""
+	case msg.OfUser != nil:
+		var text []string
+		var others []openai.ChatCompletionContentPartUnionParam
+		for _, part := range msg.OfUser.Content.OfArrayOfContentParts {
+			if part.OfText != nil {
+				text = append(text, part.OfText.Text)
+			} else {
+				others = append(others, part)
+			}
+		}
+		var encoded []byte
+		if len(others) > 0 {
+			encoded, _ = json.Marshal(others)
+		}
+		return "user", msg.OfUser.Content.OfString.Or("") + strings.Join(text, " "), string(encoded)
+	case msg.OfAssistant != nil:
+		var text []string
+		for _, part := range msg.OfAssistant.Content.OfArrayOfContentParts {
+			if part.OfText != nil {
+				text = append(text, part.OfText.Text)
+			}
+		}
+		return "assistant", msg.OfAssistant.Content.OfString.Or("") + strings.Join(text, " "), ""
+	}
+	return "", "", ""
+}
@@ -0,0 +1,103 @@
+package tooladapter_test
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+
+	tooladapter "github.com/juburr/openai-tool-adapter/v3"
+	"github.com/juburr/openai-tool-adapter/v3/tooltest"
+	"github.com/openai/openai-go/v3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWithTemplateExperiments(t *testing.T) {
+	experiments := []tooladapter.TemplateExperiment{
+		{Name: "terse", Template: "TERSE\n%s", Weight: 1},
+		{Template: "VERBOSE\n%s", Weight: 3},
+		{Name: "disabled", Template: "DISABLED\n%s", Weight: 0},
+		{Name: "invalid", Template: "no placeholder", Weight: 1},
+	}
+	var mu sync.Mutex
+	var variants []string
+	adapter := tooladapter.New(
+		tooladapter.WithSystemMessageSupport(true),
+		tooladapter.WithTemplateExperiments(experiments, tooladapter.ExperimentAssignmentHash),
+		tooladapter.WithMetricsCallback(func(data tooladapter.MetricEventData) {
+			if transformation, ok := data.(tooladapter.ToolTransformationData); ok {
+				mu.Lock()
+				variants = append(variants, transformation.TemplateVariant)
+				mu.Unlock()
+			}
+		}))
+	request := func(message string) openai.ChatCompletionNewParams {
+		req := tooltest.Request(tooltest.Tool("get_weather", "Get the weather"))
+		req.Messages = []openai.ChatCompletionMessageParamUnion{openai.UserMessage(message)}
+		return req
+	}
+	transform := func(ctx context.Context, req openai.ChatCompletionNewParams) (string, string) {
+		transformed, report, err := adapter.TransformCompletionsRequestWithReport(ctx, req)
+		require.NoError(t, err)
+		return systemPrompt(t, transformed), report.TemplateVariant
+	}
+
+	t.Run("WeightedAssignment", func(t *testing.T) {
+		counts := make(map[string]int)
+		for i := range 400 {
+			prompt, variant := transform(context.Background(), request(fmt.Sprintf("Question %d", i)))
+			counts[variant]++
+			switch variant {
+			case "terse":
+				assert.Contains(t, prompt, "TERSE")
+			case "variant-2":
+				assert.Contains(t, prompt, "VERBOSE")
+			}
+		}
+		assert.Len(t, counts, 2, "variants without weight or with invalid templates are ignored")
+		assert.InDelta(t, 100, counts["terse"], 40)
+		assert.InDelta(t, 300, counts["variant-2"], 40)
+	})
+
+	t.Run("Deterministic", func(t *testing.T) {
+		req := request("What is the weather in Paris?")
+		_, first := transform(context.Background(), req)
+		req.Messages = append(req.Messages, openai.AssistantMessage("Let me check."), openai.UserMessage("And in Oslo?"))
+		for range 5 {
+			_, variant := transform(context.Background(), req)
+			assert.Equal(t, first, variant, "later turns of the conversation keep the variant")
+		}
+	})
+
+	t.Run("ExperimentKey", func(t *testing.T) {
+		seen := make(map[string]bool)
+		for i := range 50 {
+			ctx := tooladapter.ContextWithExperimentKey(context.Background(), "user-1")
+			_, variant := transform(ctx, request(fmt.Sprintf("Question %d", i)))
+			seen[variant] = true
+		}
+		assert.Len(t, seen, 1, "requests with the same key get the same variant")
+	})
+
+	t.Run("Metrics", func(t *testing.T) {
+		mu.Lock()
+		defer mu.Unlock()
+		require.NotEmpty(t, variants)
+		for _, variant := range variants {
+			assert.Contains(t, []string{"terse", "variant-2"}, variant)
+		}
+	})
+
+	t.Run("Random", func(t *testing.T) {
+		adapter := tooladapter.New(tooladapter.WithTemplateExperiments(experiments[:2], tooladapter.ExperimentAssignmentRandom))
+		seen := make(map[string]bool)
+		req := request("What is the weather in Paris?")
+		for range 100 {
+			_, report, err := adapter.TransformCompletionsRequestWithReport(context.Background(), req)
+			require.NoError(t, err)
+			seen[report.TemplateVariant] = true
+		}
+		assert.Len(t, seen, 2, "the same request is assigned to both variants")
+	})
+}
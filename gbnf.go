@@ -0,0 +1,335 @@
+package tooladapter
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/openai/openai-go/v3"
+)
+
+// GBNFOptions configures the grammar returned by GenerateGBNF.
+type GBNFOptions struct {
+	// SingleCall limits the array of tool calls to one call, as for requests that
+	// disable parallel tool calls.
+	SingleCall bool
+
+	// AllowText also accepts a plain text answer that does not start with JSON, for
+	// requests where calling a tool is optional.
+	AllowText bool
+}
+
+// gbnfBaseRules are the JSON rules every generated grammar shares, in the style of
+// llama.cpp's json.gbnf. Whitespace is bounded so a constrained model cannot emit
+// it forever.
+const gbnfBaseRules = `value ::= object | array | string | number | boolean | null
+object ::= "{" ws ( string ws ":" ws value ( ws "," ws string ws ":" ws value )* )? ws "}"
+array ::= "[" ws ( value ( ws "," ws value )* )? ws "]"
+string ::= "\"" ( [^"\\\x7F\x00-\x1F] | "\\" ( ["\\/bfnrt] | "u" [0-9a-fA-F] [0-9a-fA-F] [0-9a-fA-F] [0-9a-fA-F] ) )* "\""
+number ::= integer ( "." [0-9]+ )? ( [eE] [-+]? [0-9]+ )?
+integer ::= "-"? ( "0" | [1-9] [0-9]* )
+boolean ::= "true" | "false"
+null ::= "null"
+text ::= [^[{ \t\r\n] [^\x00]*
+ws ::= [ \t\n]? [ \t\n]? [ \t\n]? [ \t\n]?
+`
+
+// grammarSchema is the subset of JSON Schema GenerateGBNF translates. Other
+// keywords are ignored, and schemas it cannot translate accept any JSON value.
+type grammarSchema struct {
+	Type                 schemaTypes               `json:"type"`
+	Properties           map[string]*grammarSchema `json:"properties"`
+	Required             []string                  `json:"required"`
+	Items                *grammarSchema            `json:"items"`
+	Enum                 []json.RawMessage         `json:"enum"`
+	Const                json.RawMessage           `json:"const"`
+	AnyOf                []*grammarSchema          `json:"anyOf"`
+	OneOf                []*grammarSchema          `json:"oneOf"`
+	AdditionalProperties json.RawMessage           `json:"additionalProperties"`
+}
+
+// GenerateGBNF returns a llama.cpp GBNF grammar that constrains model output to the
+// tool call JSON the injected prompt asks for: an array of {"name": ...,
+// "parameters": ...} objects whose names are those of the function tools and whose
+// parameters follow each tool's schema. Pass it as the "grammar" field of a
+// llama.cpp request, or let WithGBNFGrammar attach it.
+//
+// Types, properties, required properties, items, enum, const, anyOf and oneOf are
+// translated; properties are accepted in sorted order, required ones first, and
+// parts of a schema the grammar cannot express accept any JSON value. It returns
+// an error when tools has no function tool or a schema cannot be decoded.
+func GenerateGBNF(tools []openai.ChatCompletionToolUnionParam, opts GBNFOptions) (string, error) {
+	g := gbnfGenerator{names: map[string]bool{"root": true, "calls": true, "call": true}}
+	for _, name := range []string{"value", "object", "array", "string", "number", "integer", "boolean", "null", "text", "ws"} {
+		g.names[name] = true
+	}
+
+	var calls []string
+	for _, tool := range tools {
+		function := tool.GetFunction()
+		if function == nil {
+			continue
+		}
+		params, err := g.parameters(function.Name, function.Parameters)
+		if err != nil {
+			return "", fmt.Errorf("tool %q: %w", function.Name, err)
+		}
+		name, err := json.Marshal(function.Name)
+		if err != nil {
+			return "", fmt.Errorf("tool %q: %w", function.Name, err)
+		}
+		calls = append(calls, fmt.Sprintf(`"{" ws "\"name\"" ws ":" ws %s ws "," ws "\"parameters\"" ws ":" ws %s ws "}"`,
+			gbnfLiteral(string(name)), params))
+	}
+	if len(calls) == 0 {
+		return "", errors.New("no function tools to generate a grammar for")
+	}
+
+	var b strings.Builder
+	if opts.AllowText {
+		b.WriteString("root ::= ws ( calls ws | text )\n")
+	} else {
+		b.WriteString("root ::= ws calls ws\n")
+	}
+	if opts.SingleCall {
+		b.WriteString(`calls ::= "[" ws call ws "]"` + "\n")
+	} else {
+		b.WriteString(`calls ::= "[" ws call ( ws "," ws call )* ws "]"` + "\n")
+	}
+	b.WriteString("call ::= " + strings.Join(calls, " | ") + "\n")
+	for _, rule := range g.rules {
+		b.WriteString(rule + "\n")
+	}
+	b.WriteString(gbnfBaseRules)
+	return b.String(), nil
+}
+
+// gbnfGenerator collects the rules generated for tool parameter schemas.
+type gbnfGenerator struct {
+	names map[string]bool // rule names in use
+	rules []string
+}
+
+// parameters returns the expression of a tool's parameters. Tools without
+// properties may also pass null, as the prompt allows.
+func (g *gbnfGenerator) parameters(tool string, parameters openai.FunctionParameters) (string, error) {
+	if parameters == nil {
+		return `( "{" ws "}" | null )`, nil
+	}
+	data, err := json.Marshal(parameters)
+	if err != nil {
+		return "", err
+	}
+	var schema grammarSchema
+	if err := json.Unmarshal(data, &schema); err != nil {
+		return "", err
+	}
+	expr := g.expression(tool+"-params", &schema)
+	if len(schema.Properties) == 0 {
+		return "( " + expr + " | null )", nil
+	}
+	return expr, nil
+}
+
+// expression returns a GBNF expression matching the JSON values of schema, adding
+// rules named after name for nested objects and arrays.
+func (g *gbnfGenerator) expression(name string, schema *grammarSchema) string {
+	switch {
+	case schema == nil:
+		return "value"
+	case len(schema.Const) > 0:
+		return gbnfLiteral(compactJSON(schema.Const))
+	case len(schema.Enum) > 0:
+		values := make([]string, len(schema.Enum))
+		for i, value := range schema.Enum {
+			values[i] = gbnfLiteral(compactJSON(value))
+		}
+		return "( " + strings.Join(values, " | ") + " )"
+	case len(schema.AnyOf) > 0 || len(schema.OneOf) > 0:
+		variants := append(slices.Clone(schema.AnyOf), schema.OneOf...)
+		alternatives := make([]string, len(variants))
+		for i, variant := range variants {
+			alternatives[i] = g.expression(fmt.Sprintf("%s-%d", name, i+1), variant)
+		}
+		return "( " + strings.Join(alternatives, " | ") + " )"
+	}
+
+	types := schema.Type
+	if len(types) == 0 {
+		if len(schema.Properties) == 0 {
+			return "value"
+		}
+		types = schemaTypes{"object"}
+	}
+	alternatives := make([]string, 0, len(types))
+	for _, typ := range types {
+		switch typ {
+		case "string", "number", "integer", "boolean", "null":
+			alternatives = append(alternatives, typ)
+		case "array":
+			item := g.expression(name+"-item", schema.Items)
+			alternatives = append(alternatives, g.rule(name, fmt.Sprintf(`"[" ws ( %s ( ws "," ws %s )* )? ws "]"`, item, item)))
+		case "object":
+			alternatives = append(alternatives, g.object(name, schema))
+		default:
+			return "value"
+		}
+	}
+	if len(alternatives) == 1 {
+		return alternatives[0]
+	}
+	return "( " + strings.Join(alternatives, " | ") + " )"
+}
+
+// object returns the expression of an object schema. Properties are accepted in
+// sorted order, required ones first.
+func (g *gbnfGenerator) object(name string, schema *grammarSchema) string {
+	if len(schema.Properties) == 0 {
+		if string(schema.AdditionalProperties) == "false" {
+			return `"{" ws "}"`
+		}
+		return "object"
+	}
+
+	var required, optional []string
+	for key := range schema.Properties {
+		if slices.Contains(schema.Required, key) {
+			required = append(required, key)
+		} else {
+			optional = append(optional, key)
+		}
+	}
+	sort.Strings(required)
+	sort.Strings(optional)
+
+	property := func(key string) string {
+		encoded, _ := json.Marshal(key)
+		return fmt.Sprintf(`%s ws ":" ws %s`, gbnfLiteral(string(encoded)), g.expression(name+"-"+key, schema.Properties[key]))
+	}
+	properties := make(map[string]string, len(schema.Properties))
+	for _, key := range append(slices.Clone(required), optional...) {
+		properties[key] = property(key)
+	}
+
+	var body string
+	if len(required) > 0 {
+		parts := make([]string, 0, len(schema.Properties))
+		for i, key := range required {
+			if i == 0 {
+				parts = append(parts, properties[key])
+			} else {
+				parts = append(parts, `ws "," ws `+properties[key])
+			}
+		}
+		for _, key := range optional {
+			parts = append(parts, `( ws "," ws `+properties[key]+` )?`)
+		}
+		body = strings.Join(parts, " ")
+	} else {
+		// The first property present decides where the commas go
+		alternatives := make([]string, len(optional))
+		for i, key := range optional {
+			parts := []string{properties[key]}
+			for _, later := range optional[i+1:] {
+				parts = append(parts, `( ws "," ws `+properties[later]+` )?`)
+			}
+			alternatives[i] = strings.Join(parts, " ")
+		}
+		body = "( " + strings.Join(alternatives, " | ") + " )?"
+	}
+	return g.rule(name, `"{" ws `+body+` ws "}"`)
+}
+
+// rule adds a rule with a unique name derived from name and returns the name.
+func (g *gbnfGenerator) rule(name, body string) string {
+	base := gbnfRuleName(name)
+	unique := base
+	for i := 2; g.names[unique]; i++ {
+		unique = fmt.Sprintf("%s-%d", base, i)
+	}
+	g.names[unique] = true
+	g.rules = append(g.rules, unique+" ::= "+body)
+	return unique
+}
+
+// gbnfRuleName returns name with the characters GBNF rule names cannot contain
+// replaced by dashes.
+func gbnfRuleName(name string) string {
+	mapped := strings.Map(func(r rune) rune {
+		if r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' {
+			return r
+		}
+		return '-'
+	}, name)
+	mapped = strings.Trim(mapped, "-")
+	if mapped == "" {
+		return "tool"
+	}
+	return mapped
+}
+
+// gbnfLiteral quotes s as a GBNF string literal.
+func gbnfLiteral(s string) string {
+	var b strings.Builder
+	b.WriteByte('"')
+	for _, r := range s {
+		switch {
+		case r == '"' || r == '\\':
+			b.WriteByte('\\')
+			b.WriteRune(r)
+		case r == '\n':
+			b.WriteString(`\n`)
+		case r == '\r':
+			b.WriteString(`\r`)
+		case r == '\t':
+			b.WriteString(`\t`)
+		case r < 0x20 || r == 0x7F:
+			fmt.Fprintf(&b, `\x%02X`, r)
+		default:
+			b.WriteRune(r)
+		}
+	}
+	b.WriteByte('"')
+	return b.String()
+}
+
+// gbnfGrammarField is the llama.cpp request field WithGBNFGrammar sets.
+const gbnfGrammarField = "grammar"
+
+// addToolGrammar sets the grammar field of a transformed request offering tools to
+// the GenerateGBNF grammar of tools, the model-facing tools of the request, when
+// WithGBNFGrammar is enabled. A grammar the request already has is kept.
+func (a *Adapter) addToolGrammar(req openai.ChatCompletionNewParams, tools []openai.ChatCompletionToolUnionParam) openai.ChatCompletionNewParams {
+	if !a.gbnfGrammar {
+		return req
+	}
+	if _, ok := req.ExtraFields()[gbnfGrammarField]; ok {
+		return req
+	}
+	tools, mode, ok := a.constrainedTools(req, tools)
+	if !ok {
+		return req
+	}
+
+	grammar, err := GenerateGBNF(tools, GBNFOptions{
+		SingleCall: requestsSingleToolCall(req),
+		AllowText:  mode == "" || mode == "auto",
+	})
+	if err != nil {
+		a.log(LogCategoryRequest).Warn("Failed to generate tool call grammar, sending the request without it",
+			"error", err)
+		return req
+	}
+	fields := maps.Clone(req.ExtraFields())
+	if fields == nil {
+		fields = make(map[string]any, 1)
+	}
+	fields[gbnfGrammarField] = grammar
+	req.SetExtraFields(fields)
+	a.log(LogCategoryRequest).Debug("Attached tool call grammar", "grammar_length", len(grammar))
+	return req
+}
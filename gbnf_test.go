@@ -0,0 +1,302 @@
+package tooladapter_test
+
+import (
+	"encoding/json"
+	"strconv"
+	"strings"
+	"testing"
+
+	tooladapter "github.com/juburr/openai-tool-adapter/v3"
+	"github.com/juburr/openai-tool-adapter/v3/tooltest"
+	"github.com/openai/openai-go/v3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func gbnfTools() []openai.ChatCompletionToolUnionParam {
+	return []openai.ChatCompletionToolUnionParam{
+		openai.ChatCompletionFunctionTool(openai.FunctionDefinitionParam{
+			Name: "get_weather",
+			Parameters: openai.FunctionParameters{
+				"type": "object",
+				"properties": map[string]any{
+					"city":  map[string]any{"type": "string"},
+					"unit":  map[string]any{"type": "string", "enum": []string{"celsius", "fahrenheit"}},
+					"days":  map[string]any{"type": "integer"},
+					"hours": map[string]any{"type": "array", "items": map[string]any{"type": "number"}},
+				},
+				"required": []string{"city"},
+			},
+		}),
+		openai.ChatCompletionFunctionTool(openai.FunctionDefinitionParam{Name: "get_time"}),
+	}
+}
+
+func TestGenerateGBNF(t *testing.T) {
+	grammar, err := tooladapter.GenerateGBNF(gbnfTools(), tooladapter.GBNFOptions{})
+	require.NoError(t, err)
+	matches := parseGBNF(t, grammar)
+
+	for _, output := range []string{
+		`[{"name": "get_weather", "parameters": {"city": "Paris"}}]`,
+		`[{"name":"get_weather","parameters":{"city":"Paris","days":3,"unit":"celsius"}}]`,
+		`[{"name": "get_weather", "parameters": {"city": "Paris", "hours": [1.5, 2]}}, {"name": "get_time", "parameters": null}]`,
+		"\n[\n  {\"name\": \"get_time\", \"parameters\": {}}\n]",
+	} {
+		assert.True(t, matches(output), "accepts %s", output)
+	}
+	for _, output := range []string{
+		`[{"name": "get_weather", "parameters": {"days": 3}}]`,
+		`[{"name": "get_weather", "parameters": {"city": "Paris", "unit": "kelvin"}}]`,
+		`[{"name": "get_weather", "parameters": {"city": 1}}]`,
+		`[{"name": "delete_files", "parameters": {}}]`,
+		`Sure! [{"name": "get_time", "parameters": {}}]`,
+	} {
+		assert.False(t, matches(output), "rejects %s", output)
+	}
+
+	t.Run("Options", func(t *testing.T) {
+		grammar, err := tooladapter.GenerateGBNF(gbnfTools(), tooladapter.GBNFOptions{SingleCall: true, AllowText: true})
+		require.NoError(t, err)
+		matches := parseGBNF(t, grammar)
+		assert.True(t, matches("It is sunny in Paris."))
+		assert.True(t, matches(`[{"name": "get_time", "parameters": {}}]`))
+		assert.False(t, matches(`[{"name": "get_time", "parameters": {}}, {"name": "get_time", "parameters": {}}]`))
+	})
+
+	t.Run("NoFunctionTools", func(t *testing.T) {
+		_, err := tooladapter.GenerateGBNF(nil, tooladapter.GBNFOptions{})
+		assert.Error(t, err)
+	})
+}
+
+func TestWithGBNFGrammar(t *testing.T) {
+	adapter := tooladapter.New(tooladapter.WithModelRules(map[string]tooladapter.ModelProfile{
+		"local/*": tooladapter.LlamaCppProfile(),
+	}))
+	grammar := func(req openai.ChatCompletionNewParams) string {
+		transformed, err := adapter.TransformCompletionsRequest(req)
+		require.NoError(t, err)
+		encoded, err := json.Marshal(transformed)
+		require.NoError(t, err)
+		var body struct {
+			Grammar string `json:"grammar"`
+		}
+		require.NoError(t, json.Unmarshal(encoded, &body))
+		return body.Grammar
+	}
+
+	req := tooltest.Request(gbnfTools()...)
+	req.Model = "local/qwen3-8b"
+	matches := parseGBNF(t, grammar(req))
+	assert.True(t, matches("Hello!"), "tool calls are optional")
+	assert.True(t, matches(`[{"name": "get_time", "parameters": {}}]`))
+
+	t.Run("ForcedFunction", func(t *testing.T) {
+		forced := req
+		forced.ToolChoice = openai.ToolChoiceOptionFunctionToolChoice(openai.ChatCompletionNamedToolChoiceFunctionParam{Name: "get_time"})
+		matches := parseGBNF(t, grammar(forced))
+		assert.False(t, matches("Hello!"))
+		assert.False(t, matches(`[{"name": "get_weather", "parameters": {"city": "Paris"}}]`))
+		assert.True(t, matches(`[{"name": "get_time", "parameters": {}}]`))
+	})
+
+	t.Run("ToolChoiceNone", func(t *testing.T) {
+		none := req
+		none.ToolChoice = openai.ChatCompletionToolChoiceOptionUnionParam{OfAuto: openai.String("none")}
+		assert.Empty(t, grammar(none))
+	})
+
+	t.Run("OtherModels", func(t *testing.T) {
+		other := req
+		other.Model = "openai/gpt-4o"
+		assert.Empty(t, grammar(other))
+	})
+}
+
+// gbnfNode is a parsed GBNF expression.
+type gbnfNode struct {
+	kind     byte // 'l'iteral, 'c'lass, 'r'ule, 's'equence, 'a'lternatives, or a repetition ?*+
+	text     string
+	negated  bool
+	ranges   [][2]rune
+	children []*gbnfNode
+}
+
+// parseGBNF parses the subset of GBNF GenerateGBNF writes and returns a function
+// reporting whether the grammar's root rule matches a whole string.
+func parseGBNF(t *testing.T, grammar string) func(string) bool {
+	t.Helper()
+	rules := make(map[string]*gbnfNode)
+	for _, line := range strings.Split(strings.TrimSpace(grammar), "\n") {
+		name, body, ok := strings.Cut(line, " ::= ")
+		require.True(t, ok, "rule %q", line)
+		p := &gbnfParser{t: t, src: []rune(body)}
+		rules[name] = p.alternatives()
+		require.Equal(t, len(p.src), p.pos, "rule %q is fully parsed", line)
+	}
+	require.Contains(t, rules, "root")
+
+	var match func(n *gbnfNode, input []rune, pos int, k func(int) bool) bool
+	match = func(n *gbnfNode, input []rune, pos int, k func(int) bool) bool {
+		switch n.kind {
+		case 'l':
+			lit := []rune(n.text)
+			if pos+len(lit) > len(input) || string(input[pos:pos+len(lit)]) != n.text {
+				return false
+			}
+			return k(pos + len(lit))
+		case 'c':
+			if pos >= len(input) {
+				return false
+			}
+			in := false
+			for _, r := range n.ranges {
+				in = in || input[pos] >= r[0] && input[pos] <= r[1]
+			}
+			return in != n.negated && k(pos+1)
+		case 'r':
+			rule, ok := rules[n.text]
+			require.True(t, ok, "rule %s is defined", n.text)
+			return match(rule, input, pos, k)
+		case 's':
+			var seq func(i, pos int) bool
+			seq = func(i, pos int) bool {
+				if i == len(n.children) {
+					return k(pos)
+				}
+				return match(n.children[i], input, pos, func(next int) bool { return seq(i+1, next) })
+			}
+			return seq(0, pos)
+		case 'a':
+			for _, child := range n.children {
+				if match(child, input, pos, k) {
+					return true
+				}
+			}
+			return false
+		case '?':
+			return match(n.children[0], input, pos, k) || k(pos)
+		default: // '*' and '+'
+			var star func(pos int) bool
+			star = func(pos int) bool {
+				return match(n.children[0], input, pos, func(next int) bool { return next != pos && star(next) }) || k(pos)
+			}
+			if n.kind == '+' {
+				return match(n.children[0], input, pos, star)
+			}
+			return star(pos)
+		}
+	}
+	return func(output string) bool {
+		input := []rune(output)
+		return match(rules["root"], input, 0, func(pos int) bool { return pos == len(input) })
+	}
+}
+
+type gbnfParser struct {
+	t   *testing.T
+	src []rune
+	pos int
+}
+
+func (p *gbnfParser) skipSpace() {
+	for p.pos < len(p.src) && p.src[p.pos] == ' ' {
+		p.pos++
+	}
+}
+
+func (p *gbnfParser) alternatives() *gbnfNode {
+	node := &gbnfNode{kind: 'a', children: []*gbnfNode{p.sequence()}}
+	for p.skipSpace(); p.pos < len(p.src) && p.src[p.pos] == '|'; p.skipSpace() {
+		p.pos++
+		node.children = append(node.children, p.sequence())
+	}
+	return node
+}
+
+func (p *gbnfParser) sequence() *gbnfNode {
+	node := &gbnfNode{kind: 's'}
+	for {
+		p.skipSpace()
+		if p.pos == len(p.src) || p.src[p.pos] == '|' || p.src[p.pos] == ')' {
+			return node
+		}
+		term := p.term()
+		if p.pos < len(p.src) && strings.ContainsRune("?*+", p.src[p.pos]) {
+			term = &gbnfNode{kind: byte(p.src[p.pos]), children: []*gbnfNode{term}}
+			p.pos++
+		}
+		node.children = append(node.children, term)
+	}
+}
+
+func (p *gbnfParser) term() *gbnfNode {
+	switch c := p.src[p.pos]; {
+	case c == '(':
+		p.pos++
+		node := p.alternatives()
+		require.Equal(p.t, ')', p.src[p.pos])
+		p.pos++
+		return node
+	case c == '"':
+		p.pos++
+		var text []rune
+		for p.src[p.pos] != '"' {
+			text = append(text, p.char())
+		}
+		p.pos++
+		return &gbnfNode{kind: 'l', text: string(text)}
+	case c == '[':
+		p.pos++
+		node := &gbnfNode{kind: 'c'}
+		if p.src[p.pos] == '^' {
+			node.negated = true
+			p.pos++
+		}
+		for p.src[p.pos] != ']' {
+			low := p.char()
+			high := low
+			if p.src[p.pos] == '-' && p.src[p.pos+1] != ']' {
+				p.pos++
+				high = p.char()
+			}
+			node.ranges = append(node.ranges, [2]rune{low, high})
+		}
+		p.pos++
+		return node
+	default:
+		start := p.pos
+		for p.pos < len(p.src) && (p.src[p.pos] == '-' || strings.ContainsRune("abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789", p.src[p.pos])) {
+			p.pos++
+		}
+		require.Greater(p.t, p.pos, start, "term at %d of %q", start, string(p.src))
+		return &gbnfNode{kind: 'r', text: string(p.src[start:p.pos])}
+	}
+}
+
+// char reads one possibly escaped character of a literal or character class.
+func (p *gbnfParser) char() rune {
+	c := p.src[p.pos]
+	p.pos++
+	if c != '\\' {
+		return c
+	}
+	c = p.src[p.pos]
+	p.pos++
+	switch c {
+	case 'n':
+		return '\n'
+	case 'r':
+		return '\r'
+	case 't':
+		return '\t'
+	case 'x':
+		value, err := strconv.ParseUint(string(p.src[p.pos:p.pos+2]), 16, 32)
+		require.NoError(p.t, err)
+		p.pos += 2
+		return rune(value)
+	default:
+		return c
+	}
+}
@@ -0,0 +1,150 @@
+package tooladapter
+
+import (
+	"fmt"
+
+	"github.com/openai/openai-go/v3"
+)
+
+// InjectionPosition selects where in the conversation the tool prompt is injected.
+type InjectionPosition int
+
+const (
+	// PositionAuto appends the prompt to the last system (or developer) message,
+	// or, when the request has none, prepends a new one or, for models without
+	// system message support, prepends the prompt to the first user message
+	// (default).
+	PositionAuto InjectionPosition = iota
+
+	// PositionSystemEnd appends the prompt to the last system (or developer)
+	// message, creating one at the start of the conversation when there is none.
+	PositionSystemEnd
+
+	// PositionSystemStart prepends the prompt to the first system (or developer)
+	// message, ahead of the caller's instructions, creating one at the start of
+	// the conversation when there is none.
+	PositionSystemStart
+
+	// PositionLastUser appends the prompt to the last user message, right before
+	// the model's turn, for models that pay most attention to the end of the
+	// prompt.
+	PositionLastUser
+
+	// PositionFirstUser prepends the prompt to the first user message.
+	PositionFirstUser
+)
+
+// String returns a human-readable string representation of the InjectionPosition.
+func (p InjectionPosition) String() string {
+	switch p {
+	case PositionAuto:
+		return "PositionAuto"
+	case PositionSystemEnd:
+		return "PositionSystemEnd"
+	case PositionSystemStart:
+		return "PositionSystemStart"
+	case PositionLastUser:
+		return "PositionLastUser"
+	case PositionFirstUser:
+		return "PositionFirstUser"
+	default:
+		return fmt.Sprintf("InjectionPosition(%d)", int(p))
+	}
+}
+
+// injectAtPosition injects toolPrompt into messages at the WithInjectionPosition
+// position. It reports false, leaving the placement to the PositionAuto strategy,
+// when the position needs a message role the conversation lacks and the model
+// cannot be given: a system message for models without system message support, or
+// a user message. The input slice is not modified.
+func (a *Adapter) injectAtPosition(messages []openai.ChatCompletionMessageParamUnion, toolPrompt string, origins *messageOrigins) ([]openai.ChatCompletionMessageParamUnion, bool) {
+	switch a.injectionPosition {
+	case PositionSystemEnd, PositionSystemStart:
+		index := -1
+		for i, m := range messages {
+			instruction := m.OfSystem != nil || (a.developerMessagesSupported && m.OfDeveloper != nil)
+			if instruction && (index == -1 || a.injectionPosition == PositionSystemEnd) {
+				index = i
+			}
+		}
+		if index == -1 {
+			if !a.systemMessagesSupported && !a.developerMessagesSupported {
+				return nil, false
+			}
+			instruction := openai.SystemMessage(toolPrompt)
+			if a.developerMessagesSupported {
+				instruction = openai.DeveloperMessage(toolPrompt)
+			}
+			origins.insert(0)
+			origins.promptAt(0)
+			return append([]openai.ChatCompletionMessageParamUnion{instruction}, messages...), true
+		}
+
+		content := extractSystemContent(messages[index])
+		combined := content + "\n\n" + toolPrompt
+		if a.injectionPosition == PositionSystemStart {
+			combined = toolPrompt + "\n\n" + content
+		}
+		injected := append([]openai.ChatCompletionMessageParamUnion(nil), messages...)
+		if m := messages[index].OfDeveloper; m != nil {
+			developerMsg := *m
+			developerMsg.Content = openai.ChatCompletionDeveloperMessageParamContentUnion{OfString: openai.String(combined)}
+			injected[index] = openai.ChatCompletionMessageParamUnion{OfDeveloper: &developerMsg}
+		} else {
+			systemMsg := *messages[index].OfSystem
+			systemMsg.Content = openai.ChatCompletionSystemMessageParamContentUnion{OfString: openai.String(combined)}
+			injected[index] = openai.ChatCompletionMessageParamUnion{OfSystem: &systemMsg}
+		}
+		origins.modify(index)
+		origins.promptAt(index)
+		a.log(LogCategoryRequest).Debug("Injected tool prompt into instruction message",
+			"position", a.injectionPosition.String(),
+			"message_index", index,
+			"tool_prompt_length", len(toolPrompt))
+		return injected, true
+
+	case PositionLastUser, PositionFirstUser:
+		index := -1
+		for i, m := range messages {
+			if m.OfUser != nil && (index == -1 || a.injectionPosition == PositionLastUser) {
+				index = i
+			}
+		}
+		if index == -1 {
+			return nil, false
+		}
+		injected := append([]openai.ChatCompletionMessageParamUnion(nil), messages...)
+		if a.injectionPosition == PositionLastUser {
+			injected[index] = appendToolPromptToUserMessage(messages[index], toolPrompt)
+		} else {
+			injected[index] = prependToolPromptToUserMessage(messages[index], toolPrompt)
+		}
+		origins.modify(index)
+		origins.promptAt(index)
+		a.log(LogCategoryRequest).Debug("Injected tool prompt into user message",
+			"position", a.injectionPosition.String(),
+			"message_index", index,
+			"tool_prompt_length", len(toolPrompt))
+		return injected, true
+	}
+	return nil, false
+}
+
+// appendToolPromptToUserMessage returns a copy of a user message with toolPrompt
+// after its content. Multimodal content gets the prompt as a last text part, after
+// its images, audio and files.
+func appendToolPromptToUserMessage(msg openai.ChatCompletionMessageParamUnion, toolPrompt string) openai.ChatCompletionMessageParamUnion {
+	userMsg := *msg.OfUser
+	content := userMsg.Content
+	switch {
+	case len(content.OfArrayOfContentParts) > 0:
+		parts := append([]openai.ChatCompletionContentPartUnionParam(nil), content.OfArrayOfContentParts...)
+		parts = append(parts, openai.TextContentPart(toolPrompt))
+		userMsg.Content = openai.ChatCompletionUserMessageParamContentUnion{OfArrayOfContentParts: parts}
+	case content.OfString.Or("") != "":
+		userMsg.Content = openai.ChatCompletionUserMessageParamContentUnion{OfString: openai.String(content.OfString.Value + "\n\n" + toolPrompt)}
+	default:
+		userMsg.Content = openai.ChatCompletionUserMessageParamContentUnion{OfString: openai.String(toolPrompt)}
+	}
+	return openai.ChatCompletionMessageParamUnion{OfUser: &userMsg}
+}
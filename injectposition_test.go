@@ -0,0 +1,96 @@
+package tooladapter_test
+
+import (
+	"strings"
+	"testing"
+
+	tooladapter "github.com/juburr/openai-tool-adapter/v3"
+	"github.com/juburr/openai-tool-adapter/v3/tooltest"
+	"github.com/openai/openai-go/v3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWithInjectionPosition(t *testing.T) {
+	conversation := func() openai.ChatCompletionNewParams {
+		req := tooltest.Request(tooltest.Tool("get_weather", "Get the weather"))
+		req.Messages = []openai.ChatCompletionMessageParamUnion{
+			openai.SystemMessage("Be brief."),
+			openai.UserMessage("Hi"),
+			openai.AssistantMessage("Hello!"),
+			openai.UserMessage("Weather in Paris?"),
+		}
+		return req
+	}
+	transform := func(req openai.ChatCompletionNewParams, opts ...tooladapter.Option) []openai.ChatCompletionMessageParamUnion {
+		transformed, err := tooladapter.New(opts...).TransformCompletionsRequest(req)
+		require.NoError(t, err)
+		return transformed.Messages
+	}
+	const toolPrompt = "get_weather"
+
+	t.Run("SystemEnd", func(t *testing.T) {
+		messages := transform(conversation(), tooladapter.WithInjectionPosition(tooladapter.PositionSystemEnd))
+		require.Len(t, messages, 4)
+		text := messages[0].OfSystem.Content.OfString.Value
+		assert.True(t, strings.HasPrefix(text, "Be brief.\n\n"))
+		assert.Contains(t, text, toolPrompt)
+	})
+
+	t.Run("SystemStart", func(t *testing.T) {
+		messages := transform(conversation(), tooladapter.WithInjectionPosition(tooladapter.PositionSystemStart))
+		require.Len(t, messages, 4)
+		text := messages[0].OfSystem.Content.OfString.Value
+		assert.True(t, strings.HasSuffix(text, "\n\nBe brief."))
+		assert.Contains(t, text, toolPrompt)
+	})
+
+	t.Run("LastUser", func(t *testing.T) {
+		messages := transform(conversation(), tooladapter.WithInjectionPosition(tooladapter.PositionLastUser))
+		require.Len(t, messages, 4)
+		assert.Equal(t, "Be brief.", messages[0].OfSystem.Content.OfString.Value)
+		assert.Equal(t, "Hi", messages[1].OfUser.Content.OfString.Value)
+		text := messages[3].OfUser.Content.OfString.Value
+		assert.True(t, strings.HasPrefix(text, "Weather in Paris?\n\n"))
+		assert.Contains(t, text, toolPrompt)
+	})
+
+	t.Run("FirstUser", func(t *testing.T) {
+		messages := transform(conversation(), tooladapter.WithInjectionPosition(tooladapter.PositionFirstUser))
+		require.Len(t, messages, 4)
+		assert.Equal(t, "Be brief.", messages[0].OfSystem.Content.OfString.Value)
+		text := messages[1].OfUser.Content.OfString.Value
+		assert.True(t, strings.HasSuffix(text, "\n\nHi"))
+		assert.Contains(t, text, toolPrompt)
+	})
+
+	t.Run("LastUserMultimodal", func(t *testing.T) {
+		req := conversation()
+		req.Messages[3] = openai.UserMessage([]openai.ChatCompletionContentPartUnionParam{
+			openai.TextContentPart("What is this?"),
+			openai.ImageContentPart(openai.ChatCompletionContentPartImageImageURLParam{URL: "https://example.com/cat.png"}),
+		})
+		messages := transform(req, tooladapter.WithInjectionPosition(tooladapter.PositionLastUser))
+		parts := messages[3].OfUser.Content.OfArrayOfContentParts
+		require.Len(t, parts, 3)
+		assert.Equal(t, "What is this?", parts[0].OfText.Text)
+		assert.NotNil(t, parts[1].OfImageURL)
+		assert.Contains(t, parts[2].OfText.Text, toolPrompt)
+	})
+
+	t.Run("SystemEndWithoutSystemSupport", func(t *testing.T) {
+		req := conversation()
+		req.Messages = req.Messages[1:]
+		messages := transform(req,
+			tooladapter.WithInjectionPosition(tooladapter.PositionSystemEnd),
+			tooladapter.WithSystemMessageSupport(false))
+		require.Len(t, messages, 3, "no system message is created")
+		assert.Contains(t, messages[0].OfUser.Content.OfString.Value, toolPrompt)
+	})
+
+	t.Run("Config", func(t *testing.T) {
+		cfg, err := tooladapter.LoadConfig(strings.NewReader(`{"injection_position": "last_user"}`))
+		require.NoError(t, err)
+		assert.Equal(t, tooladapter.PositionLastUser, cfg.InjectionPosition)
+	})
+}
@@ -0,0 +1,113 @@
+package tooladapter
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+)
+
+// ToolCallAction is what a ToolCallInterceptor decides to do with a tool call.
+type ToolCallAction int
+
+const (
+	// ToolCallApprove emits the call unchanged (the zero value).
+	ToolCallApprove ToolCallAction = iota
+
+	// ToolCallRewrite emits the call with the decision's Arguments.
+	ToolCallRewrite
+
+	// ToolCallReject emits no tool calls for the response, which is returned as
+	// regular content instead, as with UnknownToolAsContent.
+	ToolCallReject
+)
+
+// String returns a human-readable string representation of the ToolCallAction.
+func (a ToolCallAction) String() string {
+	switch a {
+	case ToolCallApprove:
+		return "ToolCallApprove"
+	case ToolCallRewrite:
+		return "ToolCallRewrite"
+	case ToolCallReject:
+		return "ToolCallReject"
+	default:
+		return fmt.Sprintf("ToolCallAction(%d)", int(a))
+	}
+}
+
+// InterceptedToolCall is a parsed tool call awaiting a ToolCallInterceptor's decision.
+type InterceptedToolCall struct {
+	// Name is the function name, after namespace and unknown tool handling.
+	Name string
+
+	// Arguments holds the raw JSON arguments, after coercion. It is nil when the
+	// model supplied none.
+	Arguments json.RawMessage
+}
+
+// ToolCallDecision is the outcome of a ToolCallInterceptor.
+type ToolCallDecision struct {
+	// Action selects what happens to the call.
+	Action ToolCallAction
+
+	// Arguments replaces the call's arguments under ToolCallRewrite. It must be a
+	// JSON object.
+	Arguments json.RawMessage
+
+	// Reason is logged with rewrites and rejections.
+	Reason string
+}
+
+// ToolCallInterceptor decides whether a parsed tool call is emitted, for example
+// after asking a human for approval. A non-nil error fails the transformation.
+type ToolCallInterceptor func(ctx context.Context, call InterceptedToolCall) (ToolCallDecision, error)
+
+// interceptCalls asks the interceptor about every call. A rejected call discards
+// all calls so the response is returned as content.
+func (a *Adapter) interceptCalls(ctx context.Context, calls []functionCall) ([]functionCall, error) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	for i := range calls {
+		decision, err := a.applyToolCallInterceptor(ctx, calls[i])
+		if err != nil {
+			return nil, fmt.Errorf("tool call interceptor failed for %q: %w", calls[i].Name, err)
+		}
+
+		switch decision.Action {
+		case ToolCallApprove:
+		case ToolCallRewrite:
+			var args map[string]any
+			if err := json.Unmarshal(decision.Arguments, &args); err != nil || args == nil {
+				return nil, fmt.Errorf("tool call interceptor rewrote %q with arguments that are not a JSON object", calls[i].Name)
+			}
+			a.log(LogCategoryPolicy).Info("Tool call arguments rewritten by interceptor",
+				"function_name", calls[i].Name,
+				"reason", decision.Reason)
+			calls[i].Parameters = decision.Arguments
+		case ToolCallReject:
+			a.log(LogCategoryPolicy).Warn("Tool call rejected by interceptor, returning response as content",
+				"function_name", calls[i].Name,
+				"reason", decision.Reason)
+			recordDroppedCalls(ctx, calls, DropReasonInterceptor)
+			return nil, nil
+		default:
+			return nil, fmt.Errorf("tool call interceptor returned unknown action %s for %q", decision.Action, calls[i].Name)
+		}
+	}
+	return calls, nil
+}
+
+// applyToolCallInterceptor runs the user-supplied interceptor, treating a panic as
+// rejection.
+func (a *Adapter) applyToolCallInterceptor(ctx context.Context, call functionCall) (decision ToolCallDecision, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			a.log(LogCategoryPolicy).Error("Tool call interceptor panicked, rejecting call",
+				"function_name", call.Name,
+				"panic", r)
+			decision, err = ToolCallDecision{Action: ToolCallReject, Reason: "interceptor panicked"}, nil
+		}
+	}()
+	return a.toolCallInterceptor(ctx, InterceptedToolCall{Name: call.Name, Arguments: call.Parameters})
+}
@@ -0,0 +1,85 @@
+package tooladapter_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	tooladapter "github.com/juburr/openai-tool-adapter/v3"
+	"github.com/juburr/openai-tool-adapter/v3/tooltest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWithToolCallInterceptor(t *testing.T) {
+	content := `{"name": "delete_file", "parameters": {"path": "/etc/passwd"}}`
+
+	intercept := func(decision tooladapter.ToolCallDecision, err error) *tooladapter.Adapter {
+		return tooladapter.New(tooladapter.WithToolCallInterceptor(
+			func(_ context.Context, call tooladapter.InterceptedToolCall) (tooladapter.ToolCallDecision, error) {
+				assert.Equal(t, "delete_file", call.Name)
+				assert.JSONEq(t, `{"path": "/etc/passwd"}`, string(call.Arguments))
+				return decision, err
+			}))
+	}
+
+	t.Run("Approve", func(t *testing.T) {
+		resp, err := intercept(tooladapter.ToolCallDecision{}, nil).TransformCompletionsResponse(tooltest.Completion(content))
+		require.NoError(t, err)
+		require.Len(t, resp.Choices[0].Message.ToolCalls, 1)
+		assert.JSONEq(t, `{"path": "/etc/passwd"}`, resp.Choices[0].Message.ToolCalls[0].Function.Arguments)
+	})
+
+	t.Run("Rewrite", func(t *testing.T) {
+		adapter := intercept(tooladapter.ToolCallDecision{
+			Action:    tooladapter.ToolCallRewrite,
+			Arguments: json.RawMessage(`{"path": "/tmp/scratch"}`),
+			Reason:    "sandboxed",
+		}, nil)
+		resp, err := adapter.TransformCompletionsResponse(tooltest.Completion(content))
+		require.NoError(t, err)
+		require.Len(t, resp.Choices[0].Message.ToolCalls, 1)
+		assert.JSONEq(t, `{"path": "/tmp/scratch"}`, resp.Choices[0].Message.ToolCalls[0].Function.Arguments)
+	})
+
+	t.Run("RewriteRequiresObject", func(t *testing.T) {
+		adapter := intercept(tooladapter.ToolCallDecision{Action: tooladapter.ToolCallRewrite, Arguments: json.RawMessage(`[1]`)}, nil)
+		_, err := adapter.TransformCompletionsResponse(tooltest.Completion(content))
+		require.Error(t, err)
+	})
+
+	t.Run("Reject", func(t *testing.T) {
+		adapter := intercept(tooladapter.ToolCallDecision{Action: tooladapter.ToolCallReject, Reason: "denied"}, nil)
+		resp, err := adapter.TransformCompletionsResponse(tooltest.Completion(content))
+		require.NoError(t, err)
+		assert.Empty(t, resp.Choices[0].Message.ToolCalls)
+		assert.Equal(t, content, resp.Choices[0].Message.Content)
+
+		result := tooltest.Drain(adapter.TransformStreamingResponse(tooltest.NewContentStream(content)))
+		require.NoError(t, result.Err)
+		assert.Empty(t, result.ToolCalls)
+		assert.Equal(t, content, result.Content)
+	})
+
+	t.Run("Error", func(t *testing.T) {
+		unavailable := errors.New("approval service unavailable")
+		adapter := intercept(tooladapter.ToolCallDecision{}, unavailable)
+		_, err := adapter.TransformCompletionsResponse(tooltest.Completion(content))
+		require.ErrorIs(t, err, unavailable)
+
+		result := tooltest.Drain(adapter.TransformStreamingResponse(tooltest.NewContentStream(content)))
+		require.ErrorIs(t, result.Err, unavailable)
+		assert.Empty(t, result.ToolCalls)
+	})
+
+	t.Run("PanicRejects", func(t *testing.T) {
+		adapter := tooladapter.New(tooladapter.WithToolCallInterceptor(
+			func(context.Context, tooladapter.InterceptedToolCall) (tooladapter.ToolCallDecision, error) {
+				panic("boom")
+			}))
+		resp, err := adapter.TransformCompletionsResponse(tooltest.Completion(content))
+		require.NoError(t, err)
+		assert.Empty(t, resp.Choices[0].Message.ToolCalls)
+	})
+}
@@ -0,0 +1,144 @@
+package tooladapter
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/openai/openai-go/v3"
+)
+
+// JSONModePrompt is the instruction injected into requests that set response_format
+// to json_object when WithJSONModeEmulation is enabled and the request has no tools.
+const JSONModePrompt = `Respond with valid JSON only: a single JSON object, with no code fences, comments or text before or after it.`
+
+// JSONModePolicy controls the emulation of response_format json_object for backends
+// that ignore it.
+type JSONModePolicy int
+
+const (
+	// JSONModeOff passes json_object requests and their replies through (default).
+	JSONModeOff JSONModePolicy = iota
+
+	// JSONModeExtract injects JSONModePrompt and replaces the content of each reply
+	// with the JSON object found in it. Replies without one are left unchanged.
+	JSONModeExtract
+
+	// JSONModeError extracts like JSONModeExtract, then fails the transformation
+	// with an error wrapping ErrInvalidJSONResponse when a reply holds no JSON
+	// object. CompleteWithRetry re-asks the model instead.
+	JSONModeError
+)
+
+// String returns a human-readable string representation of the JSONModePolicy.
+func (p JSONModePolicy) String() string {
+	switch p {
+	case JSONModeOff:
+		return "JSONModeOff"
+	case JSONModeExtract:
+		return "JSONModeExtract"
+	case JSONModeError:
+		return "JSONModeError"
+	default:
+		return fmt.Sprintf("JSONModePolicy(%d)", int(p))
+	}
+}
+
+// jsonModeKey is the context key for the setting attached by ContextWithJSONMode.
+type jsonModeKey struct{}
+
+// ContextWithJSONMode returns a copy of ctx recording whether the request set
+// response_format to json_object, so response transformation can apply
+// WithJSONModeEmulation to the reply. Pass the setting of the original request to
+// the response transformation that handles its reply:
+//
+//	ctx = tooladapter.ContextWithJSONMode(ctx, req.ResponseFormat.OfJSONObject != nil)
+//	resp, err := adapter.TransformCompletionsResponseWithContext(ctx, completion)
+//
+// Sessions and CompleteWithRetry attach the request's setting automatically.
+func ContextWithJSONMode(ctx context.Context, enabled bool) context.Context {
+	return context.WithValue(ctx, jsonModeKey{}, enabled)
+}
+
+// jsonModeRequested reports whether ctx records a json_object request.
+func jsonModeRequested(ctx context.Context) bool {
+	if ctx == nil {
+		return false
+	}
+	enabled, _ := ctx.Value(jsonModeKey{}).(bool)
+	return enabled
+}
+
+// requestsJSONMode reports whether req sets response_format to json_object.
+func requestsJSONMode(req openai.ChatCompletionNewParams) bool {
+	return req.ResponseFormat.OfJSONObject != nil
+}
+
+// jsonModePrompt returns the instruction to inject into req, or "" when JSON mode is
+// not emulated for it.
+func (a *Adapter) jsonModePrompt(req openai.ChatCompletionNewParams) string {
+	if a.jsonMode == JSONModeOff || !requestsJSONMode(req) {
+		return ""
+	}
+	return JSONModePrompt
+}
+
+// applyJSONMode replaces the content of the choices without tool calls with the
+// JSON object found in them when ctx records a json_object request.
+func (a *Adapter) applyJSONMode(ctx context.Context, resp openai.ChatCompletion) (openai.ChatCompletion, error) {
+	if a.jsonMode == JSONModeOff || !jsonModeRequested(ctx) {
+		return resp, nil
+	}
+	copied := false
+	for i, choice := range resp.Choices {
+		if len(choice.Message.ToolCalls) > 0 {
+			continue
+		}
+		object, ok := a.extractJSONObject(choice.Message.Content)
+		if !ok {
+			if a.jsonMode == JSONModeError {
+				a.log(LogCategoryParse).Warn("JSON mode reply holds no JSON object",
+					"choice_index", i,
+					"content_length", len(choice.Message.Content))
+				return openai.ChatCompletion{}, wrapTransformError(PhaseResponse, "extract JSON object", i,
+					fmt.Errorf("%w: no JSON object found in the reply", ErrInvalidJSONResponse))
+			}
+			continue
+		}
+		if object == choice.Message.Content {
+			continue
+		}
+		if !copied {
+			resp.Choices = append([]openai.ChatCompletionChoice(nil), resp.Choices...)
+			copied = true
+		}
+		resp.Choices[i].Message.Content = object
+		a.log(LogCategoryParse).Debug("Extracted JSON object from JSON mode reply",
+			"choice_index", i,
+			"content_length", len(choice.Message.Content),
+			"object_length", len(object))
+	}
+	return resp, nil
+}
+
+// extractJSONObject returns the first JSON object in content, found by the tool call
+// parser outside think blocks. With WithLenientParsing, JSON5 objects are converted
+// to strict JSON.
+func (a *Adapter) extractJSONObject(content string) (string, bool) {
+	_, rest := SplitThinkBlocks(content)
+	candidates := extractJSONBlocks(rest)
+	for _, candidate := range candidates {
+		if strings.HasPrefix(candidate, "{") && json.Valid([]byte(candidate)) {
+			return candidate, true
+		}
+	}
+	if a.lenientParsing {
+		for _, candidate := range candidates {
+			if normalized, ok := normalizeJSON5(candidate); ok && strings.HasPrefix(normalized, "{") {
+				return normalized, true
+			}
+		}
+	}
+	return "", false
+}
@@ -0,0 +1,129 @@
+package tooladapter_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	tooladapter "github.com/juburr/openai-tool-adapter/v3"
+	"github.com/juburr/openai-tool-adapter/v3/tooltest"
+	"github.com/openai/openai-go/v3"
+	"github.com/openai/openai-go/v3/shared"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// jsonModeRequest returns a request without tools that asks for a JSON object.
+func jsonModeRequest() openai.ChatCompletionNewParams {
+	req := tooltest.Request()
+	req.ResponseFormat = openai.ChatCompletionNewParamsResponseFormatUnion{
+		OfJSONObject: &shared.ResponseFormatJSONObjectParam{},
+	}
+	return req
+}
+
+func TestJSONModeEmulation(t *testing.T) {
+	jsonCtx := tooladapter.ContextWithJSONMode(context.Background(), true)
+
+	t.Run("InstructionInjected", func(t *testing.T) {
+		adapter := tooladapter.New(tooladapter.WithJSONModeEmulation(tooladapter.JSONModeExtract))
+		req, err := adapter.TransformCompletionsRequest(jsonModeRequest())
+		require.NoError(t, err)
+		assert.Contains(t, injectedText(t, req), tooladapter.JSONModePrompt)
+	})
+
+	t.Run("NotInjected", func(t *testing.T) {
+		off, err := tooladapter.New().TransformCompletionsRequest(jsonModeRequest())
+		require.NoError(t, err)
+		assert.NotContains(t, injectedText(t, off), tooladapter.JSONModePrompt)
+
+		adapter := tooladapter.New(tooladapter.WithJSONModeEmulation(tooladapter.JSONModeExtract))
+		withTools := jsonModeRequest()
+		withTools.Tools = []openai.ChatCompletionToolUnionParam{tooltest.Tool("get_weather", "Get the weather")}
+		req, err := adapter.TransformCompletionsRequest(withTools)
+		require.NoError(t, err)
+		assert.NotContains(t, injectedText(t, req), tooladapter.JSONModePrompt, "tool prompts take precedence")
+
+		plain, err := adapter.TransformCompletionsRequest(tooltest.Request())
+		require.NoError(t, err)
+		assert.NotContains(t, injectedText(t, plain), tooladapter.JSONModePrompt)
+	})
+
+	t.Run("ObjectExtracted", func(t *testing.T) {
+		adapter := tooladapter.New(tooladapter.WithJSONModeEmulation(tooladapter.JSONModeExtract))
+		for name, content := range map[string]string{
+			"Fenced":   "Here it is:\n```json\n{\"city\": \"Paris\", \"temperature\": 21}\n```",
+			"Embedded": `Sure! {"city": "Paris", "temperature": 21} Anything else?`,
+			"Think":    `<think>{"draft": true}</think>{"city": "Paris", "temperature": 21}`,
+		} {
+			t.Run(name, func(t *testing.T) {
+				resp, err := adapter.TransformCompletionsResponseWithContext(jsonCtx, tooltest.Completion(content))
+				require.NoError(t, err)
+				assert.JSONEq(t, `{"city": "Paris", "temperature": 21}`, resp.Choices[0].Message.Content)
+			})
+		}
+	})
+
+	t.Run("LenientObject", func(t *testing.T) {
+		adapter := tooladapter.New(
+			tooladapter.WithJSONModeEmulation(tooladapter.JSONModeExtract),
+			tooladapter.WithLenientParsing(true))
+		resp, err := adapter.TransformCompletionsResponseWithContext(jsonCtx, tooltest.Completion(`{city: 'Paris',}`))
+		require.NoError(t, err)
+		assert.JSONEq(t, `{"city": "Paris"}`, resp.Choices[0].Message.Content)
+	})
+
+	t.Run("NoObject", func(t *testing.T) {
+		completion := tooltest.Completion("It is sunny in Paris.")
+		extract := tooladapter.New(tooladapter.WithJSONModeEmulation(tooladapter.JSONModeExtract))
+		resp, err := extract.TransformCompletionsResponseWithContext(jsonCtx, completion)
+		require.NoError(t, err)
+		assert.Equal(t, "It is sunny in Paris.", resp.Choices[0].Message.Content)
+
+		strict := tooladapter.New(tooladapter.WithJSONModeEmulation(tooladapter.JSONModeError))
+		_, err = strict.TransformCompletionsResponseWithContext(jsonCtx, completion)
+		require.ErrorIs(t, err, tooladapter.ErrInvalidJSONResponse)
+	})
+
+	t.Run("NotRequested", func(t *testing.T) {
+		adapter := tooladapter.New(tooladapter.WithJSONModeEmulation(tooladapter.JSONModeError))
+		content := `Sure! {"city": "Paris"}`
+		resp, err := adapter.TransformCompletionsResponse(tooltest.Completion(content))
+		require.NoError(t, err)
+		assert.Equal(t, content, resp.Choices[0].Message.Content)
+	})
+
+	t.Run("Session", func(t *testing.T) {
+		adapter := tooladapter.New(tooladapter.WithJSONModeEmulation(tooladapter.JSONModeExtract))
+		session := adapter.NewSession()
+		_, err := session.TransformRequest(context.Background(), jsonModeRequest())
+		require.NoError(t, err)
+		resp, err := session.TransformResponse(context.Background(), tooltest.Completion(`Result: {"ok": true}`))
+		require.NoError(t, err)
+		assert.Equal(t, `{"ok": true}`, resp.Choices[0].Message.Content)
+	})
+
+	t.Run("CompleteWithRetry", func(t *testing.T) {
+		client := &scriptedClient{replies: []string{"The weather is fine.", `{"weather": "fine"}`}}
+		adapter := tooladapter.New(tooladapter.WithJSONModeEmulation(tooladapter.JSONModeError))
+		resp, err := adapter.CompleteWithRetry(context.Background(), client, jsonModeRequest(),
+			tooladapter.RetryPolicy{MaxAttempts: 2})
+
+		require.NoError(t, err)
+		assert.Equal(t, `{"weather": "fine"}`, resp.Choices[0].Message.Content)
+		require.Len(t, client.requests, 2)
+		retried := client.requests[1].Messages
+		correction := retried[len(retried)-1].OfUser
+		require.NotNil(t, correction)
+		assert.Contains(t, correction.Content.OfString.Value, "not a JSON object")
+	})
+
+	t.Run("Config", func(t *testing.T) {
+		cfg, err := tooladapter.LoadConfig(strings.NewReader(`{"json_mode": "extract"}`))
+		require.NoError(t, err)
+		assert.Equal(t, tooladapter.JSONModeExtract, cfg.JSONMode)
+
+		_, err = tooladapter.LoadConfig(strings.NewReader(`{"json_mode": "always"}`))
+		require.ErrorIs(t, err, tooladapter.ErrInvalidConfig)
+	})
+}
@@ -0,0 +1,23 @@
+module github.com/juburr/openai-tool-adapter/v3/langchaingo
+
+go 1.24.5
+
+require (
+	github.com/juburr/openai-tool-adapter/v3 v3.0.0-20261016201218-6637f0c6584f
+	github.com/openai/openai-go/v3 v3.14.0
+	github.com/stretchr/testify v1.11.1
+	github.com/tmc/langchaingo v0.1.14
+)
+
+require (
+	github.com/davecgh/go-spew v1.1.2-0.20180830191138-d8f796af33cc // indirect
+	github.com/dlclark/regexp2 v1.10.0 // indirect
+	github.com/google/uuid v1.6.0 // indirect
+	github.com/pkoukk/tiktoken-go v0.1.6 // indirect
+	github.com/pmezard/go-difflib v1.0.1-0.20181226105442-5d4384ee4fb2 // indirect
+	github.com/tidwall/gjson v1.18.0 // indirect
+	github.com/tidwall/match v1.1.1 // indirect
+	github.com/tidwall/pretty v1.2.1 // indirect
+	github.com/tidwall/sjson v1.2.5 // indirect
+	gopkg.in/yaml.v3 v3.0.1 // indirect
+)
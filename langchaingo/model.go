@@ -0,0 +1,414 @@
+// Package langchaingo exposes the tool adapter as a LangChainGo llms.Model so that
+// existing LangChainGo agents and chains can use tools against backends that lack
+// native function calling support.
+//
+// The model converts LangChainGo messages and tool definitions into OpenAI chat
+// completion parameters, runs them through tooladapter.Adapter, calls the backend,
+// and converts the transformed response (including parsed tool calls) back into
+// an llms.ContentResponse.
+//
+// CONCURRENCY: Model instances are safe for concurrent use as long as the supplied
+// client is. The wrapped Adapter is already safe for concurrent use.
+package langchaingo
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	tooladapter "github.com/juburr/openai-tool-adapter/v3"
+	"github.com/openai/openai-go/v3"
+	"github.com/openai/openai-go/v3/option"
+	"github.com/tmc/langchaingo/llms"
+)
+
+// ChatCompletionsClient is the subset of the OpenAI SDK used by Model.
+// It is satisfied by client.Chat.Completions from github.com/openai/openai-go/v3.
+type ChatCompletionsClient interface {
+	New(ctx context.Context, body openai.ChatCompletionNewParams, opts ...option.RequestOption) (*openai.ChatCompletion, error)
+}
+
+// ErrEmptyResponse is returned when the backend returns a completion without choices.
+var ErrEmptyResponse = errors.New("langchaingo: no choices in response")
+
+// Model implements llms.Model on top of a tool adapter and an OpenAI-compatible client.
+type Model struct {
+	client  ChatCompletionsClient
+	adapter *tooladapter.Adapter
+	model   string
+}
+
+var _ llms.Model = (*Model)(nil)
+
+// New creates a LangChainGo model that routes requests through the given adapter.
+// The model name is used unless overridden per call with llms.WithModel.
+// If adapter is nil, a default adapter is created.
+func New(client ChatCompletionsClient, adapter *tooladapter.Adapter, model string) *Model {
+	if adapter == nil {
+		adapter = tooladapter.New()
+	}
+	return &Model{
+		client:  client,
+		adapter: adapter,
+		model:   model,
+	}
+}
+
+// Call implements the deprecated single-prompt interface of llms.Model.
+func (m *Model) Call(ctx context.Context, prompt string, options ...llms.CallOption) (string, error) {
+	return llms.GenerateFromSinglePrompt(ctx, m, prompt, options...)
+}
+
+// GenerateContent implements llms.Model. Tools supplied with llms.WithTools are
+// injected into the prompt by the adapter, and tool calls found in the model's
+// reply are returned as llms.ToolCall values on each choice.
+//
+// Streaming is emulated: if a StreamingFunc is configured, it is invoked once with
+// the final text content of the first choice after the response is transformed.
+func (m *Model) GenerateContent(ctx context.Context, messages []llms.MessageContent, options ...llms.CallOption) (*llms.ContentResponse, error) {
+	opts := llms.CallOptions{}
+	for _, opt := range options {
+		opt(&opts)
+	}
+
+	params, err := m.buildParams(messages, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	transformedReq, err := m.adapter.TransformCompletionsRequestWithContext(ctx, params)
+	if err != nil {
+		return nil, fmt.Errorf("langchaingo: failed to transform request: %w", err)
+	}
+
+	resp, err := m.client.New(ctx, transformedReq)
+	if err != nil {
+		return nil, err
+	}
+	if resp == nil || len(resp.Choices) == 0 {
+		return nil, ErrEmptyResponse
+	}
+
+	// Attach the tool schemas for adapters configured with WithArgumentCoercion
+	responseCtx := tooladapter.ContextWithTools(ctx, params.Tools)
+	transformedResp, err := m.adapter.TransformCompletionsResponseWithContext(responseCtx, *resp)
+	if err != nil {
+		return nil, fmt.Errorf("langchaingo: failed to transform response: %w", err)
+	}
+
+	result := convertResponse(transformedResp)
+
+	if opts.StreamingFunc != nil && len(result.Choices) > 0 && result.Choices[0].Content != "" {
+		if err := opts.StreamingFunc(ctx, []byte(result.Choices[0].Content)); err != nil {
+			return nil, err
+		}
+	}
+
+	return result, nil
+}
+
+// buildParams converts LangChainGo messages and call options into OpenAI request parameters.
+func (m *Model) buildParams(messages []llms.MessageContent, opts llms.CallOptions) (openai.ChatCompletionNewParams, error) {
+	model := m.model
+	if opts.Model != "" {
+		model = opts.Model
+	}
+
+	params := openai.ChatCompletionNewParams{
+		Model: model,
+	}
+
+	for i, msg := range messages {
+		converted, err := convertMessage(msg)
+		if err != nil {
+			return openai.ChatCompletionNewParams{}, fmt.Errorf("langchaingo: message %d: %w", i, err)
+		}
+		params.Messages = append(params.Messages, converted...)
+	}
+
+	tools, err := convertTools(opts)
+	if err != nil {
+		return openai.ChatCompletionNewParams{}, err
+	}
+	params.Tools = tools
+
+	toolChoice, err := convertToolChoice(opts)
+	if err != nil {
+		return openai.ChatCompletionNewParams{}, err
+	}
+	params.ToolChoice = toolChoice
+
+	if opts.MaxTokens > 0 {
+		params.MaxTokens = openai.Int(int64(opts.MaxTokens))
+	}
+	if opts.Temperature > 0 {
+		params.Temperature = openai.Float(opts.Temperature)
+	}
+	if opts.TopP > 0 {
+		params.TopP = openai.Float(opts.TopP)
+	}
+	if opts.Seed != 0 {
+		params.Seed = openai.Int(int64(opts.Seed))
+	}
+	if opts.N > 0 {
+		params.N = openai.Int(int64(opts.N))
+	}
+	if opts.FrequencyPenalty != 0 {
+		params.FrequencyPenalty = openai.Float(opts.FrequencyPenalty)
+	}
+	if opts.PresencePenalty != 0 {
+		params.PresencePenalty = openai.Float(opts.PresencePenalty)
+	}
+	if len(opts.StopWords) > 0 {
+		params.Stop = openai.ChatCompletionNewParamsStopUnion{OfStringArray: opts.StopWords}
+	}
+
+	return params, nil
+}
+
+// convertMessage converts a single LangChainGo message. Tool responses are expanded
+// into one OpenAI tool message per ToolCallResponse part.
+func convertMessage(msg llms.MessageContent) ([]openai.ChatCompletionMessageParamUnion, error) {
+	switch msg.Role {
+	case llms.ChatMessageTypeSystem:
+		return []openai.ChatCompletionMessageParamUnion{openai.SystemMessage(joinText(msg.Parts))}, nil
+
+	case llms.ChatMessageTypeHuman, llms.ChatMessageTypeGeneric:
+		return []openai.ChatCompletionMessageParamUnion{convertUserMessage(msg.Parts)}, nil
+
+	case llms.ChatMessageTypeAI:
+		return []openai.ChatCompletionMessageParamUnion{convertAssistantMessage(msg.Parts)}, nil
+
+	case llms.ChatMessageTypeTool:
+		var out []openai.ChatCompletionMessageParamUnion
+		for _, part := range msg.Parts {
+			if resp, ok := part.(llms.ToolCallResponse); ok {
+				out = append(out, openai.ToolMessage(resp.Content, resp.ToolCallID))
+			}
+		}
+		if len(out) == 0 {
+			return nil, errors.New("tool message contains no ToolCallResponse parts")
+		}
+		return out, nil
+
+	default:
+		return nil, fmt.Errorf("%w: %q", llms.ErrUnexpectedChatMessageType, msg.Role)
+	}
+}
+
+// convertUserMessage builds a user message, preserving images as multimodal parts.
+func convertUserMessage(parts []llms.ContentPart) openai.ChatCompletionMessageParamUnion {
+	hasMedia := false
+	for _, part := range parts {
+		switch part.(type) {
+		case llms.ImageURLContent, llms.BinaryContent:
+			hasMedia = true
+		}
+	}
+	if !hasMedia {
+		return openai.UserMessage(joinText(parts))
+	}
+
+	contentParts := make([]openai.ChatCompletionContentPartUnionParam, 0, len(parts))
+	for _, part := range parts {
+		switch p := part.(type) {
+		case llms.TextContent:
+			contentParts = append(contentParts, openai.TextContentPart(p.Text))
+		case llms.ImageURLContent:
+			contentParts = append(contentParts, openai.ImageContentPart(openai.ChatCompletionContentPartImageImageURLParam{
+				URL:    p.URL,
+				Detail: p.Detail,
+			}))
+		case llms.BinaryContent:
+			contentParts = append(contentParts, openai.ImageContentPart(openai.ChatCompletionContentPartImageImageURLParam{
+				URL: p.String(),
+			}))
+		}
+	}
+	return openai.UserMessage(contentParts)
+}
+
+// convertAssistantMessage builds an assistant message including any prior tool calls.
+func convertAssistantMessage(parts []llms.ContentPart) openai.ChatCompletionMessageParamUnion {
+	assistant := openai.ChatCompletionAssistantMessageParam{}
+	if text := joinText(parts); text != "" {
+		assistant.Content.OfString = openai.String(text)
+	}
+	for _, part := range parts {
+		call, ok := part.(llms.ToolCall)
+		if !ok || call.FunctionCall == nil {
+			continue
+		}
+		assistant.ToolCalls = append(assistant.ToolCalls, openai.ChatCompletionMessageToolCallUnionParam{
+			OfFunction: &openai.ChatCompletionMessageFunctionToolCallParam{
+				ID: call.ID,
+				Function: openai.ChatCompletionMessageFunctionToolCallFunctionParam{
+					Name:      call.FunctionCall.Name,
+					Arguments: call.FunctionCall.Arguments,
+				},
+			},
+		})
+	}
+	return openai.ChatCompletionMessageParamUnion{OfAssistant: &assistant}
+}
+
+// joinText concatenates all text parts of a message.
+func joinText(parts []llms.ContentPart) string {
+	var text string
+	for _, part := range parts {
+		if tc, ok := part.(llms.TextContent); ok {
+			text += tc.Text
+		}
+	}
+	return text
+}
+
+// convertTools converts LangChainGo tools (and deprecated functions) into OpenAI tool params.
+func convertTools(opts llms.CallOptions) ([]openai.ChatCompletionToolUnionParam, error) {
+	definitions := make([]llms.FunctionDefinition, 0, len(opts.Tools)+len(opts.Functions))
+	for _, tool := range opts.Tools {
+		if tool.Function == nil {
+			continue
+		}
+		definitions = append(definitions, *tool.Function)
+	}
+	definitions = append(definitions, opts.Functions...)
+
+	tools := make([]openai.ChatCompletionToolUnionParam, 0, len(definitions))
+	for _, def := range definitions {
+		fn := openai.FunctionDefinitionParam{
+			Name: def.Name,
+		}
+		if def.Description != "" {
+			fn.Description = openai.String(def.Description)
+		}
+		if def.Strict {
+			fn.Strict = openai.Bool(true)
+		}
+		if def.Parameters != nil {
+			params, err := toFunctionParameters(def.Parameters)
+			if err != nil {
+				return nil, fmt.Errorf("langchaingo: tool %q: %w", def.Name, err)
+			}
+			fn.Parameters = params
+		}
+		tools = append(tools, openai.ChatCompletionFunctionTool(fn))
+	}
+	return tools, nil
+}
+
+// convertToolChoice converts the LangChainGo tool choice, or the deprecated function
+// call behavior when no tool choice is set, into the OpenAI tool choice. A choice of
+// an unsupported type is an error rather than silently letting the model choose.
+func convertToolChoice(opts llms.CallOptions) (openai.ChatCompletionToolChoiceOptionUnionParam, error) {
+	choice := opts.ToolChoice
+	if choice == nil && opts.FunctionCallBehavior != "" {
+		choice = string(opts.FunctionCallBehavior)
+	}
+
+	switch c := choice.(type) {
+	case nil:
+		return openai.ChatCompletionToolChoiceOptionUnionParam{}, nil
+	case string:
+		switch c {
+		case "":
+			return openai.ChatCompletionToolChoiceOptionUnionParam{}, nil
+		case "none", "auto", "required":
+			return openai.ChatCompletionToolChoiceOptionUnionParam{OfAuto: openai.String(c)}, nil
+		}
+		return openai.ChatCompletionToolChoiceOptionUnionParam{}, fmt.Errorf("langchaingo: unsupported tool choice %q", c)
+	case llms.ToolChoice:
+		return convertNamedToolChoice(c)
+	case *llms.ToolChoice:
+		if c == nil {
+			return openai.ChatCompletionToolChoiceOptionUnionParam{}, nil
+		}
+		return convertNamedToolChoice(*c)
+	default:
+		return openai.ChatCompletionToolChoiceOptionUnionParam{}, fmt.Errorf("langchaingo: unsupported tool choice type %T", choice)
+	}
+}
+
+// convertNamedToolChoice converts a tool choice forcing a specific function.
+func convertNamedToolChoice(choice llms.ToolChoice) (openai.ChatCompletionToolChoiceOptionUnionParam, error) {
+	if choice.Type != "" && choice.Type != "function" {
+		return openai.ChatCompletionToolChoiceOptionUnionParam{}, fmt.Errorf("langchaingo: unsupported tool choice type %q", choice.Type)
+	}
+	if choice.Function == nil || choice.Function.Name == "" {
+		return openai.ChatCompletionToolChoiceOptionUnionParam{}, errors.New("langchaingo: tool choice names no function")
+	}
+	return openai.ToolChoiceOptionFunctionToolChoice(openai.ChatCompletionNamedToolChoiceFunctionParam{
+		Name: choice.Function.Name,
+	}), nil
+}
+
+// toFunctionParameters normalizes an arbitrary schema value (map, struct, or raw JSON)
+// into the map form expected by the OpenAI SDK.
+func toFunctionParameters(schema any) (openai.FunctionParameters, error) {
+	switch s := schema.(type) {
+	case map[string]any:
+		return s, nil
+	case openai.FunctionParameters:
+		return s, nil
+	}
+
+	var raw []byte
+	switch s := schema.(type) {
+	case json.RawMessage:
+		raw = s
+	case []byte:
+		raw = s
+	case string:
+		raw = []byte(s)
+	default:
+		encoded, err := json.Marshal(schema)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode parameters schema: %w", err)
+		}
+		raw = encoded
+	}
+
+	var params openai.FunctionParameters
+	if err := json.Unmarshal(raw, &params); err != nil {
+		return nil, fmt.Errorf("parameters schema must be a JSON object: %w", err)
+	}
+	return params, nil
+}
+
+// convertResponse maps a transformed OpenAI completion into a LangChainGo response.
+func convertResponse(resp openai.ChatCompletion) *llms.ContentResponse {
+	choices := make([]*llms.ContentChoice, 0, len(resp.Choices))
+	for _, c := range resp.Choices {
+		choice := &llms.ContentChoice{
+			Content:    c.Message.Content,
+			StopReason: c.FinishReason,
+			GenerationInfo: map[string]any{
+				"CompletionTokens": int(resp.Usage.CompletionTokens),
+				"PromptTokens":     int(resp.Usage.PromptTokens),
+				"TotalTokens":      int(resp.Usage.TotalTokens),
+			},
+		}
+
+		for _, tc := range c.Message.ToolCalls {
+			call := llms.ToolCall{
+				ID:   tc.ID,
+				Type: tc.Type,
+				FunctionCall: &llms.FunctionCall{
+					Name:      tc.Function.Name,
+					Arguments: tc.Function.Arguments,
+				},
+			}
+			choice.ToolCalls = append(choice.ToolCalls, call)
+		}
+
+		// Mirror the LangChainGo OpenAI backend, which also exposes the first call
+		// through the legacy FuncCall field.
+		if len(choice.ToolCalls) > 0 {
+			choice.FuncCall = choice.ToolCalls[0].FunctionCall
+		}
+
+		choices = append(choices, choice)
+	}
+	return &llms.ContentResponse{Choices: choices}
+}
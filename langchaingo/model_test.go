@@ -0,0 +1,248 @@
+package langchaingo
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	tooladapter "github.com/juburr/openai-tool-adapter/v3"
+	"github.com/openai/openai-go/v3"
+	"github.com/openai/openai-go/v3/option"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tmc/langchaingo/llms"
+)
+
+// fakeClient records the last request and returns a canned response
+type fakeClient struct {
+	lastRequest openai.ChatCompletionNewParams
+	response    *openai.ChatCompletion
+	err         error
+}
+
+func (f *fakeClient) New(_ context.Context, body openai.ChatCompletionNewParams, _ ...option.RequestOption) (*openai.ChatCompletion, error) {
+	f.lastRequest = body
+	return f.response, f.err
+}
+
+func completion(content string) *openai.ChatCompletion {
+	return &openai.ChatCompletion{
+		Choices: []openai.ChatCompletionChoice{
+			{Message: openai.ChatCompletionMessage{Role: "assistant", Content: content}},
+		},
+	}
+}
+
+var weatherTool = llms.Tool{
+	Type: "function",
+	Function: &llms.FunctionDefinition{
+		Name:        "get_weather",
+		Description: "Get current weather",
+		Parameters: map[string]any{
+			"type": "object",
+			"properties": map[string]any{
+				"location": map[string]any{"type": "string"},
+			},
+		},
+	},
+}
+
+func TestGenerateContent_ToolCall(t *testing.T) {
+	client := &fakeClient{response: completion(`[{"name": "get_weather", "parameters": {"location": "Paris"}}]`)}
+	model := New(client, tooladapter.New(), "gemma-3")
+
+	resp, err := model.GenerateContent(context.Background(),
+		[]llms.MessageContent{llms.TextParts(llms.ChatMessageTypeHuman, "Weather in Paris?")},
+		llms.WithTools([]llms.Tool{weatherTool}),
+	)
+	require.NoError(t, err)
+	require.Len(t, resp.Choices, 1)
+
+	choice := resp.Choices[0]
+	require.Len(t, choice.ToolCalls, 1)
+	assert.Equal(t, "get_weather", choice.ToolCalls[0].FunctionCall.Name)
+	assert.JSONEq(t, `{"location": "Paris"}`, choice.ToolCalls[0].FunctionCall.Arguments)
+	assert.NotEmpty(t, choice.ToolCalls[0].ID)
+	assert.Equal(t, "tool_calls", choice.StopReason)
+	assert.Equal(t, choice.ToolCalls[0].FunctionCall, choice.FuncCall)
+
+	// The backend must not receive native tools; they are injected into the prompt
+	assert.Empty(t, client.lastRequest.Tools)
+	assert.Equal(t, "gemma-3", client.lastRequest.Model)
+	require.Len(t, client.lastRequest.Messages, 1)
+	user := client.lastRequest.Messages[0].OfUser
+	require.NotNil(t, user)
+	assert.Contains(t, user.Content.OfString.Value, "get_weather")
+	assert.Contains(t, user.Content.OfString.Value, "Weather in Paris?")
+}
+
+func TestGenerateContent_PlainText(t *testing.T) {
+	client := &fakeClient{response: completion("It is sunny.")}
+	model := New(client, nil, "gemma-3")
+
+	var streamed string
+	resp, err := model.GenerateContent(context.Background(),
+		[]llms.MessageContent{llms.TextParts(llms.ChatMessageTypeHuman, "Hi")},
+		llms.WithModel("override-model"),
+		llms.WithMaxTokens(64),
+		llms.WithStopWords([]string{"END"}),
+		llms.WithStreamingFunc(func(_ context.Context, chunk []byte) error {
+			streamed += string(chunk)
+			return nil
+		}),
+	)
+	require.NoError(t, err)
+	require.Len(t, resp.Choices, 1)
+	assert.Equal(t, "It is sunny.", resp.Choices[0].Content)
+	assert.Empty(t, resp.Choices[0].ToolCalls)
+	assert.Equal(t, "It is sunny.", streamed)
+
+	assert.Equal(t, "override-model", client.lastRequest.Model)
+	assert.Equal(t, int64(64), client.lastRequest.MaxTokens.Value)
+	assert.Equal(t, []string{"END"}, client.lastRequest.Stop.OfStringArray)
+}
+
+func TestGenerateContent_MultiTurnToolResults(t *testing.T) {
+	client := &fakeClient{response: completion("Paris is 20C.")}
+	model := New(client, tooladapter.New(), "gemma-3")
+
+	messages := []llms.MessageContent{
+		llms.TextParts(llms.ChatMessageTypeSystem, "You are helpful."),
+		llms.TextParts(llms.ChatMessageTypeHuman, "Weather in Paris?"),
+		{
+			Role: llms.ChatMessageTypeAI,
+			Parts: []llms.ContentPart{llms.ToolCall{
+				ID:           "call_1",
+				Type:         "function",
+				FunctionCall: &llms.FunctionCall{Name: "get_weather", Arguments: `{"location":"Paris"}`},
+			}},
+		},
+		{
+			Role:  llms.ChatMessageTypeTool,
+			Parts: []llms.ContentPart{llms.ToolCallResponse{ToolCallID: "call_1", Name: "get_weather", Content: "20C"}},
+		},
+	}
+
+	resp, err := model.GenerateContent(context.Background(), messages, llms.WithTools([]llms.Tool{weatherTool}))
+	require.NoError(t, err)
+	assert.Equal(t, "Paris is 20C.", resp.Choices[0].Content)
+
+	// Tool messages are folded into the system prompt by the adapter
+	for _, msg := range client.lastRequest.Messages {
+		assert.Nil(t, msg.OfTool, "tool messages should be removed by the adapter")
+	}
+	system := client.lastRequest.Messages[0].OfSystem
+	require.NotNil(t, system)
+	assert.True(t, strings.Contains(system.Content.OfString.Value, "20C"))
+	assert.True(t, strings.Contains(system.Content.OfString.Value, "get_weather"))
+}
+
+func TestGenerateContent_Images(t *testing.T) {
+	client := &fakeClient{response: completion("A cat.")}
+	model := New(client, tooladapter.New(), "gemma-3")
+
+	_, err := model.GenerateContent(context.Background(), []llms.MessageContent{
+		{
+			Role: llms.ChatMessageTypeHuman,
+			Parts: []llms.ContentPart{
+				llms.TextPart("What is this?"),
+				llms.ImageURLPart("https://example.com/cat.png"),
+			},
+		},
+	}, llms.WithTools([]llms.Tool{weatherTool}))
+	require.NoError(t, err)
+
+	user := client.lastRequest.Messages[0].OfUser
+	require.NotNil(t, user)
+	parts := user.Content.OfArrayOfContentParts
+	require.Len(t, parts, 2)
+	require.NotNil(t, parts[1].OfImageURL)
+	assert.Equal(t, "https://example.com/cat.png", parts[1].OfImageURL.ImageURL.URL)
+}
+
+func TestGenerateContent_ToolChoice(t *testing.T) {
+	generate := func(t *testing.T, options ...llms.CallOption) (openai.ChatCompletionNewParams, error) {
+		t.Helper()
+		client := &fakeClient{response: completion("ok")}
+		model := New(client, tooladapter.New(tooladapter.WithPreserveToolsField(true)), "m")
+		options = append(options, llms.WithTools([]llms.Tool{weatherTool}))
+		_, err := model.GenerateContent(context.Background(), []llms.MessageContent{llms.TextParts(llms.ChatMessageTypeHuman, "hi")}, options...)
+		return client.lastRequest, err
+	}
+
+	t.Run("Mode", func(t *testing.T) {
+		req, err := generate(t, llms.WithToolChoice("required"))
+		require.NoError(t, err)
+		assert.Equal(t, "required", req.ToolChoice.OfAuto.Value)
+	})
+
+	t.Run("NamedFunction", func(t *testing.T) {
+		req, err := generate(t, llms.WithToolChoice(llms.ToolChoice{
+			Type:     "function",
+			Function: &llms.FunctionReference{Name: "get_weather"},
+		}))
+		require.NoError(t, err)
+		require.NotNil(t, req.ToolChoice.OfFunctionToolChoice)
+		assert.Equal(t, "get_weather", req.ToolChoice.OfFunctionToolChoice.Function.Name)
+	})
+
+	t.Run("DeprecatedFunctionCallBehavior", func(t *testing.T) {
+		req, err := generate(t, llms.WithFunctionCallBehavior(llms.FunctionCallBehaviorNone))
+		require.NoError(t, err)
+		assert.Equal(t, "none", req.ToolChoice.OfAuto.Value)
+	})
+
+	t.Run("Unsupported", func(t *testing.T) {
+		_, err := generate(t, llms.WithToolChoice("sometimes"))
+		assert.Error(t, err)
+		_, err = generate(t, llms.WithToolChoice(42))
+		assert.Error(t, err)
+		_, err = generate(t, llms.WithToolChoice(llms.ToolChoice{Type: "function"}))
+		assert.Error(t, err)
+	})
+}
+
+func TestGenerateContent_Errors(t *testing.T) {
+	t.Run("ClientError", func(t *testing.T) {
+		boom := errors.New("boom")
+		model := New(&fakeClient{err: boom}, nil, "m")
+		_, err := model.GenerateContent(context.Background(), []llms.MessageContent{llms.TextParts(llms.ChatMessageTypeHuman, "hi")})
+		assert.ErrorIs(t, err, boom)
+	})
+
+	t.Run("EmptyResponse", func(t *testing.T) {
+		model := New(&fakeClient{response: &openai.ChatCompletion{}}, nil, "m")
+		_, err := model.GenerateContent(context.Background(), []llms.MessageContent{llms.TextParts(llms.ChatMessageTypeHuman, "hi")})
+		assert.ErrorIs(t, err, ErrEmptyResponse)
+	})
+
+	t.Run("UnknownRole", func(t *testing.T) {
+		model := New(&fakeClient{response: completion("x")}, nil, "m")
+		_, err := model.GenerateContent(context.Background(), []llms.MessageContent{{Role: "bogus", Parts: []llms.ContentPart{llms.TextPart("x")}}})
+		assert.ErrorIs(t, err, llms.ErrUnexpectedChatMessageType)
+	})
+}
+
+func TestCall(t *testing.T) {
+	model := New(&fakeClient{response: completion("pong")}, nil, "m")
+	out, err := model.Call(context.Background(), "ping")
+	require.NoError(t, err)
+	assert.Equal(t, "pong", out)
+}
+
+func TestToFunctionParameters(t *testing.T) {
+	params, err := toFunctionParameters(`{"type":"object"}`)
+	require.NoError(t, err)
+	assert.Equal(t, "object", params["type"])
+
+	type schema struct {
+		Type string `json:"type"`
+	}
+	params, err = toFunctionParameters(schema{Type: "object"})
+	require.NoError(t, err)
+	assert.Equal(t, "object", params["type"])
+
+	_, err = toFunctionParameters(`[1,2]`)
+	assert.Error(t, err)
+}
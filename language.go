@@ -0,0 +1,306 @@
+package tooladapter
+
+import (
+	"sort"
+	"strings"
+)
+
+// promptLanguage holds the translated instruction text of one language pack.
+type promptLanguage struct {
+	// template replaces DefaultPromptTemplate and has the same single %s placeholder
+	template string
+
+	// Tool results section: the introduction and the per-result headings
+	toolResultsIntro    string
+	namedToolCallResult string // call ID and function name
+	toolCallResult      string // call ID
+	toolResult          string // 1-based result number
+
+	// toolResultAttachment labels a non-text part of a tool result by its 1-based number
+	toolResultAttachment string
+
+	// Few-shot examples section: the introduction and the turn labels
+	examplesIntro    string
+	exampleUser      string
+	exampleAssistant string
+
+	// singleToolCall is appended when the request disables parallel tool calls
+	singleToolCall string
+
+	// textArgumentTools introduces the WithToolArgumentModes text tools (one %s
+	// placeholder) and is followed by an example fenced block
+	textArgumentTools string
+
+	// codeBlockTool tells the model which function runs the code of a
+	// WithCodeBlockTools language (language and function name placeholders)
+	codeBlockTool string
+
+	// Tools updated section: the introduction and the lists of added, renamed,
+	// changed and removed functions (each with one %s placeholder)
+	toolsUpdated string
+	toolsAdded   string
+	toolsRenamed string
+	toolsChanged string
+	toolsRemoved string
+}
+
+// defaultPromptLanguage is the language of DefaultPromptTemplate.
+const defaultPromptLanguage = "en"
+
+// promptLanguages are the built-in language packs, keyed by ISO 639-1 code. Only the
+// instructions are translated; the JSON structure and the "name" and "parameters"
+// keys stay the same so responses parse identically.
+var promptLanguages = map[string]promptLanguage{
+	"en": {
+		template:             DefaultPromptTemplate,
+		toolResultsIntro:     "Previous tool calls requested by you returned the following results. They likely need formatting into a natural language response for the user:\n\n",
+		namedToolCallResult:  "Tool call %s (%s) result:\n",
+		toolCallResult:       "Tool call %s result:\n",
+		toolResult:           "Tool result %d:\n",
+		toolResultAttachment: "[Attachment %d]",
+		examplesIntro:        "Examples of correct responses:",
+		exampleUser:          "User: ",
+		exampleAssistant:     "Assistant: ",
+		singleToolCall:       "Return at most one function call: the JSON array must contain a single call. If more calls are needed, make the first one now; you can make the others after its result.",
+		textArgumentTools:    "These functions take raw text instead of JSON parameters: %s. To call one, write a fenced code block tagged with the function name and put the text in it exactly as is, without escaping:",
+		codeBlockTool:        "To run %[1]s code, write it in a fenced code block tagged %[1]s instead of a JSON call; the code is passed to the function %[2]s.",
+		toolsUpdated:         "The available functions have changed since earlier in this conversation. Call only the functions listed above; earlier instructions or calls naming other functions no longer apply.",
+		toolsAdded:           "Added: %s",
+		toolsRenamed:         "Renamed: %s",
+		toolsChanged:         "Changed definitions (use the current ones): %s",
+		toolsRemoved:         "Removed, do not call: %s",
+	},
+	"de": {
+		template: `Systemanweisungen für Werkzeuge:
+
+Dir stehen die folgenden Funktionen zur Verfügung. Wenn ein Funktionsaufruf nötig ist, antworte sofort (ab dem ersten Token) mit einem einzigen JSON-Array von Werkzeugaufrufen und schreibe keinen natürlichsprachlichen Text vor oder nach dem JSON.
+
+Verfügbare Funktionen:
+%s
+
+Formatvorgaben:
+- Die Ausgabe muss ausschließlich gültiges JSON sein (keine Codeblöcke).
+- Struktur: [{"name": "funktionsname", "parameters": {…}}] (verwende null, wenn es keine Parameter gibt).
+- Wenn mehrere Aufrufe nötig sind, füge sie alle in das eine JSON-Array ein.
+
+Entscheidungsregel:
+- Verwende Werkzeuge, wenn sie für eine korrekte oder effiziente Antwort nötig sind; antworte andernfalls in natürlicher Sprache, ohne Werkzeuge aufzurufen.`,
+		toolResultsIntro:     "Die von dir angeforderten Werkzeugaufrufe haben die folgenden Ergebnisse geliefert. Sie müssen wahrscheinlich in eine natürlichsprachliche Antwort für den Nutzer umformuliert werden:\n\n",
+		namedToolCallResult:  "Ergebnis von Werkzeugaufruf %s (%s):\n",
+		toolCallResult:       "Ergebnis von Werkzeugaufruf %s:\n",
+		toolResult:           "Werkzeugergebnis %d:\n",
+		toolResultAttachment: "[Anhang %d]",
+		examplesIntro:        "Beispiele für korrekte Antworten:",
+		exampleUser:          "Nutzer: ",
+		exampleAssistant:     "Assistent: ",
+		singleToolCall:       "Gib höchstens einen Funktionsaufruf zurück: Das JSON-Array darf nur einen einzigen Aufruf enthalten. Wenn mehrere Aufrufe nötig sind, führe jetzt den ersten aus; die übrigen kannst du nach seinem Ergebnis ausführen.",
+		textArgumentTools:    "Diese Funktionen erhalten Rohtext statt JSON-Parametern: %s. Um eine davon aufzurufen, schreibe einen Codeblock, der mit dem Funktionsnamen markiert ist, und setze den Text unverändert und ohne Escaping hinein:",
+		codeBlockTool:        "Um %[1]s-Code auszuführen, schreibe ihn statt eines JSON-Aufrufs in einen mit %[1]s markierten Codeblock; der Code wird an die Funktion %[2]s übergeben.",
+		toolsUpdated:         "Die verfügbaren Funktionen haben sich seit früher in dieser Unterhaltung geändert. Rufe nur die oben aufgeführten Funktionen auf; frühere Anweisungen oder Aufrufe, die andere Funktionen nennen, gelten nicht mehr.",
+		toolsAdded:           "Hinzugefügt: %s",
+		toolsRenamed:         "Umbenannt: %s",
+		toolsChanged:         "Geänderte Definitionen (verwende die aktuellen): %s",
+		toolsRemoved:         "Entfernt, nicht aufrufen: %s",
+	},
+	"es": {
+		template: `Instrucciones del sistema para herramientas:
+
+Tienes acceso a las siguientes funciones. Cuando se necesite una llamada a función, responde de inmediato (desde el primer token) con un único array JSON de llamadas a herramientas, sin texto en lenguaje natural antes ni después del JSON.
+
+Funciones disponibles:
+%s
+
+Requisitos de formato:
+- La salida debe ser únicamente JSON válido (sin bloques de código).
+- Estructura: [{"name": "nombre_de_funcion", "parameters": {…}}] (usa null si no hay parámetros).
+- Si se necesitan varias llamadas, inclúyelas todas en el único array JSON.
+
+Criterio de decisión:
+- Usa herramientas cuando sean necesarias para responder de forma correcta o eficiente; de lo contrario, responde en lenguaje natural sin llamar a ninguna herramienta.`,
+		toolResultsIntro:     "Las llamadas a herramientas que solicitaste devolvieron los siguientes resultados. Probablemente haya que convertirlos en una respuesta en lenguaje natural para el usuario:\n\n",
+		namedToolCallResult:  "Resultado de la llamada a herramienta %s (%s):\n",
+		toolCallResult:       "Resultado de la llamada a herramienta %s:\n",
+		toolResult:           "Resultado de herramienta %d:\n",
+		toolResultAttachment: "[Adjunto %d]",
+		examplesIntro:        "Ejemplos de respuestas correctas:",
+		exampleUser:          "Usuario: ",
+		exampleAssistant:     "Asistente: ",
+		singleToolCall:       "Devuelve como máximo una llamada a función: el array JSON debe contener una sola llamada. Si se necesitan más llamadas, haz ahora la primera; podrás hacer las demás después de su resultado.",
+		textArgumentTools:    "Estas funciones reciben texto sin procesar en lugar de parámetros JSON: %s. Para llamar a una, escribe un bloque de código etiquetado con el nombre de la función y pon el texto dentro tal cual, sin escaparlo:",
+		codeBlockTool:        "Para ejecutar código %[1]s, escríbelo en un bloque de código etiquetado %[1]s en lugar de una llamada JSON; el código se pasa a la función %[2]s.",
+		toolsUpdated:         "Las funciones disponibles han cambiado desde antes en esta conversación. Llama solo a las funciones indicadas arriba; las instrucciones o llamadas anteriores que mencionen otras funciones ya no se aplican.",
+		toolsAdded:           "Añadidas: %s",
+		toolsRenamed:         "Renombradas: %s",
+		toolsChanged:         "Definiciones cambiadas (usa las actuales): %s",
+		toolsRemoved:         "Eliminadas, no las llames: %s",
+	},
+	"fr": {
+		template: `Instructions système pour les outils :
+
+Tu as accès aux fonctions suivantes. Lorsqu'un appel de fonction est nécessaire, réponds immédiatement (dès le premier token) avec un unique tableau JSON d'appels d'outils, sans aucun texte en langage naturel avant ou après le JSON.
+
+Fonctions disponibles :
+%s
+
+Exigences de format :
+- La sortie doit être uniquement du JSON valide (pas de blocs de code).
+- Structure : [{"name": "nom_de_fonction", "parameters": {…}}] (utilise null s'il n'y a pas de paramètres).
+- Si plusieurs appels sont nécessaires, inclus-les tous dans l'unique tableau JSON.
+
+Règle de décision :
+- Utilise les outils lorsqu'ils sont nécessaires pour répondre correctement ou efficacement ; sinon, réponds en langage naturel sans appeler d'outil.`,
+		toolResultsIntro:     "Les appels d'outils que tu as demandés ont renvoyé les résultats suivants. Ils doivent probablement être reformulés en une réponse en langage naturel pour l'utilisateur :\n\n",
+		namedToolCallResult:  "Résultat de l'appel d'outil %s (%s) :\n",
+		toolCallResult:       "Résultat de l'appel d'outil %s :\n",
+		toolResult:           "Résultat d'outil %d :\n",
+		toolResultAttachment: "[Pièce jointe %d]",
+		examplesIntro:        "Exemples de réponses correctes :",
+		exampleUser:          "Utilisateur : ",
+		exampleAssistant:     "Assistant : ",
+		singleToolCall:       "Renvoie au plus un appel de fonction : le tableau JSON doit contenir un seul appel. Si plusieurs appels sont nécessaires, effectue maintenant le premier ; tu pourras effectuer les autres après son résultat.",
+		textArgumentTools:    "Ces fonctions reçoivent du texte brut au lieu de paramètres JSON : %s. Pour en appeler une, écris un bloc de code étiqueté avec le nom de la fonction et mets-y le texte tel quel, sans échappement :",
+		codeBlockTool:        "Pour exécuter du code %[1]s, écris-le dans un bloc de code étiqueté %[1]s au lieu d'un appel JSON ; le code est transmis à la fonction %[2]s.",
+		toolsUpdated:         "Les fonctions disponibles ont changé depuis le début de cette conversation. N'appelle que les fonctions listées ci-dessus ; les instructions ou appels précédents mentionnant d'autres fonctions ne s'appliquent plus.",
+		toolsAdded:           "Ajoutées : %s",
+		toolsRenamed:         "Renommées : %s",
+		toolsChanged:         "Définitions modifiées (utilise les actuelles) : %s",
+		toolsRemoved:         "Supprimées, ne pas appeler : %s",
+	},
+	"ja": {
+		template: `システム/ツールの指示:
+
+次の関数を利用できます。関数呼び出しが必要な場合は、直ちに(最初のトークンから)ツール呼び出しの単一のJSON配列で応答し、JSONの前後に自然言語のテキストを含めないでください。
+
+利用可能な関数:
+%s
+
+形式の要件:
+- 出力は有効なJSONのみとすること(コードフェンスは使わない)。
+- 構造: [{"name": "function_name", "parameters": {…}}](パラメーターがない場合は null を使用)。
+- 複数の呼び出しが必要な場合は、すべてを単一のJSON配列に含めること。
+
+判断基準:
+- 正確または効率的に回答するためにツールが必要な場合はツールを使用し、それ以外の場合はツールを呼び出さずに自然言語で回答してください。`,
+		toolResultsIntro:     "あなたが要求したツール呼び出しから次の結果が返されました。ユーザー向けの自然言語の回答に整形する必要があると思われます:\n\n",
+		namedToolCallResult:  "ツール呼び出し %s (%s) の結果:\n",
+		toolCallResult:       "ツール呼び出し %s の結果:\n",
+		toolResult:           "ツールの結果 %d:\n",
+		toolResultAttachment: "[添付 %d]",
+		examplesIntro:        "正しい応答の例:",
+		exampleUser:          "ユーザー: ",
+		exampleAssistant:     "アシスタント: ",
+		singleToolCall:       "関数呼び出しは最大1つだけ返してください。JSON配列には呼び出しを1つだけ含める必要があります。複数の呼び出しが必要な場合は、まず最初の1つを行い、その結果の後で残りを行ってください。",
+		textArgumentTools:    "次の関数はJSONパラメータの代わりに生のテキストを受け取ります: %s。呼び出すには、関数名をタグにしたコードブロックを書き、その中にテキストをエスケープせずそのまま入れてください:",
+		codeBlockTool:        "%[1]sのコードを実行するには、JSON呼び出しの代わりに%[1]sとタグ付けしたコードブロックに書いてください。コードは関数%[2]sに渡されます。",
+		toolsUpdated:         "この会話の以前の時点から利用可能な関数が変更されました。上に記載された関数のみを呼び出してください。他の関数を指定する以前の指示や呼び出しは無効です。",
+		toolsAdded:           "追加: %s",
+		toolsRenamed:         "名前変更: %s",
+		toolsChanged:         "定義の変更（現在の定義を使用）: %s",
+		toolsRemoved:         "削除（呼び出さないこと）: %s",
+	},
+	"pt": {
+		template: `Instruções do sistema para ferramentas:
+
+Você tem acesso às seguintes funções. Quando for necessária uma chamada de função, responda imediatamente (a partir do primeiro token) com um único array JSON de chamadas de ferramentas, sem texto em linguagem natural antes ou depois do JSON.
+
+Funções disponíveis:
+%s
+
+Requisitos de formato:
+- A saída deve ser apenas JSON válido (sem blocos de código).
+- Estrutura: [{"name": "nome_da_funcao", "parameters": {…}}] (use null se não houver parâmetros).
+- Se forem necessárias várias chamadas, inclua todas no único array JSON.
+
+Critério de decisão:
+- Use ferramentas quando forem necessárias para responder de forma correta ou eficiente; caso contrário, responda em linguagem natural sem chamar nenhuma ferramenta.`,
+		toolResultsIntro:     "As chamadas de ferramentas que você solicitou retornaram os seguintes resultados. Provavelmente precisam ser formatados em uma resposta em linguagem natural para o usuário:\n\n",
+		namedToolCallResult:  "Resultado da chamada de ferramenta %s (%s):\n",
+		toolCallResult:       "Resultado da chamada de ferramenta %s:\n",
+		toolResult:           "Resultado de ferramenta %d:\n",
+		toolResultAttachment: "[Anexo %d]",
+		examplesIntro:        "Exemplos de respostas corretas:",
+		exampleUser:          "Usuário: ",
+		exampleAssistant:     "Assistente: ",
+		singleToolCall:       "Retorne no máximo uma chamada de função: o array JSON deve conter uma única chamada. Se forem necessárias mais chamadas, faça agora a primeira; você poderá fazer as outras após o resultado dela.",
+		textArgumentTools:    "Estas funções recebem texto bruto em vez de parâmetros JSON: %s. Para chamar uma delas, escreva um bloco de código marcado com o nome da função e coloque o texto nele exatamente como está, sem escape:",
+		codeBlockTool:        "Para executar código %[1]s, escreva-o em um bloco de código marcado com %[1]s em vez de uma chamada JSON; o código é passado para a função %[2]s.",
+		toolsUpdated:         "As funções disponíveis mudaram desde antes nesta conversa. Chame apenas as funções listadas acima; instruções ou chamadas anteriores que mencionem outras funções não se aplicam mais.",
+		toolsAdded:           "Adicionadas: %s",
+		toolsRenamed:         "Renomeadas: %s",
+		toolsChanged:         "Definições alteradas (use as atuais): %s",
+		toolsRemoved:         "Removidas, não chame: %s",
+	},
+	"zh": {
+		template: `系统/工具说明：
+
+你可以使用以下函数。需要调用函数时，请立即（从第一个 token 开始）用一个包含工具调用的 JSON 数组进行回复，并且不要在 JSON 前后添加任何自然语言文本。
+
+可用函数：
+%s
+
+格式要求：
+- 输出必须仅为有效的 JSON（不要使用代码块）。
+- 结构：[{"name": "function_name", "parameters": {…}}]（如果没有参数，请使用 null）。
+- 如果需要多次调用，请将它们全部放在同一个 JSON 数组中。
+
+决策规则：
+- 当需要工具才能正确或高效地回答时使用工具；否则请直接用自然语言回复，不要调用任何工具。`,
+		toolResultsIntro:     "你之前请求的工具调用返回了以下结果。它们可能需要整理成面向用户的自然语言回复：\n\n",
+		namedToolCallResult:  "工具调用 %s（%s）的结果：\n",
+		toolCallResult:       "工具调用 %s 的结果：\n",
+		toolResult:           "工具结果 %d：\n",
+		toolResultAttachment: "[附件 %d]",
+		examplesIntro:        "正确回复示例：",
+		exampleUser:          "用户：",
+		exampleAssistant:     "助手：",
+		singleToolCall:       "最多只返回一个函数调用：JSON 数组必须只包含一个调用。如果需要多个调用，请现在只进行第一个；其余调用可以在获得其结果后再进行。",
+		textArgumentTools:    "以下函数接收原始文本而不是 JSON 参数：%s。调用时，请编写一个以函数名标记的代码块，并将文本原样放入其中，不要转义：",
+		codeBlockTool:        "要运行 %[1]s 代码，请将其写在以 %[1]s 标记的代码块中，而不是使用 JSON 调用；代码会传递给函数 %[2]s。",
+		toolsUpdated:         "自本次对话早些时候以来，可用函数已发生变化。只能调用上面列出的函数；之前提到其他函数的说明或调用不再适用。",
+		toolsAdded:           "新增：%s",
+		toolsRenamed:         "重命名：%s",
+		toolsChanged:         "定义已更改（请使用当前定义）：%s",
+		toolsRemoved:         "已移除，请勿调用：%s",
+	},
+}
+
+// PromptLanguages returns the codes accepted by WithPromptLanguage, sorted.
+func PromptLanguages() []string {
+	codes := make([]string, 0, len(promptLanguages))
+	for code := range promptLanguages {
+		codes = append(codes, code)
+	}
+	sort.Strings(codes)
+	return codes
+}
+
+// lookupPromptLanguage finds the language pack for a code such as "de", "DE" or
+// "de-AT", falling back from a regional tag to its base language.
+func lookupPromptLanguage(code string) (string, bool) {
+	code = strings.ToLower(strings.TrimSpace(code))
+	if _, ok := promptLanguages[code]; ok {
+		return code, true
+	}
+	if base, _, found := strings.Cut(strings.ReplaceAll(code, "_", "-"), "-"); found {
+		if _, ok := promptLanguages[base]; ok {
+			return base, true
+		}
+	}
+	return "", false
+}
+
+// language returns the adapter's language pack.
+func (a *Adapter) language() promptLanguage {
+	if pack, ok := promptLanguages[a.promptLanguage]; ok {
+		return pack
+	}
+	return promptLanguages[defaultPromptLanguage]
+}
+
+// promptLanguageCode returns the code of the adapter's language pack.
+func (a *Adapter) promptLanguageCode() string {
+	if a.promptLanguage == "" {
+		return defaultPromptLanguage
+	}
+	return a.promptLanguage
+}
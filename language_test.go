@@ -0,0 +1,111 @@
+package tooladapter_test
+
+import (
+	"bytes"
+	"log/slog"
+	"strings"
+	"testing"
+
+	tooladapter "github.com/juburr/openai-tool-adapter/v3"
+	"github.com/openai/openai-go/v3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWithPromptLanguage(t *testing.T) {
+	tools := []openai.ChatCompletionToolUnionParam{createMockTool("get_weather", "Get the weather")}
+
+	t.Run("TranslatesInstructionsAndResults", func(t *testing.T) {
+		adapter := tooladapter.New(
+			tooladapter.WithSystemMessageSupport(true),
+			tooladapter.WithPromptLanguage("de"))
+		req := createMockRequest(tools)
+		req.Messages = append(req.Messages, openai.ToolMessage("sonnig", "call_1"))
+
+		result, err := adapter.TransformCompletionsRequest(req)
+		require.NoError(t, err)
+		prompt := systemPrompt(t, result)
+		assert.Contains(t, prompt, "Verfügbare Funktionen:\n- get_weather: Get the weather")
+		assert.Contains(t, prompt, `[{"name": "funktionsname", "parameters": {…}}]`)
+		assert.Contains(t, prompt, "Ergebnis von Werkzeugaufruf call_1:\nsonnig")
+		assert.NotContains(t, prompt, "Available functions")
+	})
+
+	t.Run("EveryPackIsComplete", func(t *testing.T) {
+		languages := tooladapter.PromptLanguages()
+		assert.Subset(t, languages, []string{"de", "en", "es", "fr", "ja", "pt", "zh"})
+
+		english := tooladapter.New(tooladapter.WithSystemMessageSupport(true))
+		req := createMockRequest(tools)
+		req.Messages = append(req.Messages, openai.ToolMessage("ok", ""))
+		englishResult, err := english.TransformCompletionsRequest(req)
+		require.NoError(t, err)
+
+		for _, language := range languages {
+			adapter := tooladapter.New(
+				tooladapter.WithSystemMessageSupport(true),
+				tooladapter.WithPromptLanguage(language))
+			result, err := adapter.TransformCompletionsRequest(req)
+			require.NoError(t, err, language)
+
+			prompt := systemPrompt(t, result)
+			assert.Contains(t, prompt, "- get_weather: Get the weather", language)
+			assert.Contains(t, prompt, `"parameters"`, language)
+			assert.True(t, strings.HasSuffix(prompt, "ok\n\n"), language)
+			if language != "en" {
+				assert.NotEqual(t, systemPrompt(t, englishResult), prompt, language)
+			}
+
+			single := req
+			single.ParallelToolCalls = openai.Bool(false)
+			singleResult, err := adapter.TransformCompletionsRequest(single)
+			require.NoError(t, err, language)
+			assert.Greater(t, len(systemPrompt(t, singleResult)), len(prompt), language)
+		}
+	})
+
+	t.Run("RegionalTag", func(t *testing.T) {
+		adapter := tooladapter.New(
+			tooladapter.WithSystemMessageSupport(true),
+			tooladapter.WithPromptLanguage("pt_BR"))
+		result, err := adapter.TransformCompletionsRequest(createMockRequest(tools))
+		require.NoError(t, err)
+		assert.Contains(t, systemPrompt(t, result), "Funções disponíveis:")
+	})
+
+	t.Run("CustomTemplateKept", func(t *testing.T) {
+		// Order does not matter: the custom template always wins
+		adapter := tooladapter.New(
+			tooladapter.WithSystemMessageSupport(true),
+			tooladapter.WithCustomPromptTemplate("Werkzeuge:\n%s"),
+			tooladapter.WithPromptLanguage("ja"))
+		result, err := adapter.TransformCompletionsRequest(createMockRequest(tools))
+		require.NoError(t, err)
+		assert.True(t, strings.HasPrefix(systemPrompt(t, result), "Werkzeuge:\n- get_weather"))
+	})
+
+	t.Run("TemplateEngine", func(t *testing.T) {
+		adapter := tooladapter.New(
+			tooladapter.WithSystemMessageSupport(true),
+			tooladapter.WithPromptLanguage("it"), // unsupported, stays English
+			tooladapter.WithPromptLanguage("fr"),
+			tooladapter.WithToolPromptTemplate(`{{if eq .Language "fr"}}Outils{{else}}Tools{{end}}:
+{{.ToolDefinitions}}`))
+		result, err := adapter.TransformCompletionsRequest(createMockRequest(tools))
+		require.NoError(t, err)
+		assert.Equal(t, "Outils:\n- get_weather: Get the weather\n  Parameters: {\"properties\":{\"param1\":{\"description\":\"A parameter\",\"type\":\"string\"}},\"type\":\"object\"}", systemPrompt(t, result))
+	})
+
+	t.Run("UnsupportedLanguageWarns", func(t *testing.T) {
+		var logs bytes.Buffer
+		adapter := tooladapter.New(
+			tooladapter.WithLogger(slog.New(slog.NewTextHandler(&logs, nil))),
+			tooladapter.WithSystemMessageSupport(true),
+			tooladapter.WithPromptLanguage("xx"))
+		assert.Contains(t, logs.String(), "Unsupported prompt language ignored")
+
+		result, err := adapter.TransformCompletionsRequest(createMockRequest(tools))
+		require.NoError(t, err)
+		assert.Contains(t, systemPrompt(t, result), "Available functions:")
+	})
+}
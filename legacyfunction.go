@@ -0,0 +1,30 @@
+package tooladapter
+
+import "github.com/openai/openai-go/v3"
+
+// legacyFunctionCallCompletion copies the first tool call of each choice into the
+// legacy function_call field of its message (see WithLegacyFunctionCallOutput).
+func (a *Adapter) legacyFunctionCallCompletion(completion openai.ChatCompletion) openai.ChatCompletion {
+	copied := false
+	for i, choice := range completion.Choices {
+		toolCalls := choice.Message.ToolCalls
+		if len(toolCalls) == 0 {
+			continue
+		}
+		if !copied {
+			completion.Choices = append([]openai.ChatCompletionChoice(nil), completion.Choices...)
+			copied = true
+		}
+		if len(toolCalls) > 1 {
+			a.log(LogCategoryPolicy).Debug("Legacy function_call holds the first of several tool calls",
+				"choice_index", i,
+				"tool_calls", len(toolCalls),
+				"function_name", toolCalls[0].Function.Name)
+		}
+		completion.Choices[i].Message.FunctionCall = openai.ChatCompletionMessageFunctionCall{
+			Name:      toolCalls[0].Function.Name,
+			Arguments: toolCalls[0].Function.Arguments,
+		}
+	}
+	return completion
+}
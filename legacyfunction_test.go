@@ -0,0 +1,77 @@
+package tooladapter_test
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	tooladapter "github.com/juburr/openai-tool-adapter/v3"
+	"github.com/juburr/openai-tool-adapter/v3/tooltest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWithLegacyFunctionCallOutput(t *testing.T) {
+	legacy := tooladapter.New(
+		tooladapter.WithLegacyFunctionCallOutput(true),
+		tooladapter.WithToolPolicy(tooladapter.ToolDrainAll))
+
+	t.Run("FirstCall", func(t *testing.T) {
+		resp, err := legacy.TransformCompletionsResponse(tooltest.Completion(
+			`[{"name": "get_weather", "parameters": {"city": "Paris"}}, {"name": "get_time", "parameters": {"zone": "CET"}}]`))
+		require.NoError(t, err)
+
+		message := resp.Choices[0].Message
+		require.Len(t, message.ToolCalls, 2, "tool calls are kept")
+		assert.Equal(t, "get_weather", message.FunctionCall.Name)
+		assert.JSONEq(t, `{"city": "Paris"}`, message.FunctionCall.Arguments)
+		assert.Equal(t, "tool_calls", resp.Choices[0].FinishReason)
+	})
+
+	t.Run("NoToolCalls", func(t *testing.T) {
+		resp, err := legacy.TransformCompletionsResponse(tooltest.Completion("Hello!"))
+		require.NoError(t, err)
+		assert.Empty(t, resp.Choices[0].Message.FunctionCall.Name)
+	})
+
+	t.Run("Disabled", func(t *testing.T) {
+		resp, err := tooladapter.New().TransformCompletionsResponse(tooltest.Completion(`{"name": "get_weather", "parameters": {}}`))
+		require.NoError(t, err)
+		require.Len(t, resp.Choices[0].Message.ToolCalls, 1)
+		assert.Empty(t, resp.Choices[0].Message.FunctionCall.Name)
+	})
+
+	t.Run("MarshalOpenAICompletion", func(t *testing.T) {
+		adapter := tooladapter.New(
+			tooladapter.WithLegacyFunctionCallOutput(true),
+			tooladapter.WithStrictOpenAICompatibility(true))
+		resp, err := adapter.TransformCompletionsResponse(tooltest.Completion(`{"name": "get_weather", "parameters": {"city": "Paris"}}`))
+		require.NoError(t, err)
+		encoded, err := tooladapter.MarshalOpenAICompletion(resp)
+		require.NoError(t, err)
+
+		var wire struct {
+			Choices []struct {
+				Message struct {
+					FunctionCall struct {
+						Name      string `json:"name"`
+						Arguments string `json:"arguments"`
+					} `json:"function_call"`
+				} `json:"message"`
+			} `json:"choices"`
+		}
+		require.NoError(t, json.Unmarshal(encoded, &wire))
+		assert.Equal(t, "get_weather", wire.Choices[0].Message.FunctionCall.Name)
+		assert.JSONEq(t, `{"city": "Paris"}`, wire.Choices[0].Message.FunctionCall.Arguments)
+	})
+
+	t.Run("Config", func(t *testing.T) {
+		cfg, err := tooladapter.LoadConfig(strings.NewReader(`{"legacy_function_call_output": true}`))
+		require.NoError(t, err)
+		adapter, err := tooladapter.NewFromConfig(cfg)
+		require.NoError(t, err)
+		resp, err := adapter.TransformCompletionsResponse(tooltest.Completion(`{"name": "get_weather", "parameters": {}}`))
+		require.NoError(t, err)
+		assert.Equal(t, "get_weather", resp.Choices[0].Message.FunctionCall.Name)
+	})
+}
@@ -0,0 +1,457 @@
+package tooladapter
+
+import (
+	"encoding/json"
+	"errors"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// lenientToolCallPattern matches the start of a JSON5 or YAML tool call, such as
+// "{name: ...", "[{'name': ...", "name: ..." or "- name: ...".
+var lenientToolCallPattern = regexp.MustCompile(`^(?:\[\s*)?\{\s*['"]?name['"]?\s*:|^(?:-\s+)?name\s*:|^` + "```" + `ya?ml`)
+
+// yamlFencePattern matches fenced code blocks labelled yaml or yml.
+var yamlFencePattern = regexp.MustCompile("(?s)```ya?ml[ \t]*\n(.*?)```")
+
+// errNotYAMLSubset is returned when text falls outside the supported YAML subset.
+var errNotYAMLSubset = errors.New("unsupported YAML")
+
+// lenientCandidates derives strict JSON candidates from JSON5-style blocks and simple
+// YAML mappings in content. Blocks that are already valid JSON are skipped, since the
+// strict candidates cover them.
+func lenientCandidates(content string, strict []string) []string {
+	var candidates []string
+	for _, candidate := range strict {
+		if json.Valid([]byte(candidate)) {
+			continue
+		}
+		if normalized, ok := normalizeJSON5(candidate); ok {
+			candidates = append(candidates, normalized)
+		}
+	}
+
+	// YAML has no delimiters of its own, so only fenced blocks and responses that
+	// consist entirely of YAML are considered
+	regions := []string{content}
+	for _, match := range yamlFencePattern.FindAllStringSubmatch(content, -1) {
+		regions = append(regions, match[1])
+	}
+	for _, region := range regions {
+		if converted, ok := yamlToJSON(region); ok {
+			candidates = append(candidates, converted)
+		}
+	}
+	return candidates
+}
+
+// hasLenientToolCallPattern reports whether trimmed content starts like a JSON5 or
+// YAML tool call. It always returns false unless lenient parsing is enabled.
+func (a *Adapter) hasLenientToolCallPattern(trimmed string) bool {
+	return a.lenientParsing && lenientToolCallPattern.MatchString(trimmed)
+}
+
+// hasCompleteLenientJSON reports whether content holds a complete JSON5 tool call.
+// YAML is never considered complete mid-stream because a prefix of a YAML mapping
+// is itself a valid mapping.
+func (a *Adapter) hasCompleteLenientJSON(content string) bool {
+	if !a.lenientParsing {
+		return false
+	}
+	strict := extractJSONBlocks(content)
+	for _, candidate := range strict {
+		if json.Valid([]byte(candidate)) {
+			continue
+		}
+		if normalized, ok := normalizeJSON5(candidate); ok && len(ExtractFunctionCalls([]string{normalized})) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// normalizeJSON5 rewrites the JSON5 features small models commonly produce into
+// strict JSON: unquoted keys, single-quoted strings, comments, trailing commas,
+// leading plus signs and hexadecimal integers. The boolean result is false when the
+// rewritten text is still not valid JSON.
+func normalizeJSON5(input string) (string, bool) {
+	return normalizeLenientJSON(input, false)
+}
+
+// normalizeLenientJSON implements normalizeJSON5. When quoteBareWords is set, bare
+// words other than true, false and null are treated as strings, as in YAML flow
+// collections.
+func normalizeLenientJSON(input string, quoteBareWords bool) (string, bool) {
+	var out strings.Builder
+	out.Grow(len(input) + 16)
+
+	runes := []rune(input)
+	n := len(runes)
+	for i := 0; i < n; i++ {
+		r := runes[i]
+		switch {
+		case r == '"' || r == '\'':
+			end, ok := writeJSON5String(&out, runes, i)
+			if !ok {
+				return "", false
+			}
+			i = end
+
+		case r == '/' && i+1 < n && (runes[i+1] == '/' || runes[i+1] == '*'):
+			i = skipJSON5Comment(runes, i) - 1
+
+		case r == ',':
+			// Drop trailing commas before a closing bracket
+			next := skipJSON5Space(runes, i+1)
+			if next < n && (runes[next] == '}' || runes[next] == ']') {
+				continue
+			}
+			out.WriteRune(r)
+
+		case r == '+' && i+1 < n && (unicode.IsDigit(runes[i+1]) || runes[i+1] == '.'):
+			// JSON does not allow a leading plus sign
+
+		case r == '0' && i+2 < n && (runes[i+1] == 'x' || runes[i+1] == 'X'):
+			j := i + 2
+			for j < n && strings.ContainsRune("0123456789abcdefABCDEF", runes[j]) {
+				j++
+			}
+			value, err := strconv.ParseInt(string(runes[i+2:j]), 16, 64)
+			if err != nil {
+				return "", false
+			}
+			out.WriteString(strconv.FormatInt(value, 10))
+			i = j - 1
+
+		case unicode.IsDigit(r) || (r == '.' && i+1 < n && unicode.IsDigit(runes[i+1])):
+			// Consume the whole number so exponents are not mistaken for identifiers
+			j := i
+			for j < n && (unicode.IsDigit(runes[j]) || strings.ContainsRune(".eE", runes[j]) ||
+				((runes[j] == '+' || runes[j] == '-') && (runes[j-1] == 'e' || runes[j-1] == 'E'))) {
+				j++
+			}
+			number := string(runes[i:j])
+			if strings.HasPrefix(number, ".") {
+				number = "0" + number
+			}
+			out.WriteString(strings.TrimSuffix(number, "."))
+			i = j - 1
+
+		case unicode.IsLetter(r) || r == '_' || r == '$':
+			j := i
+			for j < n && (unicode.IsLetter(runes[j]) || unicode.IsDigit(runes[j]) || runes[j] == '_' || runes[j] == '$') {
+				j++
+			}
+			word := string(runes[i:j])
+			next := skipJSON5Space(runes, j)
+			isLiteral := word == "true" || word == "false" || word == "null"
+			if (next < n && runes[next] == ':') || (quoteBareWords && !isLiteral) {
+				out.WriteString(strconv.Quote(word))
+			} else {
+				// Bare literals are passed through; anything other than true, false
+				// or null fails the final validity check
+				out.WriteString(word)
+			}
+			i = j - 1
+
+		default:
+			out.WriteRune(r)
+		}
+	}
+
+	normalized := out.String()
+	if !json.Valid([]byte(normalized)) {
+		return "", false
+	}
+	return normalized, true
+}
+
+// writeJSON5String writes the string literal starting at runes[start] as a
+// double-quoted JSON string and returns the index of its closing quote.
+func writeJSON5String(out *strings.Builder, runes []rune, start int) (int, bool) {
+	quote := runes[start]
+	out.WriteByte('"')
+	for i := start + 1; i < len(runes); i++ {
+		r := runes[i]
+		switch {
+		case r == '\\' && i+1 < len(runes):
+			i++
+			if runes[i] == '\'' {
+				// \' is not a valid JSON escape
+				out.WriteRune('\'')
+			} else {
+				out.WriteRune('\\')
+				out.WriteRune(runes[i])
+			}
+		case r == quote:
+			out.WriteByte('"')
+			return i, true
+		case r == '"':
+			out.WriteString(`\"`)
+		case r == '\n':
+			out.WriteString(`\n`)
+		default:
+			out.WriteRune(r)
+		}
+	}
+	return 0, false
+}
+
+// skipJSON5Comment returns the index just past the comment starting at runes[start].
+func skipJSON5Comment(runes []rune, start int) int {
+	if runes[start+1] == '/' {
+		i := start + 2
+		for i < len(runes) && runes[i] != '\n' {
+			i++
+		}
+		return i
+	}
+	for i := start + 2; i+1 < len(runes); i++ {
+		if runes[i] == '*' && runes[i+1] == '/' {
+			return i + 2
+		}
+	}
+	return len(runes)
+}
+
+// skipJSON5Space returns the index of the next rune that is neither whitespace nor
+// part of a comment.
+func skipJSON5Space(runes []rune, i int) int {
+	for i < len(runes) {
+		switch {
+		case unicode.IsSpace(runes[i]):
+			i++
+		case runes[i] == '/' && i+1 < len(runes) && (runes[i+1] == '/' || runes[i+1] == '*'):
+			i = skipJSON5Comment(runes, i)
+		default:
+			return i
+		}
+	}
+	return i
+}
+
+// yamlLine is a non-blank, non-comment line of a YAML document.
+type yamlLine struct {
+	indent int
+	text   string
+}
+
+// yamlToJSON converts a simple YAML document, made of block mappings, block
+// sequences and scalar or flow values, into JSON. Anchors, multi-line strings and
+// other advanced YAML features are not supported. Only documents whose top level is
+// a mapping or a sequence of mappings are converted.
+func yamlToJSON(input string) (string, bool) {
+	var lines []yamlLine
+	for _, raw := range strings.Split(input, "\n") {
+		raw = strings.TrimRight(raw, " \t\r")
+		text := strings.TrimLeft(raw, " ")
+		if text == "" || strings.HasPrefix(text, "#") || text == "---" {
+			continue
+		}
+		if strings.HasPrefix(text, "\t") {
+			return "", false // Tabs are not valid YAML indentation
+		}
+		lines = append(lines, yamlLine{indent: len(raw) - len(text), text: text})
+	}
+	if len(lines) == 0 {
+		return "", false
+	}
+
+	p := &yamlParser{lines: lines}
+	value, err := p.parseBlock(lines[0].indent)
+	if err != nil || p.pos != len(lines) {
+		return "", false
+	}
+
+	switch v := value.(type) {
+	case map[string]any:
+	case []any:
+		for _, item := range v {
+			if _, ok := item.(map[string]any); !ok {
+				return "", false
+			}
+		}
+	default:
+		return "", false
+	}
+
+	data, err := json.Marshal(value)
+	if err != nil {
+		return "", false
+	}
+	return string(data), true
+}
+
+// yamlParser is a recursive descent parser over pre-split YAML lines.
+type yamlParser struct {
+	lines []yamlLine
+	pos   int
+}
+
+// parseBlock parses the mapping or sequence starting at the current line.
+func (p *yamlParser) parseBlock(indent int) (any, error) {
+	line := p.lines[p.pos]
+	if line.indent != indent {
+		return nil, errNotYAMLSubset
+	}
+	if isYAMLSequenceItem(line.text) {
+		return p.parseSequence(indent)
+	}
+	return p.parseMapping(indent)
+}
+
+// parseMapping parses "key: value" lines at the given indentation.
+func (p *yamlParser) parseMapping(indent int) (map[string]any, error) {
+	result := make(map[string]any)
+	for p.pos < len(p.lines) {
+		line := p.lines[p.pos]
+		if line.indent < indent || (line.indent == indent && isYAMLSequenceItem(line.text)) {
+			break
+		}
+		if line.indent > indent {
+			return nil, errNotYAMLSubset
+		}
+
+		key, rest, ok := splitYAMLKey(line.text)
+		if !ok {
+			return nil, errNotYAMLSubset
+		}
+		p.pos++
+
+		value, err := p.parseValue(indent, rest)
+		if err != nil {
+			return nil, err
+		}
+		result[key] = value
+	}
+	return result, nil
+}
+
+// parseSequence parses "- item" lines at the given indentation.
+func (p *yamlParser) parseSequence(indent int) ([]any, error) {
+	result := []any{}
+	for p.pos < len(p.lines) {
+		line := p.lines[p.pos]
+		if line.indent != indent || !isYAMLSequenceItem(line.text) {
+			if line.indent > indent {
+				return nil, errNotYAMLSubset
+			}
+			break
+		}
+
+		rest := strings.TrimLeft(strings.TrimPrefix(line.text, "-"), " ")
+		if _, _, isMapping := splitYAMLKey(rest); isMapping {
+			// "- key: value" starts a mapping indented to the position of its key
+			p.lines[p.pos] = yamlLine{indent: indent + len(line.text) - len(rest), text: rest}
+			item, err := p.parseMapping(p.lines[p.pos].indent)
+			if err != nil {
+				return nil, err
+			}
+			result = append(result, item)
+			continue
+		}
+
+		p.pos++
+		item, err := p.parseValue(indent, rest)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, item)
+	}
+	return result, nil
+}
+
+// parseValue parses an inline value, or the nested block that follows when the
+// inline value is empty.
+func (p *yamlParser) parseValue(indent int, inline string) (any, error) {
+	if inline != "" {
+		return parseYAMLScalar(inline)
+	}
+	if p.pos < len(p.lines) {
+		next := p.lines[p.pos]
+		if next.indent > indent || (next.indent == indent && isYAMLSequenceItem(next.text)) {
+			return p.parseBlock(next.indent)
+		}
+	}
+	return nil, nil
+}
+
+// isYAMLSequenceItem reports whether a line is a block sequence entry.
+func isYAMLSequenceItem(text string) bool {
+	return text == "-" || strings.HasPrefix(text, "- ")
+}
+
+// splitYAMLKey splits "key: value" into its key and value. Keys must be plain
+// identifiers or quoted strings.
+func splitYAMLKey(text string) (string, string, bool) {
+	var key, rest string
+	if text != "" && (text[0] == '"' || text[0] == '\'') {
+		end := strings.IndexByte(text[1:], text[0])
+		if end == -1 {
+			return "", "", false
+		}
+		key, rest = text[1:end+1], text[end+2:]
+		if !strings.HasPrefix(rest, ":") {
+			return "", "", false
+		}
+		rest = rest[1:]
+	} else {
+		colon := strings.IndexByte(text, ':')
+		if colon <= 0 {
+			return "", "", false
+		}
+		key, rest = text[:colon], text[colon+1:]
+		for _, r := range key {
+			if !isFunctionNameChar(r) && r != '.' {
+				return "", "", false
+			}
+		}
+	}
+	if rest != "" && rest[0] != ' ' {
+		return "", "", false
+	}
+	return key, strings.TrimSpace(rest), true
+}
+
+// parseYAMLScalar converts an inline YAML value into a JSON-compatible value.
+func parseYAMLScalar(text string) (any, error) {
+	switch text[0] {
+	case '{', '[':
+		normalized, ok := normalizeLenientJSON(text, true)
+		if !ok {
+			return nil, errNotYAMLSubset
+		}
+		return json.RawMessage(normalized), nil
+	case '"':
+		var s string
+		if err := json.Unmarshal([]byte(text), &s); err != nil {
+			return nil, errNotYAMLSubset
+		}
+		return s, nil
+	case '\'':
+		if len(text) < 2 || text[len(text)-1] != '\'' {
+			return nil, errNotYAMLSubset
+		}
+		return strings.ReplaceAll(text[1:len(text)-1], "''", "'"), nil
+	}
+
+	// Strip trailing comments from plain scalars
+	if idx := strings.Index(text, " #"); idx != -1 {
+		text = strings.TrimSpace(text[:idx])
+	}
+
+	switch text {
+	case "null", "Null", "NULL", "~":
+		return nil, nil
+	case "true", "True", "TRUE":
+		return true, nil
+	case "false", "False", "FALSE":
+		return false, nil
+	}
+	if _, err := strconv.ParseFloat(text, 64); err == nil && json.Valid([]byte(text)) {
+		return json.Number(text), nil
+	}
+	return text, nil
+}
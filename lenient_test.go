@@ -0,0 +1,157 @@
+package tooladapter
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNormalizeJSON5(t *testing.T) {
+	testCases := []struct {
+		name     string
+		input    string
+		expected string
+		ok       bool
+	}{
+		{"UnquotedKeys", `{name: "get_weather", parameters: {city: "Paris"}}`, `{"name":"get_weather","parameters":{"city":"Paris"}}`, true},
+		{"SingleQuotes", `{'name': 'get_weather', 'parameters': {'note': 'say "hi"'}}`, `{"name":"get_weather","parameters":{"note":"say \"hi\""}}`, true},
+		{"EscapedSingleQuote", `{name: 'it\'s'}`, `{"name":"it's"}`, true},
+		{"TrailingCommas", `[{name: "a", parameters: {list: [1, 2,],},},]`, `[{"name":"a","parameters":{"list":[1,2]}}]`, true},
+		{"Comments", "{name: \"a\", // the tool\n /* none */ parameters: null}", `{"name":"a","parameters":null}`, true},
+		{"Numbers", `{a: +1, b: 0x1F, c: .5, d: 1e5, e: -2.5E-3}`, `{"a":1,"b":31,"c":0.5,"d":1e5,"e":-2.5E-3}`, true},
+		{"Literals", `{a: true, b: false, c: null}`, `{"a":true,"b":false,"c":null}`, true},
+		{"UnknownLiteral", `{a: NaN}`, "", false},
+		{"Unterminated", `{name: 'get_weather}`, "", false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			normalized, ok := normalizeJSON5(tc.input)
+			require.Equal(t, tc.ok, ok, normalized)
+			if tc.ok {
+				assert.JSONEq(t, tc.expected, normalized)
+			}
+		})
+	}
+}
+
+func TestYAMLToJSON(t *testing.T) {
+	testCases := []struct {
+		name     string
+		input    string
+		expected string
+		ok       bool
+	}{
+		{
+			name:     "Mapping",
+			input:    "name: get_weather\nparameters:\n  city: Paris\n  days: 3\n  metric: true",
+			expected: `{"name":"get_weather","parameters":{"city":"Paris","days":3,"metric":true}}`,
+			ok:       true,
+		},
+		{
+			name:     "SequenceOfMappings",
+			input:    "- name: get_weather\n  parameters:\n    city: 'O''Hare'\n- name: get_time\n  parameters: ~",
+			expected: `[{"name":"get_weather","parameters":{"city":"O'Hare"}},{"name":"get_time","parameters":null}]`,
+			ok:       true,
+		},
+		{
+			name:     "NestedSequenceAndFlowValues",
+			input:    "# call\nname: search\nparameters:\n  tags:\n  - go\n  - \"yaml\"\n  filter: {lang: en, max: 5,}\n  note: hello # inline comment",
+			expected: `{"name":"search","parameters":{"tags":["go","yaml"],"filter":{"lang":"en","max":5},"note":"hello"}}`,
+			ok:       true,
+		},
+		{
+			name:     "QuotedNumberStaysString",
+			input:    "name: lookup\nparameters:\n  zip: \"02134\"",
+			expected: `{"name":"lookup","parameters":{"zip":"02134"}}`,
+			ok:       true,
+		},
+		{name: "Prose", input: "Sure thing, here is the weather.", ok: false},
+		{name: "SequenceOfScalars", input: "- one\n- two", ok: false},
+		{name: "BadIndentation", input: "name: a\n    parameters: null", ok: false},
+		{name: "Empty", input: "\n# only a comment\n", ok: false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			converted, ok := yamlToJSON(tc.input)
+			require.Equal(t, tc.ok, ok, converted)
+			if tc.ok {
+				assert.JSONEq(t, tc.expected, converted)
+			}
+		})
+	}
+}
+
+func TestLenientParsing(t *testing.T) {
+	testCases := []struct {
+		name    string
+		content string
+	}{
+		{"JSON5", `{name: 'get_weather', parameters: {city: 'Paris',},}`},
+		{"JSON5InFence", "Calling now:\n```json\n[{name: \"get_weather\", parameters: {city: \"Paris\"}}]\n```"},
+		{"YAML", "name: get_weather\nparameters:\n  city: Paris"},
+		{"YAMLSequence", "- name: get_weather\n  parameters:\n    city: Paris"},
+		{"YAMLFence", "I'll check.\n```yaml\nname: get_weather\nparameters:\n  city: Paris\n```"},
+	}
+
+	lenient := New(WithLenientParsing(true))
+	strict := New()
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			result, err := lenient.TransformCompletionsResponse(createMockResponse(tc.content))
+			require.NoError(t, err)
+			require.Len(t, result.Choices[0].Message.ToolCalls, 1)
+			call := result.Choices[0].Message.ToolCalls[0].Function
+			assert.Equal(t, "get_weather", call.Name)
+			assert.JSONEq(t, `{"city":"Paris"}`, call.Arguments)
+
+			result, err = strict.TransformCompletionsResponse(createMockResponse(tc.content))
+			require.NoError(t, err)
+			assert.Empty(t, result.Choices[0].Message.ToolCalls, "strict mode must not parse lenient formats")
+		})
+	}
+
+	t.Run("PlainTextUnaffected", func(t *testing.T) {
+		content := "Note: the weather in Paris is sunny."
+		result, err := lenient.TransformCompletionsResponse(createMockResponse(content))
+		require.NoError(t, err)
+		assert.Empty(t, result.Choices[0].Message.ToolCalls)
+		assert.Equal(t, content, result.Choices[0].Message.Content)
+	})
+
+	t.Run("StrictJSONTakesPrecedence", func(t *testing.T) {
+		content := `{"name": "get_weather", "parameters": null}` + "\n```yaml\nname: get_time\n```"
+		result, err := lenient.TransformCompletionsResponse(createMockResponse(content))
+		require.NoError(t, err)
+		require.Len(t, result.Choices[0].Message.ToolCalls, 1)
+		assert.Equal(t, "get_weather", result.Choices[0].Message.ToolCalls[0].Function.Name)
+	})
+
+	t.Run("Streaming", func(t *testing.T) {
+		for _, chunks := range [][]string{
+			{"{name: 'get_weather', ", "parameters: {city: 'Paris'}}"},
+			{"- name: get_weather\n", "  parameters:\n", "    city: Paris\n"},
+		} {
+			stream := lenient.TransformStreamingResponse(NewMockStream(chunks))
+
+			var names []string
+			var content string
+			for stream.Next() {
+				chunk := stream.Current()
+				if len(chunk.Choices) == 0 {
+					continue
+				}
+				content += chunk.Choices[0].Delta.Content
+				for _, toolCall := range chunk.Choices[0].Delta.ToolCalls {
+					names = append(names, toolCall.Function.Name)
+				}
+			}
+			require.NoError(t, stream.Err())
+			assert.Equal(t, []string{"get_weather"}, names)
+			assert.Empty(t, content)
+		}
+	})
+}
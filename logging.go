@@ -0,0 +1,154 @@
+package tooladapter
+
+import (
+	"context"
+	"log/slog"
+	"math"
+	"sync/atomic"
+)
+
+// LogCategory groups the adapter's log records by the part of the adapter that
+// writes them. Records carry their category in the LogCategoryKey attribute, and
+// WithLogCategoryLevel and WithLogCategorySampling tune each category's volume.
+type LogCategory string
+
+// Log categories.
+const (
+	// LogCategoryRequest covers request transformation: prompt building and
+	// injection, tool selection, sanitization and compaction.
+	LogCategoryRequest LogCategory = "request"
+
+	// LogCategoryParse covers tool call detection in responses and streams.
+	LogCategoryParse LogCategory = "parse"
+
+	// LogCategoryStream covers per-chunk streaming mechanics such as buffering,
+	// keep-alives and emitted chunks. It is the most verbose category.
+	LogCategoryStream LogCategory = "stream"
+
+	// LogCategoryPolicy covers decisions on parsed tool calls: unknown tools,
+	// filters, deduplication, argument coercion and schema violations.
+	LogCategoryPolicy LogCategory = "policy"
+
+	// LogCategoryLimit covers size, count and time limits being reached.
+	LogCategoryLimit LogCategory = "limit"
+)
+
+// LogCategoryKey is the attribute key carrying a record's LogCategory.
+const LogCategoryKey = "category"
+
+// logCategories lists every LogCategory.
+var logCategories = []LogCategory{
+	LogCategoryRequest,
+	LogCategoryParse,
+	LogCategoryStream,
+	LogCategoryPolicy,
+	LogCategoryLimit,
+}
+
+// knownLogCategory reports whether category is one of the LogCategory constants.
+func knownLogCategory(category LogCategory) bool {
+	for _, c := range logCategories {
+		if c == category {
+			return true
+		}
+	}
+	return false
+}
+
+// log returns the adapter's logger for a category.
+func (a *Adapter) log(category LogCategory) *slog.Logger {
+	if logger, ok := a.categoryLoggers[category]; ok {
+		return logger
+	}
+	return a.logger
+}
+
+// hasLogFilter reports whether any sampling or category level is configured.
+func (a *Adapter) hasLogFilter() bool {
+	return a.logSampling < 1 || len(a.logCategoryLevels) > 0 || len(a.logCategorySampling) > 0
+}
+
+// logFilter holds the sampling and level settings shared by a filteringHandler and
+// its derived handlers.
+type logFilter struct {
+	sampling         float64
+	categoryLevels   map[LogCategory]slog.Level
+	categorySampling map[LogCategory]float64
+	counters         map[LogCategory]*atomic.Uint64
+}
+
+func newLogFilter(a *Adapter) *logFilter {
+	f := &logFilter{
+		sampling:         a.logSampling,
+		categoryLevels:   a.logCategoryLevels,
+		categorySampling: a.logCategorySampling,
+		counters:         make(map[LogCategory]*atomic.Uint64, len(logCategories)+1),
+	}
+	f.counters[""] = new(atomic.Uint64)
+	for _, category := range logCategories {
+		f.counters[category] = new(atomic.Uint64)
+	}
+	return f
+}
+
+// rate returns the fraction of a category's Debug and Info records to keep.
+func (f *logFilter) rate(category LogCategory) float64 {
+	if rate, ok := f.categorySampling[category]; ok {
+		return rate
+	}
+	return f.sampling
+}
+
+// keep decides whether to sample a record. Sampling is deterministic: with a rate of
+// 0.01, the 1st, 101st, 201st, ... record of the category is kept.
+func (f *logFilter) keep(category LogCategory) bool {
+	rate := f.rate(category)
+	if rate >= 1 {
+		return true
+	}
+	counter, ok := f.counters[category]
+	if !ok || rate <= 0 {
+		return ok && rate > 0
+	}
+	i := float64(counter.Add(1) - 1)
+	return math.Floor(i*rate) != math.Floor((i-1)*rate)
+}
+
+// filteringHandler drops records below their category's level and samples Debug
+// and Info records. Warnings and errors are never sampled.
+type filteringHandler struct {
+	handler  slog.Handler
+	filter   *logFilter
+	category LogCategory
+}
+
+func (h *filteringHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	if minLevel, ok := h.filter.categoryLevels[h.category]; ok && level < minLevel {
+		return false
+	}
+	if level < slog.LevelWarn && h.filter.rate(h.category) <= 0 {
+		return false
+	}
+	return h.handler.Enabled(ctx, level)
+}
+
+func (h *filteringHandler) Handle(ctx context.Context, record slog.Record) error {
+	if record.Level < slog.LevelWarn && !h.filter.keep(h.category) {
+		return nil
+	}
+	return h.handler.Handle(ctx, record)
+}
+
+func (h *filteringHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	category := h.category
+	for _, attr := range attrs {
+		if attr.Key == LogCategoryKey {
+			category = LogCategory(attr.Value.String())
+		}
+	}
+	return &filteringHandler{handler: h.handler.WithAttrs(attrs), filter: h.filter, category: category}
+}
+
+func (h *filteringHandler) WithGroup(name string) slog.Handler {
+	return &filteringHandler{handler: h.handler.WithGroup(name), filter: h.filter, category: h.category}
+}
@@ -0,0 +1,147 @@
+package tooladapter_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"strings"
+	"testing"
+
+	tooladapter "github.com/juburr/openai-tool-adapter/v3"
+	"github.com/juburr/openai-tool-adapter/v3/tooltest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// logRecords decodes JSON log lines.
+func logRecords(t *testing.T, logs *bytes.Buffer) []map[string]any {
+	t.Helper()
+	var records []map[string]any
+	for _, line := range strings.Split(strings.TrimSpace(logs.String()), "\n") {
+		if line == "" {
+			continue
+		}
+		var record map[string]any
+		require.NoError(t, json.Unmarshal([]byte(line), &record))
+		records = append(records, record)
+	}
+	return records
+}
+
+// countCategory counts records of a category.
+func countCategory(records []map[string]any, category tooladapter.LogCategory) int {
+	n := 0
+	for _, record := range records {
+		if record[tooladapter.LogCategoryKey] == string(category) {
+			n++
+		}
+	}
+	return n
+}
+
+func newJSONLogger(logs *bytes.Buffer) *slog.Logger {
+	return slog.New(slog.NewJSONHandler(logs, &slog.HandlerOptions{Level: slog.LevelDebug}))
+}
+
+// streamChunks runs a plain content stream of n chunks through the adapter.
+func streamChunks(adapter *tooladapter.Adapter, n int) {
+	chunks := make([]string, n)
+	for i := range chunks {
+		chunks[i] = "word "
+	}
+	tooltest.Drain(adapter.TransformStreamingResponse(tooltest.NewContentStream(chunks...)))
+}
+
+func TestLogCategories(t *testing.T) {
+	var logs bytes.Buffer
+	adapter := tooladapter.New(tooladapter.WithLogger(newJSONLogger(&logs)))
+
+	_, err := adapter.TransformCompletionsRequest(tooltest.Request(tooltest.Tool("get_weather", "")))
+	require.NoError(t, err)
+	_, err = adapter.TransformCompletionsResponse(tooltest.Completion(`{"name": "get_weather", "parameters": null}`))
+	require.NoError(t, err)
+	streamChunks(adapter, 3)
+
+	records := logRecords(t, &logs)
+	assert.Positive(t, countCategory(records, tooladapter.LogCategoryRequest))
+	assert.Positive(t, countCategory(records, tooladapter.LogCategoryParse))
+	assert.Positive(t, countCategory(records, tooladapter.LogCategoryStream))
+}
+
+func TestWithLogSampling(t *testing.T) {
+	t.Run("SamplesDebugRecords", func(t *testing.T) {
+		var full, sampled bytes.Buffer
+		drainAll := tooladapter.WithToolPolicy(tooladapter.ToolDrainAll)
+		streamChunks(tooladapter.New(tooladapter.WithLogger(newJSONLogger(&full)), drainAll), 40)
+		streamChunks(tooladapter.New(
+			tooladapter.WithLogger(newJSONLogger(&sampled)),
+			tooladapter.WithLogSampling(0.1),
+			drainAll), 40)
+
+		all := countCategory(logRecords(t, &full), tooladapter.LogCategoryStream)
+		kept := countCategory(logRecords(t, &sampled), tooladapter.LogCategoryStream)
+		require.Greater(t, all, 20)
+		assert.Positive(t, kept, "the first record is kept")
+		assert.InDelta(t, float64(all)/10, float64(kept), 1)
+	})
+
+	t.Run("ZeroDropsAllButWarnings", func(t *testing.T) {
+		var logs bytes.Buffer
+		adapter := tooladapter.New(
+			tooladapter.WithLogger(newJSONLogger(&logs)),
+			tooladapter.WithLogSampling(0),
+			tooladapter.WithToolPolicy(tooladapter.ToolDrainAll),
+			tooladapter.WithToolCollectMaxBytes(16))
+		streamChunks(adapter, 10)
+
+		records := logRecords(t, &logs)
+		require.NotEmpty(t, records)
+		for _, record := range records {
+			assert.NotEqual(t, "DEBUG", record["level"])
+			assert.NotEqual(t, "INFO", record["level"])
+		}
+		assert.Positive(t, countCategory(records, tooladapter.LogCategoryLimit), "limit warnings always log")
+	})
+
+	t.Run("RejectsOutOfRange", func(t *testing.T) {
+		var logs bytes.Buffer
+		tooladapter.New(tooladapter.WithLogger(newJSONLogger(&logs)), tooladapter.WithLogSampling(1.5))
+		assert.Contains(t, logs.String(), "Log sampling rate out of range")
+	})
+}
+
+func TestWithLogCategorySampling(t *testing.T) {
+	var logs bytes.Buffer
+	adapter := tooladapter.New(
+		tooladapter.WithLogger(newJSONLogger(&logs)),
+		tooladapter.WithLogCategorySampling(tooladapter.LogCategoryStream, 0))
+
+	_, err := adapter.TransformCompletionsRequest(tooltest.Request(tooltest.Tool("get_weather", "")))
+	require.NoError(t, err)
+	streamChunks(adapter, 5)
+
+	records := logRecords(t, &logs)
+	assert.Zero(t, countCategory(records, tooladapter.LogCategoryStream))
+	assert.Positive(t, countCategory(records, tooladapter.LogCategoryRequest), "other categories log in full")
+
+	logs.Reset()
+	tooladapter.New(
+		tooladapter.WithLogger(newJSONLogger(&logs)),
+		tooladapter.WithLogCategorySampling("chunks", 0.5))
+	assert.Contains(t, logs.String(), "Unknown log category")
+}
+
+func TestWithLogCategoryLevel(t *testing.T) {
+	var logs bytes.Buffer
+	adapter := tooladapter.New(
+		tooladapter.WithLogger(newJSONLogger(&logs)),
+		tooladapter.WithLogCategoryLevel(tooladapter.LogCategoryRequest, slog.LevelWarn))
+
+	_, err := adapter.TransformCompletionsRequest(tooltest.Request(tooltest.Tool("get_weather", "")))
+	require.NoError(t, err)
+	streamChunks(adapter, 2)
+
+	records := logRecords(t, &logs)
+	assert.Zero(t, countCategory(records, tooladapter.LogCategoryRequest))
+	assert.Positive(t, countCategory(records, tooladapter.LogCategoryStream))
+}
@@ -0,0 +1,351 @@
+// Package mcp bridges Model Context Protocol (MCP) servers into prompt-emulated
+// tool calling. Tool definitions are pulled from a server with tools/list and
+// converted into OpenAI tool params for injection by tooladapter.Adapter, and tool
+// calls parsed from model responses are dispatched back to the owning server with
+// tools/call.
+//
+// The package depends only on the standard library and the OpenAI SDK. Any MCP
+// client implementation can be used by satisfying the Client interface; a minimal
+// JSON-RPC client for the stdio transport is provided by NewStreamClient.
+package mcp
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+)
+
+// ProtocolVersion is the MCP protocol revision advertised during initialization.
+const ProtocolVersion = "2025-06-18"
+
+// Tool is a tool definition as returned by an MCP server's tools/list method.
+type Tool struct {
+	Name        string          `json:"name"`
+	Description string          `json:"description,omitempty"`
+	InputSchema json.RawMessage `json:"inputSchema,omitempty"`
+}
+
+// Content is a single content item of a tools/call result.
+type Content struct {
+	Type     string `json:"type"`
+	Text     string `json:"text,omitempty"`
+	Data     string `json:"data,omitempty"`
+	MimeType string `json:"mimeType,omitempty"`
+}
+
+// CallToolResult is the result of an MCP tools/call request.
+type CallToolResult struct {
+	Content []Content `json:"content"`
+	IsError bool      `json:"isError,omitempty"`
+}
+
+// Client is the subset of an MCP client session used by this package.
+// Implementations must be safe for concurrent use if the Dispatcher is shared.
+type Client interface {
+	// ListTools returns all tools exposed by the server, following pagination.
+	ListTools(ctx context.Context) ([]Tool, error)
+
+	// CallTool invokes a tool with JSON-encoded arguments.
+	CallTool(ctx context.Context, name string, arguments json.RawMessage) (*CallToolResult, error)
+}
+
+// RPCError is a JSON-RPC error returned by an MCP server.
+type RPCError struct {
+	Code    int             `json:"code"`
+	Message string          `json:"message"`
+	Data    json.RawMessage `json:"data,omitempty"`
+}
+
+func (e *RPCError) Error() string {
+	return fmt.Sprintf("mcp: rpc error %d: %s", e.Code, e.Message)
+}
+
+// ErrClientClosed is returned when a request is made on a closed StreamClient.
+var ErrClientClosed = errors.New("mcp: client closed")
+
+// StreamClient is a minimal MCP client speaking newline-delimited JSON-RPC 2.0
+// over a byte stream, as used by the MCP stdio transport.
+//
+// THREAD SAFETY: A StreamClient may be shared across goroutines. Requests can be in
+// flight concurrently; a single reader goroutine, started by the first request,
+// dispatches responses to their callers by request ID, so a request that never gets
+// a response only blocks its own caller, until its context is done.
+//
+// Requests from the server are never mistaken for responses, even when their ID
+// matches a pending request: ping is answered with an empty result and any other
+// method with a method-not-found error.
+type StreamClient struct {
+	mu      sync.Mutex // guards writes, nextID, pending and closed
+	w       io.Writer
+	r       *bufio.Reader
+	closer  io.Closer
+	nextID  int64
+	pending map[int64]chan rpcResponse
+	closed  bool
+	info    ClientInfo
+
+	readOnce sync.Once
+	readDone chan struct{} // closed when the reader stops, after readErr is set
+	readErr  error
+	closing  chan struct{} // closed by Close
+}
+
+// ClientInfo identifies this client to the server during initialization.
+type ClientInfo struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+}
+
+// NewStreamClient creates a client that writes requests to w and reads responses
+// from r. If w implements io.Closer, Close closes it. Call Initialize before use.
+func NewStreamClient(r io.Reader, w io.Writer, info ClientInfo) *StreamClient {
+	c := &StreamClient{
+		w:        w,
+		r:        bufio.NewReader(r),
+		pending:  make(map[int64]chan rpcResponse),
+		info:     info,
+		readDone: make(chan struct{}),
+		closing:  make(chan struct{}),
+	}
+	if closer, ok := w.(io.Closer); ok {
+		c.closer = closer
+	}
+	return c
+}
+
+type rpcRequest struct {
+	JSONRPC string `json:"jsonrpc"`
+	ID      *int64 `json:"id,omitempty"`
+	Method  string `json:"method"`
+	Params  any    `json:"params,omitempty"`
+}
+
+type rpcResponse struct {
+	ID     *int64          `json:"id"`
+	Result json.RawMessage `json:"result"`
+	Error  *RPCError       `json:"error"`
+}
+
+// rpcServerRequest is a request or notification sent by the server. Its ID is kept
+// raw because servers may use string or numeric IDs.
+type rpcServerRequest struct {
+	ID     json.RawMessage `json:"id"`
+	Method string          `json:"method"`
+}
+
+// rpcReply answers a server request.
+type rpcReply struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id"`
+	Result  any             `json:"result,omitempty"`
+	Error   *RPCError       `json:"error,omitempty"`
+}
+
+// Initialize performs the MCP initialize handshake and sends the initialized notification.
+func (c *StreamClient) Initialize(ctx context.Context) error {
+	params := map[string]any{
+		"protocolVersion": ProtocolVersion,
+		"capabilities":    map[string]any{},
+		"clientInfo":      c.info,
+	}
+	if _, err := c.call(ctx, "initialize", params); err != nil {
+		return fmt.Errorf("mcp: initialize failed: %w", err)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.write(rpcRequest{JSONRPC: "2.0", Method: "notifications/initialized"}, "notifications/initialized")
+}
+
+// ListTools implements Client by calling tools/list until no cursor is returned.
+func (c *StreamClient) ListTools(ctx context.Context) ([]Tool, error) {
+	var tools []Tool
+	cursor := ""
+	for {
+		var params any
+		if cursor != "" {
+			params = map[string]string{"cursor": cursor}
+		}
+		raw, err := c.call(ctx, "tools/list", params)
+		if err != nil {
+			return nil, err
+		}
+
+		var page struct {
+			Tools      []Tool `json:"tools"`
+			NextCursor string `json:"nextCursor"`
+		}
+		if err := json.Unmarshal(raw, &page); err != nil {
+			return nil, fmt.Errorf("mcp: invalid tools/list result: %w", err)
+		}
+		tools = append(tools, page.Tools...)
+
+		if page.NextCursor == "" || page.NextCursor == cursor {
+			return tools, nil
+		}
+		cursor = page.NextCursor
+	}
+}
+
+// CallTool implements Client by calling tools/call.
+func (c *StreamClient) CallTool(ctx context.Context, name string, arguments json.RawMessage) (*CallToolResult, error) {
+	if len(arguments) == 0 || string(arguments) == "null" {
+		arguments = json.RawMessage("{}")
+	}
+	params := map[string]any{
+		"name":      name,
+		"arguments": arguments,
+	}
+	raw, err := c.call(ctx, "tools/call", params)
+	if err != nil {
+		return nil, err
+	}
+
+	var result CallToolResult
+	if err := json.Unmarshal(raw, &result); err != nil {
+		return nil, fmt.Errorf("mcp: invalid tools/call result: %w", err)
+	}
+	return &result, nil
+}
+
+// Close closes the underlying writer if it implements io.Closer. Requests still
+// waiting for a response return ErrClientClosed.
+func (c *StreamClient) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.closed {
+		return nil
+	}
+	c.closed = true
+	close(c.closing)
+	if c.closer != nil {
+		return c.closer.Close()
+	}
+	return nil
+}
+
+// call sends a request and waits for the response with the matching ID, until ctx
+// is done, the client is closed or reading from the server fails.
+func (c *StreamClient) call(ctx context.Context, method string, params any) (json.RawMessage, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	c.readOnce.Do(func() { go c.readLoop() })
+
+	c.mu.Lock()
+	if c.closed {
+		c.mu.Unlock()
+		return nil, ErrClientClosed
+	}
+	c.nextID++
+	id := c.nextID
+	response := make(chan rpcResponse, 1)
+	c.pending[id] = response
+	if err := c.write(rpcRequest{JSONRPC: "2.0", ID: &id, Method: method, Params: params}, method); err != nil {
+		delete(c.pending, id)
+		c.mu.Unlock()
+		return nil, err
+	}
+	c.mu.Unlock()
+
+	select {
+	case resp := <-response:
+		if resp.Error != nil {
+			return nil, resp.Error
+		}
+		return resp.Result, nil
+	case <-ctx.Done():
+		c.forget(id)
+		return nil, ctx.Err()
+	case <-c.closing:
+		c.forget(id)
+		return nil, ErrClientClosed
+	case <-c.readDone:
+		// The reader may have delivered the response before it stopped
+		select {
+		case resp := <-response:
+			if resp.Error != nil {
+				return nil, resp.Error
+			}
+			return resp.Result, nil
+		default:
+		}
+		c.forget(id)
+		return nil, fmt.Errorf("mcp: reading response for %s: %w", method, c.readErr)
+	}
+}
+
+// forget stops waiting for the response to request id.
+func (c *StreamClient) forget(id int64) {
+	c.mu.Lock()
+	delete(c.pending, id)
+	c.mu.Unlock()
+}
+
+// readLoop reads messages from the server until reading fails, handing each
+// response to the request waiting for it. Messages carrying a method are requests
+// or notifications from the server and go to handleServerRequest; noise and
+// responses nobody waits for anymore are skipped.
+func (c *StreamClient) readLoop() {
+	for {
+		line, err := c.r.ReadBytes('\n')
+		var req rpcServerRequest
+		var resp rpcResponse
+		switch {
+		case len(line) == 0 || json.Unmarshal(line, &req) != nil:
+		case req.Method != "":
+			c.handleServerRequest(req)
+		case json.Unmarshal(line, &resp) == nil && resp.ID != nil:
+			c.mu.Lock()
+			response, ok := c.pending[*resp.ID]
+			delete(c.pending, *resp.ID)
+			c.mu.Unlock()
+			if ok {
+				response <- resp
+			}
+		}
+		if err != nil {
+			c.readErr = err
+			close(c.readDone)
+			return
+		}
+	}
+}
+
+// handleServerRequest answers ping with an empty result and every other server
+// request with a method-not-found error. Notifications get no reply.
+func (c *StreamClient) handleServerRequest(req rpcServerRequest) {
+	if len(req.ID) == 0 || string(req.ID) == "null" {
+		return
+	}
+	reply := rpcReply{JSONRPC: "2.0", ID: req.ID}
+	if req.Method == "ping" {
+		reply.Result = struct{}{}
+	} else {
+		reply.Error = &RPCError{Code: -32601, Message: "Method not found"}
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if !c.closed {
+		_ = c.write(reply, req.Method)
+	}
+}
+
+// write encodes a single newline-delimited JSON-RPC message for method. Callers
+// must hold c.mu.
+func (c *StreamClient) write(msg any, method string) error {
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	data = append(data, '\n')
+	if _, err := c.w.Write(data); err != nil {
+		return fmt.Errorf("mcp: writing %s: %w", method, err)
+	}
+	return nil
+}
@@ -0,0 +1,113 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/openai/openai-go/v3"
+)
+
+// ErrUnknownTool is returned when a tool call does not match any registered server.
+var ErrUnknownTool = errors.New("mcp: no server registered for tool")
+
+// Dispatcher routes tool calls parsed by the adapter back to the MCP server that
+// owns the tool and converts the results into tool messages for the next turn.
+//
+// THREAD SAFETY: A Dispatcher is immutable after construction and safe for concurrent
+// use, provided the underlying clients are.
+type Dispatcher struct {
+	byPrefix map[string]Client
+	fallback Client
+}
+
+// NewDispatcher creates a dispatcher for the given servers. A server with an empty
+// prefix receives calls whose names carry no prefix.
+func NewDispatcher(servers ...Server) *Dispatcher {
+	d := &Dispatcher{byPrefix: make(map[string]Client)}
+	for _, server := range servers {
+		if server.Prefix == "" {
+			d.fallback = server.Client
+			continue
+		}
+		d.byPrefix[server.Prefix] = server.Client
+	}
+	return d
+}
+
+// resolve finds the client and server-local tool name for a model-facing name.
+func (d *Dispatcher) resolve(name string) (Client, string, error) {
+	if prefix, local, ok := strings.Cut(name, "."); ok {
+		if client, found := d.byPrefix[prefix]; found {
+			return client, local, nil
+		}
+	}
+	if d.fallback != nil {
+		return d.fallback, name, nil
+	}
+	return nil, "", fmt.Errorf("%w: %q", ErrUnknownTool, name)
+}
+
+// Call invokes a single tool by its model-facing name.
+func (d *Dispatcher) Call(ctx context.Context, name string, arguments string) (*CallToolResult, error) {
+	client, local, err := d.resolve(name)
+	if err != nil {
+		return nil, err
+	}
+
+	args := json.RawMessage(arguments)
+	if strings.TrimSpace(arguments) == "" {
+		args = nil
+	}
+	return client.CallTool(ctx, local, args)
+}
+
+// Dispatch executes every tool call in order and returns one tool message per call.
+//
+// Tool-level failures (errors from the server or results flagged with isError) are
+// reported to the model as tool message content rather than aborting the loop, so
+// the model can recover. Only context cancellation aborts dispatching.
+func (d *Dispatcher) Dispatch(ctx context.Context, calls []openai.ChatCompletionMessageToolCallUnion) ([]openai.ChatCompletionMessageParamUnion, error) {
+	messages := make([]openai.ChatCompletionMessageParamUnion, 0, len(calls))
+	for _, call := range calls {
+		if err := ctx.Err(); err != nil {
+			return messages, err
+		}
+
+		result, err := d.Call(ctx, call.Function.Name, call.Function.Arguments)
+		var content string
+		switch {
+		case err != nil:
+			content = "Error: " + err.Error()
+		case result.IsError:
+			content = "Error: " + ResultText(result)
+		default:
+			content = ResultText(result)
+		}
+		messages = append(messages, openai.ToolMessage(content, call.ID))
+	}
+	return messages, nil
+}
+
+// ResultText flattens a tool result into plain text. Text items are joined with
+// newlines; non-text items are summarized by type and MIME type.
+func ResultText(result *CallToolResult) string {
+	if result == nil {
+		return ""
+	}
+	parts := make([]string, 0, len(result.Content))
+	for _, item := range result.Content {
+		if item.Type == "text" {
+			parts = append(parts, item.Text)
+			continue
+		}
+		if item.MimeType != "" {
+			parts = append(parts, fmt.Sprintf("[%s content: %s]", item.Type, item.MimeType))
+		} else {
+			parts = append(parts, fmt.Sprintf("[%s content]", item.Type))
+		}
+	}
+	return strings.Join(parts, "\n")
+}
@@ -0,0 +1,334 @@
+package mcp
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"testing"
+	"time"
+
+	tooladapter "github.com/juburr/openai-tool-adapter/v3"
+	"github.com/openai/openai-go/v3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeClient is an in-memory Client implementation
+type fakeClient struct {
+	tools   []Tool
+	listErr error
+	calls   []string
+	result  *CallToolResult
+	callErr error
+}
+
+func (f *fakeClient) ListTools(context.Context) ([]Tool, error) {
+	return f.tools, f.listErr
+}
+
+func (f *fakeClient) CallTool(_ context.Context, name string, args json.RawMessage) (*CallToolResult, error) {
+	f.calls = append(f.calls, name+" "+string(args))
+	return f.result, f.callErr
+}
+
+func TestLoadTools(t *testing.T) {
+	weather := &fakeClient{tools: []Tool{{
+		Name:        "get_weather",
+		Description: "Get weather",
+		InputSchema: json.RawMessage(`{"type":"object","properties":{"city":{"type":"string"}}}`),
+	}}}
+	files := &fakeClient{tools: []Tool{{Name: "read_file"}}}
+
+	tools, err := LoadTools(context.Background(),
+		Server{Prefix: "weather", Client: weather},
+		Server{Prefix: "fs", Client: files},
+	)
+	require.NoError(t, err)
+	require.Len(t, tools, 2)
+
+	fn := tools[0].GetFunction()
+	require.NotNil(t, fn)
+	assert.Equal(t, "weather.get_weather", fn.Name)
+	assert.Equal(t, "Get weather", fn.Description.Value)
+	assert.Equal(t, "object", fn.Parameters["type"])
+
+	assert.Equal(t, "fs.read_file", tools[1].GetFunction().Name)
+	assert.Nil(t, tools[1].GetFunction().Parameters)
+
+	// The converted tools are accepted by the adapter
+	adapter := tooladapter.New()
+	req, err := adapter.TransformCompletionsRequest(openai.ChatCompletionNewParams{
+		Messages: []openai.ChatCompletionMessageParamUnion{openai.UserMessage("hi")},
+		Tools:    tools,
+	})
+	require.NoError(t, err)
+	assert.Contains(t, req.Messages[0].OfUser.Content.OfString.Value, "weather.get_weather")
+}
+
+func TestLoadTools_Errors(t *testing.T) {
+	t.Run("ListError", func(t *testing.T) {
+		boom := errors.New("boom")
+		_, err := LoadTools(context.Background(), Server{Prefix: "a", Client: &fakeClient{listErr: boom}})
+		assert.ErrorIs(t, err, boom)
+	})
+
+	t.Run("Collision", func(t *testing.T) {
+		c := &fakeClient{tools: []Tool{{Name: "dup"}}}
+		_, err := LoadTools(context.Background(), Server{Client: c}, Server{Client: c})
+		assert.ErrorContains(t, err, "dup")
+	})
+
+	t.Run("InvalidName", func(t *testing.T) {
+		c := &fakeClient{tools: []Tool{{Name: "bad name"}}}
+		_, err := LoadTools(context.Background(), Server{Prefix: "srv", Client: c})
+		assert.Error(t, err)
+	})
+
+	t.Run("InvalidSchema", func(t *testing.T) {
+		c := &fakeClient{tools: []Tool{{Name: "tool", InputSchema: json.RawMessage(`[1]`)}}}
+		_, err := LoadTools(context.Background(), Server{Prefix: "srv", Client: c})
+		assert.ErrorContains(t, err, "inputSchema")
+	})
+}
+
+func TestDispatcher(t *testing.T) {
+	weather := &fakeClient{result: &CallToolResult{Content: []Content{{Type: "text", Text: "20C"}}}}
+	failing := &fakeClient{callErr: errors.New("offline")}
+	flagged := &fakeClient{result: &CallToolResult{IsError: true, Content: []Content{{Type: "text", Text: "bad city"}}}}
+	fallback := &fakeClient{result: &CallToolResult{Content: []Content{{Type: "image", MimeType: "image/png"}}}}
+
+	d := NewDispatcher(
+		Server{Prefix: "weather", Client: weather},
+		Server{Prefix: "down", Client: failing},
+		Server{Prefix: "flag", Client: flagged},
+		Server{Client: fallback},
+	)
+
+	calls := []openai.ChatCompletionMessageToolCallUnion{
+		{ID: "call_1", Function: openai.ChatCompletionMessageFunctionToolCallFunction{Name: "weather.get_weather", Arguments: `{"city":"Paris"}`}},
+		{ID: "call_2", Function: openai.ChatCompletionMessageFunctionToolCallFunction{Name: "down.ping", Arguments: "null"}},
+		{ID: "call_3", Function: openai.ChatCompletionMessageFunctionToolCallFunction{Name: "flag.check", Arguments: ""}},
+		{ID: "call_4", Function: openai.ChatCompletionMessageFunctionToolCallFunction{Name: "screenshot", Arguments: "{}"}},
+	}
+
+	messages, err := d.Dispatch(context.Background(), calls)
+	require.NoError(t, err)
+	require.Len(t, messages, 4)
+
+	assert.Equal(t, []string{`get_weather {"city":"Paris"}`}, weather.calls)
+	assert.Equal(t, []string{"screenshot {}"}, fallback.calls)
+
+	texts := make([]string, len(messages))
+	for i, msg := range messages {
+		require.NotNil(t, msg.OfTool)
+		texts[i] = msg.OfTool.Content.OfString.Value
+		assert.Equal(t, calls[i].ID, msg.OfTool.ToolCallID)
+	}
+	assert.Equal(t, "20C", texts[0])
+	assert.Equal(t, "Error: offline", texts[1])
+	assert.Equal(t, "Error: bad city", texts[2])
+	assert.Equal(t, "[image content: image/png]", texts[3])
+}
+
+func TestDispatcher_UnknownTool(t *testing.T) {
+	d := NewDispatcher(Server{Prefix: "a", Client: &fakeClient{}})
+	_, err := d.Call(context.Background(), "b.tool", "{}")
+	assert.ErrorIs(t, err, ErrUnknownTool)
+}
+
+func TestDispatcher_ContextCancelled(t *testing.T) {
+	d := NewDispatcher(Server{Client: &fakeClient{result: &CallToolResult{}}})
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err := d.Dispatch(ctx, []openai.ChatCompletionMessageToolCallUnion{{ID: "x"}})
+	assert.ErrorIs(t, err, context.Canceled)
+}
+
+// runFakeServer answers JSON-RPC requests read from r by writing to w.
+func runFakeServer(t *testing.T, r io.Reader, w io.WriteCloser) {
+	t.Helper()
+	go func() {
+		defer w.Close()
+		scanner := bufio.NewScanner(r)
+		for scanner.Scan() {
+			var req struct {
+				ID     *int64          `json:"id"`
+				Method string          `json:"method"`
+				Params json.RawMessage `json:"params"`
+			}
+			if err := json.Unmarshal(scanner.Bytes(), &req); err != nil || req.ID == nil {
+				continue // notifications
+			}
+
+			var result any
+			var rpcErr *RPCError
+			switch req.Method {
+			case "initialize":
+				result = map[string]any{"protocolVersion": ProtocolVersion}
+			case "tools/list":
+				var p struct {
+					Cursor string `json:"cursor"`
+				}
+				_ = json.Unmarshal(req.Params, &p)
+				if p.Cursor == "" {
+					result = map[string]any{"tools": []Tool{{Name: "one"}}, "nextCursor": "page2"}
+				} else {
+					result = map[string]any{"tools": []Tool{{Name: "two"}}}
+				}
+			case "tools/call":
+				result = CallToolResult{Content: []Content{{Type: "text", Text: string(req.Params)}}}
+			default:
+				rpcErr = &RPCError{Code: -32601, Message: "method not found"}
+			}
+
+			// Interleave a notification to verify the client skips it
+			_, _ = w.Write([]byte(`{"jsonrpc":"2.0","method":"notifications/progress"}` + "\n"))
+			resp, _ := json.Marshal(map[string]any{"jsonrpc": "2.0", "id": *req.ID, "result": result, "error": rpcErr})
+			_, _ = w.Write(append(resp, '\n'))
+		}
+	}()
+}
+
+func TestStreamClient(t *testing.T) {
+	clientReader, serverWriter := io.Pipe()
+	serverReader, clientWriter := io.Pipe()
+	runFakeServer(t, serverReader, serverWriter)
+
+	client := NewStreamClient(clientReader, clientWriter, ClientInfo{Name: "test", Version: "1.0"})
+	defer client.Close()
+
+	ctx := context.Background()
+	require.NoError(t, client.Initialize(ctx))
+
+	tools, err := client.ListTools(ctx)
+	require.NoError(t, err)
+	require.Len(t, tools, 2)
+	assert.Equal(t, "one", tools[0].Name)
+	assert.Equal(t, "two", tools[1].Name)
+
+	result, err := client.CallTool(ctx, "one", nil)
+	require.NoError(t, err)
+	require.Len(t, result.Content, 1)
+	assert.JSONEq(t, `{"name":"one","arguments":{}}`, result.Content[0].Text)
+
+	_, err = client.call(ctx, "bogus", nil)
+	var rpcErr *RPCError
+	require.ErrorAs(t, err, &rpcErr)
+	assert.Equal(t, -32601, rpcErr.Code)
+
+	require.NoError(t, client.Close())
+	_, err = client.ListTools(ctx)
+	assert.ErrorIs(t, err, ErrClientClosed)
+}
+
+func TestStreamClient_UnansweredRequest(t *testing.T) {
+	clientReader, serverWriter := io.Pipe()
+	serverReader, clientWriter := io.Pipe()
+
+	// The server never answers calls of the "hang" tool
+	go func() {
+		defer serverWriter.Close()
+		scanner := bufio.NewScanner(serverReader)
+		for scanner.Scan() {
+			var req struct {
+				ID     *int64 `json:"id"`
+				Params struct {
+					Name string `json:"name"`
+				} `json:"params"`
+			}
+			if err := json.Unmarshal(scanner.Bytes(), &req); err != nil || req.ID == nil || req.Params.Name == "hang" {
+				continue
+			}
+			resp, _ := json.Marshal(map[string]any{"jsonrpc": "2.0", "id": *req.ID, "result": CallToolResult{Content: []Content{{Type: "text", Text: req.Params.Name}}}})
+			_, _ = serverWriter.Write(append(resp, '\n'))
+		}
+	}()
+
+	client := NewStreamClient(clientReader, clientWriter, ClientInfo{Name: "test", Version: "1.0"})
+	defer client.Close()
+
+	hung := make(chan error, 1)
+	hangCtx, cancel := context.WithCancel(context.Background())
+	go func() {
+		_, err := client.CallTool(hangCtx, "hang", nil)
+		hung <- err
+	}()
+
+	// Other requests are answered while the first one waits
+	result, err := client.CallTool(context.Background(), "echo", nil)
+	require.NoError(t, err)
+	assert.Equal(t, "echo", result.Content[0].Text)
+
+	cancel()
+	select {
+	case err := <-hung:
+		assert.ErrorIs(t, err, context.Canceled)
+	case <-time.After(time.Second):
+		t.Fatal("unanswered request ignored its context")
+	}
+
+	// Close releases requests still waiting
+	go func() {
+		_, err := client.CallTool(context.Background(), "hang", nil)
+		hung <- err
+	}()
+	time.Sleep(10 * time.Millisecond)
+	require.NoError(t, client.Close())
+	select {
+	case err := <-hung:
+		assert.ErrorIs(t, err, ErrClientClosed)
+	case <-time.After(time.Second):
+		t.Fatal("Close did not release the waiting request")
+	}
+}
+
+func TestStreamClient_ServerRequests(t *testing.T) {
+	clientReader, serverWriter := io.Pipe()
+	serverReader, clientWriter := io.Pipe()
+
+	// Before answering tools/list, the server sends requests reusing its ID and
+	// collects the client's replies
+	replies := make(chan map[string]any, 2)
+	go func() {
+		defer serverWriter.Close()
+		scanner := bufio.NewScanner(serverReader)
+		require.True(t, scanner.Scan())
+		var req struct {
+			ID *int64 `json:"id"`
+		}
+		require.NoError(t, json.Unmarshal(scanner.Bytes(), &req))
+		require.NotNil(t, req.ID)
+
+		for _, method := range []string{"ping", "sampling/createMessage"} {
+			msg, _ := json.Marshal(map[string]any{"jsonrpc": "2.0", "id": *req.ID, "method": method})
+			_, _ = serverWriter.Write(append(msg, '\n'))
+			require.True(t, scanner.Scan())
+			var reply map[string]any
+			require.NoError(t, json.Unmarshal(scanner.Bytes(), &reply))
+			replies <- reply
+		}
+
+		resp, _ := json.Marshal(map[string]any{"jsonrpc": "2.0", "id": *req.ID, "result": map[string]any{"tools": []Tool{{Name: "one"}}}})
+		_, _ = serverWriter.Write(append(resp, '\n'))
+		for scanner.Scan() {
+		}
+	}()
+
+	client := NewStreamClient(clientReader, clientWriter, ClientInfo{Name: "test", Version: "1.0"})
+	defer client.Close()
+
+	tools, err := client.ListTools(context.Background())
+	require.NoError(t, err)
+	require.Len(t, tools, 1)
+	assert.Equal(t, "one", tools[0].Name)
+
+	ping := <-replies
+	assert.Equal(t, map[string]any{}, ping["result"])
+	assert.Nil(t, ping["error"])
+
+	unsupported := <-replies
+	assert.Nil(t, unsupported["result"])
+	assert.Equal(t, -32601.0, unsupported["error"].(map[string]any)["code"])
+}
@@ -0,0 +1,85 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	tooladapter "github.com/juburr/openai-tool-adapter/v3"
+	"github.com/openai/openai-go/v3"
+)
+
+// Server associates an MCP client with the prefix used to namespace its tools.
+//
+// When Prefix is non-empty, tools are exposed to the model as "prefix.tool_name",
+// which is the MCP naming convention accepted by tooladapter.ValidateFunctionName.
+// The prefix must be alphanumeric.
+type Server struct {
+	Prefix string
+	Client Client
+}
+
+// ToolName returns the model-facing name for one of this server's tools.
+func (s Server) ToolName(name string) string {
+	if s.Prefix == "" {
+		return name
+	}
+	return s.Prefix + "." + name
+}
+
+// ConvertTool converts an MCP tool definition into an OpenAI function tool param.
+// The tool's inputSchema is used as the function parameters schema.
+func ConvertTool(tool Tool, name string) (openai.ChatCompletionToolUnionParam, error) {
+	if err := tooladapter.ValidateFunctionName(name); err != nil {
+		return openai.ChatCompletionToolUnionParam{}, fmt.Errorf("mcp: tool %q: %w", tool.Name, err)
+	}
+
+	fn := openai.FunctionDefinitionParam{
+		Name: name,
+	}
+	if tool.Description != "" {
+		fn.Description = openai.String(tool.Description)
+	}
+	if len(tool.InputSchema) > 0 && string(tool.InputSchema) != "null" {
+		var params openai.FunctionParameters
+		if err := json.Unmarshal(tool.InputSchema, &params); err != nil {
+			return openai.ChatCompletionToolUnionParam{}, fmt.Errorf("mcp: tool %q has invalid inputSchema: %w", tool.Name, err)
+		}
+		fn.Parameters = params
+	}
+
+	return openai.ChatCompletionFunctionTool(fn), nil
+}
+
+// LoadTools lists the tools of every server and converts them into OpenAI tool params,
+// ready to be placed on ChatCompletionNewParams.Tools before transformation.
+//
+// An error is returned if a server cannot be listed, a tool cannot be converted, or
+// two servers expose the same model-facing name.
+func LoadTools(ctx context.Context, servers ...Server) ([]openai.ChatCompletionToolUnionParam, error) {
+	var tools []openai.ChatCompletionToolUnionParam
+	seen := make(map[string]string)
+
+	for _, server := range servers {
+		listed, err := server.Client.ListTools(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("mcp: listing tools for server %q: %w", server.Prefix, err)
+		}
+
+		for _, tool := range listed {
+			name := server.ToolName(tool.Name)
+			if owner, dup := seen[name]; dup {
+				return nil, fmt.Errorf("mcp: tool name %q is exposed by both server %q and server %q", name, owner, server.Prefix)
+			}
+			seen[name] = server.Prefix
+
+			converted, err := ConvertTool(tool, name)
+			if err != nil {
+				return nil, err
+			}
+			tools = append(tools, converted)
+		}
+	}
+
+	return tools, nil
+}
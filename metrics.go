@@ -2,6 +2,13 @@ package tooladapter
 
 import "time"
 
+// MetricsSchemaVersion is the version of the metric event schema: the MetricEvent
+// names, the data types delivered for them and their JSON field names. Within a
+// major version of this module, events and fields are only ever added, never
+// renamed, retyped or removed, and MetricsSchemaVersion increases with each
+// addition so consumers can tell which fields to expect.
+const MetricsSchemaVersion = 4
+
 // MetricEvent represents the type of metric event being emitted.
 // Each event corresponds to a significant operation within the tool adapter.
 type MetricEvent string
@@ -16,6 +23,83 @@ const (
 	// This event indicates that the adapter has successfully extracted and converted
 	// function calls from LLM response text back into OpenAI-compatible tool calls.
 	MetricEventFunctionCallDetection MetricEvent = "function_call_detection"
+
+	// MetricEventArgumentViolation fires when tool call arguments break enum, range
+	// or length constraints of their schema under ArgumentViolationReport or
+	// ArgumentViolationError.
+	MetricEventArgumentViolation MetricEvent = "argument_violation"
+
+	// MetricEventToolSanitization fires when WithSanitizeToolDefinitions alters the
+	// text of tool definitions before they are injected into the prompt.
+	MetricEventToolSanitization MetricEvent = "tool_sanitization"
+
+	// MetricEventMessageTruncation fires when WithContextWindow drops messages from
+	// a request so it fits the model's context window.
+	MetricEventMessageTruncation MetricEvent = "message_truncation"
+
+	// MetricEventToolCallRateLimited fires when WithToolCallRateLimit suppresses
+	// tool calls beyond the per-response or per-conversation limit.
+	MetricEventToolCallRateLimited MetricEvent = "tool_call_rate_limited"
+
+	// MetricEventResponseParseSkipped fires when a non-streaming response is returned
+	// without tool call parsing because its content exceeds WithResponseParseMaxBytes.
+	MetricEventResponseParseSkipped MetricEvent = "response_parse_skipped"
+
+	// MetricEventRequestTransformed fires once for every request returned by
+	// TransformCompletionsRequest, including requests passed through unchanged.
+	MetricEventRequestTransformed MetricEvent = "request_transformed"
+
+	// MetricEventToolPromptRendered fires when the tool prompt injected into a
+	// request is rendered.
+	MetricEventToolPromptRendered MetricEvent = "tool_prompt_rendered"
+
+	// MetricEventToolCallDetected fires once for every tool call recognized in a
+	// response, streaming or not.
+	MetricEventToolCallDetected MetricEvent = "tool_call_detected"
+
+	// MetricEventStreamBufferFlushed fires when content a stream withheld as a
+	// possible tool call is released as regular text.
+	MetricEventStreamBufferFlushed MetricEvent = "stream_buffer_flushed"
+
+	// MetricEventPolicyLimitHit fires when a configured limit cuts tool call
+	// detection or the calls delivered short.
+	MetricEventPolicyLimitHit MetricEvent = "policy_limit_hit"
+
+	// MetricEventPostTransformReportDropped fires when a WithPostTransformHook
+	// report is dropped because the hook queue is full.
+	MetricEventPostTransformReportDropped MetricEvent = "post_transform_report_dropped"
+)
+
+// Limit values reported in PolicyLimitHitData.
+const (
+	PolicyLimitToolMaxCalls          = "tool_max_calls"
+	PolicyLimitToolCollectMaxBytes   = ParseDetailToolCollectMaxBytes
+	PolicyLimitStreamBufferLimit     = ParseDetailStreamBufferLimit
+	PolicyLimitResponseParseMaxBytes = ParseDetailResponseParseMaxBytes
+
+	PolicyLimitStreamMemoryLimit          = ParseDetailStreamMemoryLimit
+	PolicyLimitAggregateStreamMemoryLimit = ParseDetailAggregateStreamMemoryLimit
+)
+
+// Reason values reported in StreamBufferFlushedData.
+const (
+	// BufferFlushNoToolCall means the withheld content held no valid tool call.
+	BufferFlushNoToolCall = "no_tool_call"
+
+	// BufferFlushLimit means WithStreamingToolBufferSize stopped the search.
+	BufferFlushLimit = "limit"
+
+	// BufferFlushEndOfStream means StreamEOFFlushText released an incomplete tool
+	// call collection at the end of the stream.
+	BufferFlushEndOfStream = "end_of_stream"
+
+	// BufferFlushBudget means WithToolCollectTotalBudget ran out and the stream
+	// degraded to pass-through.
+	BufferFlushBudget = "budget"
+
+	// BufferFlushMemory means WithStreamMemoryLimit or WithAggregateStreamMemoryLimit
+	// was reached and the stream degraded to pass-through.
+	BufferFlushMemory = "memory"
 )
 
 // MetricEventData is implemented by all metric event data structures.
@@ -62,6 +146,25 @@ type ToolTransformationData struct {
 	// PromptLength is the length of the generated system prompt in characters
 	PromptLength int `json:"prompt_length"`
 
+	// InjectedTokens estimates the tokens of the injected prompt, including any
+	// injection markers, with the WithTokenCounter counter; 0 without one
+	InjectedTokens int `json:"injected_tokens,omitempty"`
+
+	// SchemaBytes is the size of the parameter schemas rendered into the prompt
+	SchemaBytes int `json:"schema_bytes,omitempty"`
+
+	// SchemaBytesSaved is the size of the schemas as supplied, encoded by
+	// json.Marshal, minus SchemaBytes; negative under SchemaPretty
+	SchemaBytesSaved int `json:"schema_bytes_saved,omitempty"`
+
+	// PromptCacheHit reports whether the tool prompt came from the WithPromptCache
+	// cache
+	PromptCacheHit bool `json:"prompt_cache_hit,omitempty"`
+
+	// TemplateVariant names the WithTemplateExperiments variant whose template
+	// rendered the prompt; empty without experiments
+	TemplateVariant string `json:"template_variant,omitempty"`
+
 	// Performance contains timing and resource metrics for this transformation
 	Performance PerformanceMetrics `json:"performance"`
 }
@@ -89,6 +192,14 @@ type FunctionCallDetectionData struct {
 	// Streaming indicates whether this detection occurred in streaming mode
 	Streaming bool `json:"streaming"`
 
+	// DuplicatesRemoved is the number of repeated calls collapsed by
+	// WithToolCallDeduplication
+	DuplicatesRemoved int `json:"duplicates_removed,omitempty"`
+
+	// ContentTokens estimates the tokens of the content the calls were parsed
+	// from with the WithTokenCounter counter; 0 without one
+	ContentTokens int `json:"content_tokens,omitempty"`
+
 	// Performance contains timing and resource metrics for this detection
 	Performance PerformanceMetrics `json:"performance"`
 }
@@ -96,3 +207,224 @@ type FunctionCallDetectionData struct {
 func (d FunctionCallDetectionData) EventType() MetricEvent {
 	return MetricEventFunctionCallDetection
 }
+
+// ArgumentViolationData lists the schema violations found in a batch of parsed tool
+// calls.
+type ArgumentViolationData struct {
+	// Violations describes each offending argument
+	Violations []ArgumentViolation `json:"violations"`
+}
+
+func (d ArgumentViolationData) EventType() MetricEvent {
+	return MetricEventArgumentViolation
+}
+
+// ToolSanitizationData reports the tool definition fields altered by
+// WithSanitizeToolDefinitions for a request.
+type ToolSanitizationData struct {
+	// Alterations describes each changed field with its original and sanitized text
+	Alterations []ToolDefinitionAlteration `json:"alterations"`
+}
+
+func (d ToolSanitizationData) EventType() MetricEvent {
+	return MetricEventToolSanitization
+}
+
+// ToolCallRateLimitData reports tool calls suppressed by WithToolCallRateLimit.
+type ToolCallRateLimitData struct {
+	// SuppressedCount is the number of tool calls that were not delivered
+	SuppressedCount int `json:"suppressed_count"`
+
+	// SuppressedNames lists the functions of the suppressed calls, in order
+	SuppressedNames []string `json:"suppressed_names"`
+
+	// MaxPerResponse is the configured per-response limit (0 means unlimited)
+	MaxPerResponse int `json:"max_per_response"`
+
+	// MaxPerConversation is the configured per-conversation limit (0 means unlimited)
+	MaxPerConversation int `json:"max_per_conversation"`
+
+	// ConversationToolCalls is the number of calls delivered in the conversation,
+	// or 0 when no Conversation is attached to the context
+	ConversationToolCalls int `json:"conversation_tool_calls"`
+
+	// Streaming indicates whether the calls were suppressed in a stream
+	Streaming bool `json:"streaming"`
+}
+
+func (d ToolCallRateLimitData) EventType() MetricEvent {
+	return MetricEventToolCallRateLimited
+}
+
+// MessageTruncationData reports messages dropped from a request by WithContextWindow.
+type MessageTruncationData struct {
+	// DroppedMessages is the number of messages removed
+	DroppedMessages int `json:"dropped_messages"`
+
+	// DroppedTokens estimates the tokens of the removed messages
+	DroppedTokens int `json:"dropped_tokens"`
+
+	// PromptTokens estimates the prompt tokens of the truncated request, including
+	// the injected prompt
+	PromptTokens int `json:"prompt_tokens"`
+
+	// ContextWindow is the configured context window in tokens
+	ContextWindow int `json:"context_window"`
+
+	// Strategy is the configured TruncationStrategy
+	Strategy TruncationStrategy `json:"strategy"`
+
+	// Fits reports whether the request fits the context window after truncation;
+	// system messages and the last message are never dropped
+	Fits bool `json:"fits"`
+}
+
+func (d MessageTruncationData) EventType() MetricEvent {
+	return MetricEventMessageTruncation
+}
+
+// ResponseParseSkippedData reports a choice whose content was not parsed for tool
+// calls because it exceeds WithResponseParseMaxBytes.
+type ResponseParseSkippedData struct {
+	// ChoiceIndex is the index of the skipped choice
+	ChoiceIndex int `json:"choice_index"`
+
+	// ContentLength is the content size in bytes
+	ContentLength int `json:"content_length"`
+
+	// MaxBytes is the configured limit
+	MaxBytes int `json:"max_bytes"`
+}
+
+func (d ResponseParseSkippedData) EventType() MetricEvent {
+	return MetricEventResponseParseSkipped
+}
+
+// RequestTransformedData summarizes a request returned by TransformCompletionsRequest.
+type RequestTransformedData struct {
+	// Model is the model named by the request
+	Model string `json:"model"`
+
+	// ToolCount is the number of tools in the incoming request
+	ToolCount int `json:"tool_count"`
+
+	// InjectedToolCount is the number of tools described in the injected prompt,
+	// after WithToolSelector
+	InjectedToolCount int `json:"injected_tool_count"`
+
+	// ToolResultCount is the number of tool results folded into the prompt
+	ToolResultCount int `json:"tool_result_count"`
+
+	// MessageCount is the number of messages in the transformed request
+	MessageCount int `json:"message_count"`
+
+	// Passthrough reports whether the request was returned without an injected
+	// prompt, because it carried no tools or tool results or the model supports
+	// tools natively
+	Passthrough bool `json:"passthrough"`
+
+	// Performance contains timing information for the transformation
+	Performance PerformanceMetrics `json:"performance"`
+}
+
+func (d RequestTransformedData) EventType() MetricEvent {
+	return MetricEventRequestTransformed
+}
+
+// ToolPromptRenderedData describes the tool prompt injected into a request.
+type ToolPromptRenderedData struct {
+	// ToolCount is the number of tools described in the prompt
+	ToolCount int `json:"tool_count"`
+
+	// ToolResultCount is the number of tool results included in the prompt
+	ToolResultCount int `json:"tool_result_count"`
+
+	// PromptLength is the size of the injected prompt in bytes, including any
+	// WithInjectionMarkers markers
+	PromptLength int `json:"prompt_length"`
+
+	// PromptTokens estimates the tokens of the injected prompt with the
+	// WithTokenCounter counter; 0 without one
+	PromptTokens int `json:"prompt_tokens"`
+
+	// CustomTemplate reports whether the prompt was rendered from a template other
+	// than the built-in one of the prompt language and style
+	CustomTemplate bool `json:"custom_template"`
+}
+
+func (d ToolPromptRenderedData) EventType() MetricEvent {
+	return MetricEventToolPromptRendered
+}
+
+// ToolCallDetectedData describes a single tool call recognized in a response.
+type ToolCallDetectedData struct {
+	// ToolName is the function name of the call
+	ToolName string `json:"tool_name"`
+
+	// CallIndex is the position of the call among those detected with it
+	CallIndex int `json:"call_index"`
+
+	// ChoiceIndex is the index of the choice the call was found in
+	ChoiceIndex int `json:"choice_index"`
+
+	// ArgumentsBytes is the size of the call's JSON arguments
+	ArgumentsBytes int `json:"arguments_bytes"`
+
+	// Streaming indicates whether the call was detected in a stream
+	Streaming bool `json:"streaming"`
+}
+
+func (d ToolCallDetectedData) EventType() MetricEvent {
+	return MetricEventToolCallDetected
+}
+
+// StreamBufferFlushedData reports withheld streaming content released as text.
+type StreamBufferFlushedData struct {
+	// Size is the number of bytes released
+	Size int `json:"size"`
+
+	// Reason is BufferFlushNoToolCall, BufferFlushLimit, BufferFlushEndOfStream,
+	// BufferFlushBudget or BufferFlushMemory
+	Reason string `json:"reason"`
+}
+
+func (d StreamBufferFlushedData) EventType() MetricEvent {
+	return MetricEventStreamBufferFlushed
+}
+
+// PolicyLimitHitData reports a configured limit that cut tool call detection or
+// the calls delivered short.
+type PolicyLimitHitData struct {
+	// Limit names the limit, using the PolicyLimit constants
+	Limit string `json:"limit"`
+
+	// Value is the count or size that reached the limit
+	Value int `json:"value"`
+
+	// Max is the configured limit
+	Max int `json:"max"`
+
+	// Streaming indicates whether the limit was hit in a stream
+	Streaming bool `json:"streaming"`
+}
+
+func (d PolicyLimitHitData) EventType() MetricEvent {
+	return MetricEventPolicyLimitHit
+}
+
+// PostTransformReportDroppedData reports a post-transform hook report dropped
+// because the hook queue was full.
+type PostTransformReportDroppedData struct {
+	// Model is the model of the dropped report
+	Model string `json:"model"`
+
+	// QueueSize is the configured queue size
+	QueueSize int `json:"queue_size"`
+
+	// Dropped is the number of reports the adapter has dropped so far
+	Dropped int64 `json:"dropped"`
+}
+
+func (d PostTransformReportDroppedData) EventType() MetricEvent {
+	return MetricEventPostTransformReportDropped
+}
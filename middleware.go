@@ -0,0 +1,114 @@
+package tooladapter
+
+import (
+	"context"
+
+	"github.com/openai/openai-go/v3"
+)
+
+// RequestTransform transforms a chat completion request, as
+// TransformCompletionsRequestWithContext does.
+type RequestTransform func(ctx context.Context, req openai.ChatCompletionNewParams) (openai.ChatCompletionNewParams, error)
+
+// ResponseTransform transforms a chat completion response, as
+// TransformCompletionsResponseWithContext does.
+type ResponseTransform func(ctx context.Context, resp openai.ChatCompletion) (openai.ChatCompletion, error)
+
+// StreamTransform wraps a completion stream, as TransformStreamingResponseWithContext
+// does.
+type StreamTransform func(ctx context.Context, stream ChatCompletionStreamInterface) *StreamAdapter
+
+// WithRequestMiddleware wraps TransformCompletionsRequestWithContext with middleware,
+// like http middleware wraps a handler, so cross-cutting concerns such as audit
+// logging, tenant tagging or experiment flags can run around the adapter without
+// changing it. The middleware receives the next transform in the chain and returns
+// a transform that may inspect or change the request and context before calling
+// next, inspect or change its result, or return without calling next at all.
+//
+// Each call adds a middleware; the first added is the outermost. Middleware runs once
+// per request, including every request of a batch, and is applied by this adapter
+// only: middleware in ModelProfile options is ignored. A nil middleware is ignored
+// with a warning.
+//
+// Default: none
+func WithRequestMiddleware(middleware func(next RequestTransform) RequestTransform) Option {
+	return func(a *Adapter) {
+		if middleware == nil {
+			a.logger.Warn("Nil middleware supplied to WithRequestMiddleware",
+				"implication", "The middleware is ignored",
+				"recommendation", "Supply a function returning the wrapped RequestTransform")
+			return
+		}
+		a.requestMiddleware = append(a.requestMiddleware, middleware)
+	}
+}
+
+// WithResponseMiddleware wraps TransformCompletionsResponseWithContext with
+// middleware. It is the response counterpart of WithRequestMiddleware and follows
+// the same ordering rules.
+//
+// Default: none
+func WithResponseMiddleware(middleware func(next ResponseTransform) ResponseTransform) Option {
+	return func(a *Adapter) {
+		if middleware == nil {
+			a.logger.Warn("Nil middleware supplied to WithResponseMiddleware",
+				"implication", "The middleware is ignored",
+				"recommendation", "Supply a function returning the wrapped ResponseTransform")
+			return
+		}
+		a.responseMiddleware = append(a.responseMiddleware, middleware)
+	}
+}
+
+// WithStreamMiddleware wraps TransformStreamingResponseWithContext with middleware.
+// It runs once when a stream is adapted, not per chunk; to observe chunks, wrap the
+// ChatCompletionStreamInterface passed to next. It is the stream counterpart of
+// WithRequestMiddleware and follows the same ordering rules. The transform must
+// return a non-nil StreamAdapter.
+//
+// Default: none
+func WithStreamMiddleware(middleware func(next StreamTransform) StreamTransform) Option {
+	return func(a *Adapter) {
+		if middleware == nil {
+			a.logger.Warn("Nil middleware supplied to WithStreamMiddleware",
+				"implication", "The middleware is ignored",
+				"recommendation", "Supply a function returning the wrapped StreamTransform")
+			return
+		}
+		a.streamMiddleware = append(a.streamMiddleware, middleware)
+	}
+}
+
+// withoutMiddleware keeps adapters built for a model profile from running the
+// middleware that already wraps the adapter delegating to them.
+func withoutMiddleware(a *Adapter) {
+	a.requestMiddleware = nil
+	a.responseMiddleware = nil
+	a.streamMiddleware = nil
+}
+
+// buildMiddlewareChains wraps the adapter's transforms in its middleware, the first
+// added outermost.
+func (a *Adapter) buildMiddlewareChains() {
+	if len(a.requestMiddleware) > 0 {
+		chain := RequestTransform(a.transformRequest)
+		for i := len(a.requestMiddleware) - 1; i >= 0; i-- {
+			chain = a.requestMiddleware[i](chain)
+		}
+		a.requestChain = chain
+	}
+	if len(a.responseMiddleware) > 0 {
+		chain := ResponseTransform(a.transformResponse)
+		for i := len(a.responseMiddleware) - 1; i >= 0; i-- {
+			chain = a.responseMiddleware[i](chain)
+		}
+		a.responseChain = chain
+	}
+	if len(a.streamMiddleware) > 0 {
+		chain := StreamTransform(a.transformStream)
+		for i := len(a.streamMiddleware) - 1; i >= 0; i-- {
+			chain = a.streamMiddleware[i](chain)
+		}
+		a.streamChain = chain
+	}
+}
@@ -0,0 +1,181 @@
+package tooladapter_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"log/slog"
+	"testing"
+
+	tooladapter "github.com/juburr/openai-tool-adapter/v3"
+	"github.com/juburr/openai-tool-adapter/v3/tooltest"
+	"github.com/openai/openai-go/v3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type tenantKey struct{}
+
+// traceRequests returns a request middleware that records its name in trace before
+// and after calling the rest of the chain.
+func traceRequests(name string, trace *[]string) func(tooladapter.RequestTransform) tooladapter.RequestTransform {
+	return func(next tooladapter.RequestTransform) tooladapter.RequestTransform {
+		return func(ctx context.Context, req openai.ChatCompletionNewParams) (openai.ChatCompletionNewParams, error) {
+			*trace = append(*trace, name+" before")
+			req, err := next(ctx, req)
+			*trace = append(*trace, name+" after")
+			return req, err
+		}
+	}
+}
+
+func TestWithRequestMiddleware(t *testing.T) {
+	req := tooltest.Request(tooltest.Tool("get_weather", "Get the weather"))
+
+	t.Run("FirstAddedIsOutermost", func(t *testing.T) {
+		var trace []string
+		adapter := tooladapter.New(
+			tooladapter.WithRequestMiddleware(traceRequests("outer", &trace)),
+			tooladapter.WithRequestMiddleware(traceRequests("inner", &trace)),
+		)
+
+		transformed, err := adapter.TransformCompletionsRequest(req)
+		require.NoError(t, err)
+		assert.Empty(t, transformed.Tools, "the wrapped transform still runs")
+		assert.Equal(t, []string{"outer before", "inner before", "inner after", "outer after"}, trace)
+	})
+
+	t.Run("ChangesRequestAndContext", func(t *testing.T) {
+		var tenant any
+		adapter := tooladapter.New(
+			tooladapter.WithRequestMiddleware(func(next tooladapter.RequestTransform) tooladapter.RequestTransform {
+				return func(ctx context.Context, req openai.ChatCompletionNewParams) (openai.ChatCompletionNewParams, error) {
+					req.User = openai.String("tenant-a")
+					return next(context.WithValue(ctx, tenantKey{}, "tenant-a"), req)
+				}
+			}),
+			tooladapter.WithToolSelector(func(ctx context.Context, _ []openai.ChatCompletionMessageParamUnion, tools []openai.ChatCompletionToolUnionParam) []openai.ChatCompletionToolUnionParam {
+				tenant = ctx.Value(tenantKey{})
+				return tools
+			}),
+		)
+
+		transformed, err := adapter.TransformCompletionsRequest(req)
+		require.NoError(t, err)
+		assert.Equal(t, "tenant-a", transformed.User.Value)
+		assert.Equal(t, "tenant-a", tenant)
+	})
+
+	t.Run("ShortCircuit", func(t *testing.T) {
+		errDenied := errors.New("tenant over quota")
+		adapter := tooladapter.New(
+			tooladapter.WithRequestMiddleware(func(tooladapter.RequestTransform) tooladapter.RequestTransform {
+				return func(context.Context, openai.ChatCompletionNewParams) (openai.ChatCompletionNewParams, error) {
+					return openai.ChatCompletionNewParams{}, errDenied
+				}
+			}),
+		)
+
+		_, err := adapter.TransformCompletionsRequest(req)
+		require.ErrorIs(t, err, errDenied)
+	})
+
+	t.Run("Batch", func(t *testing.T) {
+		var trace []string
+		adapter := tooladapter.New(
+			tooladapter.WithBatchConcurrency(1),
+			tooladapter.WithRequestMiddleware(traceRequests("audit", &trace)),
+		)
+
+		_, err := adapter.TransformCompletionsRequests([]openai.ChatCompletionNewParams{req, req})
+		require.NoError(t, err)
+		assert.Len(t, trace, 4)
+	})
+
+	t.Run("RunsOnceWithModelRules", func(t *testing.T) {
+		var trace []string
+		adapter := tooladapter.New(
+			tooladapter.WithRequestMiddleware(traceRequests("audit", &trace)),
+			tooladapter.WithModelRules(map[string]tooladapter.ModelProfile{
+				"google/gemma-*": {Options: []tooladapter.Option{tooladapter.WithLenientParsing(true)}},
+			}),
+		)
+
+		gemma := req
+		gemma.Model = "google/gemma-3-27b-it"
+		_, err := adapter.TransformCompletionsRequest(gemma)
+		require.NoError(t, err)
+		assert.Equal(t, []string{"audit before", "audit after"}, trace)
+	})
+
+	t.Run("Nil", func(t *testing.T) {
+		var logBuf bytes.Buffer
+		adapter := tooladapter.New(
+			tooladapter.WithLogger(slog.New(slog.NewTextHandler(&logBuf, nil))),
+			tooladapter.WithRequestMiddleware(nil),
+			tooladapter.WithResponseMiddleware(nil),
+			tooladapter.WithStreamMiddleware(nil),
+		)
+		assert.Contains(t, logBuf.String(), "Nil middleware supplied to WithRequestMiddleware")
+		assert.Contains(t, logBuf.String(), "Nil middleware supplied to WithResponseMiddleware")
+		assert.Contains(t, logBuf.String(), "Nil middleware supplied to WithStreamMiddleware")
+
+		_, err := adapter.TransformCompletionsRequest(req)
+		require.NoError(t, err)
+	})
+}
+
+func TestWithResponseMiddleware(t *testing.T) {
+	var calls []string
+	adapter := tooladapter.New(
+		tooladapter.WithResponseMiddleware(func(next tooladapter.ResponseTransform) tooladapter.ResponseTransform {
+			return func(ctx context.Context, resp openai.ChatCompletion) (openai.ChatCompletion, error) {
+				resp, err := next(ctx, resp)
+				for _, call := range resp.Choices[0].Message.ToolCalls {
+					calls = append(calls, call.Function.Name)
+				}
+				resp.ID = "audited"
+				return resp, err
+			}
+		}),
+	)
+
+	resp, err := adapter.TransformCompletionsResponse(tooltest.Completion(`{"name": "get_weather", "parameters": {"city": "Paris"}}`))
+	require.NoError(t, err)
+	assert.Equal(t, "audited", resp.ID)
+	assert.Equal(t, []string{"get_weather"}, calls)
+}
+
+// auditedStream counts the chunks read from the wrapped stream.
+type auditedStream struct {
+	tooladapter.ChatCompletionStreamInterface
+	chunks int
+}
+
+func (c *auditedStream) Next() bool {
+	if c.ChatCompletionStreamInterface.Next() {
+		c.chunks++
+		return true
+	}
+	return false
+}
+
+func TestWithStreamMiddleware(t *testing.T) {
+	var streams int
+	var counter *auditedStream
+	adapter := tooladapter.New(
+		tooladapter.WithStreamMiddleware(func(next tooladapter.StreamTransform) tooladapter.StreamTransform {
+			return func(ctx context.Context, stream tooladapter.ChatCompletionStreamInterface) *tooladapter.StreamAdapter {
+				streams++
+				counter = &auditedStream{ChatCompletionStreamInterface: stream}
+				return next(ctx, counter)
+			}
+		}),
+	)
+
+	result := tooltest.Drain(adapter.TransformStreamingResponse(tooltest.NewContentStream(`{"name": "get_weather", `, `"parameters": {}}`)))
+	require.NoError(t, result.Err)
+	assert.Equal(t, []string{"get_weather"}, result.ToolNames())
+	assert.Equal(t, 1, streams)
+	assert.Equal(t, 3, counter.chunks)
+}
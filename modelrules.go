@@ -0,0 +1,199 @@
+package tooladapter
+
+import (
+	"maps"
+	"path"
+	"sort"
+
+	"github.com/openai/openai-go/v3"
+)
+
+// ModelProfile configures how an adapter handles the models matched by a
+// WithModelRules pattern.
+type ModelProfile struct {
+	// Native passes requests and responses through untouched, as
+	// WithNativePassthrough does.
+	Native bool
+
+	// Options are applied after the adapter's own options for matching models, for
+	// example WithPromptLanguage or WithCustomPromptTemplate for the prompt,
+	// WithSystemMessageSupport, and WithLenientParsing or WithThinkBlocks for
+	// parsing.
+	Options []Option
+
+	// ExtraBody adds provider-specific fields to the JSON body of transformed
+	// requests for matching models, such as "grammar" for llama.cpp and TGI or
+	// "guided_choice" for vLLM. Fields set on the request with SetExtraFields take
+	// precedence. Values must marshal to JSON and come from trusted configuration,
+	// as they are sent as is.
+	ExtraBody map[string]any
+}
+
+// LlamaCppProfile returns the profile of models served by a llama.cpp server:
+// WithGBNFGrammar constrains their tool calls to valid JSON, followed by options.
+//
+//	tooladapter.WithModelRules(map[string]tooladapter.ModelProfile{
+//	    "local/*": tooladapter.LlamaCppProfile(tooladapter.WithStopTokenStripping()),
+//	})
+func LlamaCppProfile(options ...Option) ModelProfile {
+	return ModelProfile{Options: append([]Option{WithGBNFGrammar(true)}, options...)}
+}
+
+// modelRule is a compiled WithModelRules entry.
+type modelRule struct {
+	pattern string
+	adapter *Adapter
+}
+
+// WithModelRules selects per-model settings at transform time, so one adapter can
+// serve a gateway in front of several models. Keys are glob patterns in path.Match
+// syntax on the model name, such as "google/gemma-*", and each matching model is
+// handled by an adapter built from this adapter's options followed by the
+// profile's. When several patterns match, the longest one wins.
+//
+// Requests are matched on the request's model and responses on the model the
+// response reports. A StreamAdapter switches to the profile of the model named by
+// its first chunk before processing it, including WithStopTokenStripping,
+// WithThinkBlocks and WithStrictOpenAICompatibility; settings that act on upstream
+// before that chunk arrives, such as WithStreamReadAhead, the stream timeouts and
+// WithStreamRecorder, are taken from this adapter. Models matching no pattern use
+// this adapter's settings.
+//
+// Default: nil (all models share the adapter's settings)
+func WithModelRules(rules map[string]ModelProfile) Option {
+	return func(a *Adapter) {
+		profiles := make(map[string]ModelProfile, len(rules))
+		for pattern, profile := range rules {
+			if _, err := path.Match(pattern, ""); err != nil {
+				a.logger.Warn("Invalid model rule pattern",
+					"supplied_pattern", pattern,
+					"implication", "The rule is ignored",
+					"recommendation", "Use path.Match syntax, such as \"google/gemma-*\"")
+				continue
+			}
+			profiles[pattern] = profile
+		}
+		a.modelProfiles = profiles
+	}
+}
+
+// withoutModelRules keeps adapters built for a profile from building their own.
+func withoutModelRules(a *Adapter) {
+	a.modelProfiles = nil
+}
+
+// compileModelRules builds the adapter of every model profile from the options the
+// adapter was created with, longest pattern first.
+func (a *Adapter) compileModelRules(opts []Option) {
+	if len(a.modelProfiles) == 0 {
+		return
+	}
+	patterns := make([]string, 0, len(a.modelProfiles))
+	for pattern := range a.modelProfiles {
+		patterns = append(patterns, pattern)
+	}
+	sort.Slice(patterns, func(i, j int) bool {
+		if len(patterns[i]) != len(patterns[j]) {
+			return len(patterns[i]) > len(patterns[j])
+		}
+		return patterns[i] < patterns[j]
+	})
+
+	a.modelRules = make([]modelRule, 0, len(patterns))
+	for _, pattern := range patterns {
+		profile := a.modelProfiles[pattern]
+		profileOpts := append(opts[:len(opts):len(opts)], profile.Options...)
+		if profile.Native {
+			profileOpts = append(profileOpts, WithNativePassthrough(func(string) bool { return true }))
+		}
+		if len(profile.ExtraBody) > 0 {
+			profileOpts = append(profileOpts, withExtraBody(profile.ExtraBody))
+		}
+		profileOpts = append(profileOpts, withoutModelRules, withoutMiddleware)
+		a.modelRules = append(a.modelRules, modelRule{pattern: pattern, adapter: New(profileOpts...)})
+	}
+	a.log(LogCategoryRequest).Debug("Compiled model rules", "patterns", patterns)
+}
+
+// withExtraBody sets the ModelProfile.ExtraBody of a profile's adapter.
+func withExtraBody(fields map[string]any) Option {
+	return func(a *Adapter) {
+		a.extraBody = fields
+	}
+}
+
+// addExtraBody adds the ModelProfile.ExtraBody fields to a transformed request,
+// keeping any extra fields the request already has.
+func (a *Adapter) addExtraBody(req openai.ChatCompletionNewParams) openai.ChatCompletionNewParams {
+	if len(a.extraBody) == 0 {
+		return req
+	}
+	fields := make(map[string]any, len(a.extraBody))
+	maps.Copy(fields, a.extraBody)
+	maps.Copy(fields, req.ExtraFields())
+	req.SetExtraFields(fields)
+	a.log(LogCategoryRequest).Debug("Added extra body fields for model", "model", req.Model, "fields", len(a.extraBody))
+	return req
+}
+
+// forModel returns the adapter of the first rule matching model, or a itself.
+func (a *Adapter) forModel(model string) *Adapter {
+	if model == "" {
+		return a
+	}
+	for _, rule := range a.modelRules {
+		if matched, _ := path.Match(rule.pattern, model); matched {
+			return rule.adapter
+		}
+	}
+	return a
+}
+
+// selectFirstChunkModel reads the first chunk from upstream and switches the stream
+// to the adapter of the model it names, before the content processing of either
+// adapter sees the chunk.
+func (s *StreamAdapter) selectFirstChunkModel() {
+	s.modelSelected = true
+	if !s.upstream.Next() {
+		return
+	}
+	first := s.upstream.Current()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.selectModelAdapter(first.Model, &firstChunkStream{ChatCompletionStreamInterface: s.upstream, first: &first})
+}
+
+// selectModelAdapter switches the stream to the adapter of model, rebuilding its
+// content processing on top of upstream.
+// Callers must hold s.mu.
+func (s *StreamAdapter) selectModelAdapter(model string, upstream ChatCompletionStreamInterface) {
+	s.modelSelected = true
+	adapter := s.adapter.forModel(model)
+	if adapter != s.adapter {
+		adapter.log(LogCategoryStream).Debug("Selected model rule for stream", "model", model)
+	}
+	s.useAdapter(adapter, upstream)
+}
+
+// firstChunkStream returns a chunk already read from a stream before the rest of it.
+type firstChunkStream struct {
+	ChatCompletionStreamInterface
+	first   *openai.ChatCompletionChunk
+	current openai.ChatCompletionChunk
+}
+
+func (r *firstChunkStream) Next() bool {
+	if r.first != nil {
+		r.current, r.first = *r.first, nil
+		return true
+	}
+	if !r.ChatCompletionStreamInterface.Next() {
+		return false
+	}
+	r.current = r.ChatCompletionStreamInterface.Current()
+	return true
+}
+
+func (r *firstChunkStream) Current() openai.ChatCompletionChunk {
+	return r.current
+}
@@ -0,0 +1,152 @@
+package tooladapter_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"testing"
+
+	tooladapter "github.com/juburr/openai-tool-adapter/v3"
+	"github.com/juburr/openai-tool-adapter/v3/tooltest"
+	"github.com/openai/openai-go/v3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWithModelRules(t *testing.T) {
+	adapter := tooladapter.New(
+		tooladapter.WithToolPolicy(tooladapter.ToolCollectThenStop),
+		tooladapter.WithModelRules(map[string]tooladapter.ModelProfile{
+			"google/gemma-*":    {Options: []tooladapter.Option{tooladapter.WithSystemMessageSupport(false)}},
+			"google/gemma-3n-*": {Options: []tooladapter.Option{tooladapter.WithLenientParsing(true)}},
+			"qwen/*":            {Options: []tooladapter.Option{tooladapter.WithSystemMessageSupport(true)}},
+			"openai/*":          {Native: true},
+		}))
+	yamlCall := "name: get_weather\nparameters:\n  param1: Paris"
+
+	t.Run("SystemMessageSupport", func(t *testing.T) {
+		req := tooltest.Request(tooltest.Tool("get_weather", "Get the weather"))
+
+		req.Model = "qwen/qwen3-8b"
+		transformed, err := adapter.TransformCompletionsRequest(req)
+		require.NoError(t, err)
+		assert.NotNil(t, transformed.Messages[0].OfSystem, "qwen models get a system message")
+
+		req.Model = "google/gemma-3-27b-it"
+		transformed, err = adapter.TransformCompletionsRequest(req)
+		require.NoError(t, err)
+		assert.Nil(t, transformed.Messages[0].OfSystem)
+	})
+
+	t.Run("LongestPatternWins", func(t *testing.T) {
+		completion := tooltest.Completion(yamlCall)
+
+		completion.Model = "google/gemma-3n-e4b-it"
+		resp, err := adapter.TransformCompletionsResponse(completion)
+		require.NoError(t, err)
+		assert.Len(t, resp.Choices[0].Message.ToolCalls, 1, "lenient parsing applies to gemma-3n")
+
+		completion.Model = "google/gemma-3-27b-it"
+		resp, err = adapter.TransformCompletionsResponse(completion)
+		require.NoError(t, err)
+		assert.Empty(t, resp.Choices[0].Message.ToolCalls)
+	})
+
+	t.Run("Native", func(t *testing.T) {
+		req := tooltest.Request(tooltest.Tool("get_weather", "Get the weather"))
+		req.Model = "openai/gpt-4o"
+		transformed, err := adapter.TransformCompletionsRequest(req)
+		require.NoError(t, err)
+		assert.Len(t, transformed.Tools, 1)
+	})
+
+	t.Run("Stream", func(t *testing.T) {
+		stream := func(model string) *tooltest.MockStream {
+			first := tooltest.ContentChunk(yamlCall)
+			first.Model = model
+			return tooltest.NewMockStream(first, tooltest.FinishChunk("stop"))
+		}
+		result := tooltest.Drain(adapter.TransformStreamingResponse(stream("google/gemma-3n-e4b-it")))
+		require.NoError(t, result.Err)
+		assert.Equal(t, []string{"get_weather"}, result.ToolNames())
+
+		result = tooltest.Drain(adapter.TransformStreamingResponse(stream("mistral/small")))
+		assert.Empty(t, result.ToolCalls)
+		assert.Equal(t, yamlCall, result.Content)
+	})
+
+	t.Run("StreamContentProcessing", func(t *testing.T) {
+		adapter := tooladapter.New(tooladapter.WithModelRules(map[string]tooladapter.ModelProfile{
+			"local/*": tooladapter.LlamaCppProfile(
+				tooladapter.WithStopTokenStripping(),
+				tooladapter.WithThinkBlocks(tooladapter.ThinkBlocksStrip)),
+		}))
+		stream := func(model string) *tooltest.MockStream {
+			first := tooltest.ContentChunk("<think>Plan</think>Hello")
+			first.Model = model
+			return tooltest.NewMockStream(first, tooltest.ContentChunk(" there<|eot_id|>"), tooltest.FinishChunk("stop"))
+		}
+
+		result := tooltest.Drain(adapter.TransformStreamingResponse(stream("local/llama-3.1-8b")))
+		require.NoError(t, result.Err)
+		assert.Equal(t, "Hello there", result.Content, "the profile processes the first chunk too")
+
+		result = tooltest.Drain(adapter.TransformStreamingResponse(stream("mistral/small")))
+		require.NoError(t, result.Err)
+		assert.Equal(t, "<think>Plan</think>Hello there<|eot_id|>", result.Content)
+	})
+
+	t.Run("InvalidPattern", func(t *testing.T) {
+		var logBuf bytes.Buffer
+		tooladapter.New(
+			tooladapter.WithLogger(slog.New(slog.NewTextHandler(&logBuf, nil))),
+			tooladapter.WithModelRules(map[string]tooladapter.ModelProfile{"[": {}}))
+		assert.Contains(t, logBuf.String(), "Invalid model rule pattern")
+	})
+}
+
+func TestModelProfileExtraBody(t *testing.T) {
+	adapter := tooladapter.New(tooladapter.WithModelRules(map[string]tooladapter.ModelProfile{
+		"llama.cpp/*": {ExtraBody: map[string]any{"grammar": `root ::= "yes" | "no"`, "cache_prompt": true}},
+		"vllm/*":      {Native: true, ExtraBody: map[string]any{"guided_choice": []string{"yes", "no"}}},
+	}))
+	body := func(req openai.ChatCompletionNewParams) map[string]any {
+		transformed, err := adapter.TransformCompletionsRequest(req)
+		require.NoError(t, err)
+		encoded, err := json.Marshal(transformed)
+		require.NoError(t, err)
+		var fields map[string]any
+		require.NoError(t, json.Unmarshal(encoded, &fields))
+		return fields
+	}
+
+	t.Run("Fields", func(t *testing.T) {
+		req := tooltest.Request(tooltest.Tool("get_weather", "Get the weather"))
+		req.Model = "llama.cpp/qwen3-8b"
+		fields := body(req)
+		assert.Equal(t, `root ::= "yes" | "no"`, fields["grammar"])
+		assert.Equal(t, true, fields["cache_prompt"])
+		assert.Empty(t, req.ExtraFields(), "the input is not modified")
+	})
+
+	t.Run("NativePassthrough", func(t *testing.T) {
+		req := tooltest.Request()
+		req.Model = "vllm/llama-3.1-8b"
+		assert.Equal(t, []any{"yes", "no"}, body(req)["guided_choice"])
+	})
+
+	t.Run("RequestFieldsWin", func(t *testing.T) {
+		req := tooltest.Request()
+		req.Model = "llama.cpp/qwen3-8b"
+		req.SetExtraFields(map[string]any{"grammar": "root ::= \"ok\""})
+		fields := body(req)
+		assert.Equal(t, `root ::= "ok"`, fields["grammar"])
+		assert.Equal(t, true, fields["cache_prompt"])
+	})
+
+	t.Run("OtherModels", func(t *testing.T) {
+		req := tooltest.Request()
+		req.Model = "openai/gpt-4o"
+		assert.NotContains(t, body(req), "grammar")
+	})
+}
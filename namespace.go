@@ -0,0 +1,83 @@
+package tooladapter
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/openai/openai-go/v3"
+)
+
+// collisionSuffixSeparator separates the original name from the duplicate counter
+// when ToolCollisionRename is in effect.
+const collisionSuffixSeparator = "__"
+
+// resolveToolNames applies the configured namespace to every function tool and
+// detects name collisions. The caller's tools are never modified; a new slice is
+// returned whenever a name changes.
+func (a *Adapter) resolveToolNames(tools []openai.ChatCompletionToolUnionParam) ([]openai.ChatCompletionToolUnionParam, error) {
+	if len(tools) == 0 {
+		return tools, nil
+	}
+
+	resolved := tools
+	copied := false
+	seen := make(map[string][]int, len(tools))
+
+	for i, tool := range tools {
+		function := tool.GetFunction()
+		if function == nil {
+			continue
+		}
+
+		name := function.Name
+		if a.toolNamespace != "" {
+			name = a.toolNamespace + "." + name
+			if err := ValidateFunctionName(name); err != nil {
+				return nil, fmt.Errorf("failed to apply tool namespace %q to %q: %w", a.toolNamespace, function.Name, err)
+			}
+		}
+
+		if previous := seen[name]; len(previous) > 0 {
+			if a.toolCollisionPolicy != ToolCollisionRename {
+				return nil, &ToolNameCollisionError{Name: name, Indices: append(previous, i)}
+			}
+			renamed := name + collisionSuffixSeparator + strconv.Itoa(len(previous)+1)
+			a.log(LogCategoryRequest).Warn("Renamed colliding tool definition",
+				"original_name", name,
+				"renamed_to", renamed,
+				"tool_index", i)
+			seen[name] = append(previous, i)
+			name = renamed
+		} else {
+			seen[name] = []int{i}
+		}
+
+		if name == function.Name {
+			continue
+		}
+
+		// Copy on first rename so the caller's slice and tool structs stay untouched
+		if !copied {
+			resolved = make([]openai.ChatCompletionToolUnionParam, len(tools))
+			copy(resolved, tools)
+			copied = true
+		}
+		renamedTool := *tools[i].OfFunction
+		renamedTool.Function.Name = name
+		resolved[i] = openai.ChatCompletionToolUnionParam{OfFunction: &renamedTool}
+	}
+
+	return resolved, nil
+}
+
+// restoreToolName removes the namespace resolveToolNames added from a function name
+// parsed from a response. Duplicates renamed by ToolCollisionRename keep their
+// suffix: their original name is shared with the first definition, so only the
+// suffix tells the caller which definition the model called.
+func (a *Adapter) restoreToolName(name string) string {
+	if a.toolNamespace != "" {
+		name = strings.TrimPrefix(name, a.toolNamespace+".")
+	}
+	return name
+}
@@ -0,0 +1,166 @@
+package tooladapter_test
+
+import (
+	"bytes"
+	"log/slog"
+	"testing"
+
+	tooladapter "github.com/juburr/openai-tool-adapter/v3"
+	"github.com/openai/openai-go/v3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestToolNamespace(t *testing.T) {
+	adapter := tooladapter.New(tooladapter.WithToolNamespace("weather"))
+
+	t.Run("PrefixesToolNamesInPrompt", func(t *testing.T) {
+		tools := []openai.ChatCompletionToolUnionParam{
+			createMockTool("get_forecast", "Get the forecast"),
+		}
+		req, err := adapter.TransformCompletionsRequest(createMockRequest(tools))
+		require.NoError(t, err)
+
+		prompt := req.Messages[0].OfUser.Content.OfString.Value
+		assert.Contains(t, prompt, "- weather.get_forecast:")
+
+		// The caller's tool definitions must not be modified
+		assert.Equal(t, "get_forecast", tools[0].GetFunction().Name)
+	})
+
+	t.Run("StripsPrefixFromResponse", func(t *testing.T) {
+		resp, err := adapter.TransformCompletionsResponse(createMockCompletion(
+			`[{"name": "weather.get_forecast", "parameters": {"city": "Oslo"}}]`))
+		require.NoError(t, err)
+
+		require.Len(t, resp.Choices[0].Message.ToolCalls, 1)
+		assert.Equal(t, "get_forecast", resp.Choices[0].Message.ToolCalls[0].Function.Name)
+	})
+
+	t.Run("StripsPrefixFromStream", func(t *testing.T) {
+		stream := adapter.TransformStreamingResponse(NewMockStream([]openai.ChatCompletionChunk{
+			createStreamChunk(`[{"name": "weather.get_forecast", "parameters": null}]`),
+		}))
+
+		var names []string
+		for stream.Next() {
+			for _, tc := range stream.Current().Choices[0].Delta.ToolCalls {
+				names = append(names, tc.Function.Name)
+			}
+		}
+		require.NoError(t, stream.Err())
+		assert.Equal(t, []string{"get_forecast"}, names)
+	})
+
+	t.Run("LeavesUnprefixedNamesAlone", func(t *testing.T) {
+		resp, err := adapter.TransformCompletionsResponse(createMockCompletion(
+			`{"name": "get_forecast", "parameters": null}`))
+		require.NoError(t, err)
+
+		require.Len(t, resp.Choices[0].Message.ToolCalls, 1)
+		assert.Equal(t, "get_forecast", resp.Choices[0].Message.ToolCalls[0].Function.Name)
+	})
+
+	t.Run("RejectsAlreadyPrefixedTools", func(t *testing.T) {
+		_, err := adapter.TransformCompletionsRequest(createMockRequest([]openai.ChatCompletionToolUnionParam{
+			createMockTool("fs.read_file", ""),
+		}))
+		assert.ErrorContains(t, err, "namespace")
+	})
+
+	t.Run("InvalidPrefixIgnored", func(t *testing.T) {
+		var logs bytes.Buffer
+		logger := slog.New(slog.NewTextHandler(&logs, nil))
+		invalid := tooladapter.New(tooladapter.WithLogger(logger), tooladapter.WithToolNamespace("bad-prefix"))
+		assert.Contains(t, logs.String(), "Invalid tool namespace ignored")
+
+		req, err := invalid.TransformCompletionsRequest(createMockRequest([]openai.ChatCompletionToolUnionParam{
+			createMockTool("get_forecast", ""),
+		}))
+		require.NoError(t, err)
+		assert.NotContains(t, req.Messages[0].OfUser.Content.OfString.Value, "bad-prefix")
+	})
+}
+
+func TestToolNameCollision(t *testing.T) {
+	tools := []openai.ChatCompletionToolUnionParam{
+		createMockTool("search", "Search the web"),
+		createMockTool("lookup", "Look up a record"),
+		createMockTool("search", "Search the docs"),
+	}
+
+	t.Run("DefaultReturnsTypedError", func(t *testing.T) {
+		adapter := tooladapter.New()
+		_, err := adapter.TransformCompletionsRequest(createMockRequest(tools))
+
+		var collision *tooladapter.ToolNameCollisionError
+		require.ErrorAs(t, err, &collision)
+		assert.Equal(t, "search", collision.Name)
+		assert.Equal(t, []int{0, 2}, collision.Indices)
+		assert.Contains(t, err.Error(), "[0, 2]")
+	})
+
+	t.Run("CollisionAfterNamespace", func(t *testing.T) {
+		adapter := tooladapter.New(tooladapter.WithToolNamespace("docs"))
+		_, err := adapter.TransformCompletionsRequest(createMockRequest(tools))
+
+		var collision *tooladapter.ToolNameCollisionError
+		require.ErrorAs(t, err, &collision)
+		assert.Equal(t, "docs.search", collision.Name)
+	})
+
+	t.Run("RenameKeepsDuplicatesDistinct", func(t *testing.T) {
+		adapter := tooladapter.New(
+			tooladapter.WithToolCollisionPolicy(tooladapter.ToolCollisionRename),
+			tooladapter.WithToolPolicy(tooladapter.ToolDrainAll),
+		)
+		req, err := adapter.TransformCompletionsRequest(createMockRequest(tools))
+		require.NoError(t, err)
+
+		prompt := req.Messages[0].OfUser.Content.OfString.Value
+		assert.Contains(t, prompt, "- search: Search the web")
+		assert.Contains(t, prompt, "- search__2: Search the docs")
+		assert.Equal(t, "search", tools[2].GetFunction().Name, "caller tools must not be modified")
+
+		resp, err := adapter.TransformCompletionsResponse(createMockCompletion(
+			`[{"name": "search__2", "parameters": null}, {"name": "lookup", "parameters": null}]`))
+		require.NoError(t, err)
+
+		toolCalls := resp.Choices[0].Message.ToolCalls
+		require.Len(t, toolCalls, 2)
+		assert.Equal(t, "search__2", toolCalls[0].Function.Name, "the call must identify the second definition")
+		assert.Equal(t, "lookup", toolCalls[1].Function.Name)
+	})
+
+	t.Run("RenameWithNamespace", func(t *testing.T) {
+		adapter := tooladapter.New(
+			tooladapter.WithToolNamespace("docs"),
+			tooladapter.WithToolCollisionPolicy(tooladapter.ToolCollisionRename),
+			tooladapter.WithToolPolicy(tooladapter.ToolDrainAll),
+		)
+		resp, err := adapter.TransformCompletionsResponse(createMockCompletion(
+			`[{"name": "docs.search__2", "parameters": null}, {"name": "docs.search", "parameters": null}]`))
+		require.NoError(t, err)
+
+		toolCalls := resp.Choices[0].Message.ToolCalls
+		require.Len(t, toolCalls, 2)
+		assert.Equal(t, "search__2", toolCalls[0].Function.Name)
+		assert.Equal(t, "search", toolCalls[1].Function.Name)
+	})
+
+	t.Run("NamesEndingInCounterAreKept", func(t *testing.T) {
+		adapter := tooladapter.New(tooladapter.WithToolCollisionPolicy(tooladapter.ToolCollisionRename))
+		resp, err := adapter.TransformCompletionsResponse(createMockCompletion(
+			`{"name": "export__2024", "parameters": null}`))
+		require.NoError(t, err)
+
+		require.Len(t, resp.Choices[0].Message.ToolCalls, 1)
+		assert.Equal(t, "export__2024", resp.Choices[0].Message.ToolCalls[0].Function.Name)
+	})
+
+	t.Run("PolicyString", func(t *testing.T) {
+		assert.Equal(t, "ToolCollisionError", tooladapter.ToolCollisionError.String())
+		assert.Equal(t, "ToolCollisionRename", tooladapter.ToolCollisionRename.String())
+		assert.Equal(t, "ToolCollisionPolicy(9)", tooladapter.ToolCollisionPolicy(9).String())
+	})
+}
@@ -0,0 +1,69 @@
+package tooladapter_test
+
+import (
+	"strings"
+	"testing"
+
+	tooladapter "github.com/juburr/openai-tool-adapter/v3"
+	"github.com/juburr/openai-tool-adapter/v3/tooltest"
+	"github.com/openai/openai-go/v3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func nativeGPT(model string) bool {
+	return strings.HasPrefix(model, "gpt-")
+}
+
+func TestWithNativePassthrough(t *testing.T) {
+	adapter := tooladapter.New(tooladapter.WithNativePassthrough(nativeGPT))
+	toolCall := `{"name": "get_weather", "parameters": {"param1": "Paris"}}`
+
+	t.Run("Request", func(t *testing.T) {
+		req := tooltest.Request(tooltest.Tool("get_weather", "Get the weather"))
+		req.Model = "gpt-4o"
+		req.ToolChoice = openai.ChatCompletionToolChoiceOptionUnionParam{OfAuto: openai.String("required")}
+
+		native, err := adapter.TransformCompletionsRequest(req)
+		require.NoError(t, err)
+		assert.Len(t, native.Tools, 1)
+		assert.Equal(t, req.ToolChoice, native.ToolChoice)
+		assert.Equal(t, len(req.Messages), len(native.Messages))
+
+		req.Model = "gemma-3"
+		adapted, err := adapter.TransformCompletionsRequest(req)
+		require.NoError(t, err)
+		assert.Empty(t, adapted.Tools)
+	})
+
+	t.Run("Response", func(t *testing.T) {
+		completion := tooltest.Completion(toolCall)
+		completion.Model = "gpt-4o-2024-08-06"
+		resp, err := adapter.TransformCompletionsResponse(completion)
+		require.NoError(t, err)
+		assert.Empty(t, resp.Choices[0].Message.ToolCalls)
+		assert.Equal(t, toolCall, resp.Choices[0].Message.Content)
+
+		completion.Model = "gemma-3"
+		resp, err = adapter.TransformCompletionsResponse(completion)
+		require.NoError(t, err)
+		assert.Len(t, resp.Choices[0].Message.ToolCalls, 1)
+	})
+
+	t.Run("Stream", func(t *testing.T) {
+		stream := func(model string) *tooltest.MockStream {
+			first := tooltest.ContentChunk(toolCall)
+			first.Model = model
+			return tooltest.NewMockStream(first, tooltest.FinishChunk("stop"))
+		}
+
+		result := tooltest.Drain(adapter.TransformStreamingResponse(stream("gpt-4o")))
+		require.NoError(t, result.Err)
+		assert.Empty(t, result.ToolCalls)
+		assert.Equal(t, toolCall, result.Content)
+		assert.Equal(t, "stop", result.FinishReason)
+
+		result = tooltest.Drain(adapter.TransformStreamingResponse(stream("gemma-3")))
+		assert.Equal(t, []string{"get_weather"}, result.ToolNames())
+	})
+}
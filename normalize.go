@@ -0,0 +1,41 @@
+package tooladapter
+
+import (
+	"strings"
+	"unicode/utf8"
+)
+
+// toolTextReplacer removes the invisible characters and folds the full-width
+// brackets that some quantized models emit around tool call JSON.
+var toolTextReplacer = strings.NewReplacer(
+	"\ufeff", "", // byte order mark
+	"\u200b", "", // zero width space
+	"\u200c", "", // zero width non-joiner
+	"\u200d", "", // zero width joiner
+	"\u2060", "", // word joiner
+	"\uff5b", "{", // full-width brackets
+	"\uff5d", "}",
+	"\uff3b", "[",
+	"\uff3d", "]",
+)
+
+// normalizeToolText returns content with byte order marks and zero-width characters
+// removed and full-width brackets replaced by their ASCII forms. It reports whether
+// anything changed; ASCII content is returned as is without allocating.
+//
+// The replacement applies to the whole content, including string values, so
+// candidates found in the result are only tried after those of the original.
+func normalizeToolText(content string) (string, bool) {
+	ascii := true
+	for i := 0; i < len(content); i++ {
+		if content[i] >= utf8.RuneSelf {
+			ascii = false
+			break
+		}
+	}
+	if ascii {
+		return content, false
+	}
+	normalized := toolTextReplacer.Replace(content)
+	return normalized, normalized != content
+}
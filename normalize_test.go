@@ -0,0 +1,82 @@
+package tooladapter_test
+
+import (
+	"testing"
+
+	tooladapter "github.com/juburr/openai-tool-adapter/v3"
+	"github.com/juburr/openai-tool-adapter/v3/tooltest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// toolTextVariants are tool calls as written by quantized models that decorate the
+// JSON with unusual fences, full-width brackets or invisible characters.
+var toolTextVariants = []struct {
+	name    string
+	content string
+}{
+	{"UppercaseFence", "```JSON\n" + weatherJSON + "\n```"},
+	{"JSON5Fence", "```json5\n" + weatherJSON + "\n```"},
+	{"TildeFence", "~~~json\n" + weatherJSON + "\n~~~"},
+	{"FullWidthBrackets", `｛"name": "get_weather", "parameters": ｛"city": "Paris"｝｝`},
+	{"FullWidthArray", `［｛"name": "get_weather", "parameters": ｛"city": "Paris"｝｝］`},
+	{"ByteOrderMark", "\ufeff" + weatherJSON},
+	{"ZeroWidthSpaces", "\u200b" + `{"name"` + "\u200b" + `: "get_weather", "parameters": {"city": "Paris"}}` + "\u2060"},
+	{"ZeroWidthInFence", "\ufeff```json\n\u200d" + weatherJSON + "\u200c\n```"},
+}
+
+func TestToolTextVariants_NonStreaming(t *testing.T) {
+	for _, tc := range toolTextVariants {
+		t.Run(tc.name, func(t *testing.T) {
+			resp, err := tooladapter.New().TransformCompletionsResponse(tooltest.Completion(tc.content))
+			require.NoError(t, err)
+			require.Len(t, resp.Choices[0].Message.ToolCalls, 1)
+			call := resp.Choices[0].Message.ToolCalls[0].Function
+			assert.Equal(t, "get_weather", call.Name)
+			assert.JSONEq(t, `{"city": "Paris"}`, call.Arguments)
+		})
+	}
+}
+
+func TestToolTextVariants_Streaming(t *testing.T) {
+	for _, tc := range toolTextVariants {
+		t.Run(tc.name, func(t *testing.T) {
+			stream := tooladapter.New().TransformStreamingResponse(tooltest.NewContentStream(tc.content))
+			result := tooltest.Drain(stream)
+			require.NoError(t, result.Err)
+			assert.Equal(t, []string{"get_weather"}, result.ToolNames())
+			assert.Empty(t, result.Content)
+		})
+	}
+}
+
+func TestToolTextVariants_OriginalTakesPrecedence(t *testing.T) {
+	// The argument's own full-width brackets and zero-width joiner survive because
+	// the call parses without normalization
+	content := `{"name": "echo", "parameters": {"text": "｛a｝` + "\u200d" + `"}}`
+	var events []tooladapter.ParseEvent
+	adapter := tooladapter.New(tooladapter.WithParseEventHook(func(e tooladapter.ParseEvent) {
+		events = append(events, e)
+	}))
+
+	resp, err := adapter.TransformCompletionsResponse(tooltest.Completion(content))
+	require.NoError(t, err)
+	require.Len(t, resp.Choices[0].Message.ToolCalls, 1)
+	assert.JSONEq(t, `{"text": "｛a｝`+"\u200d"+`"}`, resp.Choices[0].Message.ToolCalls[0].Function.Arguments)
+	for _, e := range events {
+		assert.NotEqual(t, tooladapter.ParseEventRepairApplied, e.Type)
+	}
+}
+
+func TestToolTextVariants_NormalizedEvent(t *testing.T) {
+	var details []string
+	adapter := tooladapter.New(tooladapter.WithParseEventHook(func(e tooladapter.ParseEvent) {
+		if e.Type == tooladapter.ParseEventRepairApplied {
+			details = append(details, e.Detail)
+		}
+	}))
+
+	_, err := adapter.TransformCompletionsResponse(tooltest.Completion("\ufeff" + `｛"name": "get_weather"｝`))
+	require.NoError(t, err)
+	assert.Equal(t, []string{tooladapter.ParseDetailNormalized}, details)
+}
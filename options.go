@@ -1,10 +1,13 @@
 package tooladapter
 
 import (
-	"errors"
+	"context"
+	"encoding/json"
 	"fmt"
 	"io"
 	"log/slog"
+	"math"
+	"slices"
 	"strings"
 	"time"
 )
@@ -29,6 +32,14 @@ const (
 	// ToolAllowMixed streams both text content and tools together without suppression
 	// (stream text and tools together).
 	ToolAllowMixed
+
+	// ToolEmitAndContinue emits each tool call as soon as its JSON is complete and
+	// keeps streaming: content before and after the call, and later calls, are
+	// emitted too. Tool call chunks carry no finish reason; the stream's final "stop"
+	// is reported as "tool_calls" instead. Suited to speculative execution, where a
+	// UI starts running a tool while the model keeps talking. Non-streaming
+	// responses are handled as with ToolAllowMixed.
+	ToolEmitAndContinue
 )
 
 // String returns a human-readable string representation of the ToolPolicy.
@@ -42,11 +53,135 @@ func (tp ToolPolicy) String() string {
 		return "ToolDrainAll"
 	case ToolAllowMixed:
 		return "ToolAllowMixed"
+	case ToolEmitAndContinue:
+		return "ToolEmitAndContinue"
 	default:
 		return fmt.Sprintf("ToolPolicy(%d)", int(tp))
 	}
 }
 
+// ToolCollisionPolicy controls what happens when two tools in a request resolve
+// to the same function name.
+type ToolCollisionPolicy int
+
+const (
+	// ToolCollisionError rejects the request with a *ToolNameCollisionError (default).
+	ToolCollisionError ToolCollisionPolicy = iota
+
+	// ToolCollisionRename renames later duplicates to "name__2", "name__3", etc. before
+	// injection. Tool calls parsed from responses keep the suffix, so callers can tell
+	// which of the definitions sharing a name the model called.
+	ToolCollisionRename
+)
+
+// String returns a human-readable string representation of the ToolCollisionPolicy.
+func (p ToolCollisionPolicy) String() string {
+	switch p {
+	case ToolCollisionError:
+		return "ToolCollisionError"
+	case ToolCollisionRename:
+		return "ToolCollisionRename"
+	default:
+		return fmt.Sprintf("ToolCollisionPolicy(%d)", int(p))
+	}
+}
+
+// UnsupportedToolPolicy controls what happens when a request declares a tool the
+// prompt cannot describe, such as a custom tool, whose input is free-form text
+// rather than JSON arguments. Only function tools are injected.
+type UnsupportedToolPolicy int
+
+const (
+	// UnsupportedToolSkip leaves the tool out of the prompt and logs a warning
+	// (default).
+	UnsupportedToolSkip UnsupportedToolPolicy = iota
+
+	// UnsupportedToolError rejects the request with an *UnsupportedToolTypeError.
+	UnsupportedToolError
+)
+
+// String returns a human-readable string representation of the UnsupportedToolPolicy.
+func (p UnsupportedToolPolicy) String() string {
+	switch p {
+	case UnsupportedToolSkip:
+		return "UnsupportedToolSkip"
+	case UnsupportedToolError:
+		return "UnsupportedToolError"
+	default:
+		return fmt.Sprintf("UnsupportedToolPolicy(%d)", int(p))
+	}
+}
+
+// UnknownToolPolicy controls what happens when a parsed tool call names a function
+// outside the list configured with WithAllowedToolNames.
+type UnknownToolPolicy int
+
+const (
+	// UnknownToolDrop drops the unknown call and keeps any others (default).
+	UnknownToolDrop UnknownToolPolicy = iota
+
+	// UnknownToolAsContent discards all tool calls in the response and returns the
+	// model output as regular content.
+	UnknownToolAsContent
+
+	// UnknownToolError fails the transformation with an *UnknownToolCallError.
+	// In streaming mode the error is reported by the stream's Err method.
+	UnknownToolError
+
+	// UnknownToolCorrect renames the call to the most similar allowed tool name when
+	// the similarity reaches the configured threshold; otherwise the call is dropped.
+	UnknownToolCorrect
+)
+
+// String returns a human-readable string representation of the UnknownToolPolicy.
+func (p UnknownToolPolicy) String() string {
+	switch p {
+	case UnknownToolDrop:
+		return "UnknownToolDrop"
+	case UnknownToolAsContent:
+		return "UnknownToolAsContent"
+	case UnknownToolError:
+		return "UnknownToolError"
+	case UnknownToolCorrect:
+		return "UnknownToolCorrect"
+	default:
+		return fmt.Sprintf("UnknownToolPolicy(%d)", int(p))
+	}
+}
+
+// ArgumentViolationPolicy controls what happens when tool call arguments break the
+// enum, minimum/maximum or minLength/maxLength constraints of the tool's schema.
+type ArgumentViolationPolicy int
+
+const (
+	// ArgumentViolationIgnore forwards the arguments unchecked (default).
+	ArgumentViolationIgnore ArgumentViolationPolicy = iota
+
+	// ArgumentViolationReport forwards the arguments, logs a warning and emits an
+	// ArgumentViolationData metric for each response with violations.
+	ArgumentViolationReport
+
+	// ArgumentViolationError reports like ArgumentViolationReport, then fails the
+	// transformation with a *ToolArgumentError. CompleteWithRetry re-asks the
+	// model with the violation messages. In streaming mode the error is reported by
+	// the stream's Err method.
+	ArgumentViolationError
+)
+
+// String returns a human-readable string representation of the ArgumentViolationPolicy.
+func (p ArgumentViolationPolicy) String() string {
+	switch p {
+	case ArgumentViolationIgnore:
+		return "ArgumentViolationIgnore"
+	case ArgumentViolationReport:
+		return "ArgumentViolationReport"
+	case ArgumentViolationError:
+		return "ArgumentViolationError"
+	default:
+		return fmt.Sprintf("ArgumentViolationPolicy(%d)", int(p))
+	}
+}
+
 const (
 	// DefaultPromptTemplate provides a robust, concise template that works across LLM families.
 	// It emphasizes immediate, JSON-only tool calls when appropriate, and natural language otherwise.
@@ -64,6 +199,22 @@ Formatting requirements:
 
 Decision policy:
 - Use tools when they are required to answer correctly or efficiently; otherwise reply in natural language without calling any tools.`
+
+	// SpecPromptTemplate is the template of PromptStyleSpec. It presents the tools
+	// as a JSON API specification and spells out the grammar of the output.
+	SpecPromptTemplate = `System/tooling instructions:
+
+The JSON array below is the API specification of the functions you can call. Treat it as a contract: call only these functions, use their names exactly, and pass parameters that satisfy each "parameters" JSON schema.
+
+Tools:
+%s
+
+Output grammar:
+  response := calls | text
+  calls    := "[" call ("," call)* "]"
+  call     := {"name": <function name>, "parameters": <object matching its schema> | null}
+
+Respond ONLY with a JSON array of calls when a function is needed: start at the first token, use no code fences, and write nothing before or after the array. Put every call in that one array. When no function is needed, respond with text and no JSON.`
 )
 
 // Option is a function that configures the Adapter.
@@ -88,7 +239,7 @@ func WithCustomPromptTemplate(template string) Option {
 
 		// Validate that the template has exactly one %s placeholder
 		// This prevents runtime errors when formatting the prompt
-		if err := validatePromptTemplate(template); err != nil {
+		if err := ValidatePromptTemplate(template); err != nil {
 			a.logger.Warn("Invalid prompt template, using default", "error", err)
 			return
 		}
@@ -98,6 +249,230 @@ func WithCustomPromptTemplate(template string) Option {
 	}
 }
 
+// WithToolPromptTemplate renders the injected prompt with a text/template template
+// instead of the format string of WithCustomPromptTemplate. The template produces
+// the whole prompt, including the tool results section, so it can render sections
+// conditionally:
+//
+//	{{if .Tools}}You can call these functions:
+//	{{range .Tools}}- {{.Name}}: {{.Description}}
+//	{{end}}{{end}}{{if .ToolResults}}Results:
+//	{{range .ToolResults}}{{.Name}} -> {{.Content}}
+//	{{end}}{{end}}
+//
+// The template receives a PromptTemplateData with the fields .Tools, .ToolResults,
+// .Examples, .Strict, .Model, .Language, .ToolChoice and .ToolChoiceFunction, plus
+// .ToolDefinitions, .ToolResultsText and .ExamplesText with the default renderings.
+// Besides the text/template builtins, the functions json, indent, join, lower,
+// upper, trim and quote are available.
+//
+// When the request has tools, the instructions that depend on the request follow the
+// rendered prompt, as they follow the default one: text argument and code block
+// tools, the single-call instruction and the tools-updated note of a Session.
+//
+// A template that fails to parse or render with sample data is ignored with a
+// warning; use ValidateToolPromptTemplate to surface the error. A template that fails
+// while rendering a request fails the transformation with ErrTemplateRender.
+func WithToolPromptTemplate(text string) Option {
+	return func(a *Adapter) {
+		if err := ValidateToolPromptTemplate(text); err != nil {
+			a.logger.Warn("Invalid tool prompt template, keeping the previous prompt", "error", err)
+			return
+		}
+
+		// Validation already parsed the template successfully
+		a.toolPromptTemplate, _ = parseToolPromptTemplate(text)
+		a.logger.Debug("Using tool prompt template", "template_length", len(text))
+	}
+}
+
+// WithTemplateExperiments compares prompt templates online. Each request is assigned
+// to one of the variants in proportion to their weights and its prompt is rendered
+// with the variant's WithCustomPromptTemplate template; the variant's name is
+// reported in ToolTransformationData.TemplateVariant and
+// TransformReport.TemplateVariant, so metrics such as parse failures and retries can
+// be grouped by variant.
+//
+// ExperimentAssignmentHash assigns a request deterministically by the key attached
+// with ContextWithExperimentKey or, without one, by its model and first user
+// message, so the turns of a conversation stay on one variant.
+// ExperimentAssignmentRandom assigns each request at random.
+//
+// Variants with an invalid template or a weight that is not positive are ignored
+// with a warning. A WithToolPromptTemplate template takes precedence over the
+// variants' templates.
+//
+// Default: nil (every request uses the adapter's template)
+func WithTemplateExperiments(experiments []TemplateExperiment, assignment ExperimentAssignment) Option {
+	return func(a *Adapter) {
+		kept := make([]TemplateExperiment, 0, len(experiments))
+		for i, experiment := range experiments {
+			if experiment.Name == "" {
+				experiment.Name = fmt.Sprintf("variant-%d", i+1)
+			}
+			if err := ValidatePromptTemplate(experiment.Template); err != nil {
+				a.logger.Warn("Invalid template experiment template",
+					"supplied_variant", experiment.Name,
+					"error", err,
+					"implication", "The variant is ignored",
+					"recommendation", "Supply a template with exactly one %s placeholder")
+				continue
+			}
+			if !(experiment.Weight > 0) {
+				a.logger.Warn("Template experiment weight not positive",
+					"supplied_variant", experiment.Name,
+					"supplied_weight", experiment.Weight,
+					"implication", "The variant is ignored",
+					"recommendation", "Supply a positive weight, such as 1")
+				continue
+			}
+			kept = append(kept, experiment)
+		}
+		a.templateExperiments = kept
+		a.experimentAssignment = assignment
+	}
+}
+
+// WithSchemaFormat selects how tool parameter schemas are rendered into the
+// injected prompt. Both formats are canonical: object keys are sorted and HTML
+// characters are not escaped, so the same schema always yields the same prompt
+// bytes, whatever formatting it was supplied with. SchemaCompact removes all
+// whitespace; SchemaPretty indents by two spaces. The bytes saved over the
+// supplied formatting are reported in ToolTransformationData.SchemaBytesSaved.
+//
+// Default: SchemaCompact
+func WithSchemaFormat(format SchemaFormat) Option {
+	return func(a *Adapter) {
+		if format != SchemaCompact && format != SchemaPretty {
+			a.logger.Warn("Unknown schema format",
+				"supplied_format", format.String(),
+				"implication", "The previous format is kept",
+				"recommendation", "Use SchemaCompact or SchemaPretty")
+			return
+		}
+		a.schemaFormat = format
+	}
+}
+
+// WithPromptStyle selects how tool definitions are presented in the injected
+// prompt. PromptStyleProse lists each tool as text under prose instructions.
+// PromptStyleSpec presents them as one JSON array to treat as an API specification,
+// with SpecPromptTemplate spelling out the output grammar; some models follow it far
+// better. Use it in a ModelProfile to select the style per model.
+//
+// The spec template is in English and replaces the WithPromptLanguage template;
+// tool result and example headings are still translated. A template set with
+// WithCustomPromptTemplate is kept, with its %s filled by the style's tool list.
+// WithToolPromptTemplate templates render the tools themselves and ignore the style.
+//
+// Default: PromptStyleProse
+func WithPromptStyle(style PromptStyle) Option {
+	return func(a *Adapter) {
+		if style != PromptStyleProse && style != PromptStyleSpec {
+			a.logger.Warn("Unknown prompt style",
+				"supplied_style", style.String(),
+				"implication", "The previous style is kept",
+				"recommendation", "Use PromptStyleProse or PromptStyleSpec")
+			return
+		}
+		a.promptStyle = style
+	}
+}
+
+// WithPromptCache keeps the tool prompts rendered for the size most recently used
+// tool sets, so applications sending the same tools with thousands of requests do
+// not render them again each time. Prompts are keyed by a hash of the tool
+// definitions; the template and other options are fixed when the adapter is
+// created. Prompts rendered with WithToolPromptTemplate include per-request data and
+// are not cached. Cache hits are reported in ToolTransformationData.PromptCacheHit.
+//
+// Default: 0 (no caching). Negative sizes are treated as 0 with a warning.
+func WithPromptCache(size int) Option {
+	return func(a *Adapter) {
+		if size < 0 {
+			a.logger.Warn("Negative size not allowed for PromptCache",
+				"supplied_size", size,
+				"updated_size", 0,
+				"implication", "Tool prompts are not cached",
+				"recommendation", "Supply a positive size to WithPromptCache() or 0 to disable caching")
+			size = 0
+		}
+		a.promptCache = nil
+		if size > 0 {
+			a.promptCache = newLRUCache[renderedToolPrompt](size, 0)
+		}
+	}
+}
+
+// WithPromptLanguage translates the injected instructions, the default prompt template
+// and the tool results section, into another language. Non-English local models
+// often follow instructions in their training language noticeably better. The JSON
+// format the model is asked to produce is unchanged.
+//
+// language is an ISO 639-1 code such as "de", "es", "fr", "ja", "pt" or "zh";
+// regional tags like "pt-BR" use their base language. PromptLanguages lists the
+// supported codes. Unsupported codes are ignored with a warning.
+//
+// A template set with WithCustomPromptTemplate is used as given. For custom
+// translations use WithToolPromptTemplate, whose .Language field carries the code.
+//
+// Default: "en"
+func WithPromptLanguage(language string) Option {
+	return func(a *Adapter) {
+		code, ok := lookupPromptLanguage(language)
+		if !ok {
+			a.logger.Warn("Unsupported prompt language ignored",
+				"supplied_language", language,
+				"supported_languages", PromptLanguages(),
+				"implication", "Injected instructions keep the previous language",
+				"recommendation", "Translate the prompt with WithToolPromptTemplate()")
+			return
+		}
+		a.promptLanguage = code
+	}
+}
+
+// WithToolExamples adds few-shot examples to the injected prompt, showing the model
+// requests paired with the tool calls it should answer with. Examples noticeably
+// improve tool calling reliability on small models.
+//
+// Examples are keyed by the tool's declared name and rendered only when that tool is
+// in the request; calls without a Name call that tool. Examples under GlobalExamples
+// are rendered whenever every tool they call is in the request, which suits
+// examples with several calls or with a natural language Reply and no calls. Names
+// are rendered as the model sees them, including any WithToolNamespace prefix.
+//
+//	tooladapter.WithToolExamples(map[string][]tooladapter.Example{
+//		"get_weather": {{
+//			Request: "Is it raining in Paris?",
+//			Calls:   []tooladapter.ExampleCall{{Parameters: map[string]any{"city": "Paris"}}},
+//		}},
+//		tooladapter.GlobalExamples: {{Request: "Hi!", Reply: "Hello! How can I help?"}},
+//	})
+//
+// Calling the option again adds to the examples. Examples without a Request are
+// ignored with a warning. WithToolPromptTemplate templates place examples
+// themselves through .Examples or .ExamplesText.
+func WithToolExamples(examples map[string][]Example) Option {
+	return func(a *Adapter) {
+		for name, list := range examples {
+			for _, example := range list {
+				if strings.TrimSpace(example.Request) == "" {
+					a.logger.Warn("Tool example without a request ignored",
+						"tool_name", name,
+						"recommendation", "Set Example.Request to the user message the example answers")
+					continue
+				}
+				if a.toolExamples == nil {
+					a.toolExamples = make(map[string][]Example)
+				}
+				example.Calls = append([]ExampleCall(nil), example.Calls...)
+				a.toolExamples[name] = append(a.toolExamples[name], example)
+			}
+		}
+	}
+}
+
 // WithLogger sets a custom slog.Logger for the adapter.
 // This enables structured logging for operational observability in production.
 //
@@ -135,24 +510,26 @@ func WithLogLevel(level slog.Level) Option {
 	}
 }
 
-// validatePromptTemplate ensures the template can be used safely with fmt.Sprintf.
-// This prevents runtime panics from malformed templates.
-func validatePromptTemplate(template string) error {
+// ValidatePromptTemplate ensures the template can be used safely with fmt.Sprintf.
+// This prevents runtime panics from malformed templates. WithCustomPromptTemplate
+// falls back to the default template when validation fails; call this first to
+// surface the problem instead. Errors wrap ErrTemplateRender.
+func ValidatePromptTemplate(template string) error {
 	// Count %s placeholders in the template
 	// We need exactly one for the tool definitions
 	placeholders := strings.Count(template, "%s")
 
 	if placeholders == 0 {
-		return errors.New("template validation failed: template must contain exactly one %%s placeholder for tool definitions")
+		return fmt.Errorf("%w: template must contain exactly one %%s placeholder for tool definitions", ErrTemplateRender)
 	}
 	if placeholders > 1 {
-		return fmt.Errorf("template validation failed: template contains %d %%s placeholders but exactly one is required", placeholders)
+		return fmt.Errorf("%w: template contains %d %%s placeholders but exactly one is required", ErrTemplateRender, placeholders)
 	}
 
 	// Test the template with a dummy string to catch other formatting issues
 	testResult := fmt.Sprintf(template, "test")
 	if testResult == template {
-		return errors.New("template validation failed: %%s placeholder was not processed during formatting test")
+		return fmt.Errorf("%w: %%s placeholder was not processed during formatting test", ErrTemplateRender)
 	}
 
 	return nil
@@ -180,6 +557,97 @@ func DefaultOptions() []Option {
 // NOTE: The preset helpers ProductionOptions, DevelopmentOptions, and QuietOptions
 // have been removed. Prefer explicit configuration via WithLogger or WithLogLevel.
 
+// WithLogRedaction applies redactors to everything the adapter reports about model
+// output and tool results: string and error attributes of log records, the
+// content-bearing fields of metric payloads (ArgumentViolationData and
+// ToolSanitizationData), and chunks written by WithStreamRecorder. Requests,
+// responses and streams returned to the caller are never redacted.
+//
+// Redactors run in order. Use CommonSecretRedactor for well-known credential formats,
+// RegexRedactor for custom patterns and JSONFieldRedactor for named fields in tool
+// arguments and results. Calling the option again adds to the list; nil redactors
+// are ignored.
+//
+// Streamed content is logged in pieces, so a secret split across chunks may escape
+// pattern-based redaction in log previews.
+func WithLogRedaction(redactors ...Redactor) Option {
+	return func(a *Adapter) {
+		for _, r := range redactors {
+			if r != nil {
+				a.redactors = append(a.redactors, r)
+			}
+		}
+	}
+}
+
+// WithLogSampling keeps only a fraction of the adapter's Debug and Info log records,
+// for hot paths such as per-chunk streaming that are too chatty to log in full.
+// A rate of 0.01 keeps the first record and every hundredth after it; 0 drops them
+// all and 1, the default, keeps everything. Warnings and errors, such as limits
+// being exceeded, are never sampled.
+//
+// Records are counted per LogCategory, so a burst in one category does not starve
+// the others. WithLogCategorySampling overrides the rate for a single category.
+func WithLogSampling(rate float64) Option {
+	return func(a *Adapter) {
+		if rate < 0 || rate > 1 || math.IsNaN(rate) {
+			a.logger.Warn("Log sampling rate out of range",
+				"supplied_rate", rate,
+				"implication", "The previous rate is kept",
+				"recommendation", "Supply a value between 0 and 1 to WithLogSampling()")
+			return
+		}
+		a.logSampling = rate
+	}
+}
+
+// WithLogCategorySampling sets the sampling rate of one LogCategory, overriding
+// WithLogSampling. For example, WithLogCategorySampling(LogCategoryParse, 0.01)
+// logs 1% of parse events while every other category logs in full.
+func WithLogCategorySampling(category LogCategory, rate float64) Option {
+	return func(a *Adapter) {
+		if !knownLogCategory(category) {
+			a.logger.Warn("Unknown log category",
+				"supplied_category", string(category),
+				"implication", "The sampling rate is ignored",
+				"recommendation", "Use one of the LogCategory constants")
+			return
+		}
+		if rate < 0 || rate > 1 || math.IsNaN(rate) {
+			a.logger.Warn("Log sampling rate out of range",
+				"category", string(category),
+				"supplied_rate", rate,
+				"implication", "The previous rate is kept",
+				"recommendation", "Supply a value between 0 and 1 to WithLogCategorySampling()")
+			return
+		}
+		if a.logCategorySampling == nil {
+			a.logCategorySampling = make(map[LogCategory]float64)
+		}
+		a.logCategorySampling[category] = rate
+	}
+}
+
+// WithLogCategoryLevel sets the minimum level logged for one LogCategory. Records
+// below it are dropped before sampling; records at or above it still need the
+// logger's own level. For example, WithLogCategoryLevel(LogCategoryStream,
+// slog.LevelWarn) silences per-chunk debugging while keeping stream warnings.
+func WithLogCategoryLevel(category LogCategory, level slog.Level) Option {
+	return func(a *Adapter) {
+		if !knownLogCategory(category) {
+			a.logger.Warn("Unknown log category",
+				"supplied_category", string(category),
+				"implication", "The level is ignored",
+				"recommendation", "Use one of the LogCategory constants")
+			return
+		}
+		if a.logCategoryLevels == nil {
+			a.logCategoryLevels = make(map[LogCategory]slog.Level)
+		}
+		a.logCategoryLevels[category] = level
+	}
+}
+
 // WithMetricsCallback sets a callback function that receives metric events.
 // This enables integration with monitoring systems like Prometheus, DataDog, or custom metrics collection.
 //
@@ -216,6 +684,274 @@ func WithMetricsCallback(callback func(MetricEventData)) Option {
 	}
 }
 
+// WithPhaseMetrics adds an event for every phase of a transformation to the metrics
+// callback, on top of the summary events it always receives:
+//
+//   - RequestTransformedData for every request returned by TransformCompletionsRequest
+//   - ToolPromptRenderedData for every injected tool prompt
+//   - ToolCallDetectedData for every tool call recognized in a response or stream
+//   - StreamBufferFlushedData when withheld streaming content is released as text
+//   - PolicyLimitHitData when a configured limit cuts detection or calls short
+//
+// These events follow MetricsSchemaVersion: their names and JSON fields stay stable
+// within a major version. Phase events are emitted synchronously like all metrics,
+// so keep the callback fast when enabling them.
+//
+// Default: false
+func WithPhaseMetrics(enabled bool) Option {
+	return func(a *Adapter) {
+		a.phaseMetrics = enabled
+	}
+}
+
+// WithTokenCounter estimates token counts for cost accounting. Injected tool
+// prompts make a request's actual prompt tokens differ from what callers estimate
+// from their own messages; with a counter the adapter reports the difference:
+//
+//   - ToolTransformationData.InjectedTokens for each transformed request
+//   - FunctionCallDetectionData.ContentTokens for output parsed as tool calls
+//   - SessionUsage.InjectedTokens and SessionUsage.ToolCallTokens for a Session
+//
+// Use the tokenizer of the target model for accurate counts, or
+// ApproximateTokenCounter. The counter runs on every transformation, so it
+// should be fast. Pass nil to disable.
+//
+// Default: nil (tokens are not counted)
+func WithTokenCounter(counter TokenCounter) Option {
+	return func(a *Adapter) {
+		a.tokenCounter = counter
+	}
+}
+
+// WithMaxInjectedPromptBytes sets a ceiling on the size of the prompt injected into
+// requests, including tool definitions, tool results and injection markers.
+// Requests whose prompt would exceed it fail with a *PromptTooLargeError instead of
+// sending an enormous prompt to a small-context model. Unlike
+// WithToolCollectMaxBytes, which bounds streamed output, it applies to requests.
+//
+// Default: 0 (unlimited)
+func WithMaxInjectedPromptBytes(maxBytes int) Option {
+	return func(a *Adapter) {
+		if maxBytes < 0 {
+			a.logger.Warn("Invalid max injected prompt bytes, must be non-negative",
+				"supplied_maxBytes", maxBytes,
+				"implication", "The previous ceiling is kept",
+				"recommendation", "Supply 0 for unlimited or a positive byte count to WithMaxInjectedPromptBytes()")
+			return
+		}
+		a.maxInjectedPromptBytes = maxBytes
+	}
+}
+
+// WithMaxInjectedPromptTokens is WithMaxInjectedPromptBytes with a ceiling in
+// tokens, counted with the WithTokenCounter counter or ApproximateTokenCounter when
+// none is set. Both ceilings can be combined.
+//
+// Default: 0 (unlimited)
+func WithMaxInjectedPromptTokens(maxTokens int) Option {
+	return func(a *Adapter) {
+		if maxTokens < 0 {
+			a.logger.Warn("Invalid max injected prompt tokens, must be non-negative",
+				"supplied_maxTokens", maxTokens,
+				"implication", "The previous ceiling is kept",
+				"recommendation", "Supply 0 for unlimited or a positive token count to WithMaxInjectedPromptTokens()")
+			return
+		}
+		a.maxInjectedPromptTokens = maxTokens
+	}
+}
+
+// WithContextWindow truncates conversations that would not fit a model's context
+// window of modelTokens tokens. When the injected tool prompt, the messages and the
+// request's max_completion_tokens (or max_tokens) exceed the window, the oldest
+// messages are dropped according to WithTruncationStrategy, and a
+// MessageTruncationData metric is emitted. System and developer messages and the
+// last message are always kept, and the shortened conversation starts at a user
+// message.
+//
+// Tokens are estimated with the WithTokenCounter counter, or
+// ApproximateTokenCounter when none is set, over the JSON encoding of each message.
+// Requests that need no tool prompt pass through untouched.
+//
+// Default: 0 (no truncation)
+func WithContextWindow(modelTokens int) Option {
+	return func(a *Adapter) {
+		if modelTokens < 0 {
+			a.logger.Warn("Invalid context window, must be non-negative",
+				"supplied_modelTokens", modelTokens,
+				"implication", "The previous context window is kept",
+				"recommendation", "Supply 0 to disable truncation or the model's context size to WithContextWindow()")
+			return
+		}
+		a.contextWindow = modelTokens
+	}
+}
+
+// WithTruncationStrategy sets how WithContextWindow shortens conversations.
+//
+// Default: TruncateDropOldest
+func WithTruncationStrategy(strategy TruncationStrategy) Option {
+	return func(a *Adapter) {
+		if strategy != TruncateDropOldest && strategy != TruncateWithMarker {
+			a.logger.Warn("Unknown truncation strategy",
+				"supplied_strategy", strategy.String(),
+				"implication", "The previous strategy is kept",
+				"recommendation", "Use TruncateDropOldest or TruncateWithMarker")
+			return
+		}
+		a.truncationStrategy = strategy
+	}
+}
+
+// WithParseEventHook sets a hook that receives a ParseEvent for each step of tool call
+// detection: buffering starting and being flushed, tools detected, repairs applied and
+// limits exceeded. This makes it possible to see why a model's output was or wasn't
+// recognized without enabling debug logging in production.
+//
+// Example usage:
+//
+//	adapter := tooladapter.New(
+//	    tooladapter.WithParseEventHook(func(e tooladapter.ParseEvent) {
+//	        if e.Type == tooladapter.ParseEventBufferFlush {
+//	            log.Printf("buffered %d bytes were not a tool call", e.Size)
+//	        }
+//	    }),
+//	)
+//
+// Like metrics callbacks, the hook is called synchronously and panics are recovered.
+func WithParseEventHook(hook func(ParseEvent)) Option {
+	return func(a *Adapter) {
+		a.parseEventHook = hook
+	}
+}
+
+// WithPostTransformHook sets a hook that receives a PostTransformReport after each
+// non-streaming request and response transformation, for shipping reports to
+// analytics without adding latency to the request path. Every transformation then
+// builds its report as TransformCompletionsRequestWithReport and
+// TransformCompletionsResponseWithReport do.
+//
+// Example usage:
+//
+//	adapter := tooladapter.New(
+//	    tooladapter.WithPostTransformHook(func(ctx context.Context, r tooladapter.PostTransformReport) {
+//	        if r.Response != nil {
+//	            analytics.RecordParse(ctx, r.Model, r.Response)
+//	        }
+//	    }),
+//	)
+//
+// The hook runs asynchronously on a pool of worker goroutines (see
+// WithPostTransformHookPool) with a context that keeps the transformation's values
+// but not its cancellation. Reports wait in a bounded queue for a free worker;
+// reports arriving while the queue is full are dropped, logged and counted in a
+// PostTransformReportDroppedData metric. Panics are recovered. Hooks must not
+// modify the report, which may share slices with the caller's.
+func WithPostTransformHook(hook func(ctx context.Context, report PostTransformReport)) Option {
+	return func(a *Adapter) {
+		a.postTransformHook = hook
+	}
+}
+
+// WithPostTransformHookPool sets the number of worker goroutines that run the
+// WithPostTransformHook hook and the number of reports that can wait for them.
+// The workers start with the first report and keep running for the rest of the
+// process, so share an adapter with a hook rather than creating one per request.
+//
+// Default: 4 workers, 256 queued reports
+func WithPostTransformHookPool(workers, queueSize int) Option {
+	return func(a *Adapter) {
+		if workers < 1 || queueSize < 0 {
+			a.logger.Warn("Invalid post-transform hook pool",
+				"supplied_workers", workers,
+				"supplied_queue_size", queueSize,
+				"implication", "The previous pool size is kept",
+				"recommendation", "Use at least one worker and a non-negative queue size")
+			return
+		}
+		a.postTransformWorkers = workers
+		a.postTransformQueueSize = queueSize
+	}
+}
+
+// WithStreamRecorder records a JSONL transcript of every stream transformed by the
+// adapter to w: each raw upstream chunk, each chunk the adapter emits, and the upstream
+// error that ended the stream, if any. Entries from concurrent streams are interleaved
+// and tagged with a per-adapter stream number. Use ReplayStream to turn a transcript
+// back into a stream for reproducing a reported bug.
+//
+// Transcripts contain full model output, so only enable recording where that content
+// may be stored. Write failures are logged and never affect the stream. A nil writer
+// disables recording.
+func WithStreamRecorder(w io.Writer) Option {
+	return func(a *Adapter) {
+		if w == nil {
+			a.streamRecorder = nil
+			return
+		}
+		a.streamRecorder = newStreamRecorder(w)
+	}
+}
+
+// WithStrictOpenAICompatibility makes transformed streams and responses match what
+// the OpenAI API sends, for proxies that must be drop-in replacements for it. Streams
+// then carry the upstream ID, model and creation time on every chunk, the role in
+// the first delta only, each tool call as a chunk announcing it followed by a chunk
+// with its arguments, and the finish reason alone in a final chunk with an empty
+// delta; chunks after it are dropped, except usage. Transformed completions get
+// their object type, message roles and tool call types filled in.
+//
+// Empty strings and nulls are told apart only in JSON, so encode chunks with
+// MarshalOpenAIChunk and completions with MarshalOpenAICompletion rather than
+// json.Marshal. WriteSSEStream does so for streams of such adapters.
+//
+// Default: false
+func WithStrictOpenAICompatibility(enabled bool) Option {
+	return func(a *Adapter) {
+		a.strictOpenAI = enabled
+	}
+}
+
+// WithLegacyFunctionCallOutput makes transformed completions also carry each choice's
+// first tool call in the deprecated message.function_call field, for downstream
+// consumers written against the legacy functions API. Tool calls and the finish
+// reason are left as they are, so current clients are unaffected. When a choice has
+// several tool calls only the first fits the legacy field. Streams are unchanged.
+//
+// Default: false
+func WithLegacyFunctionCallOutput(enabled bool) Option {
+	return func(a *Adapter) {
+		a.legacyFunctionCall = enabled
+	}
+}
+
+// WithArgumentStreaming calls handler as soon as a StreamAdapter detects the start of
+// a tool call, with an io.Reader that is fed the call's JSON arguments as the deltas
+// arrive. Executors can start processing very large arguments, such as generated
+// documents, before the JSON completes:
+//
+//	tooladapter.WithArgumentStreaming(func(call tooladapter.ArgumentStream) {
+//	    if call.Name == "write_file" {
+//	        go render(call.Arguments)
+//	    }
+//	})
+//
+// Arguments are delivered before the tool call is parsed or validated, so the tool
+// call chunk emitted by the stream remains authoritative: a detected call may still
+// be dropped by deduplication, rate limits or an unknown-tool policy. Detection
+// requires "name" to precede "parameters", as the tool prompt asks.
+//
+// Each handler runs in its own goroutine and panics are recovered. Readers never
+// block the stream; unread bytes are held in memory until read. A handler that does
+// not need a call can ignore its reader. A nil handler disables argument streaming.
+//
+// Default: nil (disabled)
+func WithArgumentStreaming(handler func(ArgumentStream)) Option {
+	return func(a *Adapter) {
+		a.argumentHandler = handler
+	}
+}
+
 // WithToolPolicy sets the tool processing policy for the adapter.
 // This controls how tool calls are detected, collected, and emitted.
 //
@@ -224,6 +960,7 @@ func WithMetricsCallback(callback func(MetricEventData)) Option {
 //   - ToolCollectThenStop: Collect tools until array closes or limits reached
 //   - ToolDrainAll: Read entire response and collect all tools
 //   - ToolAllowMixed: Allow both text content and tools to be emitted
+//   - ToolEmitAndContinue: Emit each tool call when complete and keep streaming
 //
 // Default: ToolStopOnFirst
 func WithToolPolicy(policy ToolPolicy) Option {
@@ -256,6 +993,33 @@ func WithToolCollectWindow(duration time.Duration) Option {
 	}
 }
 
+// WithToolCollectTotalBudget limits the total time a StreamAdapter may spend
+// buffering content that may be a tool call, summed over every buffering of the
+// stream under any tool policy. WithToolCollectWindow bounds a single
+// ToolCollectThenStop window; this budget bounds the stream, protecting tail
+// latency from models that repeatedly start and abandon tool calls.
+//
+// The budget is checked when a content chunk arrives. Once it is spent, the stream
+// degrades to pass-through: the withheld content, including the text of tool calls
+// collected but not yet emitted, is released as text, and the rest of the stream is
+// emitted as it arrives without tool call detection. Tool calls emitted before that
+// are unaffected. Zero disables the budget.
+//
+// Default: 0 (no budget)
+func WithToolCollectTotalBudget(budget time.Duration) Option {
+	return func(a *Adapter) {
+		if budget < 0 {
+			a.logger.Warn("Negative duration not allowed for tool collection budget",
+				"supplied_duration", budget,
+				"updated_duration", 0,
+				"implication", "No limit will be applied to the total buffering time of a stream",
+				"recommendation", "Supply a positive duration to WithToolCollectTotalBudget()")
+			budget = 0
+		}
+		a.toolCollectTotalBudget = budget
+	}
+}
+
 // WithToolMaxCalls sets the maximum number of tool calls to collect
 // across both streaming and non-streaming modes.
 //
@@ -339,20 +1103,135 @@ func WithStreamingToolBufferSize(limitBytes int) Option {
 	}
 }
 
-// WithStreamingEarlyDetection enables early tool call detection in streaming responses
-// by looking ahead within the first N characters of content for tool call patterns.
-// This improves buffering heuristics when models emit explanatory text before JSON.
-//
-// The adapter will search for tool call patterns like {"name": or [{"name": within
-// the specified character limit. This helps catch tool calls that start after some
-// preface text, improving content suppression for ToolStopOnFirst/ToolCollectThenStop.
+// WithStreamMemoryLimit caps the memory, in bytes, a single StreamAdapter may
+// retain for tool call detection: buffered and peeked content, content suppressed
+// while collecting tool calls, and the calls collected but not yet emitted. Unlike
+// WithStreamingToolBufferSize, which bounds one buffer, it counts everything the
+// stream holds back.
 //
-// Recommended values:
-//   - 80-100 characters: Good balance of recall vs false positives
-//   - 120 characters: More generous, catches longer prefaces
-//   - 0 (default): Disabled, uses only immediate JSON detection
+// The limit is checked when a content chunk arrives at a stream that is buffering.
+// When withholding the chunk would exceed it, the stream degrades to pass-through:
+// the withheld content is released as text and the rest of the stream is emitted as
+// it arrives without tool call detection. Zero disables the limit.
 //
-// Use cases:
+// Default: 0 (no limit)
+func WithStreamMemoryLimit(limitBytes int) Option {
+	return func(a *Adapter) {
+		if limitBytes < 0 {
+			a.logger.Warn("Negative stream memory limit not allowed",
+				"supplied_limit", limitBytes,
+				"updated_limit", 0,
+				"implication", "No limit will be applied to the memory a stream retains",
+				"recommendation", "Supply a positive limit to WithStreamMemoryLimit()")
+			limitBytes = 0
+		}
+		a.streamMemoryLimit = limitBytes
+	}
+}
+
+// WithAggregateStreamMemoryLimit caps the memory, in bytes, all live StreamAdapters
+// of the adapter may retain together for tool call detection, counted like
+// WithStreamMemoryLimit, for gateways serving thousands of concurrent streams.
+//
+// When a buffering stream would take the total past the limit, that stream degrades
+// to pass-through, releasing what it holds, so the streams that keep growing give
+// way while streams within their share are unaffected. A stream stops counting once
+// it ends or is closed. Streams that select a WithModelRules rule count against the
+// limit of the adapter that created them. Zero disables the limit.
+//
+// Default: 0 (no limit)
+func WithAggregateStreamMemoryLimit(limitBytes int) Option {
+	return func(a *Adapter) {
+		if limitBytes < 0 {
+			a.logger.Warn("Negative aggregate stream memory limit not allowed",
+				"supplied_limit", limitBytes,
+				"updated_limit", 0,
+				"implication", "No limit will be applied to the memory all streams retain",
+				"recommendation", "Supply a positive limit to WithAggregateStreamMemoryLimit()")
+			limitBytes = 0
+		}
+		a.aggregateStreamMemoryLimit = limitBytes
+	}
+}
+
+// WithResponseParseMaxBytes sets the largest message content, in bytes, that
+// TransformCompletionsResponse parses for tool calls. Larger content is returned
+// unchanged, as a response without tool calls, and reported with a
+// ResponseParseSkippedData metric and a ParseEventLimitExceeded event. This bounds
+// the memory and CPU spent on giant responses, as WithStreamingToolBufferSize does
+// for streams.
+//
+// Set to 0 for no limit. Negative values are treated as 0.
+//
+// Default: 0 (no limit)
+func WithResponseParseMaxBytes(maxBytes int) Option {
+	return func(a *Adapter) {
+		if maxBytes < 0 {
+			a.logger.Warn("Negative byte count not allowed for ResponseParseMaxBytes",
+				"supplied_maxBytes", maxBytes,
+				"updated_maxBytes", 0,
+				"implication", "Responses of any size will be parsed for tool calls",
+				"recommendation", "Supply a positive number to WithResponseParseMaxBytes()")
+			maxBytes = 0
+		}
+		a.responseParseMaxBytes = maxBytes
+	}
+}
+
+// WithResponseCache caches the results of TransformCompletionsResponse in a least
+// recently used cache holding up to size responses, keyed by a hash of the response.
+// Gateways that transform the same upstream response again when a client retries
+// then return the earlier result, including its generated tool call IDs, without
+// parsing it again. Cached results older than ttl are parsed again; a ttl of 0 keeps
+// them until they are evicted.
+//
+// The key also covers the parallel tool call setting and, with WithArgumentCoercion,
+// the tools attached with ContextWithTools. Responses are parsed without the cache
+// when a WithToolCallInterceptor is set, when a Conversation is attached for
+// WithToolCallRateLimit, when WithArgumentViolationPolicy checks attached tools,
+// and when no choice can hold a tool call. A cache hit skips the metrics and parse
+// events of the parse. Failed transformations and streams are not cached.
+//
+// Default: 0 (no caching). Negative sizes and TTLs are treated as 0 with a warning.
+func WithResponseCache(size int, ttl time.Duration) Option {
+	return func(a *Adapter) {
+		if size < 0 {
+			a.logger.Warn("Negative size not allowed for ResponseCache",
+				"supplied_size", size,
+				"updated_size", 0,
+				"implication", "Transformed responses are not cached",
+				"recommendation", "Supply a positive size to WithResponseCache() or 0 to disable caching")
+			size = 0
+		}
+		if ttl < 0 {
+			a.logger.Warn("Negative TTL not allowed for ResponseCache",
+				"supplied_ttl", ttl,
+				"updated_ttl", time.Duration(0),
+				"implication", "Cached responses do not expire",
+				"recommendation", "Supply a positive TTL to WithResponseCache() or 0 to keep responses until evicted")
+			ttl = 0
+		}
+		a.responseCache = nil
+		if size > 0 {
+			a.responseCache = newLRUCache[cachedCompletion](size, ttl)
+		}
+	}
+}
+
+// WithStreamingEarlyDetection enables early tool call detection in streaming responses
+// by looking ahead within the first N characters of content for tool call patterns.
+// This improves buffering heuristics when models emit explanatory text before JSON.
+//
+// The adapter will search for tool call patterns like {"name": or [{"name": within
+// the specified character limit. This helps catch tool calls that start after some
+// preface text, improving content suppression for ToolStopOnFirst/ToolCollectThenStop.
+//
+// Recommended values:
+//   - 80-100 characters: Good balance of recall vs false positives
+//   - 120 characters: More generous, catches longer prefaces
+//   - 0 (default): Disabled, uses only immediate JSON detection
+//
+// Use cases:
 //   - Enable when models frequently add explanatory text before tool calls
 //   - Keep disabled for maximum performance and minimal false positives
 //   - Use with ToolStopOnFirst or ToolCollectThenStop for content suppression
@@ -367,6 +1246,397 @@ func WithStreamingEarlyDetection(lookAheadChars int) Option {
 	}
 }
 
+// WithBufferDecisionLookahead lets the streaming adapter peek at up to lookaheadBytes of
+// content that starts with a JSON opener ({, [ or a backtick) before deciding whether
+// to buffer it as a potential tool call.
+//
+// Without a lookahead, each chunk is judged on its own: only chunks that already show a
+// complete tool call prefix such as {"name": start buffering. Models that stream one
+// token per chunk split that prefix across chunks, and pretty-printed JSON puts
+// whitespace inside it. With a lookahead, such content is held until a "name" (or
+// "function_call"/"tool_calls") key appears, which starts buffering, or until the
+// lookahead is exhausted, at which point the held content is emitted as regular text.
+// JSON-heavy responses without tool calls are therefore delayed by at most N bytes.
+//
+// Recommended values: 16-64 bytes. 0 (default) disables peeking. Negative values are
+// ignored. Applies to ToolStopOnFirst, ToolCollectThenStop and ToolEmitAndContinue; ToolAllowMixed never
+// withholds content and ToolDrainAll buffers everything.
+func WithBufferDecisionLookahead(lookaheadBytes int) Option {
+	return func(a *Adapter) {
+		if lookaheadBytes < 0 {
+			a.logger.Warn("Negative buffer decision lookahead ignored", "lookahead", lookaheadBytes)
+			return
+		}
+		a.bufferDecisionLookahead = lookaheadBytes
+	}
+}
+
+// WithLenientParsing accepts tool calls written in JSON5 or simple YAML in addition
+// to strict JSON. Some small models emit unquoted keys, single-quoted strings and
+// trailing commas, or write calls as YAML mappings such as "- name: get_weather".
+//
+// JSON5 blocks are found wherever strict JSON would be. YAML is only recognized when
+// the whole response is YAML or inside a ```yaml code fence, and only block mappings,
+// block sequences and single-line values are supported. Converted calls still pass
+// the usual name and structure validation. Strict JSON takes precedence unless
+// WithParserPriority ranks CallFormatLenient higher.
+func WithLenientParsing(enabled bool) Option {
+	return func(a *Adapter) {
+		a.lenientParsing = enabled
+	}
+}
+
+// WithParserPriority sets which format wins when a response holds tool calls in
+// several formats, such as a bare JSON call next to a ```json fence or a fenced
+// WithCodeBlockTools block. Candidates are tried in the order of their format in
+// order, highest priority first; formats not listed follow in their default order,
+// and candidates of the same format in the order they appear in the response, so the
+// outcome is deterministic. Only the first candidate holding calls is used, so the
+// calls of the other formats are ignored. Streams parse as soon as a call is
+// complete, so there the priority only decides among the formats read so far.
+//
+// The formats are CallFormatJSON, CallFormatCodeFence, CallFormatLenient (which
+// still needs WithLenientParsing) and CallFormatTextBlock (which needs
+// WithToolArgumentModes or WithCodeBlockTools). Calling it with no formats restores
+// the default order.
+//
+// Default: CallFormatTextBlock, then CallFormatJSON and CallFormatCodeFence in the
+// order they appear, then CallFormatLenient
+func WithParserPriority(order ...CallFormat) Option {
+	return func(a *Adapter) {
+		a.parserPriority = nil
+		for _, format := range order {
+			if format < CallFormatJSON || format > CallFormatTextBlock {
+				a.logger.Warn("Unknown parser priority format",
+					"supplied_format", format.String(),
+					"implication", "The format is ignored",
+					"recommendation", "Use CallFormatJSON, CallFormatCodeFence, CallFormatLenient or CallFormatTextBlock")
+				continue
+			}
+			if slices.Contains(a.parserPriority, format) {
+				continue
+			}
+			a.parserPriority = append(a.parserPriority, format)
+		}
+	}
+}
+
+// WithThinkBlocks sets how think blocks, the <think>...</think> chain-of-thought
+// that reasoning models such as DeepSeek-R1 write before answering, are handled:
+//   - ThinkBlocksDetect: search them for tool calls like any other content
+//   - ThinkBlocksPassThrough: skip them during detection but keep them as content
+//   - ThinkBlocksStrip: skip them during detection and remove them from content
+//
+// Excluding think blocks keeps JSON the model drafts while reasoning from being
+// executed as a tool call. The policy applies to non-streaming responses and to
+// StreamAdapter, whose Reasoning method returns the think block content read.
+//
+// Default: ThinkBlocksDetect
+func WithThinkBlocks(policy ThinkBlockPolicy) Option {
+	return func(a *Adapter) {
+		if policy < ThinkBlocksDetect || policy > ThinkBlocksStrip {
+			a.logger.Warn("Unknown think block policy",
+				"supplied_policy", policy.String(),
+				"implication", "The previous policy is kept",
+				"recommendation", "Use ThinkBlocksDetect, ThinkBlocksPassThrough or ThinkBlocksStrip")
+			return
+		}
+		a.thinkBlocks = policy
+	}
+}
+
+// WithGBNFGrammar sets the "grammar" field of transformed requests that offer tools
+// to a GenerateGBNF grammar of the tools, so llama.cpp servers can only sample
+// valid tool call JSON. The grammar also accepts a plain text answer unless the
+// request's tool choice is "required" or names a function, in which case only that
+// function can be called, and it is left out when the tool choice is "none". A
+// grammar already set on the request is kept.
+//
+// Only enable this for backends that understand llama.cpp grammars, typically in a
+// ModelProfile such as LlamaCppProfile.
+//
+// Default: false
+func WithGBNFGrammar(enabled bool) Option {
+	return func(a *Adapter) {
+		a.gbnfGrammar = enabled
+	}
+}
+
+// WithToolCallConstraint sends the ToolCallSchema of a transformed request's tools
+// to backends with schema-constrained decoding, so the model can only generate
+// valid tool calls, in the way the constraint selects: response_format, vLLM's
+// guided_json or TGI's grammar field. A JSON Schema cannot also accept a text
+// answer, so the schema is only sent when the request's tool choice is "required"
+// or names a function, in which case only that function can be called. A response
+// format or field already set on the request is kept.
+//
+// Default: ToolCallConstraintNone
+func WithToolCallConstraint(constraint ToolCallConstraint) Option {
+	return func(a *Adapter) {
+		if constraint < ToolCallConstraintNone || constraint > ToolCallConstraintTGIGrammar {
+			a.logger.Warn("Unknown tool call constraint",
+				"supplied_constraint", constraint.String(),
+				"implication", "The previous constraint is kept",
+				"recommendation", "Use ToolCallConstraintNone, ToolCallConstraintResponseFormat, ToolCallConstraintGuidedJSON or ToolCallConstraintTGIGrammar")
+			return
+		}
+		a.toolCallConstraint = constraint
+	}
+}
+
+// WithStopTokenStripping removes chat template stop tokens, such as <|eot_id|>,
+// <|im_end|> or </s>, that some backends leak into the content of responses. Left in
+// place, they end up in emitted text or break the JSON of a tool call. Tokens are
+// removed before tool call detection and, in streams, before content chunks are
+// emitted; a token split across chunks is held back until it is complete.
+//
+// With no tokens, the tokens of the model's family are removed, as returned by
+// DefaultStopTokens for the response's model or the model named by a stream's first
+// chunk. Configured tokens replace the defaults for every model.
+//
+// Default: disabled
+func WithStopTokenStripping(tokens ...string) Option {
+	return func(a *Adapter) {
+		var kept []string
+		for _, token := range tokens {
+			if token == "" {
+				a.logger.Warn("Empty stop token",
+					"implication", "The token is ignored",
+					"recommendation", "Remove empty strings from the stop tokens")
+				continue
+			}
+			kept = append(kept, token)
+		}
+		a.stripStopTokens = true
+		a.stopTokens = kept
+	}
+}
+
+// WithEchoSuppression removes passages of the reply that repeat an injected tool
+// result verbatim, as some models do when they turn results into an answer. A
+// passage counts as an echo when it copies at least threshold characters of one
+// result; code fences left empty are removed with it. A reply that is nothing but an
+// echo is kept as it is and only flagged, in the EchoedToolResults field of its
+// ChoiceParseReport and with a warning, since removing it would leave no answer.
+//
+// Responses are only checked when the tool results of their request are known:
+// attach them with ContextWithToolResults, or use a Session or CompleteWithRetry,
+// which attach them automatically. Choices with tool calls and streamed responses
+// are not checked. A threshold of 0 disables the check.
+//
+// Default: 0 (disabled)
+func WithEchoSuppression(threshold int) Option {
+	return func(a *Adapter) {
+		if threshold < 0 {
+			a.logger.Warn("Negative echo suppression threshold",
+				"supplied_threshold", threshold,
+				"implication", "Echo suppression is disabled",
+				"recommendation", "Supply a positive number of characters to WithEchoSuppression(), such as 40")
+			threshold = 0
+		}
+		a.echoThreshold = threshold
+	}
+}
+
+// WithToolStopSequences adds stop sequences to every transformed request that offers
+// tools, so the model stops generating right after a tool call instead of continuing
+// with commentary. This reduces the time before a tool call can be emitted,
+// especially under ToolStopOnFirst.
+//
+// Choose sequences that only appear after a tool call in your prompt format, such as
+// "</tool_call>" with a custom template that wraps calls in tags, or "```" when the
+// model fences its JSON. Because the API removes the matched stop sequence from the
+// output, the adapter also tries each configured sequence as a closing delimiter when
+// parsing responses, so a fenced call cut off at its closing fence is still detected.
+//
+// Sequences are merged after any stop sequences already on the request, up to the
+// API limit of 4; extra sequences are dropped with a warning. Empty strings are ignored.
+//
+// Default: none
+func WithToolStopSequences(sequences ...string) Option {
+	return func(a *Adapter) {
+		a.toolStopSequences = nil
+		for _, seq := range sequences {
+			if seq != "" {
+				a.toolStopSequences = append(a.toolStopSequences, seq)
+			}
+		}
+	}
+}
+
+// WithAssistantPrefill appends an assistant message with the given text to every
+// transformed request that offers tools, so the model continues a turn that has
+// already started a tool call. Some backends, such as Claude-compatible proxies and
+// llama.cpp chat templates that support prefill, follow the tool instructions more
+// reliably this way. A typical prefill opens the JSON the prompt asks for:
+//
+//	tooladapter.WithAssistantPrefill("```json\n[")
+//
+// The model's reply continues the prefill rather than repeating it, so responses and
+// streams are parsed with the prefill restored in front of the content. Content
+// returned to the caller never contains the prefill.
+//
+// Default: "" (no prefill)
+func WithAssistantPrefill(prefill string) Option {
+	return func(a *Adapter) {
+		a.assistantPrefill = prefill
+	}
+}
+
+// WithStreamHeartbeat emits keep-alive output while the streaming adapter is holding
+// back content, such as while buffering a long tool call or during a
+// ToolCollectThenStop collection window. Without it, downstream clients see no data
+// until the tool call is complete and may time out.
+//
+// StreamAdapter emits a chunk with an empty delta (carrying the upstream chunk's ID
+// and model) when at least interval has passed since the last chunk it returned.
+// Upstream is read in a background goroutine, as with WithStreamReadAhead, so
+// heartbeats are also sent while upstream is silent and callers must close streams
+// they do not read to the end. SSEStreamAdapter writes an SSE comment line
+// (": keep-alive"), which clients ignore, as upstream data arrives.
+//
+// Set to 0 to disable.
+//
+// Default: 0 (disabled)
+func WithStreamHeartbeat(interval time.Duration) Option {
+	return func(a *Adapter) {
+		if interval < 0 {
+			a.logger.Warn("Negative duration not allowed for stream heartbeat",
+				"supplied_interval", interval,
+				"updated_interval", 0,
+				"implication", "No keep-alive chunks will be emitted while buffering",
+				"recommendation", "Supply a positive duration to WithStreamHeartbeat()")
+			interval = 0
+		}
+		a.streamHeartbeat = interval
+	}
+}
+
+// WithStreamIdleTimeout terminates a StreamAdapter whose upstream sends no chunk
+// for d while the consumer waits in Next, so a stuck upstream cannot hang the
+// consumer. The upstream stream is closed and Err returns a *StreamTimeoutError;
+// a partly buffered tool call is handled according to WithStreamErrorPolicy.
+//
+// Enforcing a timeout requires reading upstream in a background goroutine, as
+// with WithStreamReadAhead, so callers must close streams they do not read to the
+// end. Applies to StreamAdapter only.
+//
+// Set to 0 to disable.
+//
+// Default: 0 (disabled)
+func WithStreamIdleTimeout(d time.Duration) Option {
+	return func(a *Adapter) {
+		if d < 0 {
+			a.logger.Warn("Negative duration not allowed for stream idle timeout",
+				"supplied_timeout", d,
+				"updated_timeout", 0,
+				"implication", "A stuck upstream stream may block Next indefinitely",
+				"recommendation", "Supply a positive duration to WithStreamIdleTimeout()")
+			d = 0
+		}
+		a.streamIdleTimeout = d
+	}
+}
+
+// WithStreamMaxDuration terminates a StreamAdapter that is still waiting for
+// upstream chunks d after it was created. It behaves like WithStreamIdleTimeout
+// otherwise, and the two can be combined. Chunks that already arrived are still
+// returned before the timeout takes effect.
+//
+// Set to 0 to disable.
+//
+// Default: 0 (disabled)
+func WithStreamMaxDuration(d time.Duration) Option {
+	return func(a *Adapter) {
+		if d < 0 {
+			a.logger.Warn("Negative duration not allowed for stream maximum duration",
+				"supplied_duration", d,
+				"updated_duration", 0,
+				"implication", "Streams may run indefinitely",
+				"recommendation", "Supply a positive duration to WithStreamMaxDuration()")
+			d = 0
+		}
+		a.streamMaxDuration = d
+	}
+}
+
+// WithStreamReadAhead pipelines streaming: a background goroutine reads up to n
+// upstream chunks ahead of the consumer into a bounded channel, so the network
+// latency of the next chunk overlaps with the consumer's handling of the current
+// one. When the channel is full the goroutine stops reading, so a slow consumer
+// applies backpressure to upstream as it would without read-ahead.
+//
+// StreamAdapter.Close stops the goroutine and closes upstream, so callers must
+// close streams they do not read to the end; cancelling the stream's context also
+// stops it. Err reports the upstream error once every prefetched chunk has been
+// returned. Applies to StreamAdapter only; SSEStreamAdapter reads its SSEStreamReader
+// directly.
+//
+// Set to 0 to read upstream synchronously from Next.
+//
+// Default: 0 (disabled)
+func WithStreamReadAhead(n int) Option {
+	return func(a *Adapter) {
+		if n < 0 {
+			a.logger.Warn("Negative read-ahead not allowed for streaming",
+				"supplied_read_ahead", n,
+				"updated_read_ahead", 0,
+				"implication", "Upstream chunks will be read synchronously from Next",
+				"recommendation", "Supply a positive chunk count to WithStreamReadAhead()")
+			n = 0
+		}
+		a.streamReadAhead = n
+	}
+}
+
+// WithStreamErrorPolicy sets what a StreamAdapter does with a partially buffered
+// tool call when the upstream stream fails, such as on a dropped connection:
+//   - StreamErrorFlush: emit the buffered content, as a tool call if complete or as text
+//   - StreamErrorReport: discard it; Err carries it in *StreamInterruptedError
+//   - StreamErrorRepair: close the truncated JSON and emit the tool calls it contains
+//
+// Err returns a *StreamInterruptedError wrapping the upstream error with every
+// policy, so errors.Is matches both ErrStreamInterrupted and the upstream error.
+//
+// Default: StreamErrorFlush
+func WithStreamErrorPolicy(policy StreamErrorPolicy) Option {
+	return func(a *Adapter) {
+		if policy < StreamErrorFlush || policy > StreamErrorRepair {
+			a.logger.Warn("Unknown stream error policy",
+				"supplied_policy", policy.String(),
+				"implication", "The previous policy is kept",
+				"recommendation", "Use StreamErrorFlush, StreamErrorReport or StreamErrorRepair")
+			return
+		}
+		a.streamErrorPolicy = policy
+	}
+}
+
+// WithStreamEOFPolicy sets what a ToolCollectThenStop StreamAdapter does when the
+// upstream ends, with or without a finish chunk, while a tool call collection is
+// incomplete, such as a JSON array of calls that was never closed:
+//   - StreamEOFEmitCalls: emit the calls parsed so far and drop the incomplete one,
+//     or emit the buffered text as content when there are none
+//   - StreamEOFDropCalls: discard the collection, emitting neither calls nor text
+//   - StreamEOFFlushText: emit the text of the whole collection as content
+//
+// A collection whose buffer ends in complete calls is always emitted, and upstream
+// failures are handled by WithStreamErrorPolicy instead.
+//
+// Default: StreamEOFEmitCalls
+func WithStreamEOFPolicy(policy StreamEOFPolicy) Option {
+	return func(a *Adapter) {
+		if policy < StreamEOFEmitCalls || policy > StreamEOFFlushText {
+			a.logger.Warn("Unknown stream EOF policy",
+				"supplied_policy", policy.String(),
+				"implication", "The previous policy is kept",
+				"recommendation", "Use StreamEOFEmitCalls, StreamEOFDropCalls or StreamEOFFlushText")
+			return
+		}
+		a.streamEOFPolicy = policy
+	}
+}
+
 // WithPromptBufferReuseLimit sets the maximum size of prompt generation buffers
 // that will be returned to the buffer pool for reuse. Larger buffers are discarded
 // to prevent the buffer pool from growing unbounded when processing very large
@@ -390,6 +1660,25 @@ func WithPromptBufferReuseLimit(thresholdBytes int) Option {
 	}
 }
 
+// WithBatchConcurrency sets the number of worker goroutines used by the batch
+// transformations, TransformCompletionsRequests and TransformCompletionsResponses.
+// Workers share the adapter's buffer pool, so a small pool handles bursts with few
+// allocations. Values below 1 are ignored with a warning.
+//
+// Default: runtime.GOMAXPROCS(0)
+func WithBatchConcurrency(workers int) Option {
+	return func(a *Adapter) {
+		if workers < 1 {
+			a.logger.Warn("Invalid batch concurrency ignored",
+				"supplied_workers", workers,
+				"implication", "Batch transformations use the previous worker count",
+				"recommendation", "Supply a positive worker count to WithBatchConcurrency()")
+			return
+		}
+		a.batchConcurrency = workers
+	}
+}
+
 // WithNoSystemInstructionRole sets which role to use when no system message is present.
 // Default is false to support models that ignore or lack a system role (e.g., Gemma 3),
 // but you should set this to true if your model supports or requires a system message.
@@ -398,3 +1687,546 @@ func WithSystemMessageSupport(supported bool) Option {
 		a.systemMessagesSupported = supported
 	}
 }
+
+// WithDeveloperMessageSupport injects tool instructions into developer messages, for
+// OpenAI-compatible servers that distinguish the developer role from the system role.
+// The instructions are appended to the last developer message; without one, they are
+// appended to the last system message, and without either a developer message is
+// created at the start of the conversation. This takes precedence over
+// WithInjectionPosition selects where the tool prompt is injected. The default
+// PositionAuto appends it to the system message, which suits most chat templates;
+// models that pay most attention to the end of the prompt follow the tool
+// instructions more reliably with PositionLastUser. When the conversation lacks
+// the message a position needs, such as a user message, or the model cannot be
+// given a new system message (see WithSystemMessageSupport), the prompt is placed
+// as with PositionAuto.
+//
+// PositionLastUser moves the prompt with every turn, so providers cannot reuse a
+// cached prompt prefix across turns.
+//
+// Default: PositionAuto
+func WithInjectionPosition(position InjectionPosition) Option {
+	return func(a *Adapter) {
+		if position < PositionAuto || position > PositionFirstUser {
+			a.logger.Warn("Unknown injection position",
+				"supplied_position", position.String(),
+				"implication", "The previous position is kept",
+				"recommendation", "Use PositionAuto, PositionSystemEnd, PositionSystemStart, PositionLastUser or PositionFirstUser")
+			return
+		}
+		a.injectionPosition = position
+	}
+}
+
+// WithSystemMessageSupport for the role of created messages.
+//
+// Default: false
+func WithDeveloperMessageSupport(supported bool) Option {
+	return func(a *Adapter) {
+		a.developerMessagesSupported = supported
+	}
+}
+
+// WithJSONModeEmulation emulates response_format json_object for backends that
+// ignore it. Requests that set it and have no tools get JSONModePrompt injected like
+// tool instructions, and the content of each reply is replaced with the JSON object
+// found in it, using the tool call parser:
+//   - JSONModeOff: pass json_object requests and replies through
+//   - JSONModeExtract: inject and extract; leave replies without an object unchanged
+//   - JSONModeError: inject and extract; fail with ErrInvalidJSONResponse otherwise
+//
+// The response side never sees the request, so the setting must be attached to the
+// context passed to the response transformation with ContextWithJSONMode; Sessions
+// and CompleteWithRetry, which re-asks the model under JSONModeError, do so
+// automatically. Streams are not affected. Requests with tools keep the tool prompt.
+//
+// Default: JSONModeOff
+func WithJSONModeEmulation(policy JSONModePolicy) Option {
+	return func(a *Adapter) {
+		if policy < JSONModeOff || policy > JSONModeError {
+			a.logger.Warn("Unknown JSON mode policy",
+				"supplied_policy", policy.String(),
+				"implication", "The previous policy is kept",
+				"recommendation", "Use JSONModeOff, JSONModeExtract or JSONModeError")
+			return
+		}
+		a.jsonMode = policy
+	}
+}
+
+// WithPreserveToolsField keeps Tools, ToolChoice and ParallelToolCalls on transformed
+// requests while still injecting the tool prompt. Use it with backends that ignore
+// unknown fields, or behind a proxy that decides later whether to use native function
+// calling, for example while A/B testing a migration. Responses are parsed as usual,
+// and native tool calls in a response pass through unchanged.
+//
+// Default: false
+func WithPreserveToolsField(preserve bool) Option {
+	return func(a *Adapter) {
+		a.preserveToolsField = preserve
+	}
+}
+
+// WithPromptCompaction wraps injected tool instructions in sentinel markers and removes
+// any previously injected blocks from the conversation history before adding fresh ones.
+//
+// Callers commonly pass back the transformed messages from earlier turns, so without
+// compaction each turn adds another copy of the tool definitions to the history. When
+// enabled, system and user messages are scanned for marked blocks; the blocks are
+// removed and messages left empty, such as instruction messages created by the
+// adapter, are dropped. Only blocks injected with markers enabled can be recognized.
+func WithPromptCompaction(enabled bool) Option {
+	return func(a *Adapter) {
+		a.promptCompaction = enabled
+		if enabled {
+			a.markInjectedContent = true
+		}
+	}
+}
+
+// WithInjectionMarkers wraps all adapter-injected text (tool definitions and tool
+// results) in the given sentinel markers, so it can later be removed with
+// StripInjectedContent or WithPromptCompaction. Pass DefaultInjectionBeginMarker and
+// DefaultInjectionEndMarker to enable marking with the default markers.
+//
+// Pick markers that the model is unlikely to echo and that never occur in user text.
+// Empty or identical markers are ignored with a warning.
+func WithInjectionMarkers(begin, end string) Option {
+	return func(a *Adapter) {
+		if begin == "" || end == "" || begin == end {
+			a.logger.Warn("Invalid injection markers ignored",
+				"begin", begin,
+				"end", end)
+			return
+		}
+		a.markInjectedContent = true
+		a.injectionBeginMarker = begin
+		a.injectionEndMarker = end
+	}
+}
+
+// WithSanitizeToolDefinitions strips instruction-like content from tool definitions
+// before they are injected into the prompt. Tool descriptions often come from
+// configuration that may include user-supplied text, and anything injected reads to
+// the model as part of its instructions. When enabled, function descriptions and the
+// "description" and "title" strings of parameter schemas have:
+//   - control and invisible formatting characters removed
+//   - chat template tokens such as <|im_start|> or [INST] and "system:" line prefixes removed
+//   - overrides such as "ignore previous instructions" replaced with "[removed]"
+//   - backticks replaced with single quotes
+//   - line breaks collapsed into spaces
+//
+// Each altered field is logged as a warning and reported in a ToolSanitizationData
+// metric. SanitizeToolDefinitions applies the same rules without an adapter.
+// Sanitization is pattern-based and reduces, rather than removes, the risk of
+// injected instructions.
+//
+// Default: false
+func WithSanitizeToolDefinitions(enabled bool) Option {
+	return func(a *Adapter) {
+		a.sanitizeToolDefinitions = enabled
+	}
+}
+
+// WithToolSelector prunes the tools injected for each request to those the selector
+// returns, reducing prompt size when an application registers many tools. Use
+// KeywordToolSelector for keyword relevance against the last user message, or
+// supply a custom selector, for example one ranking tools by embedding similarity.
+//
+// The selector sees the caller's tools before WithToolNamespace is applied. A tool
+// forced by the request's tool choice is kept even if the selector drops it. If no
+// tools remain, the request is sent without tool instructions. Pass nil to disable.
+//
+// Default: nil (all tools are injected)
+func WithToolSelector(selector ToolSelector) Option {
+	return func(a *Adapter) {
+		a.toolSelector = selector
+	}
+}
+
+// WithToolResultTransformer rewrites the content of each tool result before it is
+// injected into the prompt, so large outputs such as big API payloads do not
+// exhaust the model's context. Use HeadTailToolResultTransformer to keep the start
+// and end of long results, or supply a custom transformer, for example one that
+// summarizes JSON payloads. The transformer runs for every request carrying tool
+// messages, including with WithToolPromptTemplate. Pass nil to disable.
+//
+// Default: nil (tool results are injected unchanged)
+func WithToolResultTransformer(transformer ToolResultTransformer) Option {
+	return func(a *Adapter) {
+		a.toolResultTransformer = transformer
+	}
+}
+
+// WithMultimodalToolResults re-injects images, audio and files returned by tools as
+// content parts instead of flattening them to text. A tool result whose content is
+// a JSON array of chat completion content parts, as json.Marshal encodes a
+// []openai.ChatCompletionContentPartUnionParam, or a single image_url, input_audio
+// or file part, has its text parts injected into the prompt as usual with a
+// numbered placeholder for each other part. The other parts are sent in a user
+// message appended to the conversation, each after its placeholder, so the model
+// can relate them to the results. Enable it only for vision or audio models; other
+// results are injected unchanged.
+//
+// Default: false (tool results are injected as text)
+func WithMultimodalToolResults(enabled bool) Option {
+	return func(a *Adapter) {
+		a.multimodalToolResults = enabled
+	}
+}
+
+// WithNativePassthrough lets one adapter serve backends with and without native
+// function calling. For models the function reports as native, requests are
+// forwarded with their tools, tool choice and tool messages untouched, and
+// responses are returned without parsing. The function receives the request's
+// model for requests and the model reported by the response otherwise: the first
+// chunk of a StreamAdapter or the stream of SSEStreamAdapter.Process. Providers
+// may report a dated model name such as "gpt-4o-2024-08-06", so match by prefix
+// where needed. Pass nil to disable.
+//
+// Default: nil (every model is adapted)
+func WithNativePassthrough(native func(model string) bool) Option {
+	return func(a *Adapter) {
+		a.nativePassthrough = native
+	}
+}
+
+// WithToolNamespace exposes every function tool to the model as "prefix.name",
+// using the MCP naming convention. The prefix is removed again from function names
+// parsed out of responses, so callers always see the original tool names.
+//
+// This is useful when tools from several sources are combined and a source label
+// helps the model, or to keep generic names such as "search" from clashing with
+// words the model would otherwise emit. The prefix must be alphanumeric; invalid
+// prefixes are ignored with a warning.
+//
+// Default: "" (no namespace)
+func WithToolNamespace(prefix string) Option {
+	return func(a *Adapter) {
+		// Validating a placeholder name reuses the MCP prefix rules (alphanumeric, length)
+		if prefix != "" && ValidateFunctionName(prefix+".x") != nil {
+			a.logger.Warn("Invalid tool namespace ignored",
+				"supplied_namespace", prefix,
+				"implication", "Tool names will not be namespaced",
+				"recommendation", "Supply an alphanumeric prefix to WithToolNamespace()")
+			return
+		}
+		a.toolNamespace = prefix
+	}
+}
+
+// WithToolCollisionPolicy controls how duplicate function names within a single
+// request are handled. Names are compared after any namespace has been applied.
+//
+// Available policies:
+//   - ToolCollisionError: TransformCompletionsRequest returns a *ToolNameCollisionError
+//   - ToolCollisionRename: duplicates are renamed "name__2", "name__3", ... by their
+//     position among the definitions sharing the name, and tool calls keep that name
+//
+// Default: ToolCollisionError
+func WithToolCollisionPolicy(policy ToolCollisionPolicy) Option {
+	return func(a *Adapter) {
+		a.toolCollisionPolicy = policy
+	}
+}
+
+// WithUnsupportedToolPolicy sets how tools other than function tools, such as the
+// custom tools of the Chat Completions API, are handled. The injected prompt can only
+// describe functions with JSON parameters, so by default such tools are left out with
+// a warning and the model never sees them.
+//
+// Default: UnsupportedToolSkip
+func WithUnsupportedToolPolicy(policy UnsupportedToolPolicy) Option {
+	return func(a *Adapter) {
+		a.unsupportedToolPolicy = policy
+	}
+}
+
+// WithAllowedToolNames restricts parsed tool calls to the given function names.
+// Calls to any other function, such as names hallucinated by the model, are handled
+// according to WithUnknownToolPolicy (dropped by default) before they reach the
+// caller. If every call in a response is dropped, the response is returned as
+// regular content.
+//
+// Names are matched against the caller's tool names, after any namespace has been
+// removed; duplicates renamed by ToolCollisionRename are matched by their renamed
+// names. Passing nil or an empty slice removes the restriction.
+//
+// Default: all function names are allowed
+func WithAllowedToolNames(names []string) Option {
+	return func(a *Adapter) {
+		if len(names) == 0 {
+			a.allowedToolNames = nil
+			return
+		}
+		a.allowedToolNames = make(map[string]struct{}, len(names))
+		for _, name := range names {
+			a.allowedToolNames[name] = struct{}{}
+		}
+	}
+}
+
+// WithToolCallFilter registers a predicate that decides whether a parsed tool call is
+// kept. It is called with the function name and raw JSON arguments (nil when the model
+// supplied none) and runs after WithAllowedToolNames. Returning false drops the call;
+// if every call in a response is dropped, the response is returned as regular content.
+//
+// The filter must be safe for concurrent use. A panicking filter drops the call.
+//
+// Default: nil (no custom filtering)
+func WithToolCallFilter(filter func(name string, args json.RawMessage) bool) Option {
+	return func(a *Adapter) {
+		a.toolCallFilter = filter
+	}
+}
+
+// WithToolCallInterceptor registers a hook, such as a human-in-the-loop approval
+// step, that decides on every parsed tool call before it is emitted into the
+// transformed response or stream. It runs last, after WithToolCallFilter and the
+// parallel tool call limit, and can:
+//   - approve the call (ToolCallApprove)
+//   - replace its arguments (ToolCallRewrite)
+//   - reject it (ToolCallReject), which returns the response as regular content
+//
+// An error from the interceptor fails the transformation; a panic rejects the call.
+// A stream's Next blocks while the interceptor runs. The interceptor must be safe
+// for concurrent use.
+//
+// Default: nil (calls are emitted without interception)
+func WithToolCallInterceptor(interceptor ToolCallInterceptor) Option {
+	return func(a *Adapter) {
+		a.toolCallInterceptor = interceptor
+	}
+}
+
+// WithToolArgumentModes sets how the model passes the arguments of the named tools.
+// Tools in ArgumentModeText take one raw text argument, such as a SQL query, a
+// Markdown document or source code, which the model writes as the body of a fenced
+// code block tagged with the tool's name instead of escaping it into JSON:
+//
+//	```run_sql
+//	SELECT name FROM users WHERE email LIKE '%@example.com'
+//	```
+//
+// The call is delivered with the block's body as the TextArgumentKey argument,
+// {"input": "..."}, so declare the tool with a single string parameter of that name.
+// The injected prompt tells the model which tools take text; a JSON call to them is
+// still accepted. Names are the caller's function names, without any
+// WithToolNamespace prefix. Tools not named keep ArgumentModeJSON. Passing nil or an
+// empty map makes every tool take JSON.
+//
+// Default: nil (every tool takes JSON)
+func WithToolArgumentModes(modes map[string]ArgumentMode) Option {
+	return func(a *Adapter) {
+		if len(modes) == 0 {
+			a.argumentModes = nil
+			return
+		}
+		a.argumentModes = make(map[string]ArgumentMode, len(modes))
+		for name, mode := range modes {
+			if mode != ArgumentModeJSON && mode != ArgumentModeText {
+				a.logger.Warn("Unknown argument mode",
+					"supplied_tool", name,
+					"supplied_mode", mode.String(),
+					"implication", "The tool takes JSON arguments",
+					"recommendation", "Use ArgumentModeJSON or ArgumentModeText")
+				continue
+			}
+			a.argumentModes[name] = mode
+		}
+	}
+}
+
+// WithCodeBlockTools makes fenced code blocks tagged with a registered language
+// calls to the tool that runs that language, the convention of code interpreter
+// agents. With {"python": {Name: "execute_python"}} the reply
+//
+//	```python
+//	print(sum(range(10)))
+//	```
+//
+// is delivered as an execute_python call with the block's body as its Argument,
+// {"code": "print(sum(range(10)))"} by default. Language tags match
+// case-insensitively; register aliases such as "py" as languages of their own. The
+// injected prompt tells the model about every language whose tool the request
+// offers, but blocks are parsed as calls whenever the language is registered, so
+// only register languages whose blocks should always run. Passing nil or an empty
+// map disables the convention.
+//
+// Default: nil (code blocks are content)
+func WithCodeBlockTools(tools map[string]CodeBlockTool) Option {
+	return func(a *Adapter) {
+		if len(tools) == 0 {
+			a.codeBlockTools = nil
+			return
+		}
+		a.codeBlockTools = make(map[string]CodeBlockTool, len(tools))
+		for language, tool := range tools {
+			if language == "" || strings.ContainsAny(language, " \t\r\n`") || tool.Name == "" {
+				a.logger.Warn("Invalid code block tool",
+					"supplied_language", language,
+					"supplied_tool", tool.Name,
+					"implication", "Code blocks of this language are content",
+					"recommendation", "Use a language tag without whitespace or backticks and a tool name")
+				continue
+			}
+			if tool.Argument == "" {
+				tool.Argument = CodeBlockArgumentKey
+			}
+			a.codeBlockTools[strings.ToLower(language)] = tool
+		}
+	}
+}
+
+// WithArgumentCoercion rewrites parsed tool call arguments to match the parameter
+// schema of the tool they call, so strict executors can unmarshal them directly:
+//   - strings holding numbers or booleans become integers, numbers or booleans,
+//     and integral numbers such as 5.0 become integers
+//   - numbers and booleans become strings where a string is declared
+//   - properties not declared in an object with "additionalProperties": false are dropped
+//   - missing properties with a declared "default" are filled in
+//
+// Nested objects and array items are coerced against their own schemas. Values that
+// cannot be converted are left unchanged, and coerced arguments are re-encoded as
+// compact JSON with sorted keys.
+//
+// The response side never sees the request, so the schemas must be attached to the
+// context passed to the response or streaming transformation with ContextWithTools.
+// Without them arguments are left unchanged.
+//
+// Default: false
+func WithArgumentCoercion(enabled bool) Option {
+	return func(a *Adapter) {
+		a.argumentCoercion = enabled
+	}
+}
+
+// WithArgumentViolationPolicy checks tool call arguments against the enum, minimum,
+// maximum, exclusiveMinimum, exclusiveMaximum, minLength and maxLength keywords of
+// the tool's schema, including nested objects and array items, and selects what
+// happens when they are violated:
+//   - ArgumentViolationIgnore: forward the arguments unchecked
+//   - ArgumentViolationReport: forward them, log a warning and emit ArgumentViolationData
+//   - ArgumentViolationError: fail with *ToolArgumentError
+//
+// Like WithArgumentCoercion, checking needs the schemas attached with
+// ContextWithTools, and runs after coercion when both are enabled. Violations can
+// also be inspected on any transformed response with ArgumentViolations.
+//
+// Default: ArgumentViolationIgnore
+func WithArgumentViolationPolicy(policy ArgumentViolationPolicy) Option {
+	return func(a *Adapter) {
+		a.argumentViolationPolicy = policy
+	}
+}
+
+// WithArgumentLimits bounds parsed tool call arguments, so adversarial or runaway
+// model output cannot overwhelm downstream unmarshalers: their encoded size, the
+// nesting depth of objects and arrays, and the length of every array. Arguments are
+// scanned without being decoded, before coercion and schema checks.
+//
+// A call exceeding a limit is reported like a schema violation, with a warning and
+// an ArgumentViolationData metric whose Constraint is ConstraintMaxBytes,
+// ConstraintMaxDepth or ConstraintMaxItems. Under ArgumentViolationError the
+// transformation fails with *ToolArgumentError; otherwise the call is dropped.
+// Limits do not need ContextWithTools. Negative limits are treated as zero.
+//
+// Default: ArgumentLimits{} (unlimited)
+func WithArgumentLimits(limits ArgumentLimits) Option {
+	return func(a *Adapter) {
+		for _, limit := range []struct {
+			name  string
+			value *int
+		}{
+			{"MaxBytes", &limits.MaxBytes},
+			{"MaxDepth", &limits.MaxDepth},
+			{"MaxArrayLength", &limits.MaxArrayLength},
+		} {
+			if *limit.value < 0 {
+				a.logger.Warn("Negative argument limit",
+					"limit", limit.name,
+					"supplied_value", *limit.value,
+					"updated_value", 0,
+					"implication", "The limit is disabled",
+					"recommendation", "Supply a positive limit to WithArgumentLimits()")
+				*limit.value = 0
+			}
+		}
+		a.argumentLimits = limits
+	}
+}
+
+// WithToolCallDeduplication collapses identical tool calls within a single response
+// or stream. Smaller models often repeat a call, for example once in prose and once
+// in a code fence, or twice in the same array. Calls are identical when their names
+// match and their arguments are equal JSON, ignoring key order and whitespace; the
+// first occurrence is kept. The number of removed calls is reported in the
+// DuplicatesRemoved field of FunctionCallDetectionData.
+//
+// Default: false
+func WithToolCallDeduplication(enabled bool) Option {
+	return func(a *Adapter) {
+		a.toolCallDedup = enabled
+	}
+}
+
+// WithToolCallRateLimit caps the tool calls delivered to the caller, protecting agent
+// loops from models that keep calling tools. maxPerResponse limits the calls in a
+// single response or stream (per choice). maxPerConversation limits the calls across
+// all turns of a conversation tracked by a Conversation attached to the context with
+// ContextWithConversation; without one only the per-response limit applies. Zero
+// disables a limit.
+//
+// Calls beyond a limit are suppressed: they are removed from the response, a warning
+// is logged and a ToolCallRateLimitData metric is emitted. A response left without
+// tool calls finishes with "stop". Negative limits are ignored with a warning.
+//
+// Default: no limits
+func WithToolCallRateLimit(maxPerResponse, maxPerConversation int) Option {
+	return func(a *Adapter) {
+		if maxPerResponse < 0 || maxPerConversation < 0 {
+			a.logger.Warn("Negative tool call rate limit ignored",
+				"max_per_response", maxPerResponse,
+				"max_per_conversation", maxPerConversation,
+				"implication", "The previous limits are kept",
+				"recommendation", "Use 0 to disable a limit in WithToolCallRateLimit()")
+			return
+		}
+		a.maxToolCallsPerResponse = maxPerResponse
+		a.maxToolCallsPerConversation = maxPerConversation
+	}
+}
+
+// WithUnknownToolPolicy selects how tool calls to functions outside the allowed list
+// are handled. It has no effect unless WithAllowedToolNames is also set.
+//
+// Available policies:
+//   - UnknownToolDrop: drop the unknown call, keep the rest
+//   - UnknownToolAsContent: return the whole response as regular content
+//   - UnknownToolError: fail with *UnknownToolCallError
+//   - UnknownToolCorrect: fuzzy-match to the closest allowed name (see WithUnknownToolMatchThreshold)
+//
+// Default: UnknownToolDrop
+func WithUnknownToolPolicy(policy UnknownToolPolicy) Option {
+	return func(a *Adapter) {
+		a.unknownToolPolicy = policy
+	}
+}
+
+// WithUnknownToolMatchThreshold sets the minimum similarity, between 0 and 1, required
+// for UnknownToolCorrect to rename an unknown call. Similarity is the case-insensitive
+// edit distance normalized by name length, so 1 means identical names.
+//
+// Default: 0.8
+func WithUnknownToolMatchThreshold(threshold float64) Option {
+	return func(a *Adapter) {
+		if threshold < 0 || threshold > 1 {
+			a.logger.Warn("Unknown tool match threshold out of range",
+				"supplied_threshold", threshold,
+				"implication", "The previous threshold is kept",
+				"recommendation", "Supply a value between 0 and 1 to WithUnknownToolMatchThreshold()")
+			return
+		}
+		a.unknownToolMatchThreshold = threshold
+	}
+}
@@ -0,0 +1,70 @@
+package tooladapter
+
+import (
+	"context"
+
+	"github.com/openai/openai-go/v3"
+)
+
+// parallelToolCallsKey is the context key for the setting attached by
+// ContextWithParallelToolCalls.
+type parallelToolCallsKey struct{}
+
+// ContextWithParallelToolCalls returns a copy of ctx carrying a request's
+// parallel_tool_calls setting, so response transformation can enforce it. With
+// enabled false, only the first tool call of a response is delivered, matching
+// OpenAI semantics for single-call workflows. Pass the setting of the original
+// request to the response or streaming transformation that handles its reply:
+//
+//	ctx = tooladapter.ContextWithParallelToolCalls(ctx, req.ParallelToolCalls.Or(true))
+//	resp, err := adapter.TransformCompletionsResponseWithContext(ctx, completion)
+//
+// Sessions and CompleteWithRetry attach the request's setting automatically.
+func ContextWithParallelToolCalls(ctx context.Context, enabled bool) context.Context {
+	return context.WithValue(ctx, parallelToolCallsKey{}, enabled)
+}
+
+// singleToolCall reports whether ctx restricts responses to one tool call.
+func singleToolCall(ctx context.Context) bool {
+	if ctx == nil {
+		return false
+	}
+	enabled, ok := ctx.Value(parallelToolCallsKey{}).(bool)
+	return ok && !enabled
+}
+
+// requestsSingleToolCall reports whether req sets parallel_tool_calls to false.
+func requestsSingleToolCall(req openai.ChatCompletionNewParams) bool {
+	return req.ParallelToolCalls.Valid() && !req.ParallelToolCalls.Value
+}
+
+// appendSingleToolCall adds the single-call instruction of the prompt language to a
+// tool prompt when req disables parallel tool calls.
+func (a *Adapter) appendSingleToolCall(req openai.ChatCompletionNewParams, toolPrompt string) string {
+	if !requestsSingleToolCall(req) {
+		return toolPrompt
+	}
+	return toolPrompt + "\n\n" + a.language().singleToolCall
+}
+
+// maxToolCalls returns the most tool calls a response may deliver: one when ctx
+// disables parallel tool calls, otherwise the WithToolMaxCalls limit (0 means
+// unlimited).
+func (a *Adapter) maxToolCalls(ctx context.Context) int {
+	if singleToolCall(ctx) {
+		return 1
+	}
+	return a.toolMaxCalls
+}
+
+// limitToSingleCall keeps the first call when ctx disables parallel tool calls.
+func (a *Adapter) limitToSingleCall(ctx context.Context, calls []functionCall) []functionCall {
+	if len(calls) <= 1 || !singleToolCall(ctx) {
+		return calls
+	}
+	a.log(LogCategoryLimit).Debug("Parallel tool calls disabled, keeping the first call",
+		"original_calls", len(calls),
+		"function_name", calls[0].Name)
+	recordDroppedCalls(ctx, calls[1:], DropReasonSingleCall)
+	return calls[:1]
+}
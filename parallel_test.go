@@ -0,0 +1,66 @@
+package tooladapter_test
+
+import (
+	"context"
+	"testing"
+
+	tooladapter "github.com/juburr/openai-tool-adapter/v3"
+	"github.com/juburr/openai-tool-adapter/v3/tooltest"
+	"github.com/openai/openai-go/v3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const twoCalls = `[{"name": "get_weather", "parameters": {"param1": "Paris"}}, {"name": "get_weather", "parameters": {"param1": "Rome"}}]`
+
+func TestParallelToolCallsDisabled(t *testing.T) {
+	adapter := tooladapter.New(
+		tooladapter.WithSystemMessageSupport(true),
+		tooladapter.WithToolPolicy(tooladapter.ToolCollectThenStop))
+
+	t.Run("Prompt", func(t *testing.T) {
+		req := tooltest.Request(tooltest.Tool("get_weather", "Get the weather"))
+		req.ParallelToolCalls = openai.Bool(false)
+		transformed, err := adapter.TransformCompletionsRequest(req)
+		require.NoError(t, err)
+		assert.Contains(t, systemPrompt(t, transformed), "Return at most one function call")
+		assert.False(t, transformed.ParallelToolCalls.Valid(), "the field is not sent without tools")
+
+		req.ParallelToolCalls = openai.Bool(true)
+		transformed, err = adapter.TransformCompletionsRequest(req)
+		require.NoError(t, err)
+		assert.NotContains(t, systemPrompt(t, transformed), "Return at most one function call")
+	})
+
+	t.Run("Response", func(t *testing.T) {
+		ctx := tooladapter.ContextWithParallelToolCalls(context.Background(), false)
+		resp, err := adapter.TransformCompletionsResponseWithContext(ctx, tooltest.Completion(twoCalls))
+		require.NoError(t, err)
+		calls := resp.Choices[0].Message.ToolCalls
+		require.Len(t, calls, 1)
+		assert.Contains(t, calls[0].Function.Arguments, "Paris")
+
+		resp, err = adapter.TransformCompletionsResponse(tooltest.Completion(twoCalls))
+		require.NoError(t, err)
+		assert.Len(t, resp.Choices[0].Message.ToolCalls, 2, "parallel calls are allowed by default")
+	})
+
+	t.Run("Streaming", func(t *testing.T) {
+		ctx := tooladapter.ContextWithParallelToolCalls(context.Background(), false)
+		result := tooltest.Drain(adapter.TransformStreamingResponseWithContext(ctx, tooltest.NewContentStream(twoCalls)))
+		require.NoError(t, result.Err)
+		require.Len(t, result.ToolCalls, 1)
+		assert.Contains(t, result.ToolCalls[0].Function.Arguments, "Paris")
+	})
+
+	t.Run("Session", func(t *testing.T) {
+		session := adapter.NewSession()
+		req := tooltest.Request(tooltest.Tool("get_weather", "Get the weather"))
+		req.ParallelToolCalls = openai.Bool(false)
+		_, err := session.TransformRequest(context.Background(), req)
+		require.NoError(t, err)
+		resp, err := session.TransformResponse(context.Background(), tooltest.Completion(twoCalls))
+		require.NoError(t, err)
+		assert.Len(t, resp.Choices[0].Message.ToolCalls, 1)
+	})
+}
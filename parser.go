@@ -2,11 +2,12 @@ package tooladapter
 
 import (
 	"encoding/json"
-	"errors"
 	"fmt"
 	"regexp"
+	"slices"
 	"strings"
 	"sync"
+	"unicode/utf8"
 )
 
 // candidatePool recycles JSONCandidate objects to reduce allocations and GC pressure.
@@ -96,6 +97,114 @@ func (je *JSONExtractor) ExtractJSONBlocks() []string {
 	return results
 }
 
+// mayContainJSON reports whether s contains a JSON opener. It lets callers skip the
+// state machine, and its rune conversion, for plain-text content.
+func mayContainJSON(s string) bool {
+	return strings.IndexByte(s, '{') >= 0 || strings.IndexByte(s, '[') >= 0
+}
+
+// extractJSONBlocks is the fast equivalent of NewJSONExtractor(content).ExtractJSONBlocks()
+// used on the hot parsing path. Content without a JSON opener returns immediately;
+// otherwise the byte-level scanner skips straight to candidate regions.
+func extractJSONBlocks(content string) []string {
+	if !mayContainJSON(content) {
+		return nil
+	}
+	return scanJSONBlocks(content)
+}
+
+// extractJSONBlockFormats is extractJSONBlocks that also returns the format of every
+// candidate.
+func extractJSONBlockFormats(content string) ([]string, []CallFormat) {
+	if !mayContainJSON(content) {
+		return nil, nil
+	}
+	var formats []CallFormat
+	candidates := scanJSONBlockFormats(content, &formats)
+	return candidates, formats
+}
+
+// prefilled returns model output with the WithAssistantPrefill text it continues
+// restored, unless the model repeated the prefill itself.
+func (a *Adapter) prefilled(content string) string {
+	if a.assistantPrefill == "" || strings.HasPrefix(strings.TrimLeft(content, " \t\r\n"), a.assistantPrefill) {
+		return content
+	}
+	return a.assistantPrefill + content
+}
+
+// extractCandidates returns the JSON candidates in model output, strict JSON first:
+//   - fenced tool calls lead the list
+//   - candidates found with each stripped tool stop sequence restored follow
+//   - when nothing is valid JSON, byte order marks, zero-width characters and
+//     full-width brackets are normalized
+//   - lenient repairs close the list
+//
+// WithParserPriority reorders the result. The streaming flag only labels parse events.
+func (a *Adapter) extractCandidates(content string, streaming bool) []string {
+	content = a.prefilled(content)
+	candidates, formats := a.contentCandidates(content)
+	for _, seq := range a.toolStopSequences {
+		if strings.HasSuffix(content, seq) {
+			continue
+		}
+		restored, restoredFormats := a.contentCandidates(content + seq)
+		for i, candidate := range restored {
+			if !slices.Contains(candidates, candidate) {
+				candidates = append(candidates, candidate)
+				formats = append(formats, restoredFormats[i])
+			}
+		}
+	}
+	if normalized, changed := normalizeToolText(content); changed && !slices.ContainsFunc(candidates, isValidJSON) {
+		normalizedCandidates, normalizedFormats := extractJSONBlockFormats(normalized)
+		for i, candidate := range normalizedCandidates {
+			if slices.Contains(candidates, candidate) {
+				continue
+			}
+			a.emitParseEvent(ParseEvent{
+				Type:      ParseEventRepairApplied,
+				Size:      len(candidate),
+				Streaming: streaming,
+				Detail:    ParseDetailNormalized,
+			})
+			candidates = append(candidates, candidate)
+			formats = append(formats, normalizedFormats[i])
+		}
+		content = normalized
+	}
+	if a.lenientParsing {
+		repaired := lenientCandidates(content, candidates)
+		for _, candidate := range repaired {
+			a.emitParseEvent(ParseEvent{
+				Type:      ParseEventRepairApplied,
+				Size:      len(candidate),
+				Streaming: streaming,
+				Detail:    ParseDetailLenient,
+			})
+			formats = append(formats, CallFormatLenient)
+		}
+		candidates = append(candidates, repaired...)
+	}
+	return a.prioritizeCandidates(candidates, formats)
+}
+
+// contentCandidates extracts the JSON blocks in content, led by the array of any
+// fenced tool calls.
+func (a *Adapter) contentCandidates(content string) ([]string, []CallFormat) {
+	candidates, formats := extractJSONBlockFormats(content)
+	if fenced := a.fencedCandidate(content); fenced != "" {
+		candidates = append([]string{fenced}, candidates...)
+		formats = append([]CallFormat{CallFormatTextBlock}, formats...)
+	}
+	return candidates, formats
+}
+
+// isValidJSON reports whether candidate is valid JSON.
+func isValidJSON(candidate string) bool {
+	return json.Valid([]byte(candidate))
+}
+
 // extractAllCandidates performs a single pass over the input, parsing both
 // markdown-enclosed and standalone JSON structures.
 func (je *JSONExtractor) extractAllCandidates() []*JSONCandidate {
@@ -145,9 +254,9 @@ func (je *JSONExtractor) extractAllCandidates() []*JSONCandidate {
 // NOTE: This function does NOT advance the main extractor's position (je.pos).
 func (je *JSONExtractor) parseTripleBacktickBlock(start int) *JSONCandidate {
 	i := start + 3 // Skip opening ```
-	// Optional language specifier "json"
-	if i+4 <= je.length && je.input[i] == 'j' && je.input[i+1] == 's' && je.input[i+2] == 'o' && je.input[i+3] == 'n' {
-		i += 4
+	// Optional language specifier, such as "json", "JSON" or "json5"
+	for i < je.length && je.input[i] < utf8.RuneSelf && isFenceInfoByte(byte(je.input[i])) {
+		i++
 	}
 
 	// Skip whitespace until content starts
@@ -342,7 +451,7 @@ func isFunctionNameChar(r rune) bool {
 func validateCharacters(s string, isValid func(rune) bool, context, pattern string) error {
 	for _, r := range s {
 		if !isValid(r) {
-			return fmt.Errorf("function name validation failed: %s %q contains invalid characters, must match pattern %s", context, s, pattern)
+			return fmt.Errorf("%w: %s %q contains invalid characters, must match pattern %s", ErrToolValidationFailed, context, s, pattern)
 		}
 	}
 	return nil
@@ -352,7 +461,7 @@ func validateCharacters(s string, isValid func(rune) bool, context, pattern stri
 func validateMCPFormat(name string, dotIndex int) error {
 	// Check total length first
 	if len(name) > MaxFunctionNameLength {
-		return fmt.Errorf("function name validation failed: MCP format name %q is %d characters long but maximum allowed is %d", name, len(name), MaxFunctionNameLength)
+		return fmt.Errorf("%w: MCP format name %q is %d characters long but maximum allowed is %d", ErrToolValidationFailed, name, len(name), MaxFunctionNameLength)
 	}
 
 	prefix := name[:dotIndex]
@@ -360,24 +469,24 @@ func validateMCPFormat(name string, dotIndex int) error {
 
 	// Check for empty parts
 	if prefix == "" {
-		return fmt.Errorf("function name validation failed: MCP server prefix cannot be empty in %q", name)
+		return fmt.Errorf("%w: MCP server prefix cannot be empty in %q", ErrToolValidationFailed, name)
 	}
 	if funcName == "" {
-		return fmt.Errorf("function name validation failed: function name part cannot be empty in %q", name)
+		return fmt.Errorf("%w: function name part cannot be empty in %q", ErrToolValidationFailed, name)
 	}
 
 	// Check length limits
 	if len(prefix) > MaxPrefixLength {
-		return fmt.Errorf("function name validation failed: MCP server prefix %q is %d characters long but maximum allowed is %d", prefix, len(prefix), MaxPrefixLength)
+		return fmt.Errorf("%w: MCP server prefix %q is %d characters long but maximum allowed is %d", ErrToolValidationFailed, prefix, len(prefix), MaxPrefixLength)
 	}
 	if len(funcName) > MaxFunctionNameLength {
-		return fmt.Errorf("function name validation failed: function name part %q is %d characters long but maximum allowed is %d", funcName, len(funcName), MaxFunctionNameLength)
+		return fmt.Errorf("%w: function name part %q is %d characters long but maximum allowed is %d", ErrToolValidationFailed, funcName, len(funcName), MaxFunctionNameLength)
 	}
 
 	// Validate characters
 	for _, r := range prefix {
 		if !isAlphaNumeric(r) {
-			return fmt.Errorf("function name validation failed: MCP server prefix %q contains invalid characters, must only contain letters and numbers (a-zA-Z0-9)", prefix)
+			return fmt.Errorf("%w: MCP server prefix %q contains invalid characters, must only contain letters and numbers (a-zA-Z0-9)", ErrToolValidationFailed, prefix)
 		}
 	}
 	return validateCharacters(funcName, isFunctionNameChar, "function name part", "^[a-zA-Z0-9_-]{1,64}$")
@@ -386,7 +495,7 @@ func validateMCPFormat(name string, dotIndex int) error {
 // validateStandardFormat validates standard format names (no prefix)
 func validateStandardFormat(name string) error {
 	if len(name) > MaxFunctionNameLength {
-		return fmt.Errorf("function name validation failed: name %q is %d characters long but maximum allowed is %d", name, len(name), MaxFunctionNameLength)
+		return fmt.Errorf("%w: name %q is %d characters long but maximum allowed is %d", ErrToolValidationFailed, name, len(name), MaxFunctionNameLength)
 	}
 	return validateCharacters(name, isFunctionNameChar, "name", "^[a-zA-Z0-9_-]{1,64}$")
 }
@@ -395,7 +504,7 @@ func validateStandardFormat(name string) error {
 // This function is thread-safe and can be called concurrently.
 func ValidateFunctionName(name string) error {
 	if name == "" {
-		return errors.New("function name validation failed: name cannot be empty")
+		return fmt.Errorf("%w: name cannot be empty", ErrToolValidationFailed)
 	}
 
 	// Find dots to determine format
@@ -409,7 +518,7 @@ func ValidateFunctionName(name string) error {
 	}
 
 	if dotCount > 1 {
-		return fmt.Errorf("function name validation failed: name %q contains %d periods but only one is allowed for MCP server prefixes", name, dotCount)
+		return fmt.Errorf("%w: name %q contains %d periods but only one is allowed for MCP server prefixes", ErrToolValidationFailed, name, dotCount)
 	}
 
 	if dotIndex != -1 {
@@ -453,14 +562,27 @@ func ValidateFunctionCallArray(calls []functionCall) bool {
 // ExtractFunctionCallsDetailed attempts to parse function calls and returns whether
 // the matched JSON was an array (true) or a single object (false). Returns nil, false when no match.
 func ExtractFunctionCallsDetailed(candidates []string) ([]functionCall, bool) {
-	for _, candidate := range candidates {
+	calls, _, parser := matchFunctionCalls(candidates)
+	return calls, parser == ToolCallParserArray || parser == ToolCallParserToolCalls
+}
+
+// matchFunctionCalls parses function calls from the first candidate that holds any,
+// returning the index of that candidate and the ToolCallParser constant naming the
+// shape it matched. Returns nil, -1, "" when no candidate matches.
+func matchFunctionCalls(candidates []string) ([]functionCall, int, string) {
+	for i, candidate := range candidates {
+		// Every accepted shape carries a "name" key; skip other JSON without decoding it
+		if !strings.Contains(candidate, `"name"`) {
+			continue
+		}
+
 		// Try parsing as array first
 		var arrayCalls []functionCall
 		decoder := json.NewDecoder(strings.NewReader(candidate))
 		decoder.DisallowUnknownFields() // Reject objects with extra fields
 		if err := decoder.Decode(&arrayCalls); err == nil && len(arrayCalls) > 0 {
 			if ValidateFunctionCallArray(arrayCalls) { // Validates all required fields and content
-				return arrayCalls, true
+				return arrayCalls, i, ToolCallParserArray
 			}
 		}
 
@@ -470,11 +592,125 @@ func ExtractFunctionCallsDetailed(candidates []string) ([]functionCall, bool) {
 		decoder.DisallowUnknownFields() // Reject objects with extra fields
 		if err := decoder.Decode(&singleCall); err == nil {
 			if ValidateFunctionCall(singleCall) { // Validates required fields and content
-				return []functionCall{singleCall}, false
+				return []functionCall{singleCall}, i, ToolCallParserObject
 			}
 		}
+
+		// Try the legacy OpenAI {"function_call": {...}} shape
+		if call, ok := parseLegacyFunctionCall(candidate); ok {
+			return []functionCall{call}, i, ToolCallParserFunctionCall
+		}
+
+		// Try the API-style {"tool_calls": [...]} wrapper
+		if calls, ok := parseToolCallsWrapper(candidate); ok {
+			return calls, i, ToolCallParserToolCalls
+		}
+	}
+	return nil, -1, ""
+}
+
+// legacyFunctionCall mirrors the deprecated OpenAI "function_call" message field,
+// which some fine-tuned models still emit verbatim.
+type legacyFunctionCall struct {
+	FunctionCall *struct {
+		Name      string          `json:"name"`
+		Arguments json.RawMessage `json:"arguments"`
+	} `json:"function_call"`
+}
+
+// parseLegacyFunctionCall parses {"function_call": {"name": ..., "arguments": "{...}"}}
+// into a functionCall. Stringified arguments are unescaped into raw JSON.
+func parseLegacyFunctionCall(candidate string) (functionCall, bool) {
+	var legacy legacyFunctionCall
+	decoder := json.NewDecoder(strings.NewReader(candidate))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&legacy); err != nil || legacy.FunctionCall == nil {
+		return functionCall{}, false
+	}
+
+	args, ok := normalizeArguments(legacy.FunctionCall.Arguments)
+	if !ok {
+		return functionCall{}, false
+	}
+
+	call := functionCall{Name: legacy.FunctionCall.Name, Parameters: args}
+	if !ValidateFunctionCall(call) {
+		return functionCall{}, false
+	}
+	return call, true
+}
+
+// toolCallsWrapper mirrors the "tool_calls" field of an OpenAI assistant message,
+// which models familiar with the API schema sometimes emit as their response.
+type toolCallsWrapper struct {
+	ToolCalls []struct {
+		ID       string `json:"id"`
+		Type     string `json:"type"`
+		Function *struct {
+			Name      string          `json:"name"`
+			Arguments json.RawMessage `json:"arguments"`
+		} `json:"function"`
+	} `json:"tool_calls"`
+}
+
+// parseToolCallsWrapper parses {"tool_calls": [{"id": ..., "type": "function",
+// "function": {"name": ..., "arguments": ...}}]} into function calls. Model-supplied
+// IDs are preserved. Every entry must be a valid function call for the wrapper to match.
+func parseToolCallsWrapper(candidate string) ([]functionCall, bool) {
+	var wrapper toolCallsWrapper
+	decoder := json.NewDecoder(strings.NewReader(candidate))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&wrapper); err != nil || len(wrapper.ToolCalls) == 0 {
+		return nil, false
+	}
+
+	calls := make([]functionCall, 0, len(wrapper.ToolCalls))
+	for _, toolCall := range wrapper.ToolCalls {
+		if toolCall.Function == nil || (toolCall.Type != "" && toolCall.Type != functionType) {
+			return nil, false
+		}
+		args, ok := normalizeArguments(toolCall.Function.Arguments)
+		if !ok {
+			return nil, false
+		}
+		calls = append(calls, functionCall{
+			Name:       toolCall.Function.Name,
+			Parameters: args,
+			ID:         strings.TrimSpace(toolCall.ID),
+		})
+	}
+
+	if !ValidateFunctionCallArray(calls) {
+		return nil, false
+	}
+	return calls, true
+}
+
+// normalizeArguments converts OpenAI-style arguments into raw JSON parameters.
+// Arguments may be a JSON-encoded string (the API wire format) or, as models often
+// produce, a plain JSON value. Empty arguments normalize to nil. The boolean result
+// is false when a string does not contain valid JSON.
+func normalizeArguments(raw json.RawMessage) (json.RawMessage, bool) {
+	trimmed := strings.TrimSpace(string(raw))
+	if trimmed == "" || trimmed == "null" {
+		return nil, true
+	}
+	if trimmed[0] != '"' {
+		return json.RawMessage(trimmed), true
+	}
+
+	var encoded string
+	if err := json.Unmarshal([]byte(trimmed), &encoded); err != nil {
+		return nil, false
+	}
+	encoded = strings.TrimSpace(encoded)
+	if encoded == "" || encoded == "null" {
+		return nil, true
+	}
+	if !json.Valid([]byte(encoded)) {
+		return nil, false
 	}
-	return nil, false
+	return json.RawMessage(encoded), true
 }
 
 // ExtractFunctionCalls preserves the previous API by returning only the parsed calls.
@@ -489,8 +725,7 @@ func HasCompleteJSON(content string) bool {
 	if strings.TrimSpace(content) == "" {
 		return false
 	}
-	extractor := NewJSONExtractor(content)
-	candidates := extractor.ExtractJSONBlocks()
+	candidates := extractJSONBlocks(content)
 	if len(candidates) == 0 {
 		return false
 	}
@@ -243,3 +243,41 @@ func BenchmarkValidateFunctionName(b *testing.B) {
 		})
 	})
 }
+
+// BenchmarkScanJSONBlocks_MultiMB compares the rune state machine with the byte-level
+// scanner used on the hot path for multi-megabyte responses.
+func BenchmarkScanJSONBlocks_MultiMB(b *testing.B) {
+	toolCall := `{"name": "get_weather", "parameters": {"location": "Boston"}}`
+	prose := "The quick brown fox jumps over the lazy dog while the model keeps talking. "
+	proseMB := strings.Repeat(prose, (4<<20)/len(prose))
+
+	testCases := []struct {
+		name    string
+		content string
+	}{
+		{"4MB_ProseThenToolCall", proseMB + "```json\n" + toolCall + "\n```"},
+		{"4MB_ScatteredJSON", strings.Repeat(prose+`{"score": [1, 2, 3]} `, (4<<20)/(len(prose)+22))},
+		{"4MB_NoOpeners", proseMB},
+	}
+
+	for _, tc := range testCases {
+		b.Run(tc.name+"/JSONExtractor", func(b *testing.B) {
+			b.SetBytes(int64(len(tc.content)))
+			b.ReportAllocs()
+			var r []string
+			for i := 0; i < b.N; i++ {
+				r = NewJSONExtractor(tc.content).ExtractJSONBlocks()
+			}
+			benchmarkResult = r
+		})
+		b.Run(tc.name+"/Scanner", func(b *testing.B) {
+			b.SetBytes(int64(len(tc.content)))
+			b.ReportAllocs()
+			var r []string
+			for i := 0; i < b.N; i++ {
+				r = extractJSONBlocks(tc.content)
+			}
+			benchmarkResult = r
+		})
+	}
+}
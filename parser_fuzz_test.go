@@ -1,8 +1,12 @@
 package tooladapter
 
 import (
+	"encoding/json"
+	"log/slog"
 	"strings"
 	"testing"
+
+	"github.com/openai/openai-go/v3"
 )
 
 // FuzzJSONExtractor fuzzes the JSON extraction logic with arbitrary input
@@ -286,6 +290,44 @@ func FuzzJSONStructureParsing(f *testing.F) {
 	})
 }
 
+// FuzzToolTextVariants fuzzes fence variants, full-width brackets and invisible
+// characters around tool JSON. The corpus in testdata/fuzz/FuzzToolTextVariants
+// holds the variants seen from quantized models.
+func FuzzToolTextVariants(f *testing.F) {
+	f.Add("```JSON\n{\"name\": \"test\"}\n```")
+	f.Add("```json5\n{name: 'test'}\n```")
+	f.Add("~~~json\n{\"name\": \"test\"}\n~~~")
+	f.Add(`｛"name": "test", "parameters": ｛｝｝`)
+	f.Add("\ufeff\u200b{\"name\"\u200d: \"test\"}\u2060")
+
+	adapter := New(WithLenientParsing(true), WithLogLevel(slog.LevelError))
+	f.Fuzz(func(t *testing.T, input string) {
+		normalized, changed := normalizeToolText(input)
+		if changed != (normalized != input) {
+			t.Errorf("normalizeToolText reported changed=%v for %q", changed, input)
+		}
+		if again, changedAgain := normalizeToolText(normalized); changedAgain {
+			t.Errorf("normalizeToolText is not idempotent for %q: %q then %q", input, normalized, again)
+		}
+		if strings.ContainsAny(normalized, "\ufeff\u200b\u200c\u200d\u2060｛｝［］") {
+			t.Errorf("normalizeToolText left characters to normalize in %q", normalized)
+		}
+
+		// The scanner must keep mirroring the extractor on normalized content
+		expected := NewJSONExtractor(normalized).ExtractJSONBlocks()
+		actual := scanJSONBlocks(normalized)
+		if strings.Join(expected, "\x00") != strings.Join(actual, "\x00") || len(expected) != len(actual) {
+			t.Errorf("scanner mismatch for %q:\nextractor: %q\nscanner:   %q", normalized, expected, actual)
+		}
+
+		for _, call := range ExtractFunctionCalls(adapter.extractCandidates(input, false)) {
+			if call.Name == "" {
+				t.Errorf("extracted a call without a name from %q", input)
+			}
+		}
+	})
+}
+
 // Helper function to check if brackets are balanced (basic check)
 func hasBalancedBrackets(s string) bool {
 	stack := 0
@@ -330,3 +372,83 @@ func unmarshalJSON(data []byte, _ interface{}) error {
 	_ = strings.NewReader(string(data))
 	return nil // For fuzzing, we just check it doesn't panic
 }
+
+// fuzzAdapter builds the adapter configuration selected by the bits of mode, so the
+// state machine is fuzzed under the options that change how content is parsed.
+func fuzzAdapter(mode byte) *Adapter {
+	policies := []ToolPolicy{ToolStopOnFirst, ToolCollectThenStop, ToolDrainAll, ToolAllowMixed, ToolEmitAndContinue}
+	opts := []Option{
+		WithLogLevel(slog.LevelError),
+		WithToolPolicy(policies[int(mode&0x07)%len(policies)]),
+		WithLenientParsing(mode&0x08 != 0),
+	}
+	if mode&0x10 != 0 {
+		opts = append(opts, WithThinkBlocks(ThinkBlocksStrip))
+	}
+	if mode&0x20 != 0 {
+		opts = append(opts, WithToolCallDeduplication(true))
+	}
+	return New(opts...)
+}
+
+// checkFuzzToolCall reports a tool call whose name or arguments could not be
+// passed to a tool.
+func checkFuzzToolCall(t *testing.T, input, name, arguments string) {
+	t.Helper()
+	if err := ValidateFunctionName(name); err != nil {
+		t.Errorf("emitted invalid function name %q for %q: %v", name, input, err)
+	}
+	if !json.Valid([]byte(arguments)) {
+		t.Errorf("emitted invalid JSON arguments %q for %q", arguments, input)
+	}
+}
+
+// FuzzExtractToolCalls fuzzes non-streaming tool call extraction across parsing
+// options. Extraction must never panic, only emit valid names with valid JSON
+// arguments, respect the tool policy, and be deterministic.
+func FuzzExtractToolCalls(f *testing.F) {
+	f.Add(`{"name": "get_weather", "parameters": {"city": "Paris"}}`, byte(0))
+	f.Add(`[{"name": "a", "parameters": {}}, {"name": "b", "parameters": {"x": [1, 2]}}]`, byte(2))
+	f.Add("Sure.\n```json\n{\"name\": \"search\", \"parameters\": {\"q\": \"go\"}}\n```", byte(3))
+	f.Add(`{name: 'lenient', parameters: {list: [1, 2,],},}`, byte(0x08))
+	f.Add("name: yaml_call\nparameters:\n  city: Paris", byte(0x08))
+	f.Add(`<think>{"name": "hidden", "parameters": {}}</think>{"name": "shown", "parameters": {}}`, byte(0x10))
+	f.Add(`{"name": "dup", "parameters": {}} {"name": "dup", "parameters": {}}`, byte(0x22))
+	f.Add(`{"function_call": {"name": "legacy", "arguments": "{\"a\": 1}"}}`, byte(1))
+	f.Add(`{"tool_calls": [{"function": {"name": "nested", "arguments": {}}}]}`, byte(4))
+	f.Add(`{"name": "truncated", "parameters": {"city": "Par`, byte(0x08))
+
+	f.Fuzz(func(t *testing.T, content string, mode byte) {
+		adapter := fuzzAdapter(mode)
+		completion := openai.ChatCompletion{
+			Choices: []openai.ChatCompletionChoice{{Message: openai.ChatCompletionMessage{Content: content}}},
+		}
+
+		first, err := adapter.TransformCompletionsResponse(completion)
+		if err != nil {
+			return
+		}
+		second, err := adapter.TransformCompletionsResponse(completion)
+		if err != nil {
+			t.Fatalf("second transformation of %q failed after the first succeeded: %v", content, err)
+		}
+
+		calls := first.Choices[0].Message.ToolCalls
+		if len(calls) != len(second.Choices[0].Message.ToolCalls) {
+			t.Errorf("extraction of %q is not deterministic: %d then %d calls", content, len(calls), len(second.Choices[0].Message.ToolCalls))
+		}
+		if adapter.toolPolicy == ToolStopOnFirst && len(calls) > 1 {
+			t.Errorf("ToolStopOnFirst emitted %d calls for %q", len(calls), content)
+		}
+		for i, call := range calls {
+			checkFuzzToolCall(t, content, call.Function.Name, call.Function.Arguments)
+			if i < len(second.Choices[0].Message.ToolCalls) {
+				again := second.Choices[0].Message.ToolCalls[i].Function
+				if again.Name != call.Function.Name || again.Arguments != call.Function.Arguments {
+					t.Errorf("extraction of %q is not deterministic: %s(%s) then %s(%s)",
+						content, call.Function.Name, call.Function.Arguments, again.Name, again.Arguments)
+				}
+			}
+		}
+	})
+}
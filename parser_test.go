@@ -1517,3 +1517,118 @@ func TestCandidatePoolManagement(t *testing.T) {
 		assert.NotContains(t, results2[0], "incomplete", "Should not contain failed parsing artifacts")
 	})
 }
+
+// TestExtractFunctionCalls_LegacyFunctionCallFormat tests normalization of the
+// deprecated {"function_call": {...}} shape with stringified arguments.
+func TestExtractFunctionCalls_LegacyFunctionCallFormat(t *testing.T) {
+	testCases := []struct {
+		name         string
+		candidate    string
+		expectedName string
+		expectedArgs string // empty means nil parameters
+		shouldMatch  bool
+	}{
+		{
+			name:         "StringifiedArguments",
+			candidate:    `{"function_call": {"name": "get_weather", "arguments": "{\"location\": \"Boston\"}"}}`,
+			expectedName: "get_weather",
+			expectedArgs: `{"location": "Boston"}`,
+			shouldMatch:  true,
+		},
+		{
+			name:         "ObjectArguments",
+			candidate:    `{"function_call": {"name": "get_weather", "arguments": {"location": "Boston"}}}`,
+			expectedName: "get_weather",
+			expectedArgs: `{"location": "Boston"}`,
+			shouldMatch:  true,
+		},
+		{
+			name:         "EmptyStringArguments",
+			candidate:    `{"function_call": {"name": "get_time", "arguments": ""}}`,
+			expectedName: "get_time",
+			shouldMatch:  true,
+		},
+		{
+			name:         "MissingArguments",
+			candidate:    `{"function_call": {"name": "get_time"}}`,
+			expectedName: "get_time",
+			shouldMatch:  true,
+		},
+		{
+			name:        "InvalidStringifiedArguments",
+			candidate:   `{"function_call": {"name": "get_weather", "arguments": "{location: Boston"}}`,
+			shouldMatch: false,
+		},
+		{
+			name:        "InvalidName",
+			candidate:   `{"function_call": {"name": "bad name", "arguments": "{}"}}`,
+			shouldMatch: false,
+		},
+		{
+			name:        "ExtraFields",
+			candidate:   `{"function_call": {"name": "get_time", "arguments": "{}"}, "extra": true}`,
+			shouldMatch: false,
+		},
+		{
+			name:        "NullFunctionCall",
+			candidate:   `{"function_call": null}`,
+			shouldMatch: false,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			calls, isArray := ExtractFunctionCallsDetailed([]string{tc.candidate})
+			if !tc.shouldMatch {
+				assert.Empty(t, calls)
+				return
+			}
+
+			require.Len(t, calls, 1)
+			assert.False(t, isArray)
+			assert.Equal(t, tc.expectedName, calls[0].Name)
+			if tc.expectedArgs == "" {
+				assert.Nil(t, calls[0].Parameters)
+			} else {
+				assert.JSONEq(t, tc.expectedArgs, string(calls[0].Parameters))
+			}
+		})
+	}
+}
+
+// TestExtractFunctionCalls_ToolCallsWrapper tests the API-style {"tool_calls": [...]} wrapper
+func TestExtractFunctionCalls_ToolCallsWrapper(t *testing.T) {
+	t.Run("MultipleCallsWithIDs", func(t *testing.T) {
+		candidate := `{"tool_calls": [
+			{"id": "call_abc", "type": "function", "function": {"name": "get_weather", "arguments": "{\"location\": \"Boston\"}"}},
+			{"type": "function", "function": {"name": "get_time", "arguments": {}}}
+		]}`
+		calls, isArray := ExtractFunctionCallsDetailed([]string{candidate})
+		require.Len(t, calls, 2)
+		assert.True(t, isArray)
+
+		assert.Equal(t, "get_weather", calls[0].Name)
+		assert.Equal(t, "call_abc", calls[0].ID)
+		assert.JSONEq(t, `{"location": "Boston"}`, string(calls[0].Parameters))
+
+		assert.Equal(t, "get_time", calls[1].Name)
+		assert.Empty(t, calls[1].ID)
+		assert.JSONEq(t, `{}`, string(calls[1].Parameters))
+	})
+
+	rejected := map[string]string{
+		"EmptyList":        `{"tool_calls": []}`,
+		"NonFunctionType":  `{"tool_calls": [{"type": "retrieval", "function": {"name": "get_time"}}]}`,
+		"MissingFunction":  `{"tool_calls": [{"id": "call_1", "type": "function"}]}`,
+		"InvalidName":      `{"tool_calls": [{"function": {"name": "bad name", "arguments": "{}"}}]}`,
+		"InvalidArguments": `{"tool_calls": [{"function": {"name": "get_time", "arguments": "{oops"}}]}`,
+		"OneInvalidEntry":  `{"tool_calls": [{"function": {"name": "get_time"}}, {"function": {"name": ""}}]}`,
+		"ExtraFields":      `{"tool_calls": [{"function": {"name": "get_time"}}], "content": "hi"}`,
+	}
+	for name, candidate := range rejected {
+		t.Run(name, func(t *testing.T) {
+			calls, _ := ExtractFunctionCallsDetailed([]string{candidate})
+			assert.Empty(t, calls)
+		})
+	}
+}
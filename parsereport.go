@@ -0,0 +1,203 @@
+package tooladapter
+
+import (
+	"bytes"
+	"context"
+	"time"
+
+	"github.com/openai/openai-go/v3"
+)
+
+// Reason values of DroppedCall.
+const (
+	// DropReasonUnknownTool marks a call to a function missing from the request's tools
+	DropReasonUnknownTool = "unknown_tool"
+
+	// DropReasonArgumentLimit marks a call whose arguments exceed WithArgumentLimits
+	DropReasonArgumentLimit = "argument_limit"
+
+	// DropReasonFilter marks a call rejected by WithToolCallFilter
+	DropReasonFilter = "filter"
+
+	// DropReasonSingleCall marks a call beyond the first when parallel tool calls are
+	// disabled
+	DropReasonSingleCall = "single_call"
+
+	// DropReasonInterceptor marks a call discarded because WithToolCallInterceptor
+	// rejected a call of the response
+	DropReasonInterceptor = "interceptor"
+
+	// DropReasonDuplicate marks a call identical to an earlier one, under
+	// WithToolCallDeduplication
+	DropReasonDuplicate = "duplicate"
+
+	// DropReasonToolPolicy marks a call the tool policy or WithToolMaxCalls left out
+	DropReasonToolPolicy = "tool_policy"
+
+	// DropReasonRateLimit marks a call refused by WithToolCallRateLimit
+	DropReasonRateLimit = "rate_limit"
+)
+
+// DroppedCall is a tool call parsed from model output that the transformed response
+// does not contain.
+type DroppedCall struct {
+	// Name is the function name, after WithToolNamespace prefixes are removed
+	Name string `json:"name"`
+
+	// Reason is one of the DropReason constants
+	Reason string `json:"reason"`
+}
+
+// ChoiceParseReport describes how the content of one choice was parsed.
+type ChoiceParseReport struct {
+	// ChoiceIndex is the index of the choice in the response
+	ChoiceIndex int `json:"choice_index"`
+
+	// CallsFound is the number of calls parsed from the content, before any were
+	// dropped, and CallsEmitted the number of tool calls in the transformed choice
+	CallsFound   int `json:"calls_found"`
+	CallsEmitted int `json:"calls_emitted"`
+
+	// Format names the shape of JSON the calls were parsed from, using the
+	// ToolCallParser constants. Empty when no calls were found.
+	Format string `json:"format,omitempty"`
+
+	// Repair names the repair applied before the JSON parsed: ParseDetailNormalized,
+	// ParseDetailLenient or ParseDetailStopSequence. Empty when the text parsed as is.
+	Repair string `json:"repair,omitempty"`
+
+	// Dropped lists the calls found but not emitted, in the order they were dropped
+	Dropped []DroppedCall `json:"dropped,omitempty"`
+
+	// SuppressedContentLength is the number of bytes of the choice's content that the
+	// transformed choice no longer contains, such as tool call JSON, think blocks and
+	// text removed by the tool policy
+	SuppressedContentLength int `json:"suppressed_content_length"`
+
+	// EchoedToolResults is the number of passages of the content that repeat an
+	// injected tool result verbatim, under WithEchoSuppression. They are removed
+	// unless the content consists of nothing else.
+	EchoedToolResults int `json:"echoed_tool_results,omitempty"`
+}
+
+// ParseReport describes what the transformation of one response did, for callers
+// that would otherwise have to infer it from logs.
+type ParseReport struct {
+	// NativeTools reports that WithNativePassthrough returned the response untouched
+	NativeTools bool `json:"native_tools"`
+
+	// Choices holds one report per choice of the response, in choice order
+	Choices []ChoiceParseReport `json:"choices"`
+
+	// choice is the index of the choice being parsed
+	choice int
+}
+
+// parseReportKey is the context key for the report filled by
+// TransformCompletionsResponseWithReport.
+type parseReportKey struct{}
+
+// newParseReport returns an empty report for a response with n choices.
+func newParseReport(n int) *ParseReport {
+	report := &ParseReport{Choices: make([]ChoiceParseReport, n)}
+	for i := range report.Choices {
+		report.Choices[i].ChoiceIndex = i
+	}
+	return report
+}
+
+// parseReportFrom returns the report being filled for the response of ctx, if any.
+func parseReportFrom(ctx context.Context) *ParseReport {
+	if ctx == nil {
+		return nil
+	}
+	report, _ := ctx.Value(parseReportKey{}).(*ParseReport)
+	return report
+}
+
+// choiceParseReport returns the report of the choice being parsed, if ctx asks for a
+// report.
+func choiceParseReport(ctx context.Context) *ChoiceParseReport {
+	report := parseReportFrom(ctx)
+	if report == nil || report.choice >= len(report.Choices) {
+		return nil
+	}
+	return &report.Choices[report.choice]
+}
+
+// recordDroppedCalls records calls as dropped for reason when ctx asks for a report.
+func recordDroppedCalls(ctx context.Context, calls []functionCall, reason string) {
+	choice := choiceParseReport(ctx)
+	if choice == nil {
+		return
+	}
+	for _, call := range calls {
+		choice.Dropped = append(choice.Dropped, DroppedCall{Name: call.Name, Reason: reason})
+	}
+}
+
+// removedCalls returns the calls of before that are missing from after, an
+// order-preserving subsequence of before.
+func removedCalls(before, after []functionCall) []functionCall {
+	var removed []functionCall
+	j := 0
+	for _, call := range before {
+		if j < len(after) && call.Name == after[j].Name && bytes.Equal(call.Parameters, after[j].Parameters) {
+			j++
+			continue
+		}
+		removed = append(removed, call)
+	}
+	return removed
+}
+
+// cloneChoiceParseReports copies choice reports so cached reports stay unchanged.
+func cloneChoiceParseReports(choices []ChoiceParseReport) []ChoiceParseReport {
+	cloned := make([]ChoiceParseReport, len(choices))
+	for i, choice := range choices {
+		if choice.Dropped != nil {
+			choice.Dropped = append([]DroppedCall(nil), choice.Dropped...)
+		}
+		cloned[i] = choice
+	}
+	return cloned
+}
+
+// TransformCompletionsResponseWithReport transforms resp like
+// TransformCompletionsResponseWithContext and also returns a report of what the
+// transformation did for each choice: the calls found and emitted, the format they
+// were parsed from, any repair, the calls dropped and why, and how much content was
+// suppressed. The report is the zero value when the transformation fails.
+func (a *Adapter) TransformCompletionsResponseWithReport(ctx context.Context, resp openai.ChatCompletion) (openai.ChatCompletion, ParseReport, error) {
+	startTime := time.Now()
+	transformed, report, err := a.transformResponseWithReport(ctx, resp)
+	if a.postTransformHook != nil {
+		a.runPostTransformHook(ctx, PostTransformReport{
+			Response: &report,
+			Model:    resp.Model,
+			Duration: time.Since(startTime),
+			Err:      err,
+		})
+	}
+	return transformed, report, err
+}
+
+// transformResponseWithReport implements TransformCompletionsResponseWithReport
+// without the post-transform hook.
+func (a *Adapter) transformResponseWithReport(ctx context.Context, resp openai.ChatCompletion) (openai.ChatCompletion, ParseReport, error) {
+	report := newParseReport(len(resp.Choices))
+	transformed, err := a.TransformCompletionsResponseWithContext(context.WithValue(ctx, parseReportKey{}, report), resp)
+	if err != nil {
+		return transformed, ParseReport{}, err
+	}
+
+	for i := range report.Choices {
+		if i >= len(transformed.Choices) {
+			break
+		}
+		choice := &report.Choices[i]
+		choice.CallsEmitted = len(transformed.Choices[i].Message.ToolCalls)
+		choice.SuppressedContentLength = max(len(resp.Choices[i].Message.Content)-len(transformed.Choices[i].Message.Content), 0)
+	}
+	return transformed, *report, nil
+}
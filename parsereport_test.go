@@ -0,0 +1,109 @@
+package tooladapter_test
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	tooladapter "github.com/juburr/openai-tool-adapter/v3"
+	"github.com/juburr/openai-tool-adapter/v3/tooltest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTransformCompletionsResponseWithReport(t *testing.T) {
+	ctx := context.Background()
+	weather := `{"name": "get_weather", "parameters": {"city": "Paris"}}`
+	search := `{"name": "search", "parameters": {"query": "go"}}`
+
+	t.Run("CallsFound", func(t *testing.T) {
+		content := "Let me check.\n```json\n" + weather + "\n```"
+		resp, report, err := tooladapter.New().TransformCompletionsResponseWithReport(ctx, tooltest.Completion(content))
+		require.NoError(t, err)
+		require.Len(t, resp.Choices[0].Message.ToolCalls, 1)
+
+		assert.False(t, report.NativeTools)
+		assert.Equal(t, []tooladapter.ChoiceParseReport{{
+			ChoiceIndex:             0,
+			CallsFound:              1,
+			CallsEmitted:            1,
+			Format:                  tooladapter.ToolCallParserObject,
+			SuppressedContentLength: len(content),
+		}}, report.Choices)
+	})
+
+	t.Run("Repair", func(t *testing.T) {
+		adapter := tooladapter.New(tooladapter.WithLenientParsing(true))
+		_, report, err := adapter.TransformCompletionsResponseWithReport(ctx,
+			tooltest.Completion(`{name: 'get_weather', parameters: {city: 'Paris'},}`))
+		require.NoError(t, err)
+		assert.Equal(t, tooladapter.ParseDetailLenient, report.Choices[0].Repair)
+		assert.Equal(t, 1, report.Choices[0].CallsEmitted)
+	})
+
+	t.Run("DroppedCalls", func(t *testing.T) {
+		adapter := tooladapter.New(
+			tooladapter.WithAllowedToolNames([]string{"get_weather", "search"}),
+			tooladapter.WithToolCallFilter(func(name string, _ json.RawMessage) bool { return name != "search" }),
+			tooladapter.WithToolCallDeduplication(true))
+		content := "[" + weather + ", " + `{"name": "delete_all", "parameters": {}}` + ", " + search + ", " + weather + "]"
+		resp, report, err := adapter.TransformCompletionsResponseWithReport(ctx, tooltest.Completion(content))
+		require.NoError(t, err)
+		require.Len(t, resp.Choices[0].Message.ToolCalls, 1)
+
+		choice := report.Choices[0]
+		assert.Equal(t, 4, choice.CallsFound)
+		assert.Equal(t, 1, choice.CallsEmitted)
+		assert.Equal(t, tooladapter.ToolCallParserArray, choice.Format)
+		assert.Equal(t, []tooladapter.DroppedCall{
+			{Name: "delete_all", Reason: tooladapter.DropReasonUnknownTool},
+			{Name: "search", Reason: tooladapter.DropReasonFilter},
+			{Name: "get_weather", Reason: tooladapter.DropReasonDuplicate},
+		}, choice.Dropped)
+	})
+
+	t.Run("ToolPolicy", func(t *testing.T) {
+		_, report, err := tooladapter.New().TransformCompletionsResponseWithReport(ctx,
+			tooltest.Completion("["+weather+", "+search+"]"))
+		require.NoError(t, err)
+		assert.Equal(t, 1, report.Choices[0].CallsEmitted)
+		assert.Equal(t, []tooladapter.DroppedCall{{Name: "search", Reason: tooladapter.DropReasonToolPolicy}}, report.Choices[0].Dropped)
+	})
+
+	t.Run("NoCalls", func(t *testing.T) {
+		resp, report, err := tooladapter.New().TransformCompletionsResponseWithReport(ctx, tooltest.Completion("Hello!"))
+		require.NoError(t, err)
+		assert.Equal(t, "Hello!", resp.Choices[0].Message.Content)
+		assert.Equal(t, []tooladapter.ChoiceParseReport{{ChoiceIndex: 0}}, report.Choices)
+	})
+
+	t.Run("Cached", func(t *testing.T) {
+		adapter := tooladapter.New(tooladapter.WithResponseCache(8, time.Minute))
+		completion := tooltest.Completion("[" + weather + ", " + search + "]")
+		_, first, err := adapter.TransformCompletionsResponseWithReport(ctx, completion)
+		require.NoError(t, err)
+		_, second, err := adapter.TransformCompletionsResponseWithReport(ctx, completion)
+		require.NoError(t, err)
+		assert.Equal(t, first, second)
+		assert.Len(t, second.Choices[0].Dropped, 1)
+	})
+
+	t.Run("NativeTools", func(t *testing.T) {
+		adapter := tooladapter.New(tooladapter.WithNativePassthrough(func(string) bool { return true }))
+		resp, report, err := adapter.TransformCompletionsResponseWithReport(ctx, tooltest.Completion(weather))
+		require.NoError(t, err)
+		assert.Equal(t, weather, resp.Choices[0].Message.Content)
+		assert.True(t, report.NativeTools)
+		assert.Zero(t, report.Choices[0].CallsFound)
+	})
+
+	t.Run("Error", func(t *testing.T) {
+		adapter := tooladapter.New(
+			tooladapter.WithAllowedToolNames([]string{"search"}),
+			tooladapter.WithUnknownToolPolicy(tooladapter.UnknownToolError))
+		_, report, err := adapter.TransformCompletionsResponseWithReport(ctx, tooltest.Completion(weather))
+		require.Error(t, err)
+		assert.Equal(t, tooladapter.ParseReport{}, report)
+	})
+}
@@ -0,0 +1,270 @@
+package tooladapter_test
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	tooladapter "github.com/juburr/openai-tool-adapter/v3"
+	"github.com/juburr/openai-tool-adapter/v3/tooltest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// phaseEvents returns the collected events of type T.
+func phaseEvents[T tooladapter.MetricEventData](collector *MetricsCollector) []T {
+	var events []T
+	for _, event := range collector.GetEvents() {
+		if typed, ok := event.(T); ok {
+			events = append(events, typed)
+		}
+	}
+	return events
+}
+
+func newPhaseMetricsAdapter(opts ...tooladapter.Option) (*tooladapter.Adapter, *MetricsCollector) {
+	collector := NewMetricsCollector()
+	opts = append([]tooladapter.Option{
+		tooladapter.WithPhaseMetrics(true),
+		tooladapter.WithMetricsCallback(collector.Callback),
+	}, opts...)
+	return tooladapter.New(opts...), collector
+}
+
+func TestPhaseMetrics_DisabledByDefault(t *testing.T) {
+	collector := NewMetricsCollector()
+	adapter := tooladapter.New(tooladapter.WithMetricsCallback(collector.Callback))
+
+	_, err := adapter.TransformCompletionsRequest(tooltest.Request(tooltest.Tool("get_weather", "Get weather")))
+	require.NoError(t, err)
+	_, err = adapter.TransformCompletionsResponse(tooltest.Completion(weatherJSON))
+	require.NoError(t, err)
+
+	for _, event := range collector.GetEvents() {
+		switch event.(type) {
+		case tooladapter.RequestTransformedData, tooladapter.ToolPromptRenderedData, tooladapter.ToolCallDetectedData:
+			t.Errorf("unexpected phase event %T without WithPhaseMetrics", event)
+		}
+	}
+}
+
+func TestPhaseMetrics_RequestTransformed(t *testing.T) {
+	adapter, collector := newPhaseMetricsAdapter()
+
+	req := tooltest.Request(tooltest.Tool("get_weather", "Get weather"), tooltest.Tool("get_time", "Get time"))
+	_, err := adapter.TransformCompletionsRequest(req)
+	require.NoError(t, err)
+
+	requests := phaseEvents[tooladapter.RequestTransformedData](collector)
+	require.Len(t, requests, 1)
+	assert.Equal(t, string(req.Model), requests[0].Model)
+	assert.Equal(t, 2, requests[0].ToolCount)
+	assert.Equal(t, 2, requests[0].InjectedToolCount)
+	assert.Equal(t, 0, requests[0].ToolResultCount)
+	assert.Positive(t, requests[0].MessageCount)
+	assert.False(t, requests[0].Passthrough)
+	assert.Positive(t, requests[0].Performance.ProcessingDuration)
+
+	prompts := phaseEvents[tooladapter.ToolPromptRenderedData](collector)
+	require.Len(t, prompts, 1)
+	assert.Equal(t, 2, prompts[0].ToolCount)
+	assert.Positive(t, prompts[0].PromptLength)
+	assert.False(t, prompts[0].CustomTemplate)
+
+	t.Run("Passthrough", func(t *testing.T) {
+		collector.Clear()
+		_, err := adapter.TransformCompletionsRequest(tooltest.Request())
+		require.NoError(t, err)
+
+		requests := phaseEvents[tooladapter.RequestTransformedData](collector)
+		require.Len(t, requests, 1)
+		assert.True(t, requests[0].Passthrough)
+		assert.Equal(t, 1, requests[0].MessageCount)
+		assert.Empty(t, phaseEvents[tooladapter.ToolPromptRenderedData](collector))
+	})
+
+	t.Run("CustomTemplate", func(t *testing.T) {
+		adapter, collector := newPhaseMetricsAdapter(tooladapter.WithCustomPromptTemplate("Tools: %s"))
+		_, err := adapter.TransformCompletionsRequest(tooltest.Request(tooltest.Tool("get_weather", "")))
+		require.NoError(t, err)
+
+		prompts := phaseEvents[tooladapter.ToolPromptRenderedData](collector)
+		require.Len(t, prompts, 1)
+		assert.True(t, prompts[0].CustomTemplate)
+	})
+}
+
+func TestPhaseMetrics_ToolCallDetected(t *testing.T) {
+	content := `[{"name": "get_weather", "parameters": {"city": "Paris"}}, {"name": "get_time", "parameters": {}}]`
+	want := []tooladapter.ToolCallDetectedData{
+		{ToolName: "get_weather", CallIndex: 0, ArgumentsBytes: len(`{"city": "Paris"}`)},
+		{ToolName: "get_time", CallIndex: 1, ArgumentsBytes: len(`{}`)},
+	}
+
+	t.Run("NonStreaming", func(t *testing.T) {
+		adapter, collector := newPhaseMetricsAdapter(tooladapter.WithToolPolicy(tooladapter.ToolDrainAll))
+		_, err := adapter.TransformCompletionsResponse(tooltest.Completion(content))
+		require.NoError(t, err)
+		assert.Equal(t, want, phaseEvents[tooladapter.ToolCallDetectedData](collector))
+	})
+
+	t.Run("Streaming", func(t *testing.T) {
+		adapter, collector := newPhaseMetricsAdapter(tooladapter.WithToolPolicy(tooladapter.ToolDrainAll))
+		result := tooltest.Drain(adapter.TransformStreamingResponse(tooltest.NewContentStream(content)))
+		require.NoError(t, result.Err)
+
+		streamed := phaseEvents[tooladapter.ToolCallDetectedData](collector)
+		require.Len(t, streamed, len(want))
+		for i := range want {
+			want[i].Streaming = true
+			assert.Equal(t, want[i], streamed[i])
+		}
+	})
+}
+
+func TestPhaseMetrics_StreamBufferFlushed(t *testing.T) {
+	t.Run("NoToolCall", func(t *testing.T) {
+		adapter, collector := newPhaseMetricsAdapter()
+		content := `{"name": "Alice", "age": 30}`
+		result := tooltest.Drain(adapter.TransformStreamingResponse(tooltest.NewContentStream(content)))
+		require.NoError(t, result.Err)
+		assert.Equal(t, content, result.Content)
+
+		flushes := phaseEvents[tooladapter.StreamBufferFlushedData](collector)
+		require.Len(t, flushes, 1)
+		assert.Equal(t, tooladapter.StreamBufferFlushedData{Size: len(content), Reason: tooladapter.BufferFlushNoToolCall}, flushes[0])
+	})
+
+	t.Run("Limit", func(t *testing.T) {
+		adapter, collector := newPhaseMetricsAdapter(tooladapter.WithStreamingToolBufferSize(16))
+		content := `{"name": "get_weather", "parameters": {"city": "` + strings.Repeat("x", 64)
+		result := tooltest.Drain(adapter.TransformStreamingResponse(tooltest.NewContentStream(content[:8], content[8:])))
+		require.NoError(t, result.Err)
+
+		flushes := phaseEvents[tooladapter.StreamBufferFlushedData](collector)
+		require.NotEmpty(t, flushes)
+		assert.Equal(t, tooladapter.BufferFlushLimit, flushes[0].Reason)
+
+		limits := phaseEvents[tooladapter.PolicyLimitHitData](collector)
+		require.NotEmpty(t, limits)
+		assert.Equal(t, tooladapter.PolicyLimitStreamBufferLimit, limits[0].Limit)
+		assert.Equal(t, 16, limits[0].Max)
+		assert.True(t, limits[0].Streaming)
+	})
+}
+
+func TestPhaseMetrics_PolicyLimitHit(t *testing.T) {
+	content := `[{"name": "a", "parameters": {}}, {"name": "b", "parameters": {}}, {"name": "c", "parameters": {}}]`
+
+	t.Run("ToolMaxCalls", func(t *testing.T) {
+		adapter, collector := newPhaseMetricsAdapter(
+			tooladapter.WithToolPolicy(tooladapter.ToolDrainAll),
+			tooladapter.WithToolMaxCalls(2),
+		)
+		resp, err := adapter.TransformCompletionsResponse(tooltest.Completion(content))
+		require.NoError(t, err)
+		require.Len(t, resp.Choices[0].Message.ToolCalls, 2)
+
+		assert.Equal(t, []tooladapter.PolicyLimitHitData{
+			{Limit: tooladapter.PolicyLimitToolMaxCalls, Value: 3, Max: 2},
+		}, phaseEvents[tooladapter.PolicyLimitHitData](collector))
+	})
+
+	t.Run("ToolMaxCallsStreaming", func(t *testing.T) {
+		adapter, collector := newPhaseMetricsAdapter(
+			tooladapter.WithToolPolicy(tooladapter.ToolDrainAll),
+			tooladapter.WithToolMaxCalls(2),
+		)
+		result := tooltest.Drain(adapter.TransformStreamingResponse(tooltest.NewContentStream(content)))
+		require.NoError(t, result.Err)
+		assert.Len(t, result.ToolCalls, 2)
+
+		limits := phaseEvents[tooladapter.PolicyLimitHitData](collector)
+		require.NotEmpty(t, limits)
+		assert.Equal(t, tooladapter.PolicyLimitToolMaxCalls, limits[0].Limit)
+		assert.Equal(t, 2, limits[0].Max)
+		assert.True(t, limits[0].Streaming)
+	})
+
+	t.Run("ResponseParseMaxBytes", func(t *testing.T) {
+		adapter, collector := newPhaseMetricsAdapter(tooladapter.WithResponseParseMaxBytes(16))
+		_, err := adapter.TransformCompletionsResponse(tooltest.Completion(content))
+		require.NoError(t, err)
+
+		assert.Equal(t, []tooladapter.PolicyLimitHitData{
+			{Limit: tooladapter.PolicyLimitResponseParseMaxBytes, Value: len(content), Max: 16},
+		}, phaseEvents[tooladapter.PolicyLimitHitData](collector))
+	})
+}
+
+func TestPhaseMetrics_Config(t *testing.T) {
+	cfg, err := tooladapter.LoadConfig(strings.NewReader(`{"phase_metrics": true}`))
+	require.NoError(t, err)
+	assert.True(t, cfg.PhaseMetrics)
+
+	collector := NewMetricsCollector()
+	opts, err := cfg.Options()
+	require.NoError(t, err)
+	opts = append(opts, tooladapter.WithMetricsCallback(collector.Callback))
+	_, err = tooladapter.New(opts...).TransformCompletionsRequest(tooltest.Request())
+	require.NoError(t, err)
+	assert.Len(t, phaseEvents[tooladapter.RequestTransformedData](collector), 1)
+}
+
+// TestPhaseMetrics_SchemaStability locks the event names and JSON field names of the
+// phase events. MetricsSchemaVersion promises they never change within a major
+// version: extend this test when fields are added, never edit existing entries.
+func TestPhaseMetrics_SchemaStability(t *testing.T) {
+	assert.Equal(t, 4, tooladapter.MetricsSchemaVersion)
+
+	tests := []struct {
+		data   tooladapter.MetricEventData
+		event  tooladapter.MetricEvent
+		fields []string
+	}{
+		{
+			tooladapter.RequestTransformedData{}, "request_transformed",
+			[]string{"model", "tool_count", "injected_tool_count", "tool_result_count", "message_count", "passthrough", "performance"},
+		},
+		{
+			tooladapter.ToolPromptRenderedData{}, "tool_prompt_rendered",
+			[]string{"tool_count", "tool_result_count", "prompt_length", "prompt_tokens", "custom_template"},
+		},
+		{
+			tooladapter.ToolCallDetectedData{}, "tool_call_detected",
+			[]string{"tool_name", "call_index", "choice_index", "arguments_bytes", "streaming"},
+		},
+		{
+			tooladapter.StreamBufferFlushedData{}, "stream_buffer_flushed",
+			[]string{"size", "reason"},
+		},
+		{
+			tooladapter.PolicyLimitHitData{}, "policy_limit_hit",
+			[]string{"limit", "value", "max", "streaming"},
+		},
+		{
+			tooladapter.PostTransformReportDroppedData{}, "post_transform_report_dropped",
+			[]string{"model", "queue_size", "dropped"},
+		},
+	}
+	for _, tc := range tests {
+		t.Run(string(tc.event), func(t *testing.T) {
+			assert.Equal(t, tc.event, tc.data.EventType())
+
+			encoded, err := json.Marshal(tc.data)
+			require.NoError(t, err)
+			var fields map[string]json.RawMessage
+			require.NoError(t, json.Unmarshal(encoded, &fields))
+			for _, field := range tc.fields {
+				assert.Contains(t, fields, field)
+			}
+		})
+	}
+
+	assert.Equal(t, "tool_max_calls", tooladapter.PolicyLimitToolMaxCalls)
+	assert.Equal(t, "tool_collect_max_bytes", tooladapter.PolicyLimitToolCollectMaxBytes)
+	assert.Equal(t, "stream_buffer_limit", tooladapter.PolicyLimitStreamBufferLimit)
+	assert.Equal(t, "response_parse_max_bytes", tooladapter.PolicyLimitResponseParseMaxBytes)
+	assert.Equal(t, "no_tool_call", tooladapter.BufferFlushNoToolCall)
+	assert.Equal(t, "limit", tooladapter.BufferFlushLimit)
+}
@@ -0,0 +1,138 @@
+package tooladapter
+
+import (
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// DefaultAdapterPoolSize is the number of adapters an AdapterPool holds when
+// NewAdapterPool is given a size of 0 or less.
+const DefaultAdapterPoolSize = 100
+
+// TenantOptionsFunc returns the options of the adapter for a tenant or profile ID,
+// for example from a database of tenant settings. A non-nil error fails Get.
+type TenantOptionsFunc func(ctx context.Context, tenant string) ([]Option, error)
+
+// AdapterPool holds one adapter per tenant or profile ID, so gateways serving many
+// tenants configure each of them separately instead of sharing one global adapter
+// or constructing adapters per request. Adapters are built on first use from the
+// pool's base options followed by the tenant's options, and the least recently used
+// are evicted once the pool is full. The pool is safe for concurrent use.
+type AdapterPool struct {
+	base          []Option
+	tenantOptions TenantOptionsFunc
+	adapters      *lruCache[*Adapter]
+
+	// Builds in progress, so concurrent first requests for a tenant share one build
+	mu       sync.Mutex
+	building map[string]*poolBuild
+}
+
+// poolBuild is an adapter being built for a tenant.
+type poolBuild struct {
+	done    chan struct{}
+	adapter *Adapter
+	err     error
+}
+
+// NewAdapterPool returns a pool holding up to size adapters, each built with base
+// followed by the options tenantOptions returns for its tenant. Adapters older than
+// ttl are built again on their next use, so changed tenant settings take effect; a
+// ttl of 0 keeps them until they are evicted or invalidated. A nil tenantOptions
+// builds every adapter from base alone.
+//
+// Example usage:
+//
+//	pool := tooladapter.NewAdapterPool(500, 10*time.Minute,
+//	    func(ctx context.Context, tenant string) ([]tooladapter.Option, error) {
+//	        settings, err := store.TenantSettings(ctx, tenant)
+//	        if err != nil {
+//	            return nil, err
+//	        }
+//	        return []tooladapter.Option{tooladapter.WithToolPolicy(settings.ToolPolicy)}, nil
+//	    },
+//	    tooladapter.WithLogger(logger))
+//
+//	adapter, err := pool.Get(ctx, tenantID)
+func NewAdapterPool(size int, ttl time.Duration, tenantOptions TenantOptionsFunc, base ...Option) *AdapterPool {
+	if size <= 0 {
+		size = DefaultAdapterPoolSize
+	}
+	return &AdapterPool{
+		base:          base,
+		tenantOptions: tenantOptions,
+		adapters:      newLRUCache[*Adapter](size, max(ttl, 0)),
+		building:      make(map[string]*poolBuild),
+	}
+}
+
+// Get returns the adapter of tenant, building it when the pool does not hold one.
+// Failed builds are not cached, so the next Get tries again.
+func (p *AdapterPool) Get(ctx context.Context, tenant string) (*Adapter, error) {
+	key := sha256.Sum256([]byte(tenant))
+	if adapter, ok := p.adapters.get(key); ok {
+		return adapter, nil
+	}
+
+	p.mu.Lock()
+	build, inProgress := p.building[tenant]
+	if !inProgress {
+		build = &poolBuild{done: make(chan struct{})}
+		p.building[tenant] = build
+	}
+	p.mu.Unlock()
+
+	if inProgress {
+		select {
+		case <-build.done:
+			return build.adapter, build.err
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+
+	build.adapter, build.err = p.build(ctx, tenant)
+	if build.err == nil {
+		p.adapters.put(key, build.adapter)
+	}
+	p.mu.Lock()
+	delete(p.building, tenant)
+	p.mu.Unlock()
+	close(build.done)
+	return build.adapter, build.err
+}
+
+// build constructs the adapter of tenant, treating a panic in the options function as
+// an error.
+func (p *AdapterPool) build(ctx context.Context, tenant string) (adapter *Adapter, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			adapter, err = nil, fmt.Errorf("adapter options for tenant %q panicked: %v", tenant, r)
+		}
+	}()
+
+	opts := p.base
+	if p.tenantOptions != nil {
+		tenantOpts, err := p.tenantOptions(ctx, tenant)
+		if err != nil {
+			return nil, fmt.Errorf("adapter options for tenant %q: %w", tenant, err)
+		}
+		opts = append(append([]Option(nil), p.base...), tenantOpts...)
+	}
+	return New(opts...), nil
+}
+
+// Invalidate removes the adapter of tenant, so the next Get builds it again with the
+// tenant's current options.
+func (p *AdapterPool) Invalidate(tenant string) {
+	p.adapters.remove(sha256.Sum256([]byte(tenant)))
+}
+
+// Len returns the number of adapters the pool holds, including expired ones not yet
+// removed.
+func (p *AdapterPool) Len() int {
+	return p.adapters.len()
+}
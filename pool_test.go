@@ -0,0 +1,120 @@
+package tooladapter_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	tooladapter "github.com/juburr/openai-tool-adapter/v3"
+	"github.com/juburr/openai-tool-adapter/v3/tooltest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAdapterPool(t *testing.T) {
+	ctx := context.Background()
+	var builds atomic.Int32
+	pool := tooladapter.NewAdapterPool(2, 0, func(_ context.Context, tenant string) ([]tooladapter.Option, error) {
+		builds.Add(1)
+		switch tenant {
+		case "drain":
+			return []tooladapter.Option{tooladapter.WithToolPolicy(tooladapter.ToolDrainAll)}, nil
+		case "broken":
+			return nil, errors.New("settings unavailable")
+		}
+		return nil, nil
+	}, tooladapter.WithToolCallDeduplication(true))
+
+	calls := func(adapter *tooladapter.Adapter) int {
+		resp, err := adapter.TransformCompletionsResponse(tooltest.Completion(
+			`[{"name": "get_weather", "parameters": {}}, {"name": "get_time", "parameters": {}}, {"name": "get_time", "parameters": {}}]`))
+		require.NoError(t, err)
+		return len(resp.Choices[0].Message.ToolCalls)
+	}
+
+	t.Run("PerTenantOptions", func(t *testing.T) {
+		drain, err := pool.Get(ctx, "drain")
+		require.NoError(t, err)
+		assert.Equal(t, 2, calls(drain), "tenant policy with the base deduplication")
+
+		other, err := pool.Get(ctx, "acme")
+		require.NoError(t, err)
+		assert.Equal(t, 1, calls(other), "default policy")
+
+		again, err := pool.Get(ctx, "drain")
+		require.NoError(t, err)
+		assert.Same(t, drain, again)
+		assert.Equal(t, int32(2), builds.Load())
+	})
+
+	t.Run("Eviction", func(t *testing.T) {
+		drain, err := pool.Get(ctx, "drain")
+		require.NoError(t, err)
+		_, err = pool.Get(ctx, "globex")
+		require.NoError(t, err)
+		assert.Equal(t, 2, pool.Len())
+
+		_, err = pool.Get(ctx, "acme") // evicted by globex
+		require.NoError(t, err)
+		again, err := pool.Get(ctx, "drain")
+		require.NoError(t, err)
+		assert.NotSame(t, drain, again)
+	})
+
+	t.Run("Invalidate", func(t *testing.T) {
+		drain, err := pool.Get(ctx, "drain")
+		require.NoError(t, err)
+		pool.Invalidate("drain")
+		again, err := pool.Get(ctx, "drain")
+		require.NoError(t, err)
+		assert.NotSame(t, drain, again)
+	})
+
+	t.Run("Error", func(t *testing.T) {
+		before := builds.Load()
+		_, err := pool.Get(ctx, "broken")
+		require.ErrorContains(t, err, `tenant "broken": settings unavailable`)
+		_, err = pool.Get(ctx, "broken")
+		require.Error(t, err)
+		assert.Equal(t, before+2, builds.Load(), "failed builds are not cached")
+	})
+}
+
+func TestAdapterPoolConcurrentBuilds(t *testing.T) {
+	release := make(chan struct{})
+	var builds atomic.Int32
+	pool := tooladapter.NewAdapterPool(0, 0, func(context.Context, string) ([]tooladapter.Option, error) {
+		builds.Add(1)
+		<-release
+		return nil, nil
+	})
+
+	adapters := make([]*tooladapter.Adapter, 8)
+	var wg sync.WaitGroup
+	for i := range adapters {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			adapter, err := pool.Get(context.Background(), "acme")
+			assert.NoError(t, err)
+			adapters[i] = adapter
+		}()
+	}
+	close(release)
+	wg.Wait()
+
+	assert.Equal(t, int32(1), builds.Load())
+	for _, adapter := range adapters {
+		assert.Same(t, adapters[0], adapter)
+	}
+}
+
+func TestAdapterPoolPanic(t *testing.T) {
+	pool := tooladapter.NewAdapterPool(1, 0, func(context.Context, string) ([]tooladapter.Option, error) {
+		panic("bad settings")
+	})
+	_, err := pool.Get(context.Background(), "acme")
+	require.ErrorContains(t, err, "panicked: bad settings")
+}
@@ -0,0 +1,84 @@
+package tooladapter
+
+import (
+	"context"
+	"time"
+)
+
+// Default size of the post-transform hook pool (see WithPostTransformHookPool).
+const (
+	postTransformHookWorkers   = 4
+	postTransformHookQueueSize = 256
+)
+
+// PostTransformReport is passed to WithPostTransformHook after each non-streaming
+// transformation. Exactly one of Request and Response is set.
+type PostTransformReport struct {
+	// Request is the report of a request transformation, as returned by
+	// TransformCompletionsRequestWithReport
+	Request *TransformReport
+
+	// Response is the report of a response transformation, as returned by
+	// TransformCompletionsResponseWithReport
+	Response *ParseReport
+
+	// Model is the model of the request or response
+	Model string
+
+	// Duration is the time the transformation took
+	Duration time.Duration
+
+	// Err is the error the transformation returned, in which case the report is the
+	// zero value
+	Err error
+}
+
+// postTransformJob is a report waiting for a post-transform hook worker.
+type postTransformJob struct {
+	ctx    context.Context
+	report PostTransformReport
+}
+
+// runPostTransformHook queues report for the post-transform hook workers, starting
+// them with the first report. When the queue is full the report is dropped, so a
+// slow hook never delays the request path.
+func (a *Adapter) runPostTransformHook(ctx context.Context, report PostTransformReport) {
+	a.postTransformStart.Do(func() {
+		for range a.postTransformWorkers {
+			go a.postTransformWorker()
+		}
+	})
+
+	// The hook outlives the transformation, so it must not see its cancellation
+	select {
+	case a.postTransformQueue <- postTransformJob{ctx: context.WithoutCancel(ctx), report: report}:
+	default:
+		dropped := a.postTransformDropped.Add(1)
+		a.logger.Warn("Post-transform hook queue full, report dropped",
+			"queue_size", cap(a.postTransformQueue),
+			"model", report.Model)
+		a.emitMetric(PostTransformReportDroppedData{
+			Model:     report.Model,
+			QueueSize: cap(a.postTransformQueue),
+			Dropped:   dropped,
+		})
+	}
+}
+
+// postTransformWorker runs the post-transform hook for queued reports.
+func (a *Adapter) postTransformWorker() {
+	for job := range a.postTransformQueue {
+		a.callPostTransformHook(job)
+	}
+}
+
+// callPostTransformHook runs the hook for one report, recovering its panics.
+func (a *Adapter) callPostTransformHook(job postTransformJob) {
+	defer func() {
+		if r := recover(); r != nil {
+			a.logger.Error("Post-transform hook panicked - report dropped but operation continues",
+				"panic", r)
+		}
+	}()
+	a.postTransformHook(job.ctx, job.report)
+}
@@ -0,0 +1,167 @@
+package tooladapter_test
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	tooladapter "github.com/juburr/openai-tool-adapter/v3"
+	"github.com/juburr/openai-tool-adapter/v3/tooltest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// hookKey is a context key whose value must reach the post-transform hook.
+type hookKey struct{}
+
+// receiveReport waits for the next report the hook delivers.
+func receiveReport(t *testing.T, reports <-chan tooladapter.PostTransformReport) tooladapter.PostTransformReport {
+	t.Helper()
+	select {
+	case report := <-reports:
+		return report
+	case <-time.After(5 * time.Second):
+		t.Fatal("post-transform hook was not called")
+		return tooladapter.PostTransformReport{}
+	}
+}
+
+func TestPostTransformHook(t *testing.T) {
+	reports := make(chan tooladapter.PostTransformReport, 4)
+	values := make(chan any, 4)
+	adapter := tooladapter.New(tooladapter.WithPostTransformHook(func(ctx context.Context, report tooladapter.PostTransformReport) {
+		values <- ctx.Value(hookKey{})
+		reports <- report
+	}))
+
+	ctx, cancel := context.WithCancel(context.WithValue(context.Background(), hookKey{}, "trace-1"))
+	req := tooltest.Request(tooltest.Tool("get_weather", "Get the weather"))
+	_, err := adapter.TransformCompletionsRequestWithContext(ctx, req)
+	require.NoError(t, err)
+	cancel()
+
+	report := receiveReport(t, reports)
+	assert.Equal(t, "trace-1", <-values)
+	require.NotNil(t, report.Request)
+	assert.Nil(t, report.Response)
+	assert.Equal(t, []string{"get_weather"}, report.Request.ToolsRendered)
+	assert.Equal(t, req.Model, report.Model)
+	assert.NoError(t, report.Err)
+
+	completion := tooltest.Completion(`{"name": "get_weather", "parameters": {"city": "Paris"}}`)
+	completion.Model = "gemma-3"
+	_, err = adapter.TransformCompletionsResponse(completion)
+	require.NoError(t, err)
+
+	report = receiveReport(t, reports)
+	<-values
+	require.NotNil(t, report.Response)
+	assert.Nil(t, report.Request)
+	assert.Equal(t, "gemma-3", report.Model)
+	assert.Equal(t, 1, report.Response.Choices[0].CallsEmitted)
+
+	t.Run("WithReportCallsHookOnce", func(t *testing.T) {
+		_, _, err := adapter.TransformCompletionsResponseWithReport(context.Background(), completion)
+		require.NoError(t, err)
+		receiveReport(t, reports)
+		<-values
+		select {
+		case <-reports:
+			t.Fatal("hook called twice for one transformation")
+		case <-time.After(50 * time.Millisecond):
+		}
+	})
+
+	t.Run("Error", func(t *testing.T) {
+		cancelled, cancel := context.WithCancel(context.Background())
+		cancel()
+		_, err := adapter.TransformCompletionsRequestWithContext(cancelled, req)
+		require.ErrorIs(t, err, context.Canceled)
+
+		report := receiveReport(t, reports)
+		<-values
+		assert.ErrorIs(t, report.Err, context.Canceled)
+		assert.Equal(t, tooladapter.TransformReport{}, *report.Request)
+	})
+}
+
+func TestPostTransformHookPool(t *testing.T) {
+	release := make(chan struct{})
+	var calls atomic.Int32
+	var dropped []tooladapter.PostTransformReportDroppedData
+	var mu sync.Mutex
+	adapter := tooladapter.New(
+		tooladapter.WithPostTransformHook(func(context.Context, tooladapter.PostTransformReport) {
+			calls.Add(1)
+			<-release
+		}),
+		tooladapter.WithPostTransformHookPool(2, 3),
+		tooladapter.WithMetricsCallback(func(data tooladapter.MetricEventData) {
+			if d, ok := data.(tooladapter.PostTransformReportDroppedData); ok {
+				mu.Lock()
+				dropped = append(dropped, d)
+				mu.Unlock()
+			}
+		}),
+	)
+
+	// Occupy both workers so the reports that follow can only wait in the queue
+	completion := tooltest.Completion("Hello!")
+	for range 2 {
+		_, err := adapter.TransformCompletionsResponse(completion)
+		require.NoError(t, err)
+	}
+	require.Eventually(t, func() bool { return calls.Load() == 2 }, 5*time.Second, time.Millisecond)
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for range 100 {
+			_, err := adapter.TransformCompletionsResponse(completion)
+			assert.NoError(t, err)
+		}
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("transformations blocked on busy hooks")
+	}
+
+	mu.Lock()
+	require.Len(t, dropped, 97, "reports beyond the queue are dropped")
+	last := dropped[len(dropped)-1]
+	mu.Unlock()
+	assert.Equal(t, int64(97), last.Dropped)
+	assert.Equal(t, 3, last.QueueSize)
+	assert.Equal(t, completion.Model, last.Model)
+
+	// Queued reports are delivered once the workers are free
+	close(release)
+	assert.Eventually(t, func() bool { return calls.Load() == 5 }, 5*time.Second, time.Millisecond)
+	assert.Never(t, func() bool { return calls.Load() > 5 }, 50*time.Millisecond, 5*time.Millisecond)
+
+	t.Run("Invalid", func(t *testing.T) {
+		var logs bytes.Buffer
+		tooladapter.New(
+			tooladapter.WithLogger(slog.New(slog.NewTextHandler(&logs, nil))),
+			tooladapter.WithPostTransformHookPool(0, 8),
+		)
+		assert.Contains(t, logs.String(), "Invalid post-transform hook pool")
+	})
+}
+
+func TestPostTransformHookPanic(t *testing.T) {
+	called := make(chan struct{})
+	adapter := tooladapter.New(tooladapter.WithPostTransformHook(func(context.Context, tooladapter.PostTransformReport) {
+		defer close(called)
+		panic("analytics down")
+	}))
+
+	_, err := adapter.TransformCompletionsResponse(tooltest.Completion("Hello!"))
+	require.NoError(t, err)
+	<-called
+}
@@ -0,0 +1,220 @@
+package tooladapter
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"strings"
+)
+
+// postProcessCalls applies response-side processing to function calls parsed from
+// model output before they are converted into OpenAI tool calls. It is shared by the
+// non-streaming, streaming, and SSE paths so all of them behave identically.
+//
+// A non-nil error is returned only when a configured policy rejects the response,
+// such as UnknownToolError.
+func (a *Adapter) postProcessCalls(ctx context.Context, calls []functionCall) ([]functionCall, error) {
+	if len(calls) == 0 {
+		return calls, nil
+	}
+
+	if a.toolNamespace != "" {
+		for i := range calls {
+			calls[i].Name = a.restoreToolName(calls[i].Name)
+		}
+	}
+
+	if a.allowedToolNames != nil {
+		var err error
+		if calls, err = a.handleUnknownTools(ctx, calls); err != nil {
+			return nil, err
+		}
+	}
+
+	if a.argumentLimits.enabled() {
+		var err error
+		if calls, err = a.limitArguments(ctx, calls); err != nil {
+			return nil, err
+		}
+	}
+
+	a.coerceCalls(ctx, calls)
+	if err := a.checkCalls(ctx, calls); err != nil {
+		return nil, err
+	}
+
+	if a.toolCallFilter != nil {
+		calls = a.filterCalls(ctx, calls)
+	}
+
+	calls = a.limitToSingleCall(ctx, calls)
+	if a.toolCallInterceptor != nil {
+		return a.interceptCalls(ctx, calls)
+	}
+	return calls, nil
+}
+
+// handleUnknownTools applies the unknown tool policy to calls whose names are not in
+// the allowed list.
+func (a *Adapter) handleUnknownTools(ctx context.Context, calls []functionCall) ([]functionCall, error) {
+	kept := calls[:0]
+	for _, call := range calls {
+		if _, ok := a.allowedToolNames[call.Name]; ok {
+			kept = append(kept, call)
+			continue
+		}
+
+		switch a.unknownToolPolicy {
+		case UnknownToolError:
+			a.log(LogCategoryPolicy).Warn("Rejected response calling unknown function", "function_name", call.Name)
+			return nil, &UnknownToolCallError{Name: call.Name}
+
+		case UnknownToolAsContent:
+			a.log(LogCategoryPolicy).Warn("Response calls unknown function, returning it as content",
+				"function_name", call.Name)
+			recordDroppedCalls(ctx, calls, DropReasonUnknownTool)
+			return nil, nil
+
+		case UnknownToolCorrect:
+			if match, score := a.closestAllowedToolName(call.Name); match != "" {
+				a.log(LogCategoryPolicy).Warn("Corrected unknown function name to closest allowed tool",
+					"function_name", call.Name,
+					"corrected_to", match,
+					"similarity", score)
+				call.Name = match
+				kept = append(kept, call)
+				continue
+			}
+			a.log(LogCategoryPolicy).Warn("Dropped tool call for unknown function with no close match",
+				"function_name", call.Name,
+				"threshold", a.unknownToolMatchThreshold)
+			recordDroppedCalls(ctx, []functionCall{call}, DropReasonUnknownTool)
+
+		default:
+			a.log(LogCategoryPolicy).Warn("Dropped tool call for function not in allowed list",
+				"function_name", call.Name)
+			recordDroppedCalls(ctx, []functionCall{call}, DropReasonUnknownTool)
+		}
+	}
+	return kept, nil
+}
+
+// closestAllowedToolName returns the allowed name most similar to name, provided the
+// similarity reaches the configured threshold. Ties resolve to the lexically smallest
+// name so results are deterministic.
+func (a *Adapter) closestAllowedToolName(name string) (string, float64) {
+	best, bestScore := "", 0.0
+	for candidate := range a.allowedToolNames {
+		score := nameSimilarity(name, candidate)
+		if score > bestScore || (score == bestScore && best != "" && candidate < best) {
+			best, bestScore = candidate, score
+		}
+	}
+	if best == "" || bestScore < a.unknownToolMatchThreshold {
+		return "", bestScore
+	}
+	return best, bestScore
+}
+
+// nameSimilarity returns a case-insensitive similarity score in [0, 1] based on the
+// Levenshtein edit distance normalized by the longer name's length.
+func nameSimilarity(a, b string) float64 {
+	ra := []rune(strings.ToLower(a))
+	rb := []rune(strings.ToLower(b))
+	longest := max(len(ra), len(rb))
+	if longest == 0 {
+		return 1
+	}
+
+	// Two-row dynamic programming over edit distances
+	prev := make([]int, len(rb)+1)
+	curr := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		curr[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			curr[j] = min(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+		}
+		prev, curr = curr, prev
+	}
+
+	return 1 - float64(prev[len(rb)])/float64(longest)
+}
+
+// filterCalls drops calls rejected by the custom filter.
+func (a *Adapter) filterCalls(ctx context.Context, calls []functionCall) []functionCall {
+	kept := calls[:0]
+	for _, call := range calls {
+		if !a.applyToolCallFilter(call) {
+			a.log(LogCategoryPolicy).Debug("Dropped tool call rejected by filter",
+				"function_name", call.Name)
+			recordDroppedCalls(ctx, []functionCall{call}, DropReasonFilter)
+			continue
+		}
+		kept = append(kept, call)
+	}
+	return kept
+}
+
+// applyToolCallFilter runs the user-supplied filter, treating a panic as rejection.
+func (a *Adapter) applyToolCallFilter(call functionCall) (keep bool) {
+	defer func() {
+		if r := recover(); r != nil {
+			a.log(LogCategoryPolicy).Error("Tool call filter panicked, dropping call",
+				"function_name", call.Name,
+				"panic", r)
+			keep = false
+		}
+	}()
+	return a.toolCallFilter(call.Name, call.Parameters)
+}
+
+// deduplicateCalls removes calls identical to an earlier call or to one recorded in
+// seen, and records the kept calls in seen. A nil seen map limits deduplication to
+// calls. It returns the kept calls and the number removed. Calls are unchanged when
+// deduplication is disabled.
+func (a *Adapter) deduplicateCalls(calls []functionCall, seen map[string]struct{}) ([]functionCall, int) {
+	if !a.toolCallDedup || len(calls) == 0 {
+		return calls, 0
+	}
+	if seen == nil {
+		seen = make(map[string]struct{}, len(calls))
+	}
+	kept := calls[:0]
+	for _, call := range calls {
+		key := toolCallKey(call)
+		if _, dup := seen[key]; dup {
+			a.log(LogCategoryPolicy).Debug("Dropped duplicate tool call", "function_name", call.Name)
+			continue
+		}
+		seen[key] = struct{}{}
+		kept = append(kept, call)
+	}
+	return kept, len(calls) - len(kept)
+}
+
+// toolCallKey identifies a call by its name and canonical arguments. Arguments are
+// re-encoded so key order and whitespace do not matter, and null arguments equal
+// missing ones.
+func toolCallKey(call functionCall) string {
+	args := "null"
+	if len(call.Parameters) > 0 {
+		var value any
+		decoder := json.NewDecoder(bytes.NewReader(call.Parameters))
+		decoder.UseNumber() // Keep large integers exact
+		if err := decoder.Decode(&value); err == nil {
+			if canonical, err := json.Marshal(value); err == nil {
+				args = string(canonical)
+			}
+		} else {
+			args = string(call.Parameters)
+		}
+	}
+	return call.Name + "\x00" + args
+}
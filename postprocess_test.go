@@ -0,0 +1,264 @@
+package tooladapter_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	tooladapter "github.com/juburr/openai-tool-adapter/v3"
+	"github.com/openai/openai-go/v3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAllowedToolNames(t *testing.T) {
+	adapter := tooladapter.New(
+		tooladapter.WithToolPolicy(tooladapter.ToolDrainAll),
+		tooladapter.WithAllowedToolNames([]string{"get_weather", "get_time"}),
+	)
+
+	t.Run("DropsUnknownNames", func(t *testing.T) {
+		resp, err := adapter.TransformCompletionsResponse(createMockCompletion(
+			`[{"name": "get_weather", "parameters": null}, {"name": "delete_everything", "parameters": null}]`))
+		require.NoError(t, err)
+
+		toolCalls := resp.Choices[0].Message.ToolCalls
+		require.Len(t, toolCalls, 1)
+		assert.Equal(t, "get_weather", toolCalls[0].Function.Name)
+	})
+
+	t.Run("AllDroppedBecomesContent", func(t *testing.T) {
+		content := `[{"name": "delete_everything", "parameters": null}]`
+		resp, err := adapter.TransformCompletionsResponse(createMockCompletion(content))
+		require.NoError(t, err)
+
+		assert.Empty(t, resp.Choices[0].Message.ToolCalls)
+		assert.Equal(t, content, resp.Choices[0].Message.Content)
+	})
+
+	t.Run("Streaming", func(t *testing.T) {
+		content := `[{"name": "delete_everything", "parameters": null}]`
+		stream := adapter.TransformStreamingResponse(NewMockStream([]openai.ChatCompletionChunk{
+			createStreamChunk(content),
+			createFinishChunk("stop"),
+		}))
+
+		var text string
+		var toolCalls int
+		for stream.Next() {
+			chunk := stream.Current()
+			if len(chunk.Choices) == 0 {
+				continue
+			}
+			text += chunk.Choices[0].Delta.Content
+			toolCalls += len(chunk.Choices[0].Delta.ToolCalls)
+		}
+		require.NoError(t, stream.Err())
+		assert.Zero(t, toolCalls)
+		assert.Equal(t, content, text)
+	})
+
+	t.Run("MatchesOriginalNamesWhenNamespaced", func(t *testing.T) {
+		namespaced := tooladapter.New(
+			tooladapter.WithToolNamespace("svc"),
+			tooladapter.WithAllowedToolNames([]string{"get_weather"}),
+		)
+		resp, err := namespaced.TransformCompletionsResponse(createMockCompletion(
+			`{"name": "svc.get_weather", "parameters": null}`))
+		require.NoError(t, err)
+
+		require.Len(t, resp.Choices[0].Message.ToolCalls, 1)
+		assert.Equal(t, "get_weather", resp.Choices[0].Message.ToolCalls[0].Function.Name)
+	})
+
+	t.Run("EmptyListAllowsAll", func(t *testing.T) {
+		unrestricted := tooladapter.New(tooladapter.WithAllowedToolNames(nil))
+		resp, err := unrestricted.TransformCompletionsResponse(createMockCompletion(
+			`{"name": "anything", "parameters": null}`))
+		require.NoError(t, err)
+		assert.Len(t, resp.Choices[0].Message.ToolCalls, 1)
+	})
+}
+
+func TestToolCallFilter(t *testing.T) {
+	var seen []string
+	adapter := tooladapter.New(
+		tooladapter.WithToolPolicy(tooladapter.ToolDrainAll),
+		tooladapter.WithToolCallFilter(func(name string, args json.RawMessage) bool {
+			seen = append(seen, name+" "+string(args))
+			return name != "rm"
+		}),
+	)
+
+	resp, err := adapter.TransformCompletionsResponse(createMockCompletion(
+		`[{"name": "ls", "parameters": {"path": "/"}}, {"name": "rm", "parameters": null}]`))
+	require.NoError(t, err)
+
+	toolCalls := resp.Choices[0].Message.ToolCalls
+	require.Len(t, toolCalls, 1)
+	assert.Equal(t, "ls", toolCalls[0].Function.Name)
+	assert.Equal(t, []string{`ls {"path": "/"}`, "rm null"}, seen)
+
+	t.Run("PanicDropsCall", func(t *testing.T) {
+		panicking := tooladapter.New(tooladapter.WithToolCallFilter(func(string, json.RawMessage) bool {
+			panic("boom")
+		}))
+		resp, err := panicking.TransformCompletionsResponse(createMockCompletion(
+			`{"name": "ls", "parameters": null}`))
+		require.NoError(t, err)
+		assert.Empty(t, resp.Choices[0].Message.ToolCalls)
+	})
+}
+
+func TestUnknownToolPolicy(t *testing.T) {
+	allowed := tooladapter.WithAllowedToolNames([]string{"get_weather", "get_time"})
+	content := `[{"name": "get_weather", "parameters": null}, {"name": "getWeather", "parameters": {"city": "Rome"}}]`
+
+	t.Run("Drop", func(t *testing.T) {
+		adapter := tooladapter.New(allowed,
+			tooladapter.WithToolPolicy(tooladapter.ToolDrainAll),
+			tooladapter.WithUnknownToolPolicy(tooladapter.UnknownToolDrop))
+		resp, err := adapter.TransformCompletionsResponse(createMockCompletion(content))
+		require.NoError(t, err)
+		assert.Len(t, resp.Choices[0].Message.ToolCalls, 1)
+	})
+
+	t.Run("AsContent", func(t *testing.T) {
+		adapter := tooladapter.New(allowed,
+			tooladapter.WithToolPolicy(tooladapter.ToolDrainAll),
+			tooladapter.WithUnknownToolPolicy(tooladapter.UnknownToolAsContent))
+		resp, err := adapter.TransformCompletionsResponse(createMockCompletion(content))
+		require.NoError(t, err)
+		assert.Empty(t, resp.Choices[0].Message.ToolCalls)
+		assert.Equal(t, content, resp.Choices[0].Message.Content)
+	})
+
+	t.Run("Error", func(t *testing.T) {
+		adapter := tooladapter.New(allowed, tooladapter.WithUnknownToolPolicy(tooladapter.UnknownToolError))
+		_, err := adapter.TransformCompletionsResponse(createMockCompletion(content))
+
+		var unknown *tooladapter.UnknownToolCallError
+		require.ErrorAs(t, err, &unknown)
+		assert.Equal(t, "getWeather", unknown.Name)
+	})
+
+	t.Run("ErrorStreaming", func(t *testing.T) {
+		adapter := tooladapter.New(allowed, tooladapter.WithUnknownToolPolicy(tooladapter.UnknownToolError))
+		stream := adapter.TransformStreamingResponse(NewMockStream([]openai.ChatCompletionChunk{
+			createStreamChunk(content),
+			createFinishChunk("stop"),
+		}))
+
+		chunks := 0
+		for stream.Next() {
+			chunks++
+		}
+		assert.Zero(t, chunks)
+
+		var unknown *tooladapter.UnknownToolCallError
+		require.ErrorAs(t, stream.Err(), &unknown)
+	})
+
+	t.Run("Correct", func(t *testing.T) {
+		adapter := tooladapter.New(allowed,
+			tooladapter.WithToolPolicy(tooladapter.ToolDrainAll),
+			tooladapter.WithUnknownToolPolicy(tooladapter.UnknownToolCorrect))
+		resp, err := adapter.TransformCompletionsResponse(createMockCompletion(content))
+		require.NoError(t, err)
+
+		toolCalls := resp.Choices[0].Message.ToolCalls
+		require.Len(t, toolCalls, 2)
+		assert.Equal(t, "get_weather", toolCalls[1].Function.Name)
+		assert.JSONEq(t, `{"city": "Rome"}`, toolCalls[1].Function.Arguments)
+	})
+
+	t.Run("CorrectBelowThreshold", func(t *testing.T) {
+		adapter := tooladapter.New(allowed,
+			tooladapter.WithUnknownToolPolicy(tooladapter.UnknownToolCorrect),
+			tooladapter.WithUnknownToolMatchThreshold(0.95))
+		resp, err := adapter.TransformCompletionsResponse(createMockCompletion(
+			`{"name": "getWeather", "parameters": null}`))
+		require.NoError(t, err)
+		assert.Empty(t, resp.Choices[0].Message.ToolCalls)
+	})
+
+	t.Run("NoEffectWithoutAllowedNames", func(t *testing.T) {
+		adapter := tooladapter.New(tooladapter.WithUnknownToolPolicy(tooladapter.UnknownToolError))
+		resp, err := adapter.TransformCompletionsResponse(createMockCompletion(content))
+		require.NoError(t, err)
+		assert.Len(t, resp.Choices[0].Message.ToolCalls, 1)
+	})
+
+	t.Run("PolicyString", func(t *testing.T) {
+		assert.Equal(t, "UnknownToolCorrect", tooladapter.UnknownToolCorrect.String())
+		assert.Equal(t, "UnknownToolPolicy(7)", tooladapter.UnknownToolPolicy(7).String())
+	})
+}
+
+func TestToolCallDeduplication(t *testing.T) {
+	duplicated := `[{"name": "get_weather", "parameters": {"city": "Paris", "unit": "c"}},
+		{"name": "get_weather", "parameters": {"unit": "c", "city": "Paris"}},
+		{"name": "get_weather", "parameters": {"city": "Rome", "unit": "c"}}]`
+
+	t.Run("CollapsesEqualArguments", func(t *testing.T) {
+		var detection tooladapter.FunctionCallDetectionData
+		adapter := tooladapter.New(
+			tooladapter.WithToolPolicy(tooladapter.ToolDrainAll),
+			tooladapter.WithToolCallDeduplication(true),
+			tooladapter.WithMetricsCallback(func(data tooladapter.MetricEventData) {
+				if d, ok := data.(tooladapter.FunctionCallDetectionData); ok {
+					detection = d
+				}
+			}))
+		resp, err := adapter.TransformCompletionsResponse(createMockCompletion(duplicated))
+		require.NoError(t, err)
+
+		toolCalls := resp.Choices[0].Message.ToolCalls
+		require.Len(t, toolCalls, 2)
+		assert.JSONEq(t, `{"city": "Paris", "unit": "c"}`, toolCalls[0].Function.Arguments)
+		assert.JSONEq(t, `{"city": "Rome", "unit": "c"}`, toolCalls[1].Function.Arguments)
+		assert.Equal(t, 1, detection.DuplicatesRemoved)
+		assert.Equal(t, 2, detection.FunctionCount)
+	})
+
+	t.Run("DisabledByDefault", func(t *testing.T) {
+		adapter := tooladapter.New(tooladapter.WithToolPolicy(tooladapter.ToolDrainAll))
+		resp, err := adapter.TransformCompletionsResponse(createMockCompletion(duplicated))
+		require.NoError(t, err)
+		assert.Len(t, resp.Choices[0].Message.ToolCalls, 3)
+	})
+
+	t.Run("NullAndMissingArgumentsMatch", func(t *testing.T) {
+		adapter := tooladapter.New(
+			tooladapter.WithToolPolicy(tooladapter.ToolDrainAll),
+			tooladapter.WithToolCallDeduplication(true))
+		resp, err := adapter.TransformCompletionsResponse(createMockCompletion(
+			`[{"name": "get_time", "parameters": null}, {"name": "get_time"}]`))
+		require.NoError(t, err)
+		assert.Len(t, resp.Choices[0].Message.ToolCalls, 1)
+	})
+
+	t.Run("StreamingAcrossBlocks", func(t *testing.T) {
+		// The call is written once in prose and repeated in a code fence
+		adapter := tooladapter.New(
+			tooladapter.WithToolPolicy(tooladapter.ToolCollectThenStop),
+			tooladapter.WithToolCallDeduplication(true))
+		stream := adapter.TransformStreamingResponse(NewMockStream([]openai.ChatCompletionChunk{
+			createStreamChunk(`{"name": "get_weather", "parameters": {"city": "Paris"}}`),
+			createStreamChunk("\nTo be explicit:\n```json\n"),
+			createStreamChunk(`{"name": "get_weather", "parameters": {"city":"Paris"}}`),
+			createStreamChunk("\n```"),
+			createFinishChunk("stop"),
+		}))
+
+		var toolCalls []openai.ChatCompletionChunkChoiceDeltaToolCall
+		for stream.Next() {
+			chunk := stream.Current()
+			if len(chunk.Choices) > 0 {
+				toolCalls = append(toolCalls, chunk.Choices[0].Delta.ToolCalls...)
+			}
+		}
+		require.NoError(t, stream.Err())
+		require.Len(t, toolCalls, 1)
+		assert.Equal(t, "get_weather", toolCalls[0].Function.Name)
+	})
+}
@@ -0,0 +1,82 @@
+package tooladapter_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	tooladapter "github.com/juburr/openai-tool-adapter/v3"
+	"github.com/juburr/openai-tool-adapter/v3/tooltest"
+	"github.com/openai/openai-go/v3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const fencePrefill = "```json\n["
+
+func TestWithAssistantPrefill(t *testing.T) {
+	adapter := tooladapter.New(
+		tooladapter.WithAssistantPrefill(fencePrefill),
+		tooladapter.WithToolPolicy(tooladapter.ToolDrainAll),
+	)
+
+	t.Run("AppendsAssistantTurn", func(t *testing.T) {
+		transformed, err := adapter.TransformCompletionsRequest(tooltest.Request(tooltest.Tool("get_weather", "Get the weather")))
+		require.NoError(t, err)
+
+		last := transformed.Messages[len(transformed.Messages)-1]
+		require.NotNil(t, last.OfAssistant)
+		assert.Equal(t, fencePrefill, last.OfAssistant.Content.OfString.Value)
+	})
+
+	t.Run("OnlyWithTools", func(t *testing.T) {
+		req := tooltest.Request()
+		req.Messages = append(req.Messages, openai.ToolMessage("sunny", "call_1"))
+		transformed, err := adapter.TransformCompletionsRequest(req)
+		require.NoError(t, err)
+
+		for _, msg := range transformed.Messages {
+			assert.Nil(t, msg.OfAssistant)
+		}
+	})
+
+	t.Run("ParsesContinuation", func(t *testing.T) {
+		resp, err := adapter.TransformCompletionsResponse(tooltest.Completion(
+			`{"name": "get_weather", "parameters": {"city": "Paris"}}, {"name": "get_time", "parameters": {}}]` + "\n```"))
+		require.NoError(t, err)
+
+		toolCalls := resp.Choices[0].Message.ToolCalls
+		require.Len(t, toolCalls, 2)
+		assert.Equal(t, "get_weather", toolCalls[0].Function.Name)
+		assert.Equal(t, "get_time", toolCalls[1].Function.Name)
+	})
+
+	t.Run("RepeatedPrefill", func(t *testing.T) {
+		resp, err := adapter.TransformCompletionsResponse(tooltest.Completion(fencePrefill + weatherJSON + "]\n```"))
+		require.NoError(t, err)
+		require.Len(t, resp.Choices[0].Message.ToolCalls, 1)
+	})
+
+	t.Run("TextUnchanged", func(t *testing.T) {
+		resp, err := adapter.TransformCompletionsResponse(tooltest.Completion("It is sunny in Paris."))
+		require.NoError(t, err)
+		assert.Empty(t, resp.Choices[0].Message.ToolCalls)
+		assert.Equal(t, "It is sunny in Paris.", resp.Choices[0].Message.Content)
+	})
+
+	t.Run("Streaming", func(t *testing.T) {
+		stream := adapter.TransformStreamingResponseWithContext(context.Background(), tooltest.NewContentStream(
+			`{"name": "get_weather", `, `"parameters": {"city": "Paris"}}, `,
+			`{"name": "get_time", "parameters": {}}]`, "\n```"))
+		result := tooltest.Drain(stream)
+		require.NoError(t, result.Err)
+		assert.Equal(t, []string{"get_weather", "get_time"}, result.ToolNames())
+		assert.NotContains(t, result.Content, "get_time")
+	})
+
+	t.Run("Config", func(t *testing.T) {
+		cfg, err := tooladapter.LoadConfig(strings.NewReader(`{"assistant_prefill": "` + "```json\\n[" + `"}`))
+		require.NoError(t, err)
+		assert.Equal(t, fencePrefill, cfg.AssistantPrefill)
+	})
+}
@@ -0,0 +1,60 @@
+package tooladapter_test
+
+import (
+	"strings"
+	"testing"
+
+	tooladapter "github.com/juburr/openai-tool-adapter/v3"
+	"github.com/juburr/openai-tool-adapter/v3/tooltest"
+	"github.com/openai/openai-go/v3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWithPreserveToolsField(t *testing.T) {
+	req := tooltest.Request(tooltest.Tool("get_weather", "Get the weather"))
+	req.ToolChoice = openai.ChatCompletionToolChoiceOptionUnionParam{OfAuto: openai.String("auto")}
+	req.ParallelToolCalls = openai.Bool(true)
+
+	t.Run("Preserved", func(t *testing.T) {
+		transformed, err := tooladapter.New(tooladapter.WithPreserveToolsField(true)).TransformCompletionsRequest(req)
+		require.NoError(t, err)
+
+		require.Len(t, transformed.Tools, 1)
+		assert.Equal(t, "get_weather", transformed.Tools[0].GetFunction().Name)
+		assert.Equal(t, "auto", transformed.ToolChoice.OfAuto.Value)
+		assert.True(t, transformed.ParallelToolCalls.Value)
+		assert.Contains(t, injectedText(t, transformed), "get_weather", "the prompt is still injected")
+	})
+
+	t.Run("StrippedByDefault", func(t *testing.T) {
+		transformed, err := tooladapter.New().TransformCompletionsRequest(req)
+		require.NoError(t, err)
+
+		assert.Empty(t, transformed.Tools)
+		assert.False(t, transformed.ToolChoice.OfAuto.Valid())
+		assert.False(t, transformed.ParallelToolCalls.Valid())
+	})
+
+	t.Run("NativeCallsPassThrough", func(t *testing.T) {
+		adapter := tooladapter.New(tooladapter.WithPreserveToolsField(true))
+		resp := tooltest.Completion("")
+		resp.Choices[0].FinishReason = "tool_calls"
+		resp.Choices[0].Message.ToolCalls = []openai.ChatCompletionMessageToolCallUnion{{
+			ID:       "call_native",
+			Type:     "function",
+			Function: openai.ChatCompletionMessageFunctionToolCallFunction{Name: "get_weather", Arguments: `{"city":"Paris"}`},
+		}}
+
+		transformed, err := adapter.TransformCompletionsResponse(resp)
+		require.NoError(t, err)
+		require.Len(t, transformed.Choices[0].Message.ToolCalls, 1)
+		assert.Equal(t, "call_native", transformed.Choices[0].Message.ToolCalls[0].ID)
+	})
+
+	t.Run("Config", func(t *testing.T) {
+		cfg, err := tooladapter.LoadConfig(strings.NewReader(`{"preserve_tools_field": true}`))
+		require.NoError(t, err)
+		assert.True(t, cfg.PreserveToolsField)
+	})
+}
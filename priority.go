@@ -0,0 +1,35 @@
+package tooladapter
+
+import "sort"
+
+// prioritizeCandidates orders candidates, whose formats are given in the parallel
+// slice formats, by the WithParserPriority rank of their format. Formats not in the
+// priority follow those that are, and candidates of the same rank keep the order
+// extractCandidates found them in, so the first candidate of the highest ranked
+// format that holds a call wins.
+func (a *Adapter) prioritizeCandidates(candidates []string, formats []CallFormat) []string {
+	if len(a.parserPriority) == 0 || len(candidates) < 2 {
+		return candidates
+	}
+	rank := func(format CallFormat) int {
+		for i, prioritized := range a.parserPriority {
+			if prioritized == format {
+				return i
+			}
+		}
+		return len(a.parserPriority)
+	}
+
+	order := make([]int, len(candidates))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(i, j int) bool {
+		return rank(formats[order[i]]) < rank(formats[order[j]])
+	})
+	prioritized := make([]string, len(candidates))
+	for i, index := range order {
+		prioritized[i] = candidates[index]
+	}
+	return prioritized
+}
@@ -0,0 +1,95 @@
+package tooladapter_test
+
+import (
+	"strings"
+	"testing"
+
+	tooladapter "github.com/juburr/openai-tool-adapter/v3"
+	"github.com/juburr/openai-tool-adapter/v3/tooltest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWithParserPriority(t *testing.T) {
+	// A bare JSON call, then a fenced JSON call, then a fenced Python block
+	const content = `{"name": "bare", "parameters": {}}` + "\n\n" +
+		"```json\n" + `{"name": "fenced", "parameters": {}}` + "\n```\n\n" +
+		"```python\nprint(1)\n```"
+	codeBlocks := tooladapter.WithCodeBlockTools(map[string]tooladapter.CodeBlockTool{"python": {Name: "execute_python"}})
+
+	firstCall := func(t *testing.T, adapter *tooladapter.Adapter, content string) string {
+		t.Helper()
+		resp, err := adapter.TransformCompletionsResponse(tooltest.Completion(content))
+		require.NoError(t, err)
+		require.NotEmpty(t, resp.Choices[0].Message.ToolCalls)
+		return resp.Choices[0].Message.ToolCalls[0].Function.Name
+	}
+
+	t.Run("DefaultOrder", func(t *testing.T) {
+		assert.Equal(t, "execute_python", firstCall(t, tooladapter.New(codeBlocks), content))
+		assert.Equal(t, "bare", firstCall(t, tooladapter.New(), content), "JSON formats in order of appearance")
+	})
+
+	t.Run("Order", func(t *testing.T) {
+		tests := []struct {
+			order    []tooladapter.CallFormat
+			expected string
+		}{
+			{[]tooladapter.CallFormat{tooladapter.CallFormatCodeFence}, "fenced"},
+			{[]tooladapter.CallFormat{tooladapter.CallFormatJSON}, "bare"},
+			{[]tooladapter.CallFormat{tooladapter.CallFormatCodeFence, tooladapter.CallFormatTextBlock}, "fenced"},
+			{[]tooladapter.CallFormat{tooladapter.CallFormatJSON, tooladapter.CallFormatCodeFence, tooladapter.CallFormatTextBlock}, "bare"},
+			{[]tooladapter.CallFormat{tooladapter.CallFormatLenient}, "execute_python"},
+		}
+		for _, tt := range tests {
+			adapter := tooladapter.New(codeBlocks, tooladapter.WithParserPriority(tt.order...))
+			assert.Equal(t, tt.expected, firstCall(t, adapter, content), "order %v", tt.order)
+		}
+	})
+
+	t.Run("TieBreakByPosition", func(t *testing.T) {
+		adapter := tooladapter.New(tooladapter.WithParserPriority(tooladapter.CallFormatCodeFence))
+		twoFences := "```json\n" + `{"name": "first", "parameters": {}}` + "\n```\n" +
+			"```json\n" + `{"name": "second", "parameters": {}}` + "\n```"
+		for range 10 {
+			assert.Equal(t, "first", firstCall(t, adapter, twoFences))
+		}
+	})
+
+	t.Run("FallsBackToLowerPriority", func(t *testing.T) {
+		adapter := tooladapter.New(codeBlocks, tooladapter.WithParserPriority(tooladapter.CallFormatCodeFence))
+		assert.Equal(t, "execute_python", firstCall(t, adapter, `{"name": "bare", "parameters": {}}`+"\n```python\nprint(1)\n```"),
+			"text blocks still precede the unlisted JSON format by default")
+	})
+
+	t.Run("Lenient", func(t *testing.T) {
+		mixed := `{name: 'json5', parameters: {}}` + "\n" + "```json\n" + `{"name": "strict", "parameters": {}}` + "\n```"
+		lenient := tooladapter.New(tooladapter.WithLenientParsing(true))
+		assert.Equal(t, "strict", firstCall(t, lenient, mixed))
+		preferred := tooladapter.New(tooladapter.WithLenientParsing(true), tooladapter.WithParserPriority(tooladapter.CallFormatLenient))
+		assert.Equal(t, "json5", firstCall(t, preferred, mixed))
+	})
+
+	t.Run("InvalidFormatsIgnored", func(t *testing.T) {
+		adapter := tooladapter.New(tooladapter.WithParserPriority(tooladapter.CallFormatUnknown, tooladapter.CallFormat(42), tooladapter.CallFormatCodeFence))
+		assert.Equal(t, "fenced", firstCall(t, adapter, content))
+	})
+
+	t.Run("Streaming", func(t *testing.T) {
+		adapter := tooladapter.New(tooladapter.WithParserPriority(tooladapter.CallFormatCodeFence))
+		// Streams parse once a call is complete, so only formats already read compete
+		result := tooltest.Drain(adapter.TransformStreamingResponse(tooltest.NewContentStream(content)))
+		require.NoError(t, result.Err)
+		require.NotEmpty(t, result.ToolCalls)
+		assert.Equal(t, "fenced", result.ToolCalls[0].Function.Name)
+	})
+
+	t.Run("Config", func(t *testing.T) {
+		cfg, err := tooladapter.LoadConfig(strings.NewReader(`{"parser_priority": ["code_fence", "CallFormatJSON"]}`))
+		require.NoError(t, err)
+		assert.Equal(t, []tooladapter.CallFormat{tooladapter.CallFormatCodeFence, tooladapter.CallFormatJSON}, cfg.ParserPriority)
+
+		_, err = tooladapter.LoadConfig(strings.NewReader(`{"parser_priority": ["pythonic"]}`))
+		assert.ErrorIs(t, err, tooladapter.ErrInvalidConfig)
+	})
+}
@@ -0,0 +1,231 @@
+package tooladapter
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/openai/openai-go/v3"
+)
+
+// CallFormat is the way a model writes a prompted tool call, as detected by
+// ProbeModel and ordered by WithParserPriority.
+type CallFormat int
+
+const (
+	// CallFormatUnknown means the model did not produce a recognizable tool call.
+	CallFormatUnknown CallFormat = iota
+
+	// CallFormatJSON means the model writes bare JSON, optionally around prose.
+	CallFormatJSON
+
+	// CallFormatCodeFence means the model wraps the JSON in a ``` code fence.
+	CallFormatCodeFence
+
+	// CallFormatLenient means the model writes JSON5 or YAML, which only parses
+	// with WithLenientParsing.
+	CallFormatLenient
+
+	// CallFormatTextBlock means the model writes a fenced code block tagged with a
+	// WithToolArgumentModes text tool or a WithCodeBlockTools language. ProbeModel
+	// never reports it.
+	CallFormatTextBlock
+)
+
+// String returns a human-readable string representation of the CallFormat.
+func (f CallFormat) String() string {
+	switch f {
+	case CallFormatUnknown:
+		return "CallFormatUnknown"
+	case CallFormatJSON:
+		return "CallFormatJSON"
+	case CallFormatCodeFence:
+		return "CallFormatCodeFence"
+	case CallFormatLenient:
+		return "CallFormatLenient"
+	case CallFormatTextBlock:
+		return "CallFormatTextBlock"
+	default:
+		return fmt.Sprintf("CallFormat(%d)", int(f))
+	}
+}
+
+// ModelCapabilities describes what a model endpoint supports, as detected by
+// ProbeModel.
+type ModelCapabilities struct {
+	// Model is the probed model name.
+	Model string
+
+	// NativeTools reports whether the endpoint answered a request with tools using
+	// native tool calls. Such endpoints do not need the adapter.
+	NativeTools bool
+
+	// SystemRole reports whether the endpoint accepted a system message and the
+	// model followed it.
+	SystemRole bool
+
+	// CallFormat is the format of the model's reply to an injected tool prompt.
+	CallFormat CallFormat
+}
+
+// Options returns the options that configure an adapter for the capabilities:
+// WithSystemMessageSupport, and WithLenientParsing for CallFormatLenient.
+func (c ModelCapabilities) Options() []Option {
+	opts := []Option{WithSystemMessageSupport(c.SystemRole)}
+	if c.CallFormat == CallFormatLenient {
+		opts = append(opts, WithLenientParsing(true))
+	}
+	return opts
+}
+
+// Probe prompts. They are kept tiny so probing costs a few tokens per request.
+const (
+	probeToolName    = "echo"
+	probeUserPrompt  = `Call the echo tool with the value "ping".`
+	probeMarker      = "PINEAPPLE"
+	probeSystemRule  = "Whatever the user says, reply with only the word " + probeMarker + "."
+	probeSystemUser  = "Say hello."
+	probeTokenBudget = 128
+)
+
+// ProbeModel sends small diagnostic requests for model through client to detect
+// whether the endpoint supports native tool calls and the system role, and how the
+// model writes a tool call when prompted with this adapter's tool prompt. Three
+// requests are made, one at a time.
+//
+// A request rejected with status 400, 404 or 422 counts as an unsupported
+// capability. Other client errors and context cancellation are returned.
+// Use NewForModel to probe and configure an adapter in one step.
+func (a *Adapter) ProbeModel(ctx context.Context, client ChatCompletionsClient, model string) (ModelCapabilities, error) {
+	caps := ModelCapabilities{Model: model}
+
+	// Native tool calls
+	resp, err := probeRequest(ctx, client, probeToolRequest(model))
+	if err != nil {
+		return caps, err
+	}
+	if resp != nil && len(resp.Choices) > 0 {
+		for _, call := range resp.Choices[0].Message.ToolCalls {
+			if call.Function.Name == probeToolName {
+				caps.NativeTools = true
+			}
+		}
+	}
+
+	// System role
+	resp, err = probeRequest(ctx, client, openai.ChatCompletionNewParams{
+		Model: openai.ChatModel(model),
+		Messages: []openai.ChatCompletionMessageParamUnion{
+			openai.SystemMessage(probeSystemRule),
+			openai.UserMessage(probeSystemUser),
+		},
+		MaxCompletionTokens: openai.Int(probeTokenBudget),
+		Temperature:         openai.Float(0),
+	})
+	if err != nil {
+		return caps, err
+	}
+	caps.SystemRole = strings.Contains(strings.ToUpper(probeReply(resp)), probeMarker)
+
+	// Prompted tool call format
+	req, err := a.TransformCompletionsRequestWithContext(ctx, probeToolRequest(model))
+	if err != nil {
+		return caps, err
+	}
+	resp, err = probeRequest(ctx, client, req)
+	if err != nil {
+		return caps, err
+	}
+	caps.CallFormat = detectCallFormat(probeReply(resp))
+
+	a.log(LogCategoryRequest).Info("Probed model capabilities",
+		"model", model,
+		"native_tools", caps.NativeTools,
+		"system_role", caps.SystemRole,
+		"call_format", caps.CallFormat.String())
+	return caps, nil
+}
+
+// NewForModel probes model with ProbeModel and returns an adapter configured for
+// its capabilities. opts are applied both to the probing adapter and after the
+// probed options, so explicit settings take precedence. On error, the adapter is
+// nil and the capabilities hold what was detected before the failure.
+func NewForModel(ctx context.Context, client ChatCompletionsClient, model string, opts ...Option) (*Adapter, ModelCapabilities, error) {
+	caps, err := New(opts...).ProbeModel(ctx, client, model)
+	if err != nil {
+		return nil, caps, err
+	}
+	return New(append(caps.Options(), opts...)...), caps, nil
+}
+
+// probeToolRequest returns a request that asks for a call to the probe tool.
+func probeToolRequest(model string) openai.ChatCompletionNewParams {
+	return openai.ChatCompletionNewParams{
+		Model:    openai.ChatModel(model),
+		Messages: []openai.ChatCompletionMessageParamUnion{openai.UserMessage(probeUserPrompt)},
+		Tools: []openai.ChatCompletionToolUnionParam{
+			openai.ChatCompletionFunctionTool(openai.FunctionDefinitionParam{
+				Name:        probeToolName,
+				Description: openai.String("Echo a value back"),
+				Parameters: openai.FunctionParameters{
+					"type": "object",
+					"properties": map[string]any{
+						"value": map[string]any{"type": "string"},
+					},
+					"required": []string{"value"},
+				},
+			}),
+		},
+		MaxCompletionTokens: openai.Int(probeTokenBudget),
+		Temperature:         openai.Float(0),
+	}
+}
+
+// probeRequest sends req, returning a nil completion when the endpoint rejected it
+// as unsupported.
+func probeRequest(ctx context.Context, client ChatCompletionsClient, req openai.ChatCompletionNewParams) (*openai.ChatCompletion, error) {
+	resp, err := client.New(ctx, req)
+	var apiErr *openai.Error
+	if errors.As(err, &apiErr) {
+		switch apiErr.StatusCode {
+		case http.StatusBadRequest, http.StatusNotFound, http.StatusUnprocessableEntity:
+			return nil, nil
+		}
+	}
+	return resp, err
+}
+
+// probeReply returns the first choice's content without think blocks.
+func probeReply(resp *openai.ChatCompletion) string {
+	if resp == nil || len(resp.Choices) == 0 {
+		return ""
+	}
+	_, rest := SplitThinkBlocks(resp.Choices[0].Message.Content)
+	return rest
+}
+
+// detectCallFormat classifies how content writes a call to the probe tool.
+func detectCallFormat(content string) CallFormat {
+	candidates := extractJSONBlocks(content)
+	if callsProbeTool(ExtractFunctionCalls(candidates)) {
+		if strings.Contains(content, "```") {
+			return CallFormatCodeFence
+		}
+		return CallFormatJSON
+	}
+	if callsProbeTool(ExtractFunctionCalls(lenientCandidates(content, candidates))) {
+		return CallFormatLenient
+	}
+	return CallFormatUnknown
+}
+
+func callsProbeTool(calls []functionCall) bool {
+	for _, call := range calls {
+		if call.Name == probeToolName {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,112 @@
+package tooladapter_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	tooladapter "github.com/juburr/openai-tool-adapter/v3"
+	"github.com/juburr/openai-tool-adapter/v3/tooltest"
+	"github.com/openai/openai-go/v3"
+	"github.com/openai/openai-go/v3/option"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// probedEndpoint simulates a backend for ProbeModel. Requests with tools fail with
+// status 400 unless nativeTools is set, and system messages fail unless systemRole
+// is set. Prompted tool calls are answered with callReply.
+type probedEndpoint struct {
+	nativeTools bool
+	systemRole  bool
+	callReply   string
+	err         error
+	requests    []openai.ChatCompletionNewParams
+}
+
+func (e *probedEndpoint) New(_ context.Context, body openai.ChatCompletionNewParams, _ ...option.RequestOption) (*openai.ChatCompletion, error) {
+	e.requests = append(e.requests, body)
+	if e.err != nil {
+		return nil, e.err
+	}
+	rejected := &openai.Error{StatusCode: 400}
+	switch {
+	case len(body.Tools) > 0:
+		if !e.nativeTools {
+			return nil, rejected
+		}
+		var completion openai.ChatCompletion
+		err := json.Unmarshal([]byte(`{"choices": [{"index": 0, "finish_reason": "tool_calls", "message": {"role": "assistant",
+			"tool_calls": [{"id": "call_1", "type": "function", "function": {"name": "echo", "arguments": "{\"value\": \"ping\"}"}}]}}]}`), &completion)
+		return &completion, err
+	case body.Messages[0].OfSystem != nil:
+		if !e.systemRole {
+			return nil, rejected
+		}
+		completion := tooltest.Completion("PINEAPPLE")
+		return &completion, nil
+	default:
+		completion := tooltest.Completion(e.callReply)
+		return &completion, nil
+	}
+}
+
+func TestProbeModel(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("PromptedModel", func(t *testing.T) {
+		endpoint := &probedEndpoint{callReply: "```json\n" + `{"name": "echo", "parameters": {"value": "ping"}}` + "\n```"}
+		caps, err := tooladapter.New().ProbeModel(ctx, endpoint, "gemma-3")
+		require.NoError(t, err)
+		assert.Equal(t, tooladapter.ModelCapabilities{Model: "gemma-3", CallFormat: tooladapter.CallFormatCodeFence}, caps)
+
+		require.Len(t, endpoint.requests, 3)
+		for _, req := range endpoint.requests {
+			assert.Equal(t, openai.ChatModel("gemma-3"), req.Model)
+		}
+		assert.Empty(t, endpoint.requests[2].Tools, "the format probe uses the injected tool prompt")
+	})
+
+	t.Run("NativeModel", func(t *testing.T) {
+		endpoint := &probedEndpoint{nativeTools: true, systemRole: true,
+			callReply: `{"name": "echo", "parameters": {"value": "ping"}}`}
+		caps, err := tooladapter.New().ProbeModel(ctx, endpoint, "gpt-4o")
+		require.NoError(t, err)
+		assert.True(t, caps.NativeTools)
+		assert.True(t, caps.SystemRole)
+		assert.Equal(t, tooladapter.CallFormatJSON, caps.CallFormat)
+	})
+
+	t.Run("UnrecognizedFormat", func(t *testing.T) {
+		caps, err := tooladapter.New().ProbeModel(ctx, &probedEndpoint{callReply: "I cannot call tools."}, "tiny")
+		require.NoError(t, err)
+		assert.Equal(t, tooladapter.CallFormatUnknown, caps.CallFormat)
+	})
+
+	t.Run("ClientError", func(t *testing.T) {
+		failure := errors.New("connection refused")
+		_, err := tooladapter.New().ProbeModel(ctx, &probedEndpoint{err: failure}, "gemma-3")
+		require.ErrorIs(t, err, failure)
+	})
+}
+
+func TestNewForModel(t *testing.T) {
+	endpoint := &probedEndpoint{systemRole: true, callReply: "name: echo\nparameters:\n  value: ping"}
+	adapter, caps, err := tooladapter.NewForModel(context.Background(), endpoint, "small")
+	require.NoError(t, err)
+	assert.Equal(t, tooladapter.CallFormatLenient, caps.CallFormat)
+	assert.True(t, caps.SystemRole)
+
+	// Lenient parsing was enabled from the probe
+	resp, err := adapter.TransformCompletionsResponse(tooltest.Completion("name: echo\nparameters:\n  value: pong"))
+	require.NoError(t, err)
+	require.Len(t, resp.Choices[0].Message.ToolCalls, 1)
+
+	// Explicit options take precedence over probed ones
+	adapter, _, err = tooladapter.NewForModel(context.Background(), endpoint, "small", tooladapter.WithLenientParsing(false))
+	require.NoError(t, err)
+	resp, err = adapter.TransformCompletionsResponse(tooltest.Completion("name: echo\nparameters:\n  value: pong"))
+	require.NoError(t, err)
+	assert.Empty(t, resp.Choices[0].Message.ToolCalls)
+}
@@ -0,0 +1,53 @@
+package tooladapter
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+
+	"github.com/openai/openai-go/v3"
+)
+
+// renderedToolPrompt is a tool prompt with the schema sizes reported in
+// ToolTransformationData, measured only when a metrics callback is configured.
+type renderedToolPrompt struct {
+	text              string
+	schemaBytes       int
+	sourceSchemaBytes int
+	measured          bool
+}
+
+// cachedToolPrompt returns the tool prompt for tools, from the WithPromptCache cache
+// when it holds one, and reports whether it did. The cache is keyed by a hash of the
+// tool definitions alone: the adapter's template and options are fixed when it is
+// created.
+func (a *Adapter) cachedToolPrompt(ctx context.Context, tools []openai.ChatCompletionToolUnionParam) (renderedToolPrompt, bool, error) {
+	var key [sha256.Size]byte
+	cacheable := false
+	if a.promptCache != nil {
+		if definitions, err := json.Marshal(tools); err == nil {
+			key = sha256.Sum256(definitions)
+			cacheable = true
+		}
+	}
+	if cacheable {
+		if prompt, ok := a.promptCache.get(key); ok {
+			a.log(LogCategoryRequest).Debug("Tool prompt served from cache", "tool_count", len(tools))
+			return prompt, true, nil
+		}
+	}
+
+	text, err := a.buildToolPromptWithContext(ctx, tools)
+	if err != nil {
+		return renderedToolPrompt{}, false, err
+	}
+	prompt := renderedToolPrompt{text: text}
+	if a.metricsCallback != nil {
+		prompt.schemaBytes, prompt.sourceSchemaBytes = a.schemaSizes(tools)
+		prompt.measured = true
+	}
+	if cacheable {
+		a.promptCache.put(key, prompt)
+	}
+	return prompt, false, nil
+}
@@ -0,0 +1,135 @@
+package tooladapter_test
+
+import (
+	"bytes"
+	"log/slog"
+	"strings"
+	"sync"
+	"testing"
+
+	tooladapter "github.com/juburr/openai-tool-adapter/v3"
+	"github.com/juburr/openai-tool-adapter/v3/tooltest"
+	"github.com/openai/openai-go/v3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// promptCacheHits returns the PromptCacheHit of every ToolTransformationData event.
+func promptCacheHits(collector *MetricsCollector) []bool {
+	var hits []bool
+	for _, event := range phaseEvents[tooladapter.ToolTransformationData](collector) {
+		hits = append(hits, event.PromptCacheHit)
+	}
+	return hits
+}
+
+func TestWithPromptCache(t *testing.T) {
+	weather := tooltest.Request(tooltest.Tool("get_weather", "Get the weather"))
+	search := tooltest.Request(tooltest.Tool("search", "Search the web"))
+
+	t.Run("HitsForSameTools", func(t *testing.T) {
+		collector := NewMetricsCollector()
+		adapter := tooladapter.New(
+			tooladapter.WithPromptCache(8),
+			tooladapter.WithMetricsCallback(collector.Callback),
+		)
+
+		first, err := adapter.TransformCompletionsRequest(weather)
+		require.NoError(t, err)
+		second, err := adapter.TransformCompletionsRequest(weather)
+		require.NoError(t, err)
+
+		assert.Equal(t, injectedText(t, first), injectedText(t, second))
+		assert.Equal(t, []bool{false, true}, promptCacheHits(collector))
+
+		events := phaseEvents[tooladapter.ToolTransformationData](collector)
+		assert.Equal(t, events[0].SchemaBytes, events[1].SchemaBytes)
+	})
+
+	t.Run("MatchesUncachedPrompt", func(t *testing.T) {
+		withResults := search
+		withResults.Messages = append(withResults.Messages, openai.ToolMessage("42 results", "call_1"))
+		single := search
+		single.ParallelToolCalls = openai.Bool(false)
+
+		cached := tooladapter.New(tooladapter.WithPromptCache(8))
+		for _, req := range []openai.ChatCompletionNewParams{search, withResults, single, withResults, single} {
+			want, err := tooladapter.New().TransformCompletionsRequest(req)
+			require.NoError(t, err)
+			got, err := cached.TransformCompletionsRequest(req)
+			require.NoError(t, err)
+			assert.Equal(t, injectedText(t, want), injectedText(t, got))
+		}
+	})
+
+	t.Run("EvictsLeastRecentlyUsed", func(t *testing.T) {
+		collector := NewMetricsCollector()
+		adapter := tooladapter.New(
+			tooladapter.WithPromptCache(1),
+			tooladapter.WithMetricsCallback(collector.Callback),
+		)
+
+		for _, req := range []openai.ChatCompletionNewParams{weather, weather, search, weather} {
+			_, err := adapter.TransformCompletionsRequest(req)
+			require.NoError(t, err)
+		}
+		assert.Equal(t, []bool{false, true, false, false}, promptCacheHits(collector))
+	})
+
+	t.Run("TemplatesNotCached", func(t *testing.T) {
+		collector := NewMetricsCollector()
+		adapter := tooladapter.New(
+			tooladapter.WithPromptCache(8),
+			tooladapter.WithToolPromptTemplate(`{{range .Tools}}{{.Name}}{{end}}`),
+			tooladapter.WithMetricsCallback(collector.Callback),
+		)
+
+		for range 2 {
+			_, err := adapter.TransformCompletionsRequest(weather)
+			require.NoError(t, err)
+		}
+		assert.Equal(t, []bool{false, false}, promptCacheHits(collector))
+	})
+
+	t.Run("Concurrent", func(t *testing.T) {
+		adapter := tooladapter.New(tooladapter.WithPromptCache(1))
+		want, err := tooladapter.New().TransformCompletionsRequest(weather)
+		require.NoError(t, err)
+
+		var wg sync.WaitGroup
+		for i := range 16 {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				req := weather
+				if i%2 == 1 {
+					req = search
+				}
+				got, err := adapter.TransformCompletionsRequest(req)
+				assert.NoError(t, err)
+				if i%2 == 0 {
+					assert.Equal(t, injectedText(t, want), injectedText(t, got))
+				}
+			}()
+		}
+		wg.Wait()
+	})
+
+	t.Run("Negative", func(t *testing.T) {
+		var logs bytes.Buffer
+		tooladapter.New(
+			tooladapter.WithLogger(slog.New(slog.NewTextHandler(&logs, nil))),
+			tooladapter.WithPromptCache(-1),
+		)
+		assert.Contains(t, logs.String(), "Negative size not allowed for PromptCache")
+	})
+
+	t.Run("Config", func(t *testing.T) {
+		cfg, err := tooladapter.LoadConfig(strings.NewReader(`{"prompt_cache_size": 16}`))
+		require.NoError(t, err)
+		assert.Equal(t, 16, cfg.PromptCacheSize)
+
+		_, err = tooladapter.NewFromConfig(tooladapter.Config{PromptCacheSize: -1})
+		require.ErrorIs(t, err, tooladapter.ErrInvalidConfig)
+	})
+}
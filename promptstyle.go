@@ -0,0 +1,101 @@
+package tooladapter
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/openai/openai-go/v3"
+)
+
+// PromptStyle selects how tool definitions are presented in the injected prompt.
+type PromptStyle int
+
+const (
+	// PromptStyleProse lists each tool as a line of text with its description and
+	// parameter schema, under prose instructions (default).
+	PromptStyleProse PromptStyle = iota
+
+	// PromptStyleSpec presents the tools as a single JSON array the model treats as
+	// an API specification, followed by an explicit grammar for the output. Some
+	// models follow spec-style prompts far better than prose descriptions.
+	PromptStyleSpec
+)
+
+// String returns a human-readable string representation of the PromptStyle.
+func (s PromptStyle) String() string {
+	switch s {
+	case PromptStyleProse:
+		return "PromptStyleProse"
+	case PromptStyleSpec:
+		return "PromptStyleSpec"
+	default:
+		return fmt.Sprintf("PromptStyle(%d)", int(s))
+	}
+}
+
+// specTool is a tool definition in the JSON array of PromptStyleSpec.
+type specTool struct {
+	Name        string          `json:"name"`
+	Description string          `json:"description,omitempty"`
+	Parameters  json.RawMessage `json:"parameters,omitempty"`
+	Strict      bool            `json:"strict,omitempty"`
+}
+
+// styleTemplate returns the prompt template used unless a custom one is set: the
+// spec template for PromptStyleSpec, otherwise the language pack's template.
+func (a *Adapter) styleTemplate() string {
+	if a.promptStyle == PromptStyleSpec {
+		return SpecPromptTemplate
+	}
+	return a.language().template
+}
+
+// writeToolSpec writes the tools as the JSON array that fills the %s placeholder
+// with PromptStyleSpec: one tool per line, or fully indented with SchemaPretty. Schemas
+// are canonical, as in the prose style.
+func (a *Adapter) writeToolSpec(ctx context.Context, buf *bytes.Buffer, tools []openai.ChatCompletionToolUnionParam) error {
+	var encoded [][]byte
+	for _, tool := range tools {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		default:
+		}
+
+		function := tool.GetFunction()
+		if function == nil {
+			continue
+		}
+		spec := specTool{
+			Name:        function.Name,
+			Description: function.Description.Or(""),
+			Strict:      function.Strict.Or(false),
+		}
+		if function.Parameters != nil {
+			if source, err := json.Marshal(function.Parameters); err == nil {
+				spec.Parameters = json.RawMessage(canonicalJSON(source, false))
+			}
+		}
+
+		var line bytes.Buffer
+		encoder := json.NewEncoder(&line)
+		encoder.SetEscapeHTML(false)
+		if err := encoder.Encode(spec); err != nil {
+			continue
+		}
+		encoded = append(encoded, bytes.TrimSuffix(line.Bytes(), []byte("\n")))
+	}
+
+	spec := append([]byte("[\n  "), bytes.Join(encoded, []byte(",\n  "))...)
+	spec = append(spec, "\n]"...)
+	if a.schemaFormat == SchemaPretty {
+		var pretty bytes.Buffer
+		if err := json.Indent(&pretty, spec, "", "  "); err == nil {
+			spec = pretty.Bytes()
+		}
+	}
+	buf.Write(spec)
+	return nil
+}
@@ -0,0 +1,149 @@
+package tooladapter_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"strings"
+	"testing"
+
+	tooladapter "github.com/juburr/openai-tool-adapter/v3"
+	"github.com/juburr/openai-tool-adapter/v3/tooltest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// specTools returns the JSON array between "Tools:" and the output grammar of a
+// spec-style prompt.
+func specTools(t *testing.T, prompt string) string {
+	t.Helper()
+	_, rest, ok := strings.Cut(prompt, "Tools:\n")
+	require.True(t, ok, prompt)
+	spec, _, ok := strings.Cut(rest, "\n\nOutput grammar:")
+	require.True(t, ok, prompt)
+	return spec
+}
+
+func TestWithPromptStyle(t *testing.T) {
+	req := tooltest.Request(tooltest.Tool("get_weather", "Get the <weather>"), tooltest.Tool("search", "Search the web"))
+
+	t.Run("Spec", func(t *testing.T) {
+		transformed, err := tooladapter.New(tooladapter.WithPromptStyle(tooladapter.PromptStyleSpec)).TransformCompletionsRequest(req)
+		require.NoError(t, err)
+		prompt := injectedText(t, transformed)
+
+		assert.Contains(t, prompt, "API specification")
+		assert.Contains(t, prompt, "Respond ONLY with a JSON array of calls")
+		assert.NotContains(t, prompt, "- get_weather:")
+
+		spec := specTools(t, prompt)
+		var tools []struct {
+			Name        string         `json:"name"`
+			Description string         `json:"description"`
+			Parameters  map[string]any `json:"parameters"`
+		}
+		require.NoError(t, json.Unmarshal([]byte(spec), &tools))
+		require.Len(t, tools, 2)
+		assert.Equal(t, "get_weather", tools[0].Name)
+		assert.Equal(t, "Get the <weather>", tools[0].Description)
+		assert.Equal(t, "object", tools[0].Parameters["type"])
+		assert.Equal(t, "search", tools[1].Name)
+		assert.Len(t, strings.Split(spec, "\n"), 4, "one tool per line")
+	})
+
+	t.Run("SpecParsesCalls", func(t *testing.T) {
+		adapter := tooladapter.New(tooladapter.WithPromptStyle(tooladapter.PromptStyleSpec))
+		resp, err := adapter.TransformCompletionsResponse(tooltest.Completion(`[{"name": "search", "parameters": {"q": "go"}}]`))
+		require.NoError(t, err)
+		require.Len(t, resp.Choices[0].Message.ToolCalls, 1)
+		assert.Equal(t, "search", resp.Choices[0].Message.ToolCalls[0].Function.Name)
+	})
+
+	t.Run("SpecPretty", func(t *testing.T) {
+		adapter := tooladapter.New(
+			tooladapter.WithPromptStyle(tooladapter.PromptStyleSpec),
+			tooladapter.WithSchemaFormat(tooladapter.SchemaPretty),
+		)
+		transformed, err := adapter.TransformCompletionsRequest(req)
+		require.NoError(t, err)
+
+		spec := specTools(t, injectedText(t, transformed))
+		assert.Contains(t, spec, "\n    \"name\": \"get_weather\",")
+		assert.True(t, json.Valid([]byte(spec)))
+	})
+
+	t.Run("ProseByDefault", func(t *testing.T) {
+		transformed, err := tooladapter.New().TransformCompletionsRequest(req)
+		require.NoError(t, err)
+		prompt := injectedText(t, transformed)
+		assert.Contains(t, prompt, "- get_weather: Get the <weather>")
+		assert.NotContains(t, prompt, "Output grammar")
+	})
+
+	t.Run("PerModel", func(t *testing.T) {
+		adapter := tooladapter.New(tooladapter.WithModelRules(map[string]tooladapter.ModelProfile{
+			"qwen/*": {Options: []tooladapter.Option{tooladapter.WithPromptStyle(tooladapter.PromptStyleSpec)}},
+		}))
+
+		qwen := req
+		qwen.Model = "qwen/qwen3-8b"
+		transformed, err := adapter.TransformCompletionsRequest(qwen)
+		require.NoError(t, err)
+		assert.Contains(t, injectedText(t, transformed), "Output grammar")
+
+		gemma := req
+		gemma.Model = "google/gemma-3-27b-it"
+		transformed, err = adapter.TransformCompletionsRequest(gemma)
+		require.NoError(t, err)
+		assert.NotContains(t, injectedText(t, transformed), "Output grammar")
+	})
+
+	t.Run("CustomTemplateKept", func(t *testing.T) {
+		adapter := tooladapter.New(
+			tooladapter.WithCustomPromptTemplate("Functions as JSON:\n%s"),
+			tooladapter.WithPromptStyle(tooladapter.PromptStyleSpec),
+		)
+		transformed, err := adapter.TransformCompletionsRequest(req)
+		require.NoError(t, err)
+		prompt := injectedText(t, transformed)
+		assert.Contains(t, prompt, "Functions as JSON:\n[\n  {\"name\":\"get_weather\"")
+		assert.NotContains(t, prompt, "Output grammar")
+	})
+
+	t.Run("NotReportedAsCustomTemplate", func(t *testing.T) {
+		collector := NewMetricsCollector()
+		adapter := tooladapter.New(
+			tooladapter.WithPromptStyle(tooladapter.PromptStyleSpec),
+			tooladapter.WithPhaseMetrics(true),
+			tooladapter.WithMetricsCallback(collector.Callback),
+		)
+		_, err := adapter.TransformCompletionsRequest(req)
+		require.NoError(t, err)
+
+		events := phaseEvents[tooladapter.ToolPromptRenderedData](collector)
+		require.Len(t, events, 1)
+		assert.False(t, events[0].CustomTemplate)
+	})
+
+	t.Run("Unknown", func(t *testing.T) {
+		var logs bytes.Buffer
+		adapter := tooladapter.New(
+			tooladapter.WithLogger(slog.New(slog.NewTextHandler(&logs, nil))),
+			tooladapter.WithPromptStyle(tooladapter.PromptStyle(99)),
+		)
+		assert.Contains(t, logs.String(), "Unknown prompt style")
+
+		transformed, err := adapter.TransformCompletionsRequest(req)
+		require.NoError(t, err)
+		assert.Contains(t, injectedText(t, transformed), "- get_weather:")
+	})
+
+	t.Run("Config", func(t *testing.T) {
+		cfg, err := tooladapter.LoadConfig(strings.NewReader(`{"prompt_style": "spec"}`))
+		require.NoError(t, err)
+		assert.Equal(t, tooladapter.PromptStyleSpec, cfg.PromptStyle)
+
+		_, err = tooladapter.LoadConfig(strings.NewReader(`{"prompt_style": "poem"}`))
+		require.ErrorIs(t, err, tooladapter.ErrInvalidConfig)
+	})
+}
@@ -0,0 +1,210 @@
+package tooladapter
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"text/template"
+
+	"github.com/openai/openai-go/v3"
+)
+
+// PromptTemplateData is the data passed to a WithToolPromptTemplate template.
+type PromptTemplateData struct {
+	// Tools lists the function tools of the request, with model-facing names
+	Tools []PromptTool
+
+	// ToolDefinitions is the tool list as rendered into the %s placeholder of
+	// WithCustomPromptTemplate, for templates that only change the surrounding text
+	ToolDefinitions string
+
+	// ToolResults lists the tool messages of the request, in order
+	ToolResults []PromptToolResult
+
+	// ToolResultsText is the default rendering of ToolResults, empty without results
+	ToolResultsText string
+
+	// Examples lists the WithToolExamples examples that apply to the request's tools
+	Examples []PromptExample
+
+	// ExamplesText is the default rendering of Examples, empty without examples
+	ExamplesText string
+
+	// Strict reports whether any tool requests strict mode
+	Strict bool
+
+	// Model is the model named in the request
+	Model string
+
+	// Language is the WithPromptLanguage code, "en" by default
+	Language string
+
+	// ToolChoice is the request's tool choice mode: "auto", "none", "required",
+	// "function" or "custom" when a tool is forced, or empty when unset
+	ToolChoice string
+
+	// ToolChoiceFunction is the model-facing name of the forced tool, if any
+	ToolChoiceFunction string
+
+	// SingleToolCall reports whether the request sets parallel_tool_calls to
+	// false, in which case only the first call of a response is delivered
+	SingleToolCall bool
+}
+
+// PromptTool describes a function tool to a prompt template.
+type PromptTool struct {
+	Name        string
+	Description string
+
+	// Parameters is the JSON schema of the parameters as WithSchemaFormat renders
+	// it, empty when the tool has none
+	Parameters string
+
+	Strict bool
+}
+
+// PromptToolResult describes a tool result to a prompt template.
+type PromptToolResult struct {
+	// CallID is the tool call ID the result answers, which may be empty
+	CallID string
+
+	// Name is the function name, known when a Session recorded the call
+	Name string
+
+	Content string
+}
+
+// promptTemplateFuncs are the helper functions available to prompt templates in
+// addition to the text/template builtins.
+var promptTemplateFuncs = template.FuncMap{
+	// json encodes a value as compact JSON
+	"json": func(v any) (string, error) {
+		b, err := json.Marshal(v)
+		return string(b), err
+	},
+	// indent prefixes every line of s with n spaces
+	"indent": func(n int, s string) string {
+		pad := strings.Repeat(" ", n)
+		return pad + strings.ReplaceAll(s, "\n", "\n"+pad)
+	},
+	"join":  strings.Join,
+	"lower": strings.ToLower,
+	"upper": strings.ToUpper,
+	"trim":  strings.TrimSpace,
+	"quote": strconv.Quote,
+}
+
+// parseToolPromptTemplate parses text as a prompt template with the helper functions.
+func parseToolPromptTemplate(text string) (*template.Template, error) {
+	tmpl, err := template.New("tool_prompt").Funcs(promptTemplateFuncs).Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrTemplateRender, err)
+	}
+	return tmpl, nil
+}
+
+// ValidateToolPromptTemplate ensures text parses as a WithToolPromptTemplate template
+// and renders with sample data. WithToolPromptTemplate keeps the previous prompt
+// when validation fails; call this first to surface the problem instead. Errors wrap
+// ErrTemplateRender.
+func ValidateToolPromptTemplate(text string) error {
+	tmpl, err := parseToolPromptTemplate(text)
+	if err != nil {
+		return err
+	}
+
+	sample := PromptTemplateData{
+		Tools:              []PromptTool{{Name: "get_weather", Description: "Get the weather", Parameters: `{"type":"object"}`}},
+		ToolDefinitions:    "- get_weather: Get the weather\n  Parameters: {\"type\":\"object\"}",
+		ToolResults:        []PromptToolResult{{CallID: "call_1", Name: "get_weather", Content: "sunny"}},
+		ToolResultsText:    "Tool call call_1 (get_weather) result:\nsunny\n\n",
+		Examples:           []PromptExample{{Request: "Weather in Paris?", Response: `[{"name":"get_weather","parameters":{"city":"Paris"}}]`}},
+		ExamplesText:       "Examples of correct responses:\n\nUser: Weather in Paris?\nAssistant: [{\"name\":\"get_weather\",\"parameters\":{\"city\":\"Paris\"}}]",
+		Model:              "model",
+		Language:           defaultPromptLanguage,
+		ToolChoice:         "function",
+		ToolChoiceFunction: "get_weather",
+	}
+	if err := tmpl.Execute(&bytes.Buffer{}, sample); err != nil {
+		return fmt.Errorf("%w: %v", ErrTemplateRender, err)
+	}
+	return nil
+}
+
+// renderToolPrompt renders the WithToolPromptTemplate template for a request,
+// producing the whole injected prompt in place of the tool and tool result sections.
+func (a *Adapter) renderToolPrompt(ctx context.Context, req openai.ChatCompletionNewParams, tools []openai.ChatCompletionToolUnionParam, results []toolResult) (string, error) {
+	buf := a.bufferPool.Get().(*bytes.Buffer)
+	defer a.putBufferToPool(buf)
+
+	if err := a.writeToolDefinitions(ctx, buf, tools); err != nil {
+		return "", err
+	}
+
+	data := PromptTemplateData{
+		ToolDefinitions: buf.String(),
+		ToolResultsText: a.buildToolResultsPrompt(results),
+		Model:           string(req.Model),
+		Language:        a.promptLanguageCode(),
+		SingleToolCall:  requestsSingleToolCall(req),
+	}
+	data.ToolChoice, data.ToolChoiceFunction = a.promptToolChoice(req.ToolChoice)
+	data.Examples = a.promptExamples(tools)
+	data.ExamplesText = a.buildExamplesPrompt(data.Examples)
+
+	for _, tool := range tools {
+		function := tool.GetFunction()
+		if function == nil {
+			continue
+		}
+		promptTool := PromptTool{
+			Name:        function.Name,
+			Description: function.Description.Or(""),
+			Strict:      function.Strict.Or(false),
+		}
+		if function.Parameters != nil {
+			if schema, ok := a.renderSchema(function.Parameters); ok {
+				promptTool.Parameters = schema
+			}
+		}
+		data.Strict = data.Strict || promptTool.Strict
+		data.Tools = append(data.Tools, promptTool)
+	}
+	for _, result := range results {
+		data.ToolResults = append(data.ToolResults, PromptToolResult{
+			CallID:  result.CallID,
+			Name:    result.Name,
+			Content: result.Content,
+		})
+	}
+
+	buf.Reset()
+	if err := a.toolPromptTemplate.Execute(buf, data); err != nil {
+		return "", fmt.Errorf("%w: %v", ErrTemplateRender, err)
+	}
+	return buf.String(), nil
+}
+
+// promptToolChoice describes a request's tool choice as a mode and, for a forced tool,
+// its model-facing name.
+func (a *Adapter) promptToolChoice(choice openai.ChatCompletionToolChoiceOptionUnionParam) (string, string) {
+	switch {
+	case choice.OfAuto.Valid():
+		return choice.OfAuto.Value, ""
+	case choice.OfAllowedTools != nil:
+		return string(choice.OfAllowedTools.AllowedTools.Mode), ""
+	case choice.OfFunctionToolChoice != nil:
+		name := choice.OfFunctionToolChoice.Function.Name
+		if a.toolNamespace != "" {
+			name = a.toolNamespace + "." + name
+		}
+		return "function", name
+	case choice.OfCustomToolChoice != nil:
+		return "custom", choice.OfCustomToolChoice.Custom.Name
+	default:
+		return "", ""
+	}
+}
@@ -0,0 +1,136 @@
+package tooladapter_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	tooladapter "github.com/juburr/openai-tool-adapter/v3"
+	"github.com/juburr/openai-tool-adapter/v3/tooltest"
+	"github.com/openai/openai-go/v3"
+	"github.com/openai/openai-go/v3/packages/param"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const conditionalPromptTemplate = `Model: {{.Model}}
+{{- if .Tools}}
+Functions{{if .Strict}} (strict){{end}}:
+{{- range .Tools}}
+- {{.Name}}: {{.Description}}{{if .Parameters}} {{.Parameters}}{{end}}
+{{- end}}
+{{- end}}
+{{- if .ToolChoiceFunction}}
+You must call {{quote .ToolChoiceFunction}}.
+{{- end}}
+{{- if .ToolResults}}
+Results:
+{{- range .ToolResults}}
+{{.Name | upper}} => {{trim .Content}}
+{{- end}}
+{{- end}}`
+
+func TestWithToolPromptTemplate(t *testing.T) {
+	ctx := context.Background()
+	weather := createMockTool("get_weather", "Get the weather")
+	weather.OfFunction.Function.Strict = param.NewOpt(true)
+
+	t.Run("ToolsOnly", func(t *testing.T) {
+		adapter := tooladapter.New(
+			tooladapter.WithSystemMessageSupport(true),
+			tooladapter.WithToolPromptTemplate(conditionalPromptTemplate))
+		req := createMockRequest([]openai.ChatCompletionToolUnionParam{weather})
+		req.Model = "gemma-3"
+		req.ToolChoice = openai.ToolChoiceOptionFunctionToolChoice(openai.ChatCompletionNamedToolChoiceFunctionParam{Name: "get_weather"})
+
+		result, err := adapter.TransformCompletionsRequestWithContext(ctx, req)
+		require.NoError(t, err)
+		prompt := systemPrompt(t, result)
+		assert.Contains(t, prompt, "Model: gemma-3\nFunctions (strict):\n- get_weather: Get the weather {")
+		assert.Contains(t, prompt, `You must call "get_weather".`)
+		assert.NotContains(t, prompt, "Results:", "the results block is only rendered with results")
+		assert.Empty(t, result.Tools)
+	})
+
+	t.Run("ToolResultsOnly", func(t *testing.T) {
+		adapter := tooladapter.New(
+			tooladapter.WithSystemMessageSupport(true),
+			tooladapter.WithToolPromptTemplate(conditionalPromptTemplate))
+		session := adapter.NewSession()
+		_, err := session.TransformResponse(ctx, createMockCompletion(`{"name": "get_weather", "parameters": {}}`))
+		require.NoError(t, err)
+		callID := session.ToolCalls()[0].ID
+
+		req := createMockRequest(nil)
+		req.Model = "gemma-3"
+		req.Messages = append(req.Messages, openai.ToolMessage("  sunny  ", callID))
+		result, err := session.TransformRequest(ctx, req)
+		require.NoError(t, err)
+		assert.Equal(t, "Model: gemma-3\nResults:\nGET_WEATHER => sunny", systemPrompt(t, result))
+	})
+
+	t.Run("DefaultRenderings", func(t *testing.T) {
+		adapter := tooladapter.New(
+			tooladapter.WithSystemMessageSupport(true),
+			tooladapter.WithToolPromptTemplate("Tools:\n{{.ToolDefinitions}}"))
+		result, err := adapter.TransformCompletionsRequest(createMockRequest([]openai.ChatCompletionToolUnionParam{weather}))
+		require.NoError(t, err)
+		assert.Equal(t, "Tools:\n- get_weather: Get the weather\n  Parameters: {\"properties\":{\"param1\":{\"description\":\"A parameter\",\"type\":\"string\"}},\"type\":\"object\"}\n  Strict: true", systemPrompt(t, result))
+	})
+
+	t.Run("RequestInstructions", func(t *testing.T) {
+		adapter := tooladapter.New(
+			tooladapter.WithSystemMessageSupport(true),
+			tooladapter.WithToolPromptTemplate("Tools:\n{{.ToolDefinitions}}"),
+			tooladapter.WithToolArgumentModes(map[string]tooladapter.ArgumentMode{"run_sql": tooladapter.ArgumentModeText}),
+			tooladapter.WithCodeBlockTools(map[string]tooladapter.CodeBlockTool{"python": {Name: "run_python"}}))
+		session := adapter.NewSession()
+		tools := []openai.ChatCompletionToolUnionParam{
+			tooltest.Tool("run_sql", "Run a query"), tooltest.Tool("run_python", "Run Python code"),
+		}
+
+		req := createMockRequest(tools)
+		req.ParallelToolCalls = param.NewOpt(false)
+		result, err := session.TransformRequest(ctx, req)
+		require.NoError(t, err)
+		prompt := systemPrompt(t, result)
+		assert.True(t, strings.HasPrefix(prompt, "Tools:\n- run_sql: Run a query"), "the template renders first")
+		assert.Contains(t, prompt, "These functions take raw text instead of JSON parameters: run_sql.")
+		assert.Contains(t, prompt, "To run python code, write it in a fenced code block tagged python")
+		assert.Contains(t, prompt, "Return at most one function call")
+		assert.NotContains(t, prompt, "have changed", "the first turn has nothing to compare with")
+
+		result, err = session.TransformRequest(ctx, createMockRequest(append(tools, weather)))
+		require.NoError(t, err)
+		prompt = systemPrompt(t, result)
+		assert.Contains(t, prompt, "- Added: get_weather")
+		assert.NotContains(t, prompt, "Return at most one function call", "only when the request disables parallel calls")
+	})
+
+	t.Run("InvalidTemplateIgnored", func(t *testing.T) {
+		require.ErrorIs(t, tooladapter.ValidateToolPromptTemplate("{{.Tools"), tooladapter.ErrTemplateRender)
+		require.ErrorIs(t, tooladapter.ValidateToolPromptTemplate("{{.Unknown}}"), tooladapter.ErrTemplateRender)
+		require.NoError(t, tooladapter.ValidateToolPromptTemplate(conditionalPromptTemplate))
+
+		adapter := tooladapter.New(
+			tooladapter.WithSystemMessageSupport(true),
+			tooladapter.WithToolPromptTemplate("{{.Unknown}}"))
+		result, err := adapter.TransformCompletionsRequest(createMockRequest([]openai.ChatCompletionToolUnionParam{weather}))
+		require.NoError(t, err)
+		assert.Contains(t, systemPrompt(t, result), "Available functions:", "the default prompt is kept")
+	})
+
+	t.Run("RenderError", func(t *testing.T) {
+		// Valid for the sample data, but out of range for this request
+		adapter := tooladapter.New(tooladapter.WithToolPromptTemplate(`{{if eq .Model "broken"}}{{json (index .ToolResults 5)}}{{end}}`))
+		req := createMockRequest(nil)
+		req.Model = "broken"
+		req.Messages = append(req.Messages, openai.ToolMessage("sunny", "call_1"))
+		_, err := adapter.TransformCompletionsRequest(req)
+		require.ErrorIs(t, err, tooladapter.ErrTemplateRender)
+
+		var transformErr *tooladapter.TransformError
+		require.ErrorAs(t, err, &transformErr)
+		assert.Equal(t, "render prompt template", transformErr.Op)
+	})
+}
@@ -0,0 +1,114 @@
+package tooladapter
+
+import (
+	"context"
+	"sync"
+)
+
+// Conversation tracks tool calls across the turns of one conversation for
+// WithToolCallRateLimit. Create one per conversation with NewConversation and attach
+// it to the context of every response or streaming transformation in that
+// conversation with ContextWithConversation. It is safe for concurrent use.
+type Conversation struct {
+	mu         sync.Mutex
+	toolCalls  int
+	suppressed int
+}
+
+// NewConversation returns a Conversation with no recorded tool calls.
+func NewConversation() *Conversation {
+	return &Conversation{}
+}
+
+// ToolCalls returns the number of tool calls delivered in the conversation so far.
+func (c *Conversation) ToolCalls() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.toolCalls
+}
+
+// Suppressed returns the number of tool calls removed by the rate limit so far.
+func (c *Conversation) Suppressed() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.suppressed
+}
+
+// Reset clears the recorded counts, for example after a human reviewed the loop.
+func (c *Conversation) Reset() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.toolCalls = 0
+	c.suppressed = 0
+}
+
+// conversationKey is the context key for ContextWithConversation.
+type conversationKey struct{}
+
+// ContextWithConversation returns a copy of ctx carrying conv, so response and
+// streaming transformation count tool calls against the conversation limit of
+// WithToolCallRateLimit:
+//
+//	conv := tooladapter.NewConversation()
+//	for {
+//		ctx := tooladapter.ContextWithConversation(ctx, conv)
+//		resp, err := adapter.TransformCompletionsResponseWithContext(ctx, completion)
+//		...
+//	}
+func ContextWithConversation(ctx context.Context, conv *Conversation) context.Context {
+	return context.WithValue(ctx, conversationKey{}, conv)
+}
+
+// allowToolCalls applies WithToolCallRateLimit to the tool calls about to be
+// delivered and returns how many of them, in order, may be delivered. emitted is the
+// number of calls already delivered for the same response, which is non-zero for
+// streams that emit tool calls more than once. Allowed calls are recorded on the
+// conversation attached to ctx, if any.
+func (a *Adapter) allowToolCalls(ctx context.Context, names []string, emitted int, streaming bool) int {
+	var conv *Conversation
+	if ctx != nil {
+		conv, _ = ctx.Value(conversationKey{}).(*Conversation)
+	}
+	if len(names) == 0 || (a.maxToolCallsPerResponse == 0 && conv == nil) {
+		return len(names)
+	}
+
+	allowed := len(names)
+	if a.maxToolCallsPerResponse > 0 {
+		allowed = min(allowed, max(a.maxToolCallsPerResponse-emitted, 0))
+	}
+
+	conversationCount := 0
+	if conv != nil {
+		conv.mu.Lock()
+		if a.maxToolCallsPerConversation > 0 {
+			allowed = min(allowed, max(a.maxToolCallsPerConversation-conv.toolCalls, 0))
+		}
+		conv.toolCalls += allowed
+		conv.suppressed += len(names) - allowed
+		conversationCount = conv.toolCalls
+		conv.mu.Unlock()
+	}
+
+	if allowed == len(names) {
+		return allowed
+	}
+
+	suppressed := names[allowed:]
+	a.log(LogCategoryLimit).Warn("Tool call rate limit reached, suppressing tool calls",
+		"suppressed_count", len(suppressed),
+		"suppressed_names", suppressed,
+		"max_per_response", a.maxToolCallsPerResponse,
+		"max_per_conversation", a.maxToolCallsPerConversation,
+		"conversation_tool_calls", conversationCount,
+		"streaming", streaming)
+	a.emitMetric(ToolCallRateLimitData{
+		SuppressedCount:       len(suppressed),
+		SuppressedNames:       append([]string(nil), suppressed...),
+		MaxPerResponse:        a.maxToolCallsPerResponse,
+		MaxPerConversation:    a.maxToolCallsPerConversation,
+		ConversationToolCalls: conversationCount,
+		Streaming:             streaming,
+	})
+	return allowed
+}
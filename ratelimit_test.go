@@ -0,0 +1,119 @@
+package tooladapter_test
+
+import (
+	"context"
+	"testing"
+
+	tooladapter "github.com/juburr/openai-tool-adapter/v3"
+	"github.com/openai/openai-go/v3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const threeCalls = `[{"name": "search", "parameters": {"q": "a"}}, {"name": "search", "parameters": {"q": "b"}}, {"name": "fetch", "parameters": {"url": "c"}}]`
+
+func TestToolCallRateLimit(t *testing.T) {
+	t.Run("PerResponse", func(t *testing.T) {
+		var limited []tooladapter.ToolCallRateLimitData
+		adapter := tooladapter.New(
+			tooladapter.WithToolPolicy(tooladapter.ToolDrainAll),
+			tooladapter.WithToolCallRateLimit(2, 0),
+			tooladapter.WithMetricsCallback(func(data tooladapter.MetricEventData) {
+				if d, ok := data.(tooladapter.ToolCallRateLimitData); ok {
+					limited = append(limited, d)
+				}
+			}))
+
+		resp, err := adapter.TransformCompletionsResponse(createMockCompletion(threeCalls))
+		require.NoError(t, err)
+
+		toolCalls := resp.Choices[0].Message.ToolCalls
+		require.Len(t, toolCalls, 2)
+		assert.JSONEq(t, `{"q": "b"}`, toolCalls[1].Function.Arguments)
+		assert.Equal(t, "tool_calls", resp.Choices[0].FinishReason)
+
+		require.Len(t, limited, 1)
+		assert.Equal(t, 1, limited[0].SuppressedCount)
+		assert.Equal(t, []string{"fetch"}, limited[0].SuppressedNames)
+		assert.Equal(t, 2, limited[0].MaxPerResponse)
+		assert.False(t, limited[0].Streaming)
+	})
+
+	t.Run("PerConversation", func(t *testing.T) {
+		adapter := tooladapter.New(
+			tooladapter.WithToolPolicy(tooladapter.ToolDrainAll),
+			tooladapter.WithToolCallRateLimit(0, 4))
+		conv := tooladapter.NewConversation()
+		ctx := tooladapter.ContextWithConversation(context.Background(), conv)
+
+		var delivered []int
+		for turn := 0; turn < 3; turn++ {
+			resp, err := adapter.TransformCompletionsResponseWithContext(ctx, createMockCompletion(threeCalls))
+			require.NoError(t, err)
+			delivered = append(delivered, len(resp.Choices[0].Message.ToolCalls))
+			if turn == 2 {
+				assert.Equal(t, "stop", resp.Choices[0].FinishReason)
+				assert.Empty(t, resp.Choices[0].Message.Content)
+			}
+		}
+
+		assert.Equal(t, []int{3, 1, 0}, delivered)
+		assert.Equal(t, 4, conv.ToolCalls())
+		assert.Equal(t, 5, conv.Suppressed())
+
+		conv.Reset()
+		resp, err := adapter.TransformCompletionsResponseWithContext(ctx, createMockCompletion(threeCalls))
+		require.NoError(t, err)
+		assert.Len(t, resp.Choices[0].Message.ToolCalls, 3)
+	})
+
+	t.Run("ConversationLimitNeedsContext", func(t *testing.T) {
+		adapter := tooladapter.New(
+			tooladapter.WithToolPolicy(tooladapter.ToolDrainAll),
+			tooladapter.WithToolCallRateLimit(0, 1))
+		resp, err := adapter.TransformCompletionsResponse(createMockCompletion(threeCalls))
+		require.NoError(t, err)
+		assert.Len(t, resp.Choices[0].Message.ToolCalls, 3)
+	})
+
+	t.Run("ConversationCountsWithoutLimits", func(t *testing.T) {
+		conv := tooladapter.NewConversation()
+		ctx := tooladapter.ContextWithConversation(context.Background(), conv)
+		_, err := tooladapter.New().TransformCompletionsResponseWithContext(ctx, createMockCompletion(threeCalls))
+		require.NoError(t, err)
+		assert.Equal(t, 1, conv.ToolCalls(), "ToolStopOnFirst delivers one call")
+	})
+
+	t.Run("Streaming", func(t *testing.T) {
+		adapter := tooladapter.New(
+			tooladapter.WithToolPolicy(tooladapter.ToolAllowMixed),
+			tooladapter.WithToolCallRateLimit(2, 0))
+		stream := adapter.TransformStreamingResponse(NewMockStream([]openai.ChatCompletionChunk{
+			createStreamChunk(`{"name": "search", "parameters": {"q": "a"}}`),
+			createStreamChunk(" then "),
+			createStreamChunk(`{"name": "search", "parameters": {"q": "b"}}`),
+			createStreamChunk(" and "),
+			createStreamChunk(`{"name": "fetch", "parameters": {"url": "c"}}`),
+			createFinishChunk("stop"),
+		}))
+
+		var names []string
+		for stream.Next() {
+			for _, call := range stream.Current().Choices[0].Delta.ToolCalls {
+				names = append(names, call.Function.Name)
+			}
+		}
+		require.NoError(t, stream.Err())
+		assert.Equal(t, []string{"search", "search"}, names)
+	})
+
+	t.Run("InvalidLimitsIgnored", func(t *testing.T) {
+		adapter := tooladapter.New(
+			tooladapter.WithToolPolicy(tooladapter.ToolDrainAll),
+			tooladapter.WithToolCallRateLimit(1, 0),
+			tooladapter.WithToolCallRateLimit(-1, 5))
+		resp, err := adapter.TransformCompletionsResponse(createMockCompletion(threeCalls))
+		require.NoError(t, err)
+		assert.Len(t, resp.Choices[0].Message.ToolCalls, 1)
+	})
+}
@@ -0,0 +1,204 @@
+package tooladapter
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/openai/openai-go/v3"
+)
+
+// readAheadStream prefetches upstream chunks in a goroutine into a bounded channel
+// (see WithStreamReadAhead), so network latency overlaps with the consumer's work.
+// A full channel blocks the reader, which in turn stops reading from upstream.
+// Because Next waits on the channel rather than on upstream, it also enforces
+// WithStreamIdleTimeout and WithStreamMaxDuration, and wakes the consumer for
+// WithStreamHeartbeat while upstream is silent.
+type readAheadStream struct {
+	source ChatCompletionStreamInterface
+	ctx    context.Context
+
+	chunks  chan openai.ChatCompletionChunk
+	stop    chan struct{} // closed by Close to release a blocked reader
+	current openai.ChatCompletionChunk
+
+	// Timeouts; zero values disable them
+	idleTimeout time.Duration
+	maxDuration time.Duration
+	deadline    time.Time
+	received    int  // chunks returned by Next
+	timedOut    bool // a timeout ended the stream
+
+	// Interval after which a waiting Next returns a heartbeat chunk; 0 disables it
+	heartbeat time.Duration
+	waitStart time.Time // start of a wait interrupted by heartbeats, zero otherwise
+
+	mu        sync.Mutex
+	err       error // upstream or timeout error, set before chunks is closed
+	closeOnce sync.Once
+	closeErr  error
+	logger    *slog.Logger
+}
+
+// newReadAheadStream starts prefetching chunks from source with the adapter's
+// read-ahead and timeout settings. The reader stops at the end of source, when ctx
+// is done or when the stream is closed.
+func newReadAheadStream(ctx context.Context, source ChatCompletionStreamInterface, a *Adapter) *readAheadStream {
+	r := &readAheadStream{
+		source:      source,
+		ctx:         ctx,
+		chunks:      make(chan openai.ChatCompletionChunk, a.streamReadAhead),
+		stop:        make(chan struct{}),
+		idleTimeout: a.streamIdleTimeout,
+		maxDuration: a.streamMaxDuration,
+		heartbeat:   a.streamHeartbeat,
+		logger:      a.log(LogCategoryLimit),
+	}
+	if r.maxDuration > 0 {
+		r.deadline = time.Now().Add(r.maxDuration)
+	}
+	go r.read()
+	return r
+}
+
+// read copies upstream chunks into the channel until upstream ends or the stream
+// is stopped.
+func (r *readAheadStream) read() {
+	defer close(r.chunks)
+	for r.source.Next() {
+		select {
+		case r.chunks <- r.source.Current():
+		case <-r.stop:
+			return
+		case <-r.ctx.Done():
+			return
+		}
+	}
+	r.mu.Lock()
+	if r.err == nil {
+		r.err = r.source.Err()
+	}
+	r.mu.Unlock()
+}
+
+// Next waits for the next prefetched chunk. It returns false once upstream has
+// ended and every prefetched chunk was consumed, when ctx is done, or when a
+// timeout expires. With a heartbeat interval, a wait of that long after the first
+// chunk returns a heartbeat chunk (see isHeartbeatChunk) instead; the idle timeout
+// keeps counting from the start of the wait.
+func (r *readAheadStream) Next() bool {
+	if r.timedOut {
+		return false
+	}
+	// An upstream that keeps sending never reaches the timers below
+	if r.maxDuration > 0 && !time.Now().Before(r.deadline) {
+		r.timeout(&StreamTimeoutError{Timeout: r.maxDuration, Chunks: r.received})
+		return false
+	}
+
+	// Chunks that already arrived are returned without starting the timers
+	select {
+	case chunk, ok := <-r.chunks:
+		return r.receive(chunk, ok)
+	default:
+	}
+
+	start := r.waitStart
+	if start.IsZero() {
+		start = time.Now()
+	}
+	var idle, total, beat <-chan time.Time
+	if r.idleTimeout > 0 {
+		timer := time.NewTimer(time.Until(start.Add(r.idleTimeout)))
+		defer timer.Stop()
+		idle = timer.C
+	}
+	if r.maxDuration > 0 {
+		timer := time.NewTimer(time.Until(r.deadline))
+		defer timer.Stop()
+		total = timer.C
+	}
+	// No heartbeat precedes the first chunk, which names the model
+	if r.heartbeat > 0 && r.received > 0 {
+		timer := time.NewTimer(r.heartbeat)
+		defer timer.Stop()
+		beat = timer.C
+	}
+
+	select {
+	case chunk, ok := <-r.chunks:
+		return r.receive(chunk, ok)
+	case <-r.ctx.Done():
+		return false
+	case <-idle:
+		r.timeout(&StreamTimeoutError{Idle: true, Timeout: r.idleTimeout, Chunks: r.received})
+		return false
+	case <-total:
+		r.timeout(&StreamTimeoutError{Timeout: r.maxDuration, Chunks: r.received})
+		return false
+	case <-beat:
+		r.waitStart = start
+		r.current = openai.ChatCompletionChunk{ID: heartbeatChunkID}
+		return true
+	}
+}
+
+// receive makes chunk current, reporting false when the channel was closed.
+func (r *readAheadStream) receive(chunk openai.ChatCompletionChunk, ok bool) bool {
+	if !ok {
+		return false
+	}
+	r.current = chunk
+	r.received++
+	r.waitStart = time.Time{}
+	return true
+}
+
+// heartbeatChunkID marks the chunks readAheadStream returns when the heartbeat
+// interval passes without an upstream chunk.
+const heartbeatChunkID = "tooladapter-heartbeat"
+
+// isHeartbeatChunk reports whether chunk is a heartbeat from readAheadStream
+// rather than an upstream chunk.
+func isHeartbeatChunk(chunk openai.ChatCompletionChunk) bool {
+	return chunk.ID == heartbeatChunkID && len(chunk.Choices) == 0
+}
+
+// timeout records err for Err and closes upstream, which releases a reader stuck
+// waiting on the network.
+func (r *readAheadStream) timeout(err *StreamTimeoutError) {
+	r.timedOut = true
+	r.mu.Lock()
+	r.err = err
+	r.mu.Unlock()
+	r.logger.Warn("Upstream stream timed out, terminating",
+		"idle", err.Idle,
+		"timeout", err.Timeout,
+		"chunks_received", err.Chunks,
+		"recommendation", "Check the upstream server, or raise WithStreamIdleTimeout or WithStreamMaxDuration")
+	_ = r.Close()
+}
+
+// Current returns the chunk returned by the last call to Next.
+func (r *readAheadStream) Current() openai.ChatCompletionChunk {
+	return r.current
+}
+
+// Err returns the timeout error, or the upstream error once the reader has
+// reached the end of upstream.
+func (r *readAheadStream) Err() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.err
+}
+
+// Close stops the reader and closes upstream once, which also unblocks a reader
+// waiting on the network. Next may still return chunks prefetched before Close.
+func (r *readAheadStream) Close() error {
+	r.closeOnce.Do(func() {
+		close(r.stop)
+		r.closeErr = r.source.Close()
+	})
+	return r.closeErr
+}
@@ -0,0 +1,209 @@
+package tooladapter_test
+
+import (
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	tooladapter "github.com/juburr/openai-tool-adapter/v3"
+	"github.com/juburr/openai-tool-adapter/v3/tooltest"
+	"github.com/openai/openai-go/v3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// countingStream counts upstream reads and blocks once its chunks run out until
+// it is closed, like a network stream waiting for the next event.
+type countingStream struct {
+	*tooltest.MockStream
+	reads     atomic.Int32
+	remaining int32
+	content   string
+	closed    chan struct{}
+	closeOnce sync.Once
+}
+
+func newCountingStream(n int) *countingStream {
+	return &countingStream{
+		MockStream: tooltest.NewMockStream(),
+		remaining:  int32(n),
+		content:    "word ",
+		closed:     make(chan struct{}),
+	}
+}
+
+func (c *countingStream) Next() bool {
+	if c.reads.Add(1) > c.remaining {
+		<-c.closed
+		return false
+	}
+	return true
+}
+
+func (c *countingStream) Current() openai.ChatCompletionChunk {
+	return tooltest.ContentChunk(c.content)
+}
+
+func (c *countingStream) Close() error {
+	c.closeOnce.Do(func() { close(c.closed) })
+	return nil
+}
+
+func TestWithStreamReadAhead(t *testing.T) {
+	adapter := tooladapter.New(tooladapter.WithStreamReadAhead(4))
+
+	t.Run("SameOutput", func(t *testing.T) {
+		chunks := []string{"Let me check. ", `{"name": "get_weather", `, `"parameters": {"param1": "Paris"}}`}
+		direct := tooltest.Drain(tooladapter.New().TransformStreamingResponse(tooltest.NewContentStream(chunks...)))
+		pipelined := tooltest.Drain(adapter.TransformStreamingResponse(tooltest.NewContentStream(chunks...)))
+		require.NoError(t, pipelined.Err)
+		assert.Equal(t, direct.Content, pipelined.Content)
+		assert.Equal(t, direct.ToolNames(), pipelined.ToolNames())
+		assert.Equal(t, direct.FinishReason, pipelined.FinishReason)
+	})
+
+	t.Run("PrefetchesWithBackpressure", func(t *testing.T) {
+		source := newCountingStream(100)
+		stream := adapter.TransformStreamingResponse(source)
+		require.True(t, stream.Next())
+
+		// One chunk consumed, four in the channel and one held by the blocked reader
+		assert.Eventually(t, func() bool { return source.reads.Load() == 6 }, time.Second, time.Millisecond)
+		time.Sleep(10 * time.Millisecond)
+		assert.Equal(t, int32(6), source.reads.Load(), "a full channel stops upstream reads")
+
+		require.NoError(t, stream.Close())
+		for stream.Next() {
+		}
+	})
+
+	t.Run("CloseStopsReader", func(t *testing.T) {
+		source := newCountingStream(1)
+		stream := adapter.TransformStreamingResponse(source)
+		require.True(t, stream.Next())
+
+		// The reader now waits on upstream; Close releases it
+		require.NoError(t, stream.Close())
+		assert.False(t, stream.Next())
+	})
+
+	t.Run("UpstreamError", func(t *testing.T) {
+		upstreamErr := errors.New("connection reset")
+		source := tooltest.NewContentStream("Hello")
+		source.SetError(upstreamErr)
+		result := tooltest.Drain(adapter.TransformStreamingResponse(source))
+		assert.Equal(t, "Hello", result.Content)
+		assert.ErrorIs(t, result.Err, upstreamErr)
+	})
+
+	t.Run("RejectsNegative", func(t *testing.T) {
+		_, err := tooladapter.NewFromConfig(tooladapter.Config{StreamReadAhead: -1})
+		require.ErrorIs(t, err, tooladapter.ErrInvalidConfig)
+	})
+}
+
+// slowStream sends a content chunk every interval until it is closed.
+type slowStream struct {
+	*countingStream
+	interval time.Duration
+}
+
+func (s *slowStream) Next() bool {
+	select {
+	case <-time.After(s.interval):
+		s.reads.Add(1)
+		return true
+	case <-s.closed:
+		return false
+	}
+}
+
+func TestWithStreamIdleTimeout(t *testing.T) {
+	adapter := tooladapter.New(tooladapter.WithStreamIdleTimeout(20 * time.Millisecond))
+
+	t.Run("TerminatesStuckUpstream", func(t *testing.T) {
+		source := newCountingStream(2)
+		result := tooltest.Drain(adapter.TransformStreamingResponse(source))
+		assert.Equal(t, "word word ", result.Content)
+
+		require.ErrorIs(t, result.Err, tooladapter.ErrStreamTimeout)
+		var timeout *tooladapter.StreamTimeoutError
+		require.ErrorAs(t, result.Err, &timeout)
+		assert.True(t, timeout.Idle)
+		assert.Equal(t, 20*time.Millisecond, timeout.Timeout)
+		assert.Equal(t, 2, timeout.Chunks)
+
+		select {
+		case <-source.closed:
+		default:
+			t.Fatal("the upstream stream is closed on timeout")
+		}
+	})
+
+	t.Run("SteadyUpstreamUnaffected", func(t *testing.T) {
+		result := tooltest.Drain(adapter.TransformStreamingResponse(tooltest.NewContentStream("Hello", " there")))
+		require.NoError(t, result.Err)
+		assert.Equal(t, "Hello there", result.Content)
+	})
+
+	t.Run("PartialToolCall", func(t *testing.T) {
+		source := newCountingStream(1)
+		source.content = `{"name": "get_weather", `
+		result := tooltest.Drain(adapter.TransformStreamingResponse(source))
+		require.ErrorIs(t, result.Err, tooladapter.ErrStreamTimeout)
+		require.ErrorIs(t, result.Err, tooladapter.ErrStreamInterrupted, "the buffered call is reported per WithStreamErrorPolicy")
+	})
+}
+
+// closeCountingStream counts how often upstream is closed.
+type closeCountingStream struct {
+	*countingStream
+	closes atomic.Int32
+}
+
+func (c *closeCountingStream) Close() error {
+	c.closes.Add(1)
+	return c.countingStream.Close()
+}
+
+func TestWithStreamMaxDuration(t *testing.T) {
+	adapter := tooladapter.New(tooladapter.WithStreamMaxDuration(50 * time.Millisecond))
+
+	t.Run("SlowUpstream", func(t *testing.T) {
+		source := &slowStream{countingStream: newCountingStream(0), interval: 5 * time.Millisecond}
+
+		start := time.Now()
+		result := tooltest.Drain(adapter.TransformStreamingResponse(source))
+		assert.Less(t, time.Since(start), time.Second)
+		assert.NotEmpty(t, result.Content)
+
+		var timeout *tooladapter.StreamTimeoutError
+		require.ErrorAs(t, result.Err, &timeout)
+		assert.False(t, timeout.Idle)
+		assert.Equal(t, 50*time.Millisecond, timeout.Timeout)
+	})
+
+	t.Run("SteadyUpstreamSlowConsumer", func(t *testing.T) {
+		// Upstream always has the next chunk ready, so Next never waits on it
+		source := &closeCountingStream{countingStream: newCountingStream(1 << 20)}
+		stream := adapter.TransformStreamingResponse(source)
+
+		start := time.Now()
+		chunks := 0
+		for chunks < 1000 && stream.Next() {
+			chunks++
+			time.Sleep(time.Millisecond)
+		}
+		assert.Less(t, time.Since(start), 500*time.Millisecond)
+		assert.Less(t, chunks, 200)
+
+		var timeout *tooladapter.StreamTimeoutError
+		require.ErrorAs(t, stream.Err(), &timeout)
+		assert.False(t, stream.Next(), "the stream stays ended after a timeout")
+
+		require.NoError(t, stream.Close())
+		assert.Equal(t, int32(1), source.closes.Load(), "upstream is closed once")
+	})
+}
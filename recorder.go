@@ -0,0 +1,204 @@
+package tooladapter
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/openai/openai-go/v3"
+)
+
+// Transcript entry directions written by the stream recorder.
+const (
+	transcriptUpstream = "upstream" // chunk received from the upstream stream
+	transcriptEmitted  = "emitted"  // chunk returned by StreamAdapter.Current
+	transcriptError    = "error"    // upstream stream ended with an error
+)
+
+// transcriptEntry is one JSONL line of a stream transcript.
+type transcriptEntry struct {
+	Stream    int64           `json:"stream"`
+	Seq       int             `json:"seq"`
+	Direction string          `json:"direction"`
+	Time      time.Time       `json:"time"`
+	Chunk     json.RawMessage `json:"chunk,omitempty"`
+	Error     string          `json:"error,omitempty"`
+}
+
+// streamRecorder writes stream transcripts for every stream created by an adapter.
+// The adapter may be shared across goroutines, so writes are serialized.
+type streamRecorder struct {
+	mu       sync.Mutex
+	enc      *json.Encoder
+	streamID atomic.Int64
+}
+
+func newStreamRecorder(w io.Writer) *streamRecorder {
+	return &streamRecorder{enc: json.NewEncoder(w)}
+}
+
+// streamTranscript records the chunks of a single stream.
+type streamTranscript struct {
+	recorder *streamRecorder
+	logger   *slog.Logger
+	adapter  *Adapter
+	stream   int64
+
+	// seq and failed are guarded by recorder.mu: with read-ahead, upstream chunks
+	// are recorded on the read-ahead goroutine while the consumer records emitted ones.
+	seq    int
+	failed bool
+}
+
+func (r *streamRecorder) newTranscript(a *Adapter) *streamTranscript {
+	return &streamTranscript{
+		recorder: r,
+		logger:   a.logger,
+		adapter:  a,
+		stream:   r.streamID.Add(1),
+	}
+}
+
+// record writes a transcript entry. Upstream chunks are stored as received when the
+// raw JSON is available; everything else is marshaled. With WithLogRedaction, every
+// string in the chunk is redacted. Failures are logged once per stream and never
+// affect the stream itself.
+func (t *streamTranscript) record(direction string, chunk *openai.ChatCompletionChunk, streamErr error) {
+	entry := transcriptEntry{
+		Stream:    t.stream,
+		Direction: direction,
+		Time:      time.Now(),
+	}
+
+	var err error
+	switch {
+	case chunk != nil && direction == transcriptUpstream && json.Valid([]byte(chunk.RawJSON())):
+		entry.Chunk = json.RawMessage(chunk.RawJSON())
+	case chunk != nil:
+		entry.Chunk, err = json.Marshal(chunk)
+	case streamErr != nil:
+		entry.Error = streamErr.Error()
+	}
+
+	if len(t.adapter.redactors) > 0 {
+		if entry.Chunk != nil {
+			entry.Chunk = t.adapter.redactJSON(entry.Chunk)
+		}
+		entry.Error = t.adapter.redact(entry.Error)
+	}
+
+	t.recorder.mu.Lock()
+	defer t.recorder.mu.Unlock()
+	entry.Seq = t.seq
+	t.seq++
+	if err == nil {
+		err = t.recorder.enc.Encode(entry)
+	}
+	if err != nil && !t.failed {
+		t.failed = true
+		t.logger.Warn("Stream recorder failed to write transcript entry",
+			"stream", t.stream,
+			"error", err)
+	}
+}
+
+// recordingStream records every upstream chunk and the terminal error, if any.
+type recordingStream struct {
+	ChatCompletionStreamInterface
+	transcript *streamTranscript
+}
+
+func (r *recordingStream) Next() bool {
+	if r.ChatCompletionStreamInterface.Next() {
+		chunk := r.ChatCompletionStreamInterface.Current()
+		r.transcript.record(transcriptUpstream, &chunk, nil)
+		return true
+	}
+	if err := r.ChatCompletionStreamInterface.Err(); err != nil {
+		r.transcript.record(transcriptError, nil, err)
+	}
+	return false
+}
+
+// ReplayStream reads a transcript written by WithStreamRecorder and returns a stream
+// that yields the recorded upstream chunks of the first stream in the transcript,
+// followed by the recorded upstream error, if any. Passing it to
+// TransformStreamingResponse reproduces the original run.
+func ReplayStream(r io.Reader) (ChatCompletionStreamInterface, error) {
+	dec := json.NewDecoder(r)
+	replay := &replayStream{index: -1}
+	var stream int64
+	for line := 1; ; line++ {
+		var entry transcriptEntry
+		if err := dec.Decode(&entry); err != nil {
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			return nil, fmt.Errorf("invalid transcript entry %d: %w", line, err)
+		}
+		if stream == 0 {
+			stream = entry.Stream
+		}
+		if entry.Stream != stream {
+			continue
+		}
+
+		switch entry.Direction {
+		case transcriptUpstream:
+			var chunk openai.ChatCompletionChunk
+			if err := json.Unmarshal(entry.Chunk, &chunk); err != nil {
+				return nil, fmt.Errorf("invalid chunk in transcript entry %d: %w", line, err)
+			}
+			replay.chunks = append(replay.chunks, chunk)
+		case transcriptError:
+			replay.err = errors.New(entry.Error)
+		}
+	}
+	if stream == 0 {
+		return nil, errors.New("transcript contains no entries")
+	}
+	return replay, nil
+}
+
+// replayStream implements ChatCompletionStreamInterface over recorded chunks.
+type replayStream struct {
+	chunks []openai.ChatCompletionChunk
+	index  int
+	err    error
+}
+
+func (r *replayStream) Next() bool {
+	if r.index+1 >= len(r.chunks) {
+		r.index = len(r.chunks)
+		return false
+	}
+	r.index++
+	return true
+}
+
+func (r *replayStream) Current() openai.ChatCompletionChunk {
+	if r.index < 0 || r.index >= len(r.chunks) {
+		return openai.ChatCompletionChunk{}
+	}
+	return r.chunks[r.index]
+}
+
+// Err returns the recorded upstream error once all chunks have been replayed.
+func (r *replayStream) Err() error {
+	if r.index >= len(r.chunks) {
+		return r.err
+	}
+	return nil
+}
+
+// Close does not end the replay. The transcript only contains chunks the original
+// upstream delivered, so replaying all of them reproduces the run even when the
+// adapter closed the upstream early.
+func (r *replayStream) Close() error {
+	return nil
+}
@@ -0,0 +1,259 @@
+package tooladapter_test
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+
+	tooladapter "github.com/juburr/openai-tool-adapter/v3"
+	"github.com/openai/openai-go/v3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// transcriptLine mirrors the JSONL entries written by the stream recorder.
+type transcriptLine struct {
+	Stream    int64           `json:"stream"`
+	Seq       int             `json:"seq"`
+	Direction string          `json:"direction"`
+	Chunk     json.RawMessage `json:"chunk"`
+	Error     string          `json:"error"`
+}
+
+func readTranscript(t *testing.T, data []byte) []transcriptLine {
+	t.Helper()
+	var lines []transcriptLine
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		var line transcriptLine
+		require.NoError(t, json.Unmarshal(scanner.Bytes(), &line))
+		lines = append(lines, line)
+	}
+	require.NoError(t, scanner.Err())
+	return lines
+}
+
+// streamErrorWrapper ends a stream with an error after its chunks.
+type streamErrorWrapper struct {
+	tooladapter.ChatCompletionStreamInterface
+	err error
+}
+
+func (s *streamErrorWrapper) Err() error { return s.err }
+
+func toolStreamChunks() []openai.ChatCompletionChunk {
+	return []openai.ChatCompletionChunk{
+		createStreamChunk(`{"name": "get_weather", `),
+		createStreamChunk(`"parameters": {"city": "Paris"}}`),
+		createFinishChunk("stop"),
+	}
+}
+
+func TestStreamRecorder(t *testing.T) {
+	t.Run("RecordsUpstreamAndEmittedChunks", func(t *testing.T) {
+		var transcript bytes.Buffer
+		adapter := tooladapter.New(tooladapter.WithStreamRecorder(&transcript))
+
+		stream := adapter.TransformStreamingResponse(NewMockStream(toolStreamChunks()))
+		emitted := 0
+		for stream.Next() {
+			emitted++
+		}
+		require.NoError(t, stream.Err())
+
+		lines := readTranscript(t, transcript.Bytes())
+		var upstream, out int
+		for i, line := range lines {
+			assert.Equal(t, int64(1), line.Stream)
+			assert.Equal(t, i, line.Seq)
+			switch line.Direction {
+			case "upstream":
+				upstream++
+			case "emitted":
+				out++
+			}
+		}
+		assert.Equal(t, 3, upstream)
+		assert.Equal(t, emitted, out)
+
+		var firstEmitted openai.ChatCompletionChunk
+		for _, line := range lines {
+			if line.Direction == "emitted" {
+				require.NoError(t, json.Unmarshal(line.Chunk, &firstEmitted))
+				break
+			}
+		}
+		require.Len(t, firstEmitted.Choices, 1)
+		require.Len(t, firstEmitted.Choices[0].Delta.ToolCalls, 1)
+		assert.Equal(t, "get_weather", firstEmitted.Choices[0].Delta.ToolCalls[0].Function.Name)
+	})
+
+	t.Run("ReplayReproducesRun", func(t *testing.T) {
+		var transcript bytes.Buffer
+		recording := tooladapter.New(tooladapter.WithStreamRecorder(&transcript))
+		stream := recording.TransformStreamingResponse(NewMockStream(toolStreamChunks()))
+		var original []openai.ChatCompletionChunk
+		for stream.Next() {
+			original = append(original, stream.Current())
+		}
+
+		replay, err := tooladapter.ReplayStream(&transcript)
+		require.NoError(t, err)
+
+		replayed := tooladapter.New().TransformStreamingResponse(replay)
+		var reproduced []openai.ChatCompletionChunk
+		for replayed.Next() {
+			reproduced = append(reproduced, replayed.Current())
+		}
+		require.NoError(t, replayed.Err())
+
+		require.Equal(t, len(original), len(reproduced))
+		for i := range original {
+			assert.Equal(t, original[i].Choices[0].Delta.Content, reproduced[i].Choices[0].Delta.Content)
+			assert.Equal(t, original[i].Choices[0].FinishReason, reproduced[i].Choices[0].FinishReason)
+			assert.Len(t, reproduced[i].Choices[0].Delta.ToolCalls, len(original[i].Choices[0].Delta.ToolCalls))
+		}
+	})
+
+	t.Run("UpstreamErrorRecordedAndReplayed", func(t *testing.T) {
+		var transcript bytes.Buffer
+		adapter := tooladapter.New(tooladapter.WithStreamRecorder(&transcript))
+
+		upstreamErr := errors.New("connection reset")
+		stream := adapter.TransformStreamingResponse(&streamErrorWrapper{
+			ChatCompletionStreamInterface: NewMockStream([]openai.ChatCompletionChunk{createStreamChunk("Hello")}),
+			err:                           upstreamErr,
+		})
+		for stream.Next() {
+		}
+		require.ErrorIs(t, stream.Err(), upstreamErr)
+
+		lines := readTranscript(t, transcript.Bytes())
+		require.NotEmpty(t, lines)
+		last := lines[len(lines)-1]
+		assert.Equal(t, "error", last.Direction)
+		assert.Equal(t, "connection reset", last.Error)
+
+		replay, err := tooladapter.ReplayStream(bytes.NewReader(transcript.Bytes()))
+		require.NoError(t, err)
+		require.True(t, replay.Next())
+		assert.Equal(t, "Hello", replay.Current().Choices[0].Delta.Content)
+		assert.NoError(t, replay.Err(), "error is reported only after the last chunk")
+		require.False(t, replay.Next())
+		assert.EqualError(t, replay.Err(), "connection reset")
+	})
+
+	t.Run("ConcurrentStreamsTaggedSeparately", func(t *testing.T) {
+		var transcript safeBuffer
+		adapter := tooladapter.New(tooladapter.WithStreamRecorder(&transcript))
+
+		var wg sync.WaitGroup
+		for range 4 {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				stream := adapter.TransformStreamingResponse(NewMockStream(toolStreamChunks()))
+				for stream.Next() {
+				}
+			}()
+		}
+		wg.Wait()
+
+		streams := map[int64]int{}
+		for _, line := range readTranscript(t, transcript.Bytes()) {
+			assert.Equal(t, streams[line.Stream], line.Seq, "entries of a stream are in order")
+			streams[line.Stream]++
+		}
+		assert.Len(t, streams, 4)
+
+		// Replay only picks up the first stream in the transcript
+		replay, err := tooladapter.ReplayStream(bytes.NewReader(transcript.Bytes()))
+		require.NoError(t, err)
+		count := 0
+		for replay.Next() {
+			count++
+		}
+		assert.Equal(t, 3, count)
+	})
+
+	t.Run("ReadAheadRecordsConcurrently", func(t *testing.T) {
+		// Upstream chunks are recorded on the read-ahead goroutine while the
+		// consumer records emitted ones; run with -race to catch unguarded state.
+		var transcript safeBuffer
+		adapter := tooladapter.New(
+			tooladapter.WithStreamRecorder(&transcript),
+			tooladapter.WithStreamReadAhead(4),
+			tooladapter.WithToolPolicy(tooladapter.ToolAllowMixed),
+		)
+
+		chunks := make([]openai.ChatCompletionChunk, 200)
+		for i := range chunks {
+			chunks[i] = createStreamChunk("word ")
+		}
+		stream := adapter.TransformStreamingResponse(NewMockStream(chunks))
+		emitted := 0
+		for stream.Next() {
+			emitted++
+		}
+		require.NoError(t, stream.Err())
+		require.NoError(t, stream.Close())
+
+		lines := readTranscript(t, transcript.Bytes())
+		var upstream, out int
+		for i, line := range lines {
+			assert.Equal(t, i, line.Seq, "sequence numbers follow write order")
+			switch line.Direction {
+			case "upstream":
+				upstream++
+			case "emitted":
+				out++
+			}
+		}
+		assert.Equal(t, 200, upstream)
+		assert.Equal(t, emitted, out)
+	})
+
+	t.Run("ReplayRejectsInvalidTranscripts", func(t *testing.T) {
+		_, err := tooladapter.ReplayStream(strings.NewReader(""))
+		assert.Error(t, err)
+
+		_, err = tooladapter.ReplayStream(strings.NewReader("not json\n"))
+		assert.ErrorContains(t, err, "invalid transcript entry 1")
+	})
+
+	t.Run("WriteFailureDoesNotAffectStream", func(t *testing.T) {
+		adapter := tooladapter.New(tooladapter.WithStreamRecorder(failingWriter{}))
+		stream := adapter.TransformStreamingResponse(NewMockStream(toolStreamChunks()))
+		require.True(t, stream.Next())
+		require.Len(t, stream.Current().Choices[0].Delta.ToolCalls, 1)
+		for stream.Next() {
+		}
+		assert.NoError(t, stream.Err())
+	})
+}
+
+// safeBuffer is a bytes.Buffer safe for concurrent writes.
+type safeBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *safeBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *safeBuffer) Bytes() []byte {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Bytes()
+}
+
+type failingWriter struct{}
+
+func (failingWriter) Write([]byte) (int, error) { return 0, errors.New("disk full") }
@@ -0,0 +1,311 @@
+package tooladapter
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"regexp"
+	"strings"
+)
+
+// RedactedText replaces values removed by the built-in redactors.
+const RedactedText = "[REDACTED]"
+
+// Redactor removes sensitive data from text before it is logged, reported in a
+// metric or written to a stream transcript. Implementations must be safe for
+// concurrent use.
+type Redactor interface {
+	Redact(text string) string
+}
+
+// RedactorFunc adapts an ordinary function to the Redactor interface.
+type RedactorFunc func(text string) string
+
+// Redact calls f(text).
+func (f RedactorFunc) Redact(text string) string {
+	return f(text)
+}
+
+// RegexRedactor returns a Redactor that replaces every match of pattern with
+// replacement, which may refer to submatches as in regexp.Regexp.ReplaceAllString.
+// An empty replacement uses RedactedText.
+func RegexRedactor(pattern *regexp.Regexp, replacement string) Redactor {
+	if replacement == "" {
+		replacement = RedactedText
+	}
+	return RedactorFunc(func(text string) string {
+		return pattern.ReplaceAllString(text, replacement)
+	})
+}
+
+// commonSecretPatterns match widely used credential formats.
+var commonSecretPatterns = []*regexp.Regexp{
+	regexp.MustCompile(`\bsk-[A-Za-z0-9_-]{16,}`),                                // OpenAI and Anthropic style API keys
+	regexp.MustCompile(`\b(?:ghp|gho|ghu|ghs|ghr|github_pat)_[A-Za-z0-9_]{20,}`), // GitHub tokens
+	regexp.MustCompile(`\bA(?:KIA|SIA)[0-9A-Z]{16}\b`),                           // AWS access key IDs
+	regexp.MustCompile(`\bxox[abposr]-[A-Za-z0-9-]{10,}`),                        // Slack tokens
+	regexp.MustCompile(`(?i)\bbearer\s+[A-Za-z0-9._~+/-]{16,}=*`),                // Authorization headers
+}
+
+// CommonSecretRedactor returns a Redactor for widely used credential formats: API
+// keys starting with "sk-", GitHub, AWS and Slack tokens, and bearer tokens. It is a
+// baseline; add RegexRedactor or JSONFieldRedactor for data specific to your tools.
+func CommonSecretRedactor() Redactor {
+	return RedactorFunc(func(text string) string {
+		for _, pattern := range commonSecretPatterns {
+			text = pattern.ReplaceAllString(text, RedactedText)
+		}
+		return text
+	})
+}
+
+// JSONFieldRedactor returns a Redactor that replaces the values of JSON fields
+// selected by JSONPath-style paths with RedactedText. Supported forms are "$.key",
+// nested "$.outer.inner", "*" for any key, and "$..key" for the key at any depth;
+// arrays are searched element by element. For example "$..password" redacts every
+// password field and "$.card.number" only the nested one.
+//
+// Text that is not valid JSON, such as a truncated log preview or JSON embedded in
+// prose, has the values of the last key of each path redacted wherever they appear.
+func JSONFieldRedactor(paths ...string) Redactor {
+	var parsed []jsonFieldPath
+	var keys []string
+	for _, path := range paths {
+		if p, ok := parseJSONFieldPath(path); ok {
+			parsed = append(parsed, p)
+			keys = append(keys, regexp.QuoteMeta(p[len(p)-1].key))
+		}
+	}
+	if len(parsed) == 0 {
+		return RedactorFunc(func(text string) string { return text })
+	}
+	// Fallback for text that is not a JSON document: "key": "value" or "key": scalar
+	fallback := regexp.MustCompile(`("(?:` + strings.Join(keys, "|") + `)"\s*:\s*)(?:"(?:[^"\\]|\\.)*"?|[^\s,}\]]+)`)
+
+	return RedactorFunc(func(text string) string {
+		trimmed := strings.TrimSpace(text)
+		if strings.HasPrefix(trimmed, "{") || strings.HasPrefix(trimmed, "[") {
+			var doc any
+			decoder := json.NewDecoder(strings.NewReader(trimmed))
+			decoder.UseNumber()
+			if decoder.Decode(&doc) == nil && !decoder.More() {
+				redacted := false
+				for _, path := range parsed {
+					if redactJSONPath(doc, path) {
+						redacted = true
+					}
+				}
+				if !redacted {
+					return text
+				}
+				if encoded, err := json.Marshal(doc); err == nil {
+					return string(encoded)
+				}
+			}
+		}
+		return fallback.ReplaceAllString(text, `${1}"`+RedactedText+`"`)
+	})
+}
+
+// jsonFieldSegment is one step of a JSONFieldRedactor path.
+type jsonFieldSegment struct {
+	key       string // field name, or "*" for any field
+	recursive bool   // match at any depth below the previous segment
+}
+
+type jsonFieldPath []jsonFieldSegment
+
+// parseJSONFieldPath parses "$.a.b", "$..a" and "a.b" forms.
+func parseJSONFieldPath(path string) (jsonFieldPath, bool) {
+	path = strings.TrimPrefix(strings.TrimSpace(path), "$")
+	if path == "" {
+		return nil, false
+	}
+	if !strings.HasPrefix(path, ".") {
+		path = "." + path
+	}
+
+	var segments jsonFieldPath
+	for path != "" {
+		recursive := strings.HasPrefix(path, "..")
+		path = strings.TrimLeft(path, ".")
+		key := path
+		if end := strings.IndexByte(path, '.'); end >= 0 {
+			key, path = path[:end], path[end:]
+		} else {
+			path = ""
+		}
+		if key == "" {
+			return nil, false
+		}
+		segments = append(segments, jsonFieldSegment{key: key, recursive: recursive})
+	}
+	return segments, true
+}
+
+// redactJSONPath replaces the values selected by path in doc and reports whether any
+// were found.
+func redactJSONPath(doc any, path jsonFieldPath) bool {
+	if len(path) == 0 {
+		return false
+	}
+	segment := path[0]
+	redacted := false
+
+	switch v := doc.(type) {
+	case []any:
+		for _, item := range v {
+			if redactJSONPath(item, path) {
+				redacted = true
+			}
+		}
+	case map[string]any:
+		for key, child := range v {
+			if segment.key == "*" || segment.key == key {
+				if len(path) == 1 {
+					v[key] = RedactedText
+					redacted = true
+					continue
+				}
+				if redactJSONPath(child, path[1:]) {
+					redacted = true
+				}
+			}
+			if segment.recursive && redactJSONPath(child, path) {
+				redacted = true
+			}
+		}
+	}
+	return redacted
+}
+
+// redact applies the configured redactors to text.
+func (a *Adapter) redact(text string) string {
+	for _, r := range a.redactors {
+		text = r.Redact(text)
+	}
+	return text
+}
+
+// redactJSON applies the configured redactors to every string in a JSON document and
+// returns it re-encoded. Documents that cannot be decoded are returned unchanged.
+func (a *Adapter) redactJSON(data []byte) []byte {
+	var doc any
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	if decoder.Decode(&doc) != nil {
+		return data
+	}
+	encoded, err := json.Marshal(a.redactValue(doc))
+	if err != nil {
+		return data
+	}
+	return encoded
+}
+
+func (a *Adapter) redactValue(value any) any {
+	switch v := value.(type) {
+	case string:
+		return a.redact(v)
+	case map[string]any:
+		for key, child := range v {
+			v[key] = a.redactValue(child)
+		}
+	case []any:
+		for i, item := range v {
+			v[i] = a.redactValue(item)
+		}
+	}
+	return value
+}
+
+// redactMetric returns a copy of data with content-bearing fields redacted. Metrics
+// that only carry names and counts are returned unchanged.
+func (a *Adapter) redactMetric(data MetricEventData) MetricEventData {
+	if len(a.redactors) == 0 {
+		return data
+	}
+	switch d := data.(type) {
+	case ArgumentViolationData:
+		violations := make([]ArgumentViolation, len(d.Violations))
+		for i, v := range d.Violations {
+			v.Value = a.redactJSON(v.Value)
+			v.Message = a.redact(v.Message)
+			violations[i] = v
+		}
+		d.Violations = violations
+		return d
+	case ToolSanitizationData:
+		alterations := make([]ToolDefinitionAlteration, len(d.Alterations))
+		for i, alt := range d.Alterations {
+			alt.Original = a.redact(alt.Original)
+			alt.Sanitized = a.redact(alt.Sanitized)
+			alterations[i] = alt
+		}
+		d.Alterations = alterations
+		return d
+	}
+	return data
+}
+
+// redactingHandler applies redactors to the string and error attributes of every log
+// record before passing it to the wrapped handler.
+type redactingHandler struct {
+	handler slog.Handler
+	adapter *Adapter
+}
+
+func (h *redactingHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return h.handler.Enabled(ctx, level)
+}
+
+func (h *redactingHandler) Handle(ctx context.Context, record slog.Record) error {
+	redacted := slog.NewRecord(record.Time, record.Level, h.adapter.redact(record.Message), record.PC)
+	record.Attrs(func(attr slog.Attr) bool {
+		redacted.AddAttrs(h.redactAttr(attr))
+		return true
+	})
+	return h.handler.Handle(ctx, redacted)
+}
+
+func (h *redactingHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	redacted := make([]slog.Attr, len(attrs))
+	for i, attr := range attrs {
+		redacted[i] = h.redactAttr(attr)
+	}
+	return &redactingHandler{handler: h.handler.WithAttrs(redacted), adapter: h.adapter}
+}
+
+func (h *redactingHandler) WithGroup(name string) slog.Handler {
+	return &redactingHandler{handler: h.handler.WithGroup(name), adapter: h.adapter}
+}
+
+func (h *redactingHandler) redactAttr(attr slog.Attr) slog.Attr {
+	value := attr.Value.Resolve()
+	switch value.Kind() {
+	case slog.KindString:
+		return slog.String(attr.Key, h.adapter.redact(value.String()))
+	case slog.KindGroup:
+		group := value.Group()
+		redacted := make([]any, len(group))
+		for i, member := range group {
+			redacted[i] = h.redactAttr(member)
+		}
+		return slog.Group(attr.Key, redacted...)
+	case slog.KindAny:
+		switch v := value.Any().(type) {
+		case error:
+			return slog.String(attr.Key, h.adapter.redact(v.Error()))
+		case []string:
+			redacted := make([]string, len(v))
+			for i, s := range v {
+				redacted[i] = h.adapter.redact(s)
+			}
+			return slog.Any(attr.Key, redacted)
+		case json.RawMessage:
+			return slog.String(attr.Key, h.adapter.redact(string(v)))
+		}
+	}
+	return slog.Attr{Key: attr.Key, Value: value}
+}
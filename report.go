@@ -0,0 +1,92 @@
+package tooladapter
+
+import (
+	"context"
+	"time"
+
+	"github.com/openai/openai-go/v3"
+)
+
+// TransformReport describes what the transformation of one request did, for callers
+// that would otherwise have to infer it from logs.
+type TransformReport struct {
+	// Passthrough reports that the request was returned without injected content,
+	// because it had no tools, tool results or JSON mode instruction, or because its
+	// model supports tools natively
+	Passthrough bool `json:"passthrough"`
+
+	// NativeTools reports that WithNativePassthrough forwarded the request untouched
+	NativeTools bool `json:"native_tools"`
+
+	// Messages holds the origin of each message of the transformed request
+	Messages MessageAnnotations `json:"messages"`
+
+	// PromptMessageIndex is the index of the transformed message holding the injected
+	// prompt, or -1 when nothing was injected
+	PromptMessageIndex int `json:"prompt_message_index"`
+
+	// InjectedBytes and InjectedTokens measure the injected prompt, including
+	// WithInjectionMarkers markers. Tokens are counted as for WithTokenCounter.
+	InjectedBytes  int `json:"injected_bytes"`
+	InjectedTokens int `json:"injected_tokens"`
+
+	// ToolsRendered lists the function names described in the prompt, after
+	// WithUnsupportedToolPolicy, WithToolSelector and WithToolNamespace
+	ToolsRendered []string `json:"tools_rendered,omitempty"`
+
+	// ToolResultsConsumed is the number of tool messages folded into the prompt
+	ToolResultsConsumed int `json:"tool_results_consumed"`
+
+	// TemplateVariant names the WithTemplateExperiments variant the request was
+	// assigned to; empty without experiments
+	TemplateVariant string `json:"template_variant,omitempty"`
+
+	// TruncatedMessages and TruncatedTokens measure the messages WithContextWindow
+	// dropped
+	TruncatedMessages int `json:"truncated_messages"`
+	TruncatedTokens   int `json:"truncated_tokens"`
+}
+
+// transformReportKey is the context key for the report filled by
+// TransformCompletionsRequestWithReport.
+type transformReportKey struct{}
+
+// transformReportFrom returns the report being filled for the request of ctx, if any.
+func transformReportFrom(ctx context.Context) *TransformReport {
+	if ctx == nil {
+		return nil
+	}
+	report, _ := ctx.Value(transformReportKey{}).(*TransformReport)
+	return report
+}
+
+// TransformCompletionsRequestWithReport transforms req like
+// TransformCompletionsRequestWithContext and also returns a report of what the
+// transformation did: the origin of every message, the size of the injected prompt,
+// the tools rendered, the tool results consumed and any truncation. The report is
+// the zero value when the transformation fails.
+func (a *Adapter) TransformCompletionsRequestWithReport(ctx context.Context, req openai.ChatCompletionNewParams) (openai.ChatCompletionNewParams, TransformReport, error) {
+	startTime := time.Now()
+	transformed, report, err := a.transformRequestWithReport(ctx, req)
+	if a.postTransformHook != nil {
+		a.runPostTransformHook(ctx, PostTransformReport{
+			Request:  &report,
+			Model:    req.Model,
+			Duration: time.Since(startTime),
+			Err:      err,
+		})
+	}
+	return transformed, report, err
+}
+
+// transformRequestWithReport implements TransformCompletionsRequestWithReport
+// without the post-transform hook.
+func (a *Adapter) transformRequestWithReport(ctx context.Context, req openai.ChatCompletionNewParams) (openai.ChatCompletionNewParams, TransformReport, error) {
+	report := &TransformReport{PromptMessageIndex: -1}
+	transformed, err := a.TransformCompletionsRequestWithContext(context.WithValue(ctx, transformReportKey{}, report), req)
+	if err != nil {
+		return transformed, TransformReport{}, err
+	}
+
+	return transformed, *report, nil
+}
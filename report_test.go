@@ -0,0 +1,87 @@
+package tooladapter_test
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	tooladapter "github.com/juburr/openai-tool-adapter/v3"
+	"github.com/juburr/openai-tool-adapter/v3/tooltest"
+	"github.com/openai/openai-go/v3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTransformCompletionsRequestWithReport(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("ToolsAndResults", func(t *testing.T) {
+		adapter := tooladapter.New(
+			tooladapter.WithSystemMessageSupport(true),
+			tooladapter.WithTokenCounter(byteCounter))
+		req := tooltest.Request(tooltest.Tool("get_weather", "Get the weather"), tooltest.Tool("get_time", "Get the time"))
+		req.Messages = append(req.Messages, openai.ToolMessage("Sunny", "call_1"))
+
+		transformed, report, err := adapter.TransformCompletionsRequestWithReport(ctx, req)
+		require.NoError(t, err)
+
+		assert.False(t, report.Passthrough)
+		assert.Equal(t, []string{"get_weather", "get_time"}, report.ToolsRendered)
+		assert.Equal(t, 1, report.ToolResultsConsumed)
+		assert.Equal(t, 0, report.PromptMessageIndex)
+		assert.Equal(t, tooladapter.MessageOriginInjected, report.Messages[0].Origin)
+		assert.Equal(t, tooladapter.MessageAnnotation{Origin: tooladapter.MessageOriginCaller, SourceIndex: 0}, report.Messages[1])
+
+		injected := transformed.Messages[0].OfSystem.Content.OfString.Value
+		assert.Equal(t, len(injected), report.InjectedBytes)
+		assert.Equal(t, len(injected), report.InjectedTokens, "counted with the token counter")
+		assert.Zero(t, report.TruncatedMessages)
+	})
+
+	t.Run("PromptInUserMessage", func(t *testing.T) {
+		_, report, err := tooladapter.New().TransformCompletionsRequestWithReport(ctx, tooltest.Request(tooltest.Tool("get_weather", "")))
+		require.NoError(t, err)
+		assert.Equal(t, 0, report.PromptMessageIndex)
+		assert.Equal(t, tooladapter.MessageOriginModified, report.Messages[0].Origin)
+	})
+
+	t.Run("Truncation", func(t *testing.T) {
+		full, err := tooladapter.New(tooladapter.WithTokenCounter(byteCounter)).TransformCompletionsRequest(conversation())
+		require.NoError(t, err)
+		encoded, err := json.Marshal(full.Messages)
+		require.NoError(t, err)
+
+		adapter := tooladapter.New(
+			tooladapter.WithTokenCounter(byteCounter),
+			tooladapter.WithContextWindow(len(encoded)-600))
+		transformed, report, err := adapter.TransformCompletionsRequestWithReport(ctx, conversation())
+		require.NoError(t, err)
+		assert.Equal(t, 2, report.TruncatedMessages)
+		assert.Positive(t, report.TruncatedTokens)
+		assert.Len(t, report.Messages, len(transformed.Messages))
+	})
+
+	t.Run("Passthrough", func(t *testing.T) {
+		_, report, err := tooladapter.New().TransformCompletionsRequestWithReport(ctx, tooltest.Request())
+		require.NoError(t, err)
+		assert.True(t, report.Passthrough)
+		assert.False(t, report.NativeTools)
+		assert.Equal(t, -1, report.PromptMessageIndex)
+		assert.Zero(t, report.InjectedBytes)
+
+		native := tooladapter.New(tooladapter.WithNativePassthrough(func(string) bool { return true }))
+		_, report, err = native.TransformCompletionsRequestWithReport(ctx, tooltest.Request(tooltest.Tool("get_weather", "")))
+		require.NoError(t, err)
+		assert.True(t, report.Passthrough)
+		assert.True(t, report.NativeTools)
+		assert.Empty(t, report.ToolsRendered)
+	})
+
+	t.Run("Error", func(t *testing.T) {
+		cancelled, cancel := context.WithCancel(ctx)
+		cancel()
+		_, report, err := tooladapter.New().TransformCompletionsRequestWithReport(cancelled, tooltest.Request(tooltest.Tool("get_weather", "")))
+		require.ErrorIs(t, err, context.Canceled)
+		assert.Equal(t, tooladapter.TransformReport{}, report)
+	})
+}
@@ -0,0 +1,150 @@
+package tooladapter
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"maps"
+	"strings"
+	"time"
+
+	"github.com/openai/openai-go/v3"
+)
+
+// cachedCompletion is a WithResponseCache entry: a transformed response, the
+// sources of its tool calls and the parse reports of its choices.
+type cachedCompletion struct {
+	completion openai.ChatCompletion
+	sources    ToolCallSources
+	choices    []ChoiceParseReport
+}
+
+// cachedResponse returns the transformation of resp from the WithResponseCache cache
+// when it holds one, and parses resp and caches the result otherwise. Responses that
+// cannot hold tool calls and transformations that depend on more than the response
+// and the request settings in the key are parsed without the cache.
+func (a *Adapter) cachedResponse(ctx context.Context, resp openai.ChatCompletion, startTime time.Time) (openai.ChatCompletion, error) {
+	if !a.mayHoldToolCalls(resp) || !a.responseCacheable(ctx) {
+		return a.parseResponse(ctx, resp, startTime)
+	}
+	key, ok := a.responseCacheKey(ctx, resp)
+	if !ok {
+		return a.parseResponse(ctx, resp, startTime)
+	}
+	sources := toolCallSourcesFrom(ctx)
+	report := parseReportFrom(ctx)
+	if cached, ok := a.responseCache.get(key); ok {
+		a.log(LogCategoryParse).Debug("Response served from cache",
+			"total_choices", len(cached.completion.Choices))
+		if sources != nil {
+			maps.Copy(sources, cached.sources)
+		}
+		if report != nil {
+			report.Choices = cloneChoiceParseReports(cached.choices)
+		}
+		return cloneCompletion(cached.completion), nil
+	}
+
+	// Sources and reports are recorded for every cached response so that later hits
+	// can return them
+	recorded := ToolCallSources{}
+	parseCtx := ContextWithToolCallSources(ctx, recorded)
+	if report == nil {
+		report = newParseReport(len(resp.Choices))
+		parseCtx = context.WithValue(parseCtx, parseReportKey{}, report)
+	}
+	transformed, err := a.parseResponse(parseCtx, resp, startTime)
+	if err != nil {
+		return transformed, err
+	}
+	if sources != nil {
+		maps.Copy(sources, recorded)
+	}
+	a.responseCache.put(key, cachedCompletion{
+		completion: cloneCompletion(transformed),
+		sources:    recorded,
+		choices:    cloneChoiceParseReports(report.Choices),
+	})
+	return transformed, nil
+}
+
+// mayHoldToolCalls reports whether parsing can find tool calls in a choice of resp,
+// checked cheaply so that plain text responses skip hashing and take the parser's
+// fast path.
+func (a *Adapter) mayHoldToolCalls(resp openai.ChatCompletion) bool {
+	if a.assistantPrefill != "" || a.lenientParsing {
+		return true
+	}
+	for _, choice := range resp.Choices {
+		content := choice.Message.Content
+		if mayContainJSON(content) || (a.hasFencedTools() && strings.Contains(content, "```")) {
+			return true
+		}
+		if normalized, changed := normalizeToolText(content); changed && mayContainJSON(normalized) {
+			return true
+		}
+	}
+	return false
+}
+
+// responseCacheable reports whether a transformation in ctx may be served from the
+// cache: not when WithToolCallInterceptor runs for every call, when a Conversation
+// counts the calls for WithToolCallRateLimit, or when WithArgumentViolationPolicy
+// reports violations against the schemas of ContextWithTools.
+func (a *Adapter) responseCacheable(ctx context.Context) bool {
+	if a.toolCallInterceptor != nil {
+		return false
+	}
+	if ctx == nil {
+		return true
+	}
+	if conv, _ := ctx.Value(conversationKey{}).(*Conversation); conv != nil {
+		return false
+	}
+	if a.argumentViolationPolicy != ArgumentViolationIgnore {
+		if schemas, _ := ctx.Value(toolSchemasKey{}).(map[string]*argumentSchema); len(schemas) > 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// responseCacheKey hashes resp together with the request settings attached to ctx
+// that change its transformation: the parallel tool call setting and, with
+// WithArgumentCoercion, the tool schemas.
+func (a *Adapter) responseCacheKey(ctx context.Context, resp openai.ChatCompletion) ([sha256.Size]byte, bool) {
+	h := sha256.New()
+	if err := json.NewEncoder(h).Encode(resp); err != nil {
+		return [sha256.Size]byte{}, false
+	}
+	if singleToolCall(ctx) {
+		h.Write([]byte{0, 's'})
+	}
+	if a.argumentCoercion && ctx != nil {
+		if schemas, _ := ctx.Value(toolSchemasKey{}).(map[string]*argumentSchema); len(schemas) > 0 {
+			h.Write([]byte{0, 't'})
+			if err := json.NewEncoder(h).Encode(schemas); err != nil {
+				return [sha256.Size]byte{}, false
+			}
+		}
+	}
+	var key [sha256.Size]byte
+	h.Sum(key[:0])
+	return key, true
+}
+
+// cloneCompletion copies the choices and tool calls of a response, the parts the
+// adapter writes, so callers cannot modify a cached response through the result.
+func cloneCompletion(resp openai.ChatCompletion) openai.ChatCompletion {
+	choices := make([]openai.ChatCompletionChoice, len(resp.Choices))
+	for i, choice := range resp.Choices {
+		if choice.Message.ToolCalls != nil {
+			toolCalls := make([]openai.ChatCompletionMessageToolCallUnion, len(choice.Message.ToolCalls))
+			copy(toolCalls, choice.Message.ToolCalls)
+			choice.Message.ToolCalls = toolCalls
+		}
+		choices[i] = choice
+	}
+	resp.Choices = choices
+	return resp
+}
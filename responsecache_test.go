@@ -0,0 +1,144 @@
+package tooladapter_test
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+
+	tooladapter "github.com/juburr/openai-tool-adapter/v3"
+	"github.com/juburr/openai-tool-adapter/v3/tooltest"
+	"github.com/openai/openai-go/v3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWithResponseCache(t *testing.T) {
+	completion := tooltest.Completion(weatherJSON)
+
+	t.Run("RetryReturnsSameResult", func(t *testing.T) {
+		collector := NewMetricsCollector()
+		adapter := tooladapter.New(
+			tooladapter.WithResponseCache(8, 0),
+			tooladapter.WithMetricsCallback(collector.Callback),
+		)
+
+		first, err := adapter.TransformCompletionsResponse(completion)
+		require.NoError(t, err)
+		second, err := adapter.TransformCompletionsResponse(completion)
+		require.NoError(t, err)
+
+		require.Len(t, second.Choices[0].Message.ToolCalls, 1)
+		assert.Equal(t, first.Choices[0].Message.ToolCalls[0].ID, second.Choices[0].Message.ToolCalls[0].ID,
+			"a retry gets the tool call IDs of the first transformation")
+		assert.Len(t, phaseEvents[tooladapter.FunctionCallDetectionData](collector), 1, "the retry is not parsed")
+	})
+
+	t.Run("DifferentResponsesMiss", func(t *testing.T) {
+		adapter := tooladapter.New(tooladapter.WithResponseCache(8, 0))
+		weather, err := adapter.TransformCompletionsResponse(completion)
+		require.NoError(t, err)
+		plain, err := adapter.TransformCompletionsResponse(tooltest.Completion("Hello there"))
+		require.NoError(t, err)
+
+		assert.Len(t, weather.Choices[0].Message.ToolCalls, 1)
+		assert.Empty(t, plain.Choices[0].Message.ToolCalls)
+		assert.Equal(t, "Hello there", plain.Choices[0].Message.Content)
+	})
+
+	t.Run("ResultsAreCopies", func(t *testing.T) {
+		adapter := tooladapter.New(tooladapter.WithResponseCache(8, 0))
+		first, err := adapter.TransformCompletionsResponse(completion)
+		require.NoError(t, err)
+		first.Choices[0].Message.ToolCalls[0].ID = "modified"
+		first.Choices[0].FinishReason = "modified"
+
+		second, err := adapter.TransformCompletionsResponse(completion)
+		require.NoError(t, err)
+		assert.NotEqual(t, "modified", second.Choices[0].Message.ToolCalls[0].ID)
+		assert.Equal(t, "tool_calls", second.Choices[0].FinishReason)
+	})
+
+	t.Run("InterceptorRunsForEveryResponse", func(t *testing.T) {
+		var intercepted int
+		adapter := tooladapter.New(
+			tooladapter.WithResponseCache(8, 0),
+			tooladapter.WithToolCallInterceptor(func(context.Context, tooladapter.InterceptedToolCall) (tooladapter.ToolCallDecision, error) {
+				intercepted++
+				return tooladapter.ToolCallDecision{}, nil
+			}),
+		)
+		for range 2 {
+			_, err := adapter.TransformCompletionsResponse(completion)
+			require.NoError(t, err)
+		}
+		assert.Equal(t, 2, intercepted)
+	})
+
+	t.Run("ConversationCountsRetries", func(t *testing.T) {
+		adapter := tooladapter.New(
+			tooladapter.WithResponseCache(8, 0),
+			tooladapter.WithToolCallRateLimit(0, 1),
+		)
+		ctx := tooladapter.ContextWithConversation(context.Background(), tooladapter.NewConversation())
+
+		first, err := adapter.TransformCompletionsResponseWithContext(ctx, completion)
+		require.NoError(t, err)
+		assert.Len(t, first.Choices[0].Message.ToolCalls, 1)
+
+		second, err := adapter.TransformCompletionsResponseWithContext(ctx, completion)
+		require.NoError(t, err)
+		assert.Empty(t, second.Choices[0].Message.ToolCalls, "the conversation limit applies to the retry")
+	})
+
+	t.Run("KeyCoversRequestSettings", func(t *testing.T) {
+		adapter := tooladapter.New(
+			tooladapter.WithResponseCache(8, 0),
+			tooladapter.WithArgumentCoercion(true),
+		)
+		booking := tooltest.Completion(`{"name": "book_table", "parameters": {"guests": "4"}}`)
+		arguments := func(ctx context.Context) string {
+			t.Helper()
+			resp, err := adapter.TransformCompletionsResponseWithContext(ctx, booking)
+			require.NoError(t, err)
+			require.Len(t, resp.Choices[0].Message.ToolCalls, 1)
+			return resp.Choices[0].Message.ToolCalls[0].Function.Arguments
+		}
+
+		assert.JSONEq(t, `{"guests": "4"}`, arguments(context.Background()))
+		withTools := tooladapter.ContextWithTools(context.Background(), []openai.ChatCompletionToolUnionParam{bookingTool()})
+		assert.JSONEq(t, `{"guests": 4, "time": "19:00"}`, arguments(withTools), "attached schemas are not served the uncoerced result")
+	})
+
+	t.Run("Expires", func(t *testing.T) {
+		adapter := tooladapter.New(tooladapter.WithResponseCache(8, time.Millisecond))
+		first, err := adapter.TransformCompletionsResponse(completion)
+		require.NoError(t, err)
+		time.Sleep(5 * time.Millisecond)
+		second, err := adapter.TransformCompletionsResponse(completion)
+		require.NoError(t, err)
+		assert.NotEqual(t, first.Choices[0].Message.ToolCalls[0].ID, second.Choices[0].Message.ToolCalls[0].ID)
+	})
+
+	t.Run("Negative", func(t *testing.T) {
+		var logs bytes.Buffer
+		tooladapter.New(
+			tooladapter.WithLogger(slog.New(slog.NewTextHandler(&logs, nil))),
+			tooladapter.WithResponseCache(-1, -time.Second),
+		)
+		assert.Contains(t, logs.String(), "Negative size not allowed for ResponseCache")
+		assert.Contains(t, logs.String(), "Negative TTL not allowed for ResponseCache")
+	})
+
+	t.Run("Config", func(t *testing.T) {
+		cfg, err := tooladapter.LoadConfig(strings.NewReader(`{"response_cache_size": 32, "response_cache_ttl": "30s"}`))
+		require.NoError(t, err)
+		assert.Equal(t, 32, cfg.ResponseCacheSize)
+		assert.Equal(t, tooladapter.Duration(30*time.Second), cfg.ResponseCacheTTL)
+
+		_, err = tooladapter.NewFromConfig(tooladapter.Config{ResponseCacheSize: -1})
+		require.ErrorIs(t, err, tooladapter.ErrInvalidConfig)
+	})
+}
@@ -0,0 +1,88 @@
+package tooladapter_test
+
+import (
+	"bytes"
+	"log/slog"
+	"strings"
+	"testing"
+
+	tooladapter "github.com/juburr/openai-tool-adapter/v3"
+	"github.com/juburr/openai-tool-adapter/v3/tooltest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWithResponseParseMaxBytes(t *testing.T) {
+	call := `{"name": "get_weather", "parameters": {"city": "Paris"}}`
+
+	t.Run("SkipsLargerContent", func(t *testing.T) {
+		var metrics []tooladapter.ResponseParseSkippedData
+		var events []tooladapter.ParseEvent
+		adapter := tooladapter.New(
+			tooladapter.WithResponseParseMaxBytes(32),
+			tooladapter.WithMetricsCallback(func(data tooladapter.MetricEventData) {
+				if skipped, ok := data.(tooladapter.ResponseParseSkippedData); ok {
+					metrics = append(metrics, skipped)
+				}
+			}),
+			tooladapter.WithParseEventHook(func(e tooladapter.ParseEvent) {
+				events = append(events, e)
+			}),
+		)
+
+		resp, err := adapter.TransformCompletionsResponse(tooltest.Completion(call))
+		require.NoError(t, err)
+		assert.Empty(t, resp.Choices[0].Message.ToolCalls)
+		assert.Equal(t, call, resp.Choices[0].Message.Content)
+
+		require.Len(t, metrics, 1)
+		assert.Equal(t, tooladapter.ResponseParseSkippedData{ChoiceIndex: 0, ContentLength: len(call), MaxBytes: 32}, metrics[0])
+		require.Len(t, events, 1)
+		assert.Equal(t, tooladapter.ParseEventLimitExceeded, events[0].Type)
+		assert.Equal(t, tooladapter.ParseDetailResponseParseMaxBytes, events[0].Detail)
+		assert.False(t, events[0].Streaming)
+		assert.ErrorIs(t, events[0].Err, tooladapter.ErrBufferLimitExceeded)
+	})
+
+	t.Run("ParsesContentWithinLimit", func(t *testing.T) {
+		adapter := tooladapter.New(tooladapter.WithResponseParseMaxBytes(len(call)))
+		resp, err := adapter.TransformCompletionsResponse(tooltest.Completion(call))
+		require.NoError(t, err)
+		require.Len(t, resp.Choices[0].Message.ToolCalls, 1)
+	})
+
+	t.Run("StreamsUnaffected", func(t *testing.T) {
+		adapter := tooladapter.New(tooladapter.WithResponseParseMaxBytes(8))
+		result := tooltest.Drain(adapter.TransformStreamingResponse(tooltest.NewContentStream(call)))
+		require.NoError(t, result.Err)
+		assert.Equal(t, []string{"get_weather"}, result.ToolNames())
+	})
+
+	t.Run("NegativeIsUnlimited", func(t *testing.T) {
+		var logs bytes.Buffer
+		adapter := tooladapter.New(
+			tooladapter.WithLogger(slog.New(slog.NewTextHandler(&logs, nil))),
+			tooladapter.WithResponseParseMaxBytes(-1),
+		)
+		assert.Contains(t, logs.String(), "Negative byte count not allowed for ResponseParseMaxBytes")
+
+		resp, err := adapter.TransformCompletionsResponse(tooltest.Completion(strings.Repeat(" ", 1024) + call))
+		require.NoError(t, err)
+		require.Len(t, resp.Choices[0].Message.ToolCalls, 1)
+	})
+
+	t.Run("Config", func(t *testing.T) {
+		cfg, err := tooladapter.LoadConfig(strings.NewReader(`{"response_parse_max_bytes": 32}`))
+		require.NoError(t, err)
+		assert.Equal(t, 32, cfg.ResponseParseMaxBytes)
+
+		adapter, err := tooladapter.NewFromConfig(cfg)
+		require.NoError(t, err)
+		resp, err := adapter.TransformCompletionsResponse(tooltest.Completion(call))
+		require.NoError(t, err)
+		assert.Empty(t, resp.Choices[0].Message.ToolCalls)
+
+		_, err = tooladapter.NewFromConfig(tooladapter.Config{ResponseParseMaxBytes: -1})
+		require.ErrorIs(t, err, tooladapter.ErrInvalidConfig)
+	})
+}
@@ -0,0 +1,251 @@
+package tooladapter
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/openai/openai-go/v3"
+	"github.com/openai/openai-go/v3/option"
+)
+
+// ChatCompletionsClient is the subset of the OpenAI SDK used by CompleteWithRetry.
+// It is satisfied by client.Chat.Completions from github.com/openai/openai-go/v3.
+type ChatCompletionsClient interface {
+	New(ctx context.Context, body openai.ChatCompletionNewParams, opts ...option.RequestOption) (*openai.ChatCompletion, error)
+}
+
+// DefaultCorrectionPrompt is the corrective message sent after an unusable tool call.
+// The %s placeholder receives the parse or validation error.
+const DefaultCorrectionPrompt = `Your previous response attempted a tool call that could not be used: %s
+
+Respond again. To call a tool, reply with only JSON in the form {"name": "function_name", "parameters": {...}}. Otherwise answer without calling a tool.`
+
+// jsonCorrectionPrompt is the corrective message sent after a reply to a json_object
+// request that holds no JSON object. The %s placeholder receives JSONModePrompt.
+const jsonCorrectionPrompt = `Your previous response was not a JSON object.
+
+%s`
+
+// RetryPolicy controls how CompleteWithRetry re-asks the model after an unusable
+// tool call. The zero value makes a single attempt.
+type RetryPolicy struct {
+	// MaxAttempts is the total number of requests, including the first. Values
+	// below 1 are treated as 1.
+	MaxAttempts int
+
+	// Backoff is the delay before the first retry. Zero retries immediately.
+	Backoff time.Duration
+
+	// BackoffMultiplier scales the delay after each retry. Values below 1 keep
+	// the delay constant.
+	BackoffMultiplier float64
+
+	// MaxBackoff caps the delay between attempts. Zero means no cap.
+	MaxBackoff time.Duration
+
+	// CorrectionPrompt overrides DefaultCorrectionPrompt. It must contain exactly
+	// one %s placeholder for the error.
+	CorrectionPrompt string
+}
+
+// RetryExhaustedError is returned by CompleteWithRetry when every attempt produced
+// an unusable tool call.
+type RetryExhaustedError struct {
+	// Attempts is the number of requests made.
+	Attempts int
+
+	// Err is the error from the final attempt. It wraps ErrMalformedToolCall,
+	// ErrUnknownTool, ErrToolValidationFailed or ErrArgumentViolation.
+	Err error
+}
+
+func (e *RetryExhaustedError) Error() string {
+	return fmt.Sprintf("tool call still unusable after %d attempts: %v", e.Attempts, e.Err)
+}
+
+func (e *RetryExhaustedError) Unwrap() error {
+	return e.Err
+}
+
+// delay returns the wait before the given retry, starting at 1.
+func (p RetryPolicy) delay(retry int) time.Duration {
+	d := p.Backoff
+	for i := 1; i < retry && p.BackoffMultiplier > 1; i++ {
+		d = time.Duration(float64(d) * p.BackoffMultiplier)
+		if p.MaxBackoff > 0 && d >= p.MaxBackoff {
+			break
+		}
+	}
+	if p.MaxBackoff > 0 && d > p.MaxBackoff {
+		d = p.MaxBackoff
+	}
+	return d
+}
+
+// CompleteWithRetry transforms params, sends them with client and transforms the
+// response. When the first choice attempts a tool call that cannot be parsed, or
+// the response fails validation (for example ErrUnknownTool under
+// UnknownToolError, or ErrArgumentViolation under ArgumentViolationError), the
+// model's reply and a corrective user message carrying the error are appended to
+// the conversation and the request is sent again, up to policy.MaxAttempts times
+// with backoff between attempts. Replies to json_object requests without a JSON
+// object are retried the same way under JSONModeError.
+//
+// Plain text answers are not retried. Client and context errors are returned
+// immediately. When attempts run out, the final response (transformed where
+// possible, so its text can still be shown) is returned with a *RetryExhaustedError.
+func (a *Adapter) CompleteWithRetry(ctx context.Context, client ChatCompletionsClient, params openai.ChatCompletionNewParams, policy RetryPolicy) (openai.ChatCompletion, error) {
+	correction := policy.CorrectionPrompt
+	if correction == "" {
+		correction = DefaultCorrectionPrompt
+	} else if err := ValidatePromptTemplate(correction); err != nil {
+		return openai.ChatCompletion{}, err
+	}
+	maxAttempts := policy.MaxAttempts
+	if maxAttempts < 1 {
+		maxAttempts = 1
+	}
+	responseCtx := ContextWithTools(ctx, params.Tools)
+	if params.ParallelToolCalls.Valid() {
+		responseCtx = ContextWithParallelToolCalls(responseCtx, params.ParallelToolCalls.Value)
+	}
+	if requestsJSONMode(params) {
+		responseCtx = ContextWithJSONMode(responseCtx, true)
+	}
+	if results := toolMessageContents(params.Messages); len(results) > 0 {
+		responseCtx = ContextWithToolResults(responseCtx, results...)
+	}
+
+	for attempt := 1; ; attempt++ {
+		transformedReq, err := a.TransformCompletionsRequestWithContext(ctx, params)
+		if err != nil {
+			return openai.ChatCompletion{}, err
+		}
+
+		resp, err := client.New(ctx, transformedReq)
+		if err != nil {
+			return openai.ChatCompletion{}, err
+		}
+		if resp == nil {
+			return openai.ChatCompletion{}, errors.New("client returned a nil completion")
+		}
+
+		result, attemptErr := a.TransformCompletionsResponseWithContext(responseCtx, *resp)
+		switch {
+		case attemptErr == nil:
+			attemptErr = a.diagnoseToolCall(result)
+		case errors.Is(attemptErr, ErrUnknownTool), errors.Is(attemptErr, ErrToolValidationFailed),
+			errors.Is(attemptErr, ErrArgumentViolation), errors.Is(attemptErr, ErrInvalidJSONResponse):
+			result = *resp
+		default:
+			return openai.ChatCompletion{}, attemptErr
+		}
+		if attemptErr == nil {
+			return result, nil
+		}
+
+		if attempt >= maxAttempts {
+			return result, &RetryExhaustedError{Attempts: attempt, Err: attemptErr}
+		}
+
+		a.logger.Warn("Model produced an unusable tool call, retrying",
+			"attempt", attempt,
+			"max_attempts", maxAttempts,
+			"error", attemptErr)
+
+		message := fmt.Sprintf(correction, attemptErr)
+		if errors.Is(attemptErr, ErrInvalidJSONResponse) {
+			message = fmt.Sprintf(jsonCorrectionPrompt, JSONModePrompt)
+		}
+		params.Messages = append(params.Messages[:len(params.Messages):len(params.Messages)],
+			openai.AssistantMessage(resp.Choices[0].Message.Content),
+			openai.UserMessage(message))
+
+		if err := sleepContext(ctx, policy.delay(attempt)); err != nil {
+			return openai.ChatCompletion{}, err
+		}
+	}
+}
+
+// diagnoseToolCall returns an error wrapping ErrMalformedToolCall when the first
+// choice of a transformed response looks like a tool call attempt but yielded no
+// tool calls. Responses that parsed, and plain text, return nil.
+func (a *Adapter) diagnoseToolCall(resp openai.ChatCompletion) error {
+	if len(resp.Choices) == 0 || len(resp.Choices[0].Message.ToolCalls) > 0 {
+		return nil
+	}
+	content := resp.Choices[0].Message.Content
+	if !looksLikeToolCall(content) || a.exceedsResponseParseLimit(len(content)) {
+		return nil
+	}
+
+	candidates := a.extractCandidates(content, false)
+	if len(ExtractFunctionCalls(candidates)) > 0 {
+		// Parsed, but dropped by a filter or policy rather than malformed
+		return nil
+	}
+	for _, candidate := range candidates {
+		if looksLikeToolCall(candidate) {
+			return fmt.Errorf("%w: %v", ErrMalformedToolCall, describeCandidateError(candidate))
+		}
+	}
+	return fmt.Errorf("%w: the JSON is incomplete or invalid", ErrMalformedToolCall)
+}
+
+// looksLikeToolCall reports whether content carries the keys of a tool call. A
+// "name" key alone is common in ordinary JSON answers, so it must be paired with
+// "parameters" or "arguments".
+func looksLikeToolCall(content string) bool {
+	if strings.Contains(content, `"function_call"`) || strings.Contains(content, `"tool_calls"`) {
+		return true
+	}
+	return strings.Contains(content, `"name"`) &&
+		(strings.Contains(content, `"parameters"`) || strings.Contains(content, `"arguments"`))
+}
+
+// describeCandidateError explains why a candidate was rejected, using the same
+// strict decoding as ExtractFunctionCallsDetailed.
+func describeCandidateError(candidate string) error {
+	decoder := json.NewDecoder(strings.NewReader(candidate))
+	decoder.DisallowUnknownFields()
+
+	var err error
+	if strings.HasPrefix(strings.TrimSpace(candidate), "[") {
+		var calls []functionCall
+		if err = decoder.Decode(&calls); err == nil {
+			for _, call := range calls {
+				if err = ValidateFunctionName(call.Name); err != nil {
+					break
+				}
+			}
+		}
+	} else {
+		var call functionCall
+		if err = decoder.Decode(&call); err == nil {
+			err = ValidateFunctionName(call.Name)
+		}
+	}
+	if err == nil {
+		return errors.New("the tool call has an unsupported shape")
+	}
+	return err
+}
+
+// sleepContext waits for d or until ctx is done.
+func sleepContext(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return ctx.Err()
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
@@ -0,0 +1,19 @@
+package tooladapter
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRetryPolicyDelay(t *testing.T) {
+	policy := RetryPolicy{Backoff: 100 * time.Millisecond, BackoffMultiplier: 2, MaxBackoff: 300 * time.Millisecond}
+	assert.Equal(t, 100*time.Millisecond, policy.delay(1))
+	assert.Equal(t, 200*time.Millisecond, policy.delay(2))
+	assert.Equal(t, 300*time.Millisecond, policy.delay(3))
+	assert.Equal(t, 300*time.Millisecond, policy.delay(10))
+
+	constant := RetryPolicy{Backoff: 50 * time.Millisecond}
+	assert.Equal(t, 50*time.Millisecond, constant.delay(4))
+}
@@ -0,0 +1,129 @@
+package tooladapter_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	tooladapter "github.com/juburr/openai-tool-adapter/v3"
+	"github.com/openai/openai-go/v3"
+	"github.com/openai/openai-go/v3/option"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// scriptedClient returns one completion per call with the given contents and
+// records the requests it receives.
+type scriptedClient struct {
+	replies  []string
+	requests []openai.ChatCompletionNewParams
+	err      error
+}
+
+func (c *scriptedClient) New(_ context.Context, body openai.ChatCompletionNewParams, _ ...option.RequestOption) (*openai.ChatCompletion, error) {
+	c.requests = append(c.requests, body)
+	if c.err != nil {
+		return nil, c.err
+	}
+	reply := c.replies[len(c.requests)-1]
+	completion := createMockCompletion(reply)
+	return &completion, nil
+}
+
+func retryRequest() openai.ChatCompletionNewParams {
+	return createMockRequest([]openai.ChatCompletionToolUnionParam{
+		createMockTool("get_weather", "Get the weather"),
+	})
+}
+
+func TestCompleteWithRetry(t *testing.T) {
+	t.Run("RetriesMalformedToolCall", func(t *testing.T) {
+		client := &scriptedClient{replies: []string{
+			`{"name": "get_weather", "arguments": {"city": "Paris"}}`,
+			`{"name": "get_weather", "parameters": {"city": "Paris"}}`,
+		}}
+		resp, err := tooladapter.New().CompleteWithRetry(context.Background(), client, retryRequest(),
+			tooladapter.RetryPolicy{MaxAttempts: 3})
+
+		require.NoError(t, err)
+		require.Len(t, resp.Choices[0].Message.ToolCalls, 1)
+		assert.Equal(t, "get_weather", resp.Choices[0].Message.ToolCalls[0].Function.Name)
+
+		require.Len(t, client.requests, 2)
+		retried := client.requests[1].Messages
+		require.Len(t, retried, len(client.requests[0].Messages)+2)
+		assert.NotNil(t, retried[len(retried)-2].OfAssistant)
+		correction := retried[len(retried)-1].OfUser
+		require.NotNil(t, correction)
+		assert.Contains(t, correction.Content.OfString.Value, `unknown field "arguments"`)
+	})
+
+	t.Run("RetriesUnknownTool", func(t *testing.T) {
+		client := &scriptedClient{replies: []string{
+			`{"name": "get_forecast", "parameters": null}`,
+			`{"name": "get_weather", "parameters": null}`,
+		}}
+		adapter := tooladapter.New(
+			tooladapter.WithAllowedToolNames([]string{"get_weather"}),
+			tooladapter.WithUnknownToolPolicy(tooladapter.UnknownToolError))
+		resp, err := adapter.CompleteWithRetry(context.Background(), client, retryRequest(),
+			tooladapter.RetryPolicy{MaxAttempts: 2})
+
+		require.NoError(t, err)
+		assert.Len(t, resp.Choices[0].Message.ToolCalls, 1)
+		assert.Len(t, client.requests, 2)
+	})
+
+	t.Run("PlainTextNotRetried", func(t *testing.T) {
+		client := &scriptedClient{replies: []string{`Paris is sunny: {"city": "Paris", "temperature": 21}.`}}
+		resp, err := tooladapter.New().CompleteWithRetry(context.Background(), client, retryRequest(),
+			tooladapter.RetryPolicy{MaxAttempts: 3})
+
+		require.NoError(t, err)
+		assert.Equal(t, `Paris is sunny: {"city": "Paris", "temperature": 21}.`, resp.Choices[0].Message.Content)
+		assert.Len(t, client.requests, 1)
+	})
+
+	t.Run("Exhausted", func(t *testing.T) {
+		client := &scriptedClient{replies: []string{
+			`{"name": "get_weather", "parameters": {"city": "Paris"`,
+			`{"name": "get_weather", "parameters": {"city": "Paris"`,
+		}}
+		resp, err := tooladapter.New().CompleteWithRetry(context.Background(), client, retryRequest(),
+			tooladapter.RetryPolicy{MaxAttempts: 2})
+
+		require.ErrorIs(t, err, tooladapter.ErrMalformedToolCall)
+		var exhausted *tooladapter.RetryExhaustedError
+		require.ErrorAs(t, err, &exhausted)
+		assert.Equal(t, 2, exhausted.Attempts)
+		assert.Equal(t, `{"name": "get_weather", "parameters": {"city": "Paris"`, resp.Choices[0].Message.Content)
+	})
+
+	t.Run("ClientErrorNotRetried", func(t *testing.T) {
+		clientErr := errors.New("connection refused")
+		client := &scriptedClient{err: clientErr}
+		_, err := tooladapter.New().CompleteWithRetry(context.Background(), client, retryRequest(),
+			tooladapter.RetryPolicy{MaxAttempts: 3})
+
+		assert.ErrorIs(t, err, clientErr)
+		assert.Len(t, client.requests, 1)
+	})
+
+	t.Run("BackoffHonorsContext", func(t *testing.T) {
+		client := &scriptedClient{replies: []string{`{"name": "get_weather", "arguments": null}`}}
+		ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+		defer cancel()
+		_, err := tooladapter.New().CompleteWithRetry(ctx, client, retryRequest(),
+			tooladapter.RetryPolicy{MaxAttempts: 3, Backoff: time.Minute})
+
+		assert.ErrorIs(t, err, context.DeadlineExceeded)
+		assert.Len(t, client.requests, 1)
+	})
+
+	t.Run("InvalidCorrectionPrompt", func(t *testing.T) {
+		_, err := tooladapter.New().CompleteWithRetry(context.Background(), &scriptedClient{}, retryRequest(),
+			tooladapter.RetryPolicy{CorrectionPrompt: "no placeholder"})
+		assert.ErrorIs(t, err, tooladapter.ErrTemplateRender)
+	})
+}
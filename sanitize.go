@@ -0,0 +1,270 @@
+package tooladapter
+
+import (
+	"encoding/json"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/openai/openai-go/v3"
+)
+
+// Reasons reported in ToolDefinitionAlteration.
+const (
+	// SanitizeReasonBackticks reports backticks replaced with single quotes, so
+	// descriptions cannot open code fences that imitate tool call examples.
+	SanitizeReasonBackticks = "backticks"
+
+	// SanitizeReasonRoleMarker reports removed chat template tokens such as
+	// <|im_start|> or [INST], and line prefixes such as "system:".
+	SanitizeReasonRoleMarker = "role_marker"
+
+	// SanitizeReasonInstruction reports removed instruction overrides such as
+	// "ignore previous instructions".
+	SanitizeReasonInstruction = "instruction_override"
+
+	// SanitizeReasonLineBreaks reports line breaks collapsed into spaces, so a
+	// description cannot start what looks like a new prompt section or tool entry.
+	SanitizeReasonLineBreaks = "line_breaks"
+
+	// SanitizeReasonControlCharacters reports removed control and invisible
+	// formatting characters.
+	SanitizeReasonControlCharacters = "control_characters"
+)
+
+// removedInstructionText replaces instruction overrides in sanitized text.
+const removedInstructionText = "[removed]"
+
+var (
+	// Special tokens of common chat templates (ChatML, Llama 2/3, Gemma, Mistral)
+	roleTokenPattern = regexp.MustCompile(`(?i)<\|[a-z0-9_]{1,32}\|>|\[/?INST\]|<</?SYS>>|</?(?:start|end)_of_turn>|</?s>`)
+
+	// Role labels at the start of a line, as in transcripts
+	rolePrefixPattern = regexp.MustCompile(`(?im)^[ \t]*(?:#+[ \t]*)?(?:system|assistant|user|developer)[ \t]*:`)
+
+	instructionPatterns = []*regexp.Regexp{
+		regexp.MustCompile(`(?i)\b(?:ignore|disregard|forget|override)\s+(?:all\s+|any\s+)?(?:of\s+)?(?:the\s+|your\s+)?(?:previous|prior|above|earlier|preceding|system|other)\s+(?:instructions?|prompts?|messages?|rules?|directions?)`),
+		regexp.MustCompile(`(?i)\byou\s+are\s+now\b`),
+		regexp.MustCompile(`(?i)\bnew\s+instructions?\s*:`),
+	}
+
+	lineBreakPattern = regexp.MustCompile(`[ \t]*[\r\n]+[ \t]*`)
+)
+
+// ToolDefinitionAlteration records one text field of a tool definition changed by
+// sanitization.
+type ToolDefinitionAlteration struct {
+	// ToolName is the function the field belongs to
+	ToolName string `json:"tool_name"`
+
+	// Field locates the text, either "description" or a path into the parameter
+	// schema such as "parameters.properties.unit.description"
+	Field string `json:"field"`
+
+	// Reasons lists what was changed, using the SanitizeReason constants
+	Reasons []string `json:"reasons"`
+
+	// Original is the text before sanitization
+	Original string `json:"original"`
+
+	// Sanitized is the text injected into the prompt
+	Sanitized string `json:"sanitized"`
+}
+
+// SanitizeToolDefinitions returns tools with instruction-like content removed from
+// function descriptions and from the "description" and "title" strings of their
+// parameter schemas, together with a report of every altered field. Tool names,
+// property names and enum values are left unchanged. The caller's tools are never
+// modified; the input slice is returned when nothing changed.
+//
+// This is the transformation applied by WithSanitizeToolDefinitions, exposed for
+// auditing tool definitions ahead of time.
+func SanitizeToolDefinitions(tools []openai.ChatCompletionToolUnionParam) ([]openai.ChatCompletionToolUnionParam, []ToolDefinitionAlteration) {
+	var alterations []ToolDefinitionAlteration
+	sanitized := tools
+	copied := false
+
+	for i, tool := range tools {
+		function := tool.GetFunction()
+		if function == nil {
+			continue
+		}
+		before := len(alterations)
+
+		description := function.Description.Or("")
+		if clean, reasons := sanitizeDefinitionText(description); len(reasons) > 0 {
+			alterations = append(alterations, ToolDefinitionAlteration{
+				ToolName:  function.Name,
+				Field:     "description",
+				Reasons:   reasons,
+				Original:  description,
+				Sanitized: clean,
+			})
+			description = clean
+		}
+
+		var parameters openai.FunctionParameters
+		if schema := schemaAsJSONValue(function.Parameters); schema != nil {
+			if clean, changed := sanitizeSchemaValue(schema, "parameters", "", function.Name, &alterations); changed {
+				parameters = clean.(map[string]any)
+			}
+		}
+
+		if len(alterations) == before {
+			continue
+		}
+
+		// Copy on first change so the caller's slice and tool structs stay untouched
+		if !copied {
+			sanitized = make([]openai.ChatCompletionToolUnionParam, len(tools))
+			copy(sanitized, tools)
+			copied = true
+		}
+		sanitizedTool := *tools[i].OfFunction
+		if function.Description.Valid() {
+			sanitizedTool.Function.Description = openai.String(description)
+		}
+		if parameters != nil {
+			sanitizedTool.Function.Parameters = parameters
+		}
+		sanitized[i] = openai.ChatCompletionToolUnionParam{OfFunction: &sanitizedTool}
+	}
+
+	return sanitized, alterations
+}
+
+// sanitizeTools applies SanitizeToolDefinitions when enabled and reports the changes
+// through the logger and metrics.
+func (a *Adapter) sanitizeTools(tools []openai.ChatCompletionToolUnionParam) []openai.ChatCompletionToolUnionParam {
+	if !a.sanitizeToolDefinitions {
+		return tools
+	}
+	sanitized, alterations := SanitizeToolDefinitions(tools)
+	if len(alterations) == 0 {
+		return tools
+	}
+	for _, alteration := range alterations {
+		a.log(LogCategoryRequest).Warn("Sanitized tool definition",
+			"function_name", alteration.ToolName,
+			"field", alteration.Field,
+			"reasons", alteration.Reasons)
+	}
+	a.emitMetric(ToolSanitizationData{Alterations: alterations})
+	return sanitized
+}
+
+// schemaAsJSONValue returns parameters as generic JSON values, so schemas built from
+// typed Go values are sanitized like decoded ones. It returns nil when there are no
+// parameters or they cannot be encoded.
+func schemaAsJSONValue(parameters openai.FunctionParameters) map[string]any {
+	if parameters == nil {
+		return nil
+	}
+	data, err := json.Marshal(parameters)
+	if err != nil {
+		return nil
+	}
+	var schema map[string]any
+	if err := json.Unmarshal(data, &schema); err != nil {
+		return nil
+	}
+	return schema
+}
+
+// sanitizeSchemaValue sanitizes the "description" and "title" strings of a decoded
+// JSON schema value in place and reports whether anything changed.
+func sanitizeSchemaValue(value any, path, key, toolName string, alterations *[]ToolDefinitionAlteration) (any, bool) {
+	switch v := value.(type) {
+	case string:
+		if key != "description" && key != "title" {
+			return v, false
+		}
+		clean, reasons := sanitizeDefinitionText(v)
+		if len(reasons) == 0 {
+			return v, false
+		}
+		*alterations = append(*alterations, ToolDefinitionAlteration{
+			ToolName:  toolName,
+			Field:     path,
+			Reasons:   reasons,
+			Original:  v,
+			Sanitized: clean,
+		})
+		return clean, true
+	case map[string]any:
+		keys := make([]string, 0, len(v))
+		for k := range v {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys) // Report alterations in a stable order
+		changed := false
+		for _, k := range keys {
+			// Keys of "properties" are property names, not schema keywords
+			childKey := k
+			if key == "properties" {
+				childKey = ""
+			}
+			if clean, ok := sanitizeSchemaValue(v[k], path+"."+k, childKey, toolName, alterations); ok {
+				v[k] = clean
+				changed = true
+			}
+		}
+		return v, changed
+	case []any:
+		changed := false
+		for i, item := range v {
+			if clean, ok := sanitizeSchemaValue(item, path+"["+strconv.Itoa(i)+"]", "", toolName, alterations); ok {
+				v[i] = clean
+				changed = true
+			}
+		}
+		return v, changed
+	}
+	return value, false
+}
+
+// sanitizeDefinitionText removes instruction-like content from text and returns the
+// result with the reasons for each kind of change, in a fixed order.
+func sanitizeDefinitionText(text string) (string, []string) {
+	if text == "" {
+		return text, nil
+	}
+	var reasons []string
+	apply := func(reason, updated string) {
+		if updated != text {
+			reasons = append(reasons, reason)
+			text = updated
+		}
+	}
+
+	apply(SanitizeReasonControlCharacters, strings.Map(func(r rune) rune {
+		if r == '\n' || r == '\r' || r == '\t' {
+			return r
+		}
+		if unicode.IsControl(r) || unicode.Is(unicode.Cf, r) {
+			return -1
+		}
+		return r
+	}, text))
+
+	apply(SanitizeReasonRoleMarker, rolePrefixPattern.ReplaceAllString(roleTokenPattern.ReplaceAllString(text, ""), ""))
+
+	updated := text
+	for _, pattern := range instructionPatterns {
+		updated = pattern.ReplaceAllString(updated, removedInstructionText)
+	}
+	apply(SanitizeReasonInstruction, updated)
+
+	apply(SanitizeReasonBackticks, strings.ReplaceAll(text, "`", "'"))
+
+	if trimmed := strings.TrimSpace(text); strings.ContainsAny(trimmed, "\r\n") {
+		apply(SanitizeReasonLineBreaks, lineBreakPattern.ReplaceAllString(trimmed, " "))
+	}
+
+	if len(reasons) == 0 {
+		return text, nil
+	}
+	return strings.TrimSpace(text), reasons
+}
@@ -0,0 +1,218 @@
+package tooladapter
+
+import (
+	"strings"
+	"unicode/utf8"
+)
+
+// jsonScanner is a byte-oriented equivalent of JSONExtractor used on the hot parsing
+// path. Every character the state machine reacts to ({ } [ ] " \ ` and ASCII
+// whitespace) is a single byte, and UTF-8 continuation bytes never collide with
+// them, so scanning bytes yields exactly the same candidates as scanning runes.
+//
+// Between candidates, the scanner jumps straight to the next '{', '[' or '`' using
+// strings.IndexByte, which is vectorized on most platforms. This keeps multi-megabyte
+// responses with little or no JSON from being walked one rune at a time.
+type jsonScanner struct {
+	input string
+
+	// Cached positions of the next '{', '[' and '`' at or after the scan position.
+	// A value of -1 means the character does not occur again.
+	next [3]int
+}
+
+// scanOpeners lists the bytes that can start a candidate, in the order of next.
+var scanOpeners = [3]byte{'{', '[', '`'}
+
+// scanJSONBlocks returns the same candidates as NewJSONExtractor(content).ExtractJSONBlocks().
+func scanJSONBlocks(content string) []string {
+	return scanJSONBlockFormats(content, nil)
+}
+
+// scanJSONBlockFormats is scanJSONBlocks that, when formats is not nil, also records
+// the format of every candidate: CallFormatCodeFence for the body of a ``` fence
+// and CallFormatJSON otherwise.
+func scanJSONBlockFormats(content string, formats *[]CallFormat) []string {
+	s := &jsonScanner{input: content}
+	for i := range s.next {
+		s.next[i] = strings.IndexByte(content, scanOpeners[i])
+	}
+
+	var results []string
+	var seen map[string]struct{}
+	pos := 0
+	for {
+		pos = s.nextOpener(pos)
+		if pos < 0 {
+			break
+		}
+
+		var start, end, next int
+		var found bool
+		format := CallFormatJSON
+		switch {
+		case content[pos] != '`':
+			start, end, next, found = s.parseStructure(pos)
+			if !found && next < len(content) {
+				// Mismatched closer: resume right after the opener
+				pos = next
+				continue
+			}
+		case pos+2 < len(content) && content[pos+1] == '`' && content[pos+2] == '`':
+			start, end, next, found = s.parseTripleBacktick(pos)
+			format = CallFormatCodeFence
+		default:
+			start, end, next, found = s.parseSingleBacktick(pos)
+		}
+		if !found {
+			// Unclosed or non-JSON code blocks and incomplete structures consume the
+			// rest of the input, matching JSONExtractor
+			break
+		}
+
+		candidate := content[start:end]
+		if !utf8.ValidString(candidate) {
+			// JSONExtractor decodes invalid bytes to U+FFFD; do the same
+			candidate = string([]rune(candidate))
+		}
+		if seen == nil {
+			seen = make(map[string]struct{})
+		}
+		if _, dup := seen[candidate]; !dup {
+			seen[candidate] = struct{}{}
+			results = append(results, candidate)
+			if formats != nil {
+				*formats = append(*formats, format)
+			}
+		}
+		pos = next
+	}
+	return results
+}
+
+// nextOpener returns the position of the first candidate opener at or after pos, or
+// -1 when none remain. Cached positions are only refreshed once pos passes them, so
+// each byte is searched at most once per opener.
+func (s *jsonScanner) nextOpener(pos int) int {
+	best := -1
+	for i, at := range s.next {
+		if at != -1 && at < pos {
+			at = -1
+			if pos < len(s.input) {
+				if idx := strings.IndexByte(s.input[pos:], scanOpeners[i]); idx != -1 {
+					at = pos + idx
+				}
+			}
+			s.next[i] = at
+		}
+		if at != -1 && (best == -1 || at < best) {
+			best = at
+		}
+	}
+	return best
+}
+
+// parseStructure mirrors JSONExtractor.parseJSONStructure. On a mismatched closer it
+// reports next as the byte after the opener; on incomplete input next is len(input).
+func (s *jsonScanner) parseStructure(start int) (int, int, int, bool) {
+	input := s.input
+	stack := make([]byte, 1, 32)
+	if input[start] == '{' {
+		stack[0] = '}'
+	} else {
+		stack[0] = ']'
+	}
+
+	inString := false
+	for i := start + 1; i < len(input); i++ {
+		c := input[i]
+		if inString {
+			switch c {
+			case '\\':
+				i++ // Skip the escaped byte
+			case '"':
+				inString = false
+			}
+			continue
+		}
+
+		switch c {
+		case '{':
+			stack = append(stack, '}')
+		case '[':
+			stack = append(stack, ']')
+		case '}', ']':
+			if stack[len(stack)-1] != c {
+				return 0, 0, start + 1, false
+			}
+			stack = stack[:len(stack)-1]
+			if len(stack) == 0 {
+				return start, i + 1, i + 1, true
+			}
+		case '"':
+			inString = true
+		}
+	}
+	return 0, 0, len(input), false
+}
+
+// parseTripleBacktick mirrors JSONExtractor.parseTripleBacktickBlock.
+func (s *jsonScanner) parseTripleBacktick(start int) (int, int, int, bool) {
+	input := s.input
+	i := start + 3
+	for i < len(input) && isFenceInfoByte(input[i]) {
+		i++
+	}
+	for i < len(input) && isASCIISpace(input[i]) {
+		i++
+	}
+
+	closing := strings.Index(input[i:], "```")
+	if closing == -1 {
+		return 0, 0, 0, false
+	}
+	contentStart, contentEnd := trimASCIISpace(input, i, i+closing)
+	if contentStart == contentEnd || (input[contentStart] != '{' && input[contentStart] != '[') {
+		return 0, 0, 0, false
+	}
+	return contentStart, contentEnd, i + closing + 3, true
+}
+
+// parseSingleBacktick mirrors JSONExtractor.parseSingleBacktickBlock.
+func (s *jsonScanner) parseSingleBacktick(start int) (int, int, int, bool) {
+	input := s.input
+	closing := strings.IndexByte(input[start+1:], '`')
+	if closing == -1 {
+		return 0, 0, 0, false
+	}
+	end := start + 1 + closing
+	contentStart, contentEnd := trimASCIISpace(input, start+1, end)
+	if contentStart == contentEnd || (input[contentStart] != '{' && input[contentStart] != '[') {
+		return 0, 0, 0, false
+	}
+	return contentStart, contentEnd, end + 1, true
+}
+
+// trimASCIISpace narrows input[start:end] to exclude leading and trailing whitespace
+// as defined by JSONExtractor.isWhitespace.
+func trimASCIISpace(input string, start, end int) (int, int) {
+	for start < end && isASCIISpace(input[start]) {
+		start++
+	}
+	for end > start && isASCIISpace(input[end-1]) {
+		end--
+	}
+	return start, end
+}
+
+// isASCIISpace matches JSONExtractor.isWhitespace for a single byte.
+func isASCIISpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r'
+}
+
+// isFenceInfoByte matches the bytes of a code fence's language tag, such as "json",
+// "JSON" or "json5".
+func isFenceInfoByte(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' ||
+		c == '+' || c == '-' || c == '_' || c == '.'
+}
@@ -0,0 +1,68 @@
+package tooladapter
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// scannerEquivalenceInputs covers the extractor edge cases the scanner must mirror.
+var scannerEquivalenceInputs = []string{
+	``,
+	`plain text without json`,
+	`{"name": "test", "parameters": {}}`,
+	`[{"name": "a"}, {"name": "b"}]`,
+	`text {"a": 1} more {"a": 1} dup`,
+	"```json\n{\"name\": \"fenced\"}\n```",
+	"```\n[1, 2]\n```",
+	"```json```",
+	"```yaml\nname: x\n``` then {\"name\": \"after\"}",
+	"``` unclosed {\"name\": \"x\"}",
+	"`{\"name\": \"inline\"}` and `not json` then {\"a\": 1}",
+	"` {\"spaced\": true} `",
+	"`unclosed {\"name\": \"x\"}",
+	`{"a": "}"} {"b": "\"}"}`,
+	`{"a": [1, 2}`,
+	`{"a": [1, 2}]} {"b": 2}`,
+	`{"incomplete": {"x": 1}`,
+	`]] }} {"ok": true}`,
+	`{"emoji": "🌤️ {", "k": "ü"} [“quotes”]`,
+	"{\"bad\": \"\xff\xfe\"}",
+	`{"esc": "\\"} {"next": 1}`,
+	"trailing backslash {\"a\": \"\\",
+	"\t\r\n`\t{\"ws\": 1}\r\n`",
+	"```JSON\n{\"name\": \"upper\"}\n```",
+	"```json5\n{name: 'x'}\n``` then {\"a\": 1}",
+	"```c++ {\"a\": 1}```",
+	"```é{\"a\": 1}```",
+	"~~~json\n{\"name\": \"tilde\"}\n~~~",
+	"\ufeff{\"bom\": 1}\u200b",
+}
+
+func TestScanJSONBlocksMatchesExtractor(t *testing.T) {
+	for _, input := range scannerEquivalenceInputs {
+		expected := NewJSONExtractor(input).ExtractJSONBlocks()
+		assert.Equal(t, expected, scanJSONBlocks(input), "input: %q", input)
+	}
+
+	// Large inputs exercise the cached opener positions across many candidates
+	large := strings.Repeat("prose without openers. ", 5000) + "`x` " +
+		strings.Repeat(`{"name": "f", "parameters": {"i": [1]}} text [1] `, 200) + "```json\n{\"end\": true}\n```"
+	assert.Equal(t, NewJSONExtractor(large).ExtractJSONBlocks(), scanJSONBlocks(large))
+}
+
+// FuzzScanJSONBlocks checks that the byte scanner and the rune state machine agree.
+func FuzzScanJSONBlocks(f *testing.F) {
+	for _, input := range scannerEquivalenceInputs {
+		f.Add(input)
+	}
+
+	f.Fuzz(func(t *testing.T, input string) {
+		expected := NewJSONExtractor(input).ExtractJSONBlocks()
+		actual := scanJSONBlocks(input)
+		if strings.Join(expected, "\x00") != strings.Join(actual, "\x00") || len(expected) != len(actual) {
+			t.Errorf("scanner mismatch for %q:\nextractor: %q\nscanner:   %q", input, expected, actual)
+		}
+	})
+}
@@ -0,0 +1,97 @@
+package tooladapter
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/openai/openai-go/v3"
+)
+
+// SchemaFormat selects how tool parameter schemas are rendered into the injected
+// prompt.
+type SchemaFormat int
+
+const (
+	// SchemaCompact renders schemas as canonical minified JSON: object keys sorted,
+	// no whitespace and no HTML escaping (default). Identical schemas always render
+	// to identical bytes, whatever formatting they were supplied with, which keeps
+	// prompts small and provider prompt caches warm.
+	SchemaCompact SchemaFormat = iota
+
+	// SchemaPretty renders schemas as canonical JSON indented by two spaces, which
+	// some models follow more reliably at the cost of a larger prompt.
+	SchemaPretty
+)
+
+// String returns a human-readable string representation of the SchemaFormat.
+func (f SchemaFormat) String() string {
+	switch f {
+	case SchemaCompact:
+		return "SchemaCompact"
+	case SchemaPretty:
+		return "SchemaPretty"
+	default:
+		return fmt.Sprintf("SchemaFormat(%d)", int(f))
+	}
+}
+
+// renderSchema renders tool parameters as WithSchemaFormat selects. It reports false
+// when the parameters cannot be encoded as JSON.
+func (a *Adapter) renderSchema(parameters openai.FunctionParameters) (string, bool) {
+	source, err := json.Marshal(parameters)
+	if err != nil {
+		return "", false
+	}
+	return a.canonicalSchema(source), true
+}
+
+// canonicalSchema re-encodes schema JSON as canonicalJSON does, minified or
+// indented by the schema format.
+func (a *Adapter) canonicalSchema(source []byte) string {
+	return canonicalJSON(source, a.schemaFormat == SchemaPretty)
+}
+
+// canonicalJSON re-encodes JSON with sorted keys and without HTML escaping,
+// minified or indented by two spaces. Nested json.RawMessage values keep their own
+// formatting and key order through json.Marshal, so the JSON is decoded and encoded
+// again rather than compacted.
+func canonicalJSON(source []byte, indent bool) string {
+	decoder := json.NewDecoder(bytes.NewReader(source))
+	decoder.UseNumber()
+	var value any
+	if err := decoder.Decode(&value); err != nil {
+		return string(source)
+	}
+
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	encoder.SetEscapeHTML(false)
+	if indent {
+		encoder.SetIndent("", "  ")
+	}
+	if err := encoder.Encode(value); err != nil {
+		return string(source)
+	}
+	return strings.TrimSuffix(buf.String(), "\n")
+}
+
+// schemaSizes returns the bytes of the tools' parameter schemas as rendered into the
+// prompt and as encoded by json.Marshal alone, which keeps the formatting of raw
+// JSON values and escapes HTML characters.
+func (a *Adapter) schemaSizes(tools []openai.ChatCompletionToolUnionParam) (rendered, source int) {
+	for _, tool := range tools {
+		function := tool.GetFunction()
+		if function == nil || function.Parameters == nil {
+			continue
+		}
+		encoded, err := json.Marshal(function.Parameters)
+		if err != nil {
+			continue
+		}
+		source += len(encoded)
+		rendered += len(a.canonicalSchema(encoded))
+	}
+	return rendered, source
+}
@@ -0,0 +1,118 @@
+package tooladapter_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"strings"
+	"testing"
+
+	tooladapter "github.com/juburr/openai-tool-adapter/v3"
+	"github.com/juburr/openai-tool-adapter/v3/tooltest"
+	"github.com/openai/openai-go/v3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// schemaRequest returns a request offering a tool whose parameters carry raw JSON
+// with whitespace, unsorted keys and HTML characters.
+func schemaRequest() openai.ChatCompletionNewParams {
+	return tooltest.Request(openai.ChatCompletionFunctionTool(openai.FunctionDefinitionParam{
+		Name: "compare",
+		Parameters: openai.FunctionParameters{
+			"type": "object",
+			"properties": json.RawMessage(`{
+				"b": {"type": "string", "description": "Value > 0 & < 10"},
+				"a": {"type": "integer"}
+			}`),
+		},
+	}))
+}
+
+func TestWithSchemaFormat(t *testing.T) {
+	compact := `{"properties":{"a":{"type":"integer"},"b":{"description":"Value > 0 & < 10","type":"string"}},"type":"object"}`
+
+	t.Run("CompactByDefault", func(t *testing.T) {
+		transformed, err := tooladapter.New().TransformCompletionsRequest(schemaRequest())
+		require.NoError(t, err)
+		assert.Contains(t, injectedText(t, transformed), "Parameters: "+compact)
+	})
+
+	t.Run("Pretty", func(t *testing.T) {
+		adapter := tooladapter.New(tooladapter.WithSchemaFormat(tooladapter.SchemaPretty))
+		transformed, err := adapter.TransformCompletionsRequest(schemaRequest())
+		require.NoError(t, err)
+
+		var pretty bytes.Buffer
+		require.NoError(t, json.Indent(&pretty, []byte(compact), "", "  "))
+		assert.Contains(t, injectedText(t, transformed), "Parameters: "+pretty.String())
+	})
+
+	t.Run("PromptTemplate", func(t *testing.T) {
+		adapter := tooladapter.New(tooladapter.WithToolPromptTemplate(`{{range .Tools}}{{.Name}} {{.Parameters}}{{end}}`))
+		transformed, err := adapter.TransformCompletionsRequest(schemaRequest())
+		require.NoError(t, err)
+		assert.Contains(t, injectedText(t, transformed), "compare "+compact)
+	})
+
+	t.Run("SavingsMetric", func(t *testing.T) {
+		var data []tooladapter.ToolTransformationData
+		callback := func(d tooladapter.MetricEventData) {
+			if transformation, ok := d.(tooladapter.ToolTransformationData); ok {
+				data = append(data, transformation)
+			}
+		}
+
+		_, err := tooladapter.New(tooladapter.WithMetricsCallback(callback)).TransformCompletionsRequest(schemaRequest())
+		require.NoError(t, err)
+		_, err = tooladapter.New(
+			tooladapter.WithMetricsCallback(callback),
+			tooladapter.WithSchemaFormat(tooladapter.SchemaPretty),
+		).TransformCompletionsRequest(schemaRequest())
+		require.NoError(t, err)
+
+		require.Len(t, data, 2)
+		assert.Equal(t, len(compact), data[0].SchemaBytes)
+		assert.Positive(t, data[0].SchemaBytesSaved, "raw whitespace and HTML escapes are removed")
+		assert.Greater(t, data[1].SchemaBytes, data[0].SchemaBytes)
+		assert.Equal(t, data[0].SchemaBytes+data[0].SchemaBytesSaved, data[1].SchemaBytes+data[1].SchemaBytesSaved,
+			"savings are measured against the same supplied schemas")
+	})
+
+	t.Run("StableAcrossFormatting", func(t *testing.T) {
+		reordered := tooltest.Request(openai.ChatCompletionFunctionTool(openai.FunctionDefinitionParam{
+			Name: "compare",
+			Parameters: openai.FunctionParameters{
+				"properties": json.RawMessage(`{"a":{"type":"integer"},"b":{"type":"string","description":"Value > 0 & < 10"}}`),
+				"type":       "object",
+			},
+		}))
+		first, err := tooladapter.New().TransformCompletionsRequest(schemaRequest())
+		require.NoError(t, err)
+		second, err := tooladapter.New().TransformCompletionsRequest(reordered)
+		require.NoError(t, err)
+		assert.Equal(t, injectedText(t, first), injectedText(t, second))
+	})
+
+	t.Run("Unknown", func(t *testing.T) {
+		var logBuf bytes.Buffer
+		adapter := tooladapter.New(
+			tooladapter.WithLogger(slog.New(slog.NewTextHandler(&logBuf, nil))),
+			tooladapter.WithSchemaFormat(tooladapter.SchemaFormat(99)),
+		)
+		assert.Contains(t, logBuf.String(), "Unknown schema format")
+
+		transformed, err := adapter.TransformCompletionsRequest(schemaRequest())
+		require.NoError(t, err)
+		assert.Contains(t, injectedText(t, transformed), "Parameters: "+compact)
+	})
+
+	t.Run("Config", func(t *testing.T) {
+		cfg, err := tooladapter.LoadConfig(strings.NewReader(`{"schema_format": "pretty"}`))
+		require.NoError(t, err)
+		assert.Equal(t, tooladapter.SchemaPretty, cfg.SchemaFormat)
+
+		_, err = tooladapter.LoadConfig(strings.NewReader(`{"schema_format": "tiny"}`))
+		require.ErrorIs(t, err, tooladapter.ErrInvalidConfig)
+	})
+}
@@ -0,0 +1,221 @@
+package tooladapter
+
+import (
+	"context"
+	"encoding/json"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/openai/openai-go/v3"
+)
+
+// ToolSelector chooses the tools to inject for a request from the request's
+// messages and tools. It returns the tools to keep, normally a subset of tools in
+// their original order; returning tools unchanged keeps them all. Selectors are
+// called concurrently when the adapter is shared and must not modify their inputs.
+type ToolSelector func(ctx context.Context, messages []openai.ChatCompletionMessageParamUnion, tools []openai.ChatCompletionToolUnionParam) []openai.ChatCompletionToolUnionParam
+
+// KeywordToolSelector returns a ToolSelector that keeps the k tools most relevant to
+// the last user message. Relevance is keyword overlap between the message and each
+// tool's name, description and parameter names and descriptions, with matches on
+// the name counting most. Words match when equal or when one is a prefix of the
+// other of at least four letters, so "booking" matches "book_table".
+//
+// Ties keep the tools' order. When no tool matches the message, such as for small
+// talk, or when k is not smaller than the number of tools, every tool is kept.
+func KeywordToolSelector(k int) ToolSelector {
+	return func(_ context.Context, messages []openai.ChatCompletionMessageParamUnion, tools []openai.ChatCompletionToolUnionParam) []openai.ChatCompletionToolUnionParam {
+		if k <= 0 || k >= len(tools) {
+			return tools
+		}
+		query := keywords(lastUserText(messages))
+		if len(query) == 0 {
+			return tools
+		}
+
+		type scored struct {
+			index int
+			score int
+		}
+		scores := make([]scored, len(tools))
+		matched := false
+		for i, tool := range tools {
+			scores[i] = scored{index: i, score: keywordScore(query, tool)}
+			matched = matched || scores[i].score > 0
+		}
+		if !matched {
+			return tools
+		}
+
+		sort.SliceStable(scores, func(i, j int) bool { return scores[i].score > scores[j].score })
+		keep := make([]int, 0, k)
+		for _, s := range scores[:k] {
+			keep = append(keep, s.index)
+		}
+		sort.Ints(keep)
+
+		selected := make([]openai.ChatCompletionToolUnionParam, len(keep))
+		for i, index := range keep {
+			selected[i] = tools[index]
+		}
+		return selected
+	}
+}
+
+// selectTools applies WithToolSelector to a request's tools. A tool forced by the
+// request's tool choice is always kept.
+func (a *Adapter) selectTools(ctx context.Context, req openai.ChatCompletionNewParams) []openai.ChatCompletionToolUnionParam {
+	if a.toolSelector == nil || len(req.Tools) == 0 {
+		return req.Tools
+	}
+
+	selected := a.toolSelector(ctx, req.Messages, req.Tools)
+
+	if forced := req.ToolChoice.OfFunctionToolChoice; forced != nil {
+		kept := false
+		for _, tool := range selected {
+			if function := tool.GetFunction(); function != nil && function.Name == forced.Function.Name {
+				kept = true
+				break
+			}
+		}
+		if !kept {
+			for _, tool := range req.Tools {
+				if function := tool.GetFunction(); function != nil && function.Name == forced.Function.Name {
+					selected = append(selected, tool)
+					break
+				}
+			}
+		}
+	}
+
+	if len(selected) != len(req.Tools) {
+		names := make([]string, 0, len(selected))
+		for _, tool := range selected {
+			if function := tool.GetFunction(); function != nil {
+				names = append(names, function.Name)
+			}
+		}
+		a.log(LogCategoryRequest).Debug("Tool selector pruned request tools",
+			"original_count", len(req.Tools),
+			"selected_count", len(selected),
+			"selected_names", names)
+	}
+	return selected
+}
+
+// lastUserText returns the text of the last user message.
+func lastUserText(messages []openai.ChatCompletionMessageParamUnion) string {
+	for i := len(messages) - 1; i >= 0; i-- {
+		user := messages[i].OfUser
+		if user == nil {
+			continue
+		}
+		if text := user.Content.OfString.Or(""); text != "" {
+			return text
+		}
+		var sb strings.Builder
+		for _, part := range user.Content.OfArrayOfContentParts {
+			if part.OfText != nil {
+				sb.WriteString(part.OfText.Text)
+				sb.WriteString(" ")
+			}
+		}
+		return sb.String()
+	}
+	return ""
+}
+
+// keywordStopWords are common words that carry no signal about tool relevance.
+var keywordStopWords = map[string]struct{}{
+	"the": {}, "and": {}, "for": {}, "with": {}, "that": {}, "this": {}, "from": {},
+	"what": {}, "when": {}, "where": {}, "which": {}, "who": {}, "how": {}, "can": {},
+	"you": {}, "your": {}, "please": {}, "are": {}, "was": {}, "will": {}, "would": {},
+	"could": {}, "should": {}, "get": {}, "set": {}, "into": {}, "about": {}, "have": {},
+	"has": {}, "not": {}, "but": {}, "all": {}, "any": {}, "some": {}, "tell": {},
+	"want": {}, "need": {}, "like": {}, "there": {}, "their": {}, "them": {}, "then": {},
+	"string": {}, "object": {}, "number": {}, "integer": {}, "boolean": {}, "array": {},
+}
+
+// keywords splits text into lowercase words of at least three characters, splitting
+// identifiers such as "bookTable" and "book_table", and drops stop words.
+func keywords(text string) map[string]struct{} {
+	words := make(map[string]struct{})
+	var current []rune
+	flush := func() {
+		if len(current) >= 3 {
+			word := string(current)
+			if _, stop := keywordStopWords[word]; !stop {
+				words[word] = struct{}{}
+			}
+		}
+		current = current[:0]
+	}
+
+	var prev rune
+	for _, r := range text {
+		switch {
+		case unicode.IsUpper(r) && unicode.IsLower(prev):
+			flush()
+			current = append(current, unicode.ToLower(r))
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			current = append(current, unicode.ToLower(r))
+		default:
+			flush()
+		}
+		prev = r
+	}
+	flush()
+	return words
+}
+
+// keywordScore scores a tool against query words: three points per word matching the
+// name and one per word matching only the description or parameters.
+func keywordScore(query map[string]struct{}, tool openai.ChatCompletionToolUnionParam) int {
+	function := tool.GetFunction()
+	if function == nil {
+		return 0
+	}
+
+	name := keywords(function.Name)
+	var details strings.Builder
+	details.WriteString(function.Description.Or(""))
+	if function.Parameters != nil {
+		// Property names and descriptions; JSON punctuation splits into separate words
+		if params, err := json.Marshal(function.Parameters); err == nil {
+			details.WriteString(" ")
+			details.Write(params)
+		}
+	}
+	description := keywords(details.String())
+
+	score := 0
+	for word := range query {
+		switch {
+		case keywordMatches(word, name):
+			score += 3
+		case keywordMatches(word, description):
+			score++
+		}
+	}
+	return score
+}
+
+// keywordMatches reports whether word equals a word in words or shares a prefix
+// relationship with one of at least four letters.
+func keywordMatches(word string, words map[string]struct{}) bool {
+	if _, ok := words[word]; ok {
+		return true
+	}
+	for candidate := range words {
+		short, long := word, candidate
+		if len(short) > len(long) {
+			short, long = long, short
+		}
+		if len(short) >= 4 && strings.HasPrefix(long, short) {
+			return true
+		}
+	}
+	return false
+}
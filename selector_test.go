@@ -0,0 +1,105 @@
+package tooladapter_test
+
+import (
+	"context"
+	"testing"
+
+	tooladapter "github.com/juburr/openai-tool-adapter/v3"
+	"github.com/openai/openai-go/v3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func selectorTools() []openai.ChatCompletionToolUnionParam {
+	return []openai.ChatCompletionToolUnionParam{
+		createMockTool("send_email", "Send an email message to a recipient"),
+		createMockTool("bookTable", "Reserve a table at a restaurant"),
+		createMockTool("get_weather", "Current weather conditions for a city"),
+		createMockTool("search_flights", "Find flights between two airports"),
+	}
+}
+
+func toolNames(tools []openai.ChatCompletionToolUnionParam) []string {
+	var names []string
+	for _, tool := range tools {
+		names = append(names, tool.GetFunction().Name)
+	}
+	return names
+}
+
+func TestKeywordToolSelector(t *testing.T) {
+	ctx := context.Background()
+	tools := selectorTools()
+
+	tests := []struct {
+		name    string
+		k       int
+		message string
+		want    []string
+	}{
+		{"NameMatch", 1, "Can you book a table for four tonight?", []string{"bookTable"}},
+		{"PrefixMatch", 1, "I need a booking downtown", []string{"bookTable"}},
+		{"KeepsToolOrder", 2, "Any flights to Paris, and what's the weather there?", []string{"get_weather", "search_flights"}},
+		{"DescriptionMatch", 1, "Email my recipient list", []string{"send_email"}},
+		{"NoMatchKeepsAll", 2, "Hello there!", []string{"send_email", "bookTable", "get_weather", "search_flights"}},
+		{"LargeKKeepsAll", 10, "weather", []string{"send_email", "bookTable", "get_weather", "search_flights"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			messages := []openai.ChatCompletionMessageParamUnion{
+				openai.UserMessage("Send an email to Bob"), // only the last user message counts
+				openai.AssistantMessage("Done."),
+				openai.UserMessage(tt.message),
+			}
+			selected := tooladapter.KeywordToolSelector(tt.k)(ctx, messages, tools)
+			assert.Equal(t, tt.want, toolNames(selected))
+		})
+	}
+}
+
+func TestWithToolSelector(t *testing.T) {
+	t.Run("PrunesInjectedTools", func(t *testing.T) {
+		adapter := tooladapter.New(
+			tooladapter.WithSystemMessageSupport(true),
+			tooladapter.WithToolSelector(tooladapter.KeywordToolSelector(1)))
+		req := createMockRequest(selectorTools())
+		req.Messages = []openai.ChatCompletionMessageParamUnion{openai.UserMessage("What's the weather in Oslo?")}
+
+		result, err := adapter.TransformCompletionsRequest(req)
+		require.NoError(t, err)
+		prompt := systemPrompt(t, result)
+		assert.Contains(t, prompt, "- get_weather")
+		assert.NotContains(t, prompt, "send_email")
+		assert.NotContains(t, prompt, "search_flights")
+		assert.Len(t, req.Tools, 4, "the caller's request is not modified")
+	})
+
+	t.Run("KeepsForcedTool", func(t *testing.T) {
+		adapter := tooladapter.New(
+			tooladapter.WithSystemMessageSupport(true),
+			tooladapter.WithToolSelector(tooladapter.KeywordToolSelector(1)))
+		req := createMockRequest(selectorTools())
+		req.Messages = []openai.ChatCompletionMessageParamUnion{openai.UserMessage("What's the weather in Oslo?")}
+		req.ToolChoice = openai.ToolChoiceOptionFunctionToolChoice(openai.ChatCompletionNamedToolChoiceFunctionParam{Name: "send_email"})
+
+		result, err := adapter.TransformCompletionsRequest(req)
+		require.NoError(t, err)
+		prompt := systemPrompt(t, result)
+		assert.Contains(t, prompt, "- get_weather")
+		assert.Contains(t, prompt, "- send_email")
+	})
+
+	t.Run("NoToolsLeft", func(t *testing.T) {
+		adapter := tooladapter.New(tooladapter.WithToolSelector(func(context.Context, []openai.ChatCompletionMessageParamUnion, []openai.ChatCompletionToolUnionParam) []openai.ChatCompletionToolUnionParam {
+			return nil
+		}))
+		req := createMockRequest(selectorTools())
+		req.ToolChoice = openai.ChatCompletionToolChoiceOptionUnionParam{OfAuto: openai.String("auto")}
+
+		result, err := adapter.TransformCompletionsRequest(req)
+		require.NoError(t, err)
+		assert.Empty(t, result.Tools)
+		assert.Equal(t, req.Messages, result.Messages, "no instructions are injected")
+		assert.False(t, result.ToolChoice.OfAuto.Valid())
+	})
+}
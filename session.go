@@ -0,0 +1,376 @@
+package tooladapter
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"sync"
+
+	"github.com/openai/openai-go/v3"
+	"github.com/openai/openai-go/v3/packages/param"
+)
+
+// Session carries the state of one multi-turn conversation, such as an agent loop,
+// so callers do not have to thread it through every transformation themselves. It
+// remembers the tools of the last request for argument coercion and violation
+// checks, counts tool calls against WithToolCallRateLimit, maps tool call IDs to
+// function names for tool results, tells the model when the tools change between
+// turns, and accumulates the tool calls, suppressed content and usage of every
+// turn.
+//
+// Create a Session with Adapter.NewSession and use its Transform methods in place of
+// the adapter's. A Session is safe for concurrent use, although the turns of one
+// conversation are normally sequential.
+type Session struct {
+	adapter      *Adapter
+	conversation *Conversation
+
+	mu            sync.Mutex
+	tools         []openai.ChatCompletionToolUnionParam
+	promptTools   []openai.ChatCompletionToolUnionParam // model-facing tools last offered
+	offeredTools  bool
+	parallelCalls param.Opt[bool]
+	jsonMode      bool
+	toolResults   []string
+	toolCallNames map[string]string
+	toolCalls     []SessionToolCall
+	suppressed    []string
+	usage         SessionUsage
+}
+
+// SessionToolCall is a tool call delivered in a session turn.
+type SessionToolCall struct {
+	// Turn is the 1-based number of the response that produced the call
+	Turn int `json:"turn"`
+
+	// ID is the tool call ID delivered to the caller
+	ID string `json:"id"`
+
+	// Name is the function name
+	Name string `json:"name"`
+
+	// Arguments is the JSON-encoded arguments
+	Arguments string `json:"arguments"`
+}
+
+// SessionUsage accumulates the cost of a session across turns.
+type SessionUsage struct {
+	// Turns is the number of responses transformed
+	Turns int `json:"turns"`
+
+	// PromptTokens, CompletionTokens and TotalTokens sum the usage reported by the
+	// provider; responses and streams without usage add nothing
+	PromptTokens     int64 `json:"prompt_tokens"`
+	CompletionTokens int64 `json:"completion_tokens"`
+	TotalTokens      int64 `json:"total_tokens"`
+
+	// RequestBytes is the JSON-encoded size of the transformed request messages,
+	// including injected tool instructions and results
+	RequestBytes int `json:"request_bytes"`
+
+	// ResponseBytes is the size of the content received from the model
+	ResponseBytes int `json:"response_bytes"`
+
+	// InjectedTokens estimates the prompt tokens added by the adapter's injected
+	// tool instructions and results, which callers counting their own messages
+	// miss. It is 0 without WithTokenCounter.
+	InjectedTokens int64 `json:"injected_tokens"`
+
+	// ToolCallTokens estimates the completion tokens the model spent on output
+	// that was converted to tool calls. It is 0 without WithTokenCounter.
+	ToolCallTokens int64 `json:"tool_call_tokens"`
+}
+
+// sessionKey is the context key under which a Session attaches itself for request
+// transformation.
+type sessionKey struct{}
+
+// NewSession starts a Session that transforms requests and responses with a.
+func (a *Adapter) NewSession() *Session {
+	return &Session{
+		adapter:       a,
+		conversation:  NewConversation(),
+		toolCallNames: make(map[string]string),
+	}
+}
+
+// Conversation returns the Conversation that counts the session's tool calls for
+// WithToolCallRateLimit.
+func (s *Session) Conversation() *Conversation {
+	return s.conversation
+}
+
+// TransformRequest transforms req like Adapter.TransformCompletionsRequestWithContext
+// and remembers its tools and parallel_tool_calls setting for the response. Tool results for calls made earlier in
+// the session are labeled with their function names.
+func (s *Session) TransformRequest(ctx context.Context, req openai.ChatCompletionNewParams) (openai.ChatCompletionNewParams, error) {
+	s.mu.Lock()
+	s.tools = req.Tools
+	s.parallelCalls = req.ParallelToolCalls
+	s.jsonMode = requestsJSONMode(req)
+	s.toolResults = toolMessageContents(req.Messages)
+	s.mu.Unlock()
+
+	transformed, err := s.adapter.TransformCompletionsRequestWithContext(context.WithValue(ctx, sessionKey{}, s), req)
+	if err != nil {
+		return transformed, err
+	}
+
+	if encoded, err := json.Marshal(transformed.Messages); err == nil {
+		s.mu.Lock()
+		s.usage.RequestBytes += len(encoded)
+		s.mu.Unlock()
+	}
+	return transformed, nil
+}
+
+// TransformResponse transforms resp like
+// Adapter.TransformCompletionsResponseWithContext, using the tools of the last
+// TransformRequest and the session's Conversation, and records the turn. Usage is
+// recorded even when transformation fails, since the tokens were spent.
+func (s *Session) TransformResponse(ctx context.Context, resp openai.ChatCompletion) (openai.ChatCompletion, error) {
+	transformed, err := s.adapter.TransformCompletionsResponseWithContext(s.responseContext(ctx), resp)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.usage.Turns++
+	s.addUsage(resp.Usage)
+	for _, choice := range resp.Choices {
+		s.usage.ResponseBytes += len(choice.Message.Content)
+	}
+	if err != nil {
+		return transformed, err
+	}
+
+	for i, choice := range transformed.Choices {
+		if len(choice.Message.ToolCalls) == 0 {
+			continue
+		}
+		for _, toolCall := range choice.Message.ToolCalls {
+			s.recordToolCall(toolCall.ID, toolCall.Function.Name, toolCall.Function.Arguments)
+		}
+		original := resp.Choices[i].Message.Content
+		s.addToolCallTokens(original, choice.Message.Content)
+		if original != choice.Message.Content {
+			s.suppressed = append(s.suppressed, original)
+		}
+	}
+	return transformed, nil
+}
+
+// TransformStreamingResponse transforms stream like
+// Adapter.TransformStreamingResponseWithContext and records the turn when the
+// stream ends.
+func (s *Session) TransformStreamingResponse(ctx context.Context, stream ChatCompletionStreamInterface) ChatCompletionStreamInterface {
+	observer := &sessionUpstream{ChatCompletionStreamInterface: stream}
+	return &sessionStream{
+		StreamAdapter: s.adapter.TransformStreamingResponseWithContext(s.responseContext(ctx), observer),
+		session:       s,
+		upstream:      observer,
+	}
+}
+
+// ToolCalls returns the tool calls delivered so far, in order.
+func (s *Session) ToolCalls() []SessionToolCall {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]SessionToolCall(nil), s.toolCalls...)
+}
+
+// ToolCallName returns the function name of a tool call delivered in the session.
+func (s *Session) ToolCallName(id string) (string, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	name, ok := s.toolCallNames[id]
+	return name, ok
+}
+
+// SuppressedContent returns the model output that was replaced by tool calls in
+// earlier turns, such as a preface the tool policy removed, one entry per turn.
+func (s *Session) SuppressedContent() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string(nil), s.suppressed...)
+}
+
+// Usage returns the accumulated usage of the session.
+func (s *Session) Usage() SessionUsage {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.usage
+}
+
+// responseContext attaches the last request's tools, parallel_tool_calls and
+// response_format settings and tool results, and the session's Conversation.
+func (s *Session) responseContext(ctx context.Context) context.Context {
+	s.mu.Lock()
+	tools, parallelCalls, jsonMode, toolResults := s.tools, s.parallelCalls, s.jsonMode, s.toolResults
+	s.mu.Unlock()
+	ctx = ContextWithTools(ctx, tools)
+	if parallelCalls.Valid() {
+		ctx = ContextWithParallelToolCalls(ctx, parallelCalls.Value)
+	}
+	if jsonMode {
+		ctx = ContextWithJSONMode(ctx, true)
+	}
+	if len(toolResults) > 0 {
+		ctx = ContextWithToolResults(ctx, toolResults...)
+	}
+	return ContextWithConversation(ctx, s.conversation)
+}
+
+// recordToolCall must be called with s.mu held.
+func (s *Session) recordToolCall(id, name, arguments string) {
+	s.toolCalls = append(s.toolCalls, SessionToolCall{
+		Turn:      s.usage.Turns,
+		ID:        id,
+		Name:      name,
+		Arguments: arguments,
+	})
+	if id != "" {
+		s.toolCallNames[id] = name
+	}
+}
+
+// addUsage must be called with s.mu held.
+func (s *Session) addUsage(usage openai.CompletionUsage) {
+	s.usage.PromptTokens += usage.PromptTokens
+	s.usage.CompletionTokens += usage.CompletionTokens
+	s.usage.TotalTokens += usage.TotalTokens
+}
+
+// addToolCallTokens counts the tokens of received model output that did not reach
+// the caller as content. It must be called with s.mu held.
+func (s *Session) addToolCallTokens(received, delivered string) {
+	if tokens := s.adapter.countTokens(received) - s.adapter.countTokens(delivered); tokens > 0 {
+		s.usage.ToolCallTokens += int64(tokens)
+	}
+}
+
+// sessionToolCallName returns the function name recorded for a tool call ID by the
+// Session attached to ctx, if any.
+func sessionToolCallName(ctx context.Context, id string) string {
+	if ctx == nil || id == "" {
+		return ""
+	}
+	s, _ := ctx.Value(sessionKey{}).(*Session)
+	if s == nil {
+		return ""
+	}
+	name, _ := s.ToolCallName(id)
+	return name
+}
+
+// recordInjectedTokens adds to the injected token count of the Session attached
+// to ctx, if any.
+func recordInjectedTokens(ctx context.Context, tokens int) {
+	if tokens == 0 {
+		return
+	}
+	s, _ := ctx.Value(sessionKey{}).(*Session)
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	s.usage.InjectedTokens += int64(tokens)
+	s.mu.Unlock()
+}
+
+// sessionToolsDelta records tools, the model-facing tools of a request, as the
+// tools offered by the Session attached to ctx, if any, and returns how they
+// differ from those of the last turn that offered tools. It reports false without
+// a Session or an earlier turn with tools.
+func sessionToolsDelta(ctx context.Context, tools []openai.ChatCompletionToolUnionParam) (toolsDelta, bool) {
+	s, _ := ctx.Value(sessionKey{}).(*Session)
+	if s == nil {
+		return toolsDelta{}, false
+	}
+	s.mu.Lock()
+	previous, offered := s.promptTools, s.offeredTools
+	s.promptTools, s.offeredTools = tools, true
+	s.mu.Unlock()
+	if !offered {
+		return toolsDelta{}, false
+	}
+	return diffTools(previous, tools), true
+}
+
+// sessionUpstream observes the upstream chunks of a session stream.
+type sessionUpstream struct {
+	ChatCompletionStreamInterface
+	content strings.Builder
+	usage   openai.CompletionUsage
+}
+
+func (u *sessionUpstream) Next() bool {
+	if !u.ChatCompletionStreamInterface.Next() {
+		return false
+	}
+	chunk := u.ChatCompletionStreamInterface.Current()
+	for _, choice := range chunk.Choices {
+		u.content.WriteString(choice.Delta.Content)
+	}
+	if chunk.Usage.TotalTokens > 0 {
+		u.usage = chunk.Usage
+	}
+	return true
+}
+
+// sessionStream records the tool calls emitted by a session stream and completes
+// the turn when the stream ends.
+type sessionStream struct {
+	*StreamAdapter
+	session  *Session
+	upstream *sessionUpstream
+
+	toolCalls []openai.ChatCompletionChunkChoiceDeltaToolCall
+	content   strings.Builder
+	finished  bool
+}
+
+func (s *sessionStream) Next() bool {
+	if s.StreamAdapter.Next() {
+		for _, choice := range s.StreamAdapter.Current().Choices {
+			s.toolCalls = append(s.toolCalls, choice.Delta.ToolCalls...)
+			s.content.WriteString(choice.Delta.Content)
+		}
+		return true
+	}
+	s.finish()
+	return false
+}
+
+// finish records the turn once.
+func (s *sessionStream) finish() {
+	if s.finished {
+		return
+	}
+	s.finished = true
+
+	session := s.session
+	session.mu.Lock()
+	defer session.mu.Unlock()
+	session.usage.Turns++
+	session.addUsage(s.upstream.usage)
+	received := s.upstream.content.String()
+	session.usage.ResponseBytes += len(received)
+	if s.StreamAdapter.Err() != nil {
+		return
+	}
+	for _, toolCall := range s.toolCalls {
+		session.recordToolCall(toolCall.ID, toolCall.Function.Name, toolCall.Function.Arguments)
+	}
+	if len(s.toolCalls) > 0 {
+		session.addToolCallTokens(received, s.content.String())
+	}
+	if len(s.toolCalls) > 0 && received != s.content.String() {
+		session.suppressed = append(session.suppressed, received)
+	}
+}
+
+// Close records the turn, if the stream was not read to the end, and closes the
+// stream.
+func (s *sessionStream) Close() error {
+	s.finish()
+	return s.StreamAdapter.Close()
+}
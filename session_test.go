@@ -0,0 +1,159 @@
+package tooladapter_test
+
+import (
+	"context"
+	"testing"
+
+	tooladapter "github.com/juburr/openai-tool-adapter/v3"
+	"github.com/juburr/openai-tool-adapter/v3/tooltest"
+	"github.com/openai/openai-go/v3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSession(t *testing.T) {
+	ctx := context.Background()
+	tools := []openai.ChatCompletionToolUnionParam{bookingTool()}
+	adapter := tooladapter.New(
+		tooladapter.WithSystemMessageSupport(true),
+		tooladapter.WithArgumentCoercion(true),
+		tooladapter.WithToolCallRateLimit(0, 2))
+	session := adapter.NewSession()
+
+	// Turn 1: the model prefaces a tool call with text
+	req := createMockRequest(tools)
+	_, err := session.TransformRequest(ctx, req)
+	require.NoError(t, err)
+	assert.Positive(t, session.Usage().RequestBytes)
+
+	completion := createMockCompletion(`Let me book that. {"name": "book_table", "parameters": {"guests": "4"}}`)
+	completion.Usage = openai.CompletionUsage{PromptTokens: 100, CompletionTokens: 20, TotalTokens: 120}
+	resp, err := session.TransformResponse(ctx, completion)
+	require.NoError(t, err)
+	require.Len(t, resp.Choices[0].Message.ToolCalls, 1)
+	toolCall := resp.Choices[0].Message.ToolCalls[0]
+	assert.JSONEq(t, `{"guests": 4, "time": "19:00"}`, toolCall.Function.Arguments,
+		"the session supplies the request's tools for coercion")
+
+	calls := session.ToolCalls()
+	require.Len(t, calls, 1)
+	assert.Equal(t, tooladapter.SessionToolCall{Turn: 1, ID: toolCall.ID, Name: "book_table", Arguments: toolCall.Function.Arguments}, calls[0])
+	assert.Equal(t, []string{completion.Choices[0].Message.Content}, session.SuppressedContent())
+	name, ok := session.ToolCallName(toolCall.ID)
+	assert.True(t, ok)
+	assert.Equal(t, "book_table", name)
+
+	// Turn 2: the tool result is labeled with the function name
+	req.Messages = append(req.Messages,
+		openai.AssistantMessage(""),
+		openai.ToolMessage(`{"confirmed": true}`, toolCall.ID))
+	transformed, err := session.TransformRequest(ctx, req)
+	require.NoError(t, err)
+	assert.Contains(t, systemPrompt(t, transformed), "Tool call "+toolCall.ID+" (book_table) result:")
+
+	stream := session.TransformStreamingResponse(ctx, NewMockStream([]openai.ChatCompletionChunk{
+		createStreamChunk(`{"name": "book_table", "parameters": {"guests": 2}}`),
+		createFinishChunk("stop"),
+	}))
+	var streamed int
+	for stream.Next() {
+		streamed += len(stream.Current().Choices[0].Delta.ToolCalls)
+	}
+	require.NoError(t, stream.Err())
+	require.NoError(t, stream.Close())
+	assert.Equal(t, 1, streamed)
+
+	// Turn 3: the conversation limit of two calls is reached
+	resp, err = session.TransformResponse(ctx, createMockCompletion(`{"name": "book_table", "parameters": {"guests": 3}}`))
+	require.NoError(t, err)
+	assert.Empty(t, resp.Choices[0].Message.ToolCalls)
+
+	calls = session.ToolCalls()
+	require.Len(t, calls, 2)
+	assert.Equal(t, 2, calls[1].Turn)
+	assert.Equal(t, 2, session.Conversation().ToolCalls())
+	assert.Equal(t, 1, session.Conversation().Suppressed())
+
+	usage := session.Usage()
+	assert.Equal(t, 3, usage.Turns)
+	assert.Equal(t, int64(120), usage.TotalTokens)
+	assert.Equal(t, int64(100), usage.PromptTokens)
+	assert.Equal(t, len(completion.Choices[0].Message.Content)+
+		len(`{"name": "book_table", "parameters": {"guests": 2}}`)+
+		len(`{"name": "book_table", "parameters": {"guests": 3}}`), usage.ResponseBytes)
+}
+
+func TestSession_RecordsUsageOnError(t *testing.T) {
+	adapter := tooladapter.New(tooladapter.WithArgumentViolationPolicy(tooladapter.ArgumentViolationError))
+	session := adapter.NewSession()
+	ctx := context.Background()
+
+	_, err := session.TransformRequest(ctx, createMockRequest([]openai.ChatCompletionToolUnionParam{constrainedWeatherTool()}))
+	require.NoError(t, err)
+
+	completion := createMockCompletion(`{"name": "get_weather", "parameters": {"city": "Paris", "unit": "kelvin"}}`)
+	completion.Usage = openai.CompletionUsage{TotalTokens: 50}
+	_, err = session.TransformResponse(ctx, completion)
+	require.ErrorIs(t, err, tooladapter.ErrArgumentViolation)
+
+	assert.Equal(t, int64(50), session.Usage().TotalTokens)
+	assert.Empty(t, session.ToolCalls())
+}
+
+func TestSession_ToolsUpdated(t *testing.T) {
+	ctx := context.Background()
+	adapter := tooladapter.New(tooladapter.WithSystemMessageSupport(true))
+	session := adapter.NewSession()
+	prompt := func(tools ...openai.ChatCompletionToolUnionParam) string {
+		transformed, err := session.TransformRequest(ctx, createMockRequest(tools))
+		require.NoError(t, err)
+		return systemPrompt(t, transformed)
+	}
+	renamed := tooltest.Tool("search_docs", "Search the documentation")
+	renamed.OfFunction.Function.Name = "find_docs"
+
+	first := prompt(tooltest.Tool("get_weather", "Get the weather"), tooltest.Tool("search_docs", "Search the documentation"))
+	assert.NotContains(t, first, "have changed", "the first turn has nothing to compare with")
+
+	unchanged := prompt(tooltest.Tool("get_weather", "Get the weather"), tooltest.Tool("search_docs", "Search the documentation"))
+	assert.NotContains(t, unchanged, "have changed")
+
+	t.Run("Added", func(t *testing.T) {
+		updated := prompt(tooltest.Tool("get_weather", "Get the weather"), tooltest.Tool("search_docs", "Search the documentation"),
+			tooltest.Tool("get_time", "Get the time"))
+		assert.Contains(t, updated, "The available functions have changed")
+		assert.Contains(t, updated, "- Added: get_time")
+		assert.NotContains(t, updated, "Removed")
+	})
+
+	t.Run("Removed", func(t *testing.T) {
+		updated := prompt(tooltest.Tool("get_weather", "Get the weather"), tooltest.Tool("search_docs", "Search the documentation"))
+		assert.Contains(t, updated, "- Removed, do not call: get_time")
+		assert.NotContains(t, updated, "Added")
+	})
+
+	t.Run("Renamed", func(t *testing.T) {
+		updated := prompt(tooltest.Tool("get_weather", "Get the weather"), renamed)
+		assert.Contains(t, updated, "- Renamed: search_docs → find_docs")
+		assert.NotContains(t, updated, "Added")
+		assert.NotContains(t, updated, "Removed")
+	})
+
+	t.Run("Changed", func(t *testing.T) {
+		updated := prompt(tooltest.Tool("get_weather", "Get the current weather"), renamed)
+		assert.Contains(t, updated, "- Changed definitions (use the current ones): get_weather")
+	})
+
+	t.Run("TurnWithoutTools", func(t *testing.T) {
+		_, err := session.TransformRequest(ctx, createMockRequest(nil))
+		require.NoError(t, err)
+		updated := prompt(tooltest.Tool("get_weather", "Get the current weather"))
+		assert.Contains(t, updated, "- Removed, do not call: find_docs", "compares with the last turn that offered tools")
+	})
+
+	t.Run("WithoutSession", func(t *testing.T) {
+		transformed, err := adapter.TransformCompletionsRequest(createMockRequest([]openai.ChatCompletionToolUnionParam{tooltest.Tool("get_time", "Get the time")}))
+		require.NoError(t, err)
+		assert.NotContains(t, systemPrompt(t, transformed), "have changed")
+	})
+}
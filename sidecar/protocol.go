@@ -0,0 +1,131 @@
+package sidecar
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+// Connect protocol constants for the JSON codec.
+const (
+	unaryContentType  = "application/json"
+	streamContentType = "application/connect+json"
+
+	// Envelope flags of streaming messages
+	flagCompressed = 0x01
+	flagEndStream  = 0x02
+
+	envelopeHeaderSize = 5
+)
+
+// Code is a Connect error code.
+type Code string
+
+// Error codes returned by the sidecar.
+const (
+	CodeCanceled           Code = "canceled"
+	CodeInvalidArgument    Code = "invalid_argument"
+	CodeDeadlineExceeded   Code = "deadline_exceeded"
+	CodeFailedPrecondition Code = "failed_precondition"
+	CodeResourceExhausted  Code = "resource_exhausted"
+	CodeUnimplemented      Code = "unimplemented"
+	CodeInternal           Code = "internal"
+	CodeUnavailable        Code = "unavailable"
+)
+
+// httpStatus maps a code to the HTTP status of a unary error response.
+func (c Code) httpStatus() int {
+	switch c {
+	case CodeCanceled:
+		return 499
+	case CodeInvalidArgument, CodeFailedPrecondition:
+		return http.StatusBadRequest
+	case CodeDeadlineExceeded:
+		return http.StatusGatewayTimeout
+	case CodeResourceExhausted:
+		return http.StatusTooManyRequests
+	case CodeUnimplemented:
+		return http.StatusNotImplemented
+	case CodeUnavailable:
+		return http.StatusServiceUnavailable
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// Error is a Connect error as sent to clients.
+type Error struct {
+	Code    Code   `json:"code"`
+	Message string `json:"message,omitempty"`
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("%s: %s", e.Code, e.Message)
+}
+
+// newError returns an *Error with a formatted message.
+func newError(code Code, format string, args ...any) *Error {
+	return &Error{Code: code, Message: fmt.Sprintf(format, args...)}
+}
+
+// writeUnaryError writes err as a unary Connect error response.
+func writeUnaryError(w http.ResponseWriter, err *Error) {
+	w.Header().Set("Content-Type", unaryContentType)
+	w.WriteHeader(err.Code.httpStatus())
+	_ = json.NewEncoder(w).Encode(err)
+}
+
+// readEnvelope reads one enveloped streaming message of at most limit bytes.
+func readEnvelope(r io.Reader, limit int) (flags byte, payload []byte, err error) {
+	var header [envelopeHeaderSize]byte
+	if _, err := io.ReadFull(r, header[:]); err != nil {
+		return 0, nil, err
+	}
+	size := binary.BigEndian.Uint32(header[1:])
+	if int64(size) > int64(limit) {
+		return 0, nil, newError(CodeResourceExhausted, "message of %d bytes exceeds the %d byte limit", size, limit)
+	}
+	payload = make([]byte, size)
+	if _, err := io.ReadFull(r, payload); err != nil {
+		return 0, nil, err
+	}
+	return header[0], payload, nil
+}
+
+// writeEnvelope writes one enveloped streaming message.
+func writeEnvelope(w io.Writer, flags byte, payload []byte) error {
+	var header [envelopeHeaderSize]byte
+	header[0] = flags
+	binary.BigEndian.PutUint32(header[1:], uint32(len(payload)))
+	if _, err := w.Write(header[:]); err != nil {
+		return err
+	}
+	_, err := w.Write(payload)
+	return err
+}
+
+// endStreamMessage is the payload of the final envelope of a stream.
+type endStreamMessage struct {
+	Error *Error `json:"error,omitempty"`
+}
+
+// writeEndStream writes the end-of-stream envelope, carrying err if not nil.
+func writeEndStream(w io.Writer, err *Error) error {
+	payload, marshalErr := json.Marshal(endStreamMessage{Error: err})
+	if marshalErr != nil {
+		return marshalErr
+	}
+	return writeEnvelope(w, flagEndStream, payload)
+}
+
+// asError converts err to an *Error, keeping an existing one.
+func asError(err error, fallback Code) *Error {
+	var connectErr *Error
+	if errors.As(err, &connectErr) {
+		return connectErr
+	}
+	return &Error{Code: fallback, Message: err.Error()}
+}
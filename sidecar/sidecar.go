@@ -0,0 +1,306 @@
+// Package sidecar exposes a tooladapter.Adapter as a network service, so services
+// written in other languages can use the adapter as a sidecar instead of
+// re-implementing its prompt injection and tool call parsing.
+//
+// The service speaks the Connect protocol (https://connectrpc.com) with the JSON
+// codec, using only the standard library. Its schema is in adapter.proto; Connect
+// clients generated from it work when configured for JSON, and unary procedures can
+// also be called with any HTTP client:
+//
+//	curl -X POST http://localhost:8080/tooladapter.v1.AdapterService/TransformRequest \
+//		-H 'Content-Type: application/json' \
+//		-d '{"request": {"model": "gemma-3", "messages": [...], "tools": [...]}}'
+//
+// The binary protobuf codec and the gRPC protocol are not served.
+package sidecar
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	tooladapter "github.com/juburr/openai-tool-adapter/v3"
+	"github.com/openai/openai-go/v3"
+)
+
+// ServicePath is the path prefix of the service's procedures, for mounting the
+// Handler on a mux:
+//
+//	mux.Handle(sidecar.ServicePath, sidecar.NewHandler(adapter, upstream))
+const ServicePath = "/tooladapter.v1.AdapterService/"
+
+// DefaultMaxMessageBytes is the default size limit of a request message.
+const DefaultMaxMessageBytes = 16 << 20
+
+// StreamFunc opens a streaming chat completion against the upstream model for
+// StreamCompletion. With the OpenAI SDK:
+//
+//	func(ctx context.Context, params openai.ChatCompletionNewParams) tooladapter.ChatCompletionStreamInterface {
+//		return client.Chat.Completions.NewStreaming(ctx, params)
+//	}
+type StreamFunc func(ctx context.Context, params openai.ChatCompletionNewParams) tooladapter.ChatCompletionStreamInterface
+
+// Handler serves the AdapterService procedures. It is safe for concurrent use.
+type Handler struct {
+	adapter         *tooladapter.Adapter
+	upstream        StreamFunc
+	maxMessageBytes int
+}
+
+// NewHandler returns a Handler that transforms with adapter. upstream serves
+// StreamCompletion; when nil, StreamCompletion fails with CodeUnimplemented.
+func NewHandler(adapter *tooladapter.Adapter, upstream StreamFunc) *Handler {
+	return &Handler{
+		adapter:         adapter,
+		upstream:        upstream,
+		maxMessageBytes: DefaultMaxMessageBytes,
+	}
+}
+
+// WithMaxMessageBytes returns a copy of h that rejects request messages larger than
+// limit bytes with CodeResourceExhausted. Values below 1 keep the current limit.
+func (h *Handler) WithMaxMessageBytes(limit int) *Handler {
+	copied := *h
+	if limit > 0 {
+		copied.maxMessageBytes = limit
+	}
+	return &copied
+}
+
+// Messages of the AdapterService procedures, in their JSON form.
+type (
+	transformRequestMessage struct {
+		Request json.RawMessage `json:"request"`
+	}
+	transformResponseMessage struct {
+		Response json.RawMessage   `json:"response"`
+		Tools    []json.RawMessage `json:"tools,omitempty"`
+	}
+	streamCompletionResponse struct {
+		Chunk openai.ChatCompletionChunk `json:"chunk"`
+	}
+)
+
+// ServeHTTP routes a request to its procedure.
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	procedure := strings.TrimPrefix(r.URL.Path, ServicePath)
+	if procedure == r.URL.Path {
+		http.NotFound(w, r)
+		return
+	}
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	ctx, cancel := requestContext(r)
+	defer cancel()
+
+	switch procedure {
+	case "TransformRequest":
+		h.serveUnary(ctx, w, r, h.transformRequest)
+	case "TransformResponse":
+		h.serveUnary(ctx, w, r, h.transformResponse)
+	case "StreamCompletion":
+		h.streamCompletion(ctx, w, r)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+// requestContext applies the client's Connect-Timeout-Ms header to the request context.
+func requestContext(r *http.Request) (context.Context, context.CancelFunc) {
+	if timeout, err := strconv.ParseInt(r.Header.Get("Connect-Timeout-Ms"), 10, 64); err == nil && timeout > 0 {
+		return context.WithTimeout(r.Context(), time.Duration(timeout)*time.Millisecond)
+	}
+	return context.WithCancel(r.Context())
+}
+
+// hasContentType reports whether the request's media type is want.
+func hasContentType(r *http.Request, want string) bool {
+	mediaType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	return err == nil && mediaType == want
+}
+
+// serveUnary reads a unary request message, calls procedure and writes its result.
+func (h *Handler) serveUnary(ctx context.Context, w http.ResponseWriter, r *http.Request, procedure func(context.Context, []byte) (any, error)) {
+	if !hasContentType(r, unaryContentType) {
+		w.Header().Set("Accept-Post", unaryContentType)
+		http.Error(w, "unsupported content type, use "+unaryContentType, http.StatusUnsupportedMediaType)
+		return
+	}
+
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, int64(h.maxMessageBytes)))
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			writeUnaryError(w, newError(CodeResourceExhausted, "message exceeds the %d byte limit", h.maxMessageBytes))
+			return
+		}
+		writeUnaryError(w, newError(CodeInvalidArgument, "failed to read message: %v", err))
+		return
+	}
+
+	result, err := procedure(ctx, body)
+	if err != nil {
+		writeUnaryError(w, asError(err, errorCode(err)))
+		return
+	}
+
+	w.Header().Set("Content-Type", unaryContentType)
+	_ = json.NewEncoder(w).Encode(result)
+}
+
+// transformRequest implements TransformRequest.
+func (h *Handler) transformRequest(ctx context.Context, body []byte) (any, error) {
+	params, err := decodeRequest(body)
+	if err != nil {
+		return nil, err
+	}
+	transformed, err := h.adapter.TransformCompletionsRequestWithContext(ctx, params)
+	if err != nil {
+		return nil, err
+	}
+	return map[string]any{"request": transformed}, nil
+}
+
+// transformResponse implements TransformResponse.
+func (h *Handler) transformResponse(ctx context.Context, body []byte) (any, error) {
+	var msg transformResponseMessage
+	if err := json.Unmarshal(body, &msg); err != nil {
+		return nil, newError(CodeInvalidArgument, "invalid message: %v", err)
+	}
+	if len(msg.Response) == 0 {
+		return nil, newError(CodeInvalidArgument, "response is required")
+	}
+
+	var completion openai.ChatCompletion
+	if err := json.Unmarshal(msg.Response, &completion); err != nil {
+		return nil, newError(CodeInvalidArgument, "invalid response: %v", err)
+	}
+	if len(msg.Tools) > 0 {
+		tools := make([]openai.ChatCompletionToolUnionParam, len(msg.Tools))
+		for i, raw := range msg.Tools {
+			if err := json.Unmarshal(raw, &tools[i]); err != nil {
+				return nil, newError(CodeInvalidArgument, "invalid tool %d: %v", i, err)
+			}
+		}
+		ctx = tooladapter.ContextWithTools(ctx, tools)
+	}
+
+	transformed, err := h.adapter.TransformCompletionsResponseWithContext(ctx, completion)
+	if err != nil {
+		return nil, err
+	}
+	return map[string]any{"response": transformed}, nil
+}
+
+// streamCompletion implements StreamCompletion. Errors after the request message is
+// read are reported in the end-of-stream message, as the protocol requires.
+func (h *Handler) streamCompletion(ctx context.Context, w http.ResponseWriter, r *http.Request) {
+	if !hasContentType(r, streamContentType) {
+		w.Header().Set("Accept-Post", streamContentType)
+		http.Error(w, "unsupported content type, use "+streamContentType, http.StatusUnsupportedMediaType)
+		return
+	}
+
+	w.Header().Set("Content-Type", streamContentType)
+	w.WriteHeader(http.StatusOK)
+	flusher, _ := w.(http.Flusher)
+	endStream := func(err *Error) {
+		_ = writeEndStream(w, err)
+		if flusher != nil {
+			flusher.Flush()
+		}
+	}
+
+	flags, body, err := readEnvelope(r.Body, h.maxMessageBytes)
+	if err != nil {
+		endStream(asError(err, CodeInvalidArgument))
+		return
+	}
+	if flags&flagCompressed != 0 {
+		endStream(newError(CodeInternal, "compressed messages are not supported"))
+		return
+	}
+	if h.upstream == nil {
+		endStream(newError(CodeUnimplemented, "the sidecar has no upstream for StreamCompletion"))
+		return
+	}
+
+	params, err := decodeRequest(body)
+	if err != nil {
+		endStream(asError(err, CodeInvalidArgument))
+		return
+	}
+	transformed, err := h.adapter.TransformCompletionsRequestWithContext(ctx, params)
+	if err != nil {
+		endStream(asError(err, errorCode(err)))
+		return
+	}
+
+	stream := h.adapter.TransformStreamingResponseWithContext(ctx, h.upstream(ctx, transformed))
+	defer stream.Close()
+	for stream.Next() {
+		payload, err := json.Marshal(streamCompletionResponse{Chunk: stream.Current()})
+		if err != nil {
+			endStream(newError(CodeInternal, "failed to encode chunk: %v", err))
+			return
+		}
+		if err := writeEnvelope(w, 0, payload); err != nil {
+			return // the client is gone
+		}
+		if flusher != nil {
+			flusher.Flush()
+		}
+	}
+
+	if err := stream.Err(); err != nil {
+		code := errorCode(err)
+		if code == CodeInvalidArgument {
+			code = CodeUnavailable // failures past the request are upstream failures
+		}
+		endStream(asError(err, code))
+		return
+	}
+	endStream(nil)
+}
+
+// decodeRequest decodes the request field of a TransformRequest or StreamCompletion
+// message.
+func decodeRequest(body []byte) (openai.ChatCompletionNewParams, error) {
+	var msg transformRequestMessage
+	if err := json.Unmarshal(body, &msg); err != nil {
+		return openai.ChatCompletionNewParams{}, newError(CodeInvalidArgument, "invalid message: %v", err)
+	}
+	if len(msg.Request) == 0 {
+		return openai.ChatCompletionNewParams{}, newError(CodeInvalidArgument, "request is required")
+	}
+
+	var params openai.ChatCompletionNewParams
+	if err := json.Unmarshal(msg.Request, &params); err != nil {
+		return openai.ChatCompletionNewParams{}, newError(CodeInvalidArgument, "invalid request: %v", err)
+	}
+	return params, nil
+}
+
+// errorCode chooses the Connect code for an adapter error.
+func errorCode(err error) Code {
+	switch {
+	case errors.Is(err, context.Canceled):
+		return CodeCanceled
+	case errors.Is(err, context.DeadlineExceeded):
+		return CodeDeadlineExceeded
+	case errors.Is(err, tooladapter.ErrUnknownTool), errors.Is(err, tooladapter.ErrArgumentViolation):
+		return CodeFailedPrecondition
+	default:
+		return CodeInvalidArgument
+	}
+}
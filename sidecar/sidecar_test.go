@@ -0,0 +1,248 @@
+package sidecar
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	tooladapter "github.com/juburr/openai-tool-adapter/v3"
+	"github.com/juburr/openai-tool-adapter/v3/tooltest"
+	"github.com/openai/openai-go/v3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// post calls a unary procedure with a JSON message.
+func post(t *testing.T, server *httptest.Server, procedure string, msg any) *http.Response {
+	t.Helper()
+	body, err := json.Marshal(msg)
+	require.NoError(t, err)
+	resp, err := http.Post(server.URL+ServicePath+procedure, unaryContentType, bytes.NewReader(body))
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = resp.Body.Close() })
+	return resp
+}
+
+// streamMessages calls StreamCompletion and returns the chunks and the end-of-stream
+// message.
+func streamMessages(t *testing.T, server *httptest.Server, msg any) ([]openai.ChatCompletionChunk, endStreamMessage) {
+	t.Helper()
+	payload, err := json.Marshal(msg)
+	require.NoError(t, err)
+	var body bytes.Buffer
+	require.NoError(t, writeEnvelope(&body, 0, payload))
+
+	resp, err := http.Post(server.URL+ServicePath+"StreamCompletion", streamContentType, &body)
+	require.NoError(t, err)
+	defer func() { _ = resp.Body.Close() }()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, streamContentType, resp.Header.Get("Content-Type"))
+
+	var chunks []openai.ChatCompletionChunk
+	for {
+		flags, data, err := readEnvelope(resp.Body, DefaultMaxMessageBytes)
+		require.NoError(t, err)
+		if flags&flagEndStream != 0 {
+			var end endStreamMessage
+			require.NoError(t, json.Unmarshal(data, &end))
+			_, _, err = readEnvelope(resp.Body, DefaultMaxMessageBytes)
+			require.ErrorIs(t, err, io.EOF, "no messages after the end of the stream")
+			return chunks, end
+		}
+		var msg struct {
+			Chunk openai.ChatCompletionChunk `json:"chunk"`
+		}
+		require.NoError(t, json.Unmarshal(data, &msg))
+		chunks = append(chunks, msg.Chunk)
+	}
+}
+
+// decodeError reads a unary error response.
+func decodeError(t *testing.T, resp *http.Response) Error {
+	t.Helper()
+	var connectErr Error
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&connectErr))
+	return connectErr
+}
+
+func TestTransformRequest(t *testing.T) {
+	server := httptest.NewServer(NewHandler(tooladapter.New(), nil))
+	defer server.Close()
+
+	t.Run("InjectsTools", func(t *testing.T) {
+		resp := post(t, server, "TransformRequest", map[string]any{
+			"request": tooltest.Request(tooltest.Tool("get_weather", "Get the weather")),
+		})
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Equal(t, unaryContentType, resp.Header.Get("Content-Type"))
+
+		var msg struct {
+			Request openai.ChatCompletionNewParams `json:"request"`
+		}
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&msg))
+		assert.Empty(t, msg.Request.Tools)
+		require.NotEmpty(t, msg.Request.Messages)
+		raw, err := json.Marshal(msg.Request.Messages)
+		require.NoError(t, err)
+		assert.Contains(t, string(raw), "get_weather")
+	})
+
+	t.Run("MissingRequest", func(t *testing.T) {
+		resp := post(t, server, "TransformRequest", map[string]any{})
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+		assert.Equal(t, Error{Code: CodeInvalidArgument, Message: "request is required"}, decodeError(t, resp))
+	})
+
+	t.Run("MalformedMessage", func(t *testing.T) {
+		resp, err := http.Post(server.URL+ServicePath+"TransformRequest", unaryContentType, strings.NewReader("{"))
+		require.NoError(t, err)
+		defer func() { _ = resp.Body.Close() }()
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+		assert.Equal(t, CodeInvalidArgument, decodeError(t, resp).Code)
+	})
+
+	t.Run("MessageTooLarge", func(t *testing.T) {
+		small := httptest.NewServer(NewHandler(tooladapter.New(), nil).WithMaxMessageBytes(16))
+		defer small.Close()
+		resp := post(t, small, "TransformRequest", map[string]any{"request": tooltest.Request()})
+		assert.Equal(t, http.StatusTooManyRequests, resp.StatusCode)
+		assert.Equal(t, CodeResourceExhausted, decodeError(t, resp).Code)
+	})
+}
+
+func TestTransformResponse(t *testing.T) {
+	t.Run("ParsesToolCalls", func(t *testing.T) {
+		server := httptest.NewServer(NewHandler(tooladapter.New(), nil))
+		defer server.Close()
+
+		resp := post(t, server, "TransformResponse", map[string]any{
+			"response": tooltest.Completion(`{"name": "get_weather", "parameters": {"param1": "Paris"}}`),
+			"tools":    []openai.ChatCompletionToolUnionParam{tooltest.Tool("get_weather", "")},
+		})
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+
+		var msg struct {
+			Response openai.ChatCompletion `json:"response"`
+		}
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&msg))
+		require.Len(t, msg.Response.Choices, 1)
+		calls := msg.Response.Choices[0].Message.ToolCalls
+		require.Len(t, calls, 1)
+		assert.Equal(t, "get_weather", calls[0].Function.Name)
+		assert.JSONEq(t, `{"param1": "Paris"}`, calls[0].Function.Arguments)
+		assert.Equal(t, "tool_calls", msg.Response.Choices[0].FinishReason)
+	})
+
+	t.Run("UnknownTool", func(t *testing.T) {
+		server := httptest.NewServer(NewHandler(tooladapter.New(
+			tooladapter.WithAllowedToolNames([]string{"get_weather"}),
+			tooladapter.WithUnknownToolPolicy(tooladapter.UnknownToolError)), nil))
+		defer server.Close()
+
+		resp := post(t, server, "TransformResponse", map[string]any{
+			"response": tooltest.Completion(`{"name": "delete_all", "parameters": null}`),
+		})
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+		connectErr := decodeError(t, resp)
+		assert.Equal(t, CodeFailedPrecondition, connectErr.Code)
+		assert.Contains(t, connectErr.Message, "delete_all")
+	})
+}
+
+func TestStreamCompletion(t *testing.T) {
+	t.Run("ToolCall", func(t *testing.T) {
+		var upstreamParams openai.ChatCompletionNewParams
+		upstream := tooltest.NewContentStream(`{"name": "get_weather", "parameters": {"param1": "Paris"}}`)
+		server := httptest.NewServer(NewHandler(tooladapter.New(),
+			func(_ context.Context, params openai.ChatCompletionNewParams) tooladapter.ChatCompletionStreamInterface {
+				upstreamParams = params
+				return upstream
+			}))
+		defer server.Close()
+
+		chunks, end := streamMessages(t, server, map[string]any{
+			"request": tooltest.Request(tooltest.Tool("get_weather", "")),
+		})
+		assert.Nil(t, end.Error)
+		assert.True(t, upstream.Closed())
+		assert.Empty(t, upstreamParams.Tools, "the upstream receives the transformed request")
+
+		var names []string
+		for _, chunk := range chunks {
+			for _, choice := range chunk.Choices {
+				for _, call := range choice.Delta.ToolCalls {
+					names = append(names, call.Function.Name)
+				}
+			}
+		}
+		assert.Equal(t, []string{"get_weather"}, names)
+	})
+
+	t.Run("UpstreamError", func(t *testing.T) {
+		upstream := tooltest.NewContentStream("Hello")
+		upstream.SetError(errors.New("upstream reset"))
+		server := httptest.NewServer(NewHandler(tooladapter.New(),
+			func(context.Context, openai.ChatCompletionNewParams) tooladapter.ChatCompletionStreamInterface {
+				return upstream
+			}))
+		defer server.Close()
+
+		_, end := streamMessages(t, server, map[string]any{"request": tooltest.Request()})
+		require.NotNil(t, end.Error)
+		assert.Equal(t, CodeUnavailable, end.Error.Code)
+		assert.Contains(t, end.Error.Message, "upstream reset")
+	})
+
+	t.Run("NoUpstream", func(t *testing.T) {
+		server := httptest.NewServer(NewHandler(tooladapter.New(), nil))
+		defer server.Close()
+
+		chunks, end := streamMessages(t, server, map[string]any{"request": tooltest.Request()})
+		assert.Empty(t, chunks)
+		require.NotNil(t, end.Error)
+		assert.Equal(t, CodeUnimplemented, end.Error.Code)
+	})
+}
+
+func TestHandlerRouting(t *testing.T) {
+	server := httptest.NewServer(NewHandler(tooladapter.New(), nil))
+	defer server.Close()
+
+	tests := []struct {
+		name        string
+		method      string
+		path        string
+		contentType string
+		status      int
+	}{
+		{"WrongMethod", http.MethodGet, ServicePath + "TransformRequest", unaryContentType, http.StatusMethodNotAllowed},
+		{"UnknownProcedure", http.MethodPost, ServicePath + "Translate", unaryContentType, http.StatusNotFound},
+		{"OutsideService", http.MethodPost, "/other.v1.Service/TransformRequest", unaryContentType, http.StatusNotFound},
+		{"BinaryCodec", http.MethodPost, ServicePath + "TransformRequest", "application/proto", http.StatusUnsupportedMediaType},
+		{"UnaryContentTypeForStream", http.MethodPost, ServicePath + "StreamCompletion", unaryContentType, http.StatusUnsupportedMediaType},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, err := http.NewRequest(tt.method, server.URL+tt.path, strings.NewReader("{}"))
+			require.NoError(t, err)
+			req.Header.Set("Content-Type", tt.contentType)
+			resp, err := http.DefaultClient.Do(req)
+			require.NoError(t, err)
+			defer func() { _ = resp.Body.Close() }()
+			assert.Equal(t, tt.status, resp.StatusCode)
+		})
+	}
+}
+
+func TestErrorCode(t *testing.T) {
+	assert.Equal(t, CodeCanceled, errorCode(context.Canceled))
+	assert.Equal(t, CodeDeadlineExceeded, errorCode(context.DeadlineExceeded))
+	assert.Equal(t, CodeFailedPrecondition, errorCode(tooladapter.ErrArgumentViolation))
+	assert.Equal(t, CodeInvalidArgument, errorCode(errors.New("bad request")))
+}
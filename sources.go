@@ -0,0 +1,243 @@
+package tooladapter
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+
+	"github.com/openai/openai-go/v3"
+)
+
+// Parser values of ToolCallSource, naming the shape of JSON a tool call was parsed from.
+const (
+	// ToolCallParserObject is a single {"name": ..., "parameters": ...} object.
+	ToolCallParserObject = "object"
+
+	// ToolCallParserArray is an array of {"name": ..., "parameters": ...} objects.
+	ToolCallParserArray = "array"
+
+	// ToolCallParserFunctionCall is the legacy OpenAI {"function_call": {...}} shape.
+	ToolCallParserFunctionCall = "function_call"
+
+	// ToolCallParserToolCalls is the API-style {"tool_calls": [...]} wrapper.
+	ToolCallParserToolCalls = "tool_calls"
+)
+
+// ToolCallSource locates the model output a tool call in a transformed response was
+// parsed from, for audit trails and for highlighting calls in the raw output.
+type ToolCallSource struct {
+	// ChoiceIndex is the index of the choice whose content held the call
+	ChoiceIndex int `json:"choice_index"`
+
+	// Start and End delimit the call's bytes in the choice's original content, as
+	// received from the upstream model. For an array or tool_calls wrapper the range
+	// covers the call's own element. When a repair rewrote the text, the range covers
+	// the text the repaired JSON was derived from.
+	Start int `json:"start"`
+	End   int `json:"end"`
+
+	// Parser names the shape of JSON that matched, using the ToolCallParser constants
+	Parser string `json:"parser"`
+
+	// Repair names the repair applied before the JSON parsed: ParseDetailNormalized,
+	// ParseDetailLenient or ParseDetailStopSequence. Empty when the text parsed as is.
+	Repair string `json:"repair,omitempty"`
+}
+
+// ToolCallSources maps the IDs of the tool calls in a transformed response to their
+// sources. Attach one to a context with ContextWithToolCallSources.
+type ToolCallSources map[string]ToolCallSource
+
+// toolCallSourcesKey is the context key for maps attached by ContextWithToolCallSources.
+type toolCallSourcesKey struct{}
+
+// ContextWithToolCallSources returns a copy of ctx that makes response transformation
+// record the source of every tool call it emits in sources, keyed by tool call ID:
+//
+//	sources := tooladapter.ToolCallSources{}
+//	ctx = tooladapter.ContextWithToolCallSources(ctx, sources)
+//	resp, err := adapter.TransformCompletionsResponseWithContext(ctx, completion)
+//	for _, call := range resp.Choices[0].Message.ToolCalls {
+//		source := sources[call.ID]
+//		highlight(completion.Choices[0].Message.Content[source.Start:source.End])
+//	}
+//
+// Use a new map for each response; the map is written without locking. Streaming
+// transformations do not record sources.
+func ContextWithToolCallSources(ctx context.Context, sources ToolCallSources) context.Context {
+	return context.WithValue(ctx, toolCallSourcesKey{}, sources)
+}
+
+// toolCallSourcesFrom returns the map attached to ctx by ContextWithToolCallSources.
+func toolCallSourcesFrom(ctx context.Context) ToolCallSources {
+	if ctx == nil {
+		return nil
+	}
+	sources, _ := ctx.Value(toolCallSourcesKey{}).(ToolCallSources)
+	return sources
+}
+
+// callSource is the source of a parsed call, carried through post-processing. The
+// range is relative to the content that was searched for tool calls.
+type callSource struct {
+	start, end int
+	parser     string
+	repair     string
+}
+
+// attachCallSources records on each of calls, parsed from candidate with parser, the
+// part of content it came from.
+func (a *Adapter) attachCallSources(calls []functionCall, content, candidate, parser string) {
+	start, end, repair := a.locateCandidate(content, candidate)
+	var elements [][2]int
+	if repair == "" || repair == ParseDetailStopSequence {
+		elements = candidateElements(candidate, parser)
+	}
+	for i := range calls {
+		source := &callSource{start: start, end: end, parser: parser, repair: repair}
+		if i < len(elements) {
+			source.start = min(start+elements[i][0], end)
+			source.end = min(start+elements[i][1], end)
+		}
+		calls[i].source = source
+	}
+}
+
+// locateCandidate returns the byte range of content that candidate was extracted
+// from and the repair that produced it, retracing extractCandidates. Content that no
+// step reproduces is reported whole.
+func (a *Adapter) locateCandidate(content, candidate string) (int, int, string) {
+	text := a.prefilled(content)
+	prefill := len(text) - len(content)
+	inContent := func(start, end int) (int, int) {
+		return max(start-prefill, 0), min(max(end-prefill, 0), len(content))
+	}
+
+	if i := strings.Index(text, candidate); i >= 0 {
+		start, end := inContent(i, i+len(candidate))
+		return start, end, ""
+	}
+	for _, seq := range a.toolStopSequences {
+		if i := strings.Index(text+seq, candidate); i >= 0 {
+			start, end := inContent(i, i+len(candidate))
+			return start, end, ParseDetailStopSequence
+		}
+	}
+
+	// Ranges in normalized text are mapped back through the removed characters
+	searched := text
+	normalized, changed := normalizeToolText(text)
+	original := func(start, end int) (int, int) {
+		if changed {
+			start, end = denormalizedRange(text, start, end)
+		}
+		return inContent(start, end)
+	}
+	if changed {
+		if i := strings.Index(normalized, candidate); i >= 0 {
+			start, end := original(i, i+len(candidate))
+			return start, end, ParseDetailNormalized
+		}
+		searched = normalized
+	}
+
+	if a.lenientParsing {
+		for _, block := range extractJSONBlocks(searched) {
+			if repaired, ok := normalizeJSON5(block); ok && repaired == candidate {
+				i := strings.Index(searched, block)
+				start, end := original(i, i+len(block))
+				return start, end, ParseDetailLenient
+			}
+		}
+		for _, match := range yamlFencePattern.FindAllStringSubmatchIndex(searched, -1) {
+			if converted, ok := yamlToJSON(searched[match[2]:match[3]]); ok && converted == candidate {
+				start, end := original(match[2], match[3])
+				return start, end, ParseDetailLenient
+			}
+		}
+		if converted, ok := yamlToJSON(searched); ok && converted == candidate {
+			return 0, len(content), ParseDetailLenient
+		}
+	}
+	return 0, len(content), ""
+}
+
+// denormalizedRange maps a byte range of normalizeToolText(text) back to text. The
+// start skips characters the normalization removed.
+func denormalizedRange(text string, start, end int) (int, int) {
+	from, to := len(text), len(text)
+	produced := 0
+	for i, r := range text {
+		width := len(toolTextReplacer.Replace(string(r)))
+		if from == len(text) && produced >= start && width > 0 {
+			from = i
+		}
+		if produced >= end {
+			to = i
+			break
+		}
+		produced += width
+	}
+	return min(from, to), to
+}
+
+// candidateElements returns the byte ranges of the calls within an array or
+// tool_calls wrapper candidate, or nil for other shapes.
+func candidateElements(candidate, parser string) [][2]int {
+	var elements []json.RawMessage
+	switch parser {
+	case ToolCallParserArray:
+		if json.Unmarshal([]byte(candidate), &elements) != nil {
+			return nil
+		}
+	case ToolCallParserToolCalls:
+		var wrapper struct {
+			ToolCalls []json.RawMessage `json:"tool_calls"`
+		}
+		if json.Unmarshal([]byte(candidate), &wrapper) != nil {
+			return nil
+		}
+		elements = wrapper.ToolCalls
+	default:
+		return nil
+	}
+
+	// Raw elements are verbatim copies, so each is found after the previous one
+	ranges := make([][2]int, 0, len(elements))
+	offset := 0
+	for _, element := range elements {
+		i := strings.Index(candidate[offset:], string(element))
+		if i < 0 {
+			return nil
+		}
+		ranges = append(ranges, [2]int{offset + i, offset + i + len(element)})
+		offset += i + len(element)
+	}
+	return ranges
+}
+
+// recordToolCallSources adds the sources of the tool calls of a transformed choice to
+// sources. The tool calls were built from calls in order. Ranges are relative to the
+// searched content, which may have had think blocks removed from original; they are
+// moved to where the same text appears in original.
+func recordToolCallSources(sources ToolCallSources, original, searched string, choiceIndex int, toolCalls []openai.ChatCompletionMessageToolCallUnion, calls []functionCall) {
+	for i, toolCall := range toolCalls {
+		if i >= len(calls) || calls[i].source == nil {
+			continue
+		}
+		source := calls[i].source
+		start, end := source.start, source.end
+		if searched != original {
+			if j := strings.Index(original, searched[start:end]); j >= 0 {
+				start, end = j, j+end-start
+			}
+		}
+		sources[toolCall.ID] = ToolCallSource{
+			ChoiceIndex: choiceIndex,
+			Start:       start,
+			End:         end,
+			Parser:      source.parser,
+			Repair:      source.repair,
+		}
+	}
+}
@@ -0,0 +1,144 @@
+package tooladapter_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	tooladapter "github.com/juburr/openai-tool-adapter/v3"
+	"github.com/juburr/openai-tool-adapter/v3/tooltest"
+	"github.com/openai/openai-go/v3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// transformWithSources transforms a completion holding content and returns its tool
+// calls with the recorded sources.
+func transformWithSources(t *testing.T, adapter *tooladapter.Adapter, content string) ([]openai.ChatCompletionMessageToolCallUnion, tooladapter.ToolCallSources) {
+	t.Helper()
+	sources := tooladapter.ToolCallSources{}
+	ctx := tooladapter.ContextWithToolCallSources(context.Background(), sources)
+	resp, err := adapter.TransformCompletionsResponseWithContext(ctx, tooltest.Completion(content))
+	require.NoError(t, err)
+	return resp.Choices[0].Message.ToolCalls, sources
+}
+
+func TestToolCallSources(t *testing.T) {
+	drainAll := tooladapter.New(tooladapter.WithToolPolicy(tooladapter.ToolDrainAll))
+
+	t.Run("FencedObject", func(t *testing.T) {
+		call := `{"name": "get_weather", "parameters": {"city": "Paris"}}`
+		content := "Let me check.\n```json\n" + call + "\n```"
+		toolCalls, sources := transformWithSources(t, tooladapter.New(), content)
+
+		require.Len(t, toolCalls, 1)
+		require.Len(t, sources, 1)
+		source := sources[toolCalls[0].ID]
+		assert.Equal(t, call, content[source.Start:source.End])
+		assert.Equal(t, tooladapter.ToolCallSource{
+			ChoiceIndex: 0,
+			Start:       source.Start,
+			End:         source.End,
+			Parser:      tooladapter.ToolCallParserObject,
+		}, source)
+	})
+
+	t.Run("ArrayElements", func(t *testing.T) {
+		first := `{"name": "get_weather", "parameters": {"city": "Paris"}}`
+		second := `{"name": "get_time", "parameters": {"zone": "CET"}}`
+		content := "[" + first + ",\n " + second + "]"
+		toolCalls, sources := transformWithSources(t, drainAll, content)
+
+		require.Len(t, toolCalls, 2)
+		for i, want := range []string{first, second} {
+			source := sources[toolCalls[i].ID]
+			assert.Equal(t, want, content[source.Start:source.End])
+			assert.Equal(t, tooladapter.ToolCallParserArray, source.Parser)
+		}
+	})
+
+	t.Run("ToolCallsWrapper", func(t *testing.T) {
+		entry := `{"id": "call_model", "type": "function", "function": {"name": "get_weather", "arguments": "{}"}}`
+		content := `{"tool_calls": [` + entry + `]}`
+		toolCalls, sources := transformWithSources(t, drainAll, content)
+
+		require.Len(t, toolCalls, 1)
+		source, ok := sources["call_model"]
+		require.True(t, ok, "the model's ID keys the source")
+		assert.Equal(t, entry, content[source.Start:source.End])
+		assert.Equal(t, tooladapter.ToolCallParserToolCalls, source.Parser)
+	})
+
+	t.Run("LegacyFunctionCall", func(t *testing.T) {
+		content := `{"function_call": {"name": "get_weather", "arguments": "{}"}}`
+		toolCalls, sources := transformWithSources(t, tooladapter.New(), content)
+
+		require.Len(t, toolCalls, 1)
+		source := sources[toolCalls[0].ID]
+		assert.Equal(t, content, content[source.Start:source.End])
+		assert.Equal(t, tooladapter.ToolCallParserFunctionCall, source.Parser)
+	})
+
+	t.Run("Normalized", func(t *testing.T) {
+		// Full-width brackets, with zero-width spaces inside a value and before the text
+		call := "\uff5b\"name\": \"get_weather\", \"parameters\": \uff5b\"city\": \"Pa\u200bris\"\uff5d\uff5d"
+		content := "\u200bCalling: " + call
+		toolCalls, sources := transformWithSources(t, tooladapter.New(), content)
+
+		require.Len(t, toolCalls, 1)
+		source := sources[toolCalls[0].ID]
+		assert.Equal(t, call, content[source.Start:source.End])
+		assert.Equal(t, tooladapter.ParseDetailNormalized, source.Repair)
+	})
+
+	t.Run("Lenient", func(t *testing.T) {
+		call := `{name: 'get_weather', parameters: {city: 'Paris',},}`
+		content := "Sure: " + call
+		toolCalls, sources := transformWithSources(t, tooladapter.New(tooladapter.WithLenientParsing(true)), content)
+
+		require.Len(t, toolCalls, 1)
+		source := sources[toolCalls[0].ID]
+		assert.Equal(t, call, content[source.Start:source.End])
+		assert.Equal(t, tooladapter.ParseDetailLenient, source.Repair)
+		assert.Equal(t, tooladapter.ToolCallParserObject, source.Parser)
+	})
+
+	t.Run("StopSequence", func(t *testing.T) {
+		content := `[{"name": "get_weather", "parameters": {}}`
+		adapter := tooladapter.New(tooladapter.WithToolStopSequences("]"))
+		toolCalls, sources := transformWithSources(t, adapter, content)
+
+		require.Len(t, toolCalls, 1)
+		source := sources[toolCalls[0].ID]
+		assert.Equal(t, `{"name": "get_weather", "parameters": {}}`, content[source.Start:source.End])
+		assert.Equal(t, tooladapter.ParseDetailStopSequence, source.Repair)
+	})
+
+	t.Run("StrippedThinkBlocks", func(t *testing.T) {
+		call := `{"name": "get_weather", "parameters": {}}`
+		content := "<think>The user wants weather.</think>\n" + call
+		adapter := tooladapter.New(tooladapter.WithThinkBlocks(tooladapter.ThinkBlocksStrip))
+		toolCalls, sources := transformWithSources(t, adapter, content)
+
+		require.Len(t, toolCalls, 1)
+		source := sources[toolCalls[0].ID]
+		assert.Equal(t, call, content[source.Start:source.End], "the range is in the upstream content")
+	})
+
+	t.Run("ResponseCache", func(t *testing.T) {
+		adapter := tooladapter.New(tooladapter.WithResponseCache(8, time.Minute))
+		content := `Calling {"name": "get_weather", "parameters": {}}`
+		first, firstSources := transformWithSources(t, adapter, content)
+		second, secondSources := transformWithSources(t, adapter, content)
+
+		require.Len(t, first, 1)
+		require.Len(t, second, 1)
+		assert.Equal(t, firstSources, secondSources, "cache hits report the sources too")
+	})
+
+	t.Run("NotRequested", func(t *testing.T) {
+		resp, err := tooladapter.New().TransformCompletionsResponse(tooltest.Completion(`{"name": "get_weather", "parameters": {}}`))
+		require.NoError(t, err)
+		assert.Len(t, resp.Choices[0].Message.ToolCalls, 1)
+	})
+}
@@ -0,0 +1,91 @@
+package tooladapter
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/openai/openai-go/v3"
+)
+
+// WriteSSEStream writes a transformed stream to an HTTP response as OpenAI-compatible
+// server-sent events, so a proxy server can relay a StreamAdapter (or a Session
+// stream) in a few lines:
+//
+//	func handler(w http.ResponseWriter, r *http.Request) {
+//		upstream := client.Chat.Completions.NewStreaming(r.Context(), req)
+//		stream := adapter.TransformStreamingResponseWithContext(r.Context(), upstream)
+//		if err := tooladapter.WriteSSEStream(r.Context(), w, stream); err != nil {
+//			log.Printf("stream ended early: %v", err)
+//		}
+//	}
+//
+// It sets the SSE headers, writes each chunk as a "data: {...}" event and flushes it,
+// and finishes with "data: [DONE]". A stream error is reported to the client as an
+// OpenAI-style error event, without [DONE], and returned. Chunks of streams from
+// adapters created with WithStrictOpenAICompatibility are encoded with
+// MarshalOpenAIChunk.
+//
+// Client disconnects are detected through ctx, normally the request's context, and
+// through failed writes; either ends the stream and returns the error. WriteSSEStream
+// checks ctx between chunks, so create the upstream request with the same context
+// to abort a pending read when the client goes away. The stream is always closed.
+func WriteSSEStream(ctx context.Context, w http.ResponseWriter, stream ChatCompletionStreamInterface) error {
+	defer stream.Close()
+
+	marshal := func(chunk openai.ChatCompletionChunk) ([]byte, error) { return json.Marshal(chunk) }
+	if adapted, ok := stream.(*StreamAdapter); ok && adapted.strict != nil {
+		marshal = MarshalOpenAIChunk
+	}
+
+	writer := NewHTTPSSEWriter(w)
+	for stream.Next() {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		data, err := marshal(stream.Current())
+		if err != nil {
+			return fmt.Errorf("failed to encode stream chunk: %w", err)
+		}
+		if err := writer.WriteRaw(sseEvent(data)); err != nil {
+			return fmt.Errorf("failed to write stream chunk: %w", err)
+		}
+	}
+
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if err := stream.Err(); err != nil {
+		if !errors.Is(err, context.Canceled) && !errors.Is(err, context.DeadlineExceeded) {
+			// Best effort: the client may already be gone
+			_ = writer.WriteRaw(sseErrorEvent(err))
+		}
+		return err
+	}
+	if err := writer.WriteDone(); err != nil {
+		return fmt.Errorf("failed to write stream end: %w", err)
+	}
+	return nil
+}
+
+// sseEvent formats data as a single SSE data event.
+func sseEvent(data []byte) []byte {
+	event := make([]byte, 0, len(data)+len("data: \n\n"))
+	event = append(event, "data: "...)
+	event = append(event, data...)
+	return append(event, "\n\n"...)
+}
+
+// sseErrorEvent formats err as an OpenAI-style error event.
+func sseErrorEvent(err error) []byte {
+	data, _ := json.Marshal(map[string]any{
+		"error": map[string]any{
+			"message": err.Error(),
+			"type":    "server_error",
+		},
+	})
+	return sseEvent(data)
+}
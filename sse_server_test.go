@@ -0,0 +1,101 @@
+package tooladapter_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	tooladapter "github.com/juburr/openai-tool-adapter/v3"
+	"github.com/juburr/openai-tool-adapter/v3/tooltest"
+	"github.com/openai/openai-go/v3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// sseEvents splits an SSE body into the payloads of its data events.
+func sseEvents(t *testing.T, body string) []string {
+	t.Helper()
+	var events []string
+	for _, event := range strings.Split(strings.TrimSuffix(body, "\n\n"), "\n\n") {
+		require.True(t, strings.HasPrefix(event, "data: "), "event %q", event)
+		events = append(events, strings.TrimPrefix(event, "data: "))
+	}
+	return events
+}
+
+// failingResponseWriter simulates a client that disconnected.
+type failingResponseWriter struct {
+	*httptest.ResponseRecorder
+}
+
+func (w failingResponseWriter) Write([]byte) (int, error) {
+	return 0, errors.New("broken pipe")
+}
+
+func TestWriteSSEStream(t *testing.T) {
+	adapter := tooladapter.New()
+
+	t.Run("ToolCall", func(t *testing.T) {
+		upstream := tooltest.NewContentStream(`{"name": "get_weather", "parameters": {"city": "Paris"}}`)
+		rec := httptest.NewRecorder()
+
+		err := tooladapter.WriteSSEStream(context.Background(), rec, adapter.TransformStreamingResponse(upstream))
+		require.NoError(t, err)
+		assert.True(t, upstream.Closed())
+		assert.Equal(t, "text/event-stream", rec.Header().Get("Content-Type"))
+		assert.True(t, rec.Flushed)
+
+		events := sseEvents(t, rec.Body.String())
+		require.GreaterOrEqual(t, len(events), 2)
+		assert.Equal(t, "[DONE]", events[len(events)-1])
+
+		var names []string
+		for _, event := range events[:len(events)-1] {
+			var chunk openai.ChatCompletionChunk
+			require.NoError(t, json.Unmarshal([]byte(event), &chunk))
+			for _, call := range chunk.Choices[0].Delta.ToolCalls {
+				names = append(names, call.Function.Name)
+			}
+		}
+		assert.Equal(t, []string{"get_weather"}, names)
+	})
+
+	t.Run("StreamError", func(t *testing.T) {
+		upstream := tooltest.NewContentStream("Hello")
+		upstream.SetError(errors.New("upstream reset"))
+		rec := httptest.NewRecorder()
+
+		err := tooladapter.WriteSSEStream(context.Background(), rec, adapter.TransformStreamingResponse(upstream))
+		require.EqualError(t, err, "upstream reset")
+
+		events := sseEvents(t, rec.Body.String())
+		last := events[len(events)-1]
+		assert.JSONEq(t, `{"error": {"message": "upstream reset", "type": "server_error"}}`, last)
+		assert.NotContains(t, rec.Body.String(), "[DONE]")
+	})
+
+	t.Run("ClientGone", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		upstream := tooltest.NewContentStream("Hello")
+		rec := httptest.NewRecorder()
+
+		err := tooladapter.WriteSSEStream(ctx, rec, adapter.TransformStreamingResponse(upstream))
+		require.ErrorIs(t, err, context.Canceled)
+		assert.True(t, upstream.Closed())
+		assert.NotContains(t, rec.Body.String(), "[DONE]")
+	})
+
+	t.Run("WriteFails", func(t *testing.T) {
+		upstream := tooltest.NewContentStream("Hello")
+		var w http.ResponseWriter = failingResponseWriter{httptest.NewRecorder()}
+
+		err := tooladapter.WriteSSEStream(context.Background(), w, adapter.TransformStreamingResponse(upstream))
+		require.ErrorContains(t, err, "broken pipe")
+		assert.True(t, upstream.Closed())
+	})
+}
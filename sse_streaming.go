@@ -4,6 +4,7 @@ import (
 	"context"
 	"encoding/json"
 	"strings"
+	"time"
 )
 
 // SSEStreamAdapter processes raw SSE streams to detect and transform tool calls.
@@ -41,6 +42,12 @@ type SSEStreamAdapter struct {
 
 	// Context for cancellation
 	ctx context.Context
+
+	// Keep-alive tracking
+	lastHeartbeat time.Time
+
+	// Repeated calls collapsed by WithToolCallDeduplication, reported in metrics
+	duplicatesRemoved int
 }
 
 // NewSSEStreamAdapter creates a new SSE stream adapter for processing raw SSE streams.
@@ -72,10 +79,24 @@ func (s *SSEStreamAdapter) captureMetadata(chunk *SSEChunk) {
 	}
 }
 
+// writeHeartbeatIfDue writes an SSE comment line as a keep-alive when the heartbeat
+// interval has elapsed while output is being withheld.
+func (s *SSEStreamAdapter) writeHeartbeatIfDue() error {
+	if s.adapter.streamHeartbeat <= 0 {
+		return nil
+	}
+	if time.Since(s.lastHeartbeat) < s.adapter.streamHeartbeat {
+		return nil
+	}
+	s.lastHeartbeat = time.Now()
+	return s.writer.WriteRaw([]byte(": keep-alive\n\n"))
+}
+
 // Process reads the SSE stream, detects tool calls, and writes transformed output.
 // It returns when the stream ends or an error occurs.
 func (s *SSEStreamAdapter) Process(ctx context.Context) error {
 	s.ctx = ctx
+	s.lastHeartbeat = time.Now()
 
 	// Collect all chunks for analysis
 	var chunks []*SSEChunk
@@ -94,7 +115,7 @@ func (s *SSEStreamAdapter) Process(ctx context.Context) error {
 
 		chunk, err := ParseSSEChunk(data)
 		if err != nil {
-			s.adapter.logger.Debug("Failed to parse SSE chunk, passing through",
+			s.adapter.log(LogCategoryStream).Debug("Failed to parse SSE chunk, passing through",
 				"error", err,
 				"data_length", len(data))
 			// Pass through unparseable chunks
@@ -109,6 +130,10 @@ func (s *SSEStreamAdapter) Process(ctx context.Context) error {
 		// Capture metadata from first chunk (including extra fields)
 		s.captureMetadata(chunk)
 
+		if err := s.writeHeartbeatIfDue(); err != nil {
+			return err
+		}
+
 		// Accumulate content
 		if len(chunk.Choices) > 0 && chunk.Choices[0].Delta.Content != "" {
 			s.contentBuffer.WriteString(chunk.Choices[0].Delta.Content)
@@ -120,6 +145,11 @@ func (s *SSEStreamAdapter) Process(ctx context.Context) error {
 		return err
 	}
 
+	// Models with native function calling are passed through
+	if s.adapter.forModel(s.model).usesNativeTools(s.model) {
+		return s.passthrough(rawChunks)
+	}
+
 	// Analyze accumulated content for tool calls
 	fullContent := s.contentBuffer.String()
 	if fullContent == "" {
@@ -128,8 +158,7 @@ func (s *SSEStreamAdapter) Process(ctx context.Context) error {
 	}
 
 	// Try to extract tool calls from the content
-	extractor := NewJSONExtractor(fullContent)
-	candidates := extractor.ExtractJSONBlocks()
+	candidates := s.adapter.extractCandidates(fullContent, true)
 
 	if len(candidates) == 0 {
 		// No JSON found - pass through all chunks
@@ -137,7 +166,10 @@ func (s *SSEStreamAdapter) Process(ctx context.Context) error {
 	}
 
 	// Try to parse function calls
-	calls := s.extractRawFunctionCalls(candidates)
+	calls, err := s.extractRawFunctionCalls(candidates)
+	if err != nil {
+		return err
+	}
 	if len(calls) == 0 {
 		// No valid function calls - pass through all chunks
 		return s.passthrough(rawChunks)
@@ -157,43 +189,31 @@ func (s *SSEStreamAdapter) passthrough(rawChunks []string) error {
 	return s.writer.WriteDone()
 }
 
-// extractRawFunctionCalls extracts function calls from JSON candidates.
-func (s *SSEStreamAdapter) extractRawFunctionCalls(candidates []string) []RawFunctionCall {
-	for _, candidate := range candidates {
-		// Try parsing as array first
-		var arrayCalls []RawFunctionCall
-		decoder := json.NewDecoder(strings.NewReader(candidate))
-		decoder.DisallowUnknownFields()
-		if err := decoder.Decode(&arrayCalls); err == nil && len(arrayCalls) > 0 {
-			if s.validateRawFunctionCalls(arrayCalls) {
-				return arrayCalls
-			}
-		}
-
-		// Try parsing as single object
-		var singleCall RawFunctionCall
-		decoder = json.NewDecoder(strings.NewReader(candidate))
-		decoder.DisallowUnknownFields()
-		if err := decoder.Decode(&singleCall); err == nil {
-			if ValidateFunctionName(singleCall.Name) == nil {
-				return []RawFunctionCall{singleCall}
-			}
-		}
+// extractRawFunctionCalls extracts function calls from JSON candidates using the
+// same parser and post-processing as the non-streaming and streaming paths.
+func (s *SSEStreamAdapter) extractRawFunctionCalls(candidates []string) ([]RawFunctionCall, error) {
+	calls, err := s.adapter.postProcessCalls(s.ctx, ExtractFunctionCalls(candidates))
+	if err != nil {
+		return nil, wrapTransformError(PhaseStreaming, "process tool calls", -1, err)
 	}
-	return nil
-}
-
-// validateRawFunctionCalls validates all function calls in a slice.
-func (s *SSEStreamAdapter) validateRawFunctionCalls(calls []RawFunctionCall) bool {
+	calls, s.duplicatesRemoved = s.adapter.deduplicateCalls(calls, nil)
 	if len(calls) == 0 {
-		return false
+		return nil, nil
 	}
-	for _, call := range calls {
-		if ValidateFunctionName(call.Name) != nil {
-			return false
-		}
+	rawCalls := make([]RawFunctionCall, len(calls))
+	for i, call := range calls {
+		rawCalls[i] = RawFunctionCall{Name: call.Name, Parameters: call.Parameters, ID: call.ID}
+	}
+	return rawCalls, nil
+}
+
+// toFunctionCalls converts parsed SSE calls back to the adapter's internal form.
+func toFunctionCalls(calls []RawFunctionCall) []functionCall {
+	converted := make([]functionCall, len(calls))
+	for i, call := range calls {
+		converted[i] = functionCall{Name: call.Name, Parameters: call.Parameters, ID: call.ID}
 	}
-	return true
+	return converted
 }
 
 // emitToolCallResponse emits a transformed response with tool calls.
@@ -201,25 +221,38 @@ func (s *SSEStreamAdapter) emitToolCallResponse(calls []RawFunctionCall, origina
 	// Apply tool policy limits
 	calls = s.applyToolPolicy(calls)
 
+	names := make([]string, len(calls))
+	for i, call := range calls {
+		names[i] = call.Name
+	}
+	calls = calls[:s.adapter.allowToolCalls(s.ctx, names, 0, true)]
+	if len(calls) == 0 {
+		return s.emitSuppressedResponse()
+	}
+
 	// Log detection
 	functionNames := make([]string, len(calls))
 	for i, call := range calls {
 		functionNames[i] = call.Name
 	}
 
-	s.adapter.logger.Info("SSE streaming: detected and converted function calls",
+	s.adapter.log(LogCategoryParse).Info("SSE streaming: detected and converted function calls",
 		"function_count", len(calls),
 		"function_names", functionNames,
 		"content_length", s.contentBuffer.Len(),
 		"streaming", true)
 
+	s.adapter.emitToolDetectedEvents(toFunctionCalls(calls), 0, s.contentBuffer.Len(), true)
+
 	// Emit metrics
 	s.adapter.emitMetric(FunctionCallDetectionData{
-		FunctionCount:  len(calls),
-		FunctionNames:  functionNames,
-		ContentLength:  s.contentBuffer.Len(),
-		JSONCandidates: len(calls),
-		Streaming:      true,
+		FunctionCount:     len(calls),
+		FunctionNames:     functionNames,
+		ContentLength:     s.contentBuffer.Len(),
+		JSONCandidates:    len(calls),
+		Streaming:         true,
+		DuplicatesRemoved: s.duplicatesRemoved,
+		ContentTokens:     s.adapter.countTokens(s.contentBuffer.String()),
 	})
 
 	// Build tool calls
@@ -232,7 +265,7 @@ func (s *SSEStreamAdapter) emitToolCallResponse(calls []RawFunctionCall, origina
 
 		toolCalls[i] = SSEToolCall{
 			Index: i,
-			ID:    s.adapter.GenerateToolCallID(),
+			ID:    s.adapter.toolCallID(call.ID),
 			Type:  "function",
 			Function: SSEFunctionCall{
 				Name:      call.Name,
@@ -286,13 +319,36 @@ func (s *SSEStreamAdapter) emitToolCallResponse(calls []RawFunctionCall, origina
 	return s.writer.WriteDone()
 }
 
+// emitSuppressedResponse ends the response without content when every tool call was
+// suppressed by WithToolCallRateLimit.
+func (s *SSEStreamAdapter) emitSuppressedResponse() error {
+	finishChunk := &SSEChunk{
+		ID:          s.completionID,
+		Object:      "chat.completion.chunk",
+		Created:     s.created,
+		Model:       s.model,
+		ExtraFields: s.chunkExtraFields,
+		Choices: []SSEChoice{
+			{
+				Index:        0,
+				Delta:        SSEDelta{Role: "assistant"},
+				FinishReason: "stop",
+			},
+		},
+	}
+	if err := s.writer.WriteChunk(finishChunk); err != nil {
+		return err
+	}
+	return s.writer.WriteDone()
+}
+
 // applyToolPolicy applies the configured tool policy to limit tool calls.
 func (s *SSEStreamAdapter) applyToolPolicy(calls []RawFunctionCall) []RawFunctionCall {
 	switch s.adapter.toolPolicy {
 	case ToolStopOnFirst:
 		// Return only the first tool call
 		if len(calls) > 0 {
-			s.adapter.logger.Debug("Applied ToolStopOnFirst policy",
+			s.adapter.log(LogCategoryStream).Debug("Applied ToolStopOnFirst policy",
 				"original_count", len(calls),
 				"result_count", 1)
 			return calls[:1]
@@ -302,17 +358,19 @@ func (s *SSEStreamAdapter) applyToolPolicy(calls []RawFunctionCall) []RawFunctio
 	case ToolCollectThenStop, ToolDrainAll:
 		// Apply max calls limit
 		if s.adapter.toolMaxCalls > 0 && len(calls) > s.adapter.toolMaxCalls {
-			s.adapter.logger.Debug("Applied tool call limit",
-				"policy", s.adapter.toolPolicy,
+			s.adapter.log(LogCategoryLimit).Debug("Applied tool call limit",
+				"policy", s.adapter.toolPolicy.String(),
 				"original_count", len(calls),
 				"max_calls", s.adapter.toolMaxCalls)
+			s.adapter.emitPolicyLimitHit(PolicyLimitToolMaxCalls, len(calls), s.adapter.toolMaxCalls, true)
 			return calls[:s.adapter.toolMaxCalls]
 		}
 		return calls
 
-	case ToolAllowMixed:
+	case ToolAllowMixed, ToolEmitAndContinue:
 		// Allow all calls (with max limit)
 		if s.adapter.toolMaxCalls > 0 && len(calls) > s.adapter.toolMaxCalls {
+			s.adapter.emitPolicyLimitHit(PolicyLimitToolMaxCalls, len(calls), s.adapter.toolMaxCalls, true)
 			return calls[:s.adapter.toolMaxCalls]
 		}
 		return calls
@@ -359,29 +417,28 @@ func (s *SSEStreamAdapter) accumulateContent(state *passthroughState, chunk *SSE
 
 	if s.hasToolPattern(state.contentSeen.String()) {
 		state.toolPatternDetected = true
-		s.adapter.logger.Debug("Tool pattern detected in early content",
+		s.adapter.log(LogCategoryStream).Debug("Tool pattern detected in early content",
 			"content_length", state.contentSeen.Len(),
 			"detection_limit", earlyDetection)
 	}
 }
 
 // analyzeContentForTools performs full analysis for tool calls.
-func (s *SSEStreamAdapter) analyzeContentForTools(state *passthroughState) ([]RawFunctionCall, bool) {
+func (s *SSEStreamAdapter) analyzeContentForTools(state *passthroughState) ([]RawFunctionCall, bool, error) {
 	s.contentBuffer.WriteString(state.contentSeen.String())
 
 	fullContent := s.contentBuffer.String()
 	if fullContent == "" {
-		return nil, false
+		return nil, false, nil
 	}
 
-	extractor := NewJSONExtractor(fullContent)
-	candidates := extractor.ExtractJSONBlocks()
+	candidates := s.adapter.extractCandidates(fullContent, true)
 	if len(candidates) == 0 {
-		return nil, false
+		return nil, false, nil
 	}
 
-	calls := s.extractRawFunctionCalls(candidates)
-	return calls, len(calls) > 0
+	calls, err := s.extractRawFunctionCalls(candidates)
+	return calls, len(calls) > 0, err
 }
 
 // ProcessWithPassthrough processes an SSE stream with the option to pass through
@@ -392,6 +449,7 @@ func (s *SSEStreamAdapter) analyzeContentForTools(state *passthroughState) ([]Ra
 // whether to buffer for tool detection. Set to 0 to always buffer entire response.
 func (s *SSEStreamAdapter) ProcessWithPassthrough(ctx context.Context, earlyDetection int) error {
 	s.ctx = ctx
+	s.lastHeartbeat = time.Now()
 
 	if earlyDetection <= 0 {
 		return s.Process(ctx)
@@ -406,6 +464,9 @@ func (s *SSEStreamAdapter) ProcessWithPassthrough(ctx context.Context, earlyDete
 		default:
 		}
 		s.processChunk(state, s.reader.Data(), earlyDetection)
+		if err := s.writeHeartbeatIfDue(); err != nil {
+			return err
+		}
 	}
 
 	if err := s.reader.Err(); err != nil {
@@ -414,12 +475,15 @@ func (s *SSEStreamAdapter) ProcessWithPassthrough(ctx context.Context, earlyDete
 
 	// No tool pattern and sufficient content seen - pass through
 	if !state.toolPatternDetected && state.contentSeen.Len() > earlyDetection {
-		s.adapter.logger.Debug("No tool pattern in early content, passing through",
+		s.adapter.log(LogCategoryStream).Debug("No tool pattern in early content, passing through",
 			"content_length", state.contentSeen.Len())
 		return s.passthrough(state.rawChunks)
 	}
 
-	calls, hasCalls := s.analyzeContentForTools(state)
+	calls, hasCalls, err := s.analyzeContentForTools(state)
+	if err != nil {
+		return err
+	}
 	if !hasCalls {
 		return s.passthrough(state.rawChunks)
 	}
@@ -429,7 +493,8 @@ func (s *SSEStreamAdapter) ProcessWithPassthrough(ctx context.Context, earlyDete
 
 // hasToolPattern checks if content contains patterns indicating a tool call.
 func (s *SSEStreamAdapter) hasToolPattern(content string) bool {
-	trimmed := strings.TrimSpace(content)
+	normalized, _ := normalizeToolText(content)
+	trimmed := strings.TrimSpace(normalized)
 	if trimmed == "" {
 		return false
 	}
@@ -438,12 +503,16 @@ func (s *SSEStreamAdapter) hasToolPattern(content string) bool {
 	if strings.HasPrefix(trimmed, `{"name":`) ||
 		strings.HasPrefix(trimmed, `{"name": `) ||
 		strings.HasPrefix(trimmed, `[{"name":`) ||
-		strings.HasPrefix(trimmed, `[{"name": `) {
+		strings.HasPrefix(trimmed, `[{"name": `) ||
+		strings.HasPrefix(trimmed, `{"function_call":`) ||
+		strings.HasPrefix(trimmed, `{"function_call": `) ||
+		strings.HasPrefix(trimmed, `{"tool_calls":`) ||
+		strings.HasPrefix(trimmed, `{"tool_calls": `) {
 		return true
 	}
 
-	// Check for markdown code blocks
-	if strings.HasPrefix(trimmed, "```json") || strings.HasPrefix(trimmed, "```") {
+	// Check for markdown code blocks, fenced with backticks or tildes
+	if strings.HasPrefix(trimmed, "```") || strings.HasPrefix(trimmed, "~~~") {
 		if strings.Contains(trimmed, `"name"`) {
 			return true
 		}
@@ -454,7 +523,8 @@ func (s *SSEStreamAdapter) hasToolPattern(content string) bool {
 		return true
 	}
 
-	return false
+	// Check for JSON5 or YAML patterns when lenient parsing is enabled
+	return s.adapter.hasLenientToolCallPattern(trimmed)
 }
 
 // SSETransformResult represents the result of processing an SSE stream.
@@ -522,15 +592,17 @@ func (s *SSEStreamAdapter) ProcessToResult(ctx context.Context) (*SSETransformRe
 	}
 
 	// Extract tool calls
-	extractor := NewJSONExtractor(fullContent)
-	candidates := extractor.ExtractJSONBlocks()
+	candidates := s.adapter.extractCandidates(fullContent, true)
 
 	if len(candidates) == 0 {
 		result.Passthrough = true
 		return result, chunks, nil
 	}
 
-	calls := s.extractRawFunctionCalls(candidates)
+	calls, err := s.extractRawFunctionCalls(candidates)
+	if err != nil {
+		return nil, chunks, err
+	}
 	if len(calls) == 0 {
 		result.Passthrough = true
 		return result, chunks, nil
@@ -538,6 +610,7 @@ func (s *SSEStreamAdapter) ProcessToResult(ctx context.Context) (*SSETransformRe
 
 	// Apply policy
 	calls = s.applyToolPolicy(calls)
+	s.adapter.emitToolDetectedEvents(toFunctionCalls(calls), 0, len(fullContent), true)
 
 	result.HasToolCalls = true
 	result.ToolCalls = calls
@@ -563,7 +636,7 @@ func (s *SSEStreamAdapter) WriteToolCallsFromResult(result *SSETransformResult)
 
 		toolCalls[i] = SSEToolCall{
 			Index: i,
-			ID:    s.adapter.GenerateToolCallID(),
+			ID:    s.adapter.toolCallID(call.ID),
 			Type:  "function",
 			Function: SSEFunctionCall{
 				Name:      call.Name,
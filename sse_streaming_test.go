@@ -237,6 +237,25 @@ func TestSSEStreamAdapter_ToolInMarkdownCodeBlock(t *testing.T) {
 	assert.Equal(t, "get_weather", toolChunk.Choices[0].Delta.ToolCalls[0].Function.Name)
 }
 
+func TestSSEStreamAdapter_ToolInTildeFenceWithFullWidthBrackets(t *testing.T) {
+	content := "\ufeff~~~JSON\n" + `［｛"name": "get_weather", "parameters": ｛"city": "NYC"｝｝］` + "\n~~~"
+	events := []string{
+		createSSEChunkJSON("chatcmpl-123", "gpt-4", content, ""),
+		createSSEChunkJSON("chatcmpl-123", "gpt-4", "", "stop"),
+	}
+
+	reader := newMockSSEReader(events)
+	writer := newMockSSEWriter()
+
+	adapter := New(WithLogLevel(slog.LevelError))
+	err := adapter.NewSSEStreamAdapter(reader, writer).Process(context.Background())
+	require.NoError(t, err)
+
+	require.Len(t, writer.chunks, 2)
+	require.Len(t, writer.chunks[0].Choices[0].Delta.ToolCalls, 1)
+	assert.Equal(t, "get_weather", writer.chunks[0].Choices[0].Delta.ToolCalls[0].Function.Name)
+}
+
 // ============================================================================
 // Tool Policy Tests
 // ============================================================================
@@ -315,6 +334,39 @@ func TestSSEStreamAdapter_PolicyMaxCalls(t *testing.T) {
 	assert.Len(t, toolChunk.Choices[0].Delta.ToolCalls, 2)
 }
 
+func TestSSEStreamAdapter_ToolCallRateLimit(t *testing.T) {
+	toolJSON := `[{"name": "t1", "parameters": {}}, {"name": "t2", "parameters": {}}]`
+	adapter := New(
+		WithLogLevel(slog.LevelError),
+		WithToolPolicy(ToolDrainAll),
+		WithToolCallRateLimit(0, 3),
+	)
+	conv := NewConversation()
+	ctx := ContextWithConversation(context.Background(), conv)
+
+	process := func() *mockSSEWriter {
+		writer := newMockSSEWriter()
+		reader := newMockSSEReader([]string{createSSEChunkJSON("chatcmpl-123", "gpt-4", toolJSON, "")})
+		require.NoError(t, adapter.NewSSEStreamAdapter(reader, writer).Process(ctx))
+		return writer
+	}
+
+	first := process()
+	require.Len(t, first.chunks, 2)
+	assert.Len(t, first.chunks[0].Choices[0].Delta.ToolCalls, 2)
+
+	second := process()
+	require.Len(t, second.chunks, 2)
+	assert.Len(t, second.chunks[0].Choices[0].Delta.ToolCalls, 1)
+
+	third := process()
+	require.Len(t, third.chunks, 1)
+	assert.Empty(t, third.chunks[0].Choices[0].Delta.ToolCalls)
+	assert.Equal(t, "stop", third.chunks[0].Choices[0].FinishReason)
+	assert.True(t, third.doneWritten)
+	assert.Equal(t, 3, conv.ToolCalls())
+}
+
 // ============================================================================
 // Early Detection Tests
 // ============================================================================
@@ -948,3 +1000,88 @@ func (s *slowMockSSEReader) Next() bool {
 	time.Sleep(s.delay)
 	return s.mockSSEReader.Next()
 }
+
+func TestSSEStreamAdapter_EarlyDetectionAlternateFormats(t *testing.T) {
+	contents := map[string]string{
+		"LegacyFunctionCall": `{"function_call": {"name": "test_func", "arguments": "{}"}}`,
+		"ToolCallsWrapper":   `{"tool_calls": [{"id": "call_1", "type": "function", "function": {"name": "test_func", "arguments": "{}"}}]}`,
+	}
+
+	for name, content := range contents {
+		t.Run(name, func(t *testing.T) {
+			events := []string{
+				createSSEChunkJSON("chatcmpl-123", "gpt-4", content, ""),
+				createSSEChunkJSON("chatcmpl-123", "gpt-4", strings.Repeat(" ", 200), ""),
+				createSSEChunkJSON("chatcmpl-123", "gpt-4", "", "stop"),
+			}
+
+			reader := newMockSSEReader(events)
+			writer := newMockSSEWriter()
+
+			adapter := New(WithLogLevel(slog.LevelError))
+			sseAdapter := adapter.NewSSEStreamAdapter(reader, writer)
+
+			err := sseAdapter.ProcessWithPassthrough(context.Background(), 200)
+			require.NoError(t, err)
+
+			require.NotEmpty(t, writer.chunks)
+			require.Len(t, writer.chunks[0].Choices[0].Delta.ToolCalls, 1)
+			assert.Equal(t, "test_func", writer.chunks[0].Choices[0].Delta.ToolCalls[0].Function.Name)
+		})
+	}
+}
+
+// slowSSEReader delays each Next call to simulate a slowly generating model
+type slowSSEReader struct {
+	*mockSSEReader
+	delay time.Duration
+}
+
+func (r *slowSSEReader) Next() bool {
+	time.Sleep(r.delay)
+	return r.mockSSEReader.Next()
+}
+
+func TestSSEStreamAdapter_Heartbeat(t *testing.T) {
+	events := []string{
+		createSSEChunkJSON("chatcmpl-123", "gpt-4", `[{"name": "test_func", `, ""),
+		createSSEChunkJSON("chatcmpl-123", "gpt-4", `"parameters": `, ""),
+		createSSEChunkJSON("chatcmpl-123", "gpt-4", `{}}]`, ""),
+		createSSEChunkJSON("chatcmpl-123", "gpt-4", "", "stop"),
+	}
+
+	reader := &slowSSEReader{mockSSEReader: newMockSSEReader(events), delay: 5 * time.Millisecond}
+	writer := newMockSSEWriter()
+
+	adapter := New(WithLogLevel(slog.LevelError), WithStreamHeartbeat(time.Millisecond))
+	err := adapter.NewSSEStreamAdapter(reader, writer).Process(context.Background())
+	require.NoError(t, err)
+
+	require.NotEmpty(t, writer.rawWrites)
+	for _, raw := range writer.rawWrites {
+		assert.Equal(t, ": keep-alive\n\n", string(raw))
+	}
+	require.NotEmpty(t, writer.chunks)
+	assert.Equal(t, "test_func", writer.chunks[0].Choices[0].Delta.ToolCalls[0].Function.Name)
+}
+
+func TestSSEStreamAdapter_ParseEvents(t *testing.T) {
+	toolJSON := `{"name": "get_weather", "parameters": null}`
+	events := []string{
+		createSSEChunkJSON("chatcmpl-123", "gpt-4", toolJSON, ""),
+		createSSEChunkJSON("chatcmpl-123", "gpt-4", "", "stop"),
+	}
+
+	var parseEvents []ParseEvent
+	adapter := New(
+		WithLogLevel(slog.LevelError),
+		WithParseEventHook(func(e ParseEvent) { parseEvents = append(parseEvents, e) }))
+	sseAdapter := adapter.NewSSEStreamAdapter(newMockSSEReader(events), newMockSSEWriter())
+
+	require.NoError(t, sseAdapter.Process(context.Background()))
+	require.Len(t, parseEvents, 1)
+	assert.Equal(t, ParseEventToolDetected, parseEvents[0].Type)
+	assert.Equal(t, "get_weather", parseEvents[0].ToolName)
+	assert.Equal(t, len(toolJSON), parseEvents[0].Size)
+	assert.True(t, parseEvents[0].Streaming)
+}
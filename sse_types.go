@@ -335,6 +335,10 @@ type RawFunctionCall struct {
 
 	// Parameters contains the function arguments as raw JSON.
 	Parameters json.RawMessage `json:"parameters,omitempty"`
+
+	// ID is the tool call ID supplied by the model, if any. When empty, an ID is
+	// generated during emission.
+	ID string `json:"id,omitempty"`
 }
 
 // SSEStreamReader provides a simple interface for reading SSE streams.
@@ -0,0 +1,45 @@
+package tooladapter
+
+import (
+	"github.com/openai/openai-go/v3"
+)
+
+// maxStopSequences is the number of stop sequences accepted by the OpenAI API.
+const maxStopSequences = 4
+
+// mergeStopSequences adds the configured tool stop sequences to a request's existing
+// stop sequences. Caller-supplied sequences come first and duplicates are removed.
+// Sequences beyond the API limit are dropped with a warning.
+func (a *Adapter) mergeStopSequences(stop openai.ChatCompletionNewParamsStopUnion) openai.ChatCompletionNewParamsStopUnion {
+	if len(a.toolStopSequences) == 0 {
+		return stop
+	}
+
+	var merged []string
+	if stop.OfString.Valid() {
+		merged = append(merged, stop.OfString.Value)
+	}
+	merged = append(merged, stop.OfStringArray...)
+
+	for _, seq := range a.toolStopSequences {
+		duplicate := false
+		for _, existing := range merged {
+			if existing == seq {
+				duplicate = true
+				break
+			}
+		}
+		if duplicate {
+			continue
+		}
+		if len(merged) >= maxStopSequences {
+			a.log(LogCategoryRequest).Warn("Stop sequence limit reached, tool stop sequence not added",
+				"stop_sequence", seq,
+				"limit", maxStopSequences)
+			continue
+		}
+		merged = append(merged, seq)
+	}
+
+	return openai.ChatCompletionNewParamsStopUnion{OfStringArray: merged}
+}
@@ -0,0 +1,87 @@
+package tooladapter_test
+
+import (
+	"testing"
+
+	tooladapter "github.com/juburr/openai-tool-adapter/v3"
+	"github.com/openai/openai-go/v3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestToolStopSequences(t *testing.T) {
+	tools := []openai.ChatCompletionToolUnionParam{createMockTool("get_weather", "Get weather")}
+
+	t.Run("AddedWhenToolsPresent", func(t *testing.T) {
+		adapter := tooladapter.New(tooladapter.WithToolStopSequences("</tool_call>", "", "```"))
+		req, err := adapter.TransformCompletionsRequest(createMockRequest(tools))
+		require.NoError(t, err)
+		assert.Equal(t, []string{"</tool_call>", "```"}, req.Stop.OfStringArray)
+	})
+
+	t.Run("MergedWithCallerSequences", func(t *testing.T) {
+		adapter := tooladapter.New(tooladapter.WithToolStopSequences("```", "</tool_call>", "\n\n\n", "END"))
+		req := createMockRequest(tools)
+		req.Stop = openai.ChatCompletionNewParamsStopUnion{OfString: openai.String("```")}
+
+		transformed, err := adapter.TransformCompletionsRequest(req)
+		require.NoError(t, err)
+		assert.Equal(t, []string{"```", "</tool_call>", "\n\n\n", "END"}, transformed.Stop.OfStringArray)
+	})
+
+	t.Run("CappedAtLimit", func(t *testing.T) {
+		adapter := tooladapter.New(tooladapter.WithToolStopSequences("a", "b", "c"))
+		req := createMockRequest(tools)
+		req.Stop = openai.ChatCompletionNewParamsStopUnion{OfStringArray: []string{"x", "y"}}
+
+		transformed, err := adapter.TransformCompletionsRequest(req)
+		require.NoError(t, err)
+		assert.Equal(t, []string{"x", "y", "a", "b"}, transformed.Stop.OfStringArray)
+	})
+
+	t.Run("NotAddedWithoutTools", func(t *testing.T) {
+		adapter := tooladapter.New(tooladapter.WithToolStopSequences("```"))
+		req, err := adapter.TransformCompletionsRequest(createMockRequest(nil))
+		require.NoError(t, err)
+		assert.Empty(t, req.Stop.OfStringArray)
+	})
+
+	t.Run("ParsesCallCutOffAtClosingFence", func(t *testing.T) {
+		content := "```json\n[{\"name\": \"get_weather\", \"parameters\": {\"location\": \"Oslo\"}}]\n"
+
+		withoutStop, err := tooladapter.New().TransformCompletionsResponse(createMockCompletion(content))
+		require.NoError(t, err)
+		assert.Empty(t, withoutStop.Choices[0].Message.ToolCalls)
+
+		adapter := tooladapter.New(tooladapter.WithToolStopSequences("```"))
+		resp, err := adapter.TransformCompletionsResponse(createMockCompletion(content))
+		require.NoError(t, err)
+		require.Len(t, resp.Choices[0].Message.ToolCalls, 1)
+		assert.Equal(t, "get_weather", resp.Choices[0].Message.ToolCalls[0].Function.Name)
+
+		stream := adapter.TransformStreamingResponse(NewMockStream([]openai.ChatCompletionChunk{
+			createStreamChunk("```json\n"),
+			createStreamChunk(`[{"name": "get_weather", "parameters": null}]`),
+			createFinishChunk("stop"),
+		}))
+		var names []string
+		for stream.Next() {
+			for _, tc := range stream.Current().Choices[0].Delta.ToolCalls {
+				names = append(names, tc.Function.Name)
+			}
+		}
+		require.NoError(t, stream.Err())
+		assert.Equal(t, []string{"get_weather"}, names)
+	})
+
+	t.Run("ParsesCutOffCallAfterProseJSON", func(t *testing.T) {
+		// Valid JSON earlier in the reply must not stop the closing fence from being restored
+		content := "Using {\"units\": \"metric\"}.\n```json\n[{\"name\": \"get_weather\", \"parameters\": {\"location\": \"Oslo\"}}]\n"
+
+		adapter := tooladapter.New(tooladapter.WithToolStopSequences("</tool_call>", "```"))
+		resp, err := adapter.TransformCompletionsResponse(createMockCompletion(content))
+		require.NoError(t, err)
+		require.Len(t, resp.Choices[0].Message.ToolCalls, 1)
+		assert.Equal(t, "get_weather", resp.Choices[0].Message.ToolCalls[0].Function.Name)
+	})
+}
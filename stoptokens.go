@@ -0,0 +1,191 @@
+package tooladapter
+
+import (
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/openai/openai-go/v3"
+)
+
+// stopTokenPreset lists the stop tokens of the chat templates of a model family.
+type stopTokenPreset struct {
+	families []string // substrings of lowercase model names
+	tokens   []string
+}
+
+var (
+	stopTokenPresets = []stopTokenPreset{
+		{families: []string{"llama-3", "llama3", "llama-4", "llama4"}, tokens: []string{"<|eot_id|>", "<|eom_id|>", "<|end_of_text|>"}},
+		{families: []string{"qwen", "chatml", "hermes", "dolphin", "yi-"}, tokens: []string{"<|im_end|>", "<|endoftext|>"}},
+		{families: []string{"mistral", "mixtral", "llama-2", "llama2", "codellama"}, tokens: []string{"</s>"}},
+		{families: []string{"gemma"}, tokens: []string{"<end_of_turn>", "<eos>"}},
+		{families: []string{"phi-3", "phi3", "phi-4", "phi4"}, tokens: []string{"<|end|>", "<|endoftext|>"}},
+		{families: []string{"deepseek"}, tokens: []string{"<｜end▁of▁sentence｜>"}},
+	}
+
+	// Tokens of models matching no preset. Tokens that could be ordinary text, such
+	// as </s> for HTML strikethrough, are left out.
+	genericStopTokens = []string{"<|eot_id|>", "<|eom_id|>", "<|end_of_text|>", "<|im_end|>", "<|endoftext|>", "<|end|>", "<end_of_turn>"}
+)
+
+// DefaultStopTokens returns the chat template stop tokens that
+// WithStopTokenStripping removes from the output of model when no tokens are
+// configured. Models are matched by family name, such as "llama-3", "qwen",
+// "mistral" or "gemma", and the tokens of every matching family are returned.
+// Models of no known family get the distinctive tokens of the common templates.
+func DefaultStopTokens(model string) []string {
+	model = strings.ToLower(model)
+	var tokens []string
+	for _, preset := range stopTokenPresets {
+		for _, family := range preset.families {
+			if strings.Contains(model, family) {
+				for _, token := range preset.tokens {
+					if !slices.Contains(tokens, token) {
+						tokens = append(tokens, token)
+					}
+				}
+				break
+			}
+		}
+	}
+	if tokens == nil {
+		return append([]string(nil), genericStopTokens...)
+	}
+	return tokens
+}
+
+// stopTokensFor returns the stop tokens removed from the output of model.
+func (a *Adapter) stopTokensFor(model string) []string {
+	if a.stopTokens != nil {
+		return a.stopTokens
+	}
+	return DefaultStopTokens(model)
+}
+
+// removeStopTokens returns content without any of tokens.
+func removeStopTokens(content string, tokens []string) string {
+	for _, token := range tokens {
+		if strings.Contains(content, token) {
+			content = strings.ReplaceAll(content, token, "")
+		}
+	}
+	return content
+}
+
+// stripStopTokensFromResponse removes stop tokens from the content of every choice
+// when WithStopTokenStripping is enabled, copying the choices rather than modifying
+// resp.
+func (a *Adapter) stripStopTokensFromResponse(resp openai.ChatCompletion) openai.ChatCompletion {
+	if !a.stripStopTokens {
+		return resp
+	}
+	tokens := a.stopTokensFor(resp.Model)
+	copied := false
+	for i, choice := range resp.Choices {
+		stripped := removeStopTokens(choice.Message.Content, tokens)
+		if stripped == choice.Message.Content {
+			continue
+		}
+		if !copied {
+			resp.Choices = append([]openai.ChatCompletionChoice(nil), resp.Choices...)
+			copied = true
+		}
+		resp.Choices[i].Message.Content = strings.TrimRight(stripped, " \t\r\n")
+		a.log(LogCategoryParse).Debug("Stripped stop tokens from choice",
+			"choice_index", i,
+			"removed_bytes", len(choice.Message.Content)-len(stripped))
+	}
+	return resp
+}
+
+// stopTokenStream removes stop tokens from upstream content chunks, holding back a
+// possible partial token at the end of a chunk until the next one shows whether it
+// is one.
+type stopTokenStream struct {
+	ChatCompletionStreamInterface
+
+	adapter *Adapter
+
+	// Guards the fields below, released while reading upstream so that
+	// StreamAdapter.Snapshot can run meanwhile
+	mu      sync.Mutex
+	tokens  []string // selected by the model of the first chunk
+	pending string
+	queue   []openai.ChatCompletionChunk
+	current openai.ChatCompletionChunk
+	ended   bool
+}
+
+func newStopTokenStream(source ChatCompletionStreamInterface, a *Adapter) *stopTokenStream {
+	return &stopTokenStream{ChatCompletionStreamInterface: source, adapter: a, tokens: a.stopTokens}
+}
+
+// Next advances to the next chunk, reading upstream until one has something left to
+// deliver.
+func (t *stopTokenStream) Next() bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for len(t.queue) == 0 {
+		if t.ended {
+			return false
+		}
+		t.mu.Unlock()
+		hasNext := t.ChatCompletionStreamInterface.Next()
+		t.mu.Lock()
+		if !hasNext {
+			t.ended = true
+			if t.pending != "" {
+				chunk := openai.ChatCompletionChunk{Choices: []openai.ChatCompletionChunkChoice{{}}}
+				chunk.Choices[0].Delta.Content, t.pending = t.pending, ""
+				t.queue = append(t.queue, chunk)
+			}
+			continue
+		}
+		chunk := t.ChatCompletionStreamInterface.Current()
+		if t.tokens == nil && !isHeartbeatChunk(chunk) {
+			t.tokens = t.adapter.stopTokensFor(chunk.Model)
+		}
+		if len(chunk.Choices) == 0 || (chunk.Choices[0].Delta.Content == "" && t.pending == "") {
+			t.queue = append(t.queue, chunk)
+			continue
+		}
+		content := t.strip(chunk.Choices[0].Delta.Content)
+		choice := chunk.Choices[0]
+		if choice.FinishReason != "" {
+			// No more content follows, so a partial token is content after all
+			content, t.pending = content+t.pending, ""
+		}
+		if content == "" && choice.FinishReason == "" && choice.Delta.Role == "" && len(choice.Delta.ToolCalls) == 0 {
+			continue
+		}
+		chunk.Choices = append([]openai.ChatCompletionChunkChoice(nil), chunk.Choices...)
+		chunk.Choices[0].Delta.Content = content
+		t.queue = append(t.queue, chunk)
+	}
+	t.current, t.queue = t.queue[0], t.queue[1:]
+	return true
+}
+
+// strip removes stop tokens from content after any content held back, holding back
+// a trailing partial token.
+func (t *stopTokenStream) strip(content string) string {
+	content = removeStopTokens(t.pending+content, t.tokens)
+	t.pending = ""
+	longest := 0
+	for _, token := range t.tokens {
+		for n := min(len(token)-1, len(content)); n > longest; n-- {
+			if strings.HasPrefix(token, content[len(content)-n:]) {
+				longest = n
+				break
+			}
+		}
+	}
+	t.pending = content[len(content)-longest:]
+	return content[:len(content)-longest]
+}
+
+// Current returns the chunk returned by the last call to Next.
+func (t *stopTokenStream) Current() openai.ChatCompletionChunk {
+	return t.current
+}
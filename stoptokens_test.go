@@ -0,0 +1,98 @@
+package tooladapter_test
+
+import (
+	"strings"
+	"testing"
+
+	tooladapter "github.com/juburr/openai-tool-adapter/v3"
+	"github.com/juburr/openai-tool-adapter/v3/tooltest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDefaultStopTokens(t *testing.T) {
+	assert.Equal(t, []string{"<|eot_id|>", "<|eom_id|>", "<|end_of_text|>"}, tooladapter.DefaultStopTokens("meta-llama/Llama-3.1-8B-Instruct"))
+	assert.Equal(t, []string{"<|im_end|>", "<|endoftext|>", "</s>"}, tooladapter.DefaultStopTokens("dolphin-2.6-mistral-7b"),
+		"the tokens of every matching family")
+
+	generic := tooladapter.DefaultStopTokens("my-finetune")
+	assert.Contains(t, generic, "<|im_end|>")
+	assert.NotContains(t, generic, "</s>", "tokens that could be ordinary text are left out")
+}
+
+func TestWithStopTokenStripping_NonStreaming(t *testing.T) {
+	t.Run("ToolCall", func(t *testing.T) {
+		adapter := tooladapter.New(tooladapter.WithStopTokenStripping())
+		completion := tooltest.Completion(`{"name": "get_weather", "parameters": {"city": "Par<|im_end|>is"}}<|im_end|>`)
+		completion.Model = "Qwen/Qwen2.5-7B-Instruct"
+		resp, err := adapter.TransformCompletionsResponse(completion)
+		require.NoError(t, err)
+		require.Len(t, resp.Choices[0].Message.ToolCalls, 1)
+		assert.JSONEq(t, `{"city": "Paris"}`, resp.Choices[0].Message.ToolCalls[0].Function.Arguments)
+	})
+
+	t.Run("Content", func(t *testing.T) {
+		adapter := tooladapter.New(tooladapter.WithStopTokenStripping())
+		completion := tooltest.Completion("It is sunny.</s>")
+		completion.Model = "mistralai/Mistral-7B-Instruct-v0.3"
+		resp, err := adapter.TransformCompletionsResponse(completion)
+		require.NoError(t, err)
+		assert.Equal(t, "It is sunny.", resp.Choices[0].Message.Content)
+		assert.Equal(t, "It is sunny.</s>", completion.Choices[0].Message.Content, "the input is not modified")
+	})
+
+	t.Run("ConfiguredTokens", func(t *testing.T) {
+		adapter := tooladapter.New(tooladapter.WithStopTokenStripping("[END]"))
+		resp, err := adapter.TransformCompletionsResponse(tooltest.Completion("Done.[END]<|im_end|>"))
+		require.NoError(t, err)
+		assert.Equal(t, "Done.<|im_end|>", resp.Choices[0].Message.Content, "configured tokens replace the defaults")
+	})
+
+	t.Run("Disabled", func(t *testing.T) {
+		resp, err := tooladapter.New().TransformCompletionsResponse(tooltest.Completion("Hi<|im_end|>"))
+		require.NoError(t, err)
+		assert.Equal(t, "Hi<|im_end|>", resp.Choices[0].Message.Content)
+	})
+
+	t.Run("Config", func(t *testing.T) {
+		cfg, err := tooladapter.LoadConfig(strings.NewReader(`{"stop_tokens": ["[END]"]}`))
+		require.NoError(t, err)
+		adapter, err := tooladapter.NewFromConfig(cfg)
+		require.NoError(t, err)
+		resp, err := adapter.TransformCompletionsResponse(tooltest.Completion("Done.[END]"))
+		require.NoError(t, err)
+		assert.Equal(t, "Done.", resp.Choices[0].Message.Content)
+	})
+}
+
+func TestWithStopTokenStripping_Streaming(t *testing.T) {
+	adapter := tooladapter.New(tooladapter.WithStopTokenStripping())
+
+	t.Run("SplitToken", func(t *testing.T) {
+		first := tooltest.ContentChunk("It is sunny.<|eo")
+		first.Model = "llama-3.1-8b"
+		result := tooltest.Drain(adapter.TransformStreamingResponse(tooltest.NewMockStream(
+			first, tooltest.ContentChunk("t_id|>"), tooltest.FinishChunk("stop"))))
+		require.NoError(t, result.Err)
+		assert.Equal(t, "It is sunny.", result.Content)
+	})
+
+	t.Run("PartialTokenIsContent", func(t *testing.T) {
+		result := tooltest.Drain(adapter.TransformStreamingResponse(tooltest.NewContentStream("a <|e", "nd of it")))
+		require.NoError(t, result.Err)
+		assert.Equal(t, "a <|end of it", result.Content)
+
+		result = tooltest.Drain(adapter.TransformStreamingResponse(tooltest.NewMockStream(tooltest.ContentChunk("trailing <|"))))
+		require.NoError(t, result.Err)
+		assert.Equal(t, "trailing <|", result.Content, "held back content is delivered when the stream ends")
+	})
+
+	t.Run("ToolCall", func(t *testing.T) {
+		result := tooltest.Drain(adapter.TransformStreamingResponse(tooltest.NewContentStream(
+			`{"name": "get_weather", "parameters": {"city": "Oslo"}}<|im_`, `end|>`)))
+		require.NoError(t, result.Err)
+		require.Len(t, result.ToolCalls, 1)
+		assert.JSONEq(t, `{"city": "Oslo"}`, result.ToolCalls[0].Function.Arguments)
+		assert.NotContains(t, result.Content, "<|im_end|>")
+	})
+}
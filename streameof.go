@@ -0,0 +1,118 @@
+package tooladapter
+
+import (
+	"fmt"
+	"strings"
+)
+
+// StreamEOFPolicy controls what a ToolCollectThenStop StreamAdapter does when the
+// upstream stream ends while a tool call collection is incomplete, such as a JSON
+// array of calls that was never closed. Upstream failures are handled by
+// WithStreamErrorPolicy instead.
+type StreamEOFPolicy int
+
+const (
+	// StreamEOFEmitCalls emits the calls parsed so far: those already collected and
+	// the complete calls of the unclosed array. The incomplete call is dropped. When
+	// there are none, the buffered text is emitted as content (default).
+	StreamEOFEmitCalls StreamEOFPolicy = iota
+
+	// StreamEOFDropCalls discards the incomplete collection, emitting neither its
+	// calls nor its text.
+	StreamEOFDropCalls
+
+	// StreamEOFFlushText emits the text of the whole collection, including the
+	// calls already collected, as content instead of tool calls.
+	StreamEOFFlushText
+)
+
+// String returns a human-readable string representation of the StreamEOFPolicy.
+func (p StreamEOFPolicy) String() string {
+	switch p {
+	case StreamEOFEmitCalls:
+		return "StreamEOFEmitCalls"
+	case StreamEOFDropCalls:
+		return "StreamEOFDropCalls"
+	case StreamEOFFlushText:
+		return "StreamEOFFlushText"
+	default:
+		return fmt.Sprintf("StreamEOFPolicy(%d)", int(p))
+	}
+}
+
+// truncatedCalls returns the complete tool calls inside JSON whose outer array or
+// object was never closed, by looking for complete calls one enclosure deeper.
+func truncatedCalls(content string) []functionCall {
+	start := strings.IndexAny(content, "{[")
+	for attempt := 0; attempt < maxRepairAttempts && start >= 0; attempt++ {
+		var calls []functionCall
+		for _, candidate := range extractJSONBlocks(content[start+1:]) {
+			calls = append(calls, ExtractFunctionCalls([]string{candidate})...)
+		}
+		if len(calls) > 0 {
+			return calls
+		}
+		next := strings.IndexAny(content[start+1:], "{[")
+		if next < 0 {
+			break
+		}
+		start += 1 + next
+	}
+	return nil
+}
+
+// endIncompleteCollection applies the WithStreamEOFPolicy policy when the upstream
+// ended while a ToolCollectThenStop collection still holds buffered content that
+// is not a complete call. It returns true when it produced a chunk to emit or
+// failed the stream, and false when it did nothing or dropped the collection,
+// leaving the end of the stream to the regular handling.
+// Callers must hold s.mu.
+func (s *StreamAdapter) endIncompleteCollection() bool {
+	if s.toolCollectionState != toolStateCollecting || s.toolCallsEmitted || s.interrupted != nil ||
+		s.buffer.Len() == 0 || s.hasCompleteJSON() {
+		return false
+	}
+	content := s.buffer.String()
+	s.adapter.log(LogCategoryStream).Debug("Stream ended with an incomplete tool call collection",
+		"buffer_length", len(content),
+		"collected_tool_count", len(s.collectedTools),
+		"policy", s.adapter.streamEOFPolicy.String())
+
+	switch s.adapter.streamEOFPolicy {
+	case StreamEOFDropCalls:
+		s.adapter.log(LogCategoryStream).Warn("Dropped incomplete tool call collection at end of stream",
+			"buffer_length", len(content),
+			"dropped_tool_count", len(s.collectedTools))
+		s.collectedTools = nil
+		s.collectedText = ""
+		s.resetBuffer()
+		s.toolCollectionState = toolStateFinished
+		return false
+
+	case StreamEOFFlushText:
+		text := s.collectedText + content
+		s.collectedTools = nil
+		s.collectedText = ""
+		s.resetBuffer()
+		s.toolCollectionState = toolStateFinished
+		s.hasEmitted = true
+		s.emitBufferFlush(len(text), BufferFlushEndOfStream)
+		s.emitContentChunk(text)
+		return true
+
+	default:
+		calls, err := s.adapter.postProcessCalls(s.ctx, truncatedCalls(content))
+		if err != nil {
+			s.fail(wrapTransformError(PhaseStreaming, "process tool calls", -1, err))
+			return true
+		}
+		s.addToolsToCollection(calls)
+		if len(s.collectedTools) == 0 {
+			// Nothing parsed so far: the buffer is handled as regular content
+			return false
+		}
+		s.resetBuffer()
+		s.processCollectedTools()
+		return true
+	}
+}
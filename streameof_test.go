@@ -0,0 +1,94 @@
+package tooladapter_test
+
+import (
+	"strings"
+	"testing"
+
+	tooladapter "github.com/juburr/openai-tool-adapter/v3"
+	"github.com/juburr/openai-tool-adapter/v3/tooltest"
+	"github.com/openai/openai-go/v3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWithStreamEOFPolicy(t *testing.T) {
+	// A complete call, then an array whose second call and closing bracket never arrive
+	chunks := []string{
+		`{"name": "first", "parameters": {}}`,
+		"\n[",
+		`{"name": "second", "parameters": {}}, `,
+		`{"name": "third", "parameters": {"city": "Par`,
+	}
+	stream := func(policy tooladapter.StreamEOFPolicy, finish bool, chunks ...string) tooltest.StreamResult {
+		adapter := tooladapter.New(
+			tooladapter.WithToolPolicy(tooladapter.ToolCollectThenStop),
+			tooladapter.WithStreamEOFPolicy(policy))
+		source := make([]openai.ChatCompletionChunk, 0, len(chunks)+1)
+		for _, content := range chunks {
+			source = append(source, tooltest.ContentChunk(content))
+		}
+		if finish {
+			source = append(source, tooltest.FinishChunk("stop"))
+		}
+		return tooltest.Drain(adapter.TransformStreamingResponse(tooltest.NewMockStream(source...)))
+	}
+
+	for _, finish := range []bool{false, true} {
+		name := "WithoutFinishChunk"
+		if finish {
+			name = "WithFinishChunk"
+		}
+		t.Run(name, func(t *testing.T) {
+			t.Run("EmitCalls", func(t *testing.T) {
+				result := stream(tooladapter.StreamEOFEmitCalls, finish, chunks...)
+				require.NoError(t, result.Err)
+				assert.Equal(t, []string{"first", "second"}, result.ToolNames())
+				assert.Empty(t, result.Content)
+			})
+
+			t.Run("DropCalls", func(t *testing.T) {
+				result := stream(tooladapter.StreamEOFDropCalls, finish, chunks...)
+				require.NoError(t, result.Err)
+				assert.Empty(t, result.ToolCalls)
+				assert.Empty(t, result.Content)
+			})
+
+			t.Run("FlushText", func(t *testing.T) {
+				result := stream(tooladapter.StreamEOFFlushText, finish, chunks...)
+				require.NoError(t, result.Err)
+				assert.Empty(t, result.ToolCalls)
+				assert.Equal(t, strings.Join(chunks, ""), result.Content)
+			})
+		})
+	}
+
+	t.Run("EmitCallsFromUnclosedArray", func(t *testing.T) {
+		result := stream(tooladapter.StreamEOFEmitCalls, true, "["+chunks[2], chunks[3])
+		require.NoError(t, result.Err)
+		assert.Equal(t, []string{"second"}, result.ToolNames())
+		assert.Empty(t, result.Content)
+	})
+
+	t.Run("EmitCallsWithoutCallsFlushesText", func(t *testing.T) {
+		result := stream(tooladapter.StreamEOFEmitCalls, true, "["+chunks[3])
+		require.NoError(t, result.Err)
+		assert.Empty(t, result.ToolCalls)
+		assert.Equal(t, "["+chunks[3], result.Content)
+	})
+
+	t.Run("CompleteCollectionAlwaysEmitted", func(t *testing.T) {
+		complete := []string{`[{"name": "second", "parameters": {}}`, "]"}
+		for _, policy := range []tooladapter.StreamEOFPolicy{tooladapter.StreamEOFDropCalls, tooladapter.StreamEOFFlushText} {
+			result := stream(policy, true, complete...)
+			require.NoError(t, result.Err)
+			assert.Equal(t, []string{"second"}, result.ToolNames(), policy.String())
+		}
+	})
+
+	t.Run("Config", func(t *testing.T) {
+		cfg, err := tooladapter.LoadConfig(strings.NewReader(`{"stream_eof_policy": "flush_text"}`))
+		require.NoError(t, err)
+		assert.Equal(t, tooladapter.StreamEOFFlushText, cfg.StreamEOFPolicy)
+		assert.Equal(t, "StreamEOFPolicy(7)", tooladapter.StreamEOFPolicy(7).String())
+	})
+}
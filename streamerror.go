@@ -0,0 +1,162 @@
+package tooladapter
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// StreamErrorPolicy controls what a StreamAdapter does with a partially buffered
+// tool call when the upstream stream fails before the call is complete. With every
+// policy, Err returns a *StreamInterruptedError wrapping the upstream error.
+type StreamErrorPolicy int
+
+const (
+	// StreamErrorFlush emits the buffered content before the error (default): tool
+	// calls complete in the buffer are emitted, anything else is emitted as text.
+	StreamErrorFlush StreamErrorPolicy = iota
+
+	// StreamErrorReport discards the buffered content. It is only available as the
+	// Partial field of the *StreamInterruptedError returned by Err.
+	StreamErrorReport
+
+	// StreamErrorRepair closes the unterminated strings, objects and arrays of the
+	// buffered JSON and emits the tool calls it then contains, falling back to
+	// StreamErrorFlush. Arguments cut off mid-value are kept as received, so
+	// callers should check Err before running a repaired call.
+	StreamErrorRepair
+)
+
+// String returns a human-readable string representation of the StreamErrorPolicy.
+func (p StreamErrorPolicy) String() string {
+	switch p {
+	case StreamErrorFlush:
+		return "StreamErrorFlush"
+	case StreamErrorReport:
+		return "StreamErrorReport"
+	case StreamErrorRepair:
+		return "StreamErrorRepair"
+	default:
+		return fmt.Sprintf("StreamErrorPolicy(%d)", int(p))
+	}
+}
+
+// maxRepairAttempts bounds how many truncation points repairTruncatedJSON tries.
+const maxRepairAttempts = 32
+
+// interruptToolCall applies the WithStreamErrorPolicy policy to the buffer after
+// the upstream failed with err, and records the error returned by Err. The buffer
+// is then handled like at a regular end of stream.
+// Callers must hold s.mu.
+func (s *StreamAdapter) interruptToolCall(err error) {
+	partial := s.buffer.String()
+	s.interrupted = &StreamInterruptedError{Partial: partial, Err: err}
+
+	s.adapter.log(LogCategoryStream).Warn("Upstream stream failed with a tool call partly buffered",
+		"buffer_length", len(partial),
+		"policy", s.adapter.streamErrorPolicy.String(),
+		"error", err)
+
+	switch s.adapter.streamErrorPolicy {
+	case StreamErrorReport:
+		s.resetBuffer()
+	case StreamErrorRepair:
+		repaired, ok := repairTruncatedJSON(partial)
+		if !ok {
+			return
+		}
+		s.resetBuffer()
+		s.buffer.WriteString(repaired)
+		s.interrupted.Repaired = true
+		s.adapter.emitParseEvent(ParseEvent{
+			Type:      ParseEventRepairApplied,
+			Size:      len(repaired),
+			Streaming: true,
+			Detail:    ParseDetailTruncated,
+		})
+	}
+}
+
+// upstreamErr returns the error to report once the upstream has ended.
+// Callers must hold s.mu.
+func (s *StreamAdapter) upstreamErr() error {
+	if s.interrupted != nil {
+		return s.interrupted
+	}
+	return s.source.Err()
+}
+
+// repairTruncatedJSON completes JSON cut off by a failed stream, keeping any text
+// before it. It first closes the open string and enclosures as they are, then
+// retries without the trailing element after each of the last commas, and returns
+// the first result that is valid JSON.
+func repairTruncatedJSON(content string) (string, bool) {
+	start := strings.IndexAny(content, "{[")
+	if start < 0 {
+		return "", false
+	}
+	prefix, truncated := content[:start], content[start:]
+
+	cut := len(truncated)
+	for attempt := 0; attempt < maxRepairAttempts && cut > 0; attempt++ {
+		if closed, ok := closeTruncatedJSON(truncated[:cut]); ok && json.Valid([]byte(closed)) {
+			return prefix + closed, true
+		}
+		cut = strings.LastIndexByte(truncated[:cut], ',')
+	}
+	return "", false
+}
+
+// closeTruncatedJSON terminates an open string and closes the open objects and
+// arrays of truncated JSON. It reports false when the text is not well nested.
+func closeTruncatedJSON(truncated string) (string, bool) {
+	var stack []byte
+	inString, escaped := false, false
+	for i := 0; i < len(truncated); i++ {
+		c := truncated[i]
+		switch {
+		case escaped:
+			escaped = false
+		case inString:
+			switch c {
+			case '\\':
+				escaped = true
+			case '"':
+				inString = false
+			}
+		case c == '"':
+			inString = true
+		case c == '{':
+			stack = append(stack, '}')
+		case c == '[':
+			stack = append(stack, ']')
+		case c == '}' || c == ']':
+			if len(stack) == 0 || stack[len(stack)-1] != c {
+				return "", false
+			}
+			stack = stack[:len(stack)-1]
+		}
+	}
+
+	var b strings.Builder
+	b.WriteString(truncated)
+	if inString {
+		if escaped {
+			// Drop the dangling backslash of an incomplete escape
+			repaired := strings.TrimSuffix(b.String(), `\`)
+			b.Reset()
+			b.WriteString(repaired)
+		}
+		b.WriteByte('"')
+	}
+	closed := strings.TrimRight(b.String(), " \t\r\n,")
+	if strings.HasSuffix(closed, ":") {
+		closed += "null"
+	}
+	b.Reset()
+	b.WriteString(closed)
+	for i := len(stack) - 1; i >= 0; i-- {
+		b.WriteByte(stack[i])
+	}
+	return b.String(), true
+}
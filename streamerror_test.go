@@ -0,0 +1,104 @@
+package tooladapter_test
+
+import (
+	"errors"
+	"testing"
+
+	tooladapter "github.com/juburr/openai-tool-adapter/v3"
+	"github.com/juburr/openai-tool-adapter/v3/tooltest"
+	"github.com/openai/openai-go/v3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var errConnectionReset = errors.New("connection reset")
+
+// disconnectedStream returns a stream that fails after the given content chunks,
+// without a finish chunk.
+func disconnectedStream(contents ...string) *tooltest.MockStream {
+	chunks := make([]openai.ChatCompletionChunk, len(contents))
+	for i, content := range contents {
+		chunks[i] = tooltest.ContentChunk(content)
+	}
+	stream := tooltest.NewMockStream(chunks...)
+	stream.SetError(errConnectionReset)
+	return stream
+}
+
+func TestWithStreamErrorPolicy(t *testing.T) {
+	preface := "Let me check. "
+	partial := []string{preface, `{"name": "get_weather", `, `"parameters": {"param1": "Par`}
+
+	t.Run("FlushByDefault", func(t *testing.T) {
+		result := tooltest.Drain(tooladapter.New().TransformStreamingResponse(disconnectedStream(partial...)))
+		assert.Equal(t, preface+partial[1]+partial[2], result.Content, "partial JSON is flushed as text")
+		assert.Empty(t, result.ToolCalls)
+
+		require.ErrorIs(t, result.Err, tooladapter.ErrStreamInterrupted)
+		require.ErrorIs(t, result.Err, errConnectionReset)
+		var interrupted *tooladapter.StreamInterruptedError
+		require.ErrorAs(t, result.Err, &interrupted)
+		assert.Equal(t, partial[1]+partial[2], interrupted.Partial)
+		assert.False(t, interrupted.Repaired)
+	})
+
+	t.Run("Report", func(t *testing.T) {
+		adapter := tooladapter.New(tooladapter.WithStreamErrorPolicy(tooladapter.StreamErrorReport))
+		result := tooltest.Drain(adapter.TransformStreamingResponse(disconnectedStream(partial...)))
+		assert.Equal(t, preface, result.Content, "the partial call is not emitted")
+		var interrupted *tooladapter.StreamInterruptedError
+		require.ErrorAs(t, result.Err, &interrupted)
+		assert.Equal(t, partial[1]+partial[2], interrupted.Partial)
+	})
+
+	t.Run("Repair", func(t *testing.T) {
+		var repairs []tooladapter.ParseEvent
+		adapter := tooladapter.New(
+			tooladapter.WithStreamErrorPolicy(tooladapter.StreamErrorRepair),
+			tooladapter.WithParseEventHook(func(e tooladapter.ParseEvent) {
+				if e.Type == tooladapter.ParseEventRepairApplied {
+					repairs = append(repairs, e)
+				}
+			}))
+
+		cases := map[string]struct {
+			chunks    []string
+			arguments string
+		}{
+			"OpenString":     {partial, `{"param1": "Par"}`},
+			"DanglingKey":    {[]string{`{"name": "get_weather", "parameters": {"param1":`}, `{"param1": null}`},
+			"TrailingComma":  {[]string{`{"name": "get_weather", "parameters": {"param1": "Paris",`}, `{"param1": "Paris"}`},
+			"PartialKey":     {[]string{`[{"name": "get_weather", "parameters": {"param1": "Paris", "par`}, `{"param1": "Paris"}`},
+			"DanglingEscape": {[]string{`{"name": "get_weather", "parameters": {"param1": "Par\`}, `{"param1": "Par"}`},
+		}
+		for name, tc := range cases {
+			t.Run(name, func(t *testing.T) {
+				repairs = nil
+				result := tooltest.Drain(adapter.TransformStreamingResponse(disconnectedStream(tc.chunks...)))
+				require.Len(t, result.ToolCalls, 1)
+				assert.Equal(t, "get_weather", result.ToolCalls[0].Function.Name)
+				assert.JSONEq(t, tc.arguments, result.ToolCalls[0].Function.Arguments)
+				require.Len(t, repairs, 1)
+				assert.Equal(t, tooladapter.ParseDetailTruncated, repairs[0].Detail)
+
+				var interrupted *tooladapter.StreamInterruptedError
+				require.ErrorAs(t, result.Err, &interrupted)
+				assert.True(t, interrupted.Repaired)
+			})
+		}
+	})
+
+	t.Run("ErrorWithoutBufferUnchanged", func(t *testing.T) {
+		result := tooltest.Drain(tooladapter.New().TransformStreamingResponse(disconnectedStream("Hello")))
+		assert.Equal(t, "Hello", result.Content)
+		assert.Equal(t, errConnectionReset, result.Err)
+	})
+
+	t.Run("Config", func(t *testing.T) {
+		var policy tooladapter.StreamErrorPolicy
+		require.NoError(t, policy.UnmarshalText([]byte("repair")))
+		assert.Equal(t, tooladapter.StreamErrorRepair, policy)
+		_, err := tooladapter.NewFromConfig(tooladapter.Config{StreamErrorPolicy: tooladapter.StreamErrorPolicy(9)})
+		require.ErrorIs(t, err, tooladapter.ErrInvalidConfig)
+	})
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
+	"iter"
 	"log/slog"
 	"strings"
 	"sync"
@@ -65,6 +66,7 @@ type StreamAdapter struct {
 	mu              sync.Mutex
 	bufferLimit     int                         // Prevent unlimited buffer growth
 	pendingFinish   *openai.ChatCompletionChunk // Store finish chunk to emit after content
+	pendingSplit    *openai.ChatCompletionChunk // Finish part of a content chunk, processed next
 	processedChunks int                         // Track chunks processed for logging
 	ctx             context.Context             // Context for cancellation support
 	cancel          context.CancelFunc          // Cancel function for cleanup
@@ -73,15 +75,72 @@ type StreamAdapter struct {
 	toolCallsEmitted    bool                // Track if we've emitted tool calls
 	toolCollectionState toolCollectionState // Current collection state
 	collectedTools      []functionCall      // Tools collected so far
+	collectedText       string              // Text the collected tools were parsed from
 	contentSuppressed   bool                // Whether content emission is suppressed
 	collectionStartTime time.Time           // When tool collection started (for timeouts)
 	bytesCollected      int                 // Bytes collected for safety limits
 	stopProcessing      bool                // Flag to stop processing further chunks after tool emission
 
+	// WithToolCollectTotalBudget tracking
+	bufferingSince time.Time     // When the current buffering began; zero when not buffering
+	bufferingSpent time.Duration // Buffering time of earlier, finished buffering
+	passThrough    bool          // Budget or memory limit reached: content is emitted without detection
+
+	// WithAggregateStreamMemoryLimit accounting; memory is nil without a limit
+	memory          *streamMemory
+	accountedMemory int // Bytes of this stream counted in memory
+
 	// Collect-then-stop specific tracking - removed complex array detection
 
 	// Upstream control
 	upstreamClosed bool // true if we explicitly closed the upstream to stop generation
+
+	// Keep-alive tracking
+	lastEmitTime time.Time // when Next last returned a chunk (or the stream was created)
+
+	// Content held back while deciding whether to buffer (see WithBufferDecisionLookahead)
+	peek strings.Builder
+
+	// Start of the buffer that ToolAllowMixed already emitted when buffering began
+	mixedEmitted string
+
+	// Upstream below the content processing of the selected adapter: stop token
+	// stripping and think block splitting, which make up source
+	upstream ChatCompletionStreamInterface
+
+	// Set once the adapter of the stream's model is selected (see WithModelRules)
+	modelSelected bool
+
+	// Transcript of upstream and emitted chunks (see WithStreamRecorder), nil when disabled
+	transcript *streamTranscript
+
+	// Keys of tool calls seen in this stream (see WithToolCallDeduplication), nil when disabled
+	seenCalls map[string]struct{}
+
+	// Tool calls emitted so far, for the per-response limit of WithToolCallRateLimit
+	deliveredToolCalls int
+
+	// Set when the upstream failed with a tool call partly buffered (see WithStreamErrorPolicy)
+	interrupted *StreamInterruptedError
+
+	// Splits think blocks off the content (see WithThinkBlocks), nil when disabled
+	think *thinkStream
+
+	// Removes stop tokens from the content (see WithStopTokenStripping), nil when disabled
+	stopTokens *stopTokenStream
+
+	// Set when the first chunk names a model served natively (see WithNativePassthrough)
+	native bool
+
+	// ID, model, creation time and fingerprint of the first upstream chunk, which
+	// Accumulate and WithStrictOpenAICompatibility report for synthetic chunks
+	firstChunk openai.ChatCompletionChunk
+
+	// Rewrites emitted chunks as the OpenAI API sends them, nil when disabled
+	strict *strictStream
+
+	// Feeds tool call arguments to the WithArgumentStreaming handler, nil when disabled
+	args *argumentStreamer
 }
 
 // TransformStreamingResponse creates a stream adapter that processes tool calls.
@@ -94,23 +153,85 @@ func (a *Adapter) TransformStreamingResponse(stream ChatCompletionStreamInterfac
 // TransformStreamingResponseWithContext creates a stream adapter that processes tool calls
 // with context support for cancellation and timeouts.
 func (a *Adapter) TransformStreamingResponseWithContext(ctx context.Context, stream ChatCompletionStreamInterface) *StreamAdapter {
+	if a.streamChain != nil {
+		return a.streamChain(ctx, stream)
+	}
+	return a.transformStream(ctx, stream)
+}
+
+// transformStream implements TransformStreamingResponseWithContext inside any
+// WithStreamMiddleware.
+func (a *Adapter) transformStream(ctx context.Context, stream ChatCompletionStreamInterface) *StreamAdapter {
 	// Create a cancellable context for this stream
 	streamCtx, cancel := context.WithCancel(ctx)
 
+	// Record upstream chunks when a transcript recorder is configured
+	var transcript *streamTranscript
+	if a.streamRecorder != nil {
+		transcript = a.streamRecorder.newTranscript(a)
+		stream = &recordingStream{ChatCompletionStreamInterface: stream, transcript: transcript}
+	}
+
+	// Read upstream in the background when read-ahead, a timeout or heartbeats are
+	// configured
+	if a.streamReadAhead > 0 || a.streamIdleTimeout > 0 || a.streamMaxDuration > 0 || a.streamHeartbeat > 0 {
+		stream = newReadAheadStream(streamCtx, stream, a)
+	}
+
 	adapter := &StreamAdapter{
-		source:      stream,
-		adapter:     a,
-		bufferLimit: a.streamBufferLimit, // Configurable buffer limit to prevent memory issues
-		ctx:         streamCtx,
-		cancel:      cancel,
+		upstream:   stream,
+		memory:     a.streamMemory,
+		ctx:        streamCtx,
+		cancel:     cancel,
+		transcript: transcript,
+
+		// With model rules, the first chunk selects the adapter before the
+		// content wrappers are built
+		modelSelected: len(a.modelRules) == 0,
+
+		lastEmitTime: time.Now(),
 	}
+	adapter.useAdapter(a, stream)
 
-	a.logger.Debug("Created streaming adapter with context support", "buffer_limit_mb", adapter.bufferLimit/(1024*1024))
+	a.log(LogCategoryStream).Debug("Created streaming adapter with context support", "buffer_limit_mb", adapter.bufferLimit/(1024*1024))
 	return adapter
 }
 
-// Next advances the stream to the next chunk.
-// It buffers content chunks until complete tool calls are detected.
+// useAdapter makes a the adapter of the stream and builds the content processing of
+// its settings on top of upstream.
+func (s *StreamAdapter) useAdapter(a *Adapter, upstream ChatCompletionStreamInterface) {
+	s.adapter = a
+	s.bufferLimit = a.streamBufferLimit // Configurable buffer limit to prevent memory issues
+
+	// Remove chat template stop tokens before anything looks at the content
+	s.stopTokens = nil
+	if a.stripStopTokens {
+		s.stopTokens = newStopTokenStream(upstream, a)
+		upstream = s.stopTokens
+	}
+
+	// Split content at think block tags when think blocks are excluded from detection
+	s.think = nil
+	if a.thinkBlocks != ThinkBlocksDetect {
+		s.think = newThinkStream(upstream, a.thinkBlocks)
+		upstream = s.think
+	}
+	s.source = upstream
+
+	s.seenCalls = nil
+	if a.toolCallDedup {
+		s.seenCalls = make(map[string]struct{})
+	}
+	s.args = nil
+	if a.argumentHandler != nil {
+		s.args = newArgumentStreamer(a)
+	}
+	s.strict = nil
+	if a.strictOpenAI {
+		s.strict = newStrictStream(a)
+	}
+}
+
 // checkCancellation checks if the context is cancelled and sets appropriate state
 func (s *StreamAdapter) checkCancellation() bool {
 	select {
@@ -126,10 +247,10 @@ func (s *StreamAdapter) checkCancellation() bool {
 // handlePendingFinish processes pending finish chunk if it exists
 func (s *StreamAdapter) handlePendingFinish() bool {
 	if s.pendingFinish != nil {
-		s.currentChunk = *s.pendingFinish
+		s.currentChunk = s.continuedFinish(*s.pendingFinish)
 		s.pendingFinish = nil
 		s.done = true
-		s.adapter.logger.Debug("Emitted pending finish chunk", "total_processed_chunks", s.processedChunks)
+		s.adapter.log(LogCategoryStream).Debug("Emitted pending finish chunk", "total_processed_chunks", s.processedChunks)
 		return true
 	}
 	return false
@@ -137,31 +258,36 @@ func (s *StreamAdapter) handlePendingFinish() bool {
 
 // handleStreamEnd processes the end of the source stream
 func (s *StreamAdapter) handleStreamEnd() bool {
+	// The upstream failed mid tool call; prepare the buffer for the handling below
+	if err := s.source.Err(); err != nil && s.buffer.Len() > 0 && !s.upstreamClosed {
+		s.interruptToolCall(err)
+	}
+
+	// Emit tools collected so far, parsing any partial buffer into the collection
+	if s.adapter.toolPolicy == ToolCollectThenStop && (s.endIncompleteCollection() || s.flushCollection()) {
+		s.done = true
+		return true
+	}
+
 	if s.buffer.Len() > 0 {
-		s.adapter.logger.Debug("Stream ended with buffered content",
+		s.adapter.log(LogCategoryStream).Debug("Stream ended with buffered content",
 			"buffer_length", s.buffer.Len(),
 			"total_processed_chunks", s.processedChunks)
-		if s.adapter.toolPolicy == ToolCollectThenStop {
-			s.processBufferedContentForCollectionPhase()
-		} else {
-			s.processBufferedContent()
-		}
+		s.processBufferedContent()
 		s.done = true
 		return true
 	}
 
-	// Check if we have collected tools that haven't been emitted yet
-	if len(s.collectedTools) > 0 {
-		s.adapter.logger.Debug("Stream ended with collected tools, processing them",
-			"collected_tool_count", len(s.collectedTools))
-		s.processCollectedTools()
+	// Release content held while peeking for a tool call
+	if s.flushPeek() {
 		s.done = true
+		s.err = s.upstreamErr()
 		return true
 	}
 
 	s.done = true
-	s.err = s.source.Err()
-	s.adapter.logger.Debug("Stream ended",
+	s.err = s.upstreamErr()
+	s.adapter.log(LogCategoryStream).Debug("Stream ended",
 		"total_processed_chunks", s.processedChunks,
 		"error", s.err)
 	return false
@@ -172,7 +298,7 @@ func (s *StreamAdapter) handleContentChunk(chunk openai.ChatCompletionChunk) boo
 	// Defensive bounds check: this should not happen since isContentChunk validates,
 	// but we add it as an additional safety measure
 	if len(chunk.Choices) == 0 {
-		s.adapter.logger.Error("handleContentChunk called with no choices")
+		s.adapter.log(LogCategoryStream).Error("handleContentChunk called with no choices")
 		return false
 	}
 
@@ -193,21 +319,40 @@ func (s *StreamAdapter) handleContentChunk(chunk openai.ChatCompletionChunk) boo
 	case ToolDrainAll:
 		return s.handleDrainAllMode(chunk, content)
 
+	case ToolEmitAndContinue:
+		return s.handleEmitAndContinueMode(chunk, content)
+
 	default:
 		// Fallback to ToolStopOnFirst for unknown policies
-		s.adapter.logger.Warn("Unknown tool policy, falling back to ToolStopOnFirst",
-			"policy", s.adapter.toolPolicy)
+		s.adapter.log(LogCategoryPolicy).Warn("Unknown tool policy, falling back to ToolStopOnFirst",
+			"policy", s.adapter.toolPolicy.String())
 		return s.handleStopOnFirstMode(chunk, content)
 	}
 }
 
+// writeBuffer appends content to the tool call buffer.
+func (s *StreamAdapter) writeBuffer(content string) {
+	s.buffer.WriteString(content)
+	if s.args != nil {
+		s.args.feed(s.buffer.String())
+	}
+}
+
+// resetBuffer clears the tool call buffer.
+func (s *StreamAdapter) resetBuffer() {
+	s.buffer.Reset()
+	if s.args != nil {
+		s.args.reset()
+	}
+}
+
 // handleBufferedContent processes content when already buffering
 func (s *StreamAdapter) handleBufferedContent(content string) bool {
-	s.buffer.WriteString(content)
+	s.writeBuffer(content)
 
 	// Check if we have a complete JSON structure
 	if s.hasCompleteJSON() {
-		s.adapter.logger.Debug("Complete JSON detected in buffer",
+		s.adapter.log(LogCategoryParse).Debug("Complete JSON detected in buffer",
 			"buffer_length", s.buffer.Len(),
 			"chunk_index", s.processedChunks)
 		s.processBufferedContent()
@@ -216,9 +361,10 @@ func (s *StreamAdapter) handleBufferedContent(content string) bool {
 
 	// Safety check: prevent unlimited buffering
 	if s.buffer.Len() > s.bufferLimit {
-		s.adapter.logger.Warn("Buffer limit exceeded, processing as regular content",
+		s.adapter.log(LogCategoryLimit).Warn("Buffer limit exceeded, processing as regular content",
 			"buffer_length", s.buffer.Len(),
 			"limit", s.bufferLimit)
+		s.emitLimitExceeded(s.buffer.Len(), s.bufferLimit, ParseDetailStreamBufferLimit)
 		s.processBufferedContentAsRegular()
 		return true
 	}
@@ -228,22 +374,139 @@ func (s *StreamAdapter) handleBufferedContent(content string) bool {
 
 // handleFinishChunk processes finish chunks with buffer handling
 func (s *StreamAdapter) handleFinishChunk(chunk openai.ChatCompletionChunk) bool {
+	// Emit collected tools before the finish chunk; providers such as llama.cpp
+	// send the finish reason in a chunk of its own after the last content
+	if s.adapter.toolPolicy == ToolCollectThenStop && (s.endIncompleteCollection() || s.flushCollection()) {
+		s.pendingFinish = &chunk
+		return true
+	}
 	// Process any remaining buffer before the finish chunk
 	if s.buffer.Len() > 0 {
-		s.adapter.logger.Debug("Processing remaining buffer before finish chunk",
+		s.adapter.log(LogCategoryStream).Debug("Processing remaining buffer before finish chunk",
 			"buffer_length", s.buffer.Len())
 		s.processBufferedContent()
 		// Store the finish chunk to emit after the content
 		s.pendingFinish = &chunk
 		return true
 	}
+	// Release content held while peeking, then emit the finish chunk
+	if s.flushPeek() {
+		s.pendingFinish = &chunk
+		return true
+	}
 	// No buffer - pass through finish chunk directly
-	s.currentChunk = chunk
+	s.currentChunk = s.continuedFinish(chunk)
 	s.done = true
 	return true
 }
 
+// continuedFinish reports "tool_calls" as the finish reason of a ToolEmitAndContinue
+// stream that emitted tool calls and stopped normally, since its tool call chunks
+// carry no finish reason of their own.
+func (s *StreamAdapter) continuedFinish(chunk openai.ChatCompletionChunk) openai.ChatCompletionChunk {
+	if s.adapter.toolPolicy != ToolEmitAndContinue || !s.toolCallsEmitted ||
+		len(chunk.Choices) == 0 || chunk.Choices[0].FinishReason != "stop" {
+		return chunk
+	}
+	finish := chunk
+	finish.Choices = append([]openai.ChatCompletionChunkChoice(nil), chunk.Choices...)
+	finish.Choices[0].FinishReason = "tool_calls"
+	return finish
+}
+
+// Next advances the stream to the next chunk.
+// It buffers content chunks until complete tool calls are detected.
 func (s *StreamAdapter) Next() bool {
+	for {
+		// Chunks queued by WithStrictOpenAICompatibility are returned first
+		s.mu.Lock()
+		if s.strict != nil && s.strict.pop(&s.currentChunk) {
+			s.recordEmitted()
+			s.mu.Unlock()
+			return true
+		}
+		s.mu.Unlock()
+
+		more := s.next()
+		s.mu.Lock()
+		s.accountMemory()
+		s.mu.Unlock()
+		if !more {
+			return false
+		}
+
+		// A response policy may have failed the stream while producing this chunk
+		s.mu.Lock()
+		if s.err != nil {
+			s.mu.Unlock()
+			return false
+		}
+		if s.strict != nil {
+			s.strict.push(s.currentChunk, s.firstChunk)
+			s.mu.Unlock()
+			continue
+		}
+		s.recordEmitted()
+		s.mu.Unlock()
+		return true
+	}
+}
+
+// recordEmitted notes that the current chunk is returned to the caller.
+// Callers must hold s.mu.
+func (s *StreamAdapter) recordEmitted() {
+	s.lastEmitTime = time.Now()
+	if s.transcript != nil {
+		s.transcript.record(transcriptEmitted, &s.currentChunk, nil)
+	}
+}
+
+// emitHeartbeatIfDue replaces a withheld chunk with an empty keep-alive chunk when
+// the heartbeat interval has elapsed since the last emitted chunk.
+// Callers must hold s.mu.
+func (s *StreamAdapter) emitHeartbeatIfDue(chunk openai.ChatCompletionChunk) bool {
+	if s.adapter.streamHeartbeat <= 0 || s.done || time.Since(s.lastEmitTime) < s.adapter.streamHeartbeat {
+		return false
+	}
+
+	heartbeat := openai.ChatCompletionChunk{
+		ID:      chunk.ID,
+		Object:  chunk.Object,
+		Created: chunk.Created,
+		Model:   chunk.Model,
+	}
+	if len(chunk.Choices) > 0 {
+		heartbeat.Choices = []openai.ChatCompletionChunkChoice{{Index: chunk.Choices[0].Index}}
+	}
+	s.currentChunk = heartbeat
+
+	s.adapter.log(LogCategoryStream).Debug("Emitted keep-alive chunk while buffering",
+		"buffer_length", s.buffer.Len(),
+		"chunk_index", s.processedChunks)
+	return true
+}
+
+// heartbeatTemplate returns a chunk with the metadata of the first upstream chunk,
+// for keep-alives sent while upstream is silent.
+// Callers must hold s.mu.
+func (s *StreamAdapter) heartbeatTemplate() openai.ChatCompletionChunk {
+	chunk := s.firstChunk
+	chunk.Choices = []openai.ChatCompletionChunkChoice{{}}
+	return chunk
+}
+
+// fail terminates the stream with err, discarding any buffered content.
+// Callers must hold s.mu.
+func (s *StreamAdapter) fail(err error) {
+	s.err = err
+	s.done = true
+	s.pendingFinish = nil
+	s.resetBuffer()
+	s.adapter.log(LogCategoryPolicy).Error("Streaming terminated by response policy", "error", err)
+}
+
+// next implements Next without the post-chunk failure check.
+func (s *StreamAdapter) next() bool {
 	// Fast state checks under lock
 	s.mu.Lock()
 	if s.done {
@@ -263,6 +526,14 @@ func (s *StreamAdapter) Next() bool {
 
 	// If we've set the stop processing flag, drain the stream until a finish chunk arrives
 	if stopProcessing {
+		s.mu.Lock()
+		if chunk, split := s.takeSplitFinish(); split {
+			s.currentChunk = chunk
+			s.done = true
+			s.mu.Unlock()
+			return true
+		}
+		s.mu.Unlock()
 		for s.source.Next() {
 			chunk := s.source.Current()
 			if s.isFinishChunk(chunk) {
@@ -291,34 +562,116 @@ func (s *StreamAdapter) Next() bool {
 			return false
 		}
 
-		// Block for next chunk WITHOUT holding the mutex to avoid deadlocks with Close()
-		hasNext := s.source.Next()
+		// A finish reason split off the previous content chunk is handled before
+		// reading further upstream
+		s.mu.Lock()
+		chunk, split := s.takeSplitFinish()
+		s.mu.Unlock()
 
-		// Check for cancellation after unblocking
-		if s.ctx.Err() != nil {
-			s.mu.Lock()
-			s.err = s.ctx.Err()
-			s.done = true
-			s.mu.Unlock()
-			return false
+		if !split && !s.modelSelected {
+			s.selectFirstChunkModel()
 		}
 
-		// Handle stream end
-		if !hasNext {
-			s.mu.Lock()
-			result := s.handleStreamEnd()
-			s.mu.Unlock()
-			return result
-		}
+		if !split {
+			// Block for next chunk WITHOUT holding the mutex to avoid deadlocks with Close()
+			hasNext := s.source.Next()
+
+			// Check for cancellation after unblocking
+			if s.ctx.Err() != nil {
+				s.mu.Lock()
+				s.err = s.ctx.Err()
+				s.done = true
+				s.mu.Unlock()
+				return false
+			}
 
-		chunk := s.source.Current()
+			// Handle stream end
+			if !hasNext {
+				s.mu.Lock()
+				result := s.handleStreamEnd()
+				s.mu.Unlock()
+				return result
+			}
+
+			chunk = s.source.Current()
+
+			// Upstream stayed silent for the heartbeat interval
+			if isHeartbeatChunk(chunk) {
+				s.mu.Lock()
+				if !s.native && s.isWithholding() && s.emitHeartbeatIfDue(s.heartbeatTemplate()) {
+					s.mu.Unlock()
+					return true
+				}
+				s.mu.Unlock()
+				continue
+			}
+		}
 
 		// Process the chunk under lock
 		s.mu.Lock()
-		s.processedChunks++
+		if !split {
+			s.processedChunks++
+		}
+
+		// The first chunk names the model, which decides whether the stream is
+		// passed through for native function calling
+		if s.processedChunks == 1 && !split {
+			s.firstChunk = openai.ChatCompletionChunk{
+				ID:                chunk.ID,
+				Model:             chunk.Model,
+				Created:           chunk.Created,
+				SystemFingerprint: chunk.SystemFingerprint,
+				ServiceTier:       chunk.ServiceTier,
+			}
+		}
+		if s.processedChunks == 1 && !split && s.adapter.usesNativeTools(chunk.Model) {
+			s.native = true
+			s.adapter.log(LogCategoryStream).Debug("Model supports tools natively, passing stream through",
+				"model", chunk.Model)
+		}
+		if s.native {
+			s.currentChunk = chunk
+			s.mu.Unlock()
+			return true
+		}
+
+		// Providers such as vLLM send the last content together with the finish
+		// reason; handle the content first so buffered tool calls are flushed
+		if s.isContentChunk(chunk) && s.isFinishChunk(chunk) {
+			chunk = s.splitFinish(chunk)
+		}
+
+		// Think blocks bypass tool call detection
+		if s.think != nil && s.think.thinking() && !split {
+			if s.emitsAfterToolCalls() {
+				s.currentChunk = chunk
+				s.mu.Unlock()
+				return true
+			}
+			s.mu.Unlock()
+			continue
+		}
 
 		if s.isContentChunk(chunk) {
-			if result := s.handleContentChunk(chunk); result {
+			// Once the collection budget is spent, content passes through untouched
+			if s.passThrough {
+				s.currentChunk = chunk
+				s.mu.Unlock()
+				return true
+			}
+			if s.exhaustCollectBudget(chunk) || s.exceedMemoryLimit(chunk) {
+				s.mu.Unlock()
+				return true
+			}
+			result := s.handleContentChunk(chunk)
+			s.trackBuffering()
+			s.accountMemory()
+			if result {
+				s.mu.Unlock()
+				return true
+			}
+			// Chunk was withheld; keep the consumer alive if it has waited too long
+			if s.emitHeartbeatIfDue(chunk) {
 				s.mu.Unlock()
 				return true
 			}
@@ -333,6 +686,17 @@ func (s *StreamAdapter) Next() bool {
 			return result
 		}
 
+		// Logprobs-only and empty chunks describe content that is being withheld,
+		// so they are withheld with it
+		if s.isWithholding() && isAnnotationChunk(chunk) {
+			if s.emitHeartbeatIfDue(chunk) {
+				s.mu.Unlock()
+				return true
+			}
+			s.mu.Unlock()
+			continue
+		}
+
 		// Pass through non-content chunks (like role assignments, etc.)
 		s.currentChunk = chunk
 		s.mu.Unlock()
@@ -344,7 +708,8 @@ func (s *StreamAdapter) Next() bool {
 // This uses a fast heuristic to minimize unnecessary buffering while catching
 // tool calls that may appear after explanatory text (when early detection is enabled)
 func (s *StreamAdapter) shouldStartBuffering(content string) bool {
-	trimmed := strings.TrimSpace(content)
+	normalized, _ := normalizeToolText(content)
+	trimmed := strings.TrimSpace(normalized)
 	if trimmed == "" {
 		return false
 	}
@@ -364,6 +729,16 @@ func (s *StreamAdapter) shouldStartBuffering(content string) bool {
 		return true
 	}
 
+	// Check for fenced code blocks calling text tools or code block tools
+	if s.adapter.hasFencedToolCallPattern(trimmed) {
+		return true
+	}
+
+	// Check for JSON5 or YAML tool calls when lenient parsing is enabled
+	if s.adapter.hasLenientToolCallPattern(trimmed) {
+		return true
+	}
+
 	// Check for tool calls within early detection lookahead range
 	if s.hasEarlyDetectionToolCall(trimmed) {
 		return true
@@ -373,17 +748,105 @@ func (s *StreamAdapter) shouldStartBuffering(content string) bool {
 	return false
 }
 
+// decideBuffering decides whether a content chunk starts a potential tool call.
+// It returns the content to start buffering with, or the content to emit as regular
+// text. With a buffer decision lookahead configured, chunks that begin with a JSON
+// opener but do not yet match a tool call pattern are held (holding is true) until a
+// tool key appears or the lookahead is exhausted, at which point the held content is
+// released as regular text.
+func (s *StreamAdapter) decideBuffering(content string) (start, release string, holding bool) {
+	lookahead := s.adapter.bufferDecisionLookahead
+	if lookahead <= 0 {
+		if s.shouldStartBuffering(content) {
+			return content, "", false
+		}
+		return "", content, false
+	}
+
+	if s.peek.Len() == 0 {
+		if s.shouldStartBuffering(content) {
+			return content, "", false
+		}
+		normalized, _ := normalizeToolText(content)
+		trimmed := strings.TrimSpace(normalized)
+		if trimmed == "" || !strings.ContainsAny(trimmed[:1], "{[`~") {
+			return "", content, false
+		}
+	}
+
+	s.peek.WriteString(content)
+	peeked := s.peek.String()
+	if s.shouldStartBuffering(peeked) || hasToolCallKey(peeked) {
+		s.peek.Reset()
+		s.adapter.log(LogCategoryStream).Debug("Tool call pattern found within buffer decision lookahead",
+			"peeked_length", len(peeked),
+			"lookahead", lookahead)
+		return peeked, "", false
+	}
+	if len(peeked) >= lookahead {
+		s.peek.Reset()
+		s.adapter.log(LogCategoryStream).Debug("No tool call pattern within buffer decision lookahead, releasing content",
+			"peeked_length", len(peeked),
+			"lookahead", lookahead)
+		return "", peeked, false
+	}
+	return "", "", true
+}
+
+// hasToolCallKey reports whether content contains a key used by a supported tool
+// call shape, allowing for whitespace the immediate patterns do not.
+func hasToolCallKey(content string) bool {
+	return strings.Contains(content, `"name"`) ||
+		strings.Contains(content, `"function_call"`) ||
+		strings.Contains(content, `"tool_calls"`)
+}
+
+// emitReleasedContent emits content as regular text using chunk's metadata. When the
+// content is exactly the chunk's own content, the chunk is passed through unchanged.
+func (s *StreamAdapter) emitReleasedContent(chunk openai.ChatCompletionChunk, content string) {
+	if len(chunk.Choices) > 0 && chunk.Choices[0].Delta.Content == content {
+		s.currentChunk = chunk
+		return
+	}
+	released := chunk
+	released.Choices = append([]openai.ChatCompletionChunkChoice(nil), chunk.Choices...)
+	if len(released.Choices) == 0 {
+		released.Choices = []openai.ChatCompletionChunkChoice{{}}
+	}
+	released.Choices[0].Delta.Content = content
+	s.currentChunk = released
+}
+
+// flushPeek emits any content held by the buffer decision lookahead as regular text.
+// It returns false when nothing was held.
+func (s *StreamAdapter) flushPeek() bool {
+	if s.peek.Len() == 0 {
+		return false
+	}
+	content := s.peek.String()
+	s.peek.Reset()
+	s.adapter.log(LogCategoryStream).Debug("Releasing peeked content at end of stream",
+		"content_length", len(content))
+	s.emitContentChunk(content)
+	return true
+}
+
 // hasImmediateToolCallPattern checks for direct function call patterns at the start
 func (s *StreamAdapter) hasImmediateToolCallPattern(trimmed string) bool {
 	return strings.HasPrefix(trimmed, `[{"name":`) ||
 		strings.HasPrefix(trimmed, `[{"name": `) ||
 		strings.HasPrefix(trimmed, `{"name":`) ||
-		strings.HasPrefix(trimmed, `{"name": `)
+		strings.HasPrefix(trimmed, `{"name": `) ||
+		strings.HasPrefix(trimmed, `{"function_call":`) ||
+		strings.HasPrefix(trimmed, `{"function_call": `) ||
+		strings.HasPrefix(trimmed, `{"tool_calls":`) ||
+		strings.HasPrefix(trimmed, `{"tool_calls": `)
 }
 
-// hasMarkdownToolCallPattern checks for markdown code blocks with tool calls
+// hasMarkdownToolCallPattern checks for markdown code blocks with tool calls,
+// fenced with backticks or tildes
 func (s *StreamAdapter) hasMarkdownToolCallPattern(trimmed string) bool {
-	if !strings.HasPrefix(trimmed, "```json") && !strings.HasPrefix(trimmed, "```") {
+	if !strings.HasPrefix(trimmed, "```") && !strings.HasPrefix(trimmed, "~~~") {
 		return false
 	}
 	// Look for function call indicators in the first part
@@ -413,7 +876,28 @@ func (s *StreamAdapter) hasEarlyDetectionToolCall(trimmed string) bool {
 	return strings.Contains(searchText, `{"name":`) ||
 		strings.Contains(searchText, `{"name": `) ||
 		strings.Contains(searchText, `[{"name":`) ||
-		strings.Contains(searchText, `[{"name": `)
+		strings.Contains(searchText, `[{"name": `) ||
+		strings.Contains(searchText, `{"function_call":`) ||
+		strings.Contains(searchText, `{"tool_calls":`)
+}
+
+// emitsAfterToolCalls reports whether content is still emitted, which it is not
+// once a policy other than ToolAllowMixed or ToolEmitAndContinue emitted tool calls.
+// Callers must hold s.mu.
+func (s *StreamAdapter) emitsAfterToolCalls() bool {
+	return !s.toolCallsEmitted || s.adapter.toolPolicy == ToolAllowMixed || s.adapter.toolPolicy == ToolEmitAndContinue
+}
+
+// Reasoning returns the think block content read from the stream so far, without
+// tags, when WithThinkBlocks excludes think blocks from detection. It is empty
+// with ThinkBlocksDetect.
+func (s *StreamAdapter) Reasoning() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.think == nil {
+		return ""
+	}
+	return s.think.reasoning()
 }
 
 // Current returns the current chunk in the stream.
@@ -430,6 +914,9 @@ func (s *StreamAdapter) Err() error {
 	if s.err != nil {
 		return s.err
 	}
+	if s.interrupted != nil {
+		return s.interrupted
+	}
 	// Shield callers from context cancellation when we intentionally closed upstream
 	if err := s.source.Err(); err != nil {
 		if s.upstreamClosed && (errors.Is(err, context.Canceled)) {
@@ -449,6 +936,14 @@ func (s *StreamAdapter) Close() error {
 	finalBufferLength := s.buffer.Len()
 	totalProcessedChunks := s.processedChunks
 
+	// Fail argument readers still waiting on a tool call
+	if s.args != nil {
+		s.args.reset()
+	}
+
+	// Stop counting what the stream retains against the adapter-wide limit
+	s.releaseMemory()
+
 	// Cancel the context to clean up any waiting operations
 	if s.cancel != nil {
 		s.cancel()
@@ -456,7 +951,7 @@ func (s *StreamAdapter) Close() error {
 	}
 
 	// Log while still holding the lock to ensure consistent state
-	s.adapter.logger.Debug("Closing streaming adapter",
+	s.adapter.log(LogCategoryStream).Debug("Closing streaming adapter",
 		"total_processed_chunks", totalProcessedChunks,
 		"final_buffer_length", finalBufferLength)
 
@@ -464,6 +959,34 @@ func (s *StreamAdapter) Close() error {
 	return s.source.Close()
 }
 
+// All returns an iterator over the chunks of the stream, as an alternative to calling
+// Next, Current and Err:
+//
+//	for chunk, err := range stream.All() {
+//	    if err != nil {
+//	        return err
+//	    }
+//	    // use chunk
+//	}
+//
+// A stream error is yielded once, with an empty chunk, after the last chunk. The
+// stream is closed when the loop ends, also when it is left early with break or
+// return; errors from closing it are not reported. The stream can be ranged over
+// once.
+func (s *StreamAdapter) All() iter.Seq2[openai.ChatCompletionChunk, error] {
+	return func(yield func(openai.ChatCompletionChunk, error) bool) {
+		defer func() { _ = s.Close() }()
+		for s.Next() {
+			if !yield(s.Current(), nil) {
+				return
+			}
+		}
+		if err := s.Err(); err != nil {
+			yield(openai.ChatCompletionChunk{}, err)
+		}
+	}
+}
+
 // isContentChunk checks if a chunk contains message content.
 func (s *StreamAdapter) isContentChunk(chunk openai.ChatCompletionChunk) bool {
 	return len(chunk.Choices) > 0 &&
@@ -476,6 +999,65 @@ func (s *StreamAdapter) isFinishChunk(chunk openai.ChatCompletionChunk) bool {
 		chunk.Choices[0].FinishReason != ""
 }
 
+// splitFinish returns the content part of a chunk carrying both content and a
+// finish reason, and stores the finish part to be processed as the next chunk.
+// Callers must hold s.mu.
+func (s *StreamAdapter) splitFinish(chunk openai.ChatCompletionChunk) openai.ChatCompletionChunk {
+	content := chunk
+	content.Choices = append([]openai.ChatCompletionChunkChoice(nil), chunk.Choices...)
+	content.Choices[0].FinishReason = ""
+
+	finish := chunk
+	finish.Choices = append([]openai.ChatCompletionChunkChoice(nil), chunk.Choices...)
+	finish.Choices[0].Delta = openai.ChatCompletionChunkChoiceDelta{}
+	finish.Choices[0].Logprobs = openai.ChatCompletionChunkChoiceLogprobs{}
+	s.pendingSplit = &finish
+
+	return content
+}
+
+// takeSplitFinish returns and clears the finish part stored by splitFinish.
+// Callers must hold s.mu.
+func (s *StreamAdapter) takeSplitFinish() (openai.ChatCompletionChunk, bool) {
+	if s.pendingSplit == nil {
+		return openai.ChatCompletionChunk{}, false
+	}
+	chunk := *s.pendingSplit
+	s.pendingSplit = nil
+	return chunk, true
+}
+
+// isAnnotationChunk reports whether a chunk carries nothing but data about content,
+// such as logprobs or provider-specific fields like llama.cpp timings. Chunks with a
+// role, content, tool calls, refusal or finish reason are not annotations, nor are
+// chunks without choices, which carry usage.
+func isAnnotationChunk(chunk openai.ChatCompletionChunk) bool {
+	if len(chunk.Choices) == 0 {
+		return false
+	}
+	for _, choice := range chunk.Choices {
+		delta := choice.Delta
+		if delta.Role != "" || delta.Content != "" || delta.Refusal != "" ||
+			len(delta.ToolCalls) > 0 || choice.FinishReason != "" {
+			return false
+		}
+	}
+	return true
+}
+
+// isWithholding reports whether content is currently held back or discarded rather
+// than emitted. In mixed mode content is always emitted, and ToolEmitAndContinue
+// only holds back content that may be a tool call.
+func (s *StreamAdapter) isWithholding() bool {
+	switch s.adapter.toolPolicy {
+	case ToolAllowMixed:
+		return false
+	case ToolEmitAndContinue:
+		return s.buffer.Len() > 0 || s.peek.Len() > 0
+	}
+	return s.buffer.Len() > 0 || s.peek.Len() > 0 || s.contentSuppressed || s.toolCallsEmitted
+}
+
 // hasCompleteJSON checks if the buffer contains complete JSON using the state machine parser
 func (s *StreamAdapter) hasCompleteJSON() bool {
 	content := s.buffer.String()
@@ -484,7 +1066,8 @@ func (s *StreamAdapter) hasCompleteJSON() bool {
 	}
 
 	// Use the state machine parser to check for complete JSON structures
-	return HasCompleteJSON(content)
+	content = s.adapter.prefilled(content)
+	return HasCompleteJSON(content) || s.adapter.hasCompleteLenientJSON(content) || s.adapter.hasCompleteFencedCall(content)
 }
 
 // processBufferedContent processes the buffered content to extract tool calls
@@ -499,21 +1082,49 @@ func (s *StreamAdapter) processBufferedContent() {
 
 	// Use state machine parser to extract JSON blocks
 	jsonStartTime := time.Now()
-	extractor := NewJSONExtractor(content)
-	candidates := extractor.ExtractJSONBlocks()
+	candidates := s.adapter.extractCandidates(content, true)
 	jsonParsingTime := time.Since(jsonStartTime)
 
 	// Extract function calls from candidates
 	extractionStartTime := time.Now()
 	calls, _ := ExtractFunctionCallsDetailed(candidates)
+	calls, err := s.adapter.postProcessCalls(s.ctx, calls)
 	extractionTime := time.Since(extractionStartTime)
+	if err != nil {
+		s.fail(wrapTransformError(PhaseStreaming, "process tool calls", -1, err))
+		return
+	}
+	parsedCount := len(calls)
+	calls, duplicates := s.adapter.deduplicateCalls(calls, s.seenCalls)
 	totalDuration := time.Since(startTime)
 
+	// Every call repeats one already emitted in this stream
+	if parsedCount > 0 && len(calls) == 0 {
+		s.adapter.log(LogCategoryStream).Debug("Buffered content only repeated earlier tool calls, discarding it",
+			"buffer_length", len(content),
+			"duplicates_removed", duplicates)
+		s.emitContentChunk("")
+		s.resetBuffer()
+		return
+	}
+
 	// Emit tool calls if found, otherwise emit as content
+	// A ToolEmitAndContinue stream that already emitted its maximum drops later calls
+	if len(calls) > 0 && s.remainingToolCalls() == 0 {
+		s.adapter.log(LogCategoryLimit).Debug("Tool call limit reached, discarding later tool calls",
+			"discarded_calls", len(calls),
+			"delivered_calls", s.deliveredToolCalls)
+		s.adapter.emitPolicyLimitHit(PolicyLimitToolMaxCalls, s.deliveredToolCalls+len(calls), s.adapter.maxToolCalls(s.ctx), true)
+		s.emitContentChunk("")
+		s.resetBuffer()
+		return
+	}
+
 	if len(calls) > 0 {
 		// Enforce global max cap as a safety
-		if s.adapter.toolMaxCalls > 0 && len(calls) > s.adapter.toolMaxCalls {
-			calls = calls[:s.adapter.toolMaxCalls]
+		if remaining := s.remainingToolCalls(); remaining > 0 && len(calls) > remaining {
+			s.adapter.emitPolicyLimitHit(PolicyLimitToolMaxCalls, len(calls), remaining, true)
+			calls = calls[:remaining]
 		}
 		// Extract function names for logging and metrics
 		functionNames := make([]string, len(calls))
@@ -543,15 +1154,19 @@ func (s *StreamAdapter) processBufferedContent() {
 			logAttrs = append(logAttrs, "function_arguments", args)
 		}
 
-		s.adapter.logger.Info("Streaming: detected and converted function calls", logAttrs...)
+		s.adapter.log(LogCategoryParse).Info("Streaming: detected and converted function calls", logAttrs...)
 
 		// Emit metrics event for streaming function call detection
+		s.adapter.emitToolDetectedEvents(calls, 0, len(content), true)
+
 		s.adapter.emitMetric(FunctionCallDetectionData{
-			FunctionCount:  len(calls),
-			FunctionNames:  functionNames,
-			ContentLength:  len(content),
-			JSONCandidates: len(candidates),
-			Streaming:      true, // This is the streaming path
+			FunctionCount:     len(calls),
+			FunctionNames:     functionNames,
+			ContentLength:     len(content),
+			JSONCandidates:    len(candidates),
+			Streaming:         true, // This is the streaming path
+			DuplicatesRemoved: duplicates,
+			ContentTokens:     s.adapter.countTokens(content),
 			Performance: PerformanceMetrics{
 				ProcessingDuration: totalDuration,
 				SubOperations: map[string]time.Duration{
@@ -563,14 +1178,29 @@ func (s *StreamAdapter) processBufferedContent() {
 
 		s.emitToolCallChunk(calls)
 	} else {
-		s.adapter.logger.Debug("Buffered content did not contain valid function calls, emitting as regular content",
+		s.adapter.log(LogCategoryParse).Debug("Buffered content did not contain valid function calls, emitting as regular content",
 			"buffer_length", len(content),
 			"candidate_count", len(candidates))
-		s.emitContentChunk(content)
+		s.emitBufferFlush(len(content), BufferFlushNoToolCall)
+		s.emitContentChunk(s.unemittedContent(content))
 	}
 
 	// Clear the buffer after processing
-	s.buffer.Reset()
+	s.resetBuffer()
+}
+
+// remainingToolCalls returns how many more tool calls the stream may emit, or -1
+// when unlimited. Only ToolEmitAndContinue emits tool calls more than once, so it
+// alone counts the calls already delivered against the limit.
+func (s *StreamAdapter) remainingToolCalls() int {
+	maxCalls := s.adapter.maxToolCalls(s.ctx)
+	if maxCalls <= 0 {
+		return -1
+	}
+	if s.adapter.toolPolicy == ToolEmitAndContinue {
+		return max(maxCalls-s.deliveredToolCalls, 0)
+	}
+	return maxCalls
 }
 
 // processBufferedContentAsRegular emits buffered content as regular text (fallback)
@@ -578,13 +1208,22 @@ func (s *StreamAdapter) processBufferedContentAsRegular() {
 	content := s.buffer.String()
 	if content != "" {
 		s.hasEmitted = true
-		s.adapter.logger.Debug("Processing buffered content as regular content (fallback)",
+		s.adapter.log(LogCategoryStream).Debug("Processing buffered content as regular content (fallback)",
 			"content_length", len(content))
-		s.emitContentChunk(content)
-		s.buffer.Reset()
+		s.emitBufferFlush(len(content), BufferFlushLimit)
+		s.emitContentChunk(s.unemittedContent(content))
+		s.resetBuffer()
 	}
 }
 
+// unemittedContent returns the part of buffered content that was not emitted yet;
+// ToolAllowMixed emits the chunk that started the buffer right away.
+func (s *StreamAdapter) unemittedContent(content string) string {
+	content = strings.TrimPrefix(content, s.mixedEmitted)
+	s.mixedEmitted = ""
+	return content
+}
+
 // emitContentChunk creates a content chunk.
 func (s *StreamAdapter) emitContentChunk(content string) {
 	s.currentChunk = openai.ChatCompletionChunk{
@@ -611,17 +1250,31 @@ func (s *StreamAdapter) emitContentChunk(content string) {
 func (s *StreamAdapter) emitToolCallChunk(calls []functionCall) {
 	// Validate input
 	if len(calls) == 0 {
-		s.adapter.logger.Warn("Attempted to emit tool call chunk with no calls")
+		s.adapter.log(LogCategoryStream).Warn("Attempted to emit tool call chunk with no calls")
 		s.emitContentChunk("") // Emit empty content as fallback
 		return
 	}
 
+	// Drop calls beyond WithToolCallRateLimit; suppressed calls are not emitted at all
+	names := make([]string, len(calls))
+	for i, call := range calls {
+		names[i] = call.Name
+	}
+	firstIndex := s.deliveredToolCalls
+	allowed := s.adapter.allowToolCalls(s.ctx, names, s.deliveredToolCalls, true)
+	s.deliveredToolCalls += allowed
+	if allowed == 0 {
+		s.emitContentChunk("")
+		return
+	}
+	calls = calls[:allowed]
+
 	// Create tool calls with bounds checking
 	toolCalls := make([]openai.ChatCompletionChunkChoiceDeltaToolCall, 0, len(calls))
 	for i, call := range calls {
 		// Skip invalid calls
 		if call.Name == "" {
-			s.adapter.logger.Warn("Skipping invalid function call with empty name", "call_index", i)
+			s.adapter.log(LogCategoryStream).Warn("Skipping invalid function call with empty name", "call_index", i)
 			continue
 		}
 
@@ -633,8 +1286,8 @@ func (s *StreamAdapter) emitToolCallChunk(calls []functionCall) {
 
 		// Generate unique IDs for each tool call using our fast ID generator
 		toolCall := openai.ChatCompletionChunkChoiceDeltaToolCall{
-			Index: int64(i),
-			ID:    s.adapter.GenerateToolCallID(),
+			Index: int64(firstIndex + len(toolCalls)),
+			ID:    s.adapter.toolCallID(call.ID),
 			Type:  functionType,
 			Function: openai.ChatCompletionChunkChoiceDeltaToolCallFunction{
 				Name:      call.Name,
@@ -657,15 +1310,20 @@ func (s *StreamAdapter) emitToolCallChunk(calls []functionCall) {
 				},
 			},
 		}
+		if s.adapter.toolPolicy == ToolEmitAndContinue {
+			// The stream goes on; its finish chunk reports the tool calls
+			s.currentChunk.Choices[0].FinishReason = ""
+		}
 
 		// Mark that we've emitted tool calls - all subsequent content will be discarded
+		// unless the policy is ToolEmitAndContinue
 		s.toolCallsEmitted = true
 
 		// Set flag to stop processing if configured and appropriate for the policy
 		if s.adapter.cancelUpstreamOnStop &&
 			(s.adapter.toolPolicy == ToolStopOnFirst ||
 				(s.adapter.toolPolicy == ToolCollectThenStop && s.toolCollectionState == toolStateFinished)) {
-			s.adapter.logger.Debug("Setting stop processing flag after emitting tool calls",
+			s.adapter.log(LogCategoryStream).Debug("Setting stop processing flag after emitting tool calls",
 				"policy", s.adapter.toolPolicy.String(),
 				"cancel_upstream_on_stop", s.adapter.cancelUpstreamOnStop)
 			s.stopProcessing = true
@@ -678,12 +1336,12 @@ func (s *StreamAdapter) emitToolCallChunk(calls []functionCall) {
 			}
 		}
 
-		s.adapter.logger.Debug("Emitted streaming tool call chunk",
+		s.adapter.log(LogCategoryStream).Debug("Emitted streaming tool call chunk",
 			"valid_tool_calls", len(toolCalls),
 			"original_call_count", len(calls))
 	} else {
 		// Fallback to content chunk if no valid tool calls
-		s.adapter.logger.Warn("No valid tool calls after processing, falling back to empty content")
+		s.adapter.log(LogCategoryStream).Warn("No valid tool calls after processing, falling back to empty content")
 		s.emitContentChunk("")
 	}
 }
@@ -699,10 +1357,12 @@ func (s *StreamAdapter) handleMixedMode(chunk openai.ChatCompletionChunk, conten
 
 	// Check if we should start buffering for tool detection
 	if s.shouldStartBuffering(content) {
-		s.buffer.WriteString(content)
-		s.adapter.logger.Debug("Started buffering potential tool call (mixed mode)",
+		s.writeBuffer(content)
+		s.adapter.log(LogCategoryStream).Debug("Started buffering potential tool call (mixed mode)",
 			"content_prefix", s.truncateForLog(content, 50),
 			"chunk_index", s.processedChunks)
+		s.emitBufferEvent(ParseEventBufferStart, len(content))
+		s.mixedEmitted = content
 	}
 
 	// Always emit content in mixed mode
@@ -714,7 +1374,7 @@ func (s *StreamAdapter) handleMixedMode(chunk openai.ChatCompletionChunk, conten
 func (s *StreamAdapter) handleStopOnFirstMode(chunk openai.ChatCompletionChunk, content string) bool {
 	// If we've already emitted tool calls, discard all subsequent content
 	if s.toolCallsEmitted {
-		s.adapter.logger.Debug("Discarding content after tool calls emitted (stop on first)",
+		s.adapter.log(LogCategoryStream).Debug("Discarding content after tool calls emitted (stop on first)",
 			"content_length", len(content),
 			"content_prefix", s.truncateForLog(content, 50),
 			"chunk_index", s.processedChunks)
@@ -729,16 +1389,49 @@ func (s *StreamAdapter) handleStopOnFirstMode(chunk openai.ChatCompletionChunk,
 	}
 
 	// Not buffering yet - decide if we should start
-	if s.shouldStartBuffering(content) {
-		s.buffer.WriteString(content)
-		s.adapter.logger.Debug("Started buffering potential tool call (stop on first)",
-			"content_prefix", s.truncateForLog(content, 50),
+	start, release, holding := s.decideBuffering(content)
+	if holding {
+		return false // Still peeking for a tool call pattern
+	}
+	if start != "" {
+		s.writeBuffer(start)
+		s.adapter.log(LogCategoryStream).Debug("Started buffering potential tool call (stop on first)",
+			"content_prefix", s.truncateForLog(start, 50),
 			"chunk_index", s.processedChunks)
+		s.emitBufferEvent(ParseEventBufferStart, len(start))
 		return false // Continue to next chunk
 	}
 
 	// Regular content - pass through immediately
-	s.currentChunk = chunk
+	s.emitReleasedContent(chunk, release)
+	return true
+}
+
+// handleEmitAndContinueMode handles ToolEmitAndContinue policy - emits each tool call
+// as soon as it is complete and keeps streaming content and later calls
+func (s *StreamAdapter) handleEmitAndContinueMode(chunk openai.ChatCompletionChunk, content string) bool {
+	// If we're already buffering, continue buffering
+	if s.buffer.Len() > 0 {
+		return s.handleBufferedContent(content)
+	}
+
+	// Not buffering - decide if we should start
+	start, release, holding := s.decideBuffering(content)
+	if holding {
+		return false // Still peeking for a tool call pattern
+	}
+	if start != "" {
+		s.adapter.log(LogCategoryStream).Debug("Started buffering potential tool call (emit and continue)",
+			"content_prefix", s.truncateForLog(start, 50),
+			"chunk_index", s.processedChunks)
+		s.emitBufferEvent(ParseEventBufferStart, len(start))
+		// Emit a call completed within this chunk now, so the content that follows
+		// it is not buffered with it
+		return s.handleBufferedContent(start)
+	}
+
+	// Regular content - pass through immediately, also after tool calls were emitted
+	s.emitReleasedContent(chunk, release)
 	return true
 }
 
@@ -750,36 +1443,44 @@ func (s *StreamAdapter) handleCollectThenStopMode(chunk openai.ChatCompletionChu
 	}
 
 	// Not buffering yet - decide if we should start
-	if s.shouldStartBuffering(content) {
-		s.startToolCollection(content)
+	start, release, holding := s.decideBuffering(content)
+	if holding {
+		return false // Still peeking for a tool call pattern
+	}
+	if start != "" {
+		s.startToolCollection(start)
 		return false // Continue to next chunk
 	}
 
 	// Regular content - pass through immediately (before any tool detection)
-	s.currentChunk = chunk
+	s.emitReleasedContent(chunk, release)
 	return true
 }
 
 // handleDrainAllMode handles ToolDrainAll policy - reads entire stream and collects all tools
 func (s *StreamAdapter) handleDrainAllMode(_ openai.ChatCompletionChunk, content string) bool {
 	// In drain all mode, never emit content until the very end
+	if !s.contentSuppressed {
+		s.emitBufferEvent(ParseEventBufferStart, len(content))
+	}
 	s.contentSuppressed = true
 
-	s.adapter.logger.Debug("Buffering content for drain all mode",
+	s.adapter.log(LogCategoryStream).Debug("Buffering content for drain all mode",
 		"content_length", len(content),
 		"buffer_length", s.buffer.Len(),
 		"chunk_index", s.processedChunks)
 
 	// Always buffer all content
-	s.buffer.WriteString(content)
+	s.writeBuffer(content)
 	s.bytesCollected += len(content)
 
 	// Check byte limits
 	if s.adapter.toolCollectMaxBytes > 0 && s.bytesCollected > s.adapter.toolCollectMaxBytes {
-		s.adapter.logger.Warn("Byte limit exceeded in drain all mode, processing collected content",
+		s.adapter.log(LogCategoryLimit).Warn("Byte limit exceeded in drain all mode, processing collected content",
 			"bytes_collected", s.bytesCollected,
 			"limit", s.adapter.toolCollectMaxBytes,
 			"recommendation", "Consider increasing limit with WithToolCollectMaxBytes() if legitimate use case")
+		s.emitLimitExceeded(s.bytesCollected, s.adapter.toolCollectMaxBytes, ParseDetailToolCollectMaxBytes)
 		s.processBufferedContent()
 		return true
 	}
@@ -796,7 +1497,7 @@ func (s *StreamAdapter) handleBufferedContentForCollection(content string) bool
 			return false
 		}
 		// Continue with regular content emission
-		s.buffer.WriteString(content)
+		s.writeBuffer(content)
 		if s.hasCompleteJSON() {
 			s.processBufferedContent()
 			return true
@@ -809,7 +1510,7 @@ func (s *StreamAdapter) handleBufferedContentForCollection(content string) bool
 	}
 
 	// Content suppressed - collecting tools
-	s.buffer.WriteString(content)
+	s.writeBuffer(content)
 	s.bytesCollected += len(content)
 
 	// Check stopping conditions
@@ -820,18 +1521,18 @@ func (s *StreamAdapter) handleBufferedContentForCollection(content string) bool
 
 	// Check for complete JSON structure
 	if s.hasCompleteJSON() {
-		s.adapter.logger.Debug("Complete JSON detected during collection",
+		s.adapter.log(LogCategoryParse).Debug("Complete JSON detected during collection",
 			"buffer_length", s.buffer.Len(),
 			"chunk_index", s.processedChunks)
-		s.processBufferedContentForCollectionPhase()
-		return true
+		return s.processBufferedContentForCollectionPhase()
 	}
 
 	// Safety check: prevent unlimited buffering
 	if s.buffer.Len() > s.bufferLimit {
-		s.adapter.logger.Warn("Buffer limit exceeded during collection, processing as regular content",
+		s.adapter.log(LogCategoryLimit).Warn("Buffer limit exceeded during collection, processing as regular content",
 			"buffer_length", s.buffer.Len(),
 			"limit", s.bufferLimit)
+		s.emitLimitExceeded(s.buffer.Len(), s.bufferLimit, ParseDetailStreamBufferLimit)
 		s.processBufferedContentAsRegular()
 		return true
 	}
@@ -842,26 +1543,27 @@ func (s *StreamAdapter) handleBufferedContentForCollection(content string) bool
 // shouldStopCollection determines if tool collection should stop based on policy limits
 func (s *StreamAdapter) shouldStopCollection() bool {
 	// Check tool count limit
-	if s.adapter.toolMaxCalls > 0 && len(s.collectedTools) >= s.adapter.toolMaxCalls {
-		s.adapter.logger.Debug("Tool collection stopped: max calls reached",
+	if maxCalls := s.adapter.maxToolCalls(s.ctx); maxCalls > 0 && len(s.collectedTools) >= maxCalls {
+		s.adapter.log(LogCategoryLimit).Debug("Tool collection stopped: max calls reached",
 			"collected_tools", len(s.collectedTools),
-			"max_calls", s.adapter.toolMaxCalls)
+			"max_calls", maxCalls)
 		return true
 	}
 
 	// Check byte limit
 	if s.adapter.toolCollectMaxBytes > 0 && s.bytesCollected > s.adapter.toolCollectMaxBytes {
-		s.adapter.logger.Warn("Tool collection stopped: max bytes reached",
+		s.adapter.log(LogCategoryLimit).Warn("Tool collection stopped: max bytes reached",
 			"bytes_collected", s.bytesCollected,
 			"max_bytes", s.adapter.toolCollectMaxBytes,
 			"recommendation", "Consider increasing limit with WithToolCollectMaxBytes() if legitimate use case")
+		s.emitLimitExceeded(s.bytesCollected, s.adapter.toolCollectMaxBytes, ParseDetailToolCollectMaxBytes)
 		return true
 	}
 
 	// Check timeout for CollectThenStop policy
 	if s.adapter.toolPolicy == ToolCollectThenStop && s.adapter.toolCollectWindow > 0 {
 		if time.Since(s.collectionStartTime) > s.adapter.toolCollectWindow {
-			s.adapter.logger.Debug("Tool collection stopped: timeout reached",
+			s.adapter.log(LogCategoryLimit).Debug("Tool collection stopped: timeout reached",
 				"elapsed", time.Since(s.collectionStartTime),
 				"window", s.adapter.toolCollectWindow)
 			return true
@@ -873,23 +1575,32 @@ func (s *StreamAdapter) shouldStopCollection() bool {
 
 // startToolCollection initializes tool collection state
 func (s *StreamAdapter) startToolCollection(content string) {
-	s.buffer.WriteString(content)
+	s.writeBuffer(content)
 	s.contentSuppressed = true
 	s.toolCollectionState = toolStateCollecting
 	s.collectionStartTime = time.Now()
-	s.adapter.logger.Debug("Started tool collection, suppressing content",
+	s.adapter.log(LogCategoryStream).Debug("Started tool collection, suppressing content",
 		"content_prefix", s.truncateForLog(content, 50),
 		"chunk_index", s.processedChunks,
-		"policy", s.adapter.toolPolicy)
+		"policy", s.adapter.toolPolicy.String())
+	s.emitBufferEvent(ParseEventBufferStart, len(content))
 }
 
 // addToolsToCollection adds tools to the collection with limit enforcement
 func (s *StreamAdapter) addToolsToCollection(calls []functionCall) {
+	calls, _ = s.adapter.deduplicateCalls(calls, s.seenCalls)
+	if len(calls) == 0 {
+		return
+	}
+
 	// Apply tool limit enforcement
 	remainingCapacity := len(calls)
-	if s.adapter.toolMaxCalls > 0 {
+	if maxCalls := s.adapter.maxToolCalls(s.ctx); maxCalls > 0 {
 		currentCount := len(s.collectedTools)
-		maxNewTools := s.adapter.toolMaxCalls - currentCount
+		maxNewTools := maxCalls - currentCount
+		if remainingCapacity > maxNewTools {
+			s.adapter.emitPolicyLimitHit(PolicyLimitToolMaxCalls, currentCount+len(calls), maxCalls, true)
+		}
 		if maxNewTools <= 0 {
 			return // Already at capacity
 		}
@@ -907,7 +1618,7 @@ func (s *StreamAdapter) addToolsToCollection(calls []functionCall) {
 // processCollectedTools processes and emits all collected tools
 func (s *StreamAdapter) processCollectedTools() {
 	if len(s.collectedTools) > 0 {
-		s.adapter.logger.Info("Processing collected tools",
+		s.adapter.log(LogCategoryParse).Info("Processing collected tools",
 			"tool_count", len(s.collectedTools),
 			"collection_duration", time.Since(s.collectionStartTime))
 		s.emitToolCallChunk(s.collectedTools)
@@ -915,34 +1626,86 @@ func (s *StreamAdapter) processCollectedTools() {
 	s.toolCollectionState = toolStateFinished
 }
 
-// processBufferedContentForCollectionPhase processes buffered content during collection phase
-func (s *StreamAdapter) processBufferedContentForCollectionPhase() {
+// processBufferedContentForCollectionPhase processes buffered content during collection phase.
+// It returns true when it produced a chunk to emit or failed the stream, and false when
+// the content was added to the collection or discarded.
+func (s *StreamAdapter) processBufferedContentForCollectionPhase() bool {
 	content := s.buffer.String()
 	if content == "" {
-		return
+		return false
 	}
 
 	// Parse JSON candidates
-	extractor := NewJSONExtractor(content)
-	candidates := extractor.ExtractJSONBlocks()
-	calls := ExtractFunctionCalls(candidates) // Simplified - no array detection
+	candidates := s.adapter.extractCandidates(content, true)
+	calls, err := s.adapter.postProcessCalls(s.ctx, ExtractFunctionCalls(candidates)) // Simplified - no array detection
+	if err != nil {
+		s.fail(wrapTransformError(PhaseStreaming, "process tool calls", -1, err))
+		return true
+	}
 	if len(calls) == 0 {
 		// Not a valid tool JSON; emit as regular content only if we haven't suppressed content
+		s.resetBuffer()
 		if !s.contentSuppressed {
+			s.emitBufferFlush(len(content), BufferFlushNoToolCall)
 			s.emitContentChunk(content)
+			return true
 		}
-		s.buffer.Reset()
-		return
+		s.collectedText += content
+		return false
 	}
 
 	// Add tools to collection (with limit enforcement)
 	s.addToolsToCollection(calls)
+	s.collectedText += content
 
 	// For CollectThenStop: continue collecting - don't immediately emit
 	// Only emit when we hit explicit stop conditions (timeout, limits, etc.)
 	// This allows multiple individual tool calls to be collected together
 
-	s.buffer.Reset()
+	s.resetBuffer()
+	return false
+}
+
+// flushCollection parses any buffered content into the collection and emits the
+// collected tools that have not been emitted yet. It returns false without touching
+// the buffer when no tools were collected, leaving it to the regular buffer handling.
+func (s *StreamAdapter) flushCollection() bool {
+	if s.toolCallsEmitted || len(s.collectedTools) == 0 {
+		return false
+	}
+	if s.processBufferedContentForCollectionPhase() && s.err != nil {
+		return true
+	}
+	s.adapter.log(LogCategoryStream).Debug("Emitting collected tools at end of generation",
+		"collected_tool_count", len(s.collectedTools))
+	s.processCollectedTools()
+	return true
+}
+
+// emitBufferEvent emits a buffer start or flush parse event for this stream.
+func (s *StreamAdapter) emitBufferEvent(eventType ParseEventType, size int) {
+	s.adapter.emitParseEvent(ParseEvent{Type: eventType, Size: size, Streaming: true})
+}
+
+// emitBufferFlush emits a ParseEventBufferFlush event and a StreamBufferFlushedData
+// metric for withheld content released as text.
+func (s *StreamAdapter) emitBufferFlush(size int, reason string) {
+	s.emitBufferEvent(ParseEventBufferFlush, size)
+	s.adapter.emitPhaseMetric(StreamBufferFlushedData{Size: size, Reason: reason})
+}
+
+// emitLimitExceeded emits a ParseEventLimitExceeded event and a PolicyLimitHitData
+// metric for this stream.
+func (s *StreamAdapter) emitLimitExceeded(size, limit int, detail string) {
+	s.adapter.emitPolicyLimitHit(detail, size, limit, true)
+	s.adapter.emitParseEvent(ParseEvent{
+		Type:      ParseEventLimitExceeded,
+		Size:      size,
+		Limit:     limit,
+		Streaming: true,
+		Detail:    detail,
+		Err:       ErrBufferLimitExceeded,
+	})
 }
 
 // truncateForLog safely truncates a string for logging purposes
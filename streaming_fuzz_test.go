@@ -0,0 +1,91 @@
+package tooladapter
+
+import (
+	"strings"
+	"testing"
+)
+
+// splitFuzzChunks cuts content into chunks whose lengths are taken from cuts, so the
+// fuzzer controls where chunk boundaries fall. Each cut byte is a length of 1 to 32
+// bytes; the remainder forms the last chunk.
+func splitFuzzChunks(content string, cuts []byte) []string {
+	var chunks []string
+	for _, cut := range cuts {
+		if content == "" {
+			break
+		}
+		n := min(int(cut%32)+1, len(content))
+		chunks = append(chunks, content[:n])
+		content = content[n:]
+	}
+	if content != "" || len(chunks) == 0 {
+		chunks = append(chunks, content)
+	}
+	return chunks
+}
+
+// FuzzStreamChunks fuzzes the streaming state machine with arbitrary content split
+// at arbitrary chunk boundaries. A stream must never panic, must terminate after a
+// bounded number of chunks, must only emit valid JSON arguments, and must release
+// all buffered content as text when it emits no tool calls.
+func FuzzStreamChunks(f *testing.F) {
+	f.Add(`{"name": "get_weather", "parameters": {"city": "Paris"}}`, []byte{0, 5, 9}, byte(0))
+	f.Add(`Let me check. [{"name": "a", "parameters": {}}, {"name": "b", "parameters": {}}]`, []byte{13, 2}, byte(2))
+	f.Add("```json\n{\"name\": \"search\", \"parameters\": {\"q\": \"go\"}}\n```\nDone.", []byte{2, 2, 2, 31}, byte(3))
+	f.Add(`{"name": "truncated", "parameters": {"city": "Par`, []byte{7, 7, 7}, byte(0))
+	f.Add(`{"not": "a tool call", "value": [1, 2, 3]} and more text`, []byte{1}, byte(0))
+	f.Add(`{name: 'lenient', parameters: {}}`, []byte{3, 3}, byte(0x08))
+	f.Add(`<think>{"name": "hidden", "parameters": {}}</think>{"name": "shown", "parameters": {}}`, []byte{3, 10, 2}, byte(0x10))
+	f.Add(`{"name": "dup", "parameters": {}} {"name": "dup", "parameters": {}}`, []byte{17, 17}, byte(0x24))
+	f.Add("`{\"name\": \"inline\", \"parameters\": {}}` then text", []byte{0, 0, 0, 0}, byte(1))
+	f.Add(`[[[[[[[[[[{"name":`, []byte{1, 1, 1, 1}, byte(0))
+
+	f.Fuzz(func(t *testing.T, content string, cuts []byte, mode byte) {
+		adapter := fuzzAdapter(mode)
+		chunks := splitFuzzChunks(content, cuts)
+		stream := adapter.TransformStreamingResponse(&mockStreamingResponse{chunks: chunks})
+		defer func() { _ = stream.Close() }()
+
+		// Chunks may be split around think tags or tool calls, but never more than
+		// once per byte of content
+		limit := 2*len(content) + 4*len(chunks) + 16
+		var text strings.Builder
+		arguments := make(map[int64]*strings.Builder)
+		names := make(map[int64]string)
+		steps := 0
+		for stream.Next() {
+			steps++
+			if steps > limit {
+				t.Fatalf("stream of %q in %d chunks did not terminate after %d chunks", content, len(chunks), limit)
+			}
+			current := stream.Current()
+			if len(current.Choices) == 0 {
+				continue
+			}
+			delta := current.Choices[0].Delta
+			text.WriteString(delta.Content)
+			for _, call := range delta.ToolCalls {
+				if arguments[call.Index] == nil {
+					arguments[call.Index] = &strings.Builder{}
+				}
+				if call.Function.Name != "" {
+					names[call.Index] = call.Function.Name
+				}
+				arguments[call.Index].WriteString(call.Function.Arguments)
+			}
+		}
+		if err := stream.Err(); err != nil {
+			if err.Error() == "" {
+				t.Errorf("stream returned an empty error message for %q", content)
+			}
+			return
+		}
+
+		for index, args := range arguments {
+			checkFuzzToolCall(t, content, names[index], args.String())
+		}
+		if len(arguments) == 0 && mode&0x10 == 0 && text.String() != content {
+			t.Errorf("stream without tool calls changed content:\ngot:  %q\nwant: %q\nchunks: %q", text.String(), content, chunks)
+		}
+	})
+}
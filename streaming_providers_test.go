@@ -0,0 +1,140 @@
+package tooladapter_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	tooladapter "github.com/juburr/openai-tool-adapter/v3"
+	"github.com/openai/openai-go/v3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// vLLM sends a role-only first chunk, logprobs with every token (including
+// logprobs-only chunks for special tokens), the last content together with the
+// finish reason, and a trailing usage chunk without choices.
+var vllmToolCallChunks = []string{
+	`{"id":"chatcmpl-1","object":"chat.completion.chunk","created":1,"model":"qwen","choices":[{"index":0,"delta":{"role":"assistant","content":""},"logprobs":null,"finish_reason":null}]}`,
+	`{"id":"chatcmpl-1","object":"chat.completion.chunk","created":1,"model":"qwen","choices":[{"index":0,"delta":{"content":"{\"name\": \"get_weather\", "},"logprobs":{"content":[{"token":"{\"","logprob":-0.01,"bytes":[123,34],"top_logprobs":[]}]},"finish_reason":null}]}`,
+	`{"id":"chatcmpl-1","object":"chat.completion.chunk","created":1,"model":"qwen","choices":[{"index":0,"delta":{"content":""},"logprobs":{"content":[{"token":"","logprob":-0.2,"bytes":[],"top_logprobs":[]}]},"finish_reason":null}]}`,
+	`{"id":"chatcmpl-1","object":"chat.completion.chunk","created":1,"model":"qwen","choices":[{"index":0,"delta":{"content":"\"parameters\": {\"city\": \"Paris\"}}"},"logprobs":{"content":[{"token":"}}","logprob":-0.01,"bytes":[125,125],"top_logprobs":[]}]},"finish_reason":"stop"}]}`,
+	`{"id":"chatcmpl-1","object":"chat.completion.chunk","created":1,"model":"qwen","choices":[],"usage":{"prompt_tokens":40,"completion_tokens":12,"total_tokens":52}}`,
+}
+
+// llama.cpp server sends a role-only first chunk with null content, empty deltas
+// annotated with timings, and the finish reason in a chunk of its own.
+var llamaCppToolCallChunks = []string{
+	`{"id":"chatcmpl-2","object":"chat.completion.chunk","created":1,"model":"gemma","choices":[{"index":0,"delta":{"role":"assistant","content":null},"finish_reason":null}]}`,
+	`{"id":"chatcmpl-2","object":"chat.completion.chunk","created":1,"model":"gemma","choices":[{"index":0,"delta":{"content":"{\"name\": \"get_weather\", "},"finish_reason":null}]}`,
+	`{"id":"chatcmpl-2","object":"chat.completion.chunk","created":1,"model":"gemma","choices":[{"index":0,"delta":{},"finish_reason":null}],"timings":{"predicted_n":7,"predicted_ms":35.2}}`,
+	`{"id":"chatcmpl-2","object":"chat.completion.chunk","created":1,"model":"gemma","choices":[{"index":0,"delta":{"content":"\"parameters\": {\"city\": \"Paris\"}}"},"finish_reason":null}]}`,
+	`{"id":"chatcmpl-2","object":"chat.completion.chunk","created":1,"model":"gemma","choices":[{"index":0,"delta":{},"finish_reason":"stop"}],"timings":{"predicted_n":12,"predicted_ms":60.1}}`,
+}
+
+func decodeChunks(t *testing.T, lines []string) []openai.ChatCompletionChunk {
+	t.Helper()
+	chunks := make([]openai.ChatCompletionChunk, len(lines))
+	for i, line := range lines {
+		require.NoError(t, json.Unmarshal([]byte(line), &chunks[i]))
+	}
+	return chunks
+}
+
+func TestProviderChunking_ToolCall(t *testing.T) {
+	providers := map[string][]string{
+		"vLLM":     vllmToolCallChunks,
+		"llamacpp": llamaCppToolCallChunks,
+	}
+	policies := []tooladapter.ToolPolicy{
+		tooladapter.ToolStopOnFirst,
+		tooladapter.ToolCollectThenStop,
+		tooladapter.ToolDrainAll,
+	}
+
+	for name, lines := range providers {
+		for _, policy := range policies {
+			t.Run(name+"/"+policy.String(), func(t *testing.T) {
+				adapter := tooladapter.New(tooladapter.WithToolPolicy(policy))
+				stream := adapter.TransformStreamingResponse(NewMockStream(decodeChunks(t, lines)))
+
+				var toolCalls []openai.ChatCompletionChunkChoiceDeltaToolCall
+				var finishReasons []string
+				for stream.Next() {
+					chunk := stream.Current()
+					if len(chunk.Choices) == 0 {
+						continue
+					}
+					choice := chunk.Choices[0]
+					assert.Empty(t, choice.Delta.Content, "tool call JSON must not leak as content")
+					assert.Empty(t, choice.Logprobs.Content, "logprobs of buffered content must not leak")
+					toolCalls = append(toolCalls, choice.Delta.ToolCalls...)
+					if choice.FinishReason != "" {
+						finishReasons = append(finishReasons, choice.FinishReason)
+					}
+				}
+				require.NoError(t, stream.Err())
+
+				require.Len(t, toolCalls, 1)
+				assert.Equal(t, "get_weather", toolCalls[0].Function.Name)
+				assert.JSONEq(t, `{"city": "Paris"}`, toolCalls[0].Function.Arguments)
+				require.NotEmpty(t, finishReasons)
+				assert.Equal(t, "tool_calls", finishReasons[0])
+			})
+		}
+	}
+}
+
+func TestProviderChunking_PlainText(t *testing.T) {
+	lines := []string{
+		`{"id":"chatcmpl-3","object":"chat.completion.chunk","created":1,"model":"qwen","choices":[{"index":0,"delta":{"role":"assistant","content":""},"logprobs":null,"finish_reason":null}]}`,
+		`{"id":"chatcmpl-3","object":"chat.completion.chunk","created":1,"model":"qwen","choices":[{"index":0,"delta":{"content":"It is sunny"},"logprobs":{"content":[{"token":"It","logprob":-0.1,"bytes":[73,116],"top_logprobs":[]}]},"finish_reason":null}]}`,
+		`{"id":"chatcmpl-3","object":"chat.completion.chunk","created":1,"model":"qwen","choices":[{"index":0,"delta":{"content":""},"logprobs":{"content":[{"token":"","logprob":-0.3,"bytes":[],"top_logprobs":[]}]},"finish_reason":null}]}`,
+		`{"id":"chatcmpl-3","object":"chat.completion.chunk","created":1,"model":"qwen","choices":[{"index":0,"delta":{"content":" in Paris."},"logprobs":null,"finish_reason":"stop"}]}`,
+	}
+
+	adapter := tooladapter.New()
+	stream := adapter.TransformStreamingResponse(NewMockStream(decodeChunks(t, lines)))
+
+	var content string
+	var logprobChunks int
+	var finishReason string
+	for stream.Next() {
+		chunk := stream.Current()
+		content += chunk.Choices[0].Delta.Content
+		if len(chunk.Choices[0].Logprobs.Content) > 0 {
+			logprobChunks++
+		}
+		if chunk.Choices[0].FinishReason != "" {
+			finishReason = chunk.Choices[0].FinishReason
+		}
+	}
+	require.NoError(t, stream.Err())
+
+	assert.Equal(t, "It is sunny in Paris.", content)
+	assert.Equal(t, 2, logprobChunks, "logprobs pass through with unbuffered text")
+	assert.Equal(t, "stop", finishReason)
+}
+
+func TestProviderChunking_TruncatedToolCallWithFinish(t *testing.T) {
+	// A length-limited generation ends mid tool call with the finish reason attached
+	// to the last content; the partial JSON is released as text before the finish
+	chunk := createStreamChunk(`"parameters": {"city": "Par`)
+	chunk.Choices[0].FinishReason = "length"
+
+	adapter := tooladapter.New()
+	stream := adapter.TransformStreamingResponse(NewMockStream([]openai.ChatCompletionChunk{
+		createStreamChunk(`{"name": "get_weather", `),
+		chunk,
+	}))
+
+	var content, finishReason string
+	for stream.Next() {
+		current := stream.Current()
+		content += current.Choices[0].Delta.Content
+		finishReason = current.Choices[0].FinishReason
+	}
+	require.NoError(t, stream.Err())
+
+	assert.Equal(t, `{"name": "get_weather", "parameters": {"city": "Par`, content)
+	assert.Equal(t, "length", finishReason)
+}
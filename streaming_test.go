@@ -9,6 +9,7 @@ import (
 	"time"
 
 	tooladapter "github.com/juburr/openai-tool-adapter/v3"
+	"github.com/juburr/openai-tool-adapter/v3/tooltest"
 	"github.com/openai/openai-go/v3"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -957,26 +958,11 @@ func TestStreamingPerformance_ResponseTimes(t *testing.T) {
 
 // Helper functions for creating test chunks
 func createStreamChunk(content string) openai.ChatCompletionChunk {
-	return openai.ChatCompletionChunk{
-		Choices: []openai.ChatCompletionChunkChoice{
-			{
-				Delta: openai.ChatCompletionChunkChoiceDelta{
-					Content: content,
-					Role:    "assistant",
-				},
-			},
-		},
-	}
+	return tooltest.ContentChunk(content)
 }
 
 func createFinishChunk(reason string) openai.ChatCompletionChunk {
-	return openai.ChatCompletionChunk{
-		Choices: []openai.ChatCompletionChunkChoice{
-			{
-				FinishReason: reason,
-			},
-		},
-	}
+	return tooltest.FinishChunk(reason)
 }
 
 // TestBufferLimitExceeded tests the critical safety mechanism for buffer overflow
@@ -2016,3 +2002,257 @@ func TestStreamEnd_WithCollectedTools(t *testing.T) {
 	require.NoError(t, streamAdapter.Err())
 	assert.NotEmpty(t, chunks, "Should handle stream end with collected tools")
 }
+
+// slowMockStream delays each Next call to simulate a slowly generating model
+type slowMockStream struct {
+	*MockChatCompletionStream
+	delay time.Duration
+}
+
+func (s *slowMockStream) Next() bool {
+	time.Sleep(s.delay)
+	return s.MockChatCompletionStream.Next()
+}
+
+// pausedMockStream blocks before its chunk at index pauseAt until resume is closed
+type pausedMockStream struct {
+	*MockChatCompletionStream
+	pauseAt int
+	resume  chan struct{}
+	calls   int
+}
+
+func (s *pausedMockStream) Next() bool {
+	if s.calls == s.pauseAt {
+		<-s.resume
+	}
+	s.calls++
+	return s.MockChatCompletionStream.Next()
+}
+
+// TestStreamHeartbeat tests keep-alive chunks emitted while a tool call is buffered
+func TestStreamHeartbeat(t *testing.T) {
+	toolCallChunks := func() []openai.ChatCompletionChunk {
+		chunks := []openai.ChatCompletionChunk{createStreamChunk(`[{"name": "get_weather", "parameters": {"location": "`)}
+		for i := 0; i < 5; i++ {
+			chunks = append(chunks, createStreamChunk("x"))
+		}
+		chunks = append(chunks, createStreamChunk(`"}}]`), createFinishChunk("tool_calls"))
+		return chunks
+	}
+
+	collect := func(adapter *tooladapter.Adapter) (heartbeats, toolCalls int) {
+		stream := adapter.TransformStreamingResponse(&slowMockStream{
+			MockChatCompletionStream: NewMockStream(toolCallChunks()),
+			delay:                    5 * time.Millisecond,
+		})
+		for stream.Next() {
+			chunk := stream.Current()
+			require.Len(t, chunk.Choices, 1)
+			delta := chunk.Choices[0].Delta
+			switch {
+			case len(delta.ToolCalls) > 0:
+				toolCalls += len(delta.ToolCalls)
+			case delta.Content == "" && chunk.Choices[0].FinishReason == "":
+				heartbeats++
+			}
+		}
+		require.NoError(t, stream.Err())
+		return heartbeats, toolCalls
+	}
+
+	t.Run("Enabled", func(t *testing.T) {
+		heartbeats, toolCalls := collect(tooladapter.New(tooladapter.WithStreamHeartbeat(time.Millisecond)))
+		assert.Equal(t, 1, toolCalls)
+		assert.Positive(t, heartbeats, "expected keep-alive chunks while buffering")
+	})
+
+	t.Run("Disabled", func(t *testing.T) {
+		heartbeats, toolCalls := collect(tooladapter.New())
+		assert.Equal(t, 1, toolCalls)
+		assert.Zero(t, heartbeats)
+	})
+
+	t.Run("NegativeIntervalDisables", func(t *testing.T) {
+		heartbeats, _ := collect(tooladapter.New(tooladapter.WithStreamHeartbeat(-time.Second)))
+		assert.Zero(t, heartbeats)
+	})
+
+	t.Run("SilentUpstream", func(t *testing.T) {
+		// Upstream stops after the start of a tool call; keep-alives must be sent
+		// before it resumes
+		chunks := toolCallChunks()
+		chunks[0].ID = "chatcmpl-silent"
+		upstream := &pausedMockStream{
+			MockChatCompletionStream: NewMockStream(chunks),
+			pauseAt:                  1,
+			resume:                   make(chan struct{}),
+		}
+		resumed := time.AfterFunc(2*time.Second, func() { close(upstream.resume) })
+		adapter := tooladapter.New(tooladapter.WithStreamHeartbeat(10 * time.Millisecond))
+		stream := adapter.TransformStreamingResponse(upstream)
+		defer func() { _ = stream.Close() }()
+
+		for range 3 {
+			require.True(t, stream.Next())
+			chunk := stream.Current()
+			require.Len(t, chunk.Choices, 1)
+			assert.Empty(t, chunk.Choices[0].Delta.Content)
+			assert.Empty(t, chunk.Choices[0].Delta.ToolCalls)
+			assert.Equal(t, "chatcmpl-silent", chunk.ID, "keep-alives carry the upstream chunk ID")
+		}
+		require.True(t, resumed.Stop(), "keep-alives arrived only after upstream resumed")
+		close(upstream.resume)
+
+		toolCalls := 0
+		for stream.Next() {
+			toolCalls += len(stream.Current().Choices[0].Delta.ToolCalls)
+		}
+		require.NoError(t, stream.Err())
+		assert.Equal(t, 1, toolCalls)
+	})
+
+	t.Run("SilentUpstreamIdleTimeout", func(t *testing.T) {
+		// Keep-alives do not restart the idle timeout
+		upstream := &pausedMockStream{
+			MockChatCompletionStream: NewMockStream(toolCallChunks()),
+			pauseAt:                  1,
+			resume:                   make(chan struct{}),
+		}
+		defer close(upstream.resume)
+		adapter := tooladapter.New(
+			tooladapter.WithStreamHeartbeat(5*time.Millisecond),
+			tooladapter.WithStreamIdleTimeout(50*time.Millisecond),
+		)
+		stream := adapter.TransformStreamingResponse(upstream)
+		defer func() { _ = stream.Close() }()
+
+		heartbeats := 0
+		for stream.Next() {
+			heartbeats++
+		}
+		var timeoutErr *tooladapter.StreamTimeoutError
+		require.ErrorAs(t, stream.Err(), &timeoutErr)
+		assert.True(t, timeoutErr.Idle)
+		assert.Positive(t, heartbeats)
+	})
+}
+
+// TestBufferDecisionLookahead tests peeking at JSON-looking content before buffering
+func TestBufferDecisionLookahead(t *testing.T) {
+	// collect drains a stream and returns its text, tool call names and finish reason
+	collect := func(t *testing.T, stream *tooladapter.StreamAdapter) (string, []string, string) {
+		t.Helper()
+		var text, finish string
+		var names []string
+		for stream.Next() {
+			chunk := stream.Current()
+			if len(chunk.Choices) == 0 {
+				continue
+			}
+			text += chunk.Choices[0].Delta.Content
+			for _, tc := range chunk.Choices[0].Delta.ToolCalls {
+				names = append(names, tc.Function.Name)
+			}
+			if chunk.Choices[0].FinishReason != "" {
+				finish = chunk.Choices[0].FinishReason
+			}
+		}
+		require.NoError(t, stream.Err())
+		return text, names, finish
+	}
+
+	t.Run("TokenSplitToolCall", func(t *testing.T) {
+		adapter := tooladapter.New(tooladapter.WithBufferDecisionLookahead(32))
+		stream := adapter.TransformStreamingResponse(NewMockStream([]openai.ChatCompletionChunk{
+			createStreamChunk("["),
+			createStreamChunk(`{"`),
+			createStreamChunk(`name": "get_weather", `),
+			createStreamChunk(`"parameters": {"city": "Oslo"}}]`),
+			createFinishChunk("stop"),
+		}))
+
+		text, names, _ := collect(t, stream)
+		assert.Empty(t, text)
+		assert.Equal(t, []string{"get_weather"}, names)
+	})
+
+	t.Run("PrettyPrintedToolCall", func(t *testing.T) {
+		adapter := tooladapter.New(tooladapter.WithBufferDecisionLookahead(32))
+		stream := adapter.TransformStreamingResponse(NewMockStream([]openai.ChatCompletionChunk{
+			createStreamChunk("{\n  \"name\": \"get_weather\",\n"),
+			createStreamChunk("  \"parameters\": null\n}"),
+			createFinishChunk("stop"),
+		}))
+
+		text, names, _ := collect(t, stream)
+		assert.Empty(t, text)
+		assert.Equal(t, []string{"get_weather"}, names)
+	})
+
+	t.Run("JSONWithoutToolCallReleased", func(t *testing.T) {
+		adapter := tooladapter.New(tooladapter.WithBufferDecisionLookahead(16))
+		chunks := []string{`{"temp": `, `21, "unit": `, `"celsius"}`, ` is the reading.`}
+		streamChunks := make([]openai.ChatCompletionChunk, 0, len(chunks)+1)
+		for _, c := range chunks {
+			chunk := createStreamChunk(c)
+			chunk.ID = "chatcmpl-lookahead"
+			streamChunks = append(streamChunks, chunk)
+		}
+		streamChunks = append(streamChunks, createFinishChunk("stop"))
+		stream := adapter.TransformStreamingResponse(NewMockStream(streamChunks))
+
+		require.True(t, stream.Next())
+		first := stream.Current()
+		assert.Equal(t, `{"temp": 21, "unit": `, first.Choices[0].Delta.Content, "held content is released once the lookahead is exhausted")
+		assert.Equal(t, "chatcmpl-lookahead", first.ID)
+
+		text, names, finish := collect(t, stream)
+		assert.Empty(t, names)
+		assert.Equal(t, `"celsius"} is the reading.`, text)
+		assert.Equal(t, "stop", finish)
+	})
+
+	t.Run("PeekFlushedAtFinish", func(t *testing.T) {
+		adapter := tooladapter.New(tooladapter.WithBufferDecisionLookahead(64))
+		stream := adapter.TransformStreamingResponse(NewMockStream([]openai.ChatCompletionChunk{
+			createStreamChunk("[1, 2"),
+			createFinishChunk("stop"),
+		}))
+
+		text, names, finish := collect(t, stream)
+		assert.Empty(t, names)
+		assert.Equal(t, "[1, 2", text)
+		assert.Equal(t, "stop", finish)
+	})
+
+	t.Run("PeekFlushedAtStreamEnd", func(t *testing.T) {
+		adapter := tooladapter.New(tooladapter.WithBufferDecisionLookahead(64))
+		stream := adapter.TransformStreamingResponse(NewMockStream([]openai.ChatCompletionChunk{
+			createStreamChunk("Text first. "),
+			createStreamChunk("[1, 2"),
+		}))
+
+		text, names, _ := collect(t, stream)
+		assert.Empty(t, names)
+		assert.Equal(t, "Text first. [1, 2", text)
+	})
+
+	t.Run("DisabledByDefault", func(t *testing.T) {
+		adapter := tooladapter.New()
+		stream := adapter.TransformStreamingResponse(NewMockStream([]openai.ChatCompletionChunk{
+			createStreamChunk(`{"temp": `),
+			createStreamChunk(`21}`),
+		}))
+
+		require.True(t, stream.Next())
+		assert.Equal(t, `{"temp": `, stream.Current().Choices[0].Delta.Content)
+	})
+
+	t.Run("NegativeIgnored", func(t *testing.T) {
+		var logs bytes.Buffer
+		logger := slog.New(slog.NewTextHandler(&logs, nil))
+		tooladapter.New(tooladapter.WithLogger(logger), tooladapter.WithBufferDecisionLookahead(-1))
+		assert.Contains(t, logs.String(), "Negative buffer decision lookahead ignored")
+	})
+}
@@ -0,0 +1,70 @@
+package tooladapter_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	tooladapter "github.com/juburr/openai-tool-adapter/v3"
+	"github.com/juburr/openai-tool-adapter/v3/tooltest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStreamAdapterAll(t *testing.T) {
+	adapter := tooladapter.New()
+
+	t.Run("Content", func(t *testing.T) {
+		upstream := tooltest.NewContentStream("Hello", ", ", "world")
+		var content strings.Builder
+		for chunk, err := range adapter.TransformStreamingResponse(upstream).All() {
+			require.NoError(t, err)
+			if len(chunk.Choices) > 0 {
+				content.WriteString(chunk.Choices[0].Delta.Content)
+			}
+		}
+		assert.Equal(t, "Hello, world", content.String())
+		assert.True(t, upstream.Closed())
+	})
+
+	t.Run("ToolCall", func(t *testing.T) {
+		upstream := tooltest.NewContentStream(`{"name": "get_weather", `, `"parameters": {"city": "Paris"}}`)
+		var names []string
+		for chunk, err := range adapter.TransformStreamingResponse(upstream).All() {
+			require.NoError(t, err)
+			for _, choice := range chunk.Choices {
+				for _, toolCall := range choice.Delta.ToolCalls {
+					names = append(names, toolCall.Function.Name)
+				}
+			}
+		}
+		assert.Equal(t, []string{"get_weather"}, names)
+	})
+
+	t.Run("BreakCloses", func(t *testing.T) {
+		upstream := tooltest.NewContentStream("one", "two", "three")
+		chunks := 0
+		for range adapter.TransformStreamingResponse(upstream).All() {
+			chunks++
+			break
+		}
+		assert.Equal(t, 1, chunks)
+		assert.True(t, upstream.Closed())
+	})
+
+	t.Run("Error", func(t *testing.T) {
+		upstreamErr := errors.New("connection reset")
+		upstream := tooltest.NewContentStream("partial")
+		upstream.SetError(upstreamErr)
+
+		var errs []error
+		for _, err := range adapter.TransformStreamingResponse(upstream).All() {
+			if err != nil {
+				errs = append(errs, err)
+			}
+		}
+		require.Len(t, errs, 1)
+		assert.ErrorIs(t, errs[0], upstreamErr)
+		assert.True(t, upstream.Closed())
+	})
+}
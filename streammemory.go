@@ -0,0 +1,99 @@
+package tooladapter
+
+import (
+	"sync/atomic"
+
+	"github.com/openai/openai-go/v3"
+)
+
+// streamMemory counts the memory retained by all live streams of an adapter for
+// WithAggregateStreamMemoryLimit.
+type streamMemory struct {
+	limit    int64
+	retained atomic.Int64
+}
+
+// RetainedStreamMemory returns the bytes all live streams of the adapter retain for
+// tool call detection, as counted against WithAggregateStreamMemoryLimit, or 0
+// without that limit.
+func (a *Adapter) RetainedStreamMemory() int64 {
+	if a.streamMemory == nil {
+		return 0
+	}
+	return a.streamMemory.retained.Load()
+}
+
+// retainedMemory returns the bytes the stream holds for tool call detection: the
+// buffered and peeked content, and the text and arguments of collected calls.
+// Callers must hold s.mu.
+func (s *StreamAdapter) retainedMemory() int {
+	if s.done {
+		return 0
+	}
+	retained := s.buffer.Len() + s.peek.Len() + len(s.collectedText)
+	for _, call := range s.collectedTools {
+		retained += len(call.Name) + len(call.Parameters) + len(call.ID)
+	}
+	return retained
+}
+
+// accountMemory records the stream's retained memory in the adapter-wide total of
+// WithAggregateStreamMemoryLimit.
+// Callers must hold s.mu.
+func (s *StreamAdapter) accountMemory() {
+	if s.memory == nil {
+		return
+	}
+	retained := s.retainedMemory()
+	if delta := retained - s.accountedMemory; delta != 0 {
+		s.memory.retained.Add(int64(delta))
+		s.accountedMemory = retained
+	}
+}
+
+// releaseMemory removes the stream from the adapter-wide total.
+// Callers must hold s.mu.
+func (s *StreamAdapter) releaseMemory() {
+	if s.memory != nil && s.accountedMemory != 0 {
+		s.memory.retained.Add(int64(-s.accountedMemory))
+		s.accountedMemory = 0
+	}
+}
+
+// exceedMemoryLimit degrades the stream to pass-through with passThroughRest when
+// withholding chunk's content would take it past WithStreamMemoryLimit, or take all
+// streams of the adapter past WithAggregateStreamMemoryLimit. Only a stream that is
+// buffering grows, so streams passing content through are never degraded. It
+// returns false while the content fits.
+// Callers must hold s.mu.
+func (s *StreamAdapter) exceedMemoryLimit(chunk openai.ChatCompletionChunk) bool {
+	if s.passThrough || !s.isBuffering() || len(chunk.Choices) == 0 {
+		return false
+	}
+	growth := len(chunk.Choices[0].Delta.Content)
+	retained := s.retainedMemory()
+
+	if limit := s.adapter.streamMemoryLimit; limit > 0 && retained+growth > limit {
+		s.adapter.log(LogCategoryLimit).Warn("Stream memory limit exceeded, passing the rest of the stream through",
+			"retained_bytes", retained+growth,
+			"limit", limit,
+			"recommendation", "Consider increasing the limit with WithStreamMemoryLimit() if legitimate use case")
+		s.emitLimitExceeded(retained+growth, limit, ParseDetailStreamMemoryLimit)
+		s.passThroughRest(chunk, BufferFlushMemory)
+		return true
+	}
+
+	if s.memory != nil {
+		total := s.memory.retained.Load() + int64(retained-s.accountedMemory+growth)
+		if total > s.memory.limit {
+			s.adapter.log(LogCategoryLimit).Warn("Aggregate stream memory limit exceeded, passing the rest of the stream through",
+				"retained_bytes", total,
+				"limit", s.memory.limit,
+				"recommendation", "Consider increasing the limit with WithAggregateStreamMemoryLimit() or reducing concurrent streams")
+			s.emitLimitExceeded(int(total), int(s.memory.limit), ParseDetailAggregateStreamMemoryLimit)
+			s.passThroughRest(chunk, BufferFlushMemory)
+			return true
+		}
+	}
+	return false
+}
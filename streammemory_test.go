@@ -0,0 +1,101 @@
+package tooladapter_test
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	tooladapter "github.com/juburr/openai-tool-adapter/v3"
+	"github.com/juburr/openai-tool-adapter/v3/tooltest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWithStreamMemoryLimit(t *testing.T) {
+	t.Run("DegradesToPassThrough", func(t *testing.T) {
+		adapter, collector := newPhaseMetricsAdapter(
+			tooladapter.WithToolPolicy(tooladapter.ToolDrainAll),
+			tooladapter.WithStreamMemoryLimit(20))
+		result := tooltest.Drain(adapter.TransformStreamingResponse(tooltest.NewContentStream(
+			"Hello ", "world, ", "this is long ", `{"name": "late", "parameters": {}}`)))
+		require.NoError(t, result.Err)
+		assert.Empty(t, result.ToolCalls, "no detection after the limit is reached")
+		assert.Equal(t, `Hello world, this is long {"name": "late", "parameters": {}}`, result.Content)
+
+		flushes := phaseEvents[tooladapter.StreamBufferFlushedData](collector)
+		require.Len(t, flushes, 1)
+		assert.Equal(t, tooladapter.StreamBufferFlushedData{Size: len("Hello world, "), Reason: tooladapter.BufferFlushMemory}, flushes[0])
+		limits := phaseEvents[tooladapter.PolicyLimitHitData](collector)
+		require.NotEmpty(t, limits)
+		assert.Equal(t, tooladapter.PolicyLimitStreamMemoryLimit, limits[0].Limit)
+	})
+
+	t.Run("CountsCollectedCalls", func(t *testing.T) {
+		adapter := tooladapter.New(
+			tooladapter.WithToolPolicy(tooladapter.ToolCollectThenStop),
+			tooladapter.WithToolCollectWindow(0),
+			tooladapter.WithStreamMemoryLimit(60))
+		result := tooltest.Drain(adapter.TransformStreamingResponse(tooltest.NewContentStream(
+			`{"name": "first", "parameters": {"city": "Paris"}}`, "\n", `{"name": "second",`, ` "parameters": {}}`)))
+		require.NoError(t, result.Err)
+		assert.Empty(t, result.ToolCalls)
+		assert.Equal(t, `{"name": "first", "parameters": {"city": "Paris"}}`+"\n"+`{"name": "second", "parameters": {}}`, result.Content)
+	})
+
+	t.Run("WithinLimit", func(t *testing.T) {
+		adapter := tooladapter.New(tooladapter.WithStreamMemoryLimit(1024))
+		result := tooltest.Drain(adapter.TransformStreamingResponse(tooltest.NewContentStream(
+			`{"name": "first", "parameters":`, ` {}}`)))
+		require.NoError(t, result.Err)
+		assert.Equal(t, []string{"first"}, result.ToolNames())
+	})
+
+	t.Run("Config", func(t *testing.T) {
+		cfg, err := tooladapter.LoadConfig(strings.NewReader(`{"stream_memory_limit": 4096, "aggregate_stream_memory_limit": 1048576}`))
+		require.NoError(t, err)
+		assert.Equal(t, 4096, cfg.StreamMemoryLimit)
+		assert.Equal(t, 1048576, cfg.AggregateStreamMemoryLimit)
+	})
+}
+
+func TestWithAggregateStreamMemoryLimit(t *testing.T) {
+	const partial = `{"name": "a", "parameters": {"text": "` // 38 bytes
+	adapter := tooladapter.New(tooladapter.WithAggregateStreamMemoryLimit(60))
+
+	// Stream a holds a partial tool call while stream b starts buffering
+	gated := &gatedStream{MockStream: tooltest.NewContentStream(partial, `abc"}}`), release: make(chan struct{})}
+	done := make(chan tooltest.StreamResult)
+	go func() {
+		done <- tooltest.Drain(adapter.TransformStreamingResponse(gated))
+	}()
+	gated.release <- struct{}{}
+	require.Eventually(t, func() bool { return adapter.RetainedStreamMemory() == int64(len(partial)) },
+		time.Second, time.Millisecond)
+
+	b := tooltest.Drain(adapter.TransformStreamingResponse(tooltest.NewContentStream(
+		`{"name": "b", "parameters": {"text": "`, `more text`, `"}}`)))
+	require.NoError(t, b.Err)
+	assert.Empty(t, b.ToolCalls, "the growing stream degrades")
+	assert.Equal(t, `{"name": "b", "parameters": {"text": "more text"}}`, b.Content)
+	assert.Equal(t, int64(len(partial)), adapter.RetainedStreamMemory(), "b no longer counts")
+
+	close(gated.release)
+	a := <-done
+	require.NoError(t, a.Err)
+	assert.Equal(t, []string{"a"}, a.ToolNames(), "the stream within the limit is unaffected")
+	assert.Zero(t, adapter.RetainedStreamMemory())
+
+	t.Run("ReleasedOnClose", func(t *testing.T) {
+		gated := &gatedStream{MockStream: tooltest.NewContentStream(partial, `"}}`), release: make(chan struct{}, 1)}
+		gated.release <- struct{}{}
+		stream := adapter.TransformStreamingResponse(gated)
+		go func() {
+			assert.Eventually(t, func() bool { return adapter.RetainedStreamMemory() > 0 }, time.Second, time.Millisecond)
+			assert.NoError(t, stream.Close())
+			assert.Zero(t, adapter.RetainedStreamMemory())
+			close(gated.release)
+		}()
+		for stream.Next() {
+		}
+	})
+}
@@ -0,0 +1,381 @@
+package tooladapter
+
+import (
+	"encoding/json"
+
+	"github.com/openai/openai-go/v3"
+)
+
+// strictStream rewrites the chunks of a StreamAdapter into the sequence the OpenAI
+// API sends (see WithStrictOpenAICompatibility): the role in the first delta only,
+// one chunk announcing each tool call followed by its arguments, and the finish
+// reason in a final chunk with an empty delta. One emitted chunk can become several,
+// so they are queued and returned by Next one at a time.
+type strictStream struct {
+	queue     []openai.ChatCompletionChunk
+	keepEmpty bool // keep empty chunks, which are keep-alive chunks with WithStreamHeartbeat
+	roleSent  bool
+	toolCalls bool // a tool call was emitted, so the finish reason reports tool calls
+	finished  bool
+}
+
+func newStrictStream(a *Adapter) *strictStream {
+	return &strictStream{keepEmpty: a.streamHeartbeat > 0}
+}
+
+// pop moves the next queued chunk into chunk and reports whether there was one.
+func (c *strictStream) pop(chunk *openai.ChatCompletionChunk) bool {
+	if len(c.queue) == 0 {
+		return false
+	}
+	*chunk = c.queue[0]
+	c.queue = c.queue[1:]
+	return true
+}
+
+// push queues the OpenAI chunks for a chunk emitted by the adapter. Metadata missing
+// from synthetic chunks is taken from first, the first upstream chunk.
+func (c *strictStream) push(chunk, first openai.ChatCompletionChunk) {
+	if len(chunk.Choices) != 1 {
+		// Usage chunks carry no choices; multi-choice chunks are relayed as they are
+		if len(chunk.Choices) == 0 || !c.finished {
+			c.queue = append(c.queue, withChunkMetadata(chunk, first))
+		}
+		return
+	}
+	if c.finished {
+		// OpenAI streams end with the finish chunk; anything after it but usage is dropped
+		return
+	}
+
+	choice := chunk.Choices[0]
+	delta := choice.Delta
+	add := func(delta openai.ChatCompletionChunkChoiceDelta, finishReason string) {
+		if !c.roleSent {
+			delta.Role = "assistant"
+			c.roleSent = true
+		} else {
+			delta.Role = ""
+		}
+		out := withChunkMetadata(openai.ChatCompletionChunk{}, first)
+		out.Choices = []openai.ChatCompletionChunkChoice{{
+			Index:        choice.Index,
+			Delta:        delta,
+			FinishReason: finishReason,
+		}}
+		c.queue = append(c.queue, out)
+	}
+
+	if delta.Content != "" {
+		if !c.roleSent {
+			// The first delta carries the role with empty content
+			add(openai.ChatCompletionChunkChoiceDelta{}, "")
+		}
+		add(openai.ChatCompletionChunkChoiceDelta{Content: delta.Content}, "")
+	}
+	for _, call := range delta.ToolCalls {
+		c.toolCalls = true
+		if call.ID != "" || call.Type != "" || call.Function.Name != "" {
+			add(openai.ChatCompletionChunkChoiceDelta{ToolCalls: []openai.ChatCompletionChunkChoiceDeltaToolCall{{
+				Index:    call.Index,
+				ID:       call.ID,
+				Type:     call.Type,
+				Function: openai.ChatCompletionChunkChoiceDeltaToolCallFunction{Name: call.Function.Name},
+			}}}, "")
+		}
+		if call.Function.Arguments != "" {
+			add(openai.ChatCompletionChunkChoiceDelta{ToolCalls: []openai.ChatCompletionChunkChoiceDeltaToolCall{{
+				Index:    call.Index,
+				Function: openai.ChatCompletionChunkChoiceDeltaToolCallFunction{Arguments: call.Function.Arguments},
+			}}}, "")
+		}
+	}
+
+	if finishReason := choice.FinishReason; finishReason != "" {
+		if c.toolCalls && finishReason == "stop" {
+			finishReason = "tool_calls"
+		}
+		if !c.roleSent {
+			add(openai.ChatCompletionChunkChoiceDelta{}, "")
+		}
+		add(openai.ChatCompletionChunkChoiceDelta{}, finishReason)
+		c.finished = true
+		if chunk.Usage.TotalTokens > 0 {
+			usage := withChunkMetadata(openai.ChatCompletionChunk{Usage: chunk.Usage}, first)
+			c.queue = append(c.queue, usage)
+		}
+		return
+	}
+
+	if delta.Content == "" && len(delta.ToolCalls) == 0 && c.keepEmpty {
+		add(openai.ChatCompletionChunkChoiceDelta{}, "")
+	}
+}
+
+// withChunkMetadata fills the ID, object, creation time, model and fingerprint of
+// chunk from first where chunk lacks them.
+func withChunkMetadata(chunk, first openai.ChatCompletionChunk) openai.ChatCompletionChunk {
+	if chunk.ID == "" {
+		chunk.ID = first.ID
+	}
+	if chunk.Created == 0 {
+		chunk.Created = first.Created
+	}
+	if chunk.Model == "" {
+		chunk.Model = first.Model
+	}
+	if chunk.SystemFingerprint == "" {
+		chunk.SystemFingerprint = first.SystemFingerprint
+	}
+	if chunk.ServiceTier == "" {
+		chunk.ServiceTier = first.ServiceTier
+	}
+	chunk.Object = "chat.completion.chunk"
+	return chunk
+}
+
+// strictCompletion fills the fields of a transformed completion that OpenAI always
+// populates. The choices are copied, so the response passed in is not modified.
+func strictCompletion(completion openai.ChatCompletion) openai.ChatCompletion {
+	completion.Object = "chat.completion"
+	completion.Choices = append([]openai.ChatCompletionChoice(nil), completion.Choices...)
+	for i := range completion.Choices {
+		message := &completion.Choices[i].Message
+		message.Role = "assistant"
+		message.ToolCalls = append([]openai.ChatCompletionMessageToolCallUnion(nil), message.ToolCalls...)
+		for j := range message.ToolCalls {
+			message.ToolCalls[j].Type = functionType
+		}
+	}
+	return completion
+}
+
+// wireChunk and the types below encode chunks and completions with the fields the
+// OpenAI API populates, in its order. json.Marshal of the openai-go types instead
+// writes every field, with empty strings where OpenAI sends null.
+type wireChunk struct {
+	ID                string            `json:"id"`
+	Object            string            `json:"object"`
+	Created           int64             `json:"created"`
+	Model             string            `json:"model"`
+	ServiceTier       string            `json:"service_tier,omitempty"`
+	SystemFingerprint *string           `json:"system_fingerprint"`
+	Choices           []wireChunkChoice `json:"choices"`
+	Usage             *wireUsage        `json:"usage,omitempty"`
+}
+
+type wireChunkChoice struct {
+	Index        int64           `json:"index"`
+	Delta        wireDelta       `json:"delta"`
+	Logprobs     json.RawMessage `json:"logprobs"`
+	FinishReason *string         `json:"finish_reason"`
+}
+
+type wireDelta struct {
+	Role      string          `json:"role,omitempty"`
+	Content   json.RawMessage `json:"content,omitempty"`
+	ToolCalls []wireToolCall  `json:"tool_calls,omitempty"`
+	Refusal   json.RawMessage `json:"refusal,omitempty"`
+}
+
+type wireToolCall struct {
+	Index    *int64       `json:"index,omitempty"`
+	ID       string       `json:"id,omitempty"`
+	Type     string       `json:"type,omitempty"`
+	Function wireFunction `json:"function"`
+}
+
+type wireFunction struct {
+	Name      string `json:"name,omitempty"`
+	Arguments string `json:"arguments"`
+}
+
+type wireCompletion struct {
+	ID                string                 `json:"id"`
+	Object            string                 `json:"object"`
+	Created           int64                  `json:"created"`
+	Model             string                 `json:"model"`
+	Choices           []wireCompletionChoice `json:"choices"`
+	Usage             wireUsage              `json:"usage"`
+	ServiceTier       string                 `json:"service_tier,omitempty"`
+	SystemFingerprint *string                `json:"system_fingerprint"`
+}
+
+type wireCompletionChoice struct {
+	Index        int64           `json:"index"`
+	Message      wireMessage     `json:"message"`
+	Logprobs     json.RawMessage `json:"logprobs"`
+	FinishReason string          `json:"finish_reason"`
+}
+
+type wireMessage struct {
+	Role         string          `json:"role"`
+	Content      json.RawMessage `json:"content"`
+	Refusal      json.RawMessage `json:"refusal"`
+	FunctionCall *wireFunction   `json:"function_call,omitempty"`
+	ToolCalls    []wireToolCall  `json:"tool_calls,omitempty"`
+	Annotations  []any           `json:"annotations"`
+}
+
+type wireUsage struct {
+	PromptTokens            int64                 `json:"prompt_tokens"`
+	CompletionTokens        int64                 `json:"completion_tokens"`
+	TotalTokens             int64                 `json:"total_tokens"`
+	PromptTokensDetails     wirePromptDetails     `json:"prompt_tokens_details"`
+	CompletionTokensDetails wireCompletionDetails `json:"completion_tokens_details"`
+}
+
+type wirePromptDetails struct {
+	CachedTokens int64 `json:"cached_tokens"`
+	AudioTokens  int64 `json:"audio_tokens"`
+}
+
+type wireCompletionDetails struct {
+	ReasoningTokens          int64 `json:"reasoning_tokens"`
+	AudioTokens              int64 `json:"audio_tokens"`
+	AcceptedPredictionTokens int64 `json:"accepted_prediction_tokens"`
+	RejectedPredictionTokens int64 `json:"rejected_prediction_tokens"`
+}
+
+var wireNull = json.RawMessage("null")
+
+// MarshalOpenAIChunk encodes a stream chunk as the OpenAI API would send it: absent
+// finish reasons, logprobs and fingerprints are null rather than empty, a delta
+// carries only the fields it sets, and the delta that carries the role reports
+// content as "" or, next to tool calls, null. Fields from other providers, such as
+// reasoning_content, are left out. WriteSSEStream uses it for streams of adapters
+// created with WithStrictOpenAICompatibility.
+func MarshalOpenAIChunk(chunk openai.ChatCompletionChunk) ([]byte, error) {
+	wire := wireChunk{
+		ID:                chunk.ID,
+		Object:            "chat.completion.chunk",
+		Created:           chunk.Created,
+		Model:             chunk.Model,
+		ServiceTier:       string(chunk.ServiceTier),
+		SystemFingerprint: wireString(chunk.SystemFingerprint),
+		Choices:           make([]wireChunkChoice, 0, len(chunk.Choices)),
+	}
+	for _, choice := range chunk.Choices {
+		delta := choice.Delta
+		out := wireChunkChoice{
+			Index:        choice.Index,
+			Logprobs:     wireRaw(choice.JSON.Logprobs.Valid(), choice.JSON.Logprobs.Raw()),
+			FinishReason: wireString(choice.FinishReason),
+			Delta:        wireDelta{Role: delta.Role},
+		}
+		switch {
+		case delta.Content != "":
+			out.Delta.Content = wireText(delta.Content)
+		case delta.Role != "" && len(delta.ToolCalls) > 0:
+			out.Delta.Content = wireNull
+		case delta.Role != "":
+			out.Delta.Content = wireText("")
+		}
+		if delta.Role != "" {
+			out.Delta.Refusal = wireNull
+		}
+		for _, call := range delta.ToolCalls {
+			index := call.Index
+			out.Delta.ToolCalls = append(out.Delta.ToolCalls, wireToolCall{
+				Index:    &index,
+				ID:       call.ID,
+				Type:     call.Type,
+				Function: wireFunction{Name: call.Function.Name, Arguments: call.Function.Arguments},
+			})
+		}
+		wire.Choices = append(wire.Choices, out)
+	}
+	if chunk.Usage.TotalTokens > 0 {
+		usage := wireUsageOf(chunk.Usage)
+		wire.Usage = &usage
+	}
+	return json.Marshal(wire)
+}
+
+// MarshalOpenAICompletion encodes a completion as the OpenAI API would send it: a
+// message with tool calls and no text has null content, refusals and absent logprobs
+// are null, and fields from other providers are left out.
+func MarshalOpenAICompletion(completion openai.ChatCompletion) ([]byte, error) {
+	wire := wireCompletion{
+		ID:                completion.ID,
+		Object:            "chat.completion",
+		Created:           completion.Created,
+		Model:             completion.Model,
+		Choices:           make([]wireCompletionChoice, 0, len(completion.Choices)),
+		Usage:             wireUsageOf(completion.Usage),
+		ServiceTier:       string(completion.ServiceTier),
+		SystemFingerprint: wireString(completion.SystemFingerprint),
+	}
+	for _, choice := range completion.Choices {
+		message := choice.Message
+		out := wireCompletionChoice{
+			Index:        choice.Index,
+			Logprobs:     wireRaw(choice.JSON.Logprobs.Valid(), choice.JSON.Logprobs.Raw()),
+			FinishReason: choice.FinishReason,
+			Message: wireMessage{
+				Role:        "assistant",
+				Content:     wireText(message.Content),
+				Refusal:     wireNull,
+				Annotations: []any{},
+			},
+		}
+		if message.Content == "" && len(message.ToolCalls) > 0 {
+			out.Message.Content = wireNull
+		}
+		if message.Refusal != "" {
+			out.Message.Refusal = wireText(message.Refusal)
+		}
+		if message.FunctionCall.Name != "" {
+			out.Message.FunctionCall = &wireFunction{Name: message.FunctionCall.Name, Arguments: message.FunctionCall.Arguments}
+		}
+		for _, call := range message.ToolCalls {
+			out.Message.ToolCalls = append(out.Message.ToolCalls, wireToolCall{
+				ID:       call.ID,
+				Type:     functionType,
+				Function: wireFunction{Name: call.Function.Name, Arguments: call.Function.Arguments},
+			})
+		}
+		wire.Choices = append(wire.Choices, out)
+	}
+	return json.Marshal(wire)
+}
+
+func wireUsageOf(usage openai.CompletionUsage) wireUsage {
+	return wireUsage{
+		PromptTokens:     usage.PromptTokens,
+		CompletionTokens: usage.CompletionTokens,
+		TotalTokens:      usage.TotalTokens,
+		PromptTokensDetails: wirePromptDetails{
+			CachedTokens: usage.PromptTokensDetails.CachedTokens,
+			AudioTokens:  usage.PromptTokensDetails.AudioTokens,
+		},
+		CompletionTokensDetails: wireCompletionDetails{
+			ReasoningTokens:          usage.CompletionTokensDetails.ReasoningTokens,
+			AudioTokens:              usage.CompletionTokensDetails.AudioTokens,
+			AcceptedPredictionTokens: usage.CompletionTokensDetails.AcceptedPredictionTokens,
+			RejectedPredictionTokens: usage.CompletionTokensDetails.RejectedPredictionTokens,
+		},
+	}
+}
+
+// wireString returns nil, encoded as null, for an empty string.
+func wireString(s string) *string {
+	if s == "" {
+		return nil
+	}
+	return &s
+}
+
+// wireText encodes s as a JSON string.
+func wireText(s string) json.RawMessage {
+	encoded, _ := json.Marshal(s)
+	return encoded
+}
+
+// wireRaw returns raw JSON received from upstream, or null when there is none.
+func wireRaw(valid bool, raw string) json.RawMessage {
+	if !valid || raw == "" || !json.Valid([]byte(raw)) {
+		return wireNull
+	}
+	return json.RawMessage(raw)
+}
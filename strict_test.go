@@ -0,0 +1,220 @@
+package tooladapter_test
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	tooladapter "github.com/juburr/openai-tool-adapter/v3"
+	"github.com/juburr/openai-tool-adapter/v3/tooltest"
+	"github.com/openai/openai-go/v3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// wireShape returns decoded JSON with every string, number and boolean replaced by
+// its zero value, so two payloads compare equal when they populate the same fields
+// with the same kinds of values, nulls included.
+func wireShape(t *testing.T, data []byte) any {
+	t.Helper()
+	var value any
+	require.NoError(t, json.Unmarshal(data, &value), string(data))
+	var shape func(any) any
+	shape = func(v any) any {
+		switch v := v.(type) {
+		case map[string]any:
+			for key, field := range v {
+				v[key] = shape(field)
+			}
+			return v
+		case []any:
+			for i, item := range v {
+				v[i] = shape(item)
+			}
+			return v
+		case string:
+			return ""
+		case float64:
+			return 0.0
+		case bool:
+			return false
+		default:
+			return v
+		}
+	}
+	return shape(value)
+}
+
+// streamShapes returns the shapes of a sequence of chunk payloads with consecutive
+// duplicates collapsed, since OpenAI splits content and arguments into any number
+// of chunks.
+func streamShapes(t *testing.T, payloads [][]byte) []any {
+	t.Helper()
+	var shapes []any
+	for _, payload := range payloads {
+		shape := wireShape(t, payload)
+		if len(shapes) > 0 && reflect.DeepEqual(shapes[len(shapes)-1], shape) {
+			continue
+		}
+		shapes = append(shapes, shape)
+	}
+	return shapes
+}
+
+// fixtureStream reads the data payloads of a recorded OpenAI SSE stream.
+func fixtureStream(t *testing.T, name string) [][]byte {
+	t.Helper()
+	file, err := os.Open(filepath.Join("testdata", "openai", name))
+	require.NoError(t, err)
+	defer file.Close()
+
+	var payloads [][]byte
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		data, ok := strings.CutPrefix(scanner.Text(), "data: ")
+		if ok && data != "[DONE]" {
+			payloads = append(payloads, []byte(data))
+		}
+	}
+	require.NoError(t, scanner.Err())
+	return payloads
+}
+
+// strictUpstreamChunk returns a content chunk with the metadata of an OpenAI chunk.
+func strictUpstreamChunk(content string) openai.ChatCompletionChunk {
+	chunk := upstreamChunk(content)
+	chunk.SystemFingerprint = "fp_local"
+	chunk.ServiceTier = "default"
+	return chunk
+}
+
+// strictStreamPayloads drains a strict stream and encodes each chunk.
+func strictStreamPayloads(t *testing.T, adapter *tooladapter.Adapter, upstream tooladapter.ChatCompletionStreamInterface) [][]byte {
+	t.Helper()
+	stream := adapter.TransformStreamingResponse(upstream)
+	defer stream.Close()
+
+	var payloads [][]byte
+	for stream.Next() {
+		data, err := tooladapter.MarshalOpenAIChunk(stream.Current())
+		require.NoError(t, err)
+		payloads = append(payloads, data)
+	}
+	require.NoError(t, stream.Err())
+	return payloads
+}
+
+func TestWithStrictOpenAICompatibility(t *testing.T) {
+	strict := tooladapter.New(
+		tooladapter.WithStrictOpenAICompatibility(true),
+		tooladapter.WithToolPolicy(tooladapter.ToolDrainAll),
+	)
+
+	t.Run("StreamToolCallsMatchFixture", func(t *testing.T) {
+		upstream := tooltest.NewMockStream(
+			strictUpstreamChunk(`[{"name": "get_weather", "parameters": {"city": "Paris"}}, `),
+			strictUpstreamChunk(`{"name": "get_time", "parameters": {"zone": "CET"}}]`),
+			tooltest.FinishChunk("stop"),
+		)
+		got := strictStreamPayloads(t, strict, upstream)
+		assert.Equal(t, streamShapes(t, fixtureStream(t, "stream_tool_calls.txt")), streamShapes(t, got))
+
+		var finish openai.ChatCompletionChunk
+		require.NoError(t, json.Unmarshal(got[len(got)-1], &finish))
+		assert.Equal(t, "chatcmpl-123", finish.ID, "synthetic chunks carry the upstream ID")
+		assert.Equal(t, "llama-3", finish.Model)
+	})
+
+	t.Run("StreamTextMatchesFixture", func(t *testing.T) {
+		finish := tooltest.FinishChunk("stop")
+		upstream := tooltest.NewMockStream(strictUpstreamChunk("Hello"), strictUpstreamChunk("!"), finish)
+		got := strictStreamPayloads(t, tooladapter.New(tooladapter.WithStrictOpenAICompatibility(true)), upstream)
+		assert.Equal(t, streamShapes(t, fixtureStream(t, "stream_text.txt")), streamShapes(t, got))
+	})
+
+	t.Run("StreamSingleFinish", func(t *testing.T) {
+		adapter := tooladapter.New(tooladapter.WithStrictOpenAICompatibility(true))
+		upstream := tooltest.NewMockStream(
+			strictUpstreamChunk(`{"name": "get_weather", "parameters": {"city": "Paris"}}`),
+			strictUpstreamChunk(" trailing text"),
+			tooltest.FinishChunk("stop"),
+		)
+		result := tooltest.Drain(adapter.TransformStreamingResponse(upstream))
+		require.NoError(t, result.Err)
+		assert.Equal(t, "tool_calls", result.FinishReason)
+		assert.Equal(t, []string{"get_weather", ""}, result.ToolNames(), "the call, then its arguments")
+		assert.JSONEq(t, `{"city": "Paris"}`, result.ToolCalls[1].Function.Arguments)
+		assert.Equal(t, 3, result.Chunks, "the trailing text and the upstream finish are dropped")
+	})
+
+	t.Run("Accumulate", func(t *testing.T) {
+		upstream := tooltest.NewMockStream(
+			strictUpstreamChunk(`{"name": "get_weather", "parameters": {"city": "Paris"}}`),
+			tooltest.FinishChunk("stop"),
+		)
+		completion, err := strict.TransformStreamingResponse(upstream).Accumulate()
+		require.NoError(t, err)
+		require.Len(t, completion.Choices[0].Message.ToolCalls, 1)
+		assert.JSONEq(t, `{"city": "Paris"}`, completion.Choices[0].Message.ToolCalls[0].Function.Arguments)
+		assert.Equal(t, "tool_calls", completion.Choices[0].FinishReason)
+	})
+
+	t.Run("CompletionToolCallsMatchFixture", func(t *testing.T) {
+		resp := tooltest.Completion(`{"name": "get_weather", "parameters": {"city": "Paris"}}`)
+		resp.ID, resp.Model, resp.Created = "chatcmpl-local", "llama-3", 1700000000
+		resp.ServiceTier, resp.SystemFingerprint = "default", "fp_local"
+		resp.Usage = openai.CompletionUsage{PromptTokens: 80, CompletionTokens: 20, TotalTokens: 100}
+
+		transformed, err := strict.TransformCompletionsResponse(resp)
+		require.NoError(t, err)
+		got, err := tooladapter.MarshalOpenAICompletion(transformed)
+		require.NoError(t, err)
+
+		fixture, err := os.ReadFile(filepath.Join("testdata", "openai", "completion_tool_calls.json"))
+		require.NoError(t, err)
+		assert.Equal(t, wireShape(t, fixture), wireShape(t, got))
+		assert.EqualValues(t, "chat.completion", transformed.Object)
+	})
+
+	t.Run("CompletionTextMatchesFixture", func(t *testing.T) {
+		resp := tooltest.Completion("Hello! How can I help you today?")
+		resp.ID, resp.Model, resp.Created = "chatcmpl-local", "llama-3", 1700000000
+		resp.ServiceTier, resp.SystemFingerprint = "default", "fp_local"
+		resp.Choices[0].FinishReason = "stop"
+
+		transformed, err := strict.TransformCompletionsResponse(resp)
+		require.NoError(t, err)
+		got, err := tooladapter.MarshalOpenAICompletion(transformed)
+		require.NoError(t, err)
+
+		fixture, err := os.ReadFile(filepath.Join("testdata", "openai", "completion_text.json"))
+		require.NoError(t, err)
+		assert.Equal(t, wireShape(t, fixture), wireShape(t, got))
+	})
+
+	t.Run("WriteSSEStream", func(t *testing.T) {
+		upstream := tooltest.NewMockStream(
+			strictUpstreamChunk(`{"name": "get_weather", "parameters": {"city": "Paris"}}`),
+			tooltest.FinishChunk("stop"),
+		)
+		recorder := httptest.NewRecorder()
+		require.NoError(t, tooladapter.WriteSSEStream(context.Background(), recorder, strict.TransformStreamingResponse(upstream)))
+
+		body := recorder.Body.String()
+		assert.Contains(t, body, `"content":null`)
+		assert.Contains(t, body, `"finish_reason":null`)
+		assert.NotContains(t, body, `"function_call"`)
+	})
+
+	t.Run("Config", func(t *testing.T) {
+		cfg, err := tooladapter.LoadConfig(strings.NewReader(`{"strict_openai_compatibility": true}`))
+		require.NoError(t, err)
+		assert.True(t, cfg.StrictOpenAICompatibility)
+	})
+}
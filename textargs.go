@@ -0,0 +1,151 @@
+package tooladapter
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/openai/openai-go/v3"
+)
+
+// ArgumentMode selects how the model passes the arguments of a tool, set per tool
+// with WithToolArgumentModes.
+type ArgumentMode int
+
+const (
+	// ArgumentModeJSON passes arguments as the JSON "parameters" of a tool call
+	// (default).
+	ArgumentModeJSON ArgumentMode = iota
+
+	// ArgumentModeText passes a single raw text argument as the body of a fenced
+	// code block tagged with the tool's name, delivered as {"input": "..."}. Raw
+	// SQL, Markdown or source code then needs no JSON escaping.
+	ArgumentModeText
+)
+
+// String returns a human-readable string representation of the ArgumentMode.
+func (m ArgumentMode) String() string {
+	switch m {
+	case ArgumentModeJSON:
+		return "ArgumentModeJSON"
+	case ArgumentModeText:
+		return "ArgumentModeText"
+	default:
+		return fmt.Sprintf("ArgumentMode(%d)", int(m))
+	}
+}
+
+// TextArgumentKey is the argument that holds the text of a call to an
+// ArgumentModeText tool.
+const TextArgumentKey = "input"
+
+// fencedBlockPattern matches a fenced code block: its info string and its body. The
+// closing fence must start a line, so backticks inside a line of the body do not
+// end it.
+var fencedBlockPattern = regexp.MustCompile("(?s)```([^\\s`]+)[ \\t]*\\r?\\n(.*?)\\r?\\n[ \\t]*```")
+
+// fencedCall is a tool call written as a fenced code block.
+type fencedCall struct {
+	name     string // model-facing function name
+	argument string
+	body     string
+}
+
+// fencedTool returns the tool a fenced block tagged with tag calls and the argument
+// its body is passed as: the tool named tag when it takes text arguments, or the
+// WithCodeBlockTools tool registered for the language tag.
+func (a *Adapter) fencedTool(tag string) (name, argument string, ok bool) {
+	if a.argumentModes[a.restoreToolName(tag)] == ArgumentModeText {
+		return tag, TextArgumentKey, true
+	}
+	if tool, ok := a.codeBlockTools[strings.ToLower(tag)]; ok {
+		if a.toolNamespace != "" {
+			return a.toolNamespace + "." + tool.Name, tool.Argument, true
+		}
+		return tool.Name, tool.Argument, true
+	}
+	return "", "", false
+}
+
+// hasFencedTools reports whether any fenced code block can be a tool call.
+func (a *Adapter) hasFencedTools() bool {
+	return len(a.argumentModes) > 0 || len(a.codeBlockTools) > 0
+}
+
+// fencedCalls returns the tool calls written as fenced code blocks in content.
+func (a *Adapter) fencedCalls(content string) []fencedCall {
+	if !a.hasFencedTools() || !strings.Contains(content, "```") {
+		return nil
+	}
+	var calls []fencedCall
+	for _, match := range fencedBlockPattern.FindAllStringSubmatch(content, -1) {
+		if name, argument, ok := a.fencedTool(match[1]); ok {
+			calls = append(calls, fencedCall{name: name, argument: argument, body: match[2]})
+		}
+	}
+	return calls
+}
+
+// fencedCandidate returns a JSON array candidate holding the tool calls written as
+// fenced code blocks in content, or "" when there are none.
+func (a *Adapter) fencedCandidate(content string) string {
+	calls := a.fencedCalls(content)
+	if len(calls) == 0 {
+		return ""
+	}
+	array := make([]map[string]any, len(calls))
+	for i, call := range calls {
+		array[i] = map[string]any{
+			"name":       call.name,
+			"parameters": map[string]string{call.argument: call.body},
+		}
+	}
+	encoded, err := json.Marshal(array)
+	if err != nil {
+		return ""
+	}
+	return string(encoded)
+}
+
+// hasFencedToolCallPattern reports whether trimmed content starts a fenced code
+// block that calls a tool.
+func (a *Adapter) hasFencedToolCallPattern(trimmed string) bool {
+	if !a.hasFencedTools() || !strings.HasPrefix(trimmed, "```") {
+		return false
+	}
+	tag := strings.TrimPrefix(trimmed, "```")
+	if end := strings.IndexAny(tag, " \t\r\n`"); end >= 0 {
+		tag = tag[:end]
+	}
+	_, _, ok := a.fencedTool(tag)
+	return ok
+}
+
+// hasCompleteFencedCall reports whether content holds a closed fenced code block
+// calling a tool.
+func (a *Adapter) hasCompleteFencedCall(content string) bool {
+	return len(a.fencedCalls(content)) > 0
+}
+
+// appendTextArgumentTools adds the instruction of the prompt language for calling
+// the tools that take text arguments to a tool prompt, when tools, the model-facing
+// tools of a request, include any.
+func (a *Adapter) appendTextArgumentTools(tools []openai.ChatCompletionToolUnionParam, toolPrompt string) string {
+	if len(a.argumentModes) == 0 {
+		return toolPrompt
+	}
+	var names []string
+	for _, tool := range tools {
+		if function := tool.GetFunction(); function != nil {
+			if a.argumentModes[a.restoreToolName(function.Name)] == ArgumentModeText {
+				names = append(names, function.Name)
+			}
+		}
+	}
+	if len(names) == 0 {
+		return toolPrompt
+	}
+	example := "```" + names[0] + "\n...\n```"
+	return toolPrompt + "\n\n" + fmt.Sprintf(a.language().textArgumentTools, strings.Join(names, ", ")) + "\n" + example
+}
@@ -0,0 +1,97 @@
+package tooladapter_test
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	tooladapter "github.com/juburr/openai-tool-adapter/v3"
+	"github.com/juburr/openai-tool-adapter/v3/tooltest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWithToolArgumentModes(t *testing.T) {
+	const query = "SELECT \"name\", note FROM users\nWHERE path LIKE 'C:\\\\%' -- `quoted`"
+	modes := map[string]tooladapter.ArgumentMode{"run_sql": tooladapter.ArgumentModeText}
+	adapter := tooladapter.New(
+		tooladapter.WithSystemMessageSupport(true),
+		tooladapter.WithToolArgumentModes(modes))
+
+	t.Run("Prompt", func(t *testing.T) {
+		transformed, err := adapter.TransformCompletionsRequest(tooltest.Request(
+			tooltest.Tool("run_sql", "Run a query"), tooltest.Tool("get_time", "Get the time")))
+		require.NoError(t, err)
+		prompt := systemPrompt(t, transformed)
+		assert.Contains(t, prompt, "These functions take raw text instead of JSON parameters: run_sql.")
+		assert.Contains(t, prompt, "```run_sql\n...\n```")
+
+		transformed, err = adapter.TransformCompletionsRequest(tooltest.Request(tooltest.Tool("get_time", "Get the time")))
+		require.NoError(t, err)
+		assert.NotContains(t, systemPrompt(t, transformed), "raw text", "no text tools offered")
+	})
+
+	t.Run("Response", func(t *testing.T) {
+		resp, err := adapter.TransformCompletionsResponse(tooltest.Completion("Let me look that up.\n```run_sql\n" + query + "\n```"))
+		require.NoError(t, err)
+		require.Len(t, resp.Choices[0].Message.ToolCalls, 1)
+		call := resp.Choices[0].Message.ToolCalls[0]
+		assert.Equal(t, "run_sql", call.Function.Name)
+		expected, err := json.Marshal(map[string]string{"input": query})
+		require.NoError(t, err)
+		assert.JSONEq(t, string(expected), call.Function.Arguments)
+	})
+
+	t.Run("SeveralBlocks", func(t *testing.T) {
+		resp, err := adapter.TransformCompletionsResponse(tooltest.Completion(
+			"```run_sql\nSELECT 1\n```\n\n```run_sql\nSELECT 2\n```"))
+		require.NoError(t, err)
+		require.Len(t, resp.Choices[0].Message.ToolCalls, 1, "ToolStopOnFirst keeps the first call")
+		assert.JSONEq(t, `{"input": "SELECT 1"}`, resp.Choices[0].Message.ToolCalls[0].Function.Arguments)
+
+		drainAll := tooladapter.New(tooladapter.WithToolArgumentModes(modes), tooladapter.WithToolPolicy(tooladapter.ToolDrainAll))
+		resp, err = drainAll.TransformCompletionsResponse(tooltest.Completion(
+			"```run_sql\nSELECT 1\n```\n\n```run_sql\nSELECT 2\n```"))
+		require.NoError(t, err)
+		assert.Len(t, resp.Choices[0].Message.ToolCalls, 2)
+	})
+
+	t.Run("OtherBlocksAreContent", func(t *testing.T) {
+		content := "Try this:\n```sql\nSELECT 1\n```"
+		resp, err := adapter.TransformCompletionsResponse(tooltest.Completion(content))
+		require.NoError(t, err)
+		assert.Empty(t, resp.Choices[0].Message.ToolCalls)
+		assert.Equal(t, content, resp.Choices[0].Message.Content)
+	})
+
+	t.Run("JSONCallStillAccepted", func(t *testing.T) {
+		resp, err := adapter.TransformCompletionsResponse(tooltest.Completion(`{"name": "run_sql", "parameters": {"input": "SELECT 1"}}`))
+		require.NoError(t, err)
+		require.Len(t, resp.Choices[0].Message.ToolCalls, 1)
+		assert.JSONEq(t, `{"input": "SELECT 1"}`, resp.Choices[0].Message.ToolCalls[0].Function.Arguments)
+	})
+
+	t.Run("Namespace", func(t *testing.T) {
+		namespaced := tooladapter.New(tooladapter.WithToolArgumentModes(modes), tooladapter.WithToolNamespace("db"))
+		resp, err := namespaced.TransformCompletionsResponse(tooltest.Completion("```db.run_sql\nSELECT 1\n```"))
+		require.NoError(t, err)
+		require.Len(t, resp.Choices[0].Message.ToolCalls, 1)
+		assert.Equal(t, "run_sql", resp.Choices[0].Message.ToolCalls[0].Function.Name)
+	})
+
+	t.Run("Streaming", func(t *testing.T) {
+		result := tooltest.Drain(adapter.TransformStreamingResponse(tooltest.NewContentStream(
+			"```run_sql\n", "SELECT \"name\"\n", "FROM users\n", "```")))
+		require.NoError(t, result.Err)
+		require.Len(t, result.ToolCalls, 1)
+		assert.Equal(t, "run_sql", result.ToolCalls[0].Function.Name)
+		assert.JSONEq(t, `{"input": "SELECT \"name\"\nFROM users"}`, result.ToolCalls[0].Function.Arguments)
+		assert.Empty(t, result.Content)
+	})
+
+	t.Run("Config", func(t *testing.T) {
+		cfg, err := tooladapter.LoadConfig(strings.NewReader(`{"tool_argument_modes": {"run_sql": "text"}}`))
+		require.NoError(t, err)
+		assert.Equal(t, modes, cfg.ToolArgumentModes)
+	})
+}
@@ -0,0 +1,277 @@
+package tooladapter
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/openai/openai-go/v3"
+)
+
+// Tags around the chain-of-thought of reasoning models such as DeepSeek-R1.
+const (
+	ThinkOpenTag  = "<think>"
+	ThinkCloseTag = "</think>"
+)
+
+// ThinkBlockPolicy controls how think blocks (text between ThinkOpenTag and
+// ThinkCloseTag) in model output are handled. Reasoning models often write JSON
+// while thinking, which would otherwise be mistaken for a tool call.
+type ThinkBlockPolicy int
+
+const (
+	// ThinkBlocksDetect treats think blocks like any other content, including tool
+	// call detection (default).
+	ThinkBlocksDetect ThinkBlockPolicy = iota
+
+	// ThinkBlocksPassThrough excludes think blocks from tool call detection but
+	// keeps them in the content. Streams emit them as they arrive, without holding
+	// them back for detection.
+	ThinkBlocksPassThrough
+
+	// ThinkBlocksStrip excludes think blocks from tool call detection and removes
+	// them, tags included, from the content. StreamAdapter.Reasoning returns what
+	// was removed from a stream; use SplitThinkBlocks on a non-streaming response
+	// before transforming it to keep its reasoning.
+	ThinkBlocksStrip
+)
+
+// String returns a human-readable string representation of the ThinkBlockPolicy.
+func (p ThinkBlockPolicy) String() string {
+	switch p {
+	case ThinkBlocksDetect:
+		return "ThinkBlocksDetect"
+	case ThinkBlocksPassThrough:
+		return "ThinkBlocksPassThrough"
+	case ThinkBlocksStrip:
+		return "ThinkBlocksStrip"
+	default:
+		return fmt.Sprintf("ThinkBlockPolicy(%d)", int(p))
+	}
+}
+
+// SplitThinkBlocks separates the think blocks of content from the rest. thinking
+// is the text inside the blocks, without tags, and rest is content with the blocks
+// removed. A block left open runs to the end of content.
+func SplitThinkBlocks(content string) (thinking, rest string) {
+	if !strings.Contains(content, ThinkOpenTag) {
+		return "", content
+	}
+	var splitter thinkSplitter
+	var thought, other strings.Builder
+	for _, segment := range splitter.split(content) {
+		if segment.thinking {
+			thought.WriteString(segment.text)
+		} else {
+			other.WriteString(segment.text)
+		}
+	}
+	for _, segment := range splitter.flush() {
+		if segment.thinking {
+			thought.WriteString(segment.text)
+		} else {
+			other.WriteString(segment.text)
+		}
+	}
+	return thought.String(), other.String()
+}
+
+// detectableContent returns the part of content searched for tool calls.
+func (a *Adapter) detectableContent(content string) string {
+	if a.thinkBlocks == ThinkBlocksDetect {
+		return content
+	}
+	_, rest := SplitThinkBlocks(content)
+	return rest
+}
+
+// stripThinkBlocks removes think blocks from the content of every choice when
+// ThinkBlocksStrip is in effect, copying the choices rather than modifying resp.
+func (a *Adapter) stripThinkBlocks(resp openai.ChatCompletion) openai.ChatCompletion {
+	if a.thinkBlocks != ThinkBlocksStrip {
+		return resp
+	}
+	copied := false
+	for i, choice := range resp.Choices {
+		thinking, rest := SplitThinkBlocks(choice.Message.Content)
+		if thinking == "" && rest == choice.Message.Content {
+			continue
+		}
+		if !copied {
+			resp.Choices = append([]openai.ChatCompletionChoice(nil), resp.Choices...)
+			copied = true
+		}
+		resp.Choices[i].Message.Content = strings.TrimLeft(rest, " \t\r\n")
+		a.log(LogCategoryParse).Debug("Stripped think blocks from choice",
+			"choice_index", i,
+			"thinking_length", len(thinking))
+	}
+	return resp
+}
+
+// thinkSegment is a piece of content inside or outside a think block.
+type thinkSegment struct {
+	text     string
+	thinking bool
+}
+
+// thinkSplitter splits streamed content at think block tags, which may themselves
+// be split across chunks.
+type thinkSplitter struct {
+	inThink bool
+	pending string // a possible partial tag at the end of the last content
+}
+
+// split returns the segments of content, holding back a trailing partial tag.
+func (t *thinkSplitter) split(content string) []thinkSegment {
+	content = t.pending + content
+	t.pending = ""
+
+	var segments []thinkSegment
+	for content != "" {
+		tag := ThinkOpenTag
+		if t.inThink {
+			tag = ThinkCloseTag
+		}
+		if i := strings.Index(content, tag); i >= 0 {
+			segments = appendThinkSegment(segments, content[:i], t.inThink)
+			content = content[i+len(tag):]
+			t.inThink = !t.inThink
+			continue
+		}
+		// Hold back a suffix that may be the start of the tag
+		for n := min(len(tag)-1, len(content)); n > 0; n-- {
+			if strings.HasPrefix(tag, content[len(content)-n:]) {
+				t.pending = content[len(content)-n:]
+				content = content[:len(content)-n]
+				break
+			}
+		}
+		segments = appendThinkSegment(segments, content, t.inThink)
+		break
+	}
+	return segments
+}
+
+// flush returns content held back as a possible partial tag at the end of output.
+func (t *thinkSplitter) flush() []thinkSegment {
+	pending := t.pending
+	t.pending = ""
+	return appendThinkSegment(nil, pending, t.inThink)
+}
+
+func appendThinkSegment(segments []thinkSegment, text string, thinking bool) []thinkSegment {
+	if text == "" {
+		return segments
+	}
+	return append(segments, thinkSegment{text: text, thinking: thinking})
+}
+
+// thinkStream splits upstream content chunks at think block tags so that every
+// content chunk it returns lies entirely inside or outside a think block. With
+// ThinkBlocksStrip, think content is recorded and removed instead.
+type thinkStream struct {
+	ChatCompletionStreamInterface
+
+	// Guards the fields below, released while reading upstream so that
+	// StreamAdapter.Snapshot can run meanwhile
+	mu sync.Mutex
+
+	strip    bool
+	splitter thinkSplitter
+	queue    []thinkChunk
+	current  thinkChunk
+	thought  strings.Builder
+	ended    bool
+}
+
+// thinkChunk is a chunk returned by thinkStream.
+type thinkChunk struct {
+	chunk    openai.ChatCompletionChunk
+	thinking bool
+}
+
+func newThinkStream(source ChatCompletionStreamInterface, policy ThinkBlockPolicy) *thinkStream {
+	return &thinkStream{ChatCompletionStreamInterface: source, strip: policy == ThinkBlocksStrip}
+}
+
+// Next advances to the next chunk, reading upstream when no split chunks remain.
+func (t *thinkStream) Next() bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for len(t.queue) == 0 {
+		if t.ended {
+			return false
+		}
+		t.mu.Unlock()
+		hasNext := t.ChatCompletionStreamInterface.Next()
+		t.mu.Lock()
+		if !hasNext {
+			t.ended = true
+			t.enqueue(openai.ChatCompletionChunk{}, t.splitter.flush(), false)
+			continue
+		}
+		chunk := t.ChatCompletionStreamInterface.Current()
+		if len(chunk.Choices) == 0 || chunk.Choices[0].Delta.Content == "" {
+			t.queue = append(t.queue, thinkChunk{chunk: chunk})
+			continue
+		}
+		t.enqueue(chunk, t.splitter.split(chunk.Choices[0].Delta.Content), true)
+	}
+	t.current, t.queue = t.queue[0], t.queue[1:]
+	return true
+}
+
+// enqueue queues one chunk per segment, using chunk's metadata. The last chunk
+// keeps the rest of chunk, such as its finish reason, when keep is set; a chunk
+// left without content and anything else to deliver is dropped.
+func (t *thinkStream) enqueue(chunk openai.ChatCompletionChunk, segments []thinkSegment, keep bool) {
+	var kept []thinkSegment
+	for _, segment := range segments {
+		if segment.thinking {
+			t.thought.WriteString(segment.text)
+			if t.strip {
+				continue
+			}
+		}
+		kept = append(kept, segment)
+	}
+
+	for i, segment := range kept {
+		split := chunk
+		split.Choices = append([]openai.ChatCompletionChunkChoice(nil), chunk.Choices...)
+		if len(split.Choices) == 0 {
+			split.Choices = []openai.ChatCompletionChunkChoice{{}}
+		}
+		split.Choices[0].Delta.Content = segment.text
+		if i < len(kept)-1 || !keep {
+			split.Choices[0].FinishReason = ""
+		}
+		t.queue = append(t.queue, thinkChunk{chunk: split, thinking: segment.thinking})
+	}
+
+	// Deliver what the chunk carries besides its content, such as a finish reason
+	if keep && len(kept) == 0 && (chunk.Choices[0].FinishReason != "" || chunk.Choices[0].Delta.Role != "") {
+		rest := chunk
+		rest.Choices = append([]openai.ChatCompletionChunkChoice(nil), chunk.Choices...)
+		rest.Choices[0].Delta.Content = ""
+		t.queue = append(t.queue, thinkChunk{chunk: rest})
+	}
+}
+
+// Current returns the chunk returned by the last call to Next.
+func (t *thinkStream) Current() openai.ChatCompletionChunk {
+	return t.current.chunk
+}
+
+// thinking reports whether the current chunk is think block content.
+func (t *thinkStream) thinking() bool {
+	return t.current.thinking
+}
+
+// reasoning returns the think block content read so far.
+func (t *thinkStream) reasoning() string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.thought.String()
+}
@@ -0,0 +1,117 @@
+package tooladapter_test
+
+import (
+	"strings"
+	"testing"
+
+	tooladapter "github.com/juburr/openai-tool-adapter/v3"
+	"github.com/juburr/openai-tool-adapter/v3/tooltest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	thought     = `I could call {"name": "delete_files", "parameters": {}} but weather fits better.`
+	thinkBlock  = tooladapter.ThinkOpenTag + thought + tooladapter.ThinkCloseTag
+	weatherJSON = `{"name": "get_weather", "parameters": {"city": "Paris"}}`
+)
+
+func TestSplitThinkBlocks(t *testing.T) {
+	thinking, rest := tooladapter.SplitThinkBlocks("<think>a</think>b<think>c</think>d")
+	assert.Equal(t, "ac", thinking)
+	assert.Equal(t, "bd", rest)
+
+	thinking, rest = tooladapter.SplitThinkBlocks("answer <think>still thinking")
+	assert.Equal(t, "still thinking", thinking, "an open block runs to the end")
+	assert.Equal(t, "answer ", rest)
+
+	thinking, rest = tooladapter.SplitThinkBlocks("no blocks <thin")
+	assert.Empty(t, thinking)
+	assert.Equal(t, "no blocks <thin", rest)
+}
+
+func TestWithThinkBlocks_NonStreaming(t *testing.T) {
+	t.Run("DetectByDefault", func(t *testing.T) {
+		resp, err := tooladapter.New().TransformCompletionsResponse(tooltest.Completion(thinkBlock))
+		require.NoError(t, err)
+		require.Len(t, resp.Choices[0].Message.ToolCalls, 1)
+		assert.Equal(t, "delete_files", resp.Choices[0].Message.ToolCalls[0].Function.Name)
+	})
+
+	t.Run("PassThroughIgnoresThinkBlock", func(t *testing.T) {
+		adapter := tooladapter.New(tooladapter.WithThinkBlocks(tooladapter.ThinkBlocksPassThrough))
+		resp, err := adapter.TransformCompletionsResponse(tooltest.Completion(thinkBlock + "It is sunny."))
+		require.NoError(t, err)
+		assert.Empty(t, resp.Choices[0].Message.ToolCalls)
+		assert.Equal(t, thinkBlock+"It is sunny.", resp.Choices[0].Message.Content)
+	})
+
+	t.Run("PassThroughDetectsCallAfterThinkBlock", func(t *testing.T) {
+		adapter := tooladapter.New(tooladapter.WithThinkBlocks(tooladapter.ThinkBlocksPassThrough))
+		resp, err := adapter.TransformCompletionsResponse(tooltest.Completion(thinkBlock + "\n" + weatherJSON))
+		require.NoError(t, err)
+		require.Len(t, resp.Choices[0].Message.ToolCalls, 1)
+		assert.Equal(t, "get_weather", resp.Choices[0].Message.ToolCalls[0].Function.Name)
+	})
+
+	t.Run("Strip", func(t *testing.T) {
+		adapter := tooladapter.New(tooladapter.WithThinkBlocks(tooladapter.ThinkBlocksStrip))
+		completion := tooltest.Completion(thinkBlock + "\nIt is sunny.")
+		resp, err := adapter.TransformCompletionsResponse(completion)
+		require.NoError(t, err)
+		assert.Empty(t, resp.Choices[0].Message.ToolCalls)
+		assert.Equal(t, "It is sunny.", resp.Choices[0].Message.Content)
+		assert.Equal(t, thinkBlock+"\nIt is sunny.", completion.Choices[0].Message.Content, "the input is not modified")
+	})
+}
+
+func TestWithThinkBlocks_Streaming(t *testing.T) {
+	// Tags and the drafted JSON are split across chunks
+	chunks := []string{"<thi", "nk>I could call ", `{"name": "delete_files", `, `"parameters": {}}</th`, "ink>", "It is sunny."}
+
+	t.Run("Strip", func(t *testing.T) {
+		adapter := tooladapter.New(tooladapter.WithThinkBlocks(tooladapter.ThinkBlocksStrip))
+		stream := adapter.TransformStreamingResponse(tooltest.NewContentStream(chunks...))
+		result := tooltest.Drain(stream)
+		require.NoError(t, result.Err)
+		assert.Empty(t, result.ToolCalls)
+		assert.Equal(t, "It is sunny.", result.Content)
+		assert.Equal(t, "stop", result.FinishReason)
+		assert.Equal(t, `I could call {"name": "delete_files", "parameters": {}}`, stream.Reasoning())
+	})
+
+	t.Run("PassThrough", func(t *testing.T) {
+		adapter := tooladapter.New(tooladapter.WithThinkBlocks(tooladapter.ThinkBlocksPassThrough))
+		stream := adapter.TransformStreamingResponse(tooltest.NewContentStream(chunks...))
+		result := tooltest.Drain(stream)
+		require.NoError(t, result.Err)
+		assert.Empty(t, result.ToolCalls)
+		assert.Equal(t, `I could call {"name": "delete_files", "parameters": {}}It is sunny.`, result.Content)
+		assert.NotEmpty(t, stream.Reasoning())
+	})
+
+	t.Run("ToolCallAfterThinkBlock", func(t *testing.T) {
+		adapter := tooladapter.New(tooladapter.WithThinkBlocks(tooladapter.ThinkBlocksStrip))
+		parts := append(append([]string(nil), chunks[:5]...), weatherJSON)
+		result := tooltest.Drain(adapter.TransformStreamingResponse(tooltest.NewContentStream(parts...)))
+		require.NoError(t, result.Err)
+		assert.Equal(t, []string{"get_weather"}, result.ToolNames())
+		assert.False(t, strings.Contains(result.Content, "delete_files"))
+	})
+
+	t.Run("DetectByDefault", func(t *testing.T) {
+		stream := tooladapter.New().TransformStreamingResponse(tooltest.NewContentStream(chunks...))
+		result := tooltest.Drain(stream)
+		assert.Equal(t, []string{"delete_files"}, result.ToolNames())
+		assert.Empty(t, stream.Reasoning())
+	})
+}
+
+func TestWithThinkBlocks_Config(t *testing.T) {
+	cfg, err := tooladapter.LoadConfig(strings.NewReader(`{"think_blocks": "pass_through"}`))
+	require.NoError(t, err)
+	assert.Equal(t, tooladapter.ThinkBlocksPassThrough, cfg.ThinkBlocks)
+
+	_, err = tooladapter.NewFromConfig(tooladapter.Config{ThinkBlocks: tooladapter.ThinkBlockPolicy(9)})
+	require.ErrorIs(t, err, tooladapter.ErrInvalidConfig)
+}
@@ -0,0 +1,64 @@
+package tooladapter
+
+import "unicode/utf8"
+
+// TokenCounter estimates the number of tokens a model's tokenizer produces for
+// text. Wrap the tokenizer of the target model, such as a tiktoken or
+// SentencePiece binding, for accurate counts, or use ApproximateTokenCounter.
+// Implementations must be safe for concurrent use.
+type TokenCounter func(text string) int
+
+// ApproximateTokenCounter estimates tokens as one per four characters, a common
+// rule of thumb for English text with BPE tokenizers. It needs no model files but
+// can be off by a wide margin for code, JSON and other languages.
+func ApproximateTokenCounter(text string) int {
+	return (utf8.RuneCountInString(text) + 3) / 4
+}
+
+// countTokens estimates the tokens of text, or returns 0 without a TokenCounter.
+func (a *Adapter) countTokens(text string) int {
+	if a.tokenCounter == nil || text == "" {
+		return 0
+	}
+	return a.tokenCounter(text)
+}
+
+// tokenEstimate counts tokens with the WithTokenCounter counter, falling back to
+// ApproximateTokenCounter.
+func (a *Adapter) tokenEstimate(text string) int {
+	if a.tokenCounter == nil {
+		return ApproximateTokenCounter(text)
+	}
+	return a.tokenCounter(text)
+}
+
+// checkInjectedPromptSize rejects an injected prompt over the configured ceilings.
+// tokens is the prompt's count with the WithTokenCounter counter, if any.
+func (a *Adapter) checkInjectedPromptSize(prompt string, tokens, toolCount int) error {
+	tooLarge := &PromptTooLargeError{
+		Bytes:     len(prompt),
+		MaxBytes:  a.maxInjectedPromptBytes,
+		MaxTokens: a.maxInjectedPromptTokens,
+		ToolCount: toolCount,
+	}
+	exceeded := a.maxInjectedPromptBytes > 0 && len(prompt) > a.maxInjectedPromptBytes
+	if !exceeded && a.maxInjectedPromptTokens > 0 {
+		if a.tokenCounter == nil {
+			tokens = a.tokenEstimate(prompt)
+		}
+		tooLarge.Tokens = tokens
+		exceeded = tokens > a.maxInjectedPromptTokens
+	}
+	if !exceeded {
+		return nil
+	}
+
+	a.log(LogCategoryLimit).Warn("Injected prompt exceeds the configured ceiling, rejecting request",
+		"prompt_bytes", tooLarge.Bytes,
+		"max_bytes", tooLarge.MaxBytes,
+		"prompt_tokens", tooLarge.Tokens,
+		"max_tokens", tooLarge.MaxTokens,
+		"tool_count", toolCount,
+		"recommendation", "Reduce the tools per request, for example with WithToolSelector, or raise the ceiling")
+	return tooLarge
+}
@@ -0,0 +1,162 @@
+package tooladapter_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	tooladapter "github.com/juburr/openai-tool-adapter/v3"
+	"github.com/juburr/openai-tool-adapter/v3/tooltest"
+	"github.com/openai/openai-go/v3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// wordCounter counts whitespace-separated words, standing in for a tokenizer.
+func wordCounter(text string) int {
+	return len(strings.Fields(text))
+}
+
+func TestApproximateTokenCounter(t *testing.T) {
+	assert.Equal(t, 0, tooladapter.ApproximateTokenCounter(""))
+	assert.Equal(t, 1, tooladapter.ApproximateTokenCounter("abc"))
+	assert.Equal(t, 2, tooladapter.ApproximateTokenCounter("abcdefgh"))
+	assert.Equal(t, 1, tooladapter.ApproximateTokenCounter("日本語"), "counts characters, not bytes")
+}
+
+func TestWithTokenCounter(t *testing.T) {
+	t.Run("Metrics", func(t *testing.T) {
+		var transformations []tooladapter.ToolTransformationData
+		var detections []tooladapter.FunctionCallDetectionData
+		adapter := tooladapter.New(
+			tooladapter.WithSystemMessageSupport(true),
+			tooladapter.WithTokenCounter(wordCounter),
+			tooladapter.WithMetricsCallback(func(data tooladapter.MetricEventData) {
+				switch d := data.(type) {
+				case tooladapter.ToolTransformationData:
+					transformations = append(transformations, d)
+				case tooladapter.FunctionCallDetectionData:
+					detections = append(detections, d)
+				}
+			}))
+
+		req, err := adapter.TransformCompletionsRequest(tooltest.Request(tooltest.Tool("get_weather", "Get the weather")))
+		require.NoError(t, err)
+		require.Len(t, transformations, 1)
+		assert.Equal(t, wordCounter(systemPrompt(t, req)), transformations[0].InjectedTokens)
+
+		content := `{"name": "get_weather", "parameters": {"param1": "Paris"}}`
+		_, err = adapter.TransformCompletionsResponse(tooltest.Completion(content))
+		require.NoError(t, err)
+		tooltest.Drain(adapter.TransformStreamingResponse(tooltest.NewContentStream(content)))
+		require.Len(t, detections, 2)
+		for _, d := range detections {
+			assert.Equal(t, wordCounter(content), d.ContentTokens, "streaming=%v", d.Streaming)
+		}
+	})
+
+	t.Run("DisabledByDefault", func(t *testing.T) {
+		var data tooladapter.ToolTransformationData
+		adapter := tooladapter.New(tooladapter.WithMetricsCallback(func(d tooladapter.MetricEventData) {
+			if td, ok := d.(tooladapter.ToolTransformationData); ok {
+				data = td
+			}
+		}))
+		_, err := adapter.TransformCompletionsRequest(tooltest.Request(tooltest.Tool("get_weather", "")))
+		require.NoError(t, err)
+		assert.Positive(t, data.PromptLength)
+		assert.Zero(t, data.InjectedTokens)
+	})
+
+	t.Run("Session", func(t *testing.T) {
+		adapter := tooladapter.New(
+			tooladapter.WithSystemMessageSupport(true),
+			tooladapter.WithTokenCounter(wordCounter))
+		session := adapter.NewSession()
+		ctx := context.Background()
+
+		req, err := session.TransformRequest(ctx, tooltest.Request(tooltest.Tool("get_weather", "Get the weather")))
+		require.NoError(t, err)
+		injected := wordCounter(systemPrompt(t, req))
+
+		call := `{"name": "get_weather", "parameters": {"param1": "Paris"}}`
+		_, err = session.TransformResponse(ctx, tooltest.Completion("Checking. "+call))
+		require.NoError(t, err)
+		_, err = session.TransformResponse(ctx, tooltest.Completion("It is sunny."))
+		require.NoError(t, err)
+		stream := session.TransformStreamingResponse(ctx, tooltest.NewContentStream(call))
+		for stream.Next() {
+		}
+		require.NoError(t, stream.Close())
+
+		usage := session.Usage()
+		assert.Equal(t, int64(injected), usage.InjectedTokens)
+		assert.Equal(t, int64(2*wordCounter(call)+1), usage.ToolCallTokens,
+			"suppressed prefaces count, plain replies do not")
+	})
+}
+
+func TestWithTokenCounter_NoToolsNoCount(t *testing.T) {
+	adapter := tooladapter.New(tooladapter.WithTokenCounter(func(string) int {
+		t.Fatal("the counter runs only on injected prompts and tool call output")
+		return 0
+	}))
+	_, err := adapter.TransformCompletionsRequest(openai.ChatCompletionNewParams{
+		Model:    openai.ChatModelGPT4o,
+		Messages: []openai.ChatCompletionMessageParamUnion{openai.UserMessage("hi")},
+	})
+	require.NoError(t, err)
+	_, err = adapter.TransformCompletionsResponse(tooltest.Completion("hello"))
+	require.NoError(t, err)
+}
+
+func TestWithMaxInjectedPromptBytes(t *testing.T) {
+	req := tooltest.Request(tooltest.Tool("get_weather", "Get the weather"), tooltest.Tool("send_email", "Send an email"))
+
+	_, err := tooladapter.New(tooladapter.WithMaxInjectedPromptBytes(100_000)).TransformCompletionsRequest(req)
+	require.NoError(t, err)
+
+	_, err = tooladapter.New(tooladapter.WithMaxInjectedPromptBytes(100)).TransformCompletionsRequest(req)
+	require.ErrorIs(t, err, tooladapter.ErrPromptTooLarge)
+	var tooLarge *tooladapter.PromptTooLargeError
+	require.ErrorAs(t, err, &tooLarge)
+	assert.Equal(t, 100, tooLarge.MaxBytes)
+	assert.Greater(t, tooLarge.Bytes, 100)
+	assert.Equal(t, 2, tooLarge.ToolCount)
+	var transformErr *tooladapter.TransformError
+	require.ErrorAs(t, err, &transformErr)
+	assert.Equal(t, tooladapter.PhaseRequest, transformErr.Phase)
+
+	// Requests without tools or results inject nothing
+	_, err = tooladapter.New(tooladapter.WithMaxInjectedPromptBytes(1)).TransformCompletionsRequest(openai.ChatCompletionNewParams{
+		Model:    openai.ChatModelGPT4o,
+		Messages: []openai.ChatCompletionMessageParamUnion{openai.UserMessage("hi")},
+	})
+	require.NoError(t, err)
+}
+
+func TestWithMaxInjectedPromptTokens(t *testing.T) {
+	req := tooltest.Request(tooltest.Tool("get_weather", "Get the weather"))
+
+	t.Run("ApproximateByDefault", func(t *testing.T) {
+		_, err := tooladapter.New(tooladapter.WithMaxInjectedPromptTokens(10)).TransformCompletionsRequest(req)
+		var tooLarge *tooladapter.PromptTooLargeError
+		require.ErrorAs(t, err, &tooLarge)
+		assert.Equal(t, 10, tooLarge.MaxTokens)
+		assert.Greater(t, tooLarge.Tokens, 10)
+		assert.Contains(t, err.Error(), "tokens")
+	})
+
+	t.Run("UsesTokenCounter", func(t *testing.T) {
+		adapter := tooladapter.New(
+			tooladapter.WithTokenCounter(func(string) int { return 5 }),
+			tooladapter.WithMaxInjectedPromptTokens(5))
+		_, err := adapter.TransformCompletionsRequest(req)
+		require.NoError(t, err)
+	})
+
+	t.Run("RejectsNegative", func(t *testing.T) {
+		_, err := tooladapter.NewFromConfig(tooladapter.Config{MaxInjectedPromptTokens: -1})
+		require.ErrorIs(t, err, tooladapter.ErrInvalidConfig)
+	})
+}
@@ -593,6 +593,21 @@ func runAllowMixed_ContentAndToolsBothEmitted(t *testing.T) {
 	assert.Contains(t, allContent, "Here's some content before", "Should preserve content before tools")
 }
 
+func runAllowMixed_UnfinishedCallEmittedOnce(t *testing.T) {
+	adapter := New(WithToolPolicy(ToolAllowMixed), WithLogLevel(slog.LevelError))
+	stream := adapter.TransformStreamingResponse(NewMockStream([]string{`{"name":`, ` "get_weather"`, " is all I know."}))
+	defer func() { require.NoError(t, stream.Close()) }()
+
+	var content strings.Builder
+	for stream.Next() {
+		if chunk := stream.Current(); len(chunk.Choices) > 0 {
+			content.WriteString(chunk.Choices[0].Delta.Content)
+		}
+	}
+	assert.Equal(t, `{"name": "get_weather" is all I know.`, content.String(),
+		"the chunk that started buffering was already emitted and must not be repeated")
+}
+
 func runAllowMixed_MultipleToolsWithContent(t *testing.T) {
 	opts := append([]Option{WithToolPolicy(ToolAllowMixed)}, WithLogLevel(slog.LevelError))
 	adapter := New(opts...)
@@ -645,6 +660,7 @@ func TestToolPolicyStreaming(t *testing.T) {
 
 	t.Run("ToolAllowMixed_Streaming/ContentAndToolsBothEmitted", func(t *testing.T) { runAllowMixed_ContentAndToolsBothEmitted(t) })
 	t.Run("ToolAllowMixed_Streaming/MultipleToolsWithContent", func(t *testing.T) { runAllowMixed_MultipleToolsWithContent(t) })
+	t.Run("ToolAllowMixed_Streaming/UnfinishedCallEmittedOnce", func(t *testing.T) { runAllowMixed_UnfinishedCallEmittedOnce(t) })
 }
 
 // ============================================================================
@@ -1150,6 +1166,7 @@ func TestToolPolicyString(t *testing.T) {
 		{ToolCollectThenStop, "ToolCollectThenStop"},
 		{ToolDrainAll, "ToolDrainAll"},
 		{ToolAllowMixed, "ToolAllowMixed"},
+		{ToolEmitAndContinue, "ToolEmitAndContinue"},
 		{ToolPolicy(999), "ToolPolicy(999)"}, // Unknown policy
 	}
 
@@ -0,0 +1,90 @@
+package tooladapter_test
+
+import (
+	"testing"
+
+	tooladapter "github.com/juburr/openai-tool-adapter/v3"
+	"github.com/juburr/openai-tool-adapter/v3/tooltest"
+	"github.com/openai/openai-go/v3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// emittedChunks drains a stream, returning every chunk it emits.
+func emittedChunks(t *testing.T, stream *tooladapter.StreamAdapter) []openai.ChatCompletionChunk {
+	t.Helper()
+	var chunks []openai.ChatCompletionChunk
+	for stream.Next() {
+		chunks = append(chunks, stream.Current())
+	}
+	require.NoError(t, stream.Err())
+	require.NoError(t, stream.Close())
+	return chunks
+}
+
+func TestToolEmitAndContinue(t *testing.T) {
+	adapter := tooladapter.New(tooladapter.WithToolPolicy(tooladapter.ToolEmitAndContinue))
+
+	t.Run("EmitsCallAndKeepsStreaming", func(t *testing.T) {
+		chunks := emittedChunks(t, adapter.TransformStreamingResponse(tooltest.NewContentStream(
+			"Let me check.",
+			`{"name": "get_weather", `,
+			`"parameters": {"param1": "Paris"}}`,
+			" While that runs, ",
+			"Paris is lovely.",
+			`{"name": "get_time", "parameters": {"param1": "Paris"}}`,
+			" Done.")))
+
+		var content string
+		var calls []string
+		var indexes []int64
+		for _, chunk := range chunks {
+			require.Len(t, chunk.Choices, 1)
+			choice := chunk.Choices[0]
+			content += choice.Delta.Content
+			for _, call := range choice.Delta.ToolCalls {
+				calls = append(calls, call.Function.Name)
+				indexes = append(indexes, call.Index)
+				assert.Empty(t, choice.FinishReason, "tool call chunks do not end the stream")
+			}
+		}
+		assert.Equal(t, "Let me check. While that runs, Paris is lovely. Done.", content)
+		assert.Equal(t, []string{"get_weather", "get_time"}, calls)
+		assert.Equal(t, []int64{0, 1}, indexes, "calls are indexed across the stream")
+		assert.Equal(t, "tool_calls", string(chunks[len(chunks)-1].Choices[0].FinishReason))
+	})
+
+	t.Run("PlainContentStops", func(t *testing.T) {
+		result := tooltest.Drain(adapter.TransformStreamingResponse(tooltest.NewContentStream("Hello", " there")))
+		require.NoError(t, result.Err)
+		assert.Equal(t, "Hello there", result.Content)
+		assert.Equal(t, "stop", result.FinishReason)
+	})
+
+	t.Run("MaxCallsAcrossStream", func(t *testing.T) {
+		limited := tooladapter.New(
+			tooladapter.WithToolPolicy(tooladapter.ToolEmitAndContinue),
+			tooladapter.WithToolMaxCalls(1))
+		result := tooltest.Drain(limited.TransformStreamingResponse(tooltest.NewContentStream(
+			`{"name": "get_weather", "parameters": {"param1": "Paris"}}`,
+			" and ",
+			`{"name": "get_time", "parameters": {"param1": "Paris"}}`)))
+		require.NoError(t, result.Err)
+		assert.Equal(t, []string{"get_weather"}, result.ToolNames())
+		assert.Equal(t, " and ", result.Content, "later calls are dropped, not emitted as text")
+	})
+
+	t.Run("NonStreamingKeepsContent", func(t *testing.T) {
+		resp, err := adapter.TransformCompletionsResponse(tooltest.Completion(
+			`Checking. {"name": "get_weather", "parameters": {"param1": "Paris"}}`))
+		require.NoError(t, err)
+		assert.Len(t, resp.Choices[0].Message.ToolCalls, 1)
+		assert.Contains(t, resp.Choices[0].Message.Content, "Checking.")
+	})
+
+	t.Run("Config", func(t *testing.T) {
+		var policy tooladapter.ToolPolicy
+		require.NoError(t, policy.UnmarshalText([]byte("emit_and_continue")))
+		assert.Equal(t, tooladapter.ToolEmitAndContinue, policy)
+	})
+}
@@ -0,0 +1,171 @@
+package tooladapter
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+
+	"github.com/openai/openai-go/v3"
+	"github.com/openai/openai-go/v3/shared"
+)
+
+// ToolCallConstraint selects how WithToolCallConstraint sends the ToolCallSchema of
+// a request to a backend with schema-constrained decoding.
+type ToolCallConstraint int
+
+const (
+	// ToolCallConstraintNone sends no schema (default).
+	ToolCallConstraintNone ToolCallConstraint = iota
+
+	// ToolCallConstraintResponseFormat sets response_format to a json_schema format,
+	// as vLLM, SGLang, LM Studio and the llama.cpp server accept.
+	ToolCallConstraintResponseFormat
+
+	// ToolCallConstraintGuidedJSON sets vLLM's guided_json field.
+	ToolCallConstraintGuidedJSON
+
+	// ToolCallConstraintTGIGrammar sets Text Generation Inference's grammar field to
+	// a json grammar.
+	ToolCallConstraintTGIGrammar
+)
+
+// String returns a human-readable string representation of the ToolCallConstraint.
+func (c ToolCallConstraint) String() string {
+	switch c {
+	case ToolCallConstraintNone:
+		return "ToolCallConstraintNone"
+	case ToolCallConstraintResponseFormat:
+		return "ToolCallConstraintResponseFormat"
+	case ToolCallConstraintGuidedJSON:
+		return "ToolCallConstraintGuidedJSON"
+	case ToolCallConstraintTGIGrammar:
+		return "ToolCallConstraintTGIGrammar"
+	default:
+		return fmt.Sprintf("ToolCallConstraint(%d)", int(c))
+	}
+}
+
+// toolCallSchemaName is the json_schema name ToolCallConstraintResponseFormat sends.
+const toolCallSchemaName = "tool_calls"
+
+// ToolCallSchema returns a JSON Schema describing the tool call JSON the injected
+// prompt asks for: an array of one or more {"name": ..., "parameters": ...}
+// objects, each naming one of the function tools and carrying parameters valid
+// against its schema. With singleCall the array holds exactly one call. Pass it to
+// backends with schema-constrained decoding, such as Outlines, vLLM or TGI, or let
+// WithToolCallConstraint attach it.
+//
+// Tool parameter schemas are embedded as they are; tools without parameters accept
+// an object or null. It returns an error when tools has no function tool.
+func ToolCallSchema(tools []openai.ChatCompletionToolUnionParam, singleCall bool) (map[string]any, error) {
+	var calls []any
+	for _, tool := range tools {
+		function := tool.GetFunction()
+		if function == nil {
+			continue
+		}
+		var parameters any = map[string]any{"type": []string{"object", "null"}}
+		if function.Parameters != nil {
+			parameters = map[string]any(function.Parameters)
+		}
+		calls = append(calls, map[string]any{
+			"type": "object",
+			"properties": map[string]any{
+				"name":       map[string]any{"type": "string", "enum": []string{function.Name}},
+				"parameters": parameters,
+			},
+			"required":             []string{"name", "parameters"},
+			"additionalProperties": false,
+		})
+	}
+	if len(calls) == 0 {
+		return nil, errors.New("no function tools to generate a schema for")
+	}
+
+	schema := map[string]any{"type": "array", "minItems": 1}
+	if singleCall {
+		schema["maxItems"] = 1
+	}
+	if len(calls) == 1 {
+		schema["items"] = calls[0]
+	} else {
+		schema["items"] = map[string]any{"anyOf": calls}
+	}
+	return schema, nil
+}
+
+// constrainedTools returns the tools a constraint on a request's output should
+// allow, the tool choice mode of the request, and whether the output should be
+// constrained at all: not when the tool choice is "none".
+func (a *Adapter) constrainedTools(req openai.ChatCompletionNewParams, tools []openai.ChatCompletionToolUnionParam) ([]openai.ChatCompletionToolUnionParam, string, bool) {
+	mode, forced := a.promptToolChoice(req.ToolChoice)
+	if mode == "none" {
+		return nil, mode, false
+	}
+	if forced != "" {
+		tools = slices.DeleteFunc(slices.Clone(tools), func(tool openai.ChatCompletionToolUnionParam) bool {
+			function := tool.GetFunction()
+			return function == nil || function.Name != forced
+		})
+	}
+	return tools, mode, true
+}
+
+// addToolCallSchema sends the ToolCallSchema of tools, the model-facing tools of a
+// transformed request, as WithToolCallConstraint selects, when the request requires
+// a tool call. A response format or field the request already has is kept.
+func (a *Adapter) addToolCallSchema(req openai.ChatCompletionNewParams, tools []openai.ChatCompletionToolUnionParam) openai.ChatCompletionNewParams {
+	if a.toolCallConstraint == ToolCallConstraintNone {
+		return req
+	}
+	tools, mode, ok := a.constrainedTools(req, tools)
+	if !ok || mode == "" || mode == "auto" {
+		return req
+	}
+
+	field := map[ToolCallConstraint]string{
+		ToolCallConstraintGuidedJSON: "guided_json",
+		ToolCallConstraintTGIGrammar: gbnfGrammarField,
+	}[a.toolCallConstraint]
+	format := req.ResponseFormat
+	constrained := format.OfText != nil || format.OfJSONSchema != nil || format.OfJSONObject != nil
+	if field != "" {
+		_, constrained = req.ExtraFields()[field]
+	}
+	if constrained {
+		a.log(LogCategoryRequest).Debug("Request already constrains its output, tool call schema not attached",
+			"constraint", a.toolCallConstraint.String())
+		return req
+	}
+
+	schema, err := ToolCallSchema(tools, requestsSingleToolCall(req))
+	if err != nil {
+		a.log(LogCategoryRequest).Warn("Failed to generate tool call schema, sending the request without it",
+			"error", err)
+		return req
+	}
+	switch a.toolCallConstraint {
+	case ToolCallConstraintResponseFormat:
+		req.ResponseFormat = openai.ChatCompletionNewParamsResponseFormatUnion{
+			OfJSONSchema: &shared.ResponseFormatJSONSchemaParam{
+				JSONSchema: shared.ResponseFormatJSONSchemaJSONSchemaParam{Name: toolCallSchemaName, Schema: schema},
+			},
+		}
+	default:
+		var value any = schema
+		if a.toolCallConstraint == ToolCallConstraintTGIGrammar {
+			value = map[string]any{"type": "json", "value": schema}
+		}
+		fields := maps.Clone(req.ExtraFields())
+		if fields == nil {
+			fields = make(map[string]any, 1)
+		}
+		fields[field] = value
+		req.SetExtraFields(fields)
+	}
+	a.log(LogCategoryRequest).Debug("Attached tool call schema",
+		"constraint", a.toolCallConstraint.String(),
+		"tool_count", len(tools))
+	return req
+}
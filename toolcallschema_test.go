@@ -0,0 +1,118 @@
+package tooladapter_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	tooladapter "github.com/juburr/openai-tool-adapter/v3"
+	"github.com/juburr/openai-tool-adapter/v3/tooltest"
+	"github.com/openai/openai-go/v3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestToolCallSchema(t *testing.T) {
+	schema, err := tooladapter.ToolCallSchema(gbnfTools(), false)
+	require.NoError(t, err)
+	encoded, err := json.Marshal(schema)
+	require.NoError(t, err)
+
+	var decoded struct {
+		Type     string `json:"type"`
+		MinItems int    `json:"minItems"`
+		MaxItems *int   `json:"maxItems"`
+		Items    struct {
+			AnyOf []struct {
+				Properties struct {
+					Name struct {
+						Enum []string `json:"enum"`
+					} `json:"name"`
+					Parameters map[string]any `json:"parameters"`
+				} `json:"properties"`
+				Required []string `json:"required"`
+			} `json:"anyOf"`
+		} `json:"items"`
+	}
+	require.NoError(t, json.Unmarshal(encoded, &decoded))
+	assert.Equal(t, "array", decoded.Type)
+	assert.Equal(t, 1, decoded.MinItems)
+	assert.Nil(t, decoded.MaxItems)
+	require.Len(t, decoded.Items.AnyOf, 2)
+	assert.Equal(t, []string{"get_weather"}, decoded.Items.AnyOf[0].Properties.Name.Enum)
+	assert.Equal(t, []any{"city"}, decoded.Items.AnyOf[0].Properties.Parameters["required"], "parameter schemas are embedded")
+	assert.Equal(t, []any{"object", "null"}, decoded.Items.AnyOf[1].Properties.Parameters["type"])
+	assert.Equal(t, []string{"name", "parameters"}, decoded.Items.AnyOf[1].Required)
+
+	single, err := tooladapter.ToolCallSchema(gbnfTools()[:1], true)
+	require.NoError(t, err)
+	assert.Equal(t, 1, single["maxItems"])
+	assert.NotContains(t, single["items"], "anyOf")
+
+	_, err = tooladapter.ToolCallSchema(nil, false)
+	assert.Error(t, err)
+}
+
+func TestWithToolCallConstraint(t *testing.T) {
+	required := tooltest.Request(gbnfTools()...)
+	required.ToolChoice = openai.ChatCompletionToolChoiceOptionUnionParam{OfAuto: openai.String("required")}
+	body := func(constraint tooladapter.ToolCallConstraint, req openai.ChatCompletionNewParams) map[string]json.RawMessage {
+		transformed, err := tooladapter.New(tooladapter.WithToolCallConstraint(constraint)).TransformCompletionsRequest(req)
+		require.NoError(t, err)
+		encoded, err := json.Marshal(transformed)
+		require.NoError(t, err)
+		var fields map[string]json.RawMessage
+		require.NoError(t, json.Unmarshal(encoded, &fields))
+		return fields
+	}
+
+	t.Run("ResponseFormat", func(t *testing.T) {
+		var format struct {
+			Type       string `json:"type"`
+			JSONSchema struct {
+				Name   string         `json:"name"`
+				Schema map[string]any `json:"schema"`
+			} `json:"json_schema"`
+		}
+		require.NoError(t, json.Unmarshal(body(tooladapter.ToolCallConstraintResponseFormat, required)["response_format"], &format))
+		assert.Equal(t, "json_schema", format.Type)
+		assert.Equal(t, "tool_calls", format.JSONSchema.Name)
+		assert.Equal(t, "array", format.JSONSchema.Schema["type"])
+	})
+
+	t.Run("GuidedJSON", func(t *testing.T) {
+		forced := required
+		forced.ToolChoice = openai.ToolChoiceOptionFunctionToolChoice(openai.ChatCompletionNamedToolChoiceFunctionParam{Name: "get_time"})
+		var schema struct {
+			Items struct {
+				Properties struct {
+					Name struct {
+						Enum []string `json:"enum"`
+					} `json:"name"`
+				} `json:"properties"`
+			} `json:"items"`
+		}
+		require.NoError(t, json.Unmarshal(body(tooladapter.ToolCallConstraintGuidedJSON, forced)["guided_json"], &schema))
+		assert.Equal(t, []string{"get_time"}, schema.Items.Properties.Name.Enum, "only the forced function")
+	})
+
+	t.Run("TGIGrammar", func(t *testing.T) {
+		var grammar struct {
+			Type  string         `json:"type"`
+			Value map[string]any `json:"value"`
+		}
+		require.NoError(t, json.Unmarshal(body(tooladapter.ToolCallConstraintTGIGrammar, required)["grammar"], &grammar))
+		assert.Equal(t, "json", grammar.Type)
+		assert.Equal(t, "array", grammar.Value["type"])
+	})
+
+	t.Run("OptionalToolCalls", func(t *testing.T) {
+		fields := body(tooladapter.ToolCallConstraintResponseFormat, tooltest.Request(gbnfTools()...))
+		assert.NotContains(t, fields, "response_format", "a schema cannot allow a text answer")
+	})
+
+	t.Run("CallerResponseFormat", func(t *testing.T) {
+		req := required
+		req.ResponseFormat = openai.ChatCompletionNewParamsResponseFormatUnion{OfText: &openai.ResponseFormatTextParam{}}
+		assert.JSONEq(t, `{"type": "text"}`, string(body(tooladapter.ToolCallConstraintResponseFormat, req)["response_format"]))
+	})
+}
@@ -0,0 +1,109 @@
+package tooladapter
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/openai/openai-go/v3"
+)
+
+// toolsDelta is how the function tools offered to the model differ from those of an
+// earlier turn, by model-facing name.
+type toolsDelta struct {
+	added   []string
+	removed []string
+	renamed [][2]string // old and new name
+	changed []string    // same name, different description or parameters
+}
+
+// empty reports whether the tools did not change.
+func (d toolsDelta) empty() bool {
+	return len(d.added) == 0 && len(d.removed) == 0 && len(d.renamed) == 0 && len(d.changed) == 0
+}
+
+// diffTools compares the function tools of two turns. A removed tool whose
+// description and parameters reappear under a new name counts as renamed.
+func diffTools(previous, current []openai.ChatCompletionToolUnionParam) toolsDelta {
+	definitions := func(tools []openai.ChatCompletionToolUnionParam) ([]string, map[string]string) {
+		var names []string
+		defs := make(map[string]string, len(tools))
+		for _, tool := range tools {
+			function := tool.GetFunction()
+			if function == nil {
+				continue
+			}
+			parameters, _ := json.Marshal(function.Parameters)
+			names = append(names, function.Name)
+			defs[function.Name] = function.Description.Or("") + "\x00" + string(parameters)
+		}
+		return names, defs
+	}
+	previousNames, previousDefs := definitions(previous)
+	currentNames, currentDefs := definitions(current)
+
+	var delta toolsDelta
+	for _, name := range currentNames {
+		def, ok := previousDefs[name]
+		switch {
+		case !ok:
+			delta.added = append(delta.added, name)
+		case def != currentDefs[name]:
+			delta.changed = append(delta.changed, name)
+		}
+	}
+	for _, name := range previousNames {
+		if _, ok := currentDefs[name]; ok {
+			continue
+		}
+		index := slices.IndexFunc(delta.added, func(added string) bool {
+			return currentDefs[added] == previousDefs[name]
+		})
+		if index == -1 {
+			delta.removed = append(delta.removed, name)
+			continue
+		}
+		delta.renamed = append(delta.renamed, [2]string{name, delta.added[index]})
+		delta.added = slices.Delete(delta.added, index, index+1)
+	}
+	return delta
+}
+
+// appendToolsUpdated adds the tools-updated instruction of the prompt language to a
+// tool prompt when the Session attached to ctx offered different tools in an
+// earlier turn, so the model does not follow earlier instructions or calls that
+// name tools it no longer has. The full prompt still lists the current tools.
+func (a *Adapter) appendToolsUpdated(ctx context.Context, tools []openai.ChatCompletionToolUnionParam, toolPrompt string) string {
+	delta, ok := sessionToolsDelta(ctx, tools)
+	if !ok || delta.empty() {
+		return toolPrompt
+	}
+
+	language := a.language()
+	lines := []string{language.toolsUpdated}
+	if len(delta.added) > 0 {
+		lines = append(lines, fmt.Sprintf(language.toolsAdded, strings.Join(delta.added, ", ")))
+	}
+	if len(delta.renamed) > 0 {
+		renames := make([]string, len(delta.renamed))
+		for i, rename := range delta.renamed {
+			renames[i] = rename[0] + " → " + rename[1]
+		}
+		lines = append(lines, fmt.Sprintf(language.toolsRenamed, strings.Join(renames, ", ")))
+	}
+	if len(delta.changed) > 0 {
+		lines = append(lines, fmt.Sprintf(language.toolsChanged, strings.Join(delta.changed, ", ")))
+	}
+	if len(delta.removed) > 0 {
+		lines = append(lines, fmt.Sprintf(language.toolsRemoved, strings.Join(delta.removed, ", ")))
+	}
+
+	a.log(LogCategoryRequest).Debug("Tools changed since an earlier turn",
+		"added", delta.added,
+		"removed", delta.removed,
+		"renamed", len(delta.renamed),
+		"changed", delta.changed)
+	return toolPrompt + "\n\n" + strings.Join(lines, "\n- ")
+}
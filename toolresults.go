@@ -0,0 +1,133 @@
+package tooladapter
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/openai/openai-go/v3"
+)
+
+// ToolResultTransformer rewrites the content of a tool result before it is injected
+// into the prompt, for example to shorten large API payloads. It receives the tool
+// call ID, which is empty when the tool message carried none. Transformers are
+// called concurrently when the adapter is shared.
+type ToolResultTransformer func(callID, content string) string
+
+// HeadTailToolResultTransformer returns a ToolResultTransformer that shortens tool
+// results longer than headBytes+tailBytes to their first headBytes and last
+// tailBytes, joined by a line stating how many bytes were omitted. Cuts fall on
+// UTF-8 character boundaries, so slightly fewer bytes may be kept; the result
+// exceeds the limits only by the omission line. Negative limits are treated as 0.
+func HeadTailToolResultTransformer(headBytes, tailBytes int) ToolResultTransformer {
+	headBytes = max(headBytes, 0)
+	tailBytes = max(tailBytes, 0)
+	return func(_, content string) string {
+		if len(content) <= headBytes+tailBytes {
+			return content
+		}
+		headEnd := headBytes
+		for headEnd > 0 && !utf8.RuneStart(content[headEnd]) {
+			headEnd--
+		}
+		tailStart := len(content) - tailBytes
+		for tailStart < len(content) && !utf8.RuneStart(content[tailStart]) {
+			tailStart++
+		}
+		return fmt.Sprintf("%s\n[... %d bytes omitted ...]\n%s", content[:headEnd], tailStart-headEnd, content[tailStart:])
+	}
+}
+
+// transformToolResults applies WithToolResultTransformer to every tool result.
+func (a *Adapter) transformToolResults(results []toolResult) {
+	if a.toolResultTransformer == nil {
+		return
+	}
+	for i := range results {
+		content := a.toolResultTransformer(results[i].CallID, results[i].Content)
+		if content == results[i].Content {
+			continue
+		}
+		a.log(LogCategoryRequest).Debug("Transformed tool result",
+			"tool_call_id", results[i].CallID,
+			"original_length", len(results[i].Content),
+			"transformed_length", len(content))
+		results[i].Content = content
+	}
+}
+
+// extractToolResultAttachments moves the non-text parts of multimodal tool results
+// into their attachments when WithMultimodalToolResults is enabled, leaving numbered
+// placeholders in the text. It returns the number of attachments.
+func (a *Adapter) extractToolResultAttachments(results []toolResult) int {
+	if !a.multimodalToolResults {
+		return 0
+	}
+	label := a.language().toolResultAttachment
+	count := 0
+	for i := range results {
+		parts, ok := multimodalContentParts(results[i].Content)
+		if !ok {
+			continue
+		}
+		var text []string
+		for _, part := range parts {
+			if part.OfText != nil {
+				text = append(text, part.OfText.Text)
+				continue
+			}
+			count++
+			text = append(text, fmt.Sprintf(label, count))
+			results[i].Attachments = append(results[i].Attachments, part)
+		}
+		a.log(LogCategoryRequest).Debug("Extracted tool result attachments",
+			"tool_call_id", results[i].CallID,
+			"attachment_count", len(results[i].Attachments),
+			"content_length", len(results[i].Content))
+		results[i].Content = strings.Join(text, "\n")
+	}
+	return count
+}
+
+// multimodalContentParts decodes tool result content holding a JSON array of content
+// parts, or a single part, with at least one part other than text.
+func multimodalContentParts(content string) ([]openai.ChatCompletionContentPartUnionParam, bool) {
+	trimmed := strings.TrimSpace(content)
+	var parts []openai.ChatCompletionContentPartUnionParam
+	switch {
+	case strings.HasPrefix(trimmed, "["):
+		if json.Unmarshal([]byte(trimmed), &parts) != nil {
+			return nil, false
+		}
+	case strings.HasPrefix(trimmed, "{"):
+		var part openai.ChatCompletionContentPartUnionParam
+		if json.Unmarshal([]byte(trimmed), &part) != nil {
+			return nil, false
+		}
+		parts = append(parts, part)
+	default:
+		return nil, false
+	}
+	for _, part := range parts {
+		if part.OfImageURL != nil || part.OfInputAudio != nil || part.OfFile != nil {
+			return parts, true
+		}
+	}
+	return nil, false
+}
+
+// toolResultAttachmentsMessage returns a user message carrying the attachments of
+// results, each preceded by the placeholder that refers to it in the prompt.
+func (a *Adapter) toolResultAttachmentsMessage(results []toolResult) openai.ChatCompletionMessageParamUnion {
+	label := a.language().toolResultAttachment
+	var parts []openai.ChatCompletionContentPartUnionParam
+	count := 0
+	for _, result := range results {
+		for _, attachment := range result.Attachments {
+			count++
+			parts = append(parts, openai.TextContentPart(fmt.Sprintf(label, count)), attachment)
+		}
+	}
+	return openai.UserMessage(parts)
+}
@@ -0,0 +1,167 @@
+package tooladapter_test
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	tooladapter "github.com/juburr/openai-tool-adapter/v3"
+	"github.com/juburr/openai-tool-adapter/v3/tooltest"
+	"github.com/openai/openai-go/v3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// requestWithToolResult returns a request answering a get_weather call with content.
+func requestWithToolResult(content string) openai.ChatCompletionNewParams {
+	req := tooltest.Request(tooltest.Tool("get_weather", "Get the weather"))
+	req.Messages = append(req.Messages, openai.ToolMessage(content, "call_1"))
+	return req
+}
+
+// injectedText returns the text of every message of a transformed request.
+func injectedText(t *testing.T, req openai.ChatCompletionNewParams) string {
+	t.Helper()
+	var text strings.Builder
+	for _, msg := range req.Messages {
+		switch {
+		case msg.OfSystem != nil:
+			text.WriteString(msg.OfSystem.Content.OfString.Value)
+		case msg.OfUser != nil:
+			text.WriteString(msg.OfUser.Content.OfString.Value)
+		}
+	}
+	return text.String()
+}
+
+func TestHeadTailToolResultTransformer(t *testing.T) {
+	tests := []struct {
+		name       string
+		head, tail int
+		content    string
+		want       string
+	}{
+		{"WithinLimit", 4, 4, "12345678", "12345678"},
+		{"HeadAndTail", 3, 2, "abcdefghij", "abc\n[... 5 bytes omitted ...]\nij"},
+		{"HeadOnly", 4, 0, "abcdefghij", "abcd\n[... 6 bytes omitted ...]\n"},
+		{"TailOnly", 0, 3, "abcdefghij", "\n[... 7 bytes omitted ...]\nhij"},
+		{"NegativeIsZero", -1, 2, "abcdef", "\n[... 4 bytes omitted ...]\nef"},
+		// "é" and "ü" are two bytes; cuts never split them
+		{"RuneBoundaries", 2, 2, "aéxxxxüb", "a\n[... 8 bytes omitted ...]\nb"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got := tooladapter.HeadTailToolResultTransformer(tc.head, tc.tail)("call_1", tc.content)
+			assert.Equal(t, tc.want, got)
+		})
+	}
+}
+
+func TestWithToolResultTransformer(t *testing.T) {
+	payload := `{"items": [` + strings.Repeat(`"x",`, 5000) + `"last"]}`
+
+	t.Run("HeadTail", func(t *testing.T) {
+		adapter := tooladapter.New(tooladapter.WithToolResultTransformer(tooladapter.HeadTailToolResultTransformer(64, 32)))
+		transformed, err := adapter.TransformCompletionsRequest(requestWithToolResult(payload))
+		require.NoError(t, err)
+
+		text := injectedText(t, transformed)
+		assert.Contains(t, text, payload[:64])
+		assert.Contains(t, text, payload[len(payload)-32:])
+		assert.Contains(t, text, "bytes omitted")
+		assert.NotContains(t, text, payload[:200])
+	})
+
+	t.Run("CustomReceivesCallID", func(t *testing.T) {
+		var callIDs []string
+		adapter := tooladapter.New(tooladapter.WithToolResultTransformer(func(callID, content string) string {
+			callIDs = append(callIDs, callID)
+			return "summary of " + callID
+		}))
+		transformed, err := adapter.TransformCompletionsRequest(requestWithToolResult(payload))
+		require.NoError(t, err)
+
+		assert.Equal(t, []string{"call_1"}, callIDs)
+		text := injectedText(t, transformed)
+		assert.Contains(t, text, "summary of call_1")
+		assert.NotContains(t, text, `"items"`)
+	})
+
+	t.Run("Config", func(t *testing.T) {
+		cfg, err := tooladapter.LoadConfig(strings.NewReader(`{"tool_result_head_bytes": 64, "tool_result_tail_bytes": 32}`))
+		require.NoError(t, err)
+		assert.Equal(t, 64, cfg.ToolResultHeadBytes)
+		assert.Equal(t, 32, cfg.ToolResultTailBytes)
+
+		adapter, err := tooladapter.NewFromConfig(cfg)
+		require.NoError(t, err)
+		transformed, err := adapter.TransformCompletionsRequest(requestWithToolResult(payload))
+		require.NoError(t, err)
+		assert.Contains(t, injectedText(t, transformed), "bytes omitted")
+	})
+}
+
+func TestWithMultimodalToolResults(t *testing.T) {
+	chart := openai.ImageContentPart(openai.ChatCompletionContentPartImageImageURLParam{URL: "data:image/png;base64,iVBORw0KGgo="})
+	content, err := json.Marshal([]openai.ChatCompletionContentPartUnionParam{
+		openai.TextContentPart("Rainfall in Paris this week:"),
+		chart,
+	})
+	require.NoError(t, err)
+	adapter := tooladapter.New(tooladapter.WithMultimodalToolResults(true))
+
+	t.Run("AttachmentsReinjected", func(t *testing.T) {
+		transformed, err := adapter.TransformCompletionsRequest(requestWithToolResult(string(content)))
+		require.NoError(t, err)
+
+		text := injectedText(t, transformed)
+		assert.Contains(t, text, "Rainfall in Paris this week:\n[Attachment 1]")
+		assert.NotContains(t, text, "base64", "the image is not flattened into the prompt")
+
+		last := transformed.Messages[len(transformed.Messages)-1].OfUser
+		require.NotNil(t, last)
+		parts := last.Content.OfArrayOfContentParts
+		require.Len(t, parts, 2)
+		require.NotNil(t, parts[0].OfText)
+		assert.Equal(t, "[Attachment 1]", parts[0].OfText.Text)
+		require.NotNil(t, parts[1].OfImageURL)
+		assert.Equal(t, chart.OfImageURL.ImageURL.URL, parts[1].OfImageURL.ImageURL.URL)
+	})
+
+	t.Run("NumberedAcrossResults", func(t *testing.T) {
+		file, err := json.Marshal(openai.FileContentPart(openai.ChatCompletionContentPartFileFileParam{FileID: openai.String("file-123")}))
+		require.NoError(t, err)
+		req := requestWithToolResult(string(content))
+		req.Messages = append(req.Messages, openai.ToolMessage(string(file), "call_2"))
+
+		transformed, err := adapter.TransformCompletionsRequest(req)
+		require.NoError(t, err)
+		assert.Contains(t, injectedText(t, transformed), "call_2 result:\n[Attachment 2]")
+		parts := transformed.Messages[len(transformed.Messages)-1].OfUser.Content.OfArrayOfContentParts
+		require.Len(t, parts, 4)
+		assert.Equal(t, "[Attachment 2]", parts[2].OfText.Text)
+		assert.NotNil(t, parts[3].OfFile)
+	})
+
+	t.Run("TextResultsUnchanged", func(t *testing.T) {
+		for _, result := range []string{`{"temperature": 21}`, `[{"type": "text", "text": "sunny"}]`, "sunny"} {
+			req := requestWithToolResult(result)
+			transformed, err := adapter.TransformCompletionsRequest(req)
+			require.NoError(t, err)
+			assert.Contains(t, injectedText(t, transformed), result)
+			assert.Len(t, transformed.Messages, len(req.Messages)-1, "no attachments message")
+		}
+	})
+
+	t.Run("Disabled", func(t *testing.T) {
+		transformed, err := tooladapter.New().TransformCompletionsRequest(requestWithToolResult(string(content)))
+		require.NoError(t, err)
+		assert.Contains(t, injectedText(t, transformed), "base64")
+	})
+
+	t.Run("Config", func(t *testing.T) {
+		cfg, err := tooladapter.LoadConfig(strings.NewReader(`{"multimodal_tool_results": true}`))
+		require.NoError(t, err)
+		assert.True(t, cfg.MultimodalToolResults)
+	})
+}
@@ -0,0 +1,233 @@
+package tooltest
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	tooladapter "github.com/juburr/openai-tool-adapter/v3"
+	"github.com/openai/openai-go/v3"
+)
+
+// NormalizedCall is a tool call reduced to what both endpoints must agree on. IDs
+// are dropped and arguments are re-encoded with sorted keys and no whitespace, so
+// calls compare equal when they name the same function with the same JSON value.
+type NormalizedCall struct {
+	Name      string
+	Arguments string
+}
+
+// String formats the call as name(arguments).
+func (c NormalizedCall) String() string {
+	return c.Name + "(" + c.Arguments + ")"
+}
+
+// NormalizeToolCalls returns the normalized tool calls of the first choice of resp,
+// sorted by name and arguments so that the order of parallel calls is ignored.
+// Arguments that are not valid JSON are kept trimmed as they are.
+func NormalizeToolCalls(resp openai.ChatCompletion) []NormalizedCall {
+	if len(resp.Choices) == 0 {
+		return nil
+	}
+	toolCalls := resp.Choices[0].Message.ToolCalls
+	calls := make([]NormalizedCall, 0, len(toolCalls))
+	for _, call := range toolCalls {
+		calls = append(calls, NormalizedCall{
+			Name:      call.Function.Name,
+			Arguments: canonicalArguments(call.Function.Arguments),
+		})
+	}
+	sort.Slice(calls, func(i, j int) bool {
+		if calls[i].Name != calls[j].Name {
+			return calls[i].Name < calls[j].Name
+		}
+		return calls[i].Arguments < calls[j].Arguments
+	})
+	return calls
+}
+
+// canonicalArguments re-encodes JSON arguments with sorted keys. Empty arguments
+// are treated as null.
+func canonicalArguments(arguments string) string {
+	trimmed := strings.TrimSpace(arguments)
+	if trimmed == "" {
+		return "null"
+	}
+	var value any
+	if json.Unmarshal([]byte(trimmed), &value) != nil {
+		return trimmed
+	}
+	canonical, err := json.Marshal(value)
+	if err != nil {
+		return trimmed
+	}
+	return string(canonical)
+}
+
+// DiffResult is the outcome of sending one request to both endpoints.
+type DiffResult struct {
+	// Native and Emulated are the normalized tool calls of each endpoint's reply
+	Native   []NormalizedCall
+	Emulated []NormalizedCall
+
+	// Differences describes each disagreement in a line of text. It is empty when
+	// both endpoints made the same calls.
+	Differences []string
+
+	// NativeResponse is the reply of the native endpoint
+	NativeResponse openai.ChatCompletion
+
+	// EmulatedResponse is the emulated endpoint's reply after the adapter's
+	// response transformation
+	EmulatedResponse openai.ChatCompletion
+}
+
+// Equal reports whether both endpoints made the same tool calls.
+func (r DiffResult) Equal() bool {
+	return len(r.Differences) == 0
+}
+
+// DiffReport aggregates the results of a differential run.
+type DiffReport struct {
+	// Results holds one result per request, in request order
+	Results []DiffResult
+}
+
+// Matched returns the number of requests on which both endpoints agreed.
+func (r DiffReport) Matched() int {
+	matched := 0
+	for _, result := range r.Results {
+		if result.Equal() {
+			matched++
+		}
+	}
+	return matched
+}
+
+// Fidelity returns the share of requests on which both endpoints agreed, from 0
+// to 1. An empty report has a fidelity of 1.
+func (r DiffReport) Fidelity() float64 {
+	if len(r.Results) == 0 {
+		return 1
+	}
+	return float64(r.Matched()) / float64(len(r.Results))
+}
+
+// Differential sends the same requests to an endpoint with native function calling
+// and, through Adapter, to an endpoint without it, and compares the tool calls each
+// returns. Run it against a representative set of prompts to quantify how closely
+// the adapter reproduces native behavior for a model before rolling it out:
+//
+//	diff := tooltest.Differential{
+//	    Native:   &nativeClient.Chat.Completions,
+//	    Emulated: &vllmClient.Chat.Completions,
+//	    Adapter:  tooladapter.New(myOptions...),
+//	}
+//	report, err := diff.Run(ctx, requests)
+//	if report.Fidelity() < 0.95 { ... }
+//
+// Models sample, so set a zero temperature and a seed on the requests where the
+// endpoints support them.
+type Differential struct {
+	// Native receives the requests unchanged
+	Native tooladapter.ChatCompletionsClient
+
+	// Emulated receives the requests transformed by Adapter
+	Emulated tooladapter.ChatCompletionsClient
+
+	// Adapter transforms the emulated requests and responses. Nil uses
+	// tooladapter.New().
+	Adapter *tooladapter.Adapter
+}
+
+// Compare sends req to both endpoints and diffs the tool calls of their first
+// choices. Errors from either endpoint or from the adapter are returned.
+func (d Differential) Compare(ctx context.Context, req openai.ChatCompletionNewParams) (DiffResult, error) {
+	adapter := d.Adapter
+	if adapter == nil {
+		adapter = tooladapter.New()
+	}
+
+	native, err := d.Native.New(ctx, req)
+	if err != nil {
+		return DiffResult{}, fmt.Errorf("native request: %w", err)
+	}
+
+	session := adapter.NewSession()
+	transformed, err := session.TransformRequest(ctx, req)
+	if err != nil {
+		return DiffResult{}, fmt.Errorf("transform request: %w", err)
+	}
+	completion, err := d.Emulated.New(ctx, transformed)
+	if err != nil {
+		return DiffResult{}, fmt.Errorf("emulated request: %w", err)
+	}
+	emulated, err := session.TransformResponse(ctx, *completion)
+	if err != nil {
+		return DiffResult{}, fmt.Errorf("transform response: %w", err)
+	}
+
+	result := DiffResult{
+		Native:           NormalizeToolCalls(*native),
+		Emulated:         NormalizeToolCalls(emulated),
+		NativeResponse:   *native,
+		EmulatedResponse: emulated,
+	}
+	result.Differences = diffCalls(result.Native, result.Emulated)
+	return result, nil
+}
+
+// Run compares every request in order and stops at the first error, returning the
+// results gathered so far.
+func (d Differential) Run(ctx context.Context, reqs []openai.ChatCompletionNewParams) (DiffReport, error) {
+	var report DiffReport
+	for i, req := range reqs {
+		result, err := d.Compare(ctx, req)
+		if err != nil {
+			return report, fmt.Errorf("request %d: %w", i, err)
+		}
+		report.Results = append(report.Results, result)
+	}
+	return report, nil
+}
+
+// diffCalls describes the differences between two sorted call lists. Identical
+// calls are paired first; remaining calls with the same name are reported as
+// argument differences and the rest as missing or unexpected.
+func diffCalls(native, emulated []NormalizedCall) []string {
+	unmatched := append([]NormalizedCall(nil), emulated...)
+	var missing []NormalizedCall
+	for _, call := range native {
+		if i := indexOfCall(unmatched, func(c NormalizedCall) bool { return c == call }); i >= 0 {
+			unmatched = append(unmatched[:i], unmatched[i+1:]...)
+			continue
+		}
+		missing = append(missing, call)
+	}
+
+	var differences []string
+	for _, call := range missing {
+		if i := indexOfCall(unmatched, func(c NormalizedCall) bool { return c.Name == call.Name }); i >= 0 {
+			differences = append(differences, fmt.Sprintf("%s: arguments %s, native %s", call.Name, unmatched[i].Arguments, call.Arguments))
+			unmatched = append(unmatched[:i], unmatched[i+1:]...)
+			continue
+		}
+		differences = append(differences, "missing call "+call.String())
+	}
+	for _, call := range unmatched {
+		differences = append(differences, "unexpected call "+call.String())
+	}
+	return differences
+}
+
+// indexOfCall returns the index of the first call matching match, or -1.
+func indexOfCall(calls []NormalizedCall, match func(NormalizedCall) bool) int {
+	for i, call := range calls {
+		if match(call) {
+			return i
+		}
+	}
+	return -1
+}
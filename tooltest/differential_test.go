@@ -0,0 +1,118 @@
+package tooltest_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/juburr/openai-tool-adapter/v3/tooltest"
+	"github.com/openai/openai-go/v3"
+	"github.com/openai/openai-go/v3/option"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// clientFunc adapts a function to tooladapter.ChatCompletionsClient.
+type clientFunc func(openai.ChatCompletionNewParams) (*openai.ChatCompletion, error)
+
+func (f clientFunc) New(_ context.Context, body openai.ChatCompletionNewParams, _ ...option.RequestOption) (*openai.ChatCompletion, error) {
+	return f(body)
+}
+
+// nativeReply returns a client answering with native tool calls, each given as a
+// name and its arguments.
+func nativeReply(calls ...[2]string) clientFunc {
+	return func(body openai.ChatCompletionNewParams) (*openai.ChatCompletion, error) {
+		completion := tooltest.Completion("")
+		for i, call := range calls {
+			completion.Choices[0].Message.ToolCalls = append(completion.Choices[0].Message.ToolCalls,
+				openai.ChatCompletionMessageToolCallUnion{
+					ID:       "call_" + string(rune('a'+i)),
+					Type:     "function",
+					Function: openai.ChatCompletionMessageFunctionToolCallFunction{Name: call[0], Arguments: call[1]},
+				})
+		}
+		return &completion, nil
+	}
+}
+
+// emulatedReply returns a client answering with content, checking that it received
+// a request without tools.
+func emulatedReply(t *testing.T, content string) clientFunc {
+	return func(body openai.ChatCompletionNewParams) (*openai.ChatCompletion, error) {
+		assert.Empty(t, body.Tools, "the emulated endpoint receives the transformed request")
+		completion := tooltest.Completion(content)
+		return &completion, nil
+	}
+}
+
+func TestDifferential(t *testing.T) {
+	req := tooltest.Request(tooltest.Tool("get_weather", "Get the weather"))
+
+	t.Run("Equal", func(t *testing.T) {
+		diff := tooltest.Differential{
+			Native:   nativeReply([2]string{"get_weather", `{"param1": "Paris", "units": "C"}`}),
+			Emulated: emulatedReply(t, `{"name": "get_weather", "parameters": {"units": "C", "param1": "Paris"}}`),
+		}
+		result, err := diff.Compare(context.Background(), req)
+		require.NoError(t, err)
+		assert.True(t, result.Equal(), result.Differences)
+		assert.Equal(t, []tooltest.NormalizedCall{{Name: "get_weather", Arguments: `{"param1":"Paris","units":"C"}`}}, result.Emulated)
+	})
+
+	t.Run("Differences", func(t *testing.T) {
+		diff := tooltest.Differential{
+			Native: nativeReply(
+				[2]string{"get_weather", `{"param1": "Paris"}`},
+				[2]string{"get_time", `{}`}),
+			Emulated: emulatedReply(t, `{"name": "get_weather", "parameters": {"param1": "Rome"}}`),
+		}
+		result, err := diff.Compare(context.Background(), req)
+		require.NoError(t, err)
+		assert.False(t, result.Equal())
+		assert.ElementsMatch(t, []string{
+			`missing call get_time({})`,
+			`get_weather: arguments {"param1":"Rome"}, native {"param1":"Paris"}`,
+		}, result.Differences)
+	})
+
+	t.Run("TextInsteadOfCall", func(t *testing.T) {
+		diff := tooltest.Differential{
+			Native:   nativeReply(),
+			Emulated: emulatedReply(t, `{"name": "get_weather", "parameters": null}`),
+		}
+		result, err := diff.Compare(context.Background(), req)
+		require.NoError(t, err)
+		assert.Equal(t, []string{"unexpected call get_weather(null)"}, result.Differences)
+	})
+
+	t.Run("Report", func(t *testing.T) {
+		replies := []string{`{"name": "get_weather", "parameters": {"param1": "Paris"}}`, "I don't know."}
+		diff := tooltest.Differential{
+			Native: nativeReply([2]string{"get_weather", `{"param1": "Paris"}`}),
+			Emulated: clientFunc(func(openai.ChatCompletionNewParams) (*openai.ChatCompletion, error) {
+				completion := tooltest.Completion(replies[0])
+				replies = replies[1:]
+				return &completion, nil
+			}),
+		}
+		report, err := diff.Run(context.Background(), []openai.ChatCompletionNewParams{req, req})
+		require.NoError(t, err)
+		assert.Equal(t, 1, report.Matched())
+		assert.InDelta(t, 0.5, report.Fidelity(), 1e-9)
+		assert.InDelta(t, 1.0, tooltest.DiffReport{}.Fidelity(), 1e-9)
+	})
+
+	t.Run("Error", func(t *testing.T) {
+		down := errors.New("connection refused")
+		diff := tooltest.Differential{
+			Native: nativeReply(),
+			Emulated: clientFunc(func(openai.ChatCompletionNewParams) (*openai.ChatCompletion, error) {
+				return nil, down
+			}),
+		}
+		_, err := diff.Run(context.Background(), []openai.ChatCompletionNewParams{req})
+		require.ErrorIs(t, err, down)
+		assert.Contains(t, err.Error(), "emulated request")
+	})
+}
@@ -0,0 +1,219 @@
+// Package tooltest provides mocks and fixtures for testing code built on
+// tooladapter. Applications can drive a tooladapter.StreamAdapter with scripted
+// chunks and build requests and completions without re-implementing mocks.
+// Differential compares the adapter against an endpoint with native function calling.
+//
+//	stream := adapter.TransformStreamingResponse(tooltest.NewContentStream(
+//	    `{"name": "get_weather", `,
+//	    `"parameters": {"city": "Paris"}}`,
+//	))
+//	result := tooltest.Drain(stream)
+package tooltest
+
+import (
+	"strings"
+	"sync"
+
+	tooladapter "github.com/juburr/openai-tool-adapter/v3"
+	"github.com/openai/openai-go/v3"
+)
+
+// MockStream implements tooladapter.ChatCompletionStreamInterface over a fixed list of
+// chunks. It is safe to call Close from another goroutine while the stream is read.
+type MockStream struct {
+	mu      sync.Mutex
+	chunks  []openai.ChatCompletionChunk
+	current int
+	err     error
+	closed  bool
+}
+
+var _ tooladapter.ChatCompletionStreamInterface = (*MockStream)(nil)
+
+// NewMockStream creates a stream that yields chunks in order.
+func NewMockStream(chunks ...openai.ChatCompletionChunk) *MockStream {
+	return &MockStream{
+		chunks:  chunks,
+		current: -1,
+	}
+}
+
+// NewContentStream creates a stream with one content chunk per string followed by a
+// finish chunk with reason "stop".
+func NewContentStream(contents ...string) *MockStream {
+	chunks := make([]openai.ChatCompletionChunk, 0, len(contents)+1)
+	for _, content := range contents {
+		chunks = append(chunks, ContentChunk(content))
+	}
+	return NewMockStream(append(chunks, FinishChunk("stop"))...)
+}
+
+// Next advances to the next chunk.
+func (m *MockStream) Next() bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.current++
+	return m.current < len(m.chunks)
+}
+
+// Current returns the current chunk.
+func (m *MockStream) Current() openai.ChatCompletionChunk {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.current >= 0 && m.current < len(m.chunks) {
+		return m.chunks[m.current]
+	}
+	return openai.ChatCompletionChunk{}
+}
+
+// Err returns the error set with SetError.
+func (m *MockStream) Err() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.err
+}
+
+// Close records that the stream was closed. It does not end the stream, so the
+// remaining chunks stand in for data a real upstream had already delivered.
+func (m *MockStream) Close() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.closed = true
+	return nil
+}
+
+// SetError sets the error returned by Err, simulating an upstream failure reported
+// after the last chunk.
+func (m *MockStream) SetError(err error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.err = err
+}
+
+// Closed reports whether Close was called, for example by upstream cancellation
+// after a tool call (see tooladapter.WithCancelUpstreamOnStop).
+func (m *MockStream) Closed() bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.closed
+}
+
+// ContentChunk returns an assistant chunk carrying content.
+func ContentChunk(content string) openai.ChatCompletionChunk {
+	return openai.ChatCompletionChunk{
+		Choices: []openai.ChatCompletionChunkChoice{
+			{
+				Delta: openai.ChatCompletionChunkChoiceDelta{
+					Content: content,
+					Role:    "assistant",
+				},
+			},
+		},
+	}
+}
+
+// FinishChunk returns a chunk that ends the stream with reason.
+func FinishChunk(reason string) openai.ChatCompletionChunk {
+	return openai.ChatCompletionChunk{
+		Choices: []openai.ChatCompletionChunkChoice{
+			{
+				FinishReason: reason,
+			},
+		},
+	}
+}
+
+// Tool returns a function tool with a single string parameter named param1. An
+// empty description is omitted.
+func Tool(name, description string) openai.ChatCompletionToolUnionParam {
+	functionDef := openai.FunctionDefinitionParam{
+		Name: name,
+		Parameters: openai.FunctionParameters{
+			"type": "object",
+			"properties": map[string]interface{}{
+				"param1": map[string]interface{}{
+					"type":        "string",
+					"description": "A parameter",
+				},
+			},
+		},
+	}
+	if description != "" {
+		functionDef.Description = openai.String(description)
+	}
+	return openai.ChatCompletionFunctionTool(functionDef)
+}
+
+// Request returns a request with a single user message offering tools.
+func Request(tools ...openai.ChatCompletionToolUnionParam) openai.ChatCompletionNewParams {
+	return openai.ChatCompletionNewParams{
+		Model: openai.ChatModelGPT4o,
+		Messages: []openai.ChatCompletionMessageParamUnion{
+			openai.UserMessage("Hello, please help me."),
+		},
+		Tools: tools,
+	}
+}
+
+// Completion returns a single-choice assistant completion with content.
+func Completion(content string) openai.ChatCompletion {
+	return openai.ChatCompletion{
+		Choices: []openai.ChatCompletionChoice{
+			{
+				Message: openai.ChatCompletionMessage{
+					Role:    "assistant",
+					Content: content,
+				},
+			},
+		},
+	}
+}
+
+// StreamResult summarizes everything a stream emitted.
+type StreamResult struct {
+	// Content is the concatenated text content of all chunks
+	Content string
+
+	// ToolCalls lists tool calls in the order they were emitted
+	ToolCalls []openai.ChatCompletionChunkChoiceDeltaToolCall
+
+	// FinishReason is the last non-empty finish reason
+	FinishReason string
+
+	// Chunks is the number of chunks emitted
+	Chunks int
+
+	// Err is the stream's error after it ended
+	Err error
+}
+
+// ToolNames returns the function names of the emitted tool calls.
+func (r StreamResult) ToolNames() []string {
+	names := make([]string, len(r.ToolCalls))
+	for i, call := range r.ToolCalls {
+		names[i] = call.Function.Name
+	}
+	return names
+}
+
+// Drain reads stream to the end and summarizes what it emitted.
+func Drain(stream *tooladapter.StreamAdapter) StreamResult {
+	var result StreamResult
+	var content strings.Builder
+	for stream.Next() {
+		result.Chunks++
+		chunk := stream.Current()
+		if len(chunk.Choices) == 0 {
+			continue
+		}
+		choice := chunk.Choices[0]
+		content.WriteString(choice.Delta.Content)
+		result.ToolCalls = append(result.ToolCalls, choice.Delta.ToolCalls...)
+		if choice.FinishReason != "" {
+			result.FinishReason = choice.FinishReason
+		}
+	}
+	result.Content = content.String()
+	result.Err = stream.Err()
+	return result
+}
@@ -0,0 +1,73 @@
+package tooltest_test
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	tooladapter "github.com/juburr/openai-tool-adapter/v3"
+	"github.com/juburr/openai-tool-adapter/v3/tooltest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDrainToolCall(t *testing.T) {
+	adapter := tooladapter.New()
+	stream := tooltest.NewContentStream(`{"name": "get_weather", `, `"parameters": {"city": "Paris"}}`)
+
+	result := tooltest.Drain(adapter.TransformStreamingResponse(stream))
+	require.NoError(t, result.Err)
+	assert.Empty(t, result.Content)
+	assert.Equal(t, []string{"get_weather"}, result.ToolNames())
+	assert.JSONEq(t, `{"city": "Paris"}`, result.ToolCalls[0].Function.Arguments)
+	assert.True(t, stream.Closed(), "upstream is cancelled after the first tool call")
+}
+
+func TestDrainPlainText(t *testing.T) {
+	adapter := tooladapter.New()
+	stream := tooltest.NewContentStream("Hello, ", "world!")
+
+	result := tooltest.Drain(adapter.TransformStreamingResponse(stream))
+	require.NoError(t, result.Err)
+	assert.Equal(t, "Hello, world!", result.Content)
+	assert.Empty(t, result.ToolCalls)
+	assert.Equal(t, "stop", result.FinishReason)
+	assert.Equal(t, 3, result.Chunks)
+	assert.False(t, stream.Closed())
+}
+
+func TestMockStreamError(t *testing.T) {
+	upstreamErr := errors.New("connection reset")
+	stream := tooltest.NewMockStream(tooltest.ContentChunk("partial"))
+	stream.SetError(upstreamErr)
+
+	result := tooltest.Drain(tooladapter.New().TransformStreamingResponse(stream))
+	assert.Equal(t, "partial", result.Content)
+	assert.ErrorIs(t, result.Err, upstreamErr)
+}
+
+func TestRequestAndCompletion(t *testing.T) {
+	adapter := tooladapter.New()
+
+	req, err := adapter.TransformCompletionsRequest(tooltest.Request(tooltest.Tool("get_weather", "Get the weather")))
+	require.NoError(t, err)
+	assert.Empty(t, req.Tools)
+	assert.NotEmpty(t, req.Messages)
+
+	resp, err := adapter.TransformCompletionsResponse(tooltest.Completion(`{"name": "get_weather", "parameters": null}`))
+	require.NoError(t, err)
+	require.Len(t, resp.Choices[0].Message.ToolCalls, 1)
+	assert.Equal(t, "get_weather", resp.Choices[0].Message.ToolCalls[0].Function.Name)
+}
+
+func ExampleDrain() {
+	adapter := tooladapter.New()
+	stream := adapter.TransformStreamingResponse(tooltest.NewContentStream(
+		`{"name": "get_weather", `,
+		`"parameters": {"city": "Paris"}}`,
+	))
+
+	result := tooltest.Drain(stream)
+	fmt.Println(result.ToolNames(), result.ToolCalls[0].Function.Arguments)
+	// Output: [get_weather] {"city": "Paris"}
+}
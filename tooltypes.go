@@ -0,0 +1,44 @@
+package tooladapter
+
+import (
+	"github.com/openai/openai-go/v3"
+)
+
+// supportedTools returns the function tools of a request. Other tools, such as
+// custom tools taking free-form input, have no JSON schema the prompt could describe
+// and are left out with a warning, or rejected with an *UnsupportedToolTypeError
+// under UnsupportedToolError. The caller's slice is never modified.
+func (a *Adapter) supportedTools(tools []openai.ChatCompletionToolUnionParam) ([]openai.ChatCompletionToolUnionParam, error) {
+	supported := tools
+	copied := false
+	for i, tool := range tools {
+		if tool.OfFunction != nil {
+			if copied {
+				supported = append(supported, tool)
+			}
+			continue
+		}
+
+		toolType, name := "", ""
+		if custom := tool.OfCustom; custom != nil {
+			toolType, name = string(custom.Type.Default()), custom.Custom.Name
+		}
+		if a.unsupportedToolPolicy == UnsupportedToolError {
+			return nil, &UnsupportedToolTypeError{Index: i, Type: toolType}
+		}
+		a.log(LogCategoryRequest).Warn("Skipping tool that cannot be injected into the prompt",
+			"tool_index", i,
+			"tool_type", toolType,
+			"tool_name", name,
+			"implication", "The model is not told about this tool",
+			"recommendation", "Declare the tool as a function tool with a JSON schema")
+
+		// Copy on first skip so the caller's slice stays untouched
+		if !copied {
+			supported = make([]openai.ChatCompletionToolUnionParam, i, len(tools)-1)
+			copy(supported, tools[:i])
+			copied = true
+		}
+	}
+	return supported, nil
+}
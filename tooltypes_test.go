@@ -0,0 +1,83 @@
+package tooladapter_test
+
+import (
+	"bytes"
+	"errors"
+	"log/slog"
+	"strings"
+	"testing"
+
+	tooladapter "github.com/juburr/openai-tool-adapter/v3"
+	"github.com/juburr/openai-tool-adapter/v3/tooltest"
+	"github.com/openai/openai-go/v3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// customTool returns a custom tool, which takes free-form text instead of JSON
+// arguments.
+func customTool(name string) openai.ChatCompletionToolUnionParam {
+	return openai.ChatCompletionCustomTool(openai.ChatCompletionCustomToolCustomParam{
+		Name:        name,
+		Description: openai.String("Run a shell command"),
+	})
+}
+
+func TestWithUnsupportedToolPolicy(t *testing.T) {
+	t.Run("SkipsCustomTools", func(t *testing.T) {
+		var logs bytes.Buffer
+		adapter := tooladapter.New(tooladapter.WithLogger(slog.New(slog.NewTextHandler(&logs, nil))))
+		req := tooltest.Request(customTool("shell"), tooltest.Tool("get_weather", "Get the weather"))
+
+		transformed, err := adapter.TransformCompletionsRequest(req)
+		require.NoError(t, err)
+
+		text := injectedText(t, transformed)
+		assert.Contains(t, text, "get_weather")
+		assert.NotContains(t, text, "shell")
+		assert.Contains(t, logs.String(), "Skipping tool that cannot be injected into the prompt")
+		assert.Len(t, req.Tools, 2, "the caller's tools are not modified")
+	})
+
+	t.Run("OnlyCustomTools", func(t *testing.T) {
+		req := tooltest.Request(customTool("shell"))
+		req.ToolChoice = openai.ToolChoiceOptionFunctionToolChoice(openai.ChatCompletionNamedToolChoiceFunctionParam{Name: "shell"})
+
+		transformed, err := tooladapter.New().TransformCompletionsRequest(req)
+		require.NoError(t, err)
+
+		assert.Empty(t, transformed.Tools)
+		assert.Nil(t, transformed.ToolChoice.OfFunctionToolChoice)
+		assert.Equal(t, injectedText(t, req), injectedText(t, transformed), "nothing is injected")
+	})
+
+	t.Run("Error", func(t *testing.T) {
+		adapter := tooladapter.New(tooladapter.WithUnsupportedToolPolicy(tooladapter.UnsupportedToolError))
+		_, err := adapter.TransformCompletionsRequest(tooltest.Request(tooltest.Tool("get_weather", "Get the weather"), customTool("shell")))
+		require.ErrorIs(t, err, tooladapter.ErrUnsupportedTool)
+
+		var unsupported *tooladapter.UnsupportedToolTypeError
+		require.True(t, errors.As(err, &unsupported))
+		assert.Equal(t, 1, unsupported.Index)
+		assert.Equal(t, "custom", unsupported.Type)
+	})
+
+	t.Run("EmptyUnion", func(t *testing.T) {
+		adapter := tooladapter.New(tooladapter.WithUnsupportedToolPolicy(tooladapter.UnsupportedToolError))
+		_, err := adapter.TransformCompletionsRequest(tooltest.Request(openai.ChatCompletionToolUnionParam{}))
+
+		var unsupported *tooladapter.UnsupportedToolTypeError
+		require.True(t, errors.As(err, &unsupported))
+		assert.Equal(t, 0, unsupported.Index)
+		assert.Empty(t, unsupported.Type)
+	})
+
+	t.Run("Config", func(t *testing.T) {
+		cfg, err := tooladapter.LoadConfig(strings.NewReader(`{"unsupported_tool_policy": "error"}`))
+		require.NoError(t, err)
+		assert.Equal(t, tooladapter.UnsupportedToolError, cfg.UnsupportedToolPolicy)
+
+		_, err = tooladapter.LoadConfig(strings.NewReader(`{"unsupported_tool_policy": "ignore"}`))
+		require.ErrorIs(t, err, tooladapter.ErrInvalidConfig)
+	})
+}
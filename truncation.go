@@ -0,0 +1,149 @@
+package tooladapter
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/openai/openai-go/v3"
+)
+
+// TruncationStrategy controls how WithContextWindow shortens conversations that do
+// not fit the model's context window.
+type TruncationStrategy int
+
+const (
+	// TruncateDropOldest drops the oldest messages (default).
+	TruncateDropOldest TruncationStrategy = iota
+
+	// TruncateWithMarker drops the oldest messages like TruncateDropOldest and
+	// tells the model how many were omitted by prepending TruncationMarkerText to
+	// the first remaining user message, so it does not mistake the shortened
+	// history for the whole conversation.
+	TruncateWithMarker
+)
+
+// String returns a human-readable string representation of the TruncationStrategy.
+func (s TruncationStrategy) String() string {
+	switch s {
+	case TruncateDropOldest:
+		return "TruncateDropOldest"
+	case TruncateWithMarker:
+		return "TruncateWithMarker"
+	default:
+		return fmt.Sprintf("TruncationStrategy(%d)", int(s))
+	}
+}
+
+// TruncationMarkerText is the note added by TruncateWithMarker. %d is replaced by
+// the number of omitted messages.
+const TruncationMarkerText = "[%d earlier messages were omitted to fit the context window.]"
+
+// truncateMessages drops the oldest messages until the conversation, the injected
+// prompt and the completion budget of req fit the context window. System and
+// developer messages and the last message are always kept, and the remaining
+// conversation starts at a user message.
+func (a *Adapter) truncateMessages(ctx context.Context, req openai.ChatCompletionNewParams, messages []openai.ChatCompletionMessageParamUnion, injectedPrompt string, origins *messageOrigins) []openai.ChatCompletionMessageParamUnion {
+	if a.contextWindow <= 0 || len(messages) < 2 {
+		return messages
+	}
+
+	tokens := make([]int, len(messages))
+	total := a.tokenEstimate(injectedPrompt)
+	for i, msg := range messages {
+		tokens[i] = a.messageTokens(msg)
+		total += tokens[i]
+	}
+	budget := a.contextWindow - int(req.MaxCompletionTokens.Or(req.MaxTokens.Or(0)))
+	if total <= budget {
+		return messages
+	}
+
+	keep := func(i int) bool {
+		return i == len(messages)-1 || messages[i].OfSystem != nil || messages[i].OfDeveloper != nil
+	}
+	dropped := make([]bool, len(messages))
+	droppedCount, droppedTokens := 0, 0
+	markerTokens := 0
+	for i := range messages {
+		fits := total-droppedTokens+markerTokens <= budget
+		if keep(i) {
+			continue
+		}
+		// Past the budget, keep dropping up to the next user message so the
+		// conversation does not open with an assistant turn
+		if fits && messages[i].OfUser != nil {
+			break
+		}
+		dropped[i] = true
+		droppedCount++
+		droppedTokens += tokens[i]
+		if a.truncationStrategy == TruncateWithMarker {
+			markerTokens = a.tokenEstimate(a.truncationMarker(droppedCount))
+		}
+	}
+	if droppedCount == 0 {
+		return messages
+	}
+
+	truncated := make([]openai.ChatCompletionMessageParamUnion, 0, len(messages)-droppedCount)
+	marked := a.truncationStrategy != TruncateWithMarker
+	for i, msg := range messages {
+		if dropped[i] {
+			origins.remove(len(truncated))
+			continue
+		}
+		if !marked && msg.OfUser != nil {
+			msg = prependToolPromptToUserMessage(msg, a.truncationMarker(droppedCount))
+			origins.modify(len(truncated))
+			marked = true
+		}
+		truncated = append(truncated, msg)
+	}
+
+	promptTokens := total - droppedTokens + markerTokens
+	a.log(LogCategoryLimit).Info("Truncated conversation to fit the context window",
+		"dropped_messages", droppedCount,
+		"dropped_tokens", droppedTokens,
+		"prompt_tokens", promptTokens,
+		"context_window", a.contextWindow,
+		"strategy", a.truncationStrategy.String())
+	if promptTokens > budget {
+		a.log(LogCategoryLimit).Warn("Conversation exceeds the context window after truncation",
+			"prompt_tokens", promptTokens,
+			"budget", budget,
+			"recommendation", "Shorten system messages or the last message, or reduce the tools per request")
+	}
+	if report := transformReportFrom(ctx); report != nil {
+		report.TruncatedMessages = droppedCount
+		report.TruncatedTokens = droppedTokens
+	}
+	a.emitMetric(MessageTruncationData{
+		DroppedMessages: droppedCount,
+		DroppedTokens:   droppedTokens,
+		PromptTokens:    promptTokens,
+		ContextWindow:   a.contextWindow,
+		Strategy:        a.truncationStrategy,
+		Fits:            promptTokens <= budget,
+	})
+	return truncated
+}
+
+// truncationMarker returns the TruncateWithMarker note for n omitted messages.
+func (a *Adapter) truncationMarker(n int) string {
+	marker := fmt.Sprintf(TruncationMarkerText, n)
+	if a.markInjectedContent {
+		marker = a.wrapInjectedPrompt(marker)
+	}
+	return marker
+}
+
+// messageTokens estimates the tokens of a message from its JSON encoding, which
+// includes the role and structure overhead the model's chat template adds.
+func (a *Adapter) messageTokens(msg openai.ChatCompletionMessageParamUnion) int {
+	encoded, err := json.Marshal(msg)
+	if err != nil {
+		return 0
+	}
+	return a.tokenEstimate(string(encoded))
+}
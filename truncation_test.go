@@ -0,0 +1,129 @@
+package tooladapter_test
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	tooladapter "github.com/juburr/openai-tool-adapter/v3"
+	"github.com/juburr/openai-tool-adapter/v3/tooltest"
+	"github.com/openai/openai-go/v3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// byteCounter counts bytes as tokens, keeping budgets easy to reason about.
+func byteCounter(text string) int {
+	return len(text)
+}
+
+// conversation returns a request with a system message and three user turns.
+func conversation() openai.ChatCompletionNewParams {
+	req := tooltest.Request(tooltest.Tool("get_weather", "Get the weather"))
+	req.Messages = []openai.ChatCompletionMessageParamUnion{
+		openai.SystemMessage("You are helpful."),
+		openai.UserMessage("opening " + strings.Repeat("a", 400)),
+		openai.AssistantMessage("opening answer " + strings.Repeat("b", 400)),
+		openai.UserMessage("second " + strings.Repeat("c", 400)),
+		openai.AssistantMessage("second answer"),
+		openai.UserMessage("third question"),
+	}
+	return req
+}
+
+func TestWithContextWindow(t *testing.T) {
+	// Size the window from an untruncated request so it fits all but the first turn
+	full, err := tooladapter.New(tooladapter.WithTokenCounter(byteCounter)).TransformCompletionsRequest(conversation())
+	require.NoError(t, err)
+	encoded, err := json.Marshal(full.Messages)
+	require.NoError(t, err)
+	window := len(encoded) - 600
+
+	t.Run("DropsOldestTurn", func(t *testing.T) {
+		var truncations []tooladapter.MessageTruncationData
+		adapter := tooladapter.New(
+			tooladapter.WithTokenCounter(byteCounter),
+			tooladapter.WithContextWindow(window),
+			tooladapter.WithMetricsCallback(func(data tooladapter.MetricEventData) {
+				if d, ok := data.(tooladapter.MessageTruncationData); ok {
+					truncations = append(truncations, d)
+				}
+			}))
+
+		req, err := adapter.TransformCompletionsRequest(conversation())
+		require.NoError(t, err)
+		messages := messagesJSON(t, req)
+		assert.NotContains(t, messages, "opening", "the oldest user message and its answer are dropped")
+		assert.Contains(t, messages, "second ")
+		assert.Contains(t, messages, "third question")
+		assert.Contains(t, messages, "You are helpful.", "system messages are kept")
+		require.NotNil(t, req.Messages[1].OfUser, "the conversation resumes at a user message")
+
+		require.Len(t, truncations, 1)
+		assert.Equal(t, 2, truncations[0].DroppedMessages)
+		assert.Equal(t, window, truncations[0].ContextWindow)
+		assert.True(t, truncations[0].Fits)
+		assert.LessOrEqual(t, truncations[0].PromptTokens, window)
+	})
+
+	t.Run("ReservesCompletionTokens", func(t *testing.T) {
+		adapter := tooladapter.New(
+			tooladapter.WithTokenCounter(byteCounter),
+			tooladapter.WithContextWindow(window))
+		req := conversation()
+		req.MaxCompletionTokens = openai.Int(500)
+
+		transformed, err := adapter.TransformCompletionsRequest(req)
+		require.NoError(t, err)
+		messages := messagesJSON(t, transformed)
+		assert.NotContains(t, messages, "second ")
+		assert.Contains(t, messages, "third question")
+	})
+
+	t.Run("WithMarker", func(t *testing.T) {
+		adapter := tooladapter.New(
+			tooladapter.WithTokenCounter(byteCounter),
+			tooladapter.WithContextWindow(window),
+			tooladapter.WithTruncationStrategy(tooladapter.TruncateWithMarker))
+
+		req, err := adapter.TransformCompletionsRequest(conversation())
+		require.NoError(t, err)
+		require.NotNil(t, req.Messages[1].OfUser)
+		text := req.Messages[1].OfUser.Content.OfString.Value
+		assert.True(t, strings.HasPrefix(text, "[2 earlier messages were omitted"), text)
+		assert.Contains(t, text, "second ")
+	})
+
+	t.Run("FitsUnchanged", func(t *testing.T) {
+		adapter := tooladapter.New(
+			tooladapter.WithTokenCounter(byteCounter),
+			tooladapter.WithContextWindow(len(encoded)+100))
+		req, err := adapter.TransformCompletionsRequest(conversation())
+		require.NoError(t, err)
+		assert.Equal(t, messagesJSON(t, full), messagesJSON(t, req))
+	})
+
+	t.Run("CannotFit", func(t *testing.T) {
+		var data tooladapter.MessageTruncationData
+		adapter := tooladapter.New(
+			tooladapter.WithContextWindow(10),
+			tooladapter.WithMetricsCallback(func(d tooladapter.MetricEventData) {
+				if td, ok := d.(tooladapter.MessageTruncationData); ok {
+					data = td
+				}
+			}))
+		req, err := adapter.TransformCompletionsRequest(conversation())
+		require.NoError(t, err, "the request is sent with what must be kept")
+		assert.Len(t, req.Messages, 2)
+		assert.False(t, data.Fits)
+	})
+}
+
+func TestWithTruncationStrategy_Config(t *testing.T) {
+	cfg, err := tooladapter.LoadConfig(strings.NewReader(`{"context_window": 8192, "truncation_strategy": "marker"}`))
+	require.NoError(t, err)
+	assert.Equal(t, tooladapter.TruncateWithMarker, cfg.TruncationStrategy)
+
+	_, err = tooladapter.NewFromConfig(tooladapter.Config{TruncationStrategy: tooladapter.TruncationStrategy(7)})
+	require.ErrorIs(t, err, tooladapter.ErrInvalidConfig)
+}
@@ -0,0 +1,29 @@
+package tooladapter
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNameSimilarity(t *testing.T) {
+	assert.Equal(t, 1.0, nameSimilarity("get_weather", "GET_WEATHER"))
+	assert.Equal(t, 1.0, nameSimilarity("", ""))
+	assert.Equal(t, 0.0, nameSimilarity("abc", ""))
+	assert.InDelta(t, 0.9, nameSimilarity("get_weathr", "get_weather"), 0.01)
+	assert.Less(t, nameSimilarity("delete_all", "get_weather"), 0.5)
+}
+
+func TestClosestAllowedToolName(t *testing.T) {
+	a := New(
+		WithAllowedToolNames([]string{"get_time", "get_tide"}),
+		WithUnknownToolMatchThreshold(0.5),
+	)
+
+	// Equal similarity resolves to the lexically smallest name
+	match, _ := a.closestAllowedToolName("get_tine")
+	assert.Equal(t, "get_tide", match)
+
+	match, _ = a.closestAllowedToolName("launch_rockets")
+	assert.Empty(t, match)
+}
@@ -0,0 +1,270 @@
+package tooladapter
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/openai/openai-go/v3"
+)
+
+// Constraint values reported in ArgumentViolation.
+const (
+	ConstraintEnum             = "enum"
+	ConstraintMinimum          = "minimum"
+	ConstraintMaximum          = "maximum"
+	ConstraintExclusiveMinimum = "exclusiveMinimum"
+	ConstraintExclusiveMaximum = "exclusiveMaximum"
+	ConstraintMinLength        = "minLength"
+	ConstraintMaxLength        = "maxLength"
+)
+
+// ArgumentViolation describes a tool call argument that breaks an enum, range or
+// length constraint of the tool's parameter schema.
+type ArgumentViolation struct {
+	// ToolName is the function whose arguments were checked
+	ToolName string `json:"tool_name"`
+
+	// Path locates the argument, such as "unit" or "stops[2].city"
+	Path string `json:"path"`
+
+	// Constraint is the violated schema keyword, using the Constraint constants
+	Constraint string `json:"constraint"`
+
+	// Value is the offending argument value
+	Value json.RawMessage `json:"value"`
+
+	// Message explains the violation and the accepted values in a form that can be
+	// shown to the model, for example `unit must be one of "celsius", "fahrenheit"`
+	Message string `json:"message"`
+}
+
+// ToolArgumentError is returned when tool call arguments violate their schema
+// under the ArgumentViolationError policy. It matches ErrArgumentViolation.
+type ToolArgumentError struct {
+	// Violations lists every violation found in the response
+	Violations []ArgumentViolation
+}
+
+func (e *ToolArgumentError) Error() string {
+	messages := make([]string, len(e.Violations))
+	for i, v := range e.Violations {
+		messages[i] = v.ToolName + ": " + v.Message
+	}
+	return "tool call arguments violate the schema: " + strings.Join(messages, "; ")
+}
+
+// Is reports whether target is ErrArgumentViolation.
+func (e *ToolArgumentError) Is(target error) bool {
+	return target == ErrArgumentViolation
+}
+
+// ArgumentViolations checks the tool calls of every choice in resp against the
+// parameter schemas of tools and returns the violations found. It is independent of
+// the configured ArgumentViolationPolicy, so it can be used to inspect a transformed
+// response directly.
+func ArgumentViolations(resp openai.ChatCompletion, tools []openai.ChatCompletionToolUnionParam) []ArgumentViolation {
+	schemas := toolSchemas(tools)
+	var violations []ArgumentViolation
+	for _, choice := range resp.Choices {
+		for _, toolCall := range choice.Message.ToolCalls {
+			schema, ok := schemas[toolCall.Function.Name]
+			if !ok {
+				continue
+			}
+			violations = append(violations,
+				checkArguments(toolCall.Function.Name, json.RawMessage(toolCall.Function.Arguments), schema)...)
+		}
+	}
+	return violations
+}
+
+// checkCalls applies the argument violation policy to calls using the schemas
+// attached to ctx with ContextWithTools.
+func (a *Adapter) checkCalls(ctx context.Context, calls []functionCall) error {
+	if a.argumentViolationPolicy == ArgumentViolationIgnore || ctx == nil {
+		return nil
+	}
+	schemas, _ := ctx.Value(toolSchemasKey{}).(map[string]*argumentSchema)
+	if len(schemas) == 0 {
+		return nil
+	}
+
+	var violations []ArgumentViolation
+	for _, call := range calls {
+		if schema, ok := schemas[call.Name]; ok {
+			violations = append(violations, checkArguments(call.Name, call.Parameters, schema)...)
+		}
+	}
+	if len(violations) == 0 {
+		return nil
+	}
+
+	for _, v := range violations {
+		a.log(LogCategoryPolicy).Warn("Tool call argument violates schema",
+			"function_name", v.ToolName,
+			"path", v.Path,
+			"constraint", v.Constraint,
+			"violation", v.Message)
+	}
+	a.emitMetric(ArgumentViolationData{Violations: violations})
+
+	if a.argumentViolationPolicy == ArgumentViolationError {
+		return &ToolArgumentError{Violations: violations}
+	}
+	return nil
+}
+
+// checkArguments returns the violations in raw arguments of the named tool.
+func checkArguments(toolName string, raw json.RawMessage, schema *argumentSchema) []ArgumentViolation {
+	if len(raw) == 0 {
+		return nil
+	}
+	var args any
+	decoder := json.NewDecoder(bytes.NewReader(raw))
+	decoder.UseNumber()
+	if decoder.Decode(&args) != nil {
+		return nil
+	}
+	var violations []ArgumentViolation
+	checkValue(toolName, "", args, schema, &violations)
+	return violations
+}
+
+// checkValue appends the violations of value and its children to violations.
+func checkValue(toolName, path string, value any, schema *argumentSchema, violations *[]ArgumentViolation) {
+	if schema == nil {
+		return
+	}
+	report := func(constraint, message string) {
+		encoded, _ := json.Marshal(value)
+		name := path
+		if name == "" {
+			name = "arguments"
+		}
+		*violations = append(*violations, ArgumentViolation{
+			ToolName:   toolName,
+			Path:       path,
+			Constraint: constraint,
+			Value:      encoded,
+			Message:    name + " " + message,
+		})
+	}
+
+	if len(schema.Enum) > 0 && !enumContains(schema.Enum, value) {
+		allowed := make([]string, len(schema.Enum))
+		for i, option := range schema.Enum {
+			allowed[i] = compactJSON(option)
+		}
+		encoded, _ := json.Marshal(value)
+		report(ConstraintEnum, fmt.Sprintf("must be one of %s, got %s", strings.Join(allowed, ", "), encoded))
+	}
+
+	switch v := value.(type) {
+	case map[string]any:
+		keys := make([]string, 0, len(v))
+		for key := range v {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys) // Report violations in a stable order
+		for _, key := range keys {
+			checkValue(toolName, joinPath(path, key), v[key], schema.Properties[key], violations)
+		}
+	case []any:
+		for i, item := range v {
+			checkValue(toolName, path+"["+strconv.Itoa(i)+"]", item, schema.Items, violations)
+		}
+	case json.Number:
+		n, err := v.Float64()
+		if err != nil {
+			return
+		}
+		if schema.Minimum != nil && n < *schema.Minimum {
+			report(ConstraintMinimum, fmt.Sprintf("must be at least %s, got %s", formatFloat(*schema.Minimum), v))
+		}
+		if schema.Maximum != nil && n > *schema.Maximum {
+			report(ConstraintMaximum, fmt.Sprintf("must be at most %s, got %s", formatFloat(*schema.Maximum), v))
+		}
+		if limit, ok := schema.exclusiveMinimum(); ok && n <= limit {
+			report(ConstraintExclusiveMinimum, fmt.Sprintf("must be greater than %s, got %s", formatFloat(limit), v))
+		}
+		if limit, ok := schema.exclusiveMaximum(); ok && n >= limit {
+			report(ConstraintExclusiveMaximum, fmt.Sprintf("must be less than %s, got %s", formatFloat(limit), v))
+		}
+	case string:
+		length := utf8.RuneCountInString(v)
+		if schema.MinLength != nil && length < *schema.MinLength {
+			report(ConstraintMinLength, fmt.Sprintf("must be at least %d characters, got %d", *schema.MinLength, length))
+		}
+		if schema.MaxLength != nil && length > *schema.MaxLength {
+			report(ConstraintMaxLength, fmt.Sprintf("must be at most %d characters, got %d", *schema.MaxLength, length))
+		}
+	}
+}
+
+// exclusiveMinimum returns the numeric exclusiveMinimum. The boolean form used by
+// JSON Schema draft 4 is not supported.
+func (s *argumentSchema) exclusiveMinimum() (float64, bool) {
+	return parseLimit(s.ExclusiveMinimum)
+}
+
+// exclusiveMaximum returns the numeric exclusiveMaximum.
+func (s *argumentSchema) exclusiveMaximum() (float64, bool) {
+	return parseLimit(s.ExclusiveMaximum)
+}
+
+func parseLimit(raw json.RawMessage) (float64, bool) {
+	if len(raw) == 0 {
+		return 0, false
+	}
+	var limit float64
+	if json.Unmarshal(raw, &limit) != nil {
+		return 0, false
+	}
+	return limit, true
+}
+
+// enumContains reports whether value equals one of the enum options as JSON.
+func enumContains(enum []json.RawMessage, value any) bool {
+	encoded, err := json.Marshal(value)
+	if err != nil {
+		return true
+	}
+	for _, option := range enum {
+		if compactJSON(option) == string(encoded) {
+			return true
+		}
+	}
+	return false
+}
+
+// compactJSON re-encodes raw with sorted keys and no whitespace.
+func compactJSON(raw json.RawMessage) string {
+	var value any
+	decoder := json.NewDecoder(bytes.NewReader(raw))
+	decoder.UseNumber()
+	if decoder.Decode(&value) != nil {
+		return string(raw)
+	}
+	encoded, err := json.Marshal(value)
+	if err != nil {
+		return string(raw)
+	}
+	return string(encoded)
+}
+
+func joinPath(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
+
+func formatFloat(f float64) string {
+	return strconv.FormatFloat(f, 'f', -1, 64)
+}
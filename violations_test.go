@@ -0,0 +1,155 @@
+package tooladapter_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	tooladapter "github.com/juburr/openai-tool-adapter/v3"
+	"github.com/openai/openai-go/v3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func constrainedWeatherTool() openai.ChatCompletionToolUnionParam {
+	return openai.ChatCompletionFunctionTool(openai.FunctionDefinitionParam{
+		Name: "get_weather",
+		Parameters: openai.FunctionParameters{
+			"type": "object",
+			"properties": map[string]any{
+				"city": map[string]any{"type": "string", "minLength": 2, "maxLength": 40},
+				"unit": map[string]any{"type": "string", "enum": []string{"celsius", "fahrenheit"}},
+				"days": map[string]any{"type": "integer", "minimum": 1, "maximum": 7},
+				"stops": map[string]any{
+					"type": "array",
+					"items": map[string]any{
+						"type": "object",
+						"properties": map[string]any{
+							"hours": map[string]any{"type": "number", "exclusiveMinimum": 0},
+						},
+					},
+				},
+			},
+		},
+	})
+}
+
+func TestArgumentViolations(t *testing.T) {
+	tools := []openai.ChatCompletionToolUnionParam{constrainedWeatherTool()}
+	ctx := tooladapter.ContextWithTools(context.Background(), tools)
+	badCall := `{"name": "get_weather", "parameters": {"city": "Paris", "unit": "kelvin", "days": 10}}`
+
+	t.Run("ReportsEachConstraint", func(t *testing.T) {
+		resp, err := tooladapter.New().TransformCompletionsResponse(createMockCompletion(
+			`{"name": "get_weather", "parameters": {"city": "P", "unit": "kelvin", "days": 0, "stops": [{"hours": 2}, {"hours": 0}]}}`))
+		require.NoError(t, err)
+
+		violations := tooladapter.ArgumentViolations(resp, tools)
+		require.Len(t, violations, 4)
+
+		assert.Equal(t, "city", violations[0].Path)
+		assert.Equal(t, tooladapter.ConstraintMinLength, violations[0].Constraint)
+		assert.Equal(t, "days", violations[1].Path)
+		assert.Equal(t, tooladapter.ConstraintMinimum, violations[1].Constraint)
+		assert.Equal(t, "stops[1].hours", violations[2].Path)
+		assert.Equal(t, tooladapter.ConstraintExclusiveMinimum, violations[2].Constraint)
+		assert.Equal(t, "unit", violations[3].Path)
+		assert.Equal(t, tooladapter.ConstraintEnum, violations[3].Constraint)
+		assert.Equal(t, "get_weather", violations[3].ToolName)
+		assert.JSONEq(t, `"kelvin"`, string(violations[3].Value))
+		assert.Equal(t, `unit must be one of "celsius", "fahrenheit", got "kelvin"`, violations[3].Message)
+	})
+
+	t.Run("ValidArguments", func(t *testing.T) {
+		resp, err := tooladapter.New().TransformCompletionsResponse(createMockCompletion(
+			`{"name": "get_weather", "parameters": {"city": "Paris", "unit": "celsius", "days": 7}}`))
+		require.NoError(t, err)
+		assert.Empty(t, tooladapter.ArgumentViolations(resp, tools))
+	})
+
+	t.Run("IgnoredByDefault", func(t *testing.T) {
+		resp, err := tooladapter.New().TransformCompletionsResponseWithContext(ctx, createMockCompletion(badCall))
+		require.NoError(t, err)
+		assert.Len(t, resp.Choices[0].Message.ToolCalls, 1)
+	})
+
+	t.Run("ReportPolicy", func(t *testing.T) {
+		var reported []tooladapter.ArgumentViolation
+		adapter := tooladapter.New(
+			tooladapter.WithArgumentViolationPolicy(tooladapter.ArgumentViolationReport),
+			tooladapter.WithMetricsCallback(func(data tooladapter.MetricEventData) {
+				if v, ok := data.(tooladapter.ArgumentViolationData); ok {
+					reported = append(reported, v.Violations...)
+				}
+			}))
+
+		resp, err := adapter.TransformCompletionsResponseWithContext(ctx, createMockCompletion(badCall))
+		require.NoError(t, err)
+		assert.Len(t, resp.Choices[0].Message.ToolCalls, 1, "arguments are still forwarded")
+		require.Len(t, reported, 2)
+		assert.Equal(t, `days must be at most 7, got 10`, reported[0].Message)
+		assert.Equal(t, tooladapter.ConstraintEnum, reported[1].Constraint)
+	})
+
+	t.Run("ErrorPolicy", func(t *testing.T) {
+		adapter := tooladapter.New(tooladapter.WithArgumentViolationPolicy(tooladapter.ArgumentViolationError))
+
+		_, err := adapter.TransformCompletionsResponseWithContext(ctx, createMockCompletion(badCall))
+		require.Error(t, err)
+		assert.True(t, errors.Is(err, tooladapter.ErrArgumentViolation))
+
+		var violationErr *tooladapter.ToolArgumentError
+		require.True(t, errors.As(err, &violationErr))
+		assert.Len(t, violationErr.Violations, 2)
+		assert.Contains(t, err.Error(), `unit must be one of "celsius", "fahrenheit", got "kelvin"`)
+	})
+
+	t.Run("ErrorPolicyStreaming", func(t *testing.T) {
+		adapter := tooladapter.New(tooladapter.WithArgumentViolationPolicy(tooladapter.ArgumentViolationError))
+		stream := adapter.TransformStreamingResponseWithContext(ctx, NewMockStream([]openai.ChatCompletionChunk{
+			createStreamChunk(badCall),
+			createFinishChunk("stop"),
+		}))
+		for stream.Next() {
+			assert.Empty(t, stream.Current().Choices[0].Delta.ToolCalls)
+		}
+		assert.True(t, errors.Is(stream.Err(), tooladapter.ErrArgumentViolation))
+	})
+
+	t.Run("CheckedAfterCoercion", func(t *testing.T) {
+		adapter := tooladapter.New(
+			tooladapter.WithArgumentCoercion(true),
+			tooladapter.WithArgumentViolationPolicy(tooladapter.ArgumentViolationError))
+
+		resp, err := adapter.TransformCompletionsResponseWithContext(ctx, createMockCompletion(
+			`{"name": "get_weather", "parameters": {"city": "Paris", "days": "3"}}`))
+		require.NoError(t, err)
+		assert.JSONEq(t, `{"city": "Paris", "days": 3}`, resp.Choices[0].Message.ToolCalls[0].Function.Arguments)
+	})
+
+	t.Run("RetriesViolations", func(t *testing.T) {
+		client := &scriptedClient{replies: []string{
+			badCall,
+			`{"name": "get_weather", "parameters": {"city": "Paris", "unit": "celsius", "days": 7}}`,
+		}}
+		params := createMockRequest(tools)
+		adapter := tooladapter.New(tooladapter.WithArgumentViolationPolicy(tooladapter.ArgumentViolationError))
+
+		resp, err := adapter.CompleteWithRetry(context.Background(), client, params, tooladapter.RetryPolicy{MaxAttempts: 2})
+		require.NoError(t, err)
+		assert.Len(t, resp.Choices[0].Message.ToolCalls, 1)
+
+		require.Len(t, client.requests, 2)
+		retried := client.requests[1].Messages
+		correction := retried[len(retried)-1].OfUser
+		require.NotNil(t, correction)
+		assert.Contains(t, correction.Content.OfString.Value, `unit must be one of "celsius", "fahrenheit", got "kelvin"`)
+	})
+}
+
+func TestArgumentViolationPolicy_String(t *testing.T) {
+	assert.Equal(t, "ArgumentViolationIgnore", tooladapter.ArgumentViolationIgnore.String())
+	assert.Equal(t, "ArgumentViolationReport", tooladapter.ArgumentViolationReport.String())
+	assert.Equal(t, "ArgumentViolationError", tooladapter.ArgumentViolationError.String())
+	assert.Equal(t, "ArgumentViolationPolicy(9)", tooladapter.ArgumentViolationPolicy(9).String())
+}